@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/spf13/cobra"
+)
+
+// addAccessCheckFlag adds --skip-access-check to a command that creates branches in GitLab
+func addAccessCheckFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("skip-access-check", false, "Don't check that the token may create the branches before creating any, e.g. for administrators, who may not be project members")
+}
+
+// checkCreateAccess fails before anything is created when the token cannot create branches
+// in projectPath: it lacks the api scope or the developer role, or one of branches matches
+// a protected branch its role may not push to. When the token may not list the protected
+// branches, only its role is checked.
+func checkCreateAccess(ctx context.Context, client gitlab.API, projectPath string, branches []string) error {
+	info, access, protected, err := createAccess(ctx, client, projectPath)
+	if err != nil {
+		return err
+	}
+	for _, branch := range branches {
+		if err := gitlab.CheckCreateBranch(access, info.Scopes, protected, branch); err != nil {
+			return err
+		}
+	}
+	logger.Debug("token may create the branches", "project", projectPath, "role", gitlab.AccessLevelName(access.AccessLevel), "protected_branches", len(protected))
+	return nil
+}
+
+// checkCreatePrefixAccess is checkCreateAccess for a run whose branch names are only known
+// as its references are read: every name starts with prefix, so it fails when a protected
+// branch could match any name starting with it
+func checkCreatePrefixAccess(ctx context.Context, client gitlab.API, projectPath, prefix string) error {
+	info, access, protected, err := createAccess(ctx, client, projectPath)
+	if err != nil {
+		return err
+	}
+	if err := gitlab.CheckCreateBranchPrefix(access, info.Scopes, protected, prefix); err != nil {
+		return err
+	}
+	logger.Debug("token may create the branches", "project", projectPath, "prefix", prefix, "role", gitlab.AccessLevelName(access.AccessLevel), "protected_branches", len(protected))
+	return nil
+}
+
+// createAccess reads the token, its access to projectPath and the project's protected
+// branches, which are left empty with a warning when the token may not list them
+func createAccess(ctx context.Context, client gitlab.API, projectPath string) (*gitlab.TokenInfo, *gitlab.ProjectAccess, []gitlab.ProtectedBranch, error) {
+	info, err := client.GetTokenInfo(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	access, err := client.GetProjectAccess(ctx, projectPath, info.Scopes)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	protected, err := client.ProtectedBranches(ctx, projectPath)
+	if errors.Is(err, gitlab.ErrUnauthorized) {
+		logger.Warn("cannot list protected branches; not checking the branches against them", "project", projectPath, "error", err)
+	} else if err != nil {
+		return nil, nil, nil, err
+	}
+	return info, access, protected, nil
+}
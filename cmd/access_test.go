@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/plan"
+)
+
+func TestCheckCreateAccess(t *testing.T) {
+	api := newMockAPI()
+	api.tokenInfo = &gitlab.TokenInfo{Name: "migration", Owner: "migrator", Scopes: []string{"api"}}
+	api.projectAccess = &gitlab.ProjectAccess{ProjectPath: "group/project", AccessLevel: 30}
+
+	if err := checkCreateAccess(context.Background(), api, "group/project", []string{"migration-pr-1"}); err != nil {
+		t.Fatalf("checkCreateAccess() unexpected error = %v", err)
+	}
+
+	api.protected = []gitlab.ProtectedBranch{{Name: "migration-*", CreateAccessLevel: 40}}
+	err := checkCreateAccess(context.Background(), api, "group/project", []string{"feature", "migration-pr-1"})
+	if !errors.Is(err, gitlab.ErrInsufficientAccess) {
+		t.Fatalf("checkCreateAccess() error = %v, want ErrInsufficientAccess", err)
+	}
+	if exitCode(err) != exitAuth {
+		t.Errorf("exitCode() = %d, want %d", exitCode(err), exitAuth)
+	}
+
+	// Tokens that may not list the protected branches are only checked for their role
+	api.protectedErr = fmt.Errorf("failed to list protected branches of group/project: %w", gitlab.ErrUnauthorized)
+	if err := checkCreateAccess(context.Background(), api, "group/project", []string{"migration-pr-1"}); err != nil {
+		t.Errorf("checkCreateAccess() unexpected error = %v", err)
+	}
+}
+
+func TestCheckCreatePrefixAccess(t *testing.T) {
+	api := newMockAPI()
+	api.tokenInfo = &gitlab.TokenInfo{Name: "migration", Owner: "migrator", Scopes: []string{"api"}}
+	api.projectAccess = &gitlab.ProjectAccess{ProjectPath: "group/project", AccessLevel: 30}
+	prefix := refNaming{}.branchPrefix()
+
+	api.protected = []gitlab.ProtectedBranch{{Name: "main", CreateAccessLevel: 40}}
+	if err := checkCreatePrefixAccess(context.Background(), api, "group/project", prefix); err != nil {
+		t.Fatalf("checkCreatePrefixAccess() unexpected error = %v", err)
+	}
+
+	// Only later merge requests would get a matching name, but the run is stopped up front
+	api.protected = []gitlab.ProtectedBranch{{Name: prefix + "2*", CreateAccessLevel: 40}}
+	err := checkCreatePrefixAccess(context.Background(), api, "group/project", prefix)
+	if !errors.Is(err, gitlab.ErrInsufficientAccess) {
+		t.Fatalf("checkCreatePrefixAccess() error = %v, want ErrInsufficientAccess", err)
+	}
+	if exitCode(err) != exitAuth {
+		t.Errorf("exitCode() = %d, want %d", exitCode(err), exitAuth)
+	}
+}
+
+func TestPlannedBranches(t *testing.T) {
+	p := &plan.Plan{
+		Actions: []plan.Action{
+			{Type: plan.ActionCreate, Branch: "migration-pr-1"},
+			{Type: plan.ActionSkip, Branch: "migration-pr-2"},
+			{Type: plan.ActionUpdate, Branch: "migration-pr-3"},
+		},
+	}
+
+	got := plannedBranches(p)
+	if len(got) != 2 || got[0] != "migration-pr-1" || got[1] != "migration-pr-3" {
+		t.Errorf("plannedBranches() = %v, want migration-pr-1 and migration-pr-3", got)
+	}
+}
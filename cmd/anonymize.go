@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/export"
+	"github.com/amenocal/gh-gl-create-refs/pkg/redact"
+	"github.com/spf13/cobra"
+)
+
+// addAnonymizeFlags registers --anonymize and --anonymize-key on an export command
+func addAnonymizeFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("anonymize", false, "Replace usernames with pseudonyms and drop names, bodies and other personal data")
+	cmd.Flags().String("anonymize-key", "", "Key the --anonymize pseudonyms are derived from, to keep them consistent across exports (default: random per run)")
+}
+
+// anonymizerFlag returns the Anonymizer selected with --anonymize, or nil
+func anonymizerFlag(cmd *cobra.Command) (*export.Anonymizer, error) {
+	anonymize, _ := cmd.Flags().GetBool("anonymize")
+	key := cmd.Flag("anonymize-key").Value.String()
+	if !anonymize {
+		if key != "" {
+			return nil, fmt.Errorf("--anonymize-key can only be used with --anonymize")
+		}
+		return nil, nil
+	}
+
+	// Whoever has the key can check guesses of who is behind a pseudonym
+	redact.Add(key)
+	return export.NewAnonymizer(key)
+}
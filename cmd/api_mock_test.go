@@ -0,0 +1,355 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strings"
+	"sync"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/github"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	gogitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// mockAPI is an in-memory gitlab.API used to test command logic without a GitLab server
+type mockAPI struct {
+	mu sync.Mutex
+
+	// refs holds the merge request references of each project; MergeRequestRefs skips
+	// those without a head SHA, as the client does, but MergeRequests lists them
+	refs map[string][]gitlab.MergeRequestRef
+	// updatedSince, when set, is returned instead of refs for incremental fetches
+	updatedSince map[string][]gitlab.MergeRequestRef
+	// fetchErr, when set, is returned after the references of a project have been yielded
+	fetchErr error
+	// branches maps project -> branch -> SHA
+	branches map[string]map[string]string
+	// failBranches makes CreateBranch fail for these branch names
+	failBranches map[string]bool
+	// createErr, when set, is returned by every CreateBranch call
+	createErr error
+
+	// discussions holds the discussions of each project's merge requests, by IID
+	discussions map[string]map[int][]gitlab.Discussion
+
+	// missingCommits maps project -> SHAs CommitExists does not find
+	missingCommits map[string]map[string]bool
+
+	// projects holds the project info returned by GetProjectInfo; missing projects are not found
+	projects map[string]*gitlab.ProjectInfo
+	// moved maps the old paths of renamed or transferred projects to their current ones
+	moved map[string]string
+
+	groupProjects []string
+	tokenInfo     *gitlab.TokenInfo
+	projectAccess *gitlab.ProjectAccess
+	// protected holds the protected branches of every project, and protectedErr, when set,
+	// is returned instead
+	protected    []gitlab.ProtectedBranch
+	protectedErr error
+
+	// calls records the mutating operations performed, in order
+	calls []string
+}
+
+var _ gitlab.API = (*mockAPI)(nil)
+
+func newMockAPI() *mockAPI {
+	return &mockAPI{
+		refs:         make(map[string][]gitlab.MergeRequestRef),
+		updatedSince: make(map[string][]gitlab.MergeRequestRef),
+		branches:     make(map[string]map[string]string),
+		failBranches: make(map[string]bool),
+		projects:     make(map[string]*gitlab.ProjectInfo),
+	}
+}
+
+func (m *mockAPI) FetchMergeRequestRefsFromRepo(ctx context.Context, repoPath string, baseURLOverride string, processor gitlab.MergeRequestProcessor, options ...gitlab.FetchOption) (string, error) {
+	_, projectPath, err := gitlab.ParseRepoPath(repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	for ref, err := range m.MergeRequestRefs(ctx, projectPath, options...) {
+		if err != nil {
+			return "", err
+		}
+		if err := processor(ref); err != nil {
+			return "", err
+		}
+	}
+	return projectPath, nil
+}
+
+func (m *mockAPI) MergeRequestRefs(ctx context.Context, projectPath string, options ...gitlab.FetchOption) iter.Seq2[gitlab.MergeRequestRef, error] {
+	opts := gitlab.NewFetchOptions(options...)
+
+	return func(yield func(gitlab.MergeRequestRef, error) bool) {
+		m.mu.Lock()
+		refs, ok := m.refs[projectPath]
+		if !opts.UpdatedAfter.IsZero() {
+			refs = m.updatedSince[projectPath]
+		}
+		m.mu.Unlock()
+
+		if !ok {
+			yield(gitlab.MergeRequestRef{}, fmt.Errorf("repository not found: %s: %w", projectPath, gitlab.ErrProjectNotFound))
+			return
+		}
+		for _, ref := range refs {
+			if ref.HeadSHA == "" {
+				continue
+			}
+			if !opts.IncludeDrafts && ref.MergeRequest != nil && ref.MergeRequest.Draft {
+				continue
+			}
+			if opts.Search != "" && (ref.MergeRequest == nil || !strings.Contains(ref.MergeRequest.Title+"\n"+ref.MergeRequest.Description, opts.Search)) {
+				continue
+			}
+			if opts.Skip != nil && opts.Skip(ref.IID) {
+				continue
+			}
+			if !yield(ref, nil) {
+				return
+			}
+		}
+		if m.fetchErr != nil {
+			yield(gitlab.MergeRequestRef{}, m.fetchErr)
+		}
+	}
+}
+
+func (m *mockAPI) MergeRequests(ctx context.Context, projectPath string, options ...gitlab.FetchOption) iter.Seq2[*gogitlab.BasicMergeRequest, error] {
+	opts := gitlab.NewFetchOptions(options...)
+
+	return func(yield func(*gogitlab.BasicMergeRequest, error) bool) {
+		m.mu.Lock()
+		refs, ok := m.refs[projectPath]
+		m.mu.Unlock()
+
+		if !ok {
+			yield(nil, fmt.Errorf("repository not found: %s: %w", projectPath, gitlab.ErrProjectNotFound))
+			return
+		}
+		for _, ref := range refs {
+			mr := &gogitlab.BasicMergeRequest{}
+			if ref.MergeRequest != nil {
+				*mr = ref.MergeRequest.BasicMergeRequest
+			}
+			mr.ID, mr.IID, mr.SHA = ref.ID, ref.IID, ref.HeadSHA
+			if !opts.IncludeDrafts && mr.Draft {
+				continue
+			}
+			if opts.Skip != nil && opts.Skip(mr.IID) {
+				continue
+			}
+			if !yield(mr, nil) {
+				return
+			}
+		}
+		if m.fetchErr != nil {
+			yield(nil, m.fetchErr)
+		}
+	}
+}
+
+func (m *mockAPI) MergeRequestDiscussions(ctx context.Context, projectPath string, iid int) ([]gitlab.Discussion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.discussions[projectPath][iid], nil
+}
+
+func (m *mockAPI) CommitExists(ctx context.Context, projectPath, sha string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return !m.missingCommits[projectPath][sha], nil
+}
+
+func (m *mockAPI) GetProjectInfo(ctx context.Context, projectPath string) (*gitlab.ProjectInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	info, ok := m.projects[projectPath]
+	if !ok {
+		return nil, fmt.Errorf("repository not found: %s: %w", projectPath, gitlab.ErrProjectNotFound)
+	}
+	return info, nil
+}
+
+func (m *mockAPI) ListGroupProjects(ctx context.Context, groupPath string, filter gitlab.ProjectFilter) ([]string, error) {
+	return m.groupProjects, nil
+}
+
+func (m *mockAPI) ListBranchSHAs(ctx context.Context, projectPath, prefix string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make(map[string]string)
+	for name, sha := range m.branches[projectPath] {
+		result[name] = sha
+	}
+	return result, nil
+}
+
+func (m *mockAPI) BranchSHA(ctx context.Context, projectPath, branchName string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sha, exists := m.branches[projectPath][branchName]
+	if !exists {
+		return "", fmt.Errorf("branch '%s' not found: %w", branchName, gitlab.ErrBranchNotFound)
+	}
+	return sha, nil
+}
+
+func (m *mockAPI) CreateBranch(ctx context.Context, projectPath, branchName, ref string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls = append(m.calls, fmt.Sprintf("create %s %s@%s", projectPath, branchName, ref))
+
+	if m.createErr != nil {
+		return m.createErr
+	}
+	if m.failBranches[branchName] {
+		return fmt.Errorf("failed to create branch '%s'", branchName)
+	}
+	if _, exists := m.branches[projectPath][branchName]; exists {
+		return fmt.Errorf("branch '%s' already exists: %w", branchName, gitlab.ErrBranchExists)
+	}
+
+	if m.branches[projectPath] == nil {
+		m.branches[projectPath] = make(map[string]string)
+	}
+	m.branches[projectPath][branchName] = ref
+	return nil
+}
+
+func (m *mockAPI) DeleteBranch(ctx context.Context, projectPath, branchName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls = append(m.calls, fmt.Sprintf("delete %s %s", projectPath, branchName))
+
+	if _, exists := m.branches[projectPath][branchName]; !exists {
+		return fmt.Errorf("branch '%s' not found: %w", branchName, gitlab.ErrBranchNotFound)
+	}
+	delete(m.branches[projectPath], branchName)
+	return nil
+}
+
+func (m *mockAPI) ProjectPath(ctx context.Context, projectPath string) (string, error) {
+	if current, ok := m.moved[projectPath]; ok {
+		return current, nil
+	}
+	return projectPath, nil
+}
+
+func (m *mockAPI) GetTokenInfo(ctx context.Context) (*gitlab.TokenInfo, error) {
+	return m.tokenInfo, nil
+}
+
+func (m *mockAPI) GetProjectAccess(ctx context.Context, projectPath string, scopes []string) (*gitlab.ProjectAccess, error) {
+	return m.projectAccess, nil
+}
+
+func (m *mockAPI) ProtectedBranches(ctx context.Context, projectPath string) ([]gitlab.ProtectedBranch, error) {
+	if m.protectedErr != nil {
+		return nil, m.protectedErr
+	}
+	return m.protected, nil
+}
+
+// mockGitHubAPI is an in-memory github.API that records the batches it receives
+type mockGitHubAPI struct {
+	// refs maps ref name -> SHA of the references created so far
+	refs map[string]string
+	// batches records the size of every CreateRefs call
+	batches []int
+	// createErr, when set, fails every CreateRefs call as a whole
+	createErr error
+	// missing holds the SHAs that are not commits in the repository
+	missing map[string]bool
+	// issues records the issues opened, as repository, title and body
+	issues [][3]string
+	// comments records the comments posted, as issue and body
+	comments [][2]string
+	// pulls holds the pull requests of the repository
+	pulls []github.PullRequest
+}
+
+var _ github.API = (*mockGitHubAPI)(nil)
+
+func newMockGitHubAPI() *mockGitHubAPI {
+	return &mockGitHubAPI{refs: make(map[string]string), missing: make(map[string]bool)}
+}
+
+func (m *mockGitHubAPI) CommitsExist(ctx context.Context, repository string, shas []string) ([]bool, error) {
+	exists := make([]bool, len(shas))
+	for i, sha := range shas {
+		exists[i] = !m.missing[sha]
+	}
+	return exists, nil
+}
+
+func (m *mockGitHubAPI) PullRequests(ctx context.Context, repository string) iter.Seq2[github.PullRequest, error] {
+	return func(yield func(github.PullRequest, error) bool) {
+		for _, pr := range m.pulls {
+			if !yield(pr, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (m *mockGitHubAPI) RepositoryID(ctx context.Context, repository string) (string, error) {
+	return "R_" + repository, nil
+}
+
+func (m *mockGitHubAPI) CreateRefs(ctx context.Context, repositoryID string, refs []github.Ref) ([]error, error) {
+	m.batches = append(m.batches, len(refs))
+	if m.createErr != nil {
+		return nil, m.createErr
+	}
+
+	results := make([]error, len(refs))
+	for i, ref := range refs {
+		if _, exists := m.refs[ref.Name]; exists {
+			results[i] = fmt.Errorf("failed to create %s: %w", ref.Name, github.ErrRefExists)
+			continue
+		}
+		m.refs[ref.Name] = ref.SHA
+	}
+	return results, nil
+}
+
+// RefTargets finds the references created so far; their node ID is their name
+func (m *mockGitHubAPI) RefTargets(ctx context.Context, repository string, names []string) ([]*github.RefTarget, error) {
+	targets := make([]*github.RefTarget, len(names))
+	for i, name := range names {
+		if sha, exists := m.refs[name]; exists {
+			targets[i] = &github.RefTarget{ID: name, SHA: sha}
+		}
+	}
+	return targets, nil
+}
+
+func (m *mockGitHubAPI) UpdateRefs(ctx context.Context, updates []github.RefUpdate) ([]error, error) {
+	for _, u := range updates {
+		m.refs[u.ID] = u.SHA
+	}
+	return make([]error, len(updates)), nil
+}
+
+func (m *mockGitHubAPI) CreateIssue(ctx context.Context, repository, title, body string) (string, error) {
+	m.issues = append(m.issues, [3]string{repository, title, body})
+	return fmt.Sprintf("https://github.com/%s/issues/%d", repository, len(m.issues)), nil
+}
+
+func (m *mockGitHubAPI) AddComment(ctx context.Context, issue github.IssueRef, body string) (string, error) {
+	m.comments = append(m.comments, [2]string{issue.String(), body})
+	return fmt.Sprintf("https://github.com/%s/issues/%d#issuecomment-%d", issue.Repository, issue.Number, len(m.comments)), nil
+}
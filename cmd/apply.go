@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/auth"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/migrate"
+	"github.com/amenocal/gh-gl-create-refs/pkg/plan"
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <plan-file>",
+	Short: "Execute the branch operations recorded in a plan file",
+	Long: `Execute exactly the actions recorded in a plan file produced by 'create-refs plan'.
+
+Branches marked 'create' are created, branches marked 'update' are deleted and recreated at
+the planned SHA, and branches marked 'skip' are left untouched. Nothing outside the plan is changed.
+
+Because updates delete branches, a plan with updates is only applied after you retype the
+target project path, unless --yes is given. Applying a plan to another project than the one
+it was made for (with --target), or on another GitLab instance (with --base-url), is refused
+without --force-project.
+
+Examples:
+  gh gl-create-refs create-refs apply plan.json
+  gh gl-create-refs create-refs apply plan.json --mock
+  gh gl-create-refs create-refs apply plan.json --yes`,
+	Args: cobra.ExactArgs(1),
+	RunE: runApply,
+}
+
+func init() {
+	createRefsCmd.AddCommand(applyCmd)
+
+	addTokenFlags(applyCmd)
+	applyCmd.Flags().StringP("base-url", "b", "", "GitLab base URL (default: the base URL recorded in the plan, or https://gitlab.com)")
+	applyCmd.Flags().Bool("mock", false, "Mock mode: print the planned actions without executing them")
+	applyCmd.Flags().BoolP("yes", "y", false, "Don't ask to retype the project path before deleting and recreating branches")
+	applyCmd.Flags().String("target", "", "GitLab project to apply the plan to (default: the project the plan was made for)")
+	applyCmd.Flags().Bool("force-project", false, "Apply the plan even when --target or --base-url is not the project or GitLab instance the plan was made for")
+	addAccessCheckFlag(applyCmd)
+}
+
+func runApply(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	baseURL := cmd.Flag("base-url").Value.String()
+	target := cmd.Flag("target").Value.String()
+	mock, _ := cmd.Flags().GetBool("mock")
+	yes, _ := cmd.Flags().GetBool("yes")
+	forceProject, _ := cmd.Flags().GetBool("force-project")
+	skipAccessCheck, _ := cmd.Flags().GetBool("skip-access-check")
+
+	p, err := plan.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := checkPlanTarget(p, baseURL, target, forceProject); err != nil {
+		return err
+	}
+	if target != "" {
+		urlBase, projectPath, _ := gitlab.ParseRepoPath(target)
+		p.Target = projectPath
+		baseURL = cmp.Or(baseURL, urlBase)
+	}
+	if baseURL == "" {
+		baseURL = p.BaseURL
+	}
+
+	if updates := p.Summarize().Update; updates > 0 && !mock && !yes {
+		prompt := newPrompter(cmd.InOrStdin(), cmd.ErrOrStderr())
+		question := fmt.Sprintf("This deletes and recreates %d branches in %s.", updates, p.Target)
+		if err := prompt.confirmTyped(question, p.Target); err != nil {
+			if errors.Is(err, errNoAnswer) {
+				return fmt.Errorf("the plan deletes and recreates %d branches in %s: confirm interactively or use --yes", updates, p.Target)
+			}
+			return err
+		}
+	}
+
+	client, err := newGitLabClient(cmd, baseURL)
+	if err != nil {
+		return err
+	}
+
+	if branches := plannedBranches(p); len(branches) > 0 && !mock && !skipAccessCheck {
+		if err := checkCreateAccess(ctx, client, p.Target, branches); err != nil {
+			return err
+		}
+	}
+
+	return applyPlan(ctx, client, p, mock)
+}
+
+// checkPlanTarget refuses a --target naming another project than the plan was made for,
+// whose branches the plan knows nothing about, and a --base-url, or a --target URL, naming
+// another GitLab instance, where the same project path may be an unrelated project
+func checkPlanTarget(p *plan.Plan, baseURL, target string, force bool) error {
+	if target != "" {
+		urlBase, projectPath, err := gitlab.ParseRepoPath(target)
+		if err != nil {
+			return fmt.Errorf("failed to parse target repository path: %w", err)
+		}
+		if projectPath != p.Target && !force {
+			return fmt.Errorf("the plan was made for %s, not %s; use --force-project to apply it anyway", p.Target, projectPath)
+		}
+		baseURL = cmp.Or(baseURL, urlBase)
+	}
+	if baseURL == "" || force {
+		return nil
+	}
+
+	planHost, err := auth.Host(p.BaseURL)
+	if err != nil {
+		return err
+	}
+	host, err := auth.Host(baseURL)
+	if err != nil {
+		return err
+	}
+	if host != planHost {
+		return fmt.Errorf("the plan targets %s on %s, not %s; use --force-project to apply it anyway", p.Target, planHost, host)
+	}
+	return nil
+}
+
+// plannedBranches returns the branches a plan creates or recreates
+func plannedBranches(p *plan.Plan) []string {
+	var branches []string
+	for _, action := range p.Actions {
+		if action.Type == plan.ActionCreate || action.Type == plan.ActionUpdate {
+			branches = append(branches, action.Branch)
+		}
+	}
+	return branches
+}
+
+func applyPlan(ctx context.Context, client gitlab.API, p *plan.Plan, mock bool) error {
+	target := p.Target
+	if mock {
+		logger.Info("mock mode: simulating plan", "project", target)
+	} else {
+		// GitLab doesn't redirect branch changes from the old path of a moved project
+		var err error
+		if target, err = followMovedProject(ctx, client, target); err != nil {
+			return err
+		}
+		l, err := lockRepository("gitlab", target)
+		if err != nil {
+			return err
+		}
+		defer releaseLock(l)
+		logger.Info("applying plan", "project", target)
+	}
+
+	successCount := 0
+	errorCount := 0
+	// driftCount are the failed actions whose branch changed since the plan was made
+	driftCount := 0
+	skipCount := 0
+	throughput := newProgress("apply throughput", len(p.Actions), "project", target)
+
+	for _, action := range p.Actions {
+		if err := interrupted(ctx); err != nil {
+			statusf("\nSummary:\n")
+			statusf("✅ %s: %d actions\n", green("Applied"), successCount)
+			return fmt.Errorf("stopped after %d of %d planned actions: %w", successCount+errorCount+skipCount, len(p.Actions), err)
+		}
+
+		throughput.add(1)
+
+		if action.Type == plan.ActionSkip {
+			skipCount++
+			continue
+		}
+		if action.Type == plan.ActionConflict {
+			// The branch is an earlier action's, as create-refs leaves it
+			logger.Error("planned action failed", "project", target, "action", action.Type, "branch", action.Branch, "iid", action.IID, "error", fmt.Errorf("branch '%s' is taken by an earlier merge request: %w", action.Branch, gitlab.ErrBranchExists))
+			errorCount++
+			runStats.branchesFailed.Add(1)
+			continue
+		}
+
+		if mock {
+			fmt.Printf("Would %s branch %s with sha: %s\n", action.Type, action.Branch, action.SHA)
+			successCount++
+			continue
+		}
+
+		err := applyAction(ctx, client, target, action)
+		switch {
+		case errors.Is(err, errPlanDrift):
+			logger.Warn("branch changed since the plan was made; action not applied", "project", target, "action", action.Type, "branch", action.Branch, "sha", action.SHA, "error", err)
+			errorCount++
+			driftCount++
+			runStats.branchesFailed.Add(1)
+		case err != nil:
+			logger.Error("planned action failed", "project", target, "action", action.Type, "branch", action.Branch, "sha", action.SHA, "error", err)
+			errorCount++
+			runStats.branchesFailed.Add(1)
+		default:
+			logger.Info("planned action applied", "project", target, "action", action.Type, "branch", action.Branch, "sha", action.SHA)
+			successCount++
+			runStats.branchesCreated.Add(1)
+		}
+	}
+
+	statusf("\nSummary:\n")
+	statusf("✅ %s: %d actions\n", green("Applied"), successCount)
+	if errorCount > 0 {
+		statusf("❌ %s: %d actions\n", red("Failed"), errorCount)
+	}
+	if driftCount > 0 {
+		statusf("⚠️  %s: %d branches\n", yellow("Changed since the plan"), driftCount)
+	}
+	statusf("⏭️  %s: %d branches\n", yellow("Unchanged"), skipCount)
+
+	if errorCount > 0 {
+		err := withExitCode(exitPartialFailure, fmt.Errorf("%d of %d planned actions failed", errorCount, successCount+errorCount))
+		if driftCount > 0 {
+			err = withHint(err, fmt.Sprintf("%d of the branches changed since the plan was made and were left alone. Make a new plan with create-refs plan to review them.", driftCount))
+		}
+		return err
+	}
+	return nil
+}
+
+// errPlanDrift fails a planned action whose branch changed since the plan was made
+var errPlanDrift = errors.New("branch changed since the plan was made")
+
+// applyAction performs one planned action, refusing with errPlanDrift to update a branch
+// that no longer points at the commit the plan saw or to create one that appeared since
+func applyAction(ctx context.Context, client gitlab.API, projectPath string, action plan.Action) error {
+	if action.Type == plan.ActionUpdate {
+		err := migrate.UpdateBranch(ctx, client, projectPath, action.Branch, action.CurrentSHA, action.SHA)
+		if errors.Is(err, migrate.ErrBranchMoved) {
+			return fmt.Errorf("%w: %w", errPlanDrift, err)
+		}
+		return err
+	}
+
+	err := client.CreateBranch(ctx, projectPath, action.Branch, action.SHA)
+	if errors.Is(err, gitlab.ErrBranchExists) {
+		return fmt.Errorf("%w: %w", errPlanDrift, err)
+	}
+	return err
+}
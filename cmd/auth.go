@@ -0,0 +1,377 @@
+package cmd
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/auth"
+	"github.com/amenocal/gh-gl-create-refs/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage GitLab tokens stored in the OS keyring",
+	Long: `Manage GitLab tokens stored in the OS keyring.
+
+Commands look up a token in this order: --token, --token-file, the GITLAB_TOKEN
+environment variable, then the keyring entry for the GitLab instance in --base-url.
+Log in once per instance with auth login, and every command finds the token there.
+Without an OS keyring, auth login stores the token in a file only you can read and
+sets it as token-file in the configuration file.`,
+}
+
+var authLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Check a GitLab token and store it in the OS keyring",
+	Long: `Log in to a GitLab instance: asks for the instance, unless --base-url is given or
+configured, and for a token, checks the token with GitLab and stores it in the OS keyring
+entry of the instance, where every command then finds it.
+
+The token is read from the prompt, where typing is hidden, from standard input or from
+--token-file, so it never appears on screen, in shell history or in process listings.
+Its owner, scopes and expiry are shown; an
+expired, revoked or inactive token is not stored. Use --skip-checks to store the token
+without contacting GitLab.
+
+When no OS keyring is available, as on headless machines, the token is written to
+tokens/<host> in the user configuration directory, readable only by you, and set as
+token-file in the configuration file, or in its --profile.
+
+Examples:
+  gh gl-create-refs auth login
+  gh gl-create-refs auth login --base-url https://gitlab.example.com
+  gh gl-create-refs auth login --base-url https://gitlab.example.com < token.txt
+  gh gl-create-refs auth login --token-file token.txt --profile staging`,
+	Args: cobra.NoArgs,
+	RunE: runAuthLogin,
+}
+
+var authLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove the GitLab token of an instance from the OS keyring",
+	Long: `Log out of a GitLab instance: removes its token from the OS keyring, and the token
+file auth login wrote when no keyring was available. The token itself stays valid; revoke
+it in GitLab under User Settings > Access Tokens if it is no longer needed.
+
+Examples:
+  gh gl-create-refs auth logout
+  gh gl-create-refs auth logout --base-url https://gitlab.example.com`,
+	Args: cobra.NoArgs,
+	RunE: runAuthLogout,
+}
+
+var authSetTokenCmd = &cobra.Command{
+	Use:   "set-token",
+	Short: "Store a GitLab token in the OS keyring",
+	Long: `Store a GitLab token in the OS keyring for a GitLab instance.
+
+The token is read from standard input or --token-file so it never appears in shell
+history or process listings.
+
+Examples:
+  gh gl-create-refs auth set-token < token.txt
+  gh gl-create-refs auth set-token --token-file token.txt --base-url https://gitlab.example.com`,
+	Args: cobra.NoArgs,
+	RunE: runAuthSetToken,
+}
+
+var authSetupGitCmd = &cobra.Command{
+	Use:   "setup-git",
+	Short: "Configure git to use the stored GitLab token for pushes",
+	Long: `Configure git to authenticate to a GitLab instance with the token these commands use,
+so pushes and fetches over https work without a token embedded in the remote URL, where it
+would leak into shell history, reflogs and .git/config.
+
+This sets credential.https://<host>.helper in your global git configuration to
+'gh gl-create-refs auth git-credential', which answers git with the token from the
+GITLAB_TOKEN environment variable or the keyring entry of the instance (see set-token).
+For GitHub, use gh's own helper: gh auth setup-git.
+
+Examples:
+  gh gl-create-refs auth setup-git
+  gh gl-create-refs auth setup-git --base-url https://gitlab.example.com`,
+	Args: cobra.NoArgs,
+	RunE: runAuthSetupGit,
+}
+
+var authGitCredentialCmd = &cobra.Command{
+	Use:    "git-credential <get|store|erase>",
+	Short:  "Git credential helper for GitLab, configured by setup-git",
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	RunE:   runAuthGitCredential,
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authLogoutCmd)
+	authCmd.AddCommand(authSetTokenCmd)
+	authCmd.AddCommand(authSetupGitCmd)
+	authCmd.AddCommand(authGitCredentialCmd)
+
+	authSetupGitCmd.Flags().StringP("base-url", "b", "", "GitLab base URL to configure git for (default: https://gitlab.com)")
+
+	authLoginCmd.Flags().StringP("base-url", "b", "", "GitLab base URL to log in to (default: asked, or https://gitlab.com)")
+	authLoginCmd.Flags().String("token-file", "", "Read the token from this file instead of asking for it")
+	authLoginCmd.Flags().Bool("skip-checks", false, "Store the token without checking it with GitLab")
+
+	authLogoutCmd.Flags().StringP("base-url", "b", "", "GitLab base URL to log out of (default: https://gitlab.com)")
+
+	authSetTokenCmd.Flags().String("token-file", "", "Read the token from this file instead of standard input")
+	authSetTokenCmd.Flags().StringP("base-url", "b", "", "GitLab base URL the token belongs to (default: https://gitlab.com)")
+}
+
+func runAuthSetToken(cmd *cobra.Command, args []string) error {
+	tokenFile := cmd.Flag("token-file").Value.String()
+	baseURL := cmd.Flag("base-url").Value.String()
+
+	var token string
+	var err error
+	if tokenFile != "" {
+		token, err = auth.ReadTokenFile(tokenFile)
+	} else {
+		token, err = readTokenFromStdin()
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := auth.StoreToken(baseURL, token); err != nil {
+		return err
+	}
+
+	host, _ := auth.Host(baseURL)
+	statusf("✅ Stored token for %s in the OS keyring\n", host)
+	return nil
+}
+
+func runAuthLogin(cmd *cobra.Command, args []string) error {
+	baseURL := cmd.Flag("base-url").Value.String()
+	tokenFile := cmd.Flag("token-file").Value.String()
+	skipChecks, _ := cmd.Flags().GetBool("skip-checks")
+
+	p := newPrompter(cmd.InOrStdin(), cmd.ErrOrStderr())
+
+	var err error
+	if baseURL == "" {
+		baseURL, err = p.ask("GitLab base URL", "https://gitlab.com", func(s string) error {
+			_, err := auth.Host(s)
+			return err
+		})
+		if errors.Is(err, errNoAnswer) {
+			return fmt.Errorf("no GitLab base URL given on standard input: use --base-url")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	host, err := auth.Host(baseURL)
+	if err != nil {
+		return err
+	}
+
+	var token string
+	if tokenFile != "" {
+		token, err = auth.ReadTokenFile(tokenFile)
+	} else {
+		p.printf("Create a token with the api scope at https://%s/-/user_settings/personal_access_tokens\n", host)
+		token, err = p.askSecret("Paste the token")
+		if errors.Is(err, errNoAnswer) {
+			return fmt.Errorf("no token given on standard input: pipe it in or use --token-file")
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if !skipChecks {
+		if _, _, err := checkInitToken(cmd, baseURL, token); err != nil {
+			return fmt.Errorf("token check failed, nothing was stored: %w", err)
+		}
+	}
+
+	if err := auth.StoreToken(baseURL, token); err != nil {
+		// Headless machines often have no keyring service to store it in
+		logger.Debug("OS keyring unavailable, storing the token in a file", "error", err)
+		path, err := storeTokenFile(cmd, host, token)
+		if err != nil {
+			return err
+		}
+		statusf("%s Logged in to %s; no OS keyring is available, so the token is stored in %s, set as token-file in %s\n", green("✅"), host, path, authConfigPath(cmd))
+	} else {
+		statusf("%s Logged in to %s; the token is stored in the OS keyring\n", green("✅"), host)
+	}
+	if os.Getenv("GITLAB_TOKEN") != "" {
+		logger.Warn("GITLAB_TOKEN is set and is used instead of the stored token; unset it to use the stored one")
+	}
+	return nil
+}
+
+func runAuthLogout(cmd *cobra.Command, args []string) error {
+	baseURL := cmd.Flag("base-url").Value.String()
+	host, err := auth.Host(baseURL)
+	if err != nil {
+		return err
+	}
+
+	removedFile, err := removeTokenFile(cmd, host)
+	if err != nil {
+		return err
+	}
+
+	if lookupStoredToken(baseURL) == "" {
+		if removedFile != "" {
+			statusf("%s Logged out of %s; the token file %s was removed\n", green("✅"), host, removedFile)
+			return nil
+		}
+		return fmt.Errorf("not logged in to %s: no token is stored in the OS keyring", host)
+	}
+
+	if err := auth.DeleteToken(baseURL); err != nil {
+		return err
+	}
+
+	statusf("%s Logged out of %s; the token was removed from the OS keyring\n", green("✅"), host)
+	return nil
+}
+
+// storeTokenFile keeps the token of host in its token file, for machines without an OS
+// keyring, and sets that file as the token-file of the configuration, or of its --profile,
+// so every command finds it. It returns the token file.
+func storeTokenFile(cmd *cobra.Command, host, token string) (string, error) {
+	path, err := auth.TokenFilePath(host)
+	if err != nil {
+		return "", err
+	}
+	if err := auth.WriteTokenFile(path, token); err != nil {
+		return "", err
+	}
+
+	err = editConfig(cmd, func(cfg *config.Config) { cfg.TokenFile = path })
+	return path, err
+}
+
+// removeTokenFile removes the token file auth login wrote for host, and unsets it as the
+// token-file of the configuration, or of its --profile. It returns the removed file, or ""
+// when there was none.
+func removeTokenFile(cmd *cobra.Command, host string) (string, error) {
+	path, err := auth.TokenFilePath(host)
+	if err != nil {
+		return "", nil
+	}
+	if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to remove token file: %w", err)
+	}
+
+	err = editConfig(cmd, func(cfg *config.Config) {
+		if cfg.TokenFile == path {
+			cfg.TokenFile = ""
+		}
+	})
+	return path, err
+}
+
+// authConfigPath returns the configuration file auth login and logout record a token file
+// in: --config, or the default configuration file
+func authConfigPath(cmd *cobra.Command) string {
+	return cmp.Or(flagValue(cmd, "config"), config.DefaultPath)
+}
+
+// editConfig applies edit to the configuration file, or to its --profile, and saves it
+func editConfig(cmd *cobra.Command, edit func(*config.Config)) error {
+	path := authConfigPath(cmd)
+	file, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+
+	if profile := flagValue(cmd, "profile"); profile != "" {
+		if file.Profiles == nil {
+			file.Profiles = make(map[string]config.Config)
+		}
+		cfg := file.Profiles[profile]
+		edit(&cfg)
+		file.Profiles[profile] = cfg
+	} else {
+		edit(&file)
+	}
+	return config.Save(path, file)
+}
+
+// readTokenFromStdin reads a token from the first line of standard input
+func readTokenFromStdin() (string, error) {
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read token from standard input: %w", err)
+	}
+
+	token := strings.TrimSpace(line)
+	if token == "" {
+		return "", fmt.Errorf("no token given on standard input")
+	}
+	return token, nil
+}
+
+func runAuthSetupGit(cmd *cobra.Command, args []string) error {
+	host, err := auth.Host(cmd.Flag("base-url").Value.String())
+	if err != nil {
+		return err
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate gh-gl-create-refs: %w", err)
+	}
+	helper := fmt.Sprintf("!'%s' auth git-credential", strings.ReplaceAll(executable, "'", `'\''`))
+
+	key, err := auth.SetupGit(host, helper)
+	if err != nil {
+		return err
+	}
+
+	statusf("✅ Configured git to authenticate to https://%s with gh-gl-create-refs (%s)\n", host, key)
+	return nil
+}
+
+// runAuthGitCredential answers git's credential requests for GitLab hosts. Only get is
+// answered; tokens are managed with set-token, so store and erase are ignored. Nothing is
+// written when no token is known, and git then asks its next helper or the user.
+func runAuthGitCredential(cmd *cobra.Command, args []string) error {
+	if args[0] != "get" {
+		return nil
+	}
+
+	request, err := auth.ReadCredential(cmd.InOrStdin())
+	if err != nil {
+		return err
+	}
+
+	// Tokens are never sent over plain http
+	if request.Protocol != "https" || request.Host == "" {
+		return nil
+	}
+
+	token := os.Getenv("GITLAB_TOKEN")
+	if token == "" {
+		token = lookupStoredToken("https://" + request.Host)
+	}
+	if token == "" {
+		return nil
+	}
+
+	answer := auth.Credential{
+		Protocol: request.Protocol,
+		Host:     request.Host,
+		Username: auth.GitLabUsername,
+		Password: token,
+	}
+	return answer.Write(cmd.OutOrStdout())
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/auth"
+	"github.com/amenocal/gh-gl-create-refs/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/zalando/go-keyring"
+)
+
+func TestRunAuthGitCredential(t *testing.T) {
+	keyring.MockInit()
+	if err := auth.StoreToken("https://gitlab.example.com", "keyring-token"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		operation string
+		input     string
+		env       string
+		expected  string
+	}{
+		{"keyring token", "get", "protocol=https\nhost=gitlab.example.com\n\n", "", "protocol=https\nhost=gitlab.example.com\nusername=oauth2\npassword=keyring-token\n"},
+		{"environment token", "get", "protocol=https\nhost=gitlab.com\n\n", "env-token", "protocol=https\nhost=gitlab.com\nusername=oauth2\npassword=env-token\n"},
+		{"unknown host", "get", "protocol=https\nhost=gitlab.other.com\n\n", "", ""},
+		{"plain http", "get", "protocol=http\nhost=gitlab.example.com\n\n", "env-token", ""},
+		{"store is ignored", "store", "protocol=https\nhost=gitlab.example.com\nusername=oauth2\npassword=other\n\n", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITLAB_TOKEN", tt.env)
+
+			var out bytes.Buffer
+			authGitCredentialCmd.SetIn(strings.NewReader(tt.input))
+			authGitCredentialCmd.SetOut(&out)
+			defer authGitCredentialCmd.SetIn(nil)
+			defer authGitCredentialCmd.SetOut(nil)
+
+			if err := runAuthGitCredential(authGitCredentialCmd, []string{tt.operation}); err != nil {
+				t.Fatalf("runAuthGitCredential() unexpected error = %v", err)
+			}
+			if got := out.String(); got != tt.expected {
+				t.Errorf("runAuthGitCredential() wrote %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRunAuthLogin(t *testing.T) {
+	tests := []struct {
+		name        string
+		flags       []string
+		input       string
+		expected    map[string]string
+		expectError bool
+	}{
+		{
+			name:     "asked for instance and token",
+			input:    "https://gitlab.example.com\nglpat-example\n",
+			expected: map[string]string{"https://gitlab.example.com": "glpat-example"},
+		},
+		{
+			name:     "token piped in",
+			flags:    []string{"--base-url", "https://gitlab.internal.com"},
+			input:    "glpat-piped\n",
+			expected: map[string]string{"https://gitlab.internal.com": "glpat-piped", "https://gitlab.example.com": ""},
+		},
+		{
+			name:        "no token",
+			flags:       []string{"--base-url", "https://gitlab.example.com"},
+			expectError: true,
+			expected:    map[string]string{"https://gitlab.example.com": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring.MockInit()
+
+			cmd := &cobra.Command{}
+			cmd.Flags().StringP("base-url", "b", "", "")
+			cmd.Flags().String("token-file", "", "")
+			cmd.Flags().Bool("skip-checks", false, "")
+			if err := cmd.ParseFlags(append([]string{"--skip-checks"}, tt.flags...)); err != nil {
+				t.Fatal(err)
+			}
+			cmd.SetIn(strings.NewReader(tt.input))
+			cmd.SetErr(io.Discard)
+
+			err := runAuthLogin(cmd, nil)
+			if tt.expectError != (err != nil) {
+				t.Fatalf("runAuthLogin() error = %v, expectError %v", err, tt.expectError)
+			}
+
+			for baseURL, want := range tt.expected {
+				if got, _ := auth.LookupToken(baseURL); got != want {
+					t.Errorf("token of %s = %q, want %q", baseURL, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunAuthLogout(t *testing.T) {
+	keyring.MockInit()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := auth.StoreToken("https://gitlab.example.com", "keyring-token"); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &cobra.Command{}
+	cmd.Flags().StringP("base-url", "b", "", "")
+	if err := cmd.ParseFlags([]string{"--base-url", "https://gitlab.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runAuthLogout(cmd, nil); err != nil {
+		t.Fatalf("runAuthLogout() unexpected error = %v", err)
+	}
+	if token, _ := auth.LookupToken("https://gitlab.example.com"); token != "" {
+		t.Errorf("token = %q, want it removed", token)
+	}
+
+	if err := runAuthLogout(cmd, nil); err == nil || !strings.Contains(err.Error(), "not logged in to gitlab.example.com") {
+		t.Errorf("runAuthLogout() error = %v, want not logged in", err)
+	}
+}
+
+func TestRunAuthLogin_NoKeyring(t *testing.T) {
+	keyring.MockInitWithError(errors.New("no keyring service"))
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+	configFile := filepath.Join(dir, "config.yml")
+
+	cmd := &cobra.Command{}
+	cmd.Flags().StringP("base-url", "b", "", "")
+	cmd.Flags().String("token-file", "", "")
+	cmd.Flags().Bool("skip-checks", false, "")
+	cmd.Flags().String("config", "", "")
+	cmd.Flags().String("profile", "", "")
+	if err := cmd.ParseFlags([]string{"--skip-checks", "--base-url", "https://gitlab.example.com", "--config", configFile, "--profile", "staging"}); err != nil {
+		t.Fatal(err)
+	}
+	cmd.SetIn(strings.NewReader("glpat-headless\n"))
+	cmd.SetErr(io.Discard)
+
+	if err := runAuthLogin(cmd, nil); err != nil {
+		t.Fatalf("runAuthLogin() unexpected error = %v", err)
+	}
+
+	path, err := auth.TokenFilePath("gitlab.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token, err := auth.ReadTokenFile(path); err != nil || token != "glpat-headless" {
+		t.Errorf("token file = %q, %v, want glpat-headless", token, err)
+	}
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Profiles["staging"].TokenFile; got != path {
+		t.Errorf("staging token-file = %q, want %q", got, path)
+	}
+
+	if err := runAuthLogout(cmd, nil); err != nil {
+		t.Fatalf("runAuthLogout() unexpected error = %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("token file still exists: %v", err)
+	}
+	if cfg, _ := config.Load(configFile); cfg.Profiles["staging"].TokenFile != "" {
+		t.Errorf("staging token-file = %q, want it unset", cfg.Profiles["staging"].TokenFile)
+	}
+
+	if err := runAuthLogout(cmd, nil); err == nil || !strings.Contains(err.Error(), "not logged in to gitlab.example.com") {
+		t.Errorf("runAuthLogout() error = %v, want not logged in", err)
+	}
+}
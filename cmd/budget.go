@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// budget caps the GitLab API requests of the run when --max-api-calls is set, shared by
+// every client the run creates
+var budget *gitlab.Budget
+
+// budgetExhausted returns an error wrapping gitlab.ErrBudgetExhausted once the run has sent
+// as many requests as --max-api-calls allows, and nil before then or without a budget
+func budgetExhausted() error {
+	if !budget.Exhausted() {
+		return nil
+	}
+	return fmt.Errorf("%w: all %d requests of --max-api-calls used", gitlab.ErrBudgetExhausted, budget.Limit())
+}
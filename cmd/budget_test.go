@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+func TestInterrupted_BudgetExhausted(t *testing.T) {
+	defer func() { budget = nil }()
+
+	budget = gitlab.NewBudget(10)
+	if err := interrupted(context.Background()); err != nil {
+		t.Errorf("interrupted() with budget left = %v, want nil", err)
+	}
+
+	budget = gitlab.NewBudget(0)
+	err := interrupted(context.Background())
+	if !errors.Is(err, gitlab.ErrBudgetExhausted) {
+		t.Fatalf("interrupted() with budget used up = %v, want ErrBudgetExhausted", err)
+	}
+	if code := exitCode(err); code != exitBudgetExhausted {
+		t.Errorf("exitCode() = %d, want %d", code, exitBudgetExhausted)
+	}
+}
+
+func TestFetchManyRefs_BudgetExhausted(t *testing.T) {
+	defer func() { budget = nil }()
+	budget = gitlab.NewBudget(0)
+
+	api := newMockAPI()
+	api.refs["group/a"] = []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaa"}}
+	api.projects["group/a"] = &gitlab.ProjectInfo{Path: "group/a", TotalMergeRequests: 1}
+
+	_, err := fetchManyRefs(context.Background(), api, nil, []string{"group/a"}, "", t.TempDir(), 1, outputFormat{})
+	if !errors.Is(err, gitlab.ErrBudgetExhausted) {
+		t.Fatalf("fetchManyRefs() error = %v, want ErrBudgetExhausted", err)
+	}
+	// The cause wins over the partial failure
+	if code := exitCode(err); code != exitBudgetExhausted {
+		t.Errorf("exitCode() = %d, want %d", code, exitBudgetExhausted)
+	}
+}
@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/auth"
+	"github.com/amenocal/gh-gl-create-refs/pkg/github"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/ratelimit"
+	"github.com/amenocal/gh-gl-create-refs/pkg/redact"
+	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
+)
+
+// oauthScopes are requested by the device authorization flow; api covers both reading
+// merge requests and creating branches
+var oauthScopes = []string{"api"}
+
+// projectCacheFilename is the file of the project lookup cache in the cache directory
+const projectCacheFilename = "projects.json"
+
+// newGitLabClient creates a GitLab client for baseURL configured from the command's
+// token flags and the global flags. With --auth-type oauth and no token, an OAuth
+// token is obtained through the device authorization flow when --oauth-client-id is set.
+func newGitLabClient(cmd *cobra.Command, baseURL string) (*gitlab.Client, error) {
+	ctx := cmd.Context()
+
+	flags := rootCmd.PersistentFlags()
+	authType, err := gitlab.ParseAuthType(flags.Lookup("auth-type").Value.String())
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := resolveToken(cmd, baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := clientOptions(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	options = append(options, gitlab.WithAuthType(authType))
+
+	tlsConfig, err := tlsConfigFromFlags()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		options = append(options, gitlab.WithTLSConfig(tlsConfig))
+		// The device authorization flow talks to the same instance
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, gitlab.NewHTTPClient(tlsConfig))
+	}
+
+	if recorder != nil {
+		var base http.RoundTripper
+		if tlsConfig != nil {
+			base = gitlab.NewHTTPClient(tlsConfig).Transport
+		}
+		options = append(options, gitlab.WithTransport(recorder.Transport(base)))
+	}
+
+	offline, _ := flags.GetBool("offline")
+	if offline {
+		cacheDir := flags.Lookup("cache-dir").Value.String()
+		if cacheDir == "" {
+			return nil, fmt.Errorf("--offline requires --cache-dir, the response cache recorded by an earlier run")
+		}
+		cache, err := gitlab.OpenOfflineCache(cacheDir)
+		if err != nil {
+			return nil, err
+		}
+		// Replayed responses don't count against GitLab's rate limit
+		options = append(options, gitlab.WithCache(cache), gitlab.WithRateLimiter(gitlab.NewTokenBucket(0, 0, logger)))
+	} else if cacheDir := flags.Lookup("cache-dir").Value.String(); cacheDir != "" {
+		ttl, _ := flags.GetDuration("cache-ttl")
+		cache, err := gitlab.NewDiskCache(cacheDir, ttl)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, gitlab.WithCache(cache))
+	}
+
+	if ttl, _ := flags.GetDuration("project-cache-ttl"); ttl > 0 {
+		if cache, err := openProjectCache(flags.Lookup("cache-dir").Value.String(), ttl); err != nil {
+			// Only repeated lookups are lost
+			logger.Warn("project cache disabled", "error", err)
+		} else {
+			options = append(options, gitlab.WithProjectCache(cache))
+		}
+	}
+
+	if authType == gitlab.AuthTypeOAuth && token == "" {
+		if offline {
+			return nil, fmt.Errorf("--offline requires the --token the responses were recorded with")
+		}
+		clientID := flags.Lookup("oauth-client-id").Value.String()
+		if clientID == "" {
+			return nil, fmt.Errorf("--auth-type oauth requires --token or --oauth-client-id")
+		}
+
+		oauthToken, err := gitlab.DeviceAuthorization(ctx, baseURL, clientID, oauthScopes, promptDeviceCode)
+		if err != nil {
+			return nil, err
+		}
+		token = oauthToken.AccessToken
+		redact.Add(oauthToken.RefreshToken)
+	}
+	redact.Add(token)
+
+	return gitlab.NewClient(token, baseURL, options...)
+}
+
+// openProjectCache opens the project lookup cache: projects.json in cacheDir when --cache-dir
+// is set, so removing it clears both caches, and in the user's cache directory otherwise
+func openProjectCache(cacheDir string, ttl time.Duration) (*gitlab.ProjectCache, error) {
+	if cacheDir == "" {
+		userDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		cacheDir = filepath.Join(userDir, "gh-gl-create-refs")
+	}
+	return gitlab.NewProjectCache(filepath.Join(cacheDir, projectCacheFilename), ttl)
+}
+
+// newGitHubClient creates a GitHub client for baseURL, or for the host gh is working with
+// (GH_HOST, the host gh is logged in to, or github.com) when baseURL is empty,
+// authenticated with the credentials gh uses for that host
+func newGitHubClient(baseURL string) (*github.Client, error) {
+	host, endpoint, err := github.ParseBaseURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if baseURL == "" {
+		host = auth.GitHubHost()
+		endpoint = github.GraphQLEndpoint(host)
+	}
+
+	token, source, err := auth.GitHubToken(host)
+	if err != nil {
+		return nil, err
+	}
+	redact.Add(token)
+	logger.Info("using GitHub credentials", "host", host, "source", source)
+
+	userAgent := rootCmd.PersistentFlags().Lookup("user-agent").Value.String()
+	// GitHub's quota is enforced from its headers; --rps and --burst only apply to GitLab
+	limiter := rateLimits.Bucket(host, 0, 1)
+	options := []github.Option{github.WithEndpoint(endpoint), github.WithUserAgent(userAgent), github.WithRateLimiter(limiter)}
+	if debugHTTP {
+		options = append(options, github.WithTrace(logger))
+	}
+	return github.NewClient(token, host, options...)
+}
+
+// tlsConfigFromFlags builds TLS settings from --ca-cert and --insecure-skip-verify,
+// or returns nil when neither is set
+func tlsConfigFromFlags() (*tls.Config, error) {
+	flags := rootCmd.PersistentFlags()
+	caCert := flags.Lookup("ca-cert").Value.String()
+	insecure, _ := flags.GetBool("insecure-skip-verify")
+
+	if caCert == "" && !insecure {
+		return nil, nil
+	}
+	if insecure {
+		logger.Warn("TLS certificate verification is disabled; only use --insecure-skip-verify for testing")
+	}
+
+	return gitlab.NewTLSConfig(caCert, insecure)
+}
+
+// resolveToken finds the token for baseURL, in order of precedence: the --token flag,
+// the --token-file flag, the GITLAB_TOKEN environment variable and the OS keyring.
+// It returns an empty string when no token is configured.
+func resolveToken(cmd *cobra.Command, baseURL string) (string, error) {
+	if token := cmd.Flag("token").Value.String(); token != "" {
+		return token, nil
+	}
+
+	if tokenFile := cmd.Flag("token-file").Value.String(); tokenFile != "" {
+		return auth.ReadTokenFile(tokenFile)
+	}
+
+	if token := os.Getenv("GITLAB_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	return lookupStoredToken(baseURL), nil
+}
+
+// lookupStoredToken returns the token stored for baseURL in the OS keyring, or "" when
+// there is none. A keyring that can't be read, as on machines without a keyring service, is
+// taken as holding no token.
+func lookupStoredToken(baseURL string) string {
+	token, err := auth.LookupToken(baseURL)
+	if err != nil {
+		logger.Debug("could not read the OS keyring", "error", err)
+		return ""
+	}
+	return token
+}
+
+// addTokenFlags registers the flags resolveToken reads
+func addTokenFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("token", "t", "", "GitLab access token (can also use GITLAB_TOKEN environment variable)")
+	cmd.Flags().String("token-file", "", "Read the GitLab access token from this file instead of the command line")
+	cmd.MarkFlagsMutuallyExclusive("token", "token-file")
+}
+
+// promptDeviceCode asks the user to approve a device authorization request
+func promptDeviceCode(verificationURI, userCode string) {
+	fmt.Fprintf(os.Stderr, "To authorize gh-gl-create-refs, open %s and enter the code %s\n", verificationURI, userCode)
+}
+
+// debugHTTP is set by --debug to trace every API request
+var debugHTTP bool
+
+// rateLimits holds the rate limiter of every API host, so that all clients of an instance
+// share its quota; it is recreated with the configured logger before any subcommand runs
+var rateLimits = ratelimit.NewHosts(logger)
+
+// clientOptions builds the client options shared by every subcommand for the GitLab
+// instance at baseURL
+func clientOptions(baseURL string) ([]gitlab.Option, error) {
+	flags := rootCmd.PersistentFlags()
+	rps, _ := flags.GetFloat64("rps")
+	burst, _ := flags.GetInt("burst")
+	userAgent := flags.Lookup("user-agent").Value.String()
+
+	host, err := auth.Host(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []gitlab.Option{
+		gitlab.WithUserAgent(userAgent),
+		gitlab.WithLogger(logger),
+		gitlab.WithRateLimiter(rateLimits.Bucket(host, rps, burst)),
+		gitlab.WithMetrics(metrics),
+	}
+	if budget != nil {
+		options = append(options, gitlab.WithBudget(budget))
+	}
+	if sudo := flags.Lookup("sudo").Value.String(); sudo != "" {
+		options = append(options, gitlab.WithSudo(sudo))
+	}
+	if debugHTTP {
+		options = append(options, gitlab.WithTrace(logger))
+	}
+	return options, nil
+}
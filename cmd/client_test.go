@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/auth"
+	"github.com/spf13/cobra"
+	"github.com/zalando/go-keyring"
+)
+
+func TestResolveToken(t *testing.T) {
+	keyring.MockInit()
+	if err := auth.StoreToken("", "keyring-token"); err != nil {
+		t.Fatal(err)
+	}
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("file-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		flags    map[string]string
+		env      string
+		baseURL  string
+		expected string
+	}{
+		{"flag wins", map[string]string{"token": "flag-token"}, "env-token", "", "flag-token"},
+		{"token file", map[string]string{"token-file": tokenFile}, "env-token", "", "file-token"},
+		{"environment", nil, "env-token", "", "env-token"},
+		{"keyring", nil, "", "", "keyring-token"},
+		{"keyring for other host", nil, "", "https://gitlab.example.com", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITLAB_TOKEN", tt.env)
+
+			cmd := &cobra.Command{}
+			addTokenFlags(cmd)
+			for name, value := range tt.flags {
+				if err := cmd.Flags().Set(name, value); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := resolveToken(cmd, tt.baseURL)
+			if err != nil {
+				t.Fatalf("resolveToken() unexpected error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("resolveToken() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewGitHubClient_Host(t *testing.T) {
+	// Only an enterprise token is available, and gh cannot be run
+	t.Setenv("GH_TOKEN", "")
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "enterprise-token")
+	t.Setenv("GH_PATH", filepath.Join(t.TempDir(), "missing-gh"))
+	t.Setenv("GH_CONFIG_DIR", t.TempDir())
+
+	tests := []struct {
+		name        string
+		baseURL     string
+		ghHost      string
+		expectError bool
+	}{
+		{"github base URL", "https://ghes.example.com", "", false},
+		{"GH_HOST", "", "ghes.example.com", false},
+		{"github.com needs GH_TOKEN", "", "", true},
+		{"base URL wins over GH_HOST", "https://github.com", "ghes.example.com", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GH_HOST", tt.ghHost)
+
+			_, err := newGitHubClient(tt.baseURL)
+			if tt.expectError && err == nil {
+				t.Error("newGitHubClient() expected an error")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("newGitHubClient() unexpected error = %v", err)
+			}
+		})
+	}
+}
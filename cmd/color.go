@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"os"
+)
+
+// ANSI escape sequences for the status colors
+const (
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
+)
+
+// colorEnabled reports whether status output is colored; it is set from --no-color,
+// NO_COLOR and whether stdout is a terminal before any subcommand runs
+var colorEnabled bool
+
+// useColor decides whether to color output written to out. Color is off with --no-color,
+// when NO_COLOR is set to any value (https://no-color.org), for TERM=dumb, and when out is
+// not a terminal, such as a pipe, a file or a CI log.
+func useColor(noColor bool, out *os.File) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(out)
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe, a file or a CI log
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// paint wraps s in an ANSI color when color is enabled
+func paint(color, s string) string {
+	if !colorEnabled {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+// green marks created, applied and valid items
+func green(s string) string {
+	return paint(ansiGreen, s)
+}
+
+// yellow marks skipped and unchanged items
+func yellow(s string) string {
+	return paint(ansiYellow, s)
+}
+
+// red marks failed items
+func red(s string) string {
+	return paint(ansiRed, s)
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUseColor(t *testing.T) {
+	// A regular file is never a terminal
+	file, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm-256color")
+	if useColor(false, file) {
+		t.Error("useColor() = true for a file")
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip("no terminal available")
+	}
+	defer tty.Close()
+
+	tests := []struct {
+		name     string
+		noColor  bool
+		env      string
+		term     string
+		expected bool
+	}{
+		{"terminal", false, "", "xterm-256color", true},
+		{"--no-color", true, "", "xterm-256color", false},
+		{"NO_COLOR", false, "1", "xterm-256color", false},
+		{"dumb terminal", false, "", "dumb", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.env)
+			t.Setenv("TERM", tt.term)
+			if got := useColor(tt.noColor, tty); got != tt.expected {
+				t.Errorf("useColor() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPaint(t *testing.T) {
+	defer func() { colorEnabled = false }()
+
+	colorEnabled = false
+	if got := green("created"); got != "created" {
+		t.Errorf("green() without color = %q", got)
+	}
+
+	colorEnabled = true
+	if got := red("failed"); got != "\x1b[31mfailed\x1b[0m" {
+		t.Errorf("red() = %q", got)
+	}
+	if got := yellow("skipped"); got != "\x1b[33mskipped\x1b[0m" {
+		t.Errorf("yellow() = %q", got)
+	}
+}
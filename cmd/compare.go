@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+	"github.com/amenocal/gh-gl-create-refs/pkg/github"
+	"github.com/amenocal/gh-gl-create-refs/pkg/migrate"
+	"github.com/spf13/cobra"
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Compare merge request references with the pull requests of a GitHub repository",
+	Long: `Compare the head commits of a CSV file of merge request references with those of the
+pull requests of the same numbers in a GitHub repository, and list every difference.
+
+The pull requests are listed 100 per GraphQL request, so large repositories are compared
+in a few hundred requests instead of one per pull request.
+
+Examples:
+  gh gl-create-refs compare --input group-project.csv --github-target org/project
+  gh gl-create-refs compare -i refs.csv --github-target org/project --github-base-url https://ghes.example.com`,
+	Args: cobra.NoArgs,
+	RunE: runCompare,
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().StringP("input", "i", "", "Input CSV file path, or - to read standard input (required)")
+	compareCmd.Flags().String("github-target", "", "GitHub repository (owner/name) whose pull requests are compared (required)")
+	compareCmd.Flags().String("github-base-url", "", "GitHub Enterprise Server URL (default: GH_HOST, or https://github.com)")
+
+	compareCmd.MarkFlagRequired("input")
+	compareCmd.MarkFlagRequired("github-target")
+}
+
+// refDifference is a merge request whose head commit differs from its pull request's
+type refDifference struct {
+	iid           int
+	gitlabSHA     string
+	githubSHA     string
+	githubState   string
+	noPullRequest bool
+}
+
+// refComparison is the outcome of comparing merge request references with pull requests
+type refComparison struct {
+	matched     int
+	differences []refDifference
+	// extra are the pull requests without a merge request of the same number
+	extra []int
+}
+
+func runCompare(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	inputFile := cmd.Flag("input").Value.String()
+	githubTarget := cmd.Flag("github-target").Value.String()
+
+	ghClient, err := newGitHubClient(cmd.Flag("github-base-url").Value.String())
+	if err != nil {
+		return err
+	}
+
+	result, err := compareRefs(ctx, ghClient, inputFile, githubTarget)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range result.differences {
+		if d.noPullRequest {
+			fmt.Printf("%s !%d: no pull request #%d in %s\n", symbols("❌"), d.iid, d.iid, githubTarget)
+			continue
+		}
+		fmt.Printf("%s !%d: head %s in GitLab, but #%d (%s) is at %s\n", symbols("❌"), d.iid, shortCommit(d.gitlabSHA), d.iid, strings.ToLower(d.githubState), red(shortCommit(d.githubSHA)))
+	}
+	if len(result.extra) > 0 {
+		logger.Info("pull requests without a merge request", "repository", githubTarget, "count", len(result.extra))
+	}
+
+	if len(result.differences) > 0 {
+		return withExitCode(exitValidation, fmt.Errorf("%d of %d merge requests differ from the pull requests of %s", len(result.differences), result.matched+len(result.differences), githubTarget))
+	}
+
+	statusf("✅ %s %s (%d merge requests)\n", inputFile, green("matches "+githubTarget), result.matched)
+	return nil
+}
+
+// compareRefs compares the references of inputFile with the pull requests of repository,
+// listed with as few requests as GitHub allows. The references are held by IID while the
+// pull requests are streamed past them.
+func compareRefs(ctx context.Context, client github.API, inputFile, repository string) (refComparison, error) {
+	var result refComparison
+
+	heads := make(map[int]string)
+	for ref, err := range csv.RefsFromFile(inputFile) {
+		if err != nil {
+			return result, fmt.Errorf("failed to read CSV file: %w", err)
+		}
+		heads[ref.IID] = ref.HeadSHA
+	}
+
+	for pr, err := range client.PullRequests(ctx, repository) {
+		if err != nil {
+			return result, err
+		}
+		sha, ok := heads[pr.Number]
+		if !ok {
+			result.extra = append(result.extra, pr.Number)
+			continue
+		}
+		delete(heads, pr.Number)
+
+		if strings.HasPrefix(pr.HeadSHA, sha) {
+			result.matched++
+			continue
+		}
+		result.differences = append(result.differences, refDifference{iid: pr.Number, gitlabSHA: sha, githubSHA: pr.HeadSHA, githubState: pr.State})
+	}
+
+	for iid, sha := range heads {
+		result.differences = append(result.differences, refDifference{iid: iid, gitlabSHA: sha, noPullRequest: true})
+	}
+	slices.SortFunc(result.differences, func(a, b refDifference) int { return a.iid - b.iid })
+	return result, nil
+}
+
+// shortCommit abbreviates sha as branch names do with --naming short-sha
+func shortCommit(sha string) string {
+	return sha[:min(len(sha), migrate.ShortSHALength)]
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/github"
+)
+
+func TestCompareRefs(t *testing.T) {
+	sha := func(c string) string { return strings.Repeat(c, 40) }
+	inputFile := filepath.Join(t.TempDir(), "refs.csv")
+	content := "1," + sha("a") + "\n2," + sha("b") + "\n3," + sha("c") + "\n"
+	if err := os.WriteFile(inputFile, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	gh := newMockGitHubAPI()
+	gh.pulls = []github.PullRequest{
+		{Number: 1, HeadSHA: sha("a"), State: "MERGED"},
+		{Number: 2, HeadSHA: sha("d"), State: "OPEN"},
+		{Number: 4, HeadSHA: sha("e"), State: "OPEN"},
+	}
+
+	result, err := compareRefs(context.Background(), gh, inputFile, "org/project")
+	if err != nil {
+		t.Fatalf("compareRefs() unexpected error = %v", err)
+	}
+	if result.matched != 1 {
+		t.Errorf("matched = %d, want 1", result.matched)
+	}
+	if len(result.differences) != 2 {
+		t.Fatalf("differences = %+v, want merge requests 2 and 3", result.differences)
+	}
+	if d := result.differences[0]; d.iid != 2 || d.githubSHA != sha("d") || d.noPullRequest {
+		t.Errorf("differences[0] = %+v, want !2 at a different head", d)
+	}
+	if d := result.differences[1]; d.iid != 3 || !d.noPullRequest {
+		t.Errorf("differences[1] = %+v, want !3 without a pull request", d)
+	}
+	if len(result.extra) != 1 || result.extra[0] != 4 {
+		t.Errorf("extra = %v, want [4]", result.extra)
+	}
+}
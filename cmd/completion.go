@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// completionMinLength is how much of a project path must be typed before GitLab is
+	// searched, so a bare TAB doesn't list every project on the instance
+	completionMinLength = 3
+	// completionLimit is the most project paths offered at once
+	completionLimit = 20
+	// completionTimeout bounds the search so a slow instance doesn't hang the shell
+	completionTimeout = 5 * time.Second
+)
+
+// addProjectCompletion completes the named flags of cmd, which take GitLab project paths,
+// by searching the instance in --base-url with the configured token
+func addProjectCompletion(cmd *cobra.Command, names ...string) {
+	for _, name := range names {
+		cobra.CheckErr(cmd.RegisterFlagCompletionFunc(name, completeProject))
+	}
+}
+
+// completeProject offers the paths of the projects containing the text typed so far.
+// Nothing is offered when no token is configured, since an OAuth device authorization
+// would otherwise wait for the user in the middle of completing.
+func completeProject(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(toComplete) < completionMinLength || strings.HasPrefix(toComplete, "http") {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	// Completion skips the root's setup, so read the base URL and token from the config file here
+	if err := applyConfig(cmd); err != nil {
+		cobra.CompDebugln(err.Error(), false)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	baseURL := cmd.Flag("base-url").Value.String()
+	token, err := resolveToken(cmd, baseURL)
+	if err != nil || token == "" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	options, err := clientOptions(baseURL)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	authType, _ := gitlab.ParseAuthType(rootCmd.PersistentFlags().Lookup("auth-type").Value.String())
+	client, err := gitlab.NewClient(token, baseURL, append(options, gitlab.WithAuthType(authType))...)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+
+	paths, err := client.SearchProjects(ctx, toComplete, completionLimit)
+	if err != nil {
+		cobra.CompDebugln(err.Error(), false)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return matchingProjects(paths, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// matchingProjects keeps the paths that start with prefix; GitLab also returns projects
+// containing the search text elsewhere in their path, which the shell would discard
+func matchingProjects(paths []string, prefix string) []string {
+	var matches []string
+	for _, path := range paths {
+		if strings.HasPrefix(strings.ToLower(path), strings.ToLower(prefix)) {
+			matches = append(matches, path)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatchingProjects(t *testing.T) {
+	paths := []string{"acme/backend/api", "Acme/Backend/web", "other/acme/backend"}
+
+	got := matchingProjects(paths, "acme/back")
+	expected := []string{"acme/backend/api", "Acme/Backend/web"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("matchingProjects() = %v, want %v", got, expected)
+	}
+}
+
+func TestCompleteProject_ShortInput(t *testing.T) {
+	completions, _ := completeProject(fetchRefCmd, nil, "ac")
+	if len(completions) != 0 {
+		t.Errorf("completeProject() = %v, want no completions before %d characters", completions, completionMinLength)
+	}
+}
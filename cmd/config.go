@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// configConflicts lists, for each configuration key, the flags that replace it: a configured
+// repository is not applied when --group or --manifest is given, for example
+var configConflicts = map[string][]string{
+	"repository": {"group", "manifest"},
+	"output":     {"group", "manifest"},
+	"token-file": {"token"},
+
+	"include-drafts": {"exclude-drafts"},
+	"exclude-drafts": {"include-drafts"},
+}
+
+// configCommands limits configuration keys to the commands whose flag of that name has the
+// same meaning; other keys apply to every command with the flag
+var configCommands = map[string][]string{
+	"output": {"fetch-refs"},
+}
+
+// configPath returns the configuration file given with --config, or the default one
+func configPath(cmd *cobra.Command) string {
+	if path := cmd.Flag("config").Value.String(); path != "" {
+		return path
+	}
+	return config.DefaultPath
+}
+
+// applyConfig sets the command's flags that were not given on the command line from the
+// configuration file, and from its --profile over the file's own values. A missing default
+// configuration file is not an error, unless a profile is selected.
+func applyConfig(cmd *cobra.Command) error {
+	path := configPath(cmd)
+	if cmd.Flag("config").Changed {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	runConfig = cfg
+
+	// init writes profiles, so it may name one the file doesn't have yet
+	flags := cfg.Flags()
+	if profile := flagValue(cmd, "profile"); profile != "" && cmd.Name() != "init" {
+		if flags, err = cfg.ProfileFlags(profile); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		logger.Debug("using config profile", "profile", profile, "config", path)
+	}
+
+	for name, value := range flags {
+		if !configApplies(cmd, name) {
+			continue
+		}
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return fmt.Errorf("invalid %s in config file %s: %w", name, path, err)
+		}
+		logger.Debug("using value from config file", "flag", name, "value", value, "config", path)
+	}
+	return nil
+}
+
+// configApplies reports whether a configuration key should set the command's flag: the
+// command has the flag, it was not given on the command line, and neither were the flags
+// replacing it
+func configApplies(cmd *cobra.Command, name string) bool {
+	flag := cmd.Flags().Lookup(name)
+	if flag == nil || flag.Changed {
+		return false
+	}
+	if commands, ok := configCommands[name]; ok && !slices.Contains(commands, cmd.Name()) {
+		return false
+	}
+	for _, conflict := range configConflicts[name] {
+		if f := cmd.Flags().Lookup(conflict); f != nil && f.Changed {
+			return false
+		}
+	}
+	return true
+}
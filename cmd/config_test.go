@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestApplyConfig(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := "base-url: https://gitlab.example.com\ntoken-file: token.txt\nrepository: group/project\noutput: refs.csv\n"
+	if err := os.WriteFile(configFile, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		command  string
+		args     map[string]string
+		expected map[string]string
+	}{
+		{
+			name:    "fills flags not given",
+			command: "fetch-refs",
+			expected: map[string]string{
+				"base-url":   "https://gitlab.example.com",
+				"token-file": "token.txt",
+				"repository": "group/project",
+				"output":     "refs.csv",
+			},
+		},
+		{
+			name:    "command line takes precedence",
+			command: "fetch-refs",
+			args:    map[string]string{"base-url": "https://gitlab.internal.com", "repository": "other/project"},
+			expected: map[string]string{
+				"base-url":   "https://gitlab.internal.com",
+				"repository": "other/project",
+			},
+		},
+		{
+			name:     "group replaces repository and output",
+			command:  "fetch-refs",
+			args:     map[string]string{"group": "acme"},
+			expected: map[string]string{"repository": "", "output": "", "group": "acme"},
+		},
+		{
+			name:     "token replaces token file",
+			command:  "fetch-refs",
+			args:     map[string]string{"token": "secret"},
+			expected: map[string]string{"token-file": ""},
+		},
+		{
+			name:     "output only applies to fetch-refs",
+			command:  "generate-workflow",
+			expected: map[string]string{"output": "", "repository": "group/project"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: tt.command}
+			cmd.Flags().String("config", "", "")
+			addTokenFlags(cmd)
+			cmd.Flags().StringP("base-url", "b", "", "")
+			cmd.Flags().StringP("repository", "r", "", "")
+			cmd.Flags().StringP("group", "g", "", "")
+			cmd.Flags().StringP("output", "o", "", "")
+
+			args := append([]string{"--config", configFile}, flagArgs(tt.args)...)
+			if err := cmd.ParseFlags(args); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := applyConfig(cmd); err != nil {
+				t.Fatalf("applyConfig() unexpected error = %v", err)
+			}
+
+			for name, want := range tt.expected {
+				if got := cmd.Flag(name).Value.String(); got != want {
+					t.Errorf("--%s = %q, want %q", name, got, want)
+				}
+			}
+			if err := cmd.ValidateFlagGroups(); err != nil {
+				t.Errorf("ValidateFlagGroups() unexpected error = %v", err)
+			}
+		})
+	}
+}
+
+func TestApplyConfig_MissingFile(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("config", "", "")
+	if err := cmd.ParseFlags([]string{"--config", filepath.Join(t.TempDir(), "missing.yaml")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := applyConfig(cmd); err == nil {
+		t.Error("applyConfig() expected error for a missing --config file, got nil")
+	}
+}
+
+func TestApplyConfig_Profile(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := "base-url: https://gitlab.com\nrepository: group/project\nprofiles:\n  prod:\n    base-url: https://gitlab.example.com\n    token-file: prod-token.txt\n"
+	if err := os.WriteFile(configFile, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	newCommand := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{Use: "fetch-refs"}
+		cmd.Flags().String("config", "", "")
+		cmd.Flags().String("profile", "", "")
+		addTokenFlags(cmd)
+		cmd.Flags().StringP("base-url", "b", "", "")
+		cmd.Flags().StringP("repository", "r", "", "")
+		if err := cmd.ParseFlags(append([]string{"--config", configFile}, args...)); err != nil {
+			t.Fatal(err)
+		}
+		return cmd
+	}
+
+	cmd := newCommand("--profile", "prod")
+	if err := applyConfig(cmd); err != nil {
+		t.Fatalf("applyConfig() unexpected error = %v", err)
+	}
+	expected := map[string]string{"base-url": "https://gitlab.example.com", "token-file": "prod-token.txt", "repository": "group/project"}
+	for name, want := range expected {
+		if got := cmd.Flag(name).Value.String(); got != want {
+			t.Errorf("--%s = %q, want %q", name, got, want)
+		}
+	}
+
+	// The command line still takes precedence over the profile
+	cmd = newCommand("--profile", "prod", "--base-url", "https://gitlab.internal.com")
+	if err := applyConfig(cmd); err != nil {
+		t.Fatalf("applyConfig() unexpected error = %v", err)
+	}
+	if got := cmd.Flag("base-url").Value.String(); got != "https://gitlab.internal.com" {
+		t.Errorf("--base-url = %q, want the command line's", got)
+	}
+
+	if err := applyConfig(newCommand("--profile", "staging")); err == nil || !strings.Contains(err.Error(), `profile "staging" not found`) {
+		t.Errorf("applyConfig() error = %v, want an unknown profile error", err)
+	}
+}
+
+// flagArgs converts flag values to command line arguments
+func flagArgs(flags map[string]string) []string {
+	var args []string
+	for name, value := range flags {
+		args = append(args, "--"+name, value)
+	}
+	return args
+}
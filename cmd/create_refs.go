@@ -1,11 +1,22 @@
 package cmd
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"iter"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+	"github.com/amenocal/gh-gl-create-refs/pkg/github"
 	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/hook"
+	"github.com/amenocal/gh-gl-create-refs/pkg/mapping"
+	"github.com/amenocal/gh-gl-create-refs/pkg/migrate"
 	"github.com/spf13/cobra"
 )
 
@@ -15,89 +26,415 @@ var createRefsCmd = &cobra.Command{
 	Long: `Create GitLab branches based on merge request references from a CSV file or by fetching them in real-time.
 
 This command reads merge request references (either from a CSV file generated by fetch-refs or by fetching directly) 
-and creates branches in the specified repository using the naming pattern 'migration-pr-<PRNumber>'.
+and creates branches in the specified repository using the naming pattern 'migration-pr-<PRNumber>',
+or another preset chosen with --naming.
 If no target repository is specified, branches will be created in the source repository.
 
 The CSV file should contain two columns:
 1. Merge request number (IID)
 2. Head SHA from diff_refs
 
+Combined files written by fetch-refs --combined create the branches of each project in turn.
+Use --github-target or --mapping to create them in GitHub instead, and --update-existing to
+repeat a run. See the flags below and the README for every option.
+
 Examples:
   gh gl-create-refs create-refs --input group-project.csv --repository group/project
   gh gl-create-refs create-refs -i refs.csv -r group/project --target target-group/target-project --token your_token
   gh gl-create-refs create-refs --repository source-group/source-project --fetch
   gh gl-create-refs create-refs -r source/repo --target target/repo --fetch --base-url https://gitlab.example.com
-  gh gl-create-refs create-refs --repository source/repo --fetch --mock`,
+  gh gl-create-refs create-refs --repository source/repo --fetch --mock
+  gh gl-create-refs create-refs --input combined-refs.csv --mapping repos.yaml
+  gh gl-create-refs create-refs -i refs.csv -r group/project --github-target octo-org/project`,
 	Args: cobra.NoArgs,
 	RunE: runCreateRefs,
 }
 
-// No global variables needed - using local variables with flag access
+// defaultBranchPrefix is the prefix of created branches when --branch-prefix is not set
+const defaultBranchPrefix = "migration-pr-"
+
+// validateBranchPrefix checks that branch names made from prefix are valid Git references
+func validateBranchPrefix(prefix string) error {
+	switch {
+	case prefix == "":
+		return fmt.Errorf("invalid --branch-prefix: must not be empty")
+	case strings.HasPrefix(prefix, "-"), strings.HasPrefix(prefix, "/"), strings.HasPrefix(prefix, "."):
+		return fmt.Errorf("invalid --branch-prefix %q: must not start with '-', '/' or '.'", prefix)
+	case strings.Contains(prefix, ".."), strings.Contains(prefix, "//"), strings.Contains(prefix, "@{"),
+		strings.Contains(prefix, "/."), strings.HasSuffix(prefix, ".lock/"):
+		return fmt.Errorf("invalid --branch-prefix %q: not a valid Git reference name", prefix)
+	case strings.ContainsAny(prefix, " ~^:?*[\\\x7f"):
+		return fmt.Errorf("invalid --branch-prefix %q: must not contain spaces or any of ~^:?*[\\", prefix)
+	}
+	for _, r := range prefix {
+		if r < 0x20 {
+			return fmt.Errorf("invalid --branch-prefix %q: must not contain control characters", prefix)
+		}
+	}
+	return nil
+}
 
 // generateBranchName creates a branch name following the migration pattern
 func generateBranchName(prNumber int) string {
-	return fmt.Sprintf("migration-pr-%d", prNumber)
+	return fmt.Sprintf("%s%d", defaultBranchPrefix, prNumber)
+}
+
+// refNaming names the branch of each merge request reference following --naming and
+// --branch-prefix. The zero refNaming is the default, pr-number with defaultBranchPrefix.
+type refNaming struct {
+	prefix string
+	preset migrate.Naming
+}
+
+// namingFlags reads and checks --naming and --branch-prefix
+func namingFlags(cmd *cobra.Command) (refNaming, error) {
+	prefix := cmd.Flag("branch-prefix").Value.String()
+	if err := validateBranchPrefix(prefix); err != nil {
+		return refNaming{}, err
+	}
+	preset, err := migrate.ParseNaming(cmd.Flag("naming").Value.String())
+	if err != nil {
+		return refNaming{}, fmt.Errorf("invalid --naming: %w", err)
+	}
+	return refNaming{prefix: prefix, preset: preset}, nil
+}
+
+// branchPrefix is the prefix shared by every branch named by n
+func (n refNaming) branchPrefix() string {
+	return cmp.Or(n.prefix, defaultBranchPrefix)
+}
+
+// branchName returns the branch created for ref
+func (n refNaming) branchName(ref gitlab.MergeRequestRef) string {
+	return n.preset.BranchName(n.branchPrefix(), ref)
+}
+
+// validate checks that the references of a run can be named: CSV files don't record the
+// source branch
+func (n refNaming) validate(fetch bool) error {
+	if n.preset == migrate.NamingSourceBranch && !fetch {
+		return fmt.Errorf("--naming %s needs --fetch: CSV files don't record the source branch of merge requests", migrate.NamingSourceBranch)
+	}
+	return nil
+}
+
+// createOptions are the settings of a create-refs run that the functions creating its
+// branches need, read from the flags once by runCreateRefs
+type createOptions struct {
+	naming refNaming
+	// updateExisting leaves existing branches at the head commit of their merge request as
+	// they are and moves the others to it, instead of failing
+	updateExisting bool
+	// hooks are left empty in mock mode, which creates nothing to hook into
+	hooks createHooks
+	// fetch and inputFile tell the summary where the references came from
+	fetch     bool
+	inputFile string
+	mock      bool
 }
 
 func init() {
 	rootCmd.AddCommand(createRefsCmd)
 
-	createRefsCmd.Flags().StringP("input", "i", "", "Input CSV file path (required unless --fetch is used)")
-	createRefsCmd.Flags().StringP("repository", "r", "", "Source GitLab repository path (required)")
+	createRefsCmd.Flags().StringP("input", "i", "", "Input CSV file path, or - to read standard input (required unless --fetch is used)")
+	createRefsCmd.Flags().StringArrayP("repository", "r", nil, "Source GitLab repository path (required unless --input is a combined file, which it then filters); repeat for several")
 	createRefsCmd.Flags().String("target", "", "Target GitLab repository path where branches will be created (optional, defaults to repository)")
-	createRefsCmd.Flags().StringP("token", "t", "", "GitLab access token (can also use GITLAB_TOKEN environment variable)")
+	createRefsCmd.Flags().String("github-target", "", "Create the branches in this GitHub repository (owner/name) instead, using gh's credentials")
+	createRefsCmd.Flags().String("mapping", "", "CSV or YAML file mapping GitLab projects to GitHub repositories: create each project's branches in its GitHub repository")
+	createRefsCmd.Flags().String("github-base-url", "", "GitHub Enterprise Server URL for --github-target, --mapping or --summary-issue (default: GH_HOST, or https://github.com)")
+	createRefsCmd.Flags().Bool("open-issue", false, "Open a GitHub issue listing the branches that could not be created, in the target repository")
+	createRefsCmd.Flags().Bool("github-tags", false, "With --github-target or --mapping, create lightweight tags named like the branches instead of branches")
+	createRefsCmd.Flags().String("issue-repository", "", "With --open-issue, open the issue in this GitHub repository (owner/name) instead, e.g. a tracking repository")
+	addSummaryIssueFlag(createRefsCmd)
+	createRefsCmd.Flags().Int("batch-size", github.DefaultBatchSize, "Branches created per GraphQL request with --github-target or --mapping")
+	createRefsCmd.Flags().Duration("batch-pause", 0, "Pause this long after every --pause-every branches, e.g. 30s, to spread out writes (default: no pause)")
+	createRefsCmd.Flags().Int("pause-every", defaultPauseEvery, "Branches created between pauses with --batch-pause")
+	addTokenFlags(createRefsCmd)
 	createRefsCmd.Flags().StringP("base-url", "b", "", "GitLab base URL (default: https://gitlab.com)")
 	createRefsCmd.Flags().Bool("fetch", false, "Fetch merge requests in real-time instead of using CSV file")
+	createRefsCmd.Flags().String("queue-dir", "", "With --fetch, keep the fetched references queued in this directory, so a run that stops is resumed by the next one without fetching again")
 	createRefsCmd.Flags().Bool("mock", false, "Mock mode: simulate branch creation without actually creating branches")
-
-	// Mark the repository flag as required
-	createRefsCmd.MarkFlagRequired("repository")
+	createRefsCmd.Flags().Bool("verify-checksum", false, "Verify the input file against its .sha256 checksum file before reading it")
+	createRefsCmd.Flags().Bool("update-existing", false, "Leave existing branches already at the merge request's head commit as they are and move the others to it, instead of failing")
+	addHookFlags(createRefsCmd)
+	addAccessCheckFlag(createRefsCmd)
+	createRefsCmd.MarkFlagsMutuallyExclusive("fetch", "verify-checksum")
+	createRefsCmd.MarkFlagsMutuallyExclusive("target", "github-target", "mapping")
+	addProjectCompletion(createRefsCmd, "repository", "target")
 }
 
 func runCreateRefs(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	startedAt := time.Now()
+
 	// Get parameters from flags
 	inputFile := cmd.Flag("input").Value.String()
-	repository := cmd.Flag("repository").Value.String()
+	repositories, _ := cmd.Flags().GetStringArray("repository")
 	targetRepository := cmd.Flag("target").Value.String()
-	token := cmd.Flag("token").Value.String()
 	baseURL := cmd.Flag("base-url").Value.String()
 	fetch, _ := cmd.Flags().GetBool("fetch")
 	mock, _ := cmd.Flags().GetBool("mock")
+	verifyChecksum, _ := cmd.Flags().GetBool("verify-checksum")
+	githubTarget := cmd.Flag("github-target").Value.String()
+	githubBaseURL := cmd.Flag("github-base-url").Value.String()
+	mappingFile := cmd.Flag("mapping").Value.String()
+	openIssue, _ := cmd.Flags().GetBool("open-issue")
+	githubTags, _ := cmd.Flags().GetBool("github-tags")
+	issueRepository := cmd.Flag("issue-repository").Value.String()
+	batchSize, _ := cmd.Flags().GetInt("batch-size")
+	batchPause, _ := cmd.Flags().GetDuration("batch-pause")
+	pauseEvery, _ := cmd.Flags().GetInt("pause-every")
+	queueDir := cmd.Flag("queue-dir").Value.String()
+	skipAccessCheck, _ := cmd.Flags().GetBool("skip-access-check")
+	updateExisting, _ := cmd.Flags().GetBool("update-existing")
+
+	summaryIssue, err := summaryIssueFlag(cmd)
+	if err != nil {
+		return err
+	}
 
 	// Validate input parameters
-	if err := validateCreateRefsFlags(repository, fetch, inputFile); err != nil {
+	if err := validateCreateRefsFlags(cmp.Or(repositories...), fetch, inputFile); err != nil {
+		return err
+	}
+	if len(repositories) > 1 && (targetRepository != "" || githubTarget != "") {
+		return fmt.Errorf("--target and --github-target cannot be used with several --repository flags; use --mapping")
+	}
+	if githubBaseURL != "" && githubTarget == "" && mappingFile == "" && summaryIssue == nil {
+		return fmt.Errorf("--github-base-url can only be used with --github-target, --mapping or --summary-issue")
+	}
+	if openIssue && githubTarget == "" && mappingFile == "" {
+		return fmt.Errorf("--open-issue can only be used with --github-target or --mapping")
+	}
+	if githubTags && githubTarget == "" && mappingFile == "" {
+		return fmt.Errorf("--github-tags can only be used with --github-target or --mapping")
+	}
+	if issueRepository != "" && !openIssue {
+		return fmt.Errorf("--issue-repository requires --open-issue")
+	}
+	if batchSize < 1 {
+		return fmt.Errorf("--batch-size must be at least 1")
+	}
+	if batchPause < 0 {
+		return fmt.Errorf("--batch-pause must not be negative")
+	}
+	if pauseEvery < 1 {
+		return fmt.Errorf("--pause-every must be at least 1")
+	}
+	if queueDir != "" && (!fetch || mock) {
+		return fmt.Errorf("--queue-dir can only be used with --fetch, and not in mock mode")
+	}
+	naming, err := namingFlags(cmd)
+	if err != nil {
 		return err
 	}
+	if err := naming.validate(fetch); err != nil {
+		return err
+	}
+
+	hooks := hookFlags(cmd)
+	if mock && hooks != (createHooks{}) {
+		logger.Info("mock mode: hooks are not run")
+		hooks = createHooks{}
+	}
+	opts := createOptions{naming: naming, updateExisting: updateExisting, hooks: hooks, fetch: fetch, inputFile: inputFile, mock: mock}
+
+	// Catch truncated or altered copies before anything is created
+	if verifyChecksum {
+		if inputFile == csv.Stdin {
+			return fmt.Errorf("--verify-checksum cannot be used when reading standard input")
+		}
+		if err := csv.VerifyChecksum(inputFile); err != nil {
+			return err
+		}
+		logger.Info("verified input checksum", "file", inputFile, "checksum", csv.ChecksumFilename(inputFile))
+	}
 
 	// Create GitLab client from flags and environment
-	client, err := gitlab.NewClient(token, baseURL)
+	client, err := newGitLabClient(cmd, baseURL)
 	if err != nil {
 		return err
 	}
 
 	// Get merge request references
-	refs, err := getMergeRequestRefs(client, fetch, inputFile, repository, baseURL)
-	if err != nil {
+	var projects []projectRefs
+	if fetch {
+		// Every repository is fetched before any branch is created
+		for _, repository := range repositories {
+			path, err := refQueuePath(queueDir, repository)
+			if err != nil {
+				return err
+			}
+			if queueDir != "" {
+				l, err := lockOutput(path)
+				if err != nil {
+					return err
+				}
+				defer releaseLock(l)
+			}
+			q, err := queueMergeRequestRefs(ctx, client, path, repository, baseURL, queueDir != "")
+			if err != nil {
+				return err
+			}
+			defer closeRefQueue(q, queueDir != "")
+
+			p := projectRefs{project: repository, refs: q.Drain(), total: q.Len()}
+			// GitHub branches are created in batches, so references only leave the queue
+			// once their batch is
+			if githubTarget != "" || mappingFile != "" {
+				p.refs, p.ack = q.Items(), q.Ack
+			}
+			projects = append(projects, p)
+		}
+	} else {
+		projects, err = streamProjectRefs(inputFile, repositories...)
+		if err != nil {
+			return err
+		}
+	}
+
+	total := 0
+	for _, p := range projects {
+		total += p.total
+	}
+
+	if len(projects) > 1 && (targetRepository != "" || githubTarget != "") {
+		return fmt.Errorf("--target and --github-target cannot be used with input covering %d projects; select one with --repository or use --mapping", len(projects))
+	}
+
+	// Every project must have a target before anything is created
+	var targets map[string]string
+	if githubTarget != "" || mappingFile != "" {
+		targets, err = githubTargets(projects, githubTarget, mappingFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Check the token may create branches in every GitLab target before creating any. The
+	// branch names are not known until the references are read, but all of them start with
+	// the prefix, so no protected branch may match a name starting with it
+	if targets == nil && !mock && !skipAccessCheck {
+		for _, target := range gitlabTargets(projects, targetRepository) {
+			_, targetProjectPath, err := gitlab.ParseRepoPath(target)
+			if err != nil {
+				return fmt.Errorf("failed to parse target repository path: %w", err)
+			}
+			if err := checkCreatePrefixAccess(ctx, client, targetProjectPath, naming.branchPrefix()); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Mock mode only prints the branches and the summary, so it needs no GitHub credentials
+	var ghClient github.API
+	if !mock && (targets != nil || summaryIssue != nil) {
+		ghClient, err = newGitHubClient(githubBaseURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	createBranches := func() (branchCounts, error) {
+		if total == 0 {
+			logger.Info("no merge request references found to process")
+			return branchCounts{}, nil
+		}
+
+		// Mock mode writes nothing, so there is nothing to spread out
+		if !mock {
+			for i := range projects {
+				projects[i].refs = paced(ctx, projects[i].refs, pauseEvery, batchPause)
+			}
+		}
+
+		if targets != nil {
+			ghOpts := githubOptions{createOptions: opts, batchSize: batchSize, openIssue: openIssue, issueRepository: issueRepository, tags: githubTags}
+			return createBranchesInProjects(projects, func(p projectRefs) (branchCounts, error) {
+				ghOpts.ack = p.ack
+				return createBranchesOnGitHub(ctx, ghClient, p.refs, p.total, p.project, targets[p.project], ghOpts)
+			})
+		}
+
+		if len(projects) > 1 {
+			return createBranchesInProjects(projects, func(p projectRefs) (branchCounts, error) {
+				return createBranchesInRepo(ctx, client, p.refs, p.total, p.project, opts)
+			})
+		}
+
+		// Determine target repository
+		targetRepo := targetRepository
+		if targetRepo == "" {
+			targetRepo = projects[0].project
+		}
+
+		// Create branches in target repository
+		return createBranchesInRepo(ctx, client, projects[0].refs, projects[0].total, targetRepo, opts)
+	}
+
+	if err := hooks.preCreate(ctx, preCreateEvent(projects, total, targets, targetRepository)); err != nil {
 		return err
 	}
 
-	if len(refs) == 0 {
-		fmt.Printf("No merge request references found to process\n")
-		return nil
+	counts, err := createBranches()
+	if err == nil && counts.failed > 0 {
+		err = withExitCode(exitPartialFailure, fmt.Errorf("%d of %d branches failed", counts.failed, counts.total))
+		if counts.existing > 0 {
+			hint := branchExistsHint
+			if targets != nil {
+				hint = refExistsHint
+			}
+			err = withHint(err, fmt.Sprintf("%d of the branches already exist. %s", counts.existing, hint))
+		}
+	}
+	if hookErr := hooks.postCreate(counts, err); hookErr != nil {
+		err = errors.Join(err, hookErr)
 	}
 
-	// Determine target repository
-	targetRepo := targetRepository
-	if targetRepo == "" {
-		targetRepo = repository
+	report := runReport{
+		command:   "create-refs",
+		startedAt: startedAt,
+		duration:  time.Since(startedAt),
+		counts: []reportCount{
+			{"Merge requests processed", counts.total},
+			{"Branches created", counts.created},
+			{"Branches failed", counts.failed},
+		},
+		err: err,
+	}
+	if counts.updated > 0 || counts.unchanged > 0 {
+		report.counts = append(report.counts, reportCount{"Branches updated", counts.updated}, reportCount{"Branches unchanged", counts.unchanged})
+	}
+	if counts.skipped > 0 {
+		report.counts = append(report.counts, reportCount{"Skipped, commit not on GitHub", counts.skipped})
+	}
+	if !fetch && inputFile != csv.Stdin {
+		report.file = inputFile
 	}
 
-	// Create branches in target repository
-	return createBranchesInRepo(client, refs, targetRepo, fetch, inputFile, mock)
+	notifyErr := notifyRunSummary(ctx, report, mock)
+	if summaryIssue != nil {
+		err = postRunSummary(ctx, ghClient, *summaryIssue, report, mock)
+	}
+	if err == nil {
+		err = notifyErr
+	}
+	return err
+}
+
+// preCreateEvent lists the projects of a run and where their branches are created, for the
+// pre-create hook
+func preCreateEvent(projects []projectRefs, total int, targets map[string]string, targetRepository string) hook.PreCreateEvent {
+	event := hook.PreCreateEvent{Total: total}
+	for _, p := range projects {
+		target := cmp.Or(targets[p.project], targetRepository, p.project)
+		event.Projects = append(event.Projects, hook.Project{Project: p.project, Target: target, Count: p.total})
+	}
+	return event
 }
 
 func validateCreateRefsFlags(repository string, fetch bool, inputFile string) error {
-	if repository == "" {
+	if fetch && repository == "" {
 		return fmt.Errorf("--repository is required")
 	}
 
@@ -108,100 +445,801 @@ func validateCreateRefsFlags(repository string, fetch bool, inputFile string) er
 	return nil
 }
 
-func getMergeRequestRefs(client *gitlab.Client, fetch bool, inputFile, repository, baseURL string) ([]gitlab.MergeRequestRef, error) {
+func getMergeRequestRefs(ctx context.Context, client gitlab.API, fetch bool, inputFile, repository, baseURL string) ([]gitlab.MergeRequestRef, error) {
 	if fetch {
-		return fetchMergeRequestRefsRealTime(client, repository, baseURL)
+		return fetchMergeRequestRefsRealTime(ctx, client, repository, baseURL)
 	}
 	return readMergeRequestRefsFromCSV(inputFile)
 }
 
-func fetchMergeRequestRefsRealTime(client *gitlab.Client, repository, baseURL string) ([]gitlab.MergeRequestRef, error) {
-	fmt.Printf("Fetching merge requests from %s...\n", repository)
+// projectRefs are the merge request references to process in one project
+type projectRefs struct {
+	project string
+	refs    iter.Seq2[gitlab.MergeRequestRef, error]
+	total   int
+	// ack, when set, removes the first n references handled from the queue refs reads
+	ack func(n int) error
+}
+
+// streamProjectRefs returns the references of a CSV file to process in each project, in the
+// order the projects first appear. The file is checked in a first pass and then read again
+// row by row, so large files are never held in memory and malformed rows are reported before
+// any branch is created. A file whose rows are grouped by project, as fetch-refs writes
+// them, is read again only once, the projects taking their rows in turn; the rows of other
+// files are read again for every project. Rows of combined files are limited to
+// repositories when any are given; other files are of the single repository given.
+func streamProjectRefs(inputFile string, repositories ...string) ([]projectRefs, error) {
+	selected := make(map[string]bool, len(repositories))
+	for _, repository := range repositories {
+		_, projectPath, err := gitlab.ParseRepoPath(repository)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse repository path: %w", err)
+		}
+		selected[projectPath] = true
+	}
 
-	var fetchedRefs []gitlab.MergeRequestRef
-	processor := func(ref gitlab.MergeRequestRef) error {
-		fetchedRefs = append(fetchedRefs, ref)
-		return nil
+	// The rows of a single-repository file are of the one repository given
+	project := ""
+	if len(repositories) == 1 {
+		_, project, _ = gitlab.ParseRepoPath(repositories[0])
+	}
+
+	logger.Info("reading merge request references", "file", inputFile)
+
+	rows := csv.ProjectRefsFromFile(inputFile, project)
+	buffered := make(map[string][]gitlab.MergeRequestRef)
+	counts := make(map[string]int)
+	var order []string
+	grouped := true
+
+	for row, err := range rows {
+		if errors.Is(err, csv.ErrNoProject) {
+			if len(repositories) > 1 {
+				return nil, fmt.Errorf("several --repository flags can only be used with --fetch or a combined --input file")
+			}
+			return nil, fmt.Errorf("--repository is required unless --input is a combined file")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV file: %w", err)
+		}
+		if len(selected) > 0 && !selected[row.Project] {
+			continue
+		}
+		if counts[row.Project] == 0 {
+			order = append(order, row.Project)
+		} else if row.Project != order[len(order)-1] {
+			grouped = false
+		}
+		counts[row.Project]++
+
+		// Standard input can only be read once, so it is buffered instead of read in two passes
+		if inputFile == csv.Stdin {
+			buffered[row.Project] = append(buffered[row.Project], row.MergeRequestRef)
+		}
+	}
+
+	var cursor *rowCursor
+	if inputFile != csv.Stdin && grouped {
+		cursor = newRowCursor(rows, counts)
+	} else if inputFile != csv.Stdin {
+		logger.Info("the rows of the projects are not grouped, reading the file once per project", "file", inputFile, "projects", len(order))
 	}
 
-	_, err := client.FetchMergeRequestRefsFromRepo(repository, baseURL, processor)
+	projects := make([]projectRefs, 0, len(order))
+	total := 0
+	for i, p := range order {
+		refs := refsOf(rows, p)
+		if inputFile == csv.Stdin {
+			refs = refSeq(buffered[p])
+		} else if cursor != nil {
+			refs = cursor.refsOf(p, i == len(order)-1)
+		}
+		projects = append(projects, projectRefs{project: p, refs: refs, total: counts[p]})
+		total += counts[p]
+	}
+
+	logger.Info("read merge request references", "file", inputFile, "count", total, "projects", len(projects))
+	return projects, nil
+}
+
+// refsOf iterates over the references of project among rows
+func refsOf(rows iter.Seq2[csv.ProjectRef, error], project string) iter.Seq2[gitlab.MergeRequestRef, error] {
+	return func(yield func(gitlab.MergeRequestRef, error) bool) {
+		for row, err := range rows {
+			if err != nil {
+				yield(gitlab.MergeRequestRef{}, err)
+				return
+			}
+			if row.Project != project {
+				continue
+			}
+			if !yield(row.MergeRequestRef, nil) {
+				return
+			}
+		}
+	}
+}
+
+// rowCursor reads rows grouped by project in a single pass shared by the projects, which
+// take their rows in the order the groups appear. A project skips what the one before it
+// left unread, and stops at the first row of the next group.
+type rowCursor struct {
+	next func() (csv.ProjectRef, error, bool)
+	stop func()
+	// projects counts the rows of the projects selected; rows of others are skipped
+	projects map[string]int
+
+	// row and err are the row read but not yet taken when pending is set
+	row     csv.ProjectRef
+	err     error
+	pending bool
+	done    bool
+}
+
+// newRowCursor starts reading rows of the projects counted in projects
+func newRowCursor(rows iter.Seq2[csv.ProjectRef, error], projects map[string]int) *rowCursor {
+	next, stop := iter.Pull2(rows)
+	return &rowCursor{next: next, stop: stop, projects: projects}
+}
+
+// peek returns the next row without taking it, or false at the end of the rows
+func (c *rowCursor) peek() (csv.ProjectRef, error, bool) {
+	if !c.pending && !c.done {
+		c.row, c.err, c.pending = c.next()
+		if !c.pending {
+			c.close()
+		}
+	}
+	return c.row, c.err, c.pending
+}
+
+// close stops reading, closing the file
+func (c *rowCursor) close() {
+	c.done = true
+	c.pending = false
+	c.stop()
+}
+
+// refsOf iterates over the rows of project's group. The file is closed once the last
+// project is done with it; a run that stops before leaves it to be closed on exit.
+func (c *rowCursor) refsOf(project string, last bool) iter.Seq2[gitlab.MergeRequestRef, error] {
+	return func(yield func(gitlab.MergeRequestRef, error) bool) {
+		if last {
+			defer c.close()
+		}
+
+		reached := false
+		for {
+			row, err, ok := c.peek()
+			if !ok {
+				return
+			}
+			if err != nil {
+				c.close()
+				yield(gitlab.MergeRequestRef{}, err)
+				return
+			}
+			if row.Project != project {
+				// The next group ends the project's rows; those of an earlier project and
+				// of projects not selected are skipped
+				if reached && c.projects[row.Project] > 0 {
+					return
+				}
+				c.pending = false
+				continue
+			}
+
+			reached = true
+			c.pending = false
+			if !yield(row.MergeRequestRef, nil) {
+				return
+			}
+		}
+	}
+}
+
+// refSeq iterates over refs already held in memory
+func refSeq(refs []gitlab.MergeRequestRef) iter.Seq2[gitlab.MergeRequestRef, error] {
+	return func(yield func(gitlab.MergeRequestRef, error) bool) {
+		for _, ref := range refs {
+			if !yield(ref, nil) {
+				return
+			}
+		}
+	}
+}
+
+func fetchMergeRequestRefsRealTime(ctx context.Context, client gitlab.API, repository, baseURL string) ([]gitlab.MergeRequestRef, error) {
+	logger.Info("fetching merge requests", "project", repository)
+
+	fetchedRefs, err := migrate.CollectRefs(ctx, client, repository, migrate.FetchOptions{
+		Check: interrupted,
+		OnRef: func(gitlab.MergeRequestRef) {
+			runStats.refsFetched.Add(1)
+		},
+		Logger: logger,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch merge requests: %w", err)
 	}
 
-	fmt.Printf("Found %d merge requests\n", len(fetchedRefs))
+	logger.Info("fetched merge requests", "project", repository, "count", len(fetchedRefs))
 	return fetchedRefs, nil
 }
 
 func readMergeRequestRefsFromCSV(inputFile string) ([]gitlab.MergeRequestRef, error) {
-	fmt.Printf("Reading merge request references from %s...\n", inputFile)
+	logger.Info("reading merge request references", "file", inputFile)
 
 	refs, err := csv.ReadRefsFromFile(inputFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read CSV file: %w", err)
 	}
 
-	fmt.Printf("Found %d merge request references in CSV file\n", len(refs))
+	logger.Info("read merge request references", "file", inputFile, "count", len(refs))
 	return refs, nil
 }
 
-func createBranchesInRepo(client *gitlab.Client, refs []gitlab.MergeRequestRef, targetRepo string, fetch bool, inputFile string, mock bool) error {
+// branchCounts tallies the branches of a run
+type branchCounts struct {
+	created int
+	failed  int
+	// skipped are branches whose commit is missing on GitHub
+	skipped int
+	// existing are the failed branches whose name was already taken
+	existing int
+	// updated and unchanged are the existing branches moved to their head commit and those
+	// already there, with --update-existing
+	updated   int
+	unchanged int
+	total     int
+}
+
+// add adds the counts of another project
+func (c *branchCounts) add(other branchCounts) {
+	c.created += other.created
+	c.failed += other.failed
+	c.skipped += other.skipped
+	c.existing += other.existing
+	c.updated += other.updated
+	c.unchanged += other.unchanged
+	c.total += other.total
+}
+
+// createBranchesInRepo creates a migration branch for each of the total references yielded by refs
+func createBranchesInRepo(ctx context.Context, client gitlab.API, refs iter.Seq2[gitlab.MergeRequestRef, error], total int, targetRepo string, opts createOptions) (branchCounts, error) {
 	// Parse target repository path
 	_, targetProjectPath, err := gitlab.ParseRepoPath(targetRepo)
 	if err != nil {
-		return fmt.Errorf("failed to parse target repository path: %w", err)
+		return branchCounts{total: total}, fmt.Errorf("failed to parse target repository path: %w", err)
 	}
 
-	if mock {
-		fmt.Printf("🧪 Mock mode: Simulating branch creation in %s...\n", targetProjectPath)
+	if opts.mock {
+		logger.Info("mock mode: simulating branch creation", "project", targetProjectPath)
 	} else {
-		fmt.Printf("Creating branches in %s...\n", targetProjectPath)
+		// GitLab doesn't redirect branch creation from the old path of a moved project
+		if targetProjectPath, err = followMovedProject(ctx, client, targetProjectPath); err != nil {
+			return branchCounts{total: total}, err
+		}
+		l, err := lockRepository("gitlab", targetProjectPath)
+		if err != nil {
+			return branchCounts{total: total}, err
+		}
+		defer releaseLock(l)
+		logger.Info("creating branches", "project", targetProjectPath)
 	}
 
-	// Create branches
-	successCount := 0
-	errorCount := 0
+	throughput := newProgress("branch throughput", total, "project", targetProjectPath)
 
-	for _, ref := range refs {
-		branchName := generateBranchName(ref.IID)
+	if opts.mock {
+		created := 0
+		for ref, err := range refs {
+			if err != nil {
+				printSummary(created, 0, total, opts.fetch, opts.inputFile)
+				return branchCounts{created: created, total: total}, fmt.Errorf("failed to read merge request references: %w", err)
+			}
+			if err := interrupted(ctx); err != nil {
+				printSummary(created, 0, total, opts.fetch, opts.inputFile)
+				return branchCounts{created: created, total: total}, fmt.Errorf("stopped after %d of %d branches: %w", created, total, err)
+			}
 
-		if mock {
 			// Mock mode: just print what would be created
-			fmt.Printf("Created branch %s with sha: %s\n", branchName, ref.HeadSHA)
-			successCount++
-		} else {
-			// Real mode: actually create the branch
-			fmt.Printf("Creating branch '%s' from SHA %s...", branchName, ref.HeadSHA)
+			fmt.Printf("%s %s with sha: %s\n", green("Created branch"), opts.naming.branchName(ref), ref.HeadSHA)
+			created++
+			throughput.add(1)
+		}
+		printSummary(created, 0, total, opts.fetch, opts.inputFile)
+		return branchCounts{created: created, total: total}, nil
+	}
 
-			err = client.CreateBranch(targetProjectPath, branchName, ref.HeadSHA)
-			if err != nil {
-				fmt.Printf(" ❌ Failed: %v\n", err)
+	var existing map[string]string
+	if opts.updateExisting {
+		logger.Info("reading existing branches", "project", targetProjectPath)
+		if existing, err = client.ListBranchSHAs(ctx, targetProjectPath, opts.naming.branchPrefix()); err != nil {
+			return branchCounts{total: total}, fmt.Errorf("failed to read existing branches: %w", err)
+		}
+	}
+
+	result, err := migrate.CreateBranches(ctx, client, targetProjectPath, refs, migrate.CreateOptions{
+		RefBranchName: opts.naming.branchName,
+		Total:         total,
+		Check:         interrupted,
+		Existing:      existing,
+		OnBranch: func(b migrate.BranchResult) {
+			switch {
+			case b.Err != nil:
+				runStats.branchesFailed.Add(1)
+			case !b.Unchanged:
+				runStats.branchesCreated.Add(1)
+			}
+			throughput.add(1)
+
+			status, reason := branchStatus(b.Err)
+			switch {
+			case b.Updated:
+				status = hook.StatusUpdated
+			case b.Unchanged:
+				status = hook.StatusUnchanged
+			}
+			opts.hooks.branchDone(ctx, hook.RefEvent{Platform: "gitlab", Repository: targetProjectPath, IID: b.Ref.IID, Branch: b.Branch, SHA: b.Ref.HeadSHA, Status: status, Error: reason})
+		},
+		Logger: logger,
+	})
+	printSummary(result.Created, result.Failed, total, opts.fetch, opts.inputFile)
+	printExistingSummary(result.Updated, result.Unchanged)
+	return branchCounts{created: result.Created, failed: result.Failed, existing: result.Existing, updated: result.Updated, unchanged: result.Unchanged, total: total}, err
+}
+
+// githubOptions controls how branches are created on GitHub
+type githubOptions struct {
+	createOptions
+	// batchSize is how many branches are created per GraphQL request
+	batchSize int
+	// openIssue files an issue listing the branches that could not be created
+	openIssue bool
+	// issueRepository receives that issue instead of the target repository when set
+	issueRepository string
+	// tags creates lightweight tags named like the branches instead of branches
+	tags bool
+	// ack, when set, is called with the number of references taken since the last call each
+	// time a batch has been created, so a queue only drops them then
+	ack func(n int) error
+}
+
+// ref returns the GitHub reference created for a branch name and SHA
+func (o githubOptions) ref(name, sha string) github.Ref {
+	if o.tags {
+		return github.TagRef(name, sha)
+	}
+	return github.BranchRef(name, sha)
+}
+
+// kind is what is created: "branch" or "tag"
+func (o githubOptions) kind() string {
+	if o.tags {
+		return "tag"
+	}
+	return "branch"
+}
+
+// missingCommitReason explains why the commit of ref is not on GitHub. Only references
+// fetched with --fetch are known to come from forks; CSV files don't record it.
+func missingCommitReason(ref gitlab.MergeRequestRef) string {
+	if ref.Fork {
+		return fmt.Sprintf("commit not found on GitHub: the merge request is from a fork, so its commits are only in %s, which a mirror push of branches and tags does not copy", gitlab.MergeRequestHeadRef(ref.IID))
+	}
+	return "commit not found on GitHub"
+}
+
+// failedBranch is a branch that could not be created, and why
+type failedBranch struct {
+	ref    gitlab.MergeRequestRef
+	branch string
+	reason string
+}
+
+// createBranchesOnGitHub creates a migration branch in a GitHub owner/name repository for each
+// of the total references of the GitLab project source yielded by refs, or a tag with
+// opts.tags, sending opts.batchSize branches per GraphQL request. References whose commit GitHub does not have
+// are skipped and reported instead.
+func createBranchesOnGitHub(ctx context.Context, client github.API, refs iter.Seq2[gitlab.MergeRequestRef, error], total int, source, repository string, opts githubOptions) (branchCounts, error) {
+	var repositoryID string
+	if opts.mock {
+		logger.Info("mock mode: simulating branch creation", "repository", repository)
+	} else {
+		l, err := lockRepository("github", repository)
+		if err != nil {
+			return branchCounts{total: total}, err
+		}
+		defer releaseLock(l)
+
+		id, err := client.RepositoryID(ctx, repository)
+		if err != nil {
+			return branchCounts{total: total}, err
+		}
+		repositoryID = id
+		logger.Info("creating "+opts.kind()+"s", "repository", repository, "batch_size", opts.batchSize)
+	}
+
+	successCount := 0
+	errorCount := 0
+	missingCount := 0
+	existingCount := 0
+	updatedCount := 0
+	unchangedCount := 0
+	var failed []failedBranch
+	counts := func() branchCounts {
+		return branchCounts{created: successCount, failed: errorCount, skipped: missingCount, existing: existingCount, updated: updatedCount, unchanged: unchangedCount, total: total}
+	}
+	handled := func() int {
+		return successCount + errorCount + missingCount + updatedCount + unchangedCount
+	}
+	pending := make([]gitlab.MergeRequestRef, 0, opts.batchSize)
+	// taken counts the references taken from refs since they were last acknowledged
+	taken := 0
+	ack := func() error {
+		if opts.ack == nil || taken == 0 {
+			return nil
+		}
+		n := taken
+		taken = 0
+		return opts.ack(n)
+	}
+	throughput := newProgress("branch throughput", total, "repository", repository)
+
+	// record counts the outcome of creating, or with update moving, each of refs
+	record := func(refs []gitlab.MergeRequestRef, results []error, update bool) {
+		for i, ref := range refs {
+			branchName := opts.naming.branchName(ref)
+			status, reason := branchStatus(results[i])
+			if update && results[i] == nil {
+				status = hook.StatusUpdated
+			}
+			opts.hooks.branchDone(ctx, hook.RefEvent{Platform: "github", Repository: repository, IID: ref.IID, Branch: branchName, SHA: ref.HeadSHA, Status: status, Error: reason})
+			if results[i] != nil {
+				logger.Error("failed to create "+opts.kind(), "repository", repository, "iid", ref.IID, "branch", branchName, "sha", ref.HeadSHA, "error", results[i])
 				errorCount++
+				runStats.branchesFailed.Add(1)
+				if errors.Is(results[i], github.ErrRefExists) {
+					existingCount++
+				}
+				failed = append(failed, failedBranch{ref: ref, branch: branchName, reason: results[i].Error()})
+				continue
+			}
+			if update {
+				logger.Info("updated "+opts.kind(), "repository", repository, "iid", ref.IID, "branch", branchName, "sha", ref.HeadSHA)
+				updatedCount++
 			} else {
-				fmt.Printf(" ✅ Created successfully\n")
+				logger.Info("created "+opts.kind(), "repository", repository, "iid", ref.IID, "branch", branchName, "sha", ref.HeadSHA)
 				successCount++
 			}
+			runStats.branchesCreated.Add(1)
 		}
 	}
 
-	printSummary(successCount, errorCount, len(refs), fetch, inputFile)
-	return nil
+	// createPending creates the pending branches whose commit exists on GitHub, with one
+	// request each for the check and the creation
+	createPending := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+
+		shas := make([]string, len(pending))
+		for i, ref := range pending {
+			shas[i] = ref.HeadSHA
+		}
+		exists, err := client.CommitsExist(ctx, repository, shas)
+		if err != nil {
+			errorCount += len(pending)
+			return fmt.Errorf("stopped after %d of %d branches: %w", handled(), total, err)
+		}
+
+		ready := pending[:0]
+		for i, ref := range pending {
+			if !exists[i] {
+				logger.Warn("commit not found on GitHub, skipping "+opts.kind(), "repository", repository, "iid", ref.IID, "branch", opts.naming.branchName(ref), "sha", ref.HeadSHA, "fork", ref.Fork)
+				missingCount++
+				failed = append(failed, failedBranch{ref: ref, branch: opts.naming.branchName(ref), reason: missingCommitReason(ref)})
+				opts.hooks.branchDone(ctx, hook.RefEvent{Platform: "github", Repository: repository, IID: ref.IID, Branch: opts.naming.branchName(ref), SHA: ref.HeadSHA, Status: hook.StatusSkipped, Error: missingCommitReason(ref)})
+				continue
+			}
+			ready = append(ready, ref)
+		}
+		pending = ready
+		if len(pending) == 0 {
+			return nil
+		}
+
+		// With --update-existing, existing references are moved instead of created
+		creates, existing, unchanged, err := splitExistingRefs(ctx, client, repository, pending, opts)
+		if err != nil {
+			errorCount += len(pending)
+			return fmt.Errorf("stopped after %d of %d branches: %w", handled(), total, err)
+		}
+		for _, ref := range unchanged {
+			logger.Info(opts.kind()+" already at head commit", "repository", repository, "iid", ref.IID, "branch", opts.naming.branchName(ref), "sha", ref.HeadSHA)
+			unchangedCount++
+			opts.hooks.branchDone(ctx, hook.RefEvent{Platform: "github", Repository: repository, IID: ref.IID, Branch: opts.naming.branchName(ref), SHA: ref.HeadSHA, Status: hook.StatusUnchanged})
+		}
+
+		batch := make([]github.Ref, len(creates))
+		for i, ref := range creates {
+			batch[i] = opts.ref(opts.naming.branchName(ref), ref.HeadSHA)
+		}
+		updates := make([]github.RefUpdate, len(existing))
+		updated := make([]gitlab.MergeRequestRef, len(existing))
+		for i, e := range existing {
+			updates[i] = github.RefUpdate{ID: e.id, Ref: opts.ref(opts.naming.branchName(e.ref), e.ref.HeadSHA)}
+			updated[i] = e.ref
+		}
+
+		results, err := client.CreateRefs(ctx, repositoryID, batch)
+		if err != nil {
+			errorCount += len(creates) + len(updates)
+			return fmt.Errorf("stopped after %d of %d branches: %w", handled(), total, err)
+		}
+		updateResults, err := client.UpdateRefs(ctx, updates)
+		if err != nil {
+			record(creates, results, false)
+			errorCount += len(updates)
+			return fmt.Errorf("stopped after %d of %d branches: %w", handled(), total, err)
+		}
+
+		record(creates, results, false)
+		record(updated, updateResults, true)
+		throughput.add(len(pending))
+
+		return nil
+	}
+
+	// flush creates the pending branches, and only then acknowledges their references
+	flush := func() error {
+		if err := createPending(); err != nil {
+			return err
+		}
+		return ack()
+	}
+
+	for ref, err := range refs {
+		if err != nil {
+			printGitHubSummary(counts(), opts.fetch, opts.inputFile)
+			return counts(), fmt.Errorf("failed to read merge request references: %w", err)
+		}
+
+		if opts.mock {
+			fmt.Printf("%s %s with sha: %s\n", green("Created "+opts.kind()), opts.naming.branchName(ref), ref.HeadSHA)
+			successCount++
+			throughput.add(1)
+			continue
+		}
+
+		// Branches already pending are still created, but no more are added
+		if err := interrupted(ctx); err != nil {
+			flushErr := flush()
+			printGitHubSummary(counts(), opts.fetch, opts.inputFile)
+			return counts(), errors.Join(fmt.Errorf("stopped after %d of %d branches: %w", handled(), total, err), flushErr)
+		}
+
+		pending = append(pending, ref)
+		taken++
+		if len(pending) == opts.batchSize {
+			if err := flush(); err != nil {
+				printGitHubSummary(counts(), opts.fetch, opts.inputFile)
+				return counts(), err
+			}
+			pending = pending[:0]
+		}
+	}
+
+	err := flush()
+	printGitHubSummary(counts(), opts.fetch, opts.inputFile)
+	if err != nil {
+		return counts(), err
+	}
+
+	if opts.openIssue && len(failed) > 0 {
+		issueRepository := cmp.Or(opts.issueRepository, repository)
+		url, err := client.CreateIssue(ctx, issueRepository, failedBranchesTitle(len(failed), repository), failedBranchesBody(failed, source, repository))
+		if err != nil {
+			return counts(), fmt.Errorf("failed to open issue for %d branches: %w", len(failed), err)
+		}
+		logger.Info("opened issue for branches that could not be created", "repository", issueRepository, "count", len(failed), "url", url)
+		statusf("📝 Issue: %s\n", url)
+	}
+
+	return counts(), nil
+}
+
+// existingRef is a reference whose branch already exists on GitHub at another commit, with
+// the node ID of that branch
+type existingRef struct {
+	ref gitlab.MergeRequestRef
+	id  string
+}
+
+// splitExistingRefs sorts refs into those to create, those whose branch exists at another
+// commit and those whose branch is already at their head commit. Without --update-existing,
+// every reference is created, and one whose name is taken fails.
+func splitExistingRefs(ctx context.Context, client github.API, repository string, refs []gitlab.MergeRequestRef, opts githubOptions) ([]gitlab.MergeRequestRef, []existingRef, []gitlab.MergeRequestRef, error) {
+	if !opts.updateExisting {
+		return refs, nil, nil, nil
+	}
+
+	names := make([]string, len(refs))
+	for i, ref := range refs {
+		names[i] = opts.ref(opts.naming.branchName(ref), ref.HeadSHA).Name
+	}
+	targets, err := client.RefTargets(ctx, repository, names)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var creates, unchanged []gitlab.MergeRequestRef
+	var existing []existingRef
+	// Names shared by several references are only taken by the first
+	taken := make(map[string]bool, len(refs))
+	for i, ref := range refs {
+		switch {
+		case targets[i] == nil || taken[names[i]]:
+			creates = append(creates, ref)
+		case targets[i].SHA == ref.HeadSHA:
+			unchanged = append(unchanged, ref)
+		default:
+			existing = append(existing, existingRef{ref: ref, id: targets[i].ID})
+		}
+		taken[names[i]] = true
+	}
+	return creates, existing, unchanged, nil
+}
+
+// maxIssueRows limits the rows listed in an issue, keeping it well below GitHub's body size limit
+const maxIssueRows = 500
+
+// failedBranchesTitle is the title of the issue listing count branches that could not be created
+func failedBranchesTitle(count int, repository string) string {
+	return fmt.Sprintf("Migration: %d merge request branches could not be created in %s", count, repository)
+}
+
+// failedBranchesBody lists the branches that could not be created as a Markdown table
+func failedBranchesBody(failed []failedBranch, source, repository string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "gh-gl-create-refs could not create these migration branches in `%s` for the merge requests of the GitLab project `%s`.\n\n", repository, source)
+	b.WriteString("| Merge request | Branch | SHA | Problem |\n|---|---|---|---|\n")
+	for i, f := range failed {
+		if i == maxIssueRows {
+			fmt.Fprintf(&b, "\n…and %d more.\n", len(failed)-maxIssueRows)
+			break
+		}
+		fmt.Fprintf(&b, "| !%d | `%s` | `%s` | %s |\n", f.ref.IID, f.branch, f.ref.HeadSHA, strings.ReplaceAll(f.reason, "|", "\\|"))
+	}
+	b.WriteString("\nPush the missing commits to this repository, or fix the source references, then run create-refs again.\n")
+	return b.String()
+}
+
+// printGitHubSummary prints the summary of a GitHub run, including the references skipped
+// because their commit is missing on GitHub
+func printGitHubSummary(counts branchCounts, fetch bool, inputFile string) {
+	printSummary(counts.created, counts.failed, counts.total, fetch, inputFile)
+	if counts.skipped > 0 {
+		statusf("⚠️  %s: %d branches\n", yellow("Skipped, commit not on GitHub"), counts.skipped)
+	}
+	printExistingSummary(counts.updated, counts.unchanged)
+}
+
+// gitlabTargets returns the GitLab projects branches are created in: targetRepository when
+// set, or else every project with references
+func gitlabTargets(projects []projectRefs, targetRepository string) []string {
+	if targetRepository != "" {
+		return []string{targetRepository}
+	}
+	var targets []string
+	for _, p := range projects {
+		if p.total > 0 {
+			targets = append(targets, p.project)
+		}
+	}
+	return targets
+}
+
+// githubTargets returns the GitHub repository the branches of each project are created in:
+// githubTarget for a single project, or the repository the mapping file lists for it
+func githubTargets(projects []projectRefs, githubTarget, mappingFile string) (map[string]string, error) {
+	targets := make(map[string]string, len(projects))
+	if githubTarget != "" {
+		for _, p := range projects {
+			targets[p.project] = githubTarget
+		}
+		return targets, nil
+	}
+
+	m, err := mapping.ReadFile(mappingFile)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range projects {
+		target, err := m.Target(p.project)
+		if err != nil {
+			return nil, fmt.Errorf("no GitHub repository for %s in %s: %w", p.project, mappingFile, err)
+		}
+		targets[p.project] = target
+	}
+	return targets, nil
+}
+
+// createBranchesInProjects runs create for each project in turn, continuing with the next
+// project when one fails, and totals their counts. A single project is processed directly.
+func createBranchesInProjects(projects []projectRefs, create func(projectRefs) (branchCounts, error)) (branchCounts, error) {
+	if len(projects) == 1 {
+		return create(projects[0])
+	}
+
+	var counts branchCounts
+	failed := 0
+	// Each project prints its own summary; the table compares them at the end
+	var rows [][]string
+	defer func() {
+		statusTable([]string{"PROJECT", "CREATED", "FAILED", "SKIPPED", "DURATION", "STATUS"}, rows)
+	}()
+
+	for _, p := range projects {
+		logger.Info("processing project", "project", p.project, "count", p.total)
+
+		start := time.Now()
+		projectCounts, err := create(p)
+		counts.add(projectCounts)
+		rows = append(rows, []string{
+			p.project,
+			strconv.Itoa(projectCounts.created),
+			strconv.Itoa(projectCounts.failed),
+			strconv.Itoa(projectCounts.skipped),
+			formatDuration(time.Since(start)),
+			statusCell(err),
+		})
+
+		if err != nil {
+			logger.Error("failed to create branches", "project", p.project, "error", err)
+			failed++
+
+			// These fail every remaining project the same way
+			if errors.Is(err, errInterrupted) || errors.Is(err, gitlab.ErrBudgetExhausted) || errors.Is(err, gitlab.ErrUnauthorized) || errors.Is(err, gitlab.ErrRateLimited) ||
+				errors.Is(err, github.ErrUnauthorized) || errors.Is(err, github.ErrRateLimited) {
+				return counts, err
+			}
+		}
+	}
+
+	if failed > 0 {
+		return counts, withExitCode(exitPartialFailure, fmt.Errorf("%d of %d projects failed", failed, len(projects)))
+	}
+	return counts, nil
+}
+
+// printExistingSummary adds the existing branches handled with --update-existing to a summary
+func printExistingSummary(updated, unchanged int) {
+	if updated > 0 {
+		statusf("🔄 %s: %d branches\n", green("Updated"), updated)
+	}
+	if unchanged > 0 {
+		statusf("⏭️  %s: %d branches\n", yellow("Unchanged"), unchanged)
+	}
 }
 
 func printSummary(successCount, errorCount, totalCount int, fetch bool, inputFile string) {
-	fmt.Printf("\nSummary:\n")
-	fmt.Printf("✅ Successfully created: %d branches\n", successCount)
+	statusf("\nSummary:\n")
+	statusf("✅ %s: %d branches\n", green("Successfully created"), successCount)
 	if errorCount > 0 {
-		fmt.Printf("❌ Failed: %d branches\n", errorCount)
+		statusf("❌ %s: %d branches\n", red("Failed"), errorCount)
 	}
-	fmt.Printf("📋 Total processed: %d merge requests\n", totalCount)
+	statusf("📋 Total processed: %d merge requests\n", totalCount)
 
 	// Get absolute path for the input file if used
-	if !fetch && inputFile != "" {
+	if !fetch && inputFile == csv.Stdin {
+		statusf("📄 Input file: stdin\n")
+	} else if !fetch && inputFile != "" {
 		absPath, err := filepath.Abs(inputFile)
 		if err != nil {
 			absPath = inputFile // Fallback to relative path
 		}
-		fmt.Printf("📄 Input file: %s\n", absPath)
+		statusf("📄 Input file: %s\n", absPath)
 	}
 }
@@ -1,7 +1,24 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+	"github.com/amenocal/gh-gl-create-refs/pkg/github"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/hook"
+	"github.com/amenocal/gh-gl-create-refs/pkg/mapping"
+	"github.com/amenocal/gh-gl-create-refs/pkg/migrate"
 )
 
 func TestGenerateBranchName(t *testing.T) {
@@ -88,10 +105,17 @@ func TestValidateCreateRefsFlags(t *testing.T) {
 			wantErr:    false,
 		},
 		{
-			name:       "missing repository",
+			name:       "missing repository with input file",
 			repository: "",
 			fetch:      false,
 			inputFile:  "test.csv",
+			wantErr:    false, // Combined input files name their projects
+		},
+		{
+			name:       "missing repository with fetch mode",
+			repository: "",
+			fetch:      true,
+			inputFile:  "",
 			wantErr:    true,
 			errMsg:     "--repository is required",
 		},
@@ -109,7 +133,7 @@ func TestValidateCreateRefsFlags(t *testing.T) {
 			fetch:      false,
 			inputFile:  "",
 			wantErr:    true,
-			errMsg:     "--repository is required",
+			errMsg:     "--input is required unless --fetch is used",
 		},
 		{
 			name:       "whitespace repository",
@@ -161,3 +185,674 @@ func TestValidateCreateRefsFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateBranchesInRepo(t *testing.T) {
+	api := newMockAPI()
+	api.failBranches["migration-pr-2"] = true
+
+	refs := []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: "aaaaaaa"},
+		{IID: 2, HeadSHA: "bbbbbbb"},
+		{IID: 3, HeadSHA: "ccccccc"},
+	}
+
+	_, err := createBranchesInRepo(context.Background(), api, refSeq(refs), len(refs), "target/project", createOptions{fetch: true})
+	if err != nil {
+		t.Fatalf("createBranchesInRepo() unexpected error = %v", err)
+	}
+
+	expected := map[string]string{
+		"migration-pr-1": "aaaaaaa",
+		"migration-pr-3": "ccccccc",
+	}
+	got := api.branches["target/project"]
+	if len(got) != len(expected) {
+		t.Fatalf("Expected branches %v, got %v", expected, got)
+	}
+	for name, sha := range expected {
+		if got[name] != sha {
+			t.Errorf("Branch %s = %q, want %q", name, got[name], sha)
+		}
+	}
+}
+
+func TestCreateBranchesInRepo_MovedProject(t *testing.T) {
+	api := newMockAPI()
+	api.moved = map[string]string{"old-group/project": "new-group/project"}
+
+	refs := []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaaaaaa"}}
+
+	if _, err := createBranchesInRepo(context.Background(), api, refSeq(refs), len(refs), "old-group/project", createOptions{fetch: true}); err != nil {
+		t.Fatalf("createBranchesInRepo() unexpected error = %v", err)
+	}
+	if api.branches["new-group/project"]["migration-pr-1"] != "aaaaaaa" {
+		t.Errorf("Expected the branch in the project's new path, got %v", api.branches)
+	}
+}
+
+func TestCreateBranchesInRepo_CountsExisting(t *testing.T) {
+	api := newMockAPI()
+	api.branches["target/project"] = map[string]string{"migration-pr-1": "old"}
+	api.failBranches["migration-pr-2"] = true
+
+	refs := []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: "aaaaaaa"},
+		{IID: 2, HeadSHA: "bbbbbbb"},
+		{IID: 3, HeadSHA: "ccccccc"},
+	}
+
+	counts, err := createBranchesInRepo(context.Background(), api, refSeq(refs), len(refs), "target/project", createOptions{fetch: true})
+	if err != nil {
+		t.Fatalf("createBranchesInRepo() unexpected error = %v", err)
+	}
+	if counts != (branchCounts{created: 1, failed: 2, existing: 1, total: 3}) {
+		t.Errorf("createBranchesInRepo() counts = %+v", counts)
+	}
+}
+
+func TestCreateBranchesInRepo_UpdateExisting(t *testing.T) {
+	api := newMockAPI()
+	api.branches["target/project"] = map[string]string{"migration-pr-1": "aaaaaaa", "migration-pr-2": "old"}
+
+	refs := []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: "aaaaaaa"},
+		{IID: 2, HeadSHA: "bbbbbbb"},
+		{IID: 3, HeadSHA: "ccccccc"},
+	}
+
+	counts, err := createBranchesInRepo(context.Background(), api, refSeq(refs), len(refs), "target/project", createOptions{updateExisting: true, fetch: true})
+	if err != nil {
+		t.Fatalf("createBranchesInRepo() unexpected error = %v", err)
+	}
+	if counts != (branchCounts{created: 1, updated: 1, unchanged: 1, total: 3}) {
+		t.Errorf("createBranchesInRepo() counts = %+v", counts)
+	}
+	if got := api.branches["target/project"]["migration-pr-2"]; got != "bbbbbbb" {
+		t.Errorf("Branch migration-pr-2 = %q, want it moved to bbbbbbb", got)
+	}
+	if fmt.Sprint(api.calls) != "[delete target/project migration-pr-2 create target/project migration-pr-2@bbbbbbb create target/project migration-pr-3@ccccccc]" {
+		t.Errorf("Calls = %v", api.calls)
+	}
+}
+
+func TestCreateBranchesInRepo_Mock(t *testing.T) {
+	api := newMockAPI()
+
+	refs := []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaaaaaa"}}
+
+	if _, err := createBranchesInRepo(context.Background(), api, refSeq(refs), len(refs), "target/project", createOptions{fetch: true, mock: true}); err != nil {
+		t.Fatalf("createBranchesInRepo() unexpected error = %v", err)
+	}
+
+	if len(api.calls) != 0 {
+		t.Errorf("Mock mode should not call the API, got %v", api.calls)
+	}
+}
+
+func TestGetMergeRequestRefs_Fetch(t *testing.T) {
+	api := newMockAPI()
+	api.refs["group/project"] = []gitlab.MergeRequestRef{{IID: 7, HeadSHA: "abc"}}
+
+	refs, err := getMergeRequestRefs(context.Background(), api, true, "", "group/project", "")
+	if err != nil {
+		t.Fatalf("getMergeRequestRefs() unexpected error = %v", err)
+	}
+
+	if len(refs) != 1 || refs[0].IID != 7 {
+		t.Errorf("getMergeRequestRefs() = %v", refs)
+	}
+
+	if _, err := getMergeRequestRefs(context.Background(), api, true, "", "group/missing", ""); err == nil {
+		t.Error("getMergeRequestRefs() expected error for unknown repository")
+	}
+}
+
+func TestCreateBranchesInRepo_StopsOnUnauthorized(t *testing.T) {
+	api := newMockAPI()
+	api.createErr = fmt.Errorf("failed to create branch: %w", gitlab.ErrUnauthorized)
+
+	refs := []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: "aaaaaaa"},
+		{IID: 2, HeadSHA: "bbbbbbb"},
+	}
+
+	_, err := createBranchesInRepo(context.Background(), api, refSeq(refs), len(refs), "target/project", createOptions{fetch: true})
+	if !errors.Is(err, gitlab.ErrUnauthorized) {
+		t.Fatalf("createBranchesInRepo() error = %v, want ErrUnauthorized", err)
+	}
+
+	if len(api.calls) != 1 {
+		t.Errorf("Expected the run to stop after the first failure, got calls %v", api.calls)
+	}
+}
+
+func TestStreamMergeRequestRefs_CSV(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		expectedTotal int
+		expectError   bool
+	}{
+		{
+			name:          "valid file",
+			content:       "# gh-gl-create-refs refs v1\n1,aaaaaaa\n2,bbbbbbb\n",
+			expectedTotal: 2,
+		},
+		{
+			name:        "malformed row after valid rows",
+			content:     "1,aaaaaaa\n2,bbbbbbb\n3\n",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newMockAPI()
+			inputFile := filepath.Join(t.TempDir(), "refs.csv")
+			if err := os.WriteFile(inputFile, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			projects, err := streamProjectRefs(inputFile, "group/project")
+			if tt.expectError {
+				// Malformed files are rejected before any branch is created
+				if err == nil {
+					t.Error("streamProjectRefs() expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("streamProjectRefs() unexpected error = %v", err)
+			}
+			if len(projects) != 1 || projects[0].project != "group/project" {
+				t.Fatalf("streamProjectRefs() = %+v, want one group/project entry", projects)
+			}
+			if projects[0].total != tt.expectedTotal {
+				t.Errorf("total = %d, want %d", projects[0].total, tt.expectedTotal)
+			}
+
+			if _, err := createBranchesInRepo(context.Background(), api, projects[0].refs, projects[0].total, "target/project", createOptions{inputFile: inputFile}); err != nil {
+				t.Fatalf("createBranchesInRepo() unexpected error = %v", err)
+			}
+			if len(api.branches["target/project"]) != tt.expectedTotal {
+				t.Errorf("Expected %d branches, got %v", tt.expectedTotal, api.branches["target/project"])
+			}
+		})
+	}
+}
+
+func TestRunCreateRefs_VerifyChecksumFailure(t *testing.T) {
+	inputFile := filepath.Join(t.TempDir(), "refs.csv")
+	refs := []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaaaaaa"}, {IID: 2, HeadSHA: "bbbbbbb"}}
+	if err := csv.WriteRefsToFile(refs, inputFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a truncated copy
+	if err := os.Truncate(inputFile, 20); err != nil {
+		t.Fatal(err)
+	}
+
+	flags := createRefsCmd.Flags()
+	for name, value := range map[string]string{"input": inputFile, "repository": "group/project", "verify-checksum": "true"} {
+		if err := flags.Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		flags.Set("input", "")
+		flags.Set("repository", "")
+		flags.Set("verify-checksum", "false")
+	})
+
+	err := runCreateRefs(createRefsCmd, nil)
+	if !errors.Is(err, csv.ErrChecksumMismatch) {
+		t.Fatalf("runCreateRefs() error = %v, want ErrChecksumMismatch", err)
+	}
+}
+
+func TestStreamMergeRequestRefs_Stdin(t *testing.T) {
+	stdin := filepath.Join(t.TempDir(), "stdin.csv")
+	if err := os.WriteFile(stdin, []byte("1,aaaaaaa\n2,bbbbbbb\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	original := os.Stdin
+	os.Stdin = file
+	t.Cleanup(func() { os.Stdin = original })
+
+	api := newMockAPI()
+	projects, err := streamProjectRefs(csv.Stdin, "group/project")
+	if err != nil {
+		t.Fatalf("streamProjectRefs() unexpected error = %v", err)
+	}
+	if len(projects) != 1 || projects[0].total != 2 {
+		t.Fatalf("streamProjectRefs() = %+v, want 2 references in one project", projects)
+	}
+
+	if _, err := createBranchesInRepo(context.Background(), api, projects[0].refs, projects[0].total, "target/project", createOptions{inputFile: csv.Stdin}); err != nil {
+		t.Fatalf("createBranchesInRepo() unexpected error = %v", err)
+	}
+	if len(api.branches["target/project"]) != 2 {
+		t.Errorf("Expected 2 branches, got %v", api.branches["target/project"])
+	}
+}
+
+func TestStreamProjectRefs_Combined(t *testing.T) {
+	inputFile := filepath.Join(t.TempDir(), "combined.csv")
+	content := "# gh-gl-create-refs combined v1\nacme/a,1,aaaaaaa\nacme/b,1,bbbbbbb\nacme/a,2,ccccccc\n"
+	if err := os.WriteFile(inputFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		repositories []string
+		expected     map[string]int
+	}{
+		{
+			name:     "every project",
+			expected: map[string]int{"acme/a": 2, "acme/b": 1},
+		},
+		{
+			name:         "filtered by repository",
+			repositories: []string{"https://gitlab.com/acme/a"},
+			expected:     map[string]int{"acme/a": 2},
+		},
+		{
+			name:         "filtered by several repositories",
+			repositories: []string{"acme/b", "acme/a", "acme/c"},
+			expected:     map[string]int{"acme/a": 2, "acme/b": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newMockAPI()
+			projects, err := streamProjectRefs(inputFile, tt.repositories...)
+			if err != nil {
+				t.Fatalf("streamProjectRefs() unexpected error = %v", err)
+			}
+			if len(projects) != len(tt.expected) {
+				t.Fatalf("streamProjectRefs() returned %d projects, want %d", len(projects), len(tt.expected))
+			}
+
+			_, err = createBranchesInProjects(projects, func(p projectRefs) (branchCounts, error) {
+				return createBranchesInRepo(context.Background(), api, p.refs, p.total, p.project, createOptions{inputFile: inputFile})
+			})
+			if err != nil {
+				t.Fatalf("createBranchesInProjects() unexpected error = %v", err)
+			}
+			for project, count := range tt.expected {
+				if len(api.branches[project]) != count {
+					t.Errorf("Expected %d branches in %s, got %v", count, project, api.branches[project])
+				}
+			}
+		})
+	}
+}
+
+func TestStreamProjectRefs_Grouped(t *testing.T) {
+	inputFile := filepath.Join(t.TempDir(), "combined.csv")
+	content := "# gh-gl-create-refs combined v1\nacme/a,1,aaaaaaa\nacme/a,2,bbbbbbb\nacme/a,3,ccccccc\nacme/c,1,ddddddd\nacme/b,1,eeeeeee\nacme/b,2,fffffff\n"
+	if err := os.WriteFile(inputFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	projects, err := streamProjectRefs(inputFile, "acme/a", "acme/b")
+	if err != nil {
+		t.Fatalf("streamProjectRefs() unexpected error = %v", err)
+	}
+	if len(projects) != 2 || projects[0].total != 3 || projects[1].total != 2 {
+		t.Fatalf("streamProjectRefs() = %+v, want acme/a with 3 references and acme/b with 2", projects)
+	}
+
+	// acme/a stops after its first reference; acme/b still gets all of its own
+	for ref, err := range projects[0].refs {
+		if err != nil || ref.IID != 1 {
+			t.Fatalf("first reference of acme/a = %+v, %v", ref, err)
+		}
+		break
+	}
+	var iids []int
+	for ref, err := range projects[1].refs {
+		if err != nil {
+			t.Fatalf("reading acme/b: %v", err)
+		}
+		iids = append(iids, ref.IID)
+	}
+	if !reflect.DeepEqual(iids, []int{1, 2}) {
+		t.Errorf("acme/b references = %v, want [1 2]", iids)
+	}
+}
+
+func TestStreamProjectRefs_RequiresRepository(t *testing.T) {
+	inputFile := filepath.Join(t.TempDir(), "refs.csv")
+	if err := os.WriteFile(inputFile, []byte("1,aaaaaaa\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := streamProjectRefs(inputFile)
+	if err == nil || err.Error() != "--repository is required unless --input is a combined file" {
+		t.Errorf("streamProjectRefs() error = %v, want --repository to be required", err)
+	}
+
+	_, err = streamProjectRefs(inputFile, "group/a", "group/b")
+	if err == nil || !strings.Contains(err.Error(), "several --repository flags") {
+		t.Errorf("streamProjectRefs() error = %v, want several repositories to need a combined file", err)
+	}
+}
+
+func TestCreateBranchesOnGitHub(t *testing.T) {
+	api := newMockGitHubAPI()
+	api.refs["refs/heads/migration-pr-3"] = "old"
+
+	var refs []gitlab.MergeRequestRef
+	for i := 1; i <= 5; i++ {
+		refs = append(refs, gitlab.MergeRequestRef{IID: i, HeadSHA: fmt.Sprintf("sha%d", i)})
+	}
+
+	_, err := createBranchesOnGitHub(context.Background(), api, refSeq(refs), len(refs), "acme/project", "octo/repo", githubOptions{createOptions: createOptions{fetch: true}, batchSize: 2})
+	if err != nil {
+		t.Fatalf("createBranchesOnGitHub() unexpected error = %v", err)
+	}
+
+	// Five branches in batches of two take three requests
+	if fmt.Sprint(api.batches) != "[2 2 1]" {
+		t.Errorf("Batches = %v, want [2 2 1]", api.batches)
+	}
+	if api.refs["refs/heads/migration-pr-5"] != "sha5" {
+		t.Errorf("Expected migration-pr-5 to be created, got %v", api.refs)
+	}
+	if api.refs["refs/heads/migration-pr-3"] != "old" {
+		t.Error("Existing branch should not be changed")
+	}
+}
+
+func TestCreateBranchesOnGitHub_UpdateExisting(t *testing.T) {
+	api := newMockGitHubAPI()
+	api.refs["refs/heads/migration-pr-1"] = "sha1"
+	api.refs["refs/heads/migration-pr-2"] = "old"
+
+	var refs []gitlab.MergeRequestRef
+	for i := 1; i <= 3; i++ {
+		refs = append(refs, gitlab.MergeRequestRef{IID: i, HeadSHA: fmt.Sprintf("sha%d", i)})
+	}
+
+	counts, err := createBranchesOnGitHub(context.Background(), api, refSeq(refs), len(refs), "acme/project", "octo/repo", githubOptions{createOptions: createOptions{updateExisting: true, fetch: true}, batchSize: 50})
+	if err != nil {
+		t.Fatalf("createBranchesOnGitHub() unexpected error = %v", err)
+	}
+	if counts != (branchCounts{created: 1, updated: 1, unchanged: 1, total: 3}) {
+		t.Errorf("createBranchesOnGitHub() counts = %+v", counts)
+	}
+	if api.refs["refs/heads/migration-pr-2"] != "sha2" || api.refs["refs/heads/migration-pr-3"] != "sha3" {
+		t.Errorf("Refs = %v", api.refs)
+	}
+}
+
+func TestCreateBranchesOnGitHub_StopsOnUnauthorized(t *testing.T) {
+	api := newMockGitHubAPI()
+	api.createErr = github.ErrUnauthorized
+
+	refs := []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "a"}, {IID: 2, HeadSHA: "b"}, {IID: 3, HeadSHA: "c"}}
+	_, err := createBranchesOnGitHub(context.Background(), api, refSeq(refs), len(refs), "acme/project", "octo/repo", githubOptions{createOptions: createOptions{fetch: true}, batchSize: 2})
+	if !errors.Is(err, github.ErrUnauthorized) {
+		t.Fatalf("createBranchesOnGitHub() error = %v, want ErrUnauthorized", err)
+	}
+	if len(api.batches) != 1 {
+		t.Errorf("Expected to stop after the first batch, got %v", api.batches)
+	}
+}
+
+func TestCreateBranchesOnGitHub_SkipsMissingCommits(t *testing.T) {
+	api := newMockGitHubAPI()
+	api.missing["sha2"] = true
+	api.missing["sha3"] = true
+
+	refs := []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "sha1"}, {IID: 2, HeadSHA: "sha2"}, {IID: 3, HeadSHA: "sha3"}}
+	counts, err := createBranchesOnGitHub(context.Background(), api, refSeq(refs), len(refs), "acme/project", "octo/repo", githubOptions{createOptions: createOptions{fetch: true}, batchSize: 2})
+	if err != nil {
+		t.Fatalf("createBranchesOnGitHub() unexpected error = %v", err)
+	}
+	if counts != (branchCounts{created: 1, skipped: 2, total: 3}) {
+		t.Errorf("createBranchesOnGitHub() counts = %+v", counts)
+	}
+
+	// The second batch only has a missing commit, so no branches are requested for it
+	if fmt.Sprint(api.batches) != "[1]" {
+		t.Errorf("Batches = %v, want [1]", api.batches)
+	}
+	if len(api.refs) != 1 || api.refs["refs/heads/migration-pr-1"] != "sha1" {
+		t.Errorf("Expected only migration-pr-1 to be created, got %v", api.refs)
+	}
+}
+
+func TestGithubTargets(t *testing.T) {
+	mappingFile := filepath.Join(t.TempDir(), "mapping.csv")
+	if err := os.WriteFile(mappingFile, []byte("gitlab,github\nacme/a,octo-org/alpha\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	projects := []projectRefs{{project: "acme/a"}}
+
+	targets, err := githubTargets(projects, "", mappingFile)
+	if err != nil {
+		t.Fatalf("githubTargets() unexpected error = %v", err)
+	}
+	if targets["acme/a"] != "octo-org/alpha" {
+		t.Errorf("githubTargets() = %v", targets)
+	}
+
+	targets, err = githubTargets(projects, "octo-org/override", "")
+	if err != nil || targets["acme/a"] != "octo-org/override" {
+		t.Errorf("githubTargets() = %v, %v; want octo-org/override", targets, err)
+	}
+
+	// Every project must be mapped before anything is created
+	projects = append(projects, projectRefs{project: "acme/b"})
+	if _, err := githubTargets(projects, "", mappingFile); !errors.Is(err, mapping.ErrNotMapped) {
+		t.Errorf("githubTargets() error = %v, want ErrNotMapped", err)
+	}
+}
+
+func TestCreateBranchesOnGitHub_OpenIssue(t *testing.T) {
+	tests := []struct {
+		name               string
+		missing            []string
+		issueRepository    string
+		expectedRepository string
+		expectIssue        bool
+	}{
+		{
+			name:               "target repository",
+			missing:            []string{"sha2"},
+			expectedRepository: "octo/repo",
+			expectIssue:        true,
+		},
+		{
+			name:               "tracking repository",
+			missing:            []string{"sha2"},
+			issueRepository:    "octo/tracking",
+			expectedRepository: "octo/tracking",
+			expectIssue:        true,
+		},
+		{
+			name: "nothing failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newMockGitHubAPI()
+			for _, sha := range tt.missing {
+				api.missing[sha] = true
+			}
+
+			refs := []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "sha1"}, {IID: 2, HeadSHA: "sha2"}}
+			opts := githubOptions{createOptions: createOptions{fetch: true}, batchSize: 50, openIssue: true, issueRepository: tt.issueRepository}
+			if _, err := createBranchesOnGitHub(context.Background(), api, refSeq(refs), len(refs), "acme/project", "octo/repo", opts); err != nil {
+				t.Fatalf("createBranchesOnGitHub() unexpected error = %v", err)
+			}
+
+			if !tt.expectIssue {
+				if len(api.issues) != 0 {
+					t.Errorf("Expected no issue, got %v", api.issues)
+				}
+				return
+			}
+			if len(api.issues) != 1 {
+				t.Fatalf("Expected one issue, got %d", len(api.issues))
+			}
+			issue := api.issues[0]
+			if issue[0] != tt.expectedRepository {
+				t.Errorf("Issue repository = %q, want %q", issue[0], tt.expectedRepository)
+			}
+			for _, want := range []string{"acme/project", "| !2 | `migration-pr-2` | `sha2` | commit not found on GitHub |"} {
+				if !strings.Contains(issue[2], want) {
+					t.Errorf("Issue body should contain %q:\n%s", want, issue[2])
+				}
+			}
+			if strings.Contains(issue[2], "migration-pr-1`") {
+				t.Errorf("Issue body should not list created branches:\n%s", issue[2])
+			}
+		})
+	}
+}
+
+func TestMissingCommitReason(t *testing.T) {
+	if got := missingCommitReason(gitlab.MergeRequestRef{IID: 3}); got != "commit not found on GitHub" {
+		t.Errorf("missingCommitReason() = %q", got)
+	}
+	if got := missingCommitReason(gitlab.MergeRequestRef{IID: 3, Fork: true}); !strings.Contains(got, "from a fork") || !strings.Contains(got, "refs/merge-requests/3/head") {
+		t.Errorf("missingCommitReason() for a fork = %q, want it to name the merge request ref", got)
+	}
+}
+
+func TestValidateBranchPrefix(t *testing.T) {
+	tests := []struct {
+		prefix      string
+		expectError bool
+	}{
+		{"migration-pr-", false},
+		{"gitlab/mr-", false},
+		{"", true},
+		{"-mr-", true},
+		{"/mr-", true},
+		{"mr..", true},
+		{"mr pr-", true},
+		{"mr~", true},
+		{"mr:", true},
+		{"mr@{", true},
+		{"gitlab/.mr-", true},
+	}
+
+	for _, tt := range tests {
+		err := validateBranchPrefix(tt.prefix)
+		if tt.expectError && err == nil {
+			t.Errorf("validateBranchPrefix(%q) expected error, got nil", tt.prefix)
+		}
+		if !tt.expectError && err != nil {
+			t.Errorf("validateBranchPrefix(%q) unexpected error = %v", tt.prefix, err)
+		}
+	}
+}
+
+func TestCreateBranchesInRepo_RefHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	out := filepath.Join(t.TempDir(), "events.ndjson")
+	hooks := createHooks{ref: `cat >> "` + out + `"`}
+
+	api := newMockAPI()
+	api.failBranches["migration-pr-2"] = true
+	refs := []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: "aaaaaaa"},
+		{IID: 2, HeadSHA: "bbbbbbb"},
+	}
+
+	if _, err := createBranchesInRepo(context.Background(), api, refSeq(refs), len(refs), "target/project", createOptions{hooks: hooks, fetch: true}); err != nil {
+		t.Fatalf("createBranchesInRepo() unexpected error = %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("ref hook ran %d times, want 2: %q", len(lines), data)
+	}
+	var created, failed hook.RefEvent
+	if err := json.Unmarshal([]byte(lines[0]), &created); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &failed); err != nil {
+		t.Fatal(err)
+	}
+	if created != (hook.RefEvent{Event: hook.EventRef, Platform: "gitlab", Repository: "target/project", IID: 1, Branch: "migration-pr-1", SHA: "aaaaaaa", Status: hook.StatusCreated}) {
+		t.Errorf("first event = %+v", created)
+	}
+	if failed.IID != 2 || failed.Status != hook.StatusFailed || failed.Error == "" {
+		t.Errorf("second event = %+v, want a failure with its error", failed)
+	}
+}
+
+func TestPreCreateEvent(t *testing.T) {
+	projects := []projectRefs{{project: "acme/backend", total: 3}, {project: "acme/frontend", total: 2}}
+
+	event := preCreateEvent(projects, 5, map[string]string{"acme/backend": "octo/backend"}, "")
+	want := []hook.Project{
+		{Project: "acme/backend", Target: "octo/backend", Count: 3},
+		{Project: "acme/frontend", Target: "acme/frontend", Count: 2},
+	}
+	if event.Total != 5 || !slices.Equal(event.Projects, want) {
+		t.Errorf("preCreateEvent() = %+v, want projects %+v", event, want)
+	}
+}
+
+func TestBranchName_Naming(t *testing.T) {
+	ref := gitlab.MergeRequestRef{IID: 7, HeadSHA: "0123456789abcdef", SourceBranch: "feature/search"}
+	if got := (refNaming{preset: migrate.NamingPRAndSHA}).branchName(ref); got != "migration-pr-7-01234567" {
+		t.Errorf("branchName() = %q", got)
+	}
+	if got := (refNaming{prefix: "gl-"}).branchName(ref); got != "gl-7" {
+		t.Errorf("branchName() = %q", got)
+	}
+
+	naming := refNaming{preset: migrate.NamingSourceBranch}
+	if got := naming.branchName(ref); got != "migration-pr-feature/search" {
+		t.Errorf("branchName() = %q", got)
+	}
+	if err := naming.validate(false); err == nil {
+		t.Error("validate() expected an error for CSV input with source-branch naming")
+	}
+	if err := naming.validate(true); err != nil {
+		t.Errorf("validate() unexpected error = %v", err)
+	}
+}
+
+func TestCreateBranchesOnGitHub_Tags(t *testing.T) {
+	api := newMockGitHubAPI()
+
+	refs := []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "sha1"}, {IID: 2, HeadSHA: "sha2"}}
+	counts, err := createBranchesOnGitHub(context.Background(), api, refSeq(refs), len(refs), "acme/project", "octo/repo", githubOptions{createOptions: createOptions{fetch: true}, batchSize: 50, tags: true})
+	if err != nil {
+		t.Fatalf("createBranchesOnGitHub() unexpected error = %v", err)
+	}
+	if counts.created != 2 {
+		t.Errorf("createBranchesOnGitHub() counts = %+v", counts)
+	}
+	if api.refs["refs/tags/migration-pr-1"] != "sha1" || api.refs["refs/tags/migration-pr-2"] != "sha2" {
+		t.Errorf("Expected tags to be created, got %v", api.refs)
+	}
+	if _, ok := api.refs["refs/heads/migration-pr-1"]; ok {
+		t.Error("No branch should be created with tags")
+	}
+}
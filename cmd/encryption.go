@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+	"github.com/amenocal/gh-gl-create-refs/pkg/encrypt"
+	"github.com/spf13/cobra"
+)
+
+// addEncryptFlag registers --encrypt-recipient on a command that writes output files
+func addEncryptFlag(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("encrypt-recipient", nil, "Encrypt output files for this age public key (age1...) or GPG key ID or email; repeat for several recipients")
+}
+
+// encryptRecipients returns the recipients given with --encrypt-recipient
+func encryptRecipients(cmd *cobra.Command) []string {
+	recipients, _ := cmd.Flags().GetStringSlice("encrypt-recipient")
+	return recipients
+}
+
+// setupEncryption has every output file encrypted for the --encrypt-recipient recipients,
+// if any, failing before anything is fetched when age or gpg is missing
+func setupEncryption(cmd *cobra.Command) error {
+	recipients := encryptRecipients(cmd)
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	e, err := encrypt.New(recipients)
+	if err != nil {
+		return err
+	}
+	csv.SetEncrypter(e)
+	logger.Debug("encrypting output files", "tool", e.Tool(), "recipients", len(recipients))
+	return nil
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/estimate"
+	"github.com/amenocal/gh-gl-create-refs/pkg/github"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/spf13/cobra"
+)
+
+var estimateCmd = &cobra.Command{
+	Use:   "estimate",
+	Short: "Estimate the API requests and duration of fetching and creating branches",
+	Long: `Predict how many API requests fetch-refs followed by create-refs will make for a repository,
+group or manifest, and roughly how long the run will take, without changing anything.
+
+The merge request count of each project is read from GitLab's pagination headers (three
+read requests per project). Durations assume --latency per request, one request at a time
+per project and --concurrency projects fetched at once, all sharing the --rps and --burst
+rate limit; branch creation adds its --batch-pause pauses. Requests that depend on data,
+such as head lookups for merge requests from forks and retries, are not counted, so the
+numbers are lower bounds.
+
+Examples:
+  gh gl-create-refs estimate --repository group/project
+  gh gl-create-refs estimate --group acme --concurrency 8 --rps 5
+  gh gl-create-refs estimate -r group/project --all-versions --verify-sha
+  gh gl-create-refs estimate -r group/project --github --batch-size 100 --pause-every 100 --batch-pause 30s`,
+	Args: cobra.NoArgs,
+	RunE: runEstimate,
+}
+
+func init() {
+	rootCmd.AddCommand(estimateCmd)
+
+	addTokenFlags(estimateCmd)
+	estimateCmd.Flags().StringP("base-url", "b", "", "GitLab base URL (default: https://gitlab.com)")
+	estimateCmd.Flags().StringP("repository", "r", "", "GitLab repository path")
+	estimateCmd.Flags().StringP("group", "g", "", "GitLab group path: estimate every project in the group and its subgroups")
+	estimateCmd.Flags().StringSlice("include", nil, "Only estimate group projects whose relative path matches one of these glob patterns")
+	estimateCmd.Flags().StringSlice("exclude", nil, "Skip group projects whose relative path matches one of these glob patterns")
+	estimateCmd.Flags().StringP("manifest", "m", "", "File listing GitLab repository paths, one per line")
+	estimateCmd.Flags().IntP("concurrency", "c", 4, "Number of repositories fetch-refs fetches in parallel")
+	estimateCmd.Flags().Bool("all-versions", false, "Estimate fetch-refs --all-versions")
+	estimateCmd.Flags().Bool("verify-sha", false, "Estimate fetch-refs --verify-sha")
+	estimateCmd.Flags().Bool("github", false, "Estimate creating the branches on GitHub with --github-target or --mapping")
+	estimateCmd.Flags().Int("batch-size", github.DefaultBatchSize, "create-refs --batch-size")
+	estimateCmd.Flags().Duration("batch-pause", 0, "create-refs --batch-pause")
+	estimateCmd.Flags().Int("pause-every", defaultPauseEvery, "create-refs --pause-every")
+	estimateCmd.Flags().Duration("latency", estimate.DefaultLatency, "Assumed time of one API request")
+	estimateCmd.MarkFlagsOneRequired("repository", "group", "manifest")
+	estimateCmd.MarkFlagsMutuallyExclusive("repository", "group", "manifest")
+	addProjectCompletion(estimateCmd, "repository")
+}
+
+func runEstimate(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	repository := cmd.Flag("repository").Value.String()
+	group := cmd.Flag("group").Value.String()
+	include, _ := cmd.Flags().GetStringSlice("include")
+	exclude, _ := cmd.Flags().GetStringSlice("exclude")
+	manifest := cmd.Flag("manifest").Value.String()
+	baseURL := cmd.Flag("base-url").Value.String()
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	allVersions, _ := cmd.Flags().GetBool("all-versions")
+	verifySHA, _ := cmd.Flags().GetBool("verify-sha")
+	onGitHub, _ := cmd.Flags().GetBool("github")
+	batchSize, _ := cmd.Flags().GetInt("batch-size")
+	batchPause, _ := cmd.Flags().GetDuration("batch-pause")
+	pauseEvery, _ := cmd.Flags().GetInt("pause-every")
+	latency, _ := cmd.Flags().GetDuration("latency")
+	rps, _ := rootCmd.PersistentFlags().GetFloat64("rps")
+	burst, _ := rootCmd.PersistentFlags().GetInt("burst")
+
+	if batchSize < 1 || pauseEvery < 1 {
+		return fmt.Errorf("--batch-size and --pause-every must be at least 1")
+	}
+	if batchPause < 0 || latency < 0 {
+		return fmt.Errorf("--batch-pause and --latency must not be negative")
+	}
+	if (len(include) > 0 || len(exclude) > 0) && group == "" {
+		return fmt.Errorf("--include and --exclude can only be used with --group")
+	}
+
+	client, err := newGitLabClient(cmd, baseURL)
+	if err != nil {
+		return err
+	}
+
+	repositories := []string{repository}
+	if repository == "" {
+		repositories, err = resolveProjects(ctx, client, group, manifest, gitlab.ProjectFilter{Include: include, Exclude: exclude})
+		if err != nil {
+			return err
+		}
+	}
+
+	run := estimate.Run{
+		PerPage:           gitlab.NewFetchOptions().PerPage,
+		Detail:            true,
+		AllVersions:       allVersions,
+		VerifySHA:         verifySHA,
+		GitHub:            onGitHub,
+		BatchSize:         batchSize,
+		BatchPause:        batchPause,
+		PauseEvery:        pauseEvery,
+		Concurrency:       concurrency,
+		RequestsPerSecond: rps,
+		Burst:             burst,
+		Latency:           latency,
+	}
+
+	rows, err := estimateProjects(ctx, client, repositories, &run)
+	if err != nil {
+		return err
+	}
+
+	e := run.Estimate()
+	writeTable(os.Stdout, []string{"PROJECT", "MERGE REQUESTS", "FETCH REQUESTS", "CREATE REQUESTS"}, rows)
+	creator := "GitLab"
+	if onGitHub {
+		creator = "GitHub"
+	}
+	fmt.Printf("\nFetch:  %d GitLab API requests, about %s\n", e.FetchRequests, formatDuration(e.FetchDuration))
+	fmt.Printf("Create: %d %s API requests, about %s\n", e.CreateRequests, creator, formatDuration(e.CreateDuration))
+	fmt.Printf("Total:  about %s\n", formatDuration(e.Total()))
+	return nil
+}
+
+// estimateProjects adds the merge request count of each repository to run and returns a
+// table row per repository. Projects whose count GitLab doesn't report are left out with a
+// warning.
+func estimateProjects(ctx context.Context, client gitlab.API, repositories []string, run *estimate.Run) ([][]string, error) {
+	var rows [][]string
+	for _, repository := range repositories {
+		if err := interrupted(ctx); err != nil {
+			return nil, err
+		}
+
+		_, projectPath, err := gitlab.ParseRepoPath(repository)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse repository path: %w", err)
+		}
+		info, err := client.GetProjectInfo(ctx, projectPath)
+		if err != nil {
+			return nil, err
+		}
+
+		n := info.TotalMergeRequests
+		if n < 0 {
+			logger.Warn("GitLab does not report the merge request count of this project, leaving it out of the estimate", "project", info.Path)
+			rows = append(rows, []string{info.Path, "unknown", "-", "-"})
+			continue
+		}
+
+		run.MergeRequests = append(run.MergeRequests, n)
+		rows = append(rows, []string{info.Path, strconv.Itoa(n), strconv.Itoa(run.FetchRequests(n)), strconv.Itoa(run.CreateRequests(n))})
+	}
+	return rows, nil
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/estimate"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+func TestEstimateProjects(t *testing.T) {
+	api := newMockAPI()
+	api.projects["acme/backend"] = &gitlab.ProjectInfo{Path: "acme/backend", TotalMergeRequests: 250}
+	api.projects["acme/huge"] = &gitlab.ProjectInfo{Path: "acme/huge", TotalMergeRequests: -1}
+
+	run := estimate.Run{PerPage: 100, Detail: true, BatchSize: 50}
+	rows, err := estimateProjects(context.Background(), api, []string{"acme/backend", "acme/huge"}, &run)
+	if err != nil {
+		t.Fatalf("estimateProjects() unexpected error = %v", err)
+	}
+
+	if !slices.Equal(run.MergeRequests, []int{250}) {
+		t.Errorf("MergeRequests = %v, want the known count only", run.MergeRequests)
+	}
+	expected := [][]string{
+		{"acme/backend", "250", "256", "250"},
+		{"acme/huge", "unknown", "-", "-"},
+	}
+	if !slices.EqualFunc(rows, expected, slices.Equal) {
+		t.Errorf("rows = %v, want %v", rows, expected)
+	}
+}
+
+func TestEstimateProjects_NotFound(t *testing.T) {
+	run := estimate.Run{PerPage: 100}
+	if _, err := estimateProjects(context.Background(), newMockAPI(), []string{"acme/missing"}, &run); err == nil {
+		t.Error("Expected an error for a missing project")
+	}
+}
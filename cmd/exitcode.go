@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/auth"
+	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+	"github.com/amenocal/gh-gl-create-refs/pkg/github"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/lock"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of the commands, so scripts can branch on the class of failure. They are part
+// of the interface: don't renumber them.
+const (
+	exitOK = 0
+	// exitError is any failure not covered by a more specific code
+	exitError = 1
+	// exitUsage means the command line could not be parsed
+	exitUsage = 2
+	// exitAuth means a token is missing, invalid, expired or lacks access
+	exitAuth = 3
+	// exitNotFound means a project, repository or issue does not exist or is not visible
+	exitNotFound = 4
+	// exitRateLimited means the run was aborted by an API rate limit
+	exitRateLimited = 5
+	// exitPartialFailure means the run completed but some branches, projects or
+	// repositories failed
+	exitPartialFailure = 6
+	// exitValidation means an input file failed validation
+	exitValidation = 7
+	// exitLocked means another run is writing the same output or creating branches in the
+	// same repository
+	exitLocked = 8
+	// exitBudgetExhausted means the run stopped after using the requests --max-api-calls allows
+	exitBudgetExhausted = 9
+	// exitInterrupted means the run was stopped by Ctrl+C or SIGTERM, following the shell
+	// convention of 128 plus the signal number
+	exitInterrupted = 130
+)
+
+// exitCodeError attaches an exit code to an error without changing its message
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitCodeError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode makes the process exit with code when err ends the command
+func withExitCode(code int, err error) error {
+	return &exitCodeError{code: code, err: err}
+}
+
+// exitCode returns the exit code for the error a command ended with. The causes wrapped in
+// err take precedence over a code attached with withExitCode, so that a run stopped by a
+// rate limit exits with exitRateLimited even though it also partially failed.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errInterrupted):
+		return exitInterrupted
+	case errors.Is(err, gitlab.ErrBudgetExhausted):
+		return exitBudgetExhausted
+	case errors.Is(err, gitlab.ErrRateLimited), errors.Is(err, github.ErrRateLimited):
+		return exitRateLimited
+	case errors.Is(err, gitlab.ErrUnauthorized), errors.Is(err, gitlab.ErrInsufficientAccess), errors.Is(err, github.ErrUnauthorized), errors.Is(err, auth.ErrNoGitHubToken):
+		return exitAuth
+	case errors.Is(err, gitlab.ErrProjectNotFound), errors.Is(err, gitlab.ErrProjectMoved), errors.Is(err, github.ErrRepositoryNotFound), errors.Is(err, github.ErrIssueNotFound):
+		return exitNotFound
+	case errors.Is(err, csv.ErrChecksumMismatch), errors.Is(err, csv.ErrNoProject), errors.Is(err, csv.ErrDuplicateIID), errors.Is(err, csv.ErrInvalidSHA):
+		return exitValidation
+	case errors.Is(err, lock.ErrLocked):
+		return exitLocked
+	}
+
+	var codeErr *exitCodeError
+	if errors.As(err, &codeErr) {
+		return codeErr.code
+	}
+	return exitError
+}
+
+// flagError marks errors parsing the command line as usage errors
+func flagError(cmd *cobra.Command, err error) error {
+	return withExitCode(exitUsage, err)
+}
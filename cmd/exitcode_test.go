@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/auth"
+	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+	"github.com/amenocal/gh-gl-create-refs/pkg/github"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/lock"
+	"github.com/spf13/cobra"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"success", nil, exitOK},
+		{"unclassified", errors.New("boom"), exitError},
+		{"gitlab unauthorized", fmt.Errorf("failed to get project: %w", gitlab.ErrUnauthorized), exitAuth},
+		{"github unauthorized", github.ErrUnauthorized, exitAuth},
+		{"no github token", auth.ErrNoGitHubToken, exitAuth},
+		{"project not found", fmt.Errorf("failed to get project: %w", gitlab.ErrProjectNotFound), exitNotFound},
+		{"github repository not found", github.ErrRepositoryNotFound, exitNotFound},
+		{"issue not found", github.ErrIssueNotFound, exitNotFound},
+		{"gitlab rate limited", gitlab.ErrRateLimited, exitRateLimited},
+		{"github rate limited", github.ErrRateLimited, exitRateLimited},
+		{"checksum mismatch", fmt.Errorf("refs.csv: %w", csv.ErrChecksumMismatch), exitValidation},
+		{"duplicate IID", fmt.Errorf("refs.csv: %w", csv.ErrDuplicateIID), exitValidation},
+		{"invalid SHA", fmt.Errorf("refs.csv: %w", csv.ErrInvalidSHA), exitValidation},
+		{"locked", fmt.Errorf("another run is writing refs.csv: %w", lock.ErrLocked), exitLocked},
+		{"budget exhausted", fmt.Errorf("stopped after 3 of 5 branches: %w", gitlab.ErrBudgetExhausted), exitBudgetExhausted},
+		{"partial failure", withExitCode(exitPartialFailure, errors.New("2 of 5 projects failed")), exitPartialFailure},
+		{
+			"rate limit takes precedence over partial failure",
+			withExitCode(exitPartialFailure, fmt.Errorf("stopped after 3 of 5 branches: %w", gitlab.ErrRateLimited)),
+			exitRateLimited,
+		},
+		{"wrapped exit code", fmt.Errorf("run failed: %w", withExitCode(exitValidation, errors.New("bad rows"))), exitValidation},
+		{"flag error", flagError(&cobra.Command{}, errors.New("unknown flag: --nope")), exitUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.expected {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWithExitCode_KeepsMessage(t *testing.T) {
+	cause := errors.New("2 of 5 projects failed")
+	err := withExitCode(exitPartialFailure, cause)
+
+	if err.Error() != cause.Error() {
+		t.Errorf("Error() = %q, want %q", err.Error(), cause.Error())
+	}
+	if !errors.Is(err, cause) {
+		t.Error("withExitCode() should wrap the cause")
+	}
+}
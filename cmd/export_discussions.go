@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/export"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var exportDiscussionsCmd = &cobra.Command{
+	Use:   "export-discussions",
+	Short: "Export the discussion threads of merge requests to NDJSON",
+	Long: `Export the discussion threads of every merge request of a GitLab repository to a
+newline-delimited JSON file, one thread per line, so the comments can later be replayed
+onto the GitHub pull requests created from the migration branches.
+
+Each line holds the project, the merge request IID, the discussion ID and the thread's
+notes in order, with their author's username and display name, body, creation and update
+times and resolution state. Comments on the diff also carry their position: the diff
+version's base, start and head SHAs and the old and new path and line.
+
+Notes GitLab writes itself, such as "added 1 commit" or "changed the description", are left
+out unless --include-system-notes is set.
+
+--anonymize prepares an export to be shared outside the organization: usernames are replaced
+with pseudonyms and display names and note bodies are dropped, while IIDs, SHAs and diff
+positions are kept. Pass the same --anonymize-key to export-reviewers to give each person
+the same pseudonym in both exports.
+
+Examples:
+  gh gl-create-refs export-discussions --repository group/project
+  gh gl-create-refs export-discussions -r group/project -o discussions.ndjson
+  gh gl-create-refs export-discussions -r group/project --include-system-notes
+  gh gl-create-refs export-discussions -r group/project --anonymize`,
+	Args: cobra.NoArgs,
+	RunE: runExportDiscussions,
+}
+
+func init() {
+	rootCmd.AddCommand(exportDiscussionsCmd)
+
+	addTokenFlags(exportDiscussionsCmd)
+	exportDiscussionsCmd.Flags().StringP("base-url", "b", "", "GitLab base URL (default: https://gitlab.com)")
+	exportDiscussionsCmd.Flags().StringP("repository", "r", "", "GitLab repository path (required)")
+	exportDiscussionsCmd.Flags().StringP("output", "o", "", "Output NDJSON file path or s3://, gs:// or azblob:// URL (default: <group-project>-discussions.ndjson)")
+	exportDiscussionsCmd.Flags().Bool("include-system-notes", false, "Also export the notes GitLab writes itself, such as \"added 1 commit\"")
+	addAnonymizeFlags(exportDiscussionsCmd)
+	addEncryptFlag(exportDiscussionsCmd)
+	exportDiscussionsCmd.MarkFlagRequired("repository")
+	addProjectCompletion(exportDiscussionsCmd, "repository")
+}
+
+func runExportDiscussions(cmd *cobra.Command, args []string) error {
+	repository := cmd.Flag("repository").Value.String()
+	baseURL := cmd.Flag("base-url").Value.String()
+	outputPath := cmd.Flag("output").Value.String()
+	includeSystem, _ := cmd.Flags().GetBool("include-system-notes")
+
+	if outputPath == "" {
+		outputPath = export.Filename(repository, "discussions")
+	}
+
+	anonymizer, err := anonymizerFlag(cmd)
+	if err != nil {
+		return err
+	}
+	if err := setupEncryption(cmd); err != nil {
+		return err
+	}
+
+	// Where the export ends up, reported once it is written
+	destination := outputPath
+	var remote *remoteOutput
+	if output.IsRemote(outputPath) {
+		if outputPath, remote, err = stageRemoteOutput(outputPath, false); err != nil {
+			return err
+		}
+		defer remote.cleanup()
+	}
+
+	client, err := newGitLabClient(cmd, baseURL)
+	if err != nil {
+		return err
+	}
+
+	counts, err := exportDiscussions(cmd.Context(), client, repository, outputPath, includeSystem, anonymizer)
+	if err != nil {
+		return err
+	}
+	if remote != nil {
+		if _, err := remote.upload(cmd.Context()); err != nil {
+			return err
+		}
+	} else {
+		destination, _ = filepath.Abs(outputPath)
+	}
+
+	statusf("%s %d discussions of %d merge requests to: %s\n", green("Successfully exported"), counts.discussions, counts.mergeRequests, destination)
+	return nil
+}
+
+// discussionCounts counts what an export wrote
+type discussionCounts struct {
+	mergeRequests int
+	discussions   int
+}
+
+// exportDiscussions writes the discussions of every merge request of repository to
+// outputPath, without system notes unless includeSystem is set and anonymized by anonymizer
+// when it is non-nil
+func exportDiscussions(ctx context.Context, client gitlab.API, repository, outputPath string, includeSystem bool, anonymizer *export.Anonymizer) (discussionCounts, error) {
+	var counts discussionCounts
+
+	_, projectPath, err := gitlab.ParseRepoPath(repository)
+	if err != nil {
+		return counts, fmt.Errorf("failed to parse repository path: %w", err)
+	}
+
+	l, err := lockOutput(outputPath)
+	if err != nil {
+		return counts, err
+	}
+	defer releaseLock(l)
+
+	w, err := export.Create(outputPath)
+	if err != nil {
+		return counts, err
+	}
+	defer w.Abort()
+
+	// Only the IIDs are needed, and merge requests without a head commit have discussions too
+	for mr, err := range client.MergeRequests(ctx, projectPath) {
+		if err != nil {
+			return counts, err
+		}
+		if err := interrupted(ctx); err != nil {
+			return counts, err
+		}
+
+		discussions, err := client.MergeRequestDiscussions(ctx, projectPath, mr.IID)
+		if err != nil {
+			return counts, err
+		}
+		if !includeSystem {
+			discussions = export.WithoutSystemNotes(discussions)
+		}
+
+		for _, d := range discussions {
+			if err := w.Write(export.DiscussionRecord{Project: projectPath, IID: mr.IID, Discussion: anonymizer.Discussion(d)}); err != nil {
+				return counts, err
+			}
+		}
+		counts.mergeRequests++
+		counts.discussions += len(discussions)
+
+		if counts.mergeRequests%progressInterval == 0 {
+			logger.Info("exporting discussions", "project", projectPath, "merge_requests", counts.mergeRequests, "discussions", counts.discussions)
+		}
+	}
+
+	return counts, w.Close()
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/export"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+func TestExportDiscussions(t *testing.T) {
+	api := newMockAPI()
+	api.refs["group/project"] = []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaaaaaa"}, {IID: 2, HeadSHA: "bbbbbbb"}}
+	api.discussions = map[string]map[int][]gitlab.Discussion{
+		"group/project": {
+			1: {
+				{ID: "d1", Notes: []gitlab.Note{{ID: 1, Author: "alice", Body: "Please rename"}, {ID: 2, Author: "bob", Body: "Done"}}},
+				{ID: "d2", IndividualNote: true, Notes: []gitlab.Note{{ID: 3, Body: "added 1 commit", System: true}}},
+			},
+		},
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "discussions.ndjson")
+	counts, err := exportDiscussions(context.Background(), api, "group/project", outputPath, false, nil)
+	if err != nil {
+		t.Fatalf("exportDiscussions() unexpected error = %v", err)
+	}
+	if counts.mergeRequests != 2 || counts.discussions != 1 {
+		t.Errorf("counts = %+v, want 2 merge requests and 1 discussion", counts)
+	}
+
+	file, err := os.Open(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var records []export.DiscussionRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record export.DiscussionRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Invalid line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %+v", records)
+	}
+	if r := records[0]; r.Project != "group/project" || r.IID != 1 || r.ID != "d1" || len(r.Notes) != 2 || r.Notes[0].Author != "alice" {
+		t.Errorf("Record = %+v", r)
+	}
+}
+
+func TestExportDiscussions_IncludeSystemNotes(t *testing.T) {
+	api := newMockAPI()
+	api.refs["group/project"] = []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaaaaaa"}}
+	api.discussions = map[string]map[int][]gitlab.Discussion{
+		"group/project": {1: {{ID: "d1", Notes: []gitlab.Note{{ID: 1, Body: "added 1 commit", System: true}}}}},
+	}
+
+	counts, err := exportDiscussions(context.Background(), api, "group/project", filepath.Join(t.TempDir(), "discussions.ndjson"), true, nil)
+	if err != nil {
+		t.Fatalf("exportDiscussions() unexpected error = %v", err)
+	}
+	if counts.discussions != 1 {
+		t.Errorf("Expected the system note's discussion to be exported, got %+v", counts)
+	}
+}
+
+func TestExportDiscussions_WithoutHeadSHA(t *testing.T) {
+	api := newMockAPI()
+	// A merge request whose source branch is gone has no head SHA, but keeps its discussions
+	api.refs["group/project"] = []gitlab.MergeRequestRef{{IID: 1}}
+	api.discussions = map[string]map[int][]gitlab.Discussion{
+		"group/project": {1: {{ID: "d1", Notes: []gitlab.Note{{ID: 1, Author: "alice", Body: "LGTM"}}}}},
+	}
+
+	counts, err := exportDiscussions(context.Background(), api, "group/project", filepath.Join(t.TempDir(), "discussions.ndjson"), false, nil)
+	if err != nil {
+		t.Fatalf("exportDiscussions() unexpected error = %v", err)
+	}
+	if counts.mergeRequests != 1 || counts.discussions != 1 {
+		t.Errorf("counts = %+v, want the merge request without a head SHA exported", counts)
+	}
+}
+
+func TestExportDiscussions_Anonymize(t *testing.T) {
+	api := newMockAPI()
+	api.refs["group/project"] = []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaaaaaa"}}
+	api.discussions = map[string]map[int][]gitlab.Discussion{
+		"group/project": {
+			1: {{ID: "d1", Notes: []gitlab.Note{{ID: 1, Author: "alice", AuthorName: "Alice Liddell", Body: "Please rename",
+				Position: &gitlab.NotePosition{HeadSHA: "aaaaaaa", NewPath: "main.go", NewLine: 3}}}}},
+		},
+	}
+	anonymizer, err := export.NewAnonymizer("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "discussions.ndjson")
+	if _, err := exportDiscussions(context.Background(), api, "group/project", outputPath, false, anonymizer); err != nil {
+		t.Fatalf("exportDiscussions() unexpected error = %v", err)
+	}
+
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, personal := range []string{"alice", "Alice Liddell", "Please rename"} {
+		if strings.Contains(string(data), personal) {
+			t.Errorf("Output %s contains %q", data, personal)
+		}
+	}
+	for _, kept := range []string{`"iid":1`, "aaaaaaa", anonymizer.Username("alice")} {
+		if !strings.Contains(string(data), kept) {
+			t.Errorf("Output %s is missing %q", data, kept)
+		}
+	}
+}
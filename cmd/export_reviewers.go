@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/export"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/mapping"
+	"github.com/amenocal/gh-gl-create-refs/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var exportReviewersCmd = &cobra.Command{
+	Use:   "export-reviewers",
+	Short: "Export the assignees and reviewers of merge requests to NDJSON",
+	Long: `Export the assignees and reviewers of every merge request of a GitLab repository to a
+newline-delimited JSON file, one merge request per line, so reviews can be requested again
+on the GitHub pull requests created from the migration branches.
+
+People are listed by GitLab username. With --user-mapping, a CSV or YAML file mapping GitLab
+usernames to GitHub logins (laid out like the --mapping file of create-refs), each person
+also carries their GitHub login, and the users the file does not list are reported at the end.
+
+--anonymize replaces usernames with pseudonyms and drops GitHub logins, so the export can be
+shared outside the organization; see export-discussions.
+
+Examples:
+  gh gl-create-refs export-reviewers --repository group/project
+  gh gl-create-refs export-reviewers -r group/project --user-mapping users.csv -o reviewers.ndjson
+  gh gl-create-refs export-reviewers -r group/project --anonymize --anonymize-key "$KEY"`,
+	Args: cobra.NoArgs,
+	RunE: runExportReviewers,
+}
+
+func init() {
+	rootCmd.AddCommand(exportReviewersCmd)
+
+	addTokenFlags(exportReviewersCmd)
+	exportReviewersCmd.Flags().StringP("base-url", "b", "", "GitLab base URL (default: https://gitlab.com)")
+	exportReviewersCmd.Flags().StringP("repository", "r", "", "GitLab repository path (required)")
+	exportReviewersCmd.Flags().StringP("output", "o", "", "Output NDJSON file path or s3://, gs:// or azblob:// URL (default: <group-project>-reviewers.ndjson)")
+	exportReviewersCmd.Flags().String("user-mapping", "", "CSV or YAML file mapping GitLab usernames to GitHub logins")
+	addAnonymizeFlags(exportReviewersCmd)
+	addEncryptFlag(exportReviewersCmd)
+	exportReviewersCmd.MarkFlagRequired("repository")
+	addProjectCompletion(exportReviewersCmd, "repository")
+}
+
+func runExportReviewers(cmd *cobra.Command, args []string) error {
+	repository := cmd.Flag("repository").Value.String()
+	baseURL := cmd.Flag("base-url").Value.String()
+	outputPath := cmd.Flag("output").Value.String()
+	userMapping := cmd.Flag("user-mapping").Value.String()
+
+	if outputPath == "" {
+		outputPath = export.Filename(repository, "reviewers")
+	}
+
+	var users mapping.Users
+	if userMapping != "" {
+		var err error
+		if users, err = mapping.ReadUsersFile(userMapping); err != nil {
+			return err
+		}
+	}
+
+	anonymizer, err := anonymizerFlag(cmd)
+	if err != nil {
+		return err
+	}
+	if err := setupEncryption(cmd); err != nil {
+		return err
+	}
+
+	// Where the export ends up, reported once it is written
+	destination := outputPath
+	var remote *remoteOutput
+	if output.IsRemote(outputPath) {
+		if outputPath, remote, err = stageRemoteOutput(outputPath, false); err != nil {
+			return err
+		}
+		defer remote.cleanup()
+	}
+
+	client, err := newGitLabClient(cmd, baseURL)
+	if err != nil {
+		return err
+	}
+
+	count, unmapped, err := exportReviewers(cmd.Context(), client, repository, outputPath, users, anonymizer)
+	if err != nil {
+		return err
+	}
+	if remote != nil {
+		if _, err := remote.upload(cmd.Context()); err != nil {
+			return err
+		}
+	} else {
+		destination, _ = filepath.Abs(outputPath)
+	}
+
+	statusf("%s assignees and reviewers of %d merge requests to: %s\n", green("Successfully exported"), count, destination)
+	if len(unmapped) > 0 {
+		logger.Warn("users not in the user mapping", "count", len(unmapped), "users", unmapped)
+	}
+	return nil
+}
+
+// exportReviewers writes the assignees and reviewers of every merge request of repository
+// to outputPath, translated with users and anonymized by anonymizer when they are non-nil. It
+// returns the number of merge requests and the sorted usernames users does not list.
+func exportReviewers(ctx context.Context, client gitlab.API, repository, outputPath string, users mapping.Users, anonymizer *export.Anonymizer) (int, []string, error) {
+	_, projectPath, err := gitlab.ParseRepoPath(repository)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to parse repository path: %w", err)
+	}
+
+	l, err := lockOutput(outputPath)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer releaseLock(l)
+
+	w, err := export.Create(outputPath)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer w.Abort()
+
+	var unmapped []string
+	// The list response carries assignees and reviewers, including for merge requests
+	// without a head commit
+	for mr, err := range client.MergeRequests(ctx, projectPath) {
+		if err != nil {
+			return 0, nil, err
+		}
+		if err := interrupted(ctx); err != nil {
+			return 0, nil, err
+		}
+
+		record, missing := export.NewReviewersRecord(projectPath, mr, users)
+		if err := w.Write(anonymizer.Reviewers(record)); err != nil {
+			return 0, nil, err
+		}
+		unmapped = append(unmapped, missing...)
+	}
+
+	if err := w.Close(); err != nil {
+		return 0, nil, err
+	}
+
+	slices.Sort(unmapped)
+	return w.Count(), slices.Compact(unmapped), nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/mapping"
+	gogitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestExportReviewers(t *testing.T) {
+	api := newMockAPI()
+	api.refs["group/project"] = []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: "aaaaaaa", MergeRequest: &gogitlab.MergeRequest{BasicMergeRequest: gogitlab.BasicMergeRequest{
+			Assignees: []*gogitlab.BasicUser{{Username: "alice"}},
+			Reviewers: []*gogitlab.BasicUser{{Username: "bob"}, {Username: "carol"}},
+		}}},
+		// Without a head SHA, as when the source branch was deleted, but still exported
+		{IID: 2, MergeRequest: &gogitlab.MergeRequest{BasicMergeRequest: gogitlab.BasicMergeRequest{
+			Reviewers: []*gogitlab.BasicUser{{Username: "carol"}},
+		}}},
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "reviewers.ndjson")
+	count, unmapped, err := exportReviewers(context.Background(), api, "group/project", outputPath, mapping.Users{"alice": "alice-gh", "bob": "bobby"}, nil)
+	if err != nil {
+		t.Fatalf("exportReviewers() unexpected error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+	if !slices.Equal(unmapped, []string{"carol"}) {
+		t.Errorf("unmapped = %v, want carol once", unmapped)
+	}
+
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	expected := `{"project":"group/project","iid":1,"assignees":[{"gitlab":"alice","github":"alice-gh"}],"reviewers":[{"gitlab":"bob","github":"bobby"},{"gitlab":"carol"}]}`
+	if len(lines) != 2 || lines[0] != expected {
+		t.Errorf("Output = %s, want first line %s", data, expected)
+	}
+}
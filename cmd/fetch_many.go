@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/batch"
+	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/migrate"
+	"github.com/amenocal/gh-gl-create-refs/pkg/output"
+	"github.com/amenocal/gh-gl-create-refs/pkg/queue"
+	"github.com/amenocal/gh-gl-create-refs/pkg/state"
+)
+
+// summaryFilename is the combined summary written alongside per-repository outputs
+const summaryFilename = "fetch-summary.csv"
+
+// combinedFilename is the default output file of a combined fetch
+const combinedFilename = "combined-refs.csv"
+
+// resolveProjects returns the repositories selected by --group or --manifest
+func resolveProjects(ctx context.Context, client gitlab.API, group, manifest string, filter gitlab.ProjectFilter) ([]string, error) {
+	if manifest != "" {
+		logger.Info("reading repositories from manifest", "file", manifest)
+		return batch.ReadManifest(manifest)
+	}
+
+	_, groupPath, err := gitlab.ParseGroupPath(group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse group path: %w", err)
+	}
+
+	logger.Info("listing group projects", "group", groupPath)
+
+	return client.ListGroupProjects(ctx, groupPath, filter)
+}
+
+// fetchManyRefs fetches each repository into its own CSV file in outputDir, or at the path
+// given by outputDir when it is a template, running up to concurrency fetches at once,
+// and writes a combined summary
+func fetchManyRefs(ctx context.Context, client gitlab.API, store *state.Store, repositories []string, baseURL, outputDir string, concurrency int, format outputFormat) (batch.Summary, error) {
+	if len(repositories) == 0 {
+		logger.Info("no matching repositories found")
+		return batch.Summary{}, nil
+	}
+
+	logger.Info("fetching repositories", "count", len(repositories), "concurrency", concurrency)
+
+	// All repositories of a run share the date and time in templated paths
+	startedAt := time.Now()
+	summaryDir := outputDir
+	if output.IsTemplate(outputDir) {
+		summaryDir = output.TemplateDir(outputDir)
+	}
+
+	if summaryDir != "" {
+		if err := os.MkdirAll(summaryDir, 0755); err != nil {
+			return batch.Summary{}, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	throughput := newProgress("repository throughput", len(repositories))
+	runStats.repositoriesQueued.Store(int64(len(repositories)))
+	defer runStats.repositoriesQueued.Store(0)
+
+	results := batch.Run(repositories, concurrency, func(repository string) batch.Result {
+		runStats.repositoriesQueued.Add(-1)
+		defer throughput.add(1)
+
+		// Don't start new repositories once the run has been cancelled
+		if err := interrupted(ctx); err != nil {
+			return batch.Result{Err: err}
+		}
+
+		outputPath, err := outputPathFor(outputDir, repository, true, format, startedAt)
+		if err != nil {
+			logger.Error("failed to fetch repository", "project", repository, "error", err)
+			return batch.Result{Err: err}
+		}
+
+		refCount, _, err := fetchRepository(ctx, client, store, repository, baseURL, outputPath, format)
+		if err != nil {
+			logger.Error("failed to fetch repository", "project", repository, "error", err)
+			return batch.Result{Err: err}
+		}
+
+		logger.Info("fetched repository", "project", repository, "count", refCount, "output", outputPath)
+		return batch.Result{Refs: refCount, Output: outputPath}
+	})
+
+	return writeFetchSummary(results, filepath.Join(summaryDir, summaryFilename), format)
+}
+
+// fetchCombinedRefs fetches each repository into a single combined CSV file at outputPath,
+// running up to concurrency fetches at once, and writes a summary next to it. The rows of
+// each repository are kept together; repositories that fail are left out of the file. Each
+// repository is fetched into its own temporary queue and copied into the file once it is
+// complete, so the references of a run are not held in memory.
+func fetchCombinedRefs(ctx context.Context, client gitlab.API, repositories []string, baseURL, outputPath string, concurrency int, format outputFormat) (batch.Summary, error) {
+	if len(repositories) == 0 {
+		logger.Info("no matching repositories found")
+		return batch.Summary{}, nil
+	}
+
+	if outputPath == "" {
+		outputPath = combinedFilename
+	}
+
+	logger.Info("fetching repositories", "count", len(repositories), "concurrency", concurrency, "output", outputPath)
+
+	l, err := lockOutput(outputPath)
+	if err != nil {
+		return batch.Summary{}, err
+	}
+	defer releaseLock(l)
+
+	writer, err := csv.NewCombinedStreamWriter(outputPath, output.CSVOptions(format.Options)...)
+	if err != nil {
+		return batch.Summary{}, err
+	}
+	defer writer.Abort()
+
+	// Repositories are fetched in parallel but written one at a time
+	var mu sync.Mutex
+
+	throughput := newProgress("repository throughput", len(repositories))
+	runStats.repositoriesQueued.Store(int64(len(repositories)))
+	defer runStats.repositoriesQueued.Store(0)
+
+	results := batch.Run(repositories, concurrency, func(repository string) batch.Result {
+		runStats.repositoriesQueued.Add(-1)
+		defer throughput.add(1)
+
+		// Don't start new repositories once the run has been cancelled
+		if err := interrupted(ctx); err != nil {
+			return batch.Result{Err: err}
+		}
+
+		_, projectPath, err := gitlab.ParseRepoPath(repository)
+		if err != nil {
+			logger.Error("failed to fetch repository", "project", repository, "error", err)
+			return batch.Result{Err: fmt.Errorf("failed to parse repository path: %w", err)}
+		}
+
+		// The combined file records a renamed or transferred project at its new path
+		projectPath, err = followMovedProject(ctx, client, projectPath)
+		if err != nil {
+			logger.Error("failed to fetch repository", "project", repository, "error", err)
+			return batch.Result{Err: err}
+		}
+
+		spool, err := spoolProjectRefs(ctx, client, projectPath, format)
+		if err != nil {
+			logger.Error("failed to fetch repository", "project", repository, "error", err)
+			return batch.Result{Err: err}
+		}
+		defer closeRefQueue(spool, false)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		for ref, err := range spool.Items() {
+			if err != nil {
+				return batch.Result{Err: err}
+			}
+			if err := writer.WriteProjectRef(csv.ProjectRef{Project: projectPath, MergeRequestRef: ref}); err != nil {
+				return batch.Result{Err: err}
+			}
+		}
+
+		return batch.Result{Refs: spool.Len(), Output: outputPath}
+	})
+
+	if err := writer.Close(); err != nil {
+		return batch.Summarize(results), err
+	}
+
+	statusf("Exported merge request references to: %s\n", outputPath)
+
+	summary, err := writeFetchSummary(results, filepath.Join(filepath.Dir(outputPath), summaryFilename), format)
+	summary.Output = outputPath
+	return summary, err
+}
+
+// spoolProjectRefs fetches the merge request references of projectPath into a temporary
+// queue. They are only held in memory when the output is sorted.
+func spoolProjectRefs(ctx context.Context, client gitlab.API, projectPath string, format outputFormat) (*refQueue, error) {
+	path, err := refQueuePath("", projectPath)
+	if err != nil {
+		return nil, err
+	}
+	q, err := queue.Open[gitlab.MergeRequestRef](path)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("fetching merge requests", "project", projectPath, "queue", path)
+
+	opts := migrate.FetchOptions{
+		Filters: format.filters,
+		Check:   interrupted,
+		OnRef: func(gitlab.MergeRequestRef) {
+			runStats.refsFetched.Add(1)
+		},
+		Logger: logger,
+	}
+	if format.sortByIID {
+		opts.Sort = format.sortRefs
+	}
+	if _, err := migrate.FetchRefs(ctx, client, projectPath, queueWriter{q}, opts); err != nil {
+		closeRefQueue(q, false)
+		return nil, fmt.Errorf("failed to fetch merge requests: %w", err)
+	}
+
+	logger.Info("fetched merge requests", "project", projectPath, "count", q.Len())
+	return q, nil
+}
+
+// writeFetchSummary writes the summary file of a multi-repository fetch and prints its totals
+func writeFetchSummary(results []batch.Result, summaryPath string, format outputFormat) (batch.Summary, error) {
+	if err := csv.WriteSummaryFile(results, summaryPath, output.CSVOptions(format.Options)...); err != nil {
+		return batch.Summarize(results), err
+	}
+
+	s := batch.Summarize(results)
+	s.Output = summaryPath
+
+	rows := make([][]string, len(results))
+	for i, result := range results {
+		rows[i] = []string{result.Repository, strconv.Itoa(result.Refs), formatDuration(result.Duration), statusCell(result.Err)}
+	}
+	statusTable([]string{"REPOSITORY", "MERGE REQUESTS", "DURATION", "STATUS"}, rows)
+
+	statusf("\nSummary:\n")
+	statusf("📋 Repositories processed: %d\n", s.Repositories)
+	statusf("✅ %s: %d\n", green("Merge requests exported"), s.Refs)
+	if s.Failed > 0 {
+		statusf("❌ %s: %d\n", red("Failed repositories"), s.Failed)
+	}
+	statusf("📄 Summary file: %s\n", summaryPath)
+
+	// A budget exhausted midway left the remaining repositories unattempted; exit with the cause
+	for _, result := range results {
+		if errors.Is(result.Err, gitlab.ErrBudgetExhausted) {
+			return s, fmt.Errorf("%d of %d repositories failed: %w", s.Failed, s.Repositories, result.Err)
+		}
+	}
+	if s.Failed > 0 {
+		return s, withExitCode(exitPartialFailure, fmt.Errorf("%d of %d repositories failed", s.Failed, s.Repositories))
+	}
+
+	return s, nil
+}
@@ -1,11 +1,24 @@
 package cmd
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/amenocal/gh-gl-create-refs/pkg/batch"
 	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+	"github.com/amenocal/gh-gl-create-refs/pkg/github"
 	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/migrate"
+	"github.com/amenocal/gh-gl-create-refs/pkg/output"
+	"github.com/amenocal/gh-gl-create-refs/pkg/redact"
+	"github.com/amenocal/gh-gl-create-refs/pkg/state"
 	"github.com/spf13/cobra"
 )
 
@@ -23,82 +36,427 @@ The output CSV file will contain two columns:
 1. Merge request number (IID)
 2. Head SHA from diff_refs
 
+Use --group, --manifest or a repeated --repository to fetch several repositories at once,
+--incremental to only fetch what changed since the last run, and --all-versions to also
+write every diff version. See the flags below and the README for every option.
+
 Examples:
   gh gl-create-refs fetch-refs --repository group/project
   gh gl-create-refs fetch-refs --repository https://gitlab.example.com/group/subgroup/project
-  gh gl-create-refs fetch-refs -r group/subgroup/subgroup/project`,
+  gh gl-create-refs fetch-refs -r group/subgroup/subgroup/project
+  gh gl-create-refs fetch-refs --group acme --include 'backend/*' --output refs/
+  gh gl-create-refs fetch-refs -r group/project --incremental
+  gh gl-create-refs fetch-refs -r group/project --schedule "0 2 * * *" --sync-target target/project`,
 	Args: cobra.NoArgs,
 	RunE: runFetchRef,
 }
 
-// No global variables needed - using local variables with flag access
-
 func init() {
 	rootCmd.AddCommand(fetchRefCmd)
 
-	fetchRefCmd.Flags().StringP("token", "t", "", "GitLab access token (can also use GITLAB_TOKEN environment variable)")
+	addTokenFlags(fetchRefCmd)
 	fetchRefCmd.Flags().StringP("base-url", "b", "", "GitLab base URL (default: https://gitlab.com)")
-	fetchRefCmd.Flags().StringP("output", "o", "", "Output CSV file path (default: auto-generated from repository name)")
-	fetchRefCmd.Flags().StringP("repository", "r", "", "GitLab repository path (required)")
+	fetchRefCmd.Flags().StringP("output", "o", "", "Output CSV file path, path template such as 'out/{{.Repo}}-{{.Date}}.csv', or s3://, gs:// or azblob:// URL, or output directory with --group, --manifest or several --repository flags (default: auto-generated from repository name)")
+	fetchRefCmd.Flags().StringArrayP("repository", "r", nil, "GitLab repository path (required unless --group or --manifest is used); repeat to fetch several")
+	fetchRefCmd.Flags().StringP("group", "g", "", "GitLab group path: fetch every project in the group and its subgroups")
+	fetchRefCmd.Flags().StringSlice("include", nil, "Only fetch group projects whose relative path matches one of these glob patterns ('*' does not match '/')")
+	fetchRefCmd.Flags().StringSlice("exclude", nil, "Skip group projects whose relative path matches one of these glob patterns")
+	fetchRefCmd.Flags().StringP("manifest", "m", "", "File listing GitLab repository paths to fetch, one per line")
+	fetchRefCmd.Flags().IntP("concurrency", "c", 4, "Number of repositories fetched in parallel with --group, --manifest or several --repository flags")
+	fetchRefCmd.Flags().Bool("combined", false, "With --group, --manifest or several --repository flags, write every repository into one file with a project column (default file: combined-refs.csv)")
+	fetchRefCmd.Flags().Bool("incremental", false, "Only fetch merge requests updated since the last recorded fetch and merge them into the existing output")
+	fetchRefCmd.Flags().String("state-file", state.DefaultPath, "File recording fetch progress and run history")
+	fetchRefCmd.Flags().String("schedule", "", "Run as a daemon, repeating the incremental fetch on this cron schedule (e.g. \"0 2 * * *\")")
+	fetchRefCmd.Flags().Bool("all-versions", false, "Also write every diff version of each merge request to <output>-versions.csv")
+	addFormatFlag(fetchRefCmd)
+	fetchRefCmd.Flags().String("delimiter", ",", "Field delimiter of the output files: comma, tab, semicolon or pipe")
+	fetchRefCmd.Flags().Int("chunk-size", 0, "Split each output into numbered files of at most this many merge requests (0 disables splitting)")
+	fetchRefCmd.Flags().String("order-by", "", "Order output rows by created_at, updated_at or iid, which holds the references in memory until the fetch completes (default: GitLab's order, newest first)")
+	fetchRefCmd.Flags().String("sort", "", "Sort direction of --order-by: asc or desc (default: asc for iid, desc otherwise)")
+	fetchRefCmd.Flags().Bool("excel-compatible", false, "Write files that open cleanly in Excel: UTF-8 byte order mark, CRLF line endings and SHAs kept as text")
+	addEncryptFlag(fetchRefCmd)
+	fetchRefCmd.Flags().Bool("verify-sha", false, "Check that each head SHA exists in the project, one more request per merge request, writing the references that don't to <output>-unreachable.csv")
+	fetchRefCmd.Flags().Bool("include-drafts", false, "Fetch draft merge requests too (the default)")
+	fetchRefCmd.Flags().Bool("exclude-drafts", false, "Skip draft merge requests, using GitLab's wip filter")
+	fetchRefCmd.Flags().String("search", "", "Only fetch merge requests whose title or description contains this text")
+	fetchRefCmd.Flags().String("sync-target", "", "With --schedule, create or update migration branches in this repository after each fetch (requires a single --repository)")
+	addSummaryIssueFlag(fetchRefCmd)
+	fetchRefCmd.Flags().String("github-base-url", "", "GitHub Enterprise Server URL for --summary-issue (default: GH_HOST, or https://github.com)")
+
+	// Exactly one of repository, group or manifest must be given
+	fetchRefCmd.MarkFlagsOneRequired("repository", "group", "manifest")
+	fetchRefCmd.MarkFlagsMutuallyExclusive("repository", "group", "manifest")
+	fetchRefCmd.MarkFlagsMutuallyExclusive("include-drafts", "exclude-drafts")
+	addProjectCompletion(fetchRefCmd, "repository", "sync-target")
+}
+
+// progressInterval is how many merge requests are fetched between progress messages
+const progressInterval = 100
+
+// outputFormat controls what is written to the output files
+type outputFormat struct {
+	output.Options
+	// name is the registered format selected with --format; empty means CSV
+	name string
+	// sortByIID orders rows by merge request IID instead of fetch order, highest first when
+	// descending is set
+	sortByIID  bool
+	descending bool
+	// verifySHA moves references whose head commit is missing from the project to unreachableFilename
+	verifySHA bool
+	// filters select the merge requests written, such as --exclude-drafts
+	filters []gitlab.FetchOption
+}
 
-	// Mark the repository flag as required
-	fetchRefCmd.MarkFlagRequired("repository")
+// chunkCount returns how many chunk files hold refCount references; an empty output is one empty chunk
+func chunkCount(refCount, chunkSize int) int {
+	return max(1, (refCount+chunkSize-1)/chunkSize)
+}
+
+// formatter returns the formatter that writes the output files
+func (f outputFormat) formatter() (output.Formatter, error) {
+	return output.New(cmp.Or(f.name, output.FormatCSV), f.Options)
+}
+
+// sortRefs orders refs by IID when the output is sorted by IID
+func (f outputFormat) sortRefs(refs []gitlab.MergeRequestRef) {
+	if !f.sortByIID {
+		return
+	}
+	csv.SortRefsByIID(refs)
+	if f.descending {
+		slices.Reverse(refs)
+	}
+}
+
+// unreachableFilename returns the file that holds the references of outputPath whose head
+// commit is missing from the project, such as refs-unreachable.csv for refs.csv
+func unreachableFilename(outputPath string) string {
+	ext := filepath.Ext(outputPath)
+	return strings.TrimSuffix(outputPath, ext) + "-unreachable" + ext
+}
+
+// filename returns the default output file name of repository, with the format's extension
+func (f outputFormat) filename(repository string) (string, error) {
+	formatter, err := f.formatter()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(csv.GenerateFilename(repository), ".csv") + formatter.Extension(), nil
+}
+
+// fetchOptions holds the settings for a single fetch run
+type fetchOptions struct {
+	repository string
+	// repositories are the repositories of a run given several --repository flags
+	repositories []string
+	baseURL      string
+	output       string
+	group        string
+	manifest     string
+	filter       gitlab.ProjectFilter
+	concurrency  int
+	// combined writes every repository of a group or manifest fetch into one file
+	combined bool
+	format   outputFormat
+	// store enables incremental fetches when non-nil
+	store *state.Store
+	// naming names the branches --sync-target creates
+	naming refNaming
 }
 
 func runFetchRef(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	startedAt := time.Now()
+
 	// Get parameters from flags
-	repository := cmd.Flag("repository").Value.String()
-	gitlabToken := cmd.Flag("token").Value.String()
+	repositories, _ := cmd.Flags().GetStringArray("repository")
 	gitlabBaseURL := cmd.Flag("base-url").Value.String()
 	outputFile := cmd.Flag("output").Value.String()
+	group := cmd.Flag("group").Value.String()
+	include, _ := cmd.Flags().GetStringSlice("include")
+	exclude, _ := cmd.Flags().GetStringSlice("exclude")
+	manifest := cmd.Flag("manifest").Value.String()
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	incremental, _ := cmd.Flags().GetBool("incremental")
+	stateFile := cmd.Flag("state-file").Value.String()
+	scheduleSpec := cmd.Flag("schedule").Value.String()
+	syncTarget := cmd.Flag("sync-target").Value.String()
+	allVersions, _ := cmd.Flags().GetBool("all-versions")
+	combined, _ := cmd.Flags().GetBool("combined")
+	excludeDrafts, _ := cmd.Flags().GetBool("exclude-drafts")
+	search := cmd.Flag("search").Value.String()
+	verifySHA, _ := cmd.Flags().GetBool("verify-sha")
+	githubBaseURL := cmd.Flag("github-base-url").Value.String()
 
-	// Create GitLab client from flags and environment
-	client, err := gitlab.NewClient(gitlabToken, gitlabBaseURL)
+	summaryIssue, err := summaryIssueFlag(cmd)
 	if err != nil {
 		return err
 	}
+	if summaryIssue != nil && scheduleSpec != "" {
+		return fmt.Errorf("--summary-issue cannot be used with --schedule")
+	}
+	if githubBaseURL != "" && summaryIssue == nil {
+		return fmt.Errorf("--github-base-url can only be used with --summary-issue")
+	}
 
-	fmt.Printf("Fetching merge requests from repository...\n")
+	chunkSize, _ := cmd.Flags().GetInt("chunk-size")
+	excel, _ := cmd.Flags().GetBool("excel-compatible")
 
-	// Determine output file path
-	var outputPath string
-	if outputFile != "" {
-		outputPath = outputFile
-	} else {
-		outputPath = csv.GenerateFilename(repository)
+	delimiter, err := csv.ParseDelimiter(cmd.Flag("delimiter").Value.String())
+	if err != nil {
+		return err
 	}
 
-	// Create CSV stream writer for incremental writing
-	csvWriter, err := csv.NewStreamWriter(outputPath)
+	formatName := cmd.Flag("format").Value.String()
+	// Combined files and incremental merges are read back as CSV
+	if formatName != output.FormatCSV && (combined || incremental || scheduleSpec != "") {
+		return fmt.Errorf("--format %s cannot be used with --combined, --incremental or --schedule, which only write CSV", formatName)
+	}
+
+	orderBy, sortOrder, err := parseOrder(cmd.Flag("order-by").Value.String(), cmd.Flag("sort").Value.String())
 	if err != nil {
-		return fmt.Errorf("failed to create CSV writer: %w", err)
+		return err
 	}
-	defer csvWriter.Close()
 
-	// Track progress
-	refCount := 0
+	naming, err := namingFlags(cmd)
+	if err != nil {
+		return err
+	}
 
-	// Create processor callback that writes each MR to CSV immediately
-	processor := func(ref gitlab.MergeRequestRef) error {
-		if err := csvWriter.WriteRef(ref); err != nil {
-			return fmt.Errorf("failed to write merge request %d to CSV: %w", ref.IID, err)
+	if output.IsTemplate(outputFile) {
+		if err := output.ParsePathTemplate(outputFile); err != nil {
+			return err
 		}
-		refCount++
-		return nil
 	}
 
-	// Fetch merge request references using the callback-based API
-	projectPath, err := client.FetchMergeRequestRefsFromRepo(repository, gitlabBaseURL, processor)
+	if chunkSize < 0 {
+		return fmt.Errorf("--chunk-size must not be negative")
+	}
+	if chunkSize > 0 && (incremental || scheduleSpec != "") {
+		return fmt.Errorf("--chunk-size cannot be used with --incremental or --schedule")
+	}
+
+	if len(include) > 0 || len(exclude) > 0 {
+		if group == "" {
+			return fmt.Errorf("--include and --exclude can only be used with --group")
+		}
+	}
+
+	// A single repository is fetched on its own; several are fetched like a manifest's
+	var repository string
+	if len(repositories) == 1 {
+		repository, repositories = repositories[0], nil
+	}
+	multi := group != "" || manifest != "" || len(repositories) > 0
+
+	if combined {
+		if !multi {
+			return fmt.Errorf("--combined can only be used with --group, --manifest or several --repository flags")
+		}
+		if incremental || scheduleSpec != "" || allVersions || chunkSize > 0 {
+			return fmt.Errorf("--combined cannot be used with --incremental, --schedule, --all-versions or --chunk-size")
+		}
+		if output.IsTemplate(outputFile) {
+			return fmt.Errorf("--combined cannot be used with an --output template")
+		}
+	}
+
+	if allVersions && (incremental || scheduleSpec != "") {
+		return fmt.Errorf("--all-versions cannot be used with --incremental or --schedule")
+	}
+
+	if verifySHA && (combined || incremental || scheduleSpec != "") {
+		return fmt.Errorf("--verify-sha cannot be used with --combined, --incremental or --schedule")
+	}
+
+	// Incremental fetches read their earlier output back
+	if len(encryptRecipients(cmd)) > 0 && (incremental || scheduleSpec != "") {
+		return fmt.Errorf("--encrypt-recipient cannot be used with --incremental or --schedule")
+	}
+	if output.IsRemote(outputFile) && (incremental || scheduleSpec != "") {
+		return fmt.Errorf("--output %s cannot be used with --incremental or --schedule, which read their output back", outputFile)
+	}
+	if err := setupEncryption(cmd); err != nil {
+		return err
+	}
+
+	if syncTarget != "" {
+		if scheduleSpec == "" {
+			return fmt.Errorf("--sync-target can only be used with --schedule")
+		}
+		if repository == "" {
+			return fmt.Errorf("--sync-target requires a single --repository")
+		}
+	}
+
+	// Create GitLab client from flags and environment
+	client, err := newGitLabClient(cmd, gitlabBaseURL)
 	if err != nil {
 		return err
 	}
 
+	// Fail before fetching when the summary could not be posted
+	var ghClient github.API
+	if summaryIssue != nil {
+		ghClient, err = newGitHubClient(githubBaseURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	var remote *remoteOutput
+	if output.IsRemote(outputFile) {
+		outputFile, remote, err = stageRemoteOutput(outputFile, multi && !combined)
+		if err != nil {
+			return err
+		}
+		// Also on the early returns below; finish removes it once the fetch has run
+		defer remote.cleanup()
+	}
+
+	opts := fetchOptions{
+		repository:   repository,
+		repositories: repositories,
+		baseURL:      gitlabBaseURL,
+		output:       outputFile,
+		group:        group,
+		manifest:     manifest,
+		filter:       gitlab.ProjectFilter{Include: include, Exclude: exclude},
+		concurrency:  concurrency,
+		combined:     combined,
+		format: outputFormat{
+			name: formatName,
+			Options: output.Options{
+				Delimiter:   delimiter,
+				Excel:       excel,
+				ChunkSize:   chunkSize,
+				AllVersions: allVersions,
+			},
+			sortByIID:  orderBy == "iid",
+			descending: sortOrder == "desc",
+			verifySHA:  verifySHA,
+			filters:    []gitlab.FetchOption{gitlab.WithDrafts(!excludeDrafts), gitlab.WithSearch(search)},
+		},
+		naming: naming,
+	}
+
+	if orderBy != "iid" {
+		opts.format.filters = append(opts.format.filters, gitlab.WithOrder(orderBy, sortOrder))
+	}
+
+	// Scheduled runs are always incremental
+	if incremental || scheduleSpec != "" {
+		l, err := lockOutput(stateFile)
+		if err != nil {
+			return err
+		}
+		defer releaseLock(l)
+
+		opts.store, err = state.Open(stateFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if scheduleSpec != "" {
+		return runScheduledFetch(ctx, client, opts, scheduleSpec, syncTarget)
+	}
+
+	summary, err := runFetch(ctx, client, opts)
+	if remote != nil {
+		err = remote.finish(ctx, err)
+		summary.Output = cmd.Flag("output").Value.String()
+	}
+
+	report := runReport{
+		command:   "fetch-refs",
+		startedAt: startedAt,
+		duration:  time.Since(startedAt),
+		counts: []reportCount{
+			{"Repositories processed", summary.Repositories},
+			{"Merge requests exported", summary.Refs},
+			{"Failed repositories", summary.Failed},
+		},
+		file: summary.Output,
+		err:  err,
+	}
+
+	notifyErr := notifyRunSummary(ctx, report, false)
+	if summaryIssue != nil {
+		err = postRunSummary(ctx, ghClient, *summaryIssue, report, false)
+	}
+	if err == nil {
+		err = notifyErr
+	}
+	return err
+}
+
+// runFetch performs one fetch of the configured repositories and, for incremental
+// fetches, records the run in the state store
+func runFetch(ctx context.Context, client gitlab.API, opts fetchOptions) (batch.Summary, error) {
+	startedAt := time.Now()
+
+	summary, err := fetchOnce(ctx, client, opts)
+
+	if opts.store != nil {
+		run := state.Run{
+			StartedAt:    startedAt,
+			FinishedAt:   time.Now(),
+			Mode:         "incremental",
+			Repositories: summary.Repositories,
+			Refs:         summary.Refs,
+			Failed:       summary.Failed,
+		}
+		if err != nil {
+			run.Error = redact.String(err.Error())
+		}
+		opts.store.RecordRun(run)
+
+		if saveErr := opts.store.Save(); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+
+	return summary, err
+}
+
+func fetchOnce(ctx context.Context, client gitlab.API, opts fetchOptions) (batch.Summary, error) {
+	if opts.group != "" || opts.manifest != "" || len(opts.repositories) > 0 {
+		projects := opts.repositories
+		if len(projects) == 0 {
+			var err error
+			if projects, err = resolveProjects(ctx, client, opts.group, opts.manifest, opts.filter); err != nil {
+				return batch.Summary{}, err
+			}
+		}
+		if opts.combined {
+			return fetchCombinedRefs(ctx, client, projects, opts.baseURL, opts.output, opts.concurrency, opts.format)
+		}
+		return fetchManyRefs(ctx, client, opts.store, projects, opts.baseURL, opts.output, opts.concurrency, opts.format)
+	}
+
+	logger.Info("fetching merge requests", "project", opts.repository)
+
+	// Determine output file path
+	outputPath, err := outputPathFor(opts.output, opts.repository, false, opts.format, time.Now())
+	if err != nil {
+		return batch.Summary{Repositories: 1, Failed: 1}, err
+	}
+
+	refCount, projectPath, err := fetchRepository(ctx, client, opts.store, opts.repository, opts.baseURL, outputPath, opts.format)
+	if err != nil {
+		return batch.Summary{Repositories: 1, Failed: 1}, err
+	}
+
+	summary := batch.Summary{Repositories: 1, Refs: refCount, Output: outputPath}
+
 	if refCount == 0 {
-		fmt.Printf("No merge requests found in %s\n", projectPath)
-		return nil
+		logger.Info("no merge requests found", "project", projectPath)
+		return summary, nil
 	}
 
-	fmt.Printf("Found %d merge requests from %s\n", refCount, projectPath)
+	logger.Info("fetched merge requests", "project", projectPath, "count", refCount)
 
 	// Get absolute path for the output
 	absPath, err := filepath.Abs(outputPath)
@@ -106,7 +464,238 @@ func runFetchRef(cmd *cobra.Command, args []string) error {
 		absPath = outputPath // Fallback to relative path
 	}
 
-	fmt.Printf("Successfully exported merge request references to: %s\n", absPath)
+	if opts.format.ChunkSize > 0 {
+		chunks := chunkCount(refCount, opts.format.ChunkSize)
+		statusf("%s merge request references to %d files: %s ... %s\n", green("Successfully exported"), chunks, csv.ChunkFilename(absPath, 1), csv.ChunkFilename(absPath, chunks))
+		return summary, nil
+	}
+
+	statusf("%s merge request references to: %s\n", green("Successfully exported"), absPath)
+
+	return summary, nil
+}
+
+// parseOrder checks --order-by and --sort. created_at and updated_at are passed to GitLab,
+// which streams merge requests in that order; iid is sorted locally, since GitLab can't
+// order by it. --sort iid is the older spelling of --order-by iid.
+func parseOrder(orderBy, sort string) (string, string, error) {
+	if sort == "iid" && orderBy == "" {
+		orderBy, sort = "iid", ""
+	}
+
+	switch orderBy {
+	case "", "created_at", "updated_at", "iid":
+	default:
+		return "", "", fmt.Errorf("unsupported --order-by value %q: must be created_at, updated_at or iid", orderBy)
+	}
+	switch sort {
+	case "", "asc", "desc":
+	default:
+		return "", "", fmt.Errorf("unsupported --sort value %q: must be asc or desc", sort)
+	}
+	return orderBy, sort, nil
+}
+
+// outputPathFor returns where the output of repository is written: the expanded --output
+// template, a file named after the repository in the --output directory for multi-repository
+// runs, or the --output file. Directories of templated outputs are created.
+func outputPathFor(outputFlag, repository string, multi bool, format outputFormat, startedAt time.Time) (string, error) {
+	if output.IsTemplate(outputFlag) {
+		formatter, err := format.formatter()
+		if err != nil {
+			return "", err
+		}
+
+		outputPath, err := output.ExpandPath(outputFlag, output.NewPathData(repository, formatter, startedAt))
+		if err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return "", fmt.Errorf("failed to create output directory: %w", err)
+		}
+		return outputPath, nil
+	}
+
+	if outputFlag != "" && !multi {
+		return outputFlag, nil
+	}
+
+	filename, err := format.filename(repository)
+	if err != nil {
+		return "", err
+	}
+	if multi {
+		return filepath.Join(outputFlag, filename), nil
+	}
+	return filename, nil
+}
+
+// fetchRepository fetches one repository into outputPath, incrementally when store is non-nil,
+// and returns the number of references in the output file
+func fetchRepository(ctx context.Context, client gitlab.API, store *state.Store, repository, baseURL, outputPath string, format outputFormat) (int, string, error) {
+	l, err := lockOutput(outputPath)
+	if err != nil {
+		return 0, "", err
+	}
+	defer releaseLock(l)
+
+	if store == nil {
+		return fetchRefsToFile(ctx, client, repository, baseURL, outputPath, format)
+	}
+
+	startedAt := time.Now()
+
+	// Without a previous fetch or output file there is nothing to merge into, so fetch everything
+	previous, ok := store.Repository(repository)
+	if _, err := os.Stat(outputPath); !ok || errors.Is(err, os.ErrNotExist) {
+		refCount, projectPath, err := fetchRefsToFile(ctx, client, repository, baseURL, outputPath, format)
+		if err != nil {
+			return 0, "", err
+		}
+		store.RecordFetch(repository, startedAt, refCount)
+		return refCount, projectPath, nil
+	}
+
+	var updated []gitlab.MergeRequestRef
+	processor := func(ref gitlab.MergeRequestRef) error {
+		if err := interrupted(ctx); err != nil {
+			return err
+		}
+		updated = append(updated, ref)
+		runStats.refsFetched.Add(1)
+		return nil
+	}
+
+	projectPath, err := client.FetchMergeRequestRefsFromRepo(ctx, repository, baseURL, processor, append([]gitlab.FetchOption{gitlab.WithUpdatedAfter(previous.LastFetchedAt)}, format.filters...)...)
+	if err != nil {
+		return 0, "", err
+	}
+
+	existing, err := csv.ReadRefsFromFile(outputPath)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read existing output: %w", err)
+	}
+
+	merged := csv.MergeRefs(existing, updated)
+	format.sortRefs(merged)
+	formatter, err := format.formatter()
+	if err != nil {
+		return 0, "", err
+	}
+	if err := output.WriteRefs(formatter, outputPath, merged); err != nil {
+		return 0, "", err
+	}
+
+	logger.Info("merged updated merge requests", "project", projectPath, "updated", len(updated), "since", previous.LastFetchedAt.Format(time.RFC3339))
+
+	store.RecordFetch(repository, startedAt, len(merged))
+	return len(merged), projectPath, nil
+}
+
+// fetchRefsToFile streams the merge request references of one repository into a CSV file.
+// With format.AllVersions, every diff version is also written to the matching versions file;
+// with format.verifySHA, references whose head commit is missing go to the unreachable file.
+func fetchRefsToFile(ctx context.Context, client gitlab.API, repository, baseURL, outputPath string, format outputFormat) (int, string, error) {
+	_, projectPath, err := gitlab.ParseRepoPath(repository)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to parse repository path: %w", err)
+	}
+
+	// Pre-flight check: fail before creating any output if the project is not accessible
+	info, err := client.GetProjectInfo(ctx, projectPath)
+	if err != nil {
+		return 0, "", err
+	}
+	// A renamed or transferred project is fetched from its new path
+	if moved := movedProject(projectPath, info.Path); moved != projectPath {
+		projectPath, repository = moved, moved
+	}
+	if info.Archived {
+		logger.Warn("project is archived", "project", info.Path)
+	}
+	logger.Info("project merge requests", "project", info.Path, "total", info.TotalMergeRequests, "open", info.OpenMergeRequests)
+
+	formatter, err := format.formatter()
+	if err != nil {
+		return 0, "", err
+	}
+
+	// Create the output writer for incremental writing; the output only appears once the fetch completes
+	writer, err := formatter.NewWriter(outputPath)
+	if err != nil {
+		return 0, "", err
+	}
+	defer writer.Abort()
+
+	var unreachable output.Writer
+	unreachablePath := unreachableFilename(outputPath)
+	if format.verifySHA {
+		// Only the references need to be reviewed; their versions stay in the versions file
+		unreachableFormatter, err := output.New(cmp.Or(format.name, output.FormatCSV), output.Options{Delimiter: format.Delimiter, Excel: format.Excel})
+		if err != nil {
+			return 0, "", err
+		}
+		unreachable, err = unreachableFormatter.NewWriter(unreachablePath)
+		if err != nil {
+			return 0, "", err
+		}
+		defer unreachable.Abort()
+	}
+
+	throughput := newProgress("fetch throughput", info.TotalMergeRequests, "project", info.Path)
+
+	// Each merge request is written as soon as it is fetched, unless the output is sorted
+	refCount := 0
+	opts := migrate.FetchOptions{
+		Filters:     append([]gitlab.FetchOption{gitlab.WithAllVersions(format.AllVersions)}, format.filters...),
+		VerifySHA:   format.verifySHA,
+		Unreachable: unreachable,
+		// An interrupted fetch discards its output rather than leaving a partial file
+		Check: interrupted,
+		OnRef: func(ref gitlab.MergeRequestRef) {
+			refCount++
+			runStats.refsFetched.Add(1)
+			throughput.add(1)
+			if refCount%progressInterval == 0 {
+				logger.Info("fetch progress", "project", info.Path, "fetched", refCount, "total", info.TotalMergeRequests)
+			}
+		},
+		Logger: logger,
+	}
+	if format.sortByIID {
+		opts.Sort = format.sortRefs
+	}
+
+	result, err := migrate.FetchRefs(ctx, client, repository, writer, opts)
+	if err != nil {
+		return 0, "", err
+	}
+	unreachableCount, forkCount := result.Unreachable, result.Forks
+
+	if err := writer.Close(); err != nil {
+		return 0, "", err
+	}
+	if format.verifySHA {
+		if unreachableCount == 0 {
+			// Don't leave the unreachable references of an earlier run behind
+			for _, name := range []string{unreachablePath, csv.ChecksumFilename(unreachablePath)} {
+				if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+					return 0, "", err
+				}
+			}
+		} else {
+			if err := unreachable.Close(); err != nil {
+				return 0, "", err
+			}
+			logger.Warn("merge requests with unreachable head commits left out of the output", "project", info.Path, "count", unreachableCount, "file", unreachablePath)
+		}
+	}
+	if forkCount > 0 {
+		logger.Info("merge requests from forks; their commits are in the project only through refs/merge-requests/<iid>/head", "project", info.Path, "count", forkCount)
+	}
+	if format.ChunkSize > 0 {
+		logger.Info("split output into chunks", "project", info.Path, "files", chunkCount(refCount, format.ChunkSize), "chunk_size", format.ChunkSize)
+	}
 
-	return nil
+	return refCount, projectPath, nil
 }
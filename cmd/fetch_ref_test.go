@@ -1,9 +1,20 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
+	"time"
 
+	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlabtest"
+	"github.com/amenocal/gh-gl-create-refs/pkg/output"
 	"github.com/spf13/cobra"
+	gogitlab "gitlab.com/gitlab-org/api/client-go"
 )
 
 func TestRunFetchRef_FlagExtraction(t *testing.T) {
@@ -135,6 +146,24 @@ func TestFetchRefCmd_FlagDefinitions(t *testing.T) {
 		{"token", "t", false},
 		{"base-url", "b", false},
 		{"output", "o", false},
+		{"group", "g", false},
+		{"include", "", false},
+		{"exclude", "", false},
+		{"manifest", "m", false},
+		{"concurrency", "c", false},
+		{"incremental", "", false},
+		{"state-file", "", false},
+		{"schedule", "", false},
+		{"sync-target", "", false},
+		{"all-versions", "", false},
+		{"delimiter", "", false},
+		{"chunk-size", "", false},
+		{"order-by", "", false},
+		{"sort", "", false},
+		{"excel-compatible", "", false},
+		{"verify-sha", "", false},
+		{"encrypt-recipient", "", false},
+		{"combined", "", false},
 	}
 
 	for _, expected := range expectedFlags {
@@ -500,3 +529,522 @@ func containsSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestFetchRefsToFile_AllVersions(t *testing.T) {
+	api := newMockAPI()
+	api.refs["group/project"] = []gitlab.MergeRequestRef{
+		{
+			IID:     1,
+			HeadSHA: "new",
+			Versions: []gitlab.MergeRequestVersion{
+				{HeadSHA: "old", BaseSHA: "base"},
+				{HeadSHA: "new", BaseSHA: "base"},
+			},
+		},
+	}
+
+	api.projects["group/project"] = &gitlab.ProjectInfo{Path: "group/project", TotalMergeRequests: 1}
+
+	outputPath := filepath.Join(t.TempDir(), "refs.csv")
+
+	count, _, err := fetchRefsToFile(context.Background(), api, "group/project", "", outputPath, outputFormat{Options: output.Options{AllVersions: true}})
+	if err != nil {
+		t.Fatalf("fetchRefsToFile() unexpected error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 reference, got %d", count)
+	}
+
+	refs, err := os.ReadFile(outputPath)
+	if err != nil || string(refs) != "# gh-gl-create-refs refs v1\n1,new\n" {
+		t.Errorf("References file = %q, %v", refs, err)
+	}
+
+	versions, err := os.ReadFile(filepath.Join(filepath.Dir(outputPath), "refs-versions.csv"))
+	if err != nil || string(versions) != "# gh-gl-create-refs versions v1\n1,1,old,base,\n1,2,new,base,\n" {
+		t.Errorf("Versions file = %q, %v", versions, err)
+	}
+}
+
+func TestFetchRefsToFile_Delimiter(t *testing.T) {
+	api := newMockAPI()
+	api.refs["group/project"] = []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaaaaaa"}, {IID: 2, HeadSHA: "bbbbbbb"}}
+	api.projects["group/project"] = &gitlab.ProjectInfo{Path: "group/project", TotalMergeRequests: 2}
+
+	outputPath := filepath.Join(t.TempDir(), "refs.tsv")
+
+	_, _, err := fetchRefsToFile(context.Background(), api, "group/project", "", outputPath, outputFormat{Options: output.Options{Delimiter: '\t'}})
+	if err != nil {
+		t.Fatalf("fetchRefsToFile() unexpected error = %v", err)
+	}
+
+	refs, err := os.ReadFile(outputPath)
+	if err != nil || string(refs) != "# gh-gl-create-refs refs v1\n1\taaaaaaa\n2\tbbbbbbb\n" {
+		t.Errorf("References file = %q, %v", refs, err)
+	}
+}
+
+func TestFetchRefsToFile_ExcludeDrafts(t *testing.T) {
+	api := newMockAPI()
+	api.refs["group/project"] = []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: "aaaaaaa", MergeRequest: &gogitlab.MergeRequest{}},
+		{IID: 2, HeadSHA: "bbbbbbb", MergeRequest: &gogitlab.MergeRequest{BasicMergeRequest: gogitlab.BasicMergeRequest{Draft: true}}},
+	}
+	api.projects["group/project"] = &gitlab.ProjectInfo{Path: "group/project", TotalMergeRequests: 2}
+
+	outputPath := filepath.Join(t.TempDir(), "refs.csv")
+
+	count, _, err := fetchRefsToFile(context.Background(), api, "group/project", "", outputPath, outputFormat{filters: []gitlab.FetchOption{gitlab.WithDrafts(false)}})
+	if err != nil {
+		t.Fatalf("fetchRefsToFile() unexpected error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected the draft to be skipped, got %d references", count)
+	}
+
+	refs, err := os.ReadFile(outputPath)
+	if err != nil || string(refs) != "# gh-gl-create-refs refs v1\n1,aaaaaaa\n" {
+		t.Errorf("References file = %q, %v", refs, err)
+	}
+}
+
+func TestFetchRefsToFile_VerifySHA(t *testing.T) {
+	api := newMockAPI()
+	api.refs["group/project"] = []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: "aaaaaaa"},
+		{IID: 2, HeadSHA: "bbbbbbb"},
+		{IID: 3, HeadSHA: "ccccccc"},
+	}
+	api.projects["group/project"] = &gitlab.ProjectInfo{Path: "group/project", TotalMergeRequests: 3}
+	api.missingCommits = map[string]map[string]bool{"group/project": {"bbbbbbb": true}}
+
+	outputPath := filepath.Join(t.TempDir(), "refs.csv")
+
+	count, _, err := fetchRefsToFile(context.Background(), api, "group/project", "", outputPath, outputFormat{verifySHA: true})
+	if err != nil {
+		t.Fatalf("fetchRefsToFile() unexpected error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 reachable references, got %d", count)
+	}
+
+	refs, err := os.ReadFile(outputPath)
+	if err != nil || string(refs) != "# gh-gl-create-refs refs v1\n1,aaaaaaa\n3,ccccccc\n" {
+		t.Errorf("References file = %q, %v", refs, err)
+	}
+
+	unreachable, err := os.ReadFile(unreachableFilename(outputPath))
+	if err != nil || string(unreachable) != "# gh-gl-create-refs refs v1\n2,bbbbbbb\n" {
+		t.Errorf("Unreachable file = %q, %v", unreachable, err)
+	}
+
+	// A later run where every commit is found removes the stale file
+	api.missingCommits = nil
+	if _, _, err := fetchRefsToFile(context.Background(), api, "group/project", "", outputPath, outputFormat{verifySHA: true}); err != nil {
+		t.Fatalf("fetchRefsToFile() unexpected error = %v", err)
+	}
+	if _, err := os.Stat(unreachableFilename(outputPath)); !os.IsNotExist(err) {
+		t.Errorf("Expected the unreachable file to be removed, got %v", err)
+	}
+}
+
+func TestFetchRefsToFile_Search(t *testing.T) {
+	api := newMockAPI()
+	api.refs["group/project"] = []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: "aaaaaaa", MergeRequest: &gogitlab.MergeRequest{BasicMergeRequest: gogitlab.BasicMergeRequest{Title: "JIRA-1234: Add login"}}},
+		{IID: 2, HeadSHA: "bbbbbbb", MergeRequest: &gogitlab.MergeRequest{BasicMergeRequest: gogitlab.BasicMergeRequest{Title: "Fix typo"}}},
+		{IID: 3, HeadSHA: "ccccccc", MergeRequest: &gogitlab.MergeRequest{BasicMergeRequest: gogitlab.BasicMergeRequest{Title: "Follow-up", Description: "Part of JIRA-1234"}}},
+	}
+	api.projects["group/project"] = &gitlab.ProjectInfo{Path: "group/project", TotalMergeRequests: 3}
+
+	outputPath := filepath.Join(t.TempDir(), "refs.csv")
+
+	_, _, err := fetchRefsToFile(context.Background(), api, "group/project", "", outputPath, outputFormat{filters: []gitlab.FetchOption{gitlab.WithSearch("JIRA-1234")}})
+	if err != nil {
+		t.Fatalf("fetchRefsToFile() unexpected error = %v", err)
+	}
+
+	refs, err := os.ReadFile(outputPath)
+	if err != nil || string(refs) != "# gh-gl-create-refs refs v1\n1,aaaaaaa\n3,ccccccc\n" {
+		t.Errorf("References file = %q, %v", refs, err)
+	}
+}
+
+func TestFetchRefsToFile_PreflightFailure(t *testing.T) {
+	api := newMockAPI()
+
+	outputPath := filepath.Join(t.TempDir(), "refs.csv")
+
+	_, _, err := fetchRefsToFile(context.Background(), api, "group/missing", "", outputPath, outputFormat{})
+	if !errors.Is(err, gitlab.ErrProjectNotFound) {
+		t.Fatalf("fetchRefsToFile() error = %v, want ErrProjectNotFound", err)
+	}
+
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Error("No output file should be created when the pre-flight check fails")
+	}
+}
+
+func TestFetchRefsToFile_InterruptedFetchKeepsPreviousOutput(t *testing.T) {
+	api := newMockAPI()
+	api.refs["group/project"] = []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaaaaaa"}}
+	api.projects["group/project"] = &gitlab.ProjectInfo{Path: "group/project", TotalMergeRequests: 2}
+	api.fetchErr = gitlab.ErrRateLimited
+
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "refs.csv")
+	previous := "# gh-gl-create-refs refs v1\n1,old\n2,old\n"
+	if err := os.WriteFile(outputPath, []byte(previous), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err := fetchRefsToFile(context.Background(), api, "group/project", "", outputPath, outputFormat{Options: output.Options{AllVersions: true}})
+	if !errors.Is(err, gitlab.ErrRateLimited) {
+		t.Fatalf("fetchRefsToFile() error = %v, want ErrRateLimited", err)
+	}
+
+	content, err := os.ReadFile(outputPath)
+	if err != nil || string(content) != previous {
+		t.Errorf("Output file = %q, %v; want the previous output untouched", content, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the previous output in %s, found %d entries", dir, len(entries))
+	}
+}
+
+func TestFetchRefsToFile_ChunkSize(t *testing.T) {
+	api := newMockAPI()
+	api.refs["group/project"] = []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaaaaaa"}, {IID: 2, HeadSHA: "bbbbbbb"}, {IID: 3, HeadSHA: "ccccccc"}}
+	api.projects["group/project"] = &gitlab.ProjectInfo{Path: "group/project", TotalMergeRequests: 3}
+
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "group-project.csv")
+
+	count, _, err := fetchRefsToFile(context.Background(), api, "group/project", "", outputPath, outputFormat{Options: output.Options{ChunkSize: 2}})
+	if err != nil {
+		t.Fatalf("fetchRefsToFile() unexpected error = %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 references, got %d", count)
+	}
+
+	expected := map[string]string{
+		"group-project-001.csv": "# gh-gl-create-refs refs v1\n1,aaaaaaa\n2,bbbbbbb\n",
+		"group-project-002.csv": "# gh-gl-create-refs refs v1\n3,ccccccc\n",
+	}
+	for name, want := range expected {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(content) != want {
+			t.Errorf("%s = %q, %v; want %q", name, content, err, want)
+		}
+	}
+
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Error("The unchunked output should not be written with a chunk size")
+	}
+}
+
+func TestFetchRefsToFile_SortByIID(t *testing.T) {
+	api := newMockAPI()
+	// Fetched most recently updated first
+	api.refs["group/project"] = []gitlab.MergeRequestRef{
+		{IID: 3, HeadSHA: "ccccccc", Versions: []gitlab.MergeRequestVersion{{HeadSHA: "ccccccc"}}},
+		{IID: 1, HeadSHA: "aaaaaaa", Versions: []gitlab.MergeRequestVersion{{HeadSHA: "aaaaaaa"}}},
+		{IID: 2, HeadSHA: "bbbbbbb", Versions: []gitlab.MergeRequestVersion{{HeadSHA: "bbbbbbb"}}},
+	}
+	api.projects["group/project"] = &gitlab.ProjectInfo{Path: "group/project", TotalMergeRequests: 3}
+
+	outputPath := filepath.Join(t.TempDir(), "refs.csv")
+
+	_, _, err := fetchRefsToFile(context.Background(), api, "group/project", "", outputPath, outputFormat{Options: output.Options{AllVersions: true}, sortByIID: true})
+	if err != nil {
+		t.Fatalf("fetchRefsToFile() unexpected error = %v", err)
+	}
+
+	refs, err := os.ReadFile(outputPath)
+	if err != nil || string(refs) != "# gh-gl-create-refs refs v1\n1,aaaaaaa\n2,bbbbbbb\n3,ccccccc\n" {
+		t.Errorf("References file = %q, %v", refs, err)
+	}
+
+	versions, err := os.ReadFile(csv.VersionsFilename(outputPath))
+	if err != nil || string(versions) != "# gh-gl-create-refs versions v1\n1,1,aaaaaaa,,\n2,1,bbbbbbb,,\n3,1,ccccccc,,\n" {
+		t.Errorf("Versions file = %q, %v", versions, err)
+	}
+}
+
+func TestFetchRefsToFile_SortByIIDDescending(t *testing.T) {
+	api := newMockAPI()
+	api.refs["group/project"] = []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: "aaaaaaa"},
+		{IID: 3, HeadSHA: "ccccccc"},
+		{IID: 2, HeadSHA: "bbbbbbb"},
+	}
+	api.projects["group/project"] = &gitlab.ProjectInfo{Path: "group/project", TotalMergeRequests: 3}
+
+	outputPath := filepath.Join(t.TempDir(), "refs.csv")
+
+	_, _, err := fetchRefsToFile(context.Background(), api, "group/project", "", outputPath, outputFormat{sortByIID: true, descending: true})
+	if err != nil {
+		t.Fatalf("fetchRefsToFile() unexpected error = %v", err)
+	}
+
+	refs, err := os.ReadFile(outputPath)
+	if err != nil || string(refs) != "# gh-gl-create-refs refs v1\n3,ccccccc\n2,bbbbbbb\n1,aaaaaaa\n" {
+		t.Errorf("References file = %q, %v", refs, err)
+	}
+}
+
+func TestParseOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		orderBy     string
+		sort        string
+		wantOrderBy string
+		wantSort    string
+		wantErr     bool
+	}{
+		{"defaults", "", "", "", "", false},
+		{"oldest first", "created_at", "asc", "created_at", "asc", false},
+		{"updated", "updated_at", "desc", "updated_at", "desc", false},
+		{"iid descending", "iid", "desc", "iid", "desc", false},
+		{"legacy sort iid", "", "iid", "iid", "", false},
+		{"sort direction alone", "", "asc", "", "asc", false},
+		{"unsupported order", "title", "", "", "", true},
+		{"unsupported direction", "iid", "up", "", "", true},
+		{"sort iid with order-by", "created_at", "iid", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderBy, sort, err := parseOrder(tt.orderBy, tt.sort)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if orderBy != tt.wantOrderBy || sort != tt.wantSort {
+				t.Errorf("parseOrder() = %q, %q, want %q, %q", orderBy, sort, tt.wantOrderBy, tt.wantSort)
+			}
+		})
+	}
+}
+
+func TestOutputPathFor(t *testing.T) {
+	dir := t.TempDir()
+	startedAt := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		outputFlag string
+		multi      bool
+		expected   string
+	}{
+		{
+			name:     "default file name",
+			expected: "group-project.csv",
+		},
+		{
+			name:       "output file",
+			outputFlag: "refs.csv",
+			expected:   "refs.csv",
+		},
+		{
+			name:       "output directory for multiple repositories",
+			outputFlag: "refs",
+			multi:      true,
+			expected:   filepath.Join("refs", "group-project.csv"),
+		},
+		{
+			name:       "template",
+			outputFlag: filepath.Join(dir, "{{.Date}}", "{{.Repo}}.{{.Ext}}"),
+			expected:   filepath.Join(dir, "2024-01-31", "group-project.csv"),
+		},
+		{
+			name:       "template for multiple repositories",
+			outputFlag: filepath.Join(dir, "{{.Repo}}-{{.Timestamp}}.csv"),
+			multi:      true,
+			expected:   filepath.Join(dir, "group-project-20240131T120000Z.csv"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := outputPathFor(tt.outputFlag, "group/project", tt.multi, outputFormat{}, startedAt)
+			if err != nil {
+				t.Fatalf("outputPathFor() unexpected error = %v", err)
+			}
+			if path != tt.expected {
+				t.Errorf("outputPathFor() = %q, want %q", path, tt.expected)
+			}
+		})
+	}
+
+	// Template directories are created
+	if _, err := os.Stat(filepath.Join(dir, "2024-01-31")); err != nil {
+		t.Errorf("Template directory should be created: %v", err)
+	}
+}
+
+func TestFetchManyRefs_OutputTemplate(t *testing.T) {
+	api := newMockAPI()
+	for _, project := range []string{"acme/a", "acme/b"} {
+		api.refs[project] = []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaaaaaa"}}
+		api.projects[project] = &gitlab.ProjectInfo{Path: project, TotalMergeRequests: 1}
+	}
+
+	dir := t.TempDir()
+	template := filepath.Join(dir, "out", "{{.Project}}.csv")
+
+	_, err := fetchManyRefs(context.Background(), api, nil, []string{"acme/a", "acme/b"}, "", template, 2, outputFormat{})
+	if err != nil {
+		t.Fatalf("fetchManyRefs() unexpected error = %v", err)
+	}
+
+	for _, name := range []string{"out/acme/a.csv", "out/acme/b.csv", "out/fetch-summary.csv"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s to be written: %v", name, err)
+		}
+	}
+}
+
+func TestFetchOnce_SeveralRepositories(t *testing.T) {
+	api := newMockAPI()
+	for _, project := range []string{"acme/a", "acme/b"} {
+		api.refs[project] = []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaaaaaa"}}
+		api.projects[project] = &gitlab.ProjectInfo{Path: project, TotalMergeRequests: 1}
+	}
+
+	dir := t.TempDir()
+	s, err := fetchOnce(context.Background(), api, fetchOptions{repositories: []string{"acme/a", "acme/b"}, output: dir, concurrency: 1})
+	if err != nil {
+		t.Fatalf("fetchOnce() unexpected error = %v", err)
+	}
+	if s.Repositories != 2 || s.Refs != 2 {
+		t.Errorf("fetchOnce() summary = %+v, want 2 repositories and 2 references", s)
+	}
+
+	for _, name := range []string{"acme-a.csv", "acme-b.csv", summaryFilename} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s to be written: %v", name, err)
+		}
+	}
+}
+
+func TestFetchCombinedRefs(t *testing.T) {
+	api := newMockAPI()
+	api.refs["acme/a"] = []gitlab.MergeRequestRef{{IID: 2, HeadSHA: "bbbbbbb"}, {IID: 1, HeadSHA: "aaaaaaa"}}
+	api.refs["acme/b"] = []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "ccccccc"}}
+
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "acme.csv")
+	format := outputFormat{sortByIID: true}
+
+	s, err := fetchCombinedRefs(context.Background(), api, []string{"acme/a", "acme/b", "acme/missing"}, "", outputPath, 2, format)
+	if err == nil {
+		t.Error("fetchCombinedRefs() expected an error for the missing repository")
+	}
+	if s.Repositories != 3 || s.Failed != 1 || s.Refs != 3 {
+		t.Errorf("fetchCombinedRefs() summary = %+v", s)
+	}
+
+	counts := make(map[string]int)
+	var iids []int
+	for ref, err := range csv.ProjectRefsFromFile(outputPath, "") {
+		if err != nil {
+			t.Fatalf("ProjectRefsFromFile() unexpected error = %v", err)
+		}
+		counts[ref.Project]++
+		if ref.Project == "acme/a" {
+			iids = append(iids, ref.IID)
+		}
+	}
+	if counts["acme/a"] != 2 || counts["acme/b"] != 1 {
+		t.Errorf("Combined file rows per project = %v", counts)
+	}
+	if !slices.Equal(iids, []int{1, 2}) {
+		t.Errorf("acme/a IIDs = %v, want sorted [1 2]", iids)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, summaryFilename)); err != nil {
+		t.Errorf("Summary file should be written next to the combined file: %v", err)
+	}
+}
+
+func TestFetchCombinedRefs_Spooled(t *testing.T) {
+	api := newMockAPI()
+	api.refs["acme/a"] = []gitlab.MergeRequestRef{{IID: 2, HeadSHA: "bbbbbbb"}, {IID: 1, HeadSHA: "aaaaaaa"}}
+
+	// Each project is spooled to a temporary queue, which is removed once copied
+	spoolDir := t.TempDir()
+	t.Setenv("TMPDIR", spoolDir)
+
+	outputPath := filepath.Join(t.TempDir(), "acme.csv")
+	if _, err := fetchCombinedRefs(context.Background(), api, []string{"acme/a"}, "", outputPath, 1, outputFormat{}); err != nil {
+		t.Fatalf("fetchCombinedRefs() unexpected error = %v", err)
+	}
+
+	// Without --sort the rows keep the order GitLab listed them in
+	var iids []int
+	for ref, err := range csv.ProjectRefsFromFile(outputPath, "") {
+		if err != nil {
+			t.Fatalf("ProjectRefsFromFile() unexpected error = %v", err)
+		}
+		iids = append(iids, ref.IID)
+	}
+	if !slices.Equal(iids, []int{2, 1}) {
+		t.Errorf("acme/a IIDs = %v, want [2 1]", iids)
+	}
+
+	entries, err := os.ReadDir(spoolDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Spool files left behind: %v", entries)
+	}
+}
+
+func TestOutputFormat_Filename(t *testing.T) {
+	filename, err := outputFormat{name: output.FormatCSV}.filename("group/project")
+	if err != nil {
+		t.Fatalf("filename() unexpected error = %v", err)
+	}
+	if filename != "group-project.csv" {
+		t.Errorf("filename() = %q, want group-project.csv", filename)
+	}
+
+	if _, err := (outputFormat{name: "xml"}).filename("group/project"); err == nil {
+		t.Error("filename() expected error for an unregistered format, got nil")
+	}
+}
+
+func TestFetchRefsToFile_GitLabServer(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	srv.AddProject("group/project",
+		gitlabtest.MergeRequest{IID: 2, SHA: "2222222", BaseSHA: "0000000"},
+		gitlabtest.MergeRequest{IID: 1, SHA: "1111111", BaseSHA: "0000000", State: "merged"},
+	)
+	srv.RateLimit(1)
+
+	client, err := gitlab.NewClient("token", srv.URL, gitlab.WithRateLimit(0, 1), gitlab.WithLogger(logger))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "refs.csv")
+	count, _, err := fetchRefsToFile(context.Background(), client, "group/project", "", outputPath, outputFormat{})
+	if err != nil {
+		t.Fatalf("fetchRefsToFile() unexpected error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 references, got %d", count)
+	}
+
+	refs, err := os.ReadFile(outputPath)
+	if err != nil || string(refs) != "# gh-gl-create-refs refs v1\n2,2222222\n1,1111111\n" {
+		t.Errorf("References file = %q, %v", refs, err)
+	}
+}
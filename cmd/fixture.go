@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"github.com/amenocal/gh-gl-create-refs/pkg/replay"
+)
+
+// recorder records the GitLab API requests of the run when --record-fixture is set
+var recorder *replay.Recorder
+
+// saveFixture writes the requests recorded during the run to --record-fixture
+func saveFixture() error {
+	if recorder == nil {
+		return nil
+	}
+
+	filename := rootCmd.PersistentFlags().Lookup("record-fixture").Value.String()
+	fixture := recorder.Fixture()
+	if err := fixture.Save(filename); err != nil {
+		return err
+	}
+	logger.Info("recorded API requests", "file", filename, "interactions", len(fixture.Interactions))
+	return nil
+}
@@ -0,0 +1,13 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// addFormatFlag registers --format on a command that writes merge request references
+func addFormatFlag(cmd *cobra.Command) {
+	cmd.Flags().String("format", output.FormatCSV, "Format of the merge request references written: "+strings.Join(output.Formats(), ", "))
+}
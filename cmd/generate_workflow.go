@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/workflow"
+	"github.com/spf13/cobra"
+)
+
+var generateWorkflowCmd = &cobra.Command{
+	Use:   "generate-workflow",
+	Short: "Generate a GitHub Actions workflow that runs fetch-refs and create-refs on a schedule",
+	Long: `Generate a ready-to-use GitHub Actions workflow that installs this extension and runs it
+on a schedule, instead of writing the workflow by hand.
+
+The workflow fetches the merge request references of --repository, --group or --manifest
+into refs.csv, and with --target, --github-target or --mapping then creates the branches
+with create-refs --update-existing, so each run moves the branches of merge requests that
+got new commits and leaves the others as they are.
+--group and --manifest are fetched into one combined file, so their branches can only be
+created with --mapping. --incremental keeps the state file and refs.csv in the Actions cache
+between runs so only updated merge requests are fetched. --verify validates refs.csv and
+checks its checksum before any branch is created. The references are uploaded as an
+artifact of every run.
+
+Tokens are read from repository secrets: the GitLab token from --gitlab-token-secret
+(GITLAB_TOKEN by default), and the GitHub token from --github-token-secret, or the
+workflow's own token when it is not set. Creating branches in another GitHub repository
+needs a secret with access to it.
+
+The workflow is written to standard output, or to --output. Missing directories are created.
+
+Examples:
+  gh gl-create-refs generate-workflow -r group/project --incremental --target target/project > .github/workflows/gitlab-refs.yml
+  gh gl-create-refs generate-workflow -r group/project --github-target org/project --verify --github-token-secret MIGRATION_TOKEN -o .github/workflows/gitlab-refs.yml
+  gh gl-create-refs generate-workflow --group acme --mapping repos.yaml --schedule "30 1 * * 1-5" -o .github/workflows/gitlab-refs.yml`,
+	Args: cobra.NoArgs,
+	RunE: runGenerateWorkflow,
+}
+
+func init() {
+	rootCmd.AddCommand(generateWorkflowCmd)
+
+	generateWorkflowCmd.Flags().StringP("repository", "r", "", "GitLab repository path to fetch")
+	generateWorkflowCmd.Flags().StringP("group", "g", "", "GitLab group path: fetch every project in the group and its subgroups")
+	generateWorkflowCmd.Flags().StringP("manifest", "m", "", "File in the workflow's repository listing GitLab repository paths to fetch")
+	generateWorkflowCmd.Flags().StringP("base-url", "b", "", "GitLab base URL (default: https://gitlab.com)")
+	generateWorkflowCmd.Flags().String("schedule", workflow.DefaultSchedule, "Five-field cron expression the workflow runs on, in UTC")
+	generateWorkflowCmd.Flags().Bool("incremental", false, "Only fetch merge requests updated since the previous run, keeping state in the Actions cache")
+	generateWorkflowCmd.Flags().String("target", "", "Create or update the branches in this GitLab repository after fetching")
+	generateWorkflowCmd.Flags().String("github-target", "", "Create the branches in this GitHub repository (owner/name) after fetching")
+	generateWorkflowCmd.Flags().String("mapping", "", "Mapping file in the workflow's repository: create each project's branches in its GitHub repository")
+	generateWorkflowCmd.Flags().Bool("verify", false, "Validate the fetched references and check their checksum before creating branches")
+	generateWorkflowCmd.Flags().String("name", workflow.DefaultName, "Workflow name")
+	generateWorkflowCmd.Flags().String("gitlab-token-secret", workflow.DefaultGitLabTokenSecret, "Repository secret holding the GitLab token")
+	generateWorkflowCmd.Flags().String("github-token-secret", "", "Repository secret holding a GitHub token for creating branches (default: the workflow's token)")
+	generateWorkflowCmd.Flags().StringP("output", "o", "", "Write the workflow to this file instead of standard output")
+
+	generateWorkflowCmd.MarkFlagsOneRequired("repository", "group", "manifest")
+	generateWorkflowCmd.MarkFlagsMutuallyExclusive("repository", "group", "manifest")
+	generateWorkflowCmd.MarkFlagsMutuallyExclusive("target", "github-target", "mapping")
+}
+
+func runGenerateWorkflow(cmd *cobra.Command, args []string) error {
+	incremental, _ := cmd.Flags().GetBool("incremental")
+	verify, _ := cmd.Flags().GetBool("verify")
+	outputFile := cmd.Flag("output").Value.String()
+
+	opts := workflow.Options{
+		Name:              cmd.Flag("name").Value.String(),
+		Schedule:          cmd.Flag("schedule").Value.String(),
+		Repository:        cmd.Flag("repository").Value.String(),
+		Group:             cmd.Flag("group").Value.String(),
+		Manifest:          cmd.Flag("manifest").Value.String(),
+		BaseURL:           cmd.Flag("base-url").Value.String(),
+		Incremental:       incremental,
+		Target:            cmd.Flag("target").Value.String(),
+		GitHubTarget:      cmd.Flag("github-target").Value.String(),
+		Mapping:           cmd.Flag("mapping").Value.String(),
+		Verify:            verify,
+		GitLabTokenSecret: cmd.Flag("gitlab-token-secret").Value.String(),
+		GitHubTokenSecret: cmd.Flag("github-token-secret").Value.String(),
+	}
+
+	var buf bytes.Buffer
+	if err := workflow.Generate(&buf, opts); err != nil {
+		return err
+	}
+
+	if outputFile == "" {
+		_, err := cmd.OutOrStdout().Write(buf.Bytes())
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(outputFile, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write workflow: %w", err)
+	}
+
+	logger.Info("wrote workflow", "file", outputFile)
+	return nil
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/auth"
+	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+	"github.com/amenocal/gh-gl-create-refs/pkg/github"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/lock"
+	"github.com/spf13/cobra"
+)
+
+// branchExistsHint suggests what to do about branches that could not be created because
+// their name is taken
+const branchExistsHint = "Run 'create-refs plan' to see which branches already exist and 'create-refs apply' to create only the missing ones and move the outdated ones, or use --branch-prefix to create branches with other names."
+
+// refExistsHint is branchExistsHint for GitHub targets, which plan and apply don't support
+const refExistsHint = "Run the same command with --update-existing to leave the branches already at their merge request's head commit as they are and move the others to it, or use --branch-prefix to create branches with other names."
+
+// hintError attaches a suggestion for fixing an error without changing its message
+type hintError struct {
+	err  error
+	hint string
+}
+
+func (e *hintError) Error() string {
+	return e.err.Error()
+}
+
+func (e *hintError) Unwrap() error {
+	return e.err
+}
+
+// withHint makes hint be printed after err when err ends the command
+func withHint(err error, hint string) error {
+	return &hintError{err: err, hint: hint}
+}
+
+// errorHint returns a suggestion for fixing the error cmd ended with, or "" when there is
+// none. A hint attached with withHint takes precedence over the hints for classes of errors.
+func errorHint(cmd *cobra.Command, err error) string {
+	var hinted *hintError
+	if errors.As(err, &hinted) {
+		return hinted.hint
+	}
+
+	switch {
+	case errors.Is(err, errInterrupted):
+		return resumeHint(cmd, "")
+	case errors.Is(err, gitlab.ErrUnauthorized):
+		if hint := baseURLHint(cmd); hint != "" {
+			return hint
+		}
+		if gitlab.StatusCode(err) == 403 {
+			if sudo := sudoUser(cmd); sudo != "" {
+				return "Requests on behalf of another user with --sudo need an administrator's token with the sudo scope, and the api or read_api scope, and " + sudo + " must have access to the project. Check the token with: " + tokenInfoCommand(cmd)
+			}
+			return "The token was accepted but lacks permission. Fetching needs the read_api scope and creating branches the api scope and at least the Developer role. Check the token with: " + tokenInfoCommand(cmd)
+		}
+		return "The token is missing, invalid, expired or for another GitLab instance; use --base-url for self-managed instances. Check the token with: " + tokenInfoCommand(cmd) + ", or store a new one with: " + authLoginCommand(cmd)
+	case errors.Is(err, gitlab.ErrInsufficientAccess):
+		return "Use a token with the api scope whose user has at least the Developer role, and a --branch-prefix that no protected branch pattern matches. Check the token with: " + tokenInfoCommand(cmd) + ". If the check is wrong, for instance for an administrator, skip it with --skip-access-check."
+	case errors.Is(err, gitlab.ErrProjectNotFound):
+		if hint := baseURLHint(cmd); hint != "" {
+			return hint
+		}
+		if sudo := sudoUser(cmd); sudo != "" {
+			return "With --sudo, GitLab reports projects " + sudo + " cannot see, and users that don't exist, as not found. Check the username and that " + sudo + " is a member of the project or of a parent group."
+		}
+		return "GitLab reports projects the token cannot see as not found. Check the full path, including subgroups, and that the token's user is a member of the project or of a parent group. Check the token's access with: " + tokenInfoCommand(cmd)
+	case errors.Is(err, gitlab.ErrProjectMoved):
+		return "The project was renamed or transferred since its path was recorded. Use its new path, for instance by fetching the references again or making a new plan."
+	case errors.Is(err, gitlab.ErrBranchExists):
+		return branchExistsHint
+	case errors.Is(err, github.ErrRefExists):
+		return refExistsHint
+	case errors.Is(err, gitlab.ErrOffline):
+		return "--offline only replays GET requests an earlier run recorded. Record them by running the same command online with the same --cache-dir and token, and use create-refs --dry-run or plan, which don't change GitLab."
+	case errors.Is(err, gitlab.ErrBudgetExhausted):
+		return resumeHint(cmd, " once more requests may be used") + " See how many requests a run needs with: gh gl-create-refs estimate"
+	case errors.Is(err, gitlab.ErrRateLimited):
+		return "Lower --rps, and --concurrency for multi-project runs, or wait for the rate limit to reset and run the command again."
+	case errors.Is(err, auth.ErrNoGitHubToken):
+		return "Log in to GitHub with: gh auth login"
+	case errors.Is(err, csv.ErrDuplicateIID):
+		return validateHint(cmd, "Keep one row per merge request.")
+	case errors.Is(err, csv.ErrInvalidSHA):
+		return validateHint(cmd, "The file may be corrupted or hand-edited; fetch-refs writes full 40-character SHAs.")
+	case errors.Is(err, lock.ErrLocked):
+		return "Wait for the other run to finish, or stop it. Locks are released when a run exits, even when it crashes, so there is nothing to clean up."
+	}
+	return ""
+}
+
+// resumeHint tells what a run of cmd that stopped early kept, and how to carry on with the
+// rest when, if not empty, is fulfilled. There is no --resume: a branch creation picks up
+// where it stopped by leaving the branches already created as they are, and a fetch by
+// fetching again.
+func resumeHint(cmd *cobra.Command, when string) string {
+	switch cmd.Name() {
+	case "create-refs":
+		return "The branches created so far were kept. Run the same command again" + when + " with --update-existing to create the rest: branches already at their merge request's head commit are left as they are."
+	case "apply":
+		return "The actions applied so far took effect. Make a new plan with 'create-refs plan'" + when + " and apply it to carry out the rest: branches already in place are skipped."
+	case "fetch-refs":
+		return "The outputs of the repositories fetched completely were kept, and the one being written was discarded. Run the same command again" + when + " to fetch the rest; with --incremental, the completed repositories are recorded in the state file and only what changed since is fetched for them."
+	}
+	return "Completed outputs were kept and the one being written was discarded. Run the same command again" + when + " to start over."
+}
+
+// baseURLHint suggests --base-url when the repository or group of the command is a URL on
+// another host than the GitLab instance the requests were sent to
+func baseURLHint(cmd *cobra.Command) string {
+	var urlBase string
+	if repository := flagValue(cmd, "repository"); repository != "" {
+		urlBase, _, _ = gitlab.ParseRepoPath(repository)
+	}
+	if flag := cmd.Flags().Lookup("group"); urlBase == "" && flag != nil && flag.Value.String() != "" {
+		urlBase, _, _ = gitlab.ParseGroupPath(flag.Value.String())
+	}
+	if urlBase == "" {
+		return ""
+	}
+
+	var baseURL string
+	if flag := cmd.Flags().Lookup("base-url"); flag != nil {
+		baseURL = flag.Value.String()
+	}
+	host, err := auth.Host(baseURL)
+	if err != nil {
+		return ""
+	}
+	urlHost, err := auth.Host(urlBase)
+	if err != nil || urlHost == host {
+		return ""
+	}
+	return fmt.Sprintf("The requests went to %s, but the URL you gave is on %s. Did you mean --base-url %s?", host, urlHost, urlBase)
+}
+
+// sudoUser returns the user the requests of cmd were made on behalf of with --sudo, or ""
+func sudoUser(cmd *cobra.Command) string {
+	if flag := cmd.Flags().Lookup("sudo"); flag != nil {
+		return flag.Value.String()
+	}
+	return ""
+}
+
+// validateHint follows hint with the validate command listing every problem in the
+// command's input file, when it has one
+func validateHint(cmd *cobra.Command, hint string) string {
+	if flag := cmd.Flags().Lookup("input"); flag != nil && flag.Value.String() != "" && flag.Value.String() != csv.Stdin {
+		return hint + " List every problem in the file with: gh gl-create-refs validate --input " + flag.Value.String()
+	}
+	return hint
+}
+
+// authLoginCommand returns the auth login command line for the GitLab instance of cmd
+func authLoginCommand(cmd *cobra.Command) string {
+	if baseURL := flagValue(cmd, "base-url"); baseURL != "" {
+		return "gh gl-create-refs auth login --base-url " + baseURL
+	}
+	return "gh gl-create-refs auth login"
+}
+
+// tokenInfoCommand returns the token-info command line checking the token against the
+// GitLab instance and repository of cmd
+func tokenInfoCommand(cmd *cobra.Command) string {
+	command := "gh gl-create-refs token-info"
+	for _, name := range []string{"base-url", "repository"} {
+		if value := flagValue(cmd, name); value != "" {
+			command += fmt.Sprintf(" --%s %s", name, value)
+		}
+	}
+	return command
+}
+
+// flagValue returns the value of the flag name of cmd, the first one of a repeated flag such
+// as --repository, or "" when cmd has no such flag
+func flagValue(cmd *cobra.Command, name string) string {
+	flag := cmd.Flags().Lookup(name)
+	if flag == nil {
+		return ""
+	}
+	if values, err := cmd.Flags().GetStringArray(name); err == nil {
+		if len(values) == 0 {
+			return ""
+		}
+		return values[0]
+	}
+	return flag.Value.String()
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+	"github.com/amenocal/gh-gl-create-refs/pkg/github"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/spf13/cobra"
+	gogitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestErrorHint(t *testing.T) {
+	forbidden := fmt.Errorf("%w: %w", gitlab.ErrUnauthorized, &gogitlab.ErrorResponse{Response: &http.Response{StatusCode: http.StatusForbidden}})
+
+	tests := []struct {
+		name     string
+		args     []string
+		err      error
+		expected string
+	}{
+		{"no hint", nil, errors.New("boom"), ""},
+		{"attached hint", nil, fmt.Errorf("run failed: %w", withHint(errors.New("boom"), "try again")), "try again"},
+		{"attached hint wins", nil, withHint(gitlab.ErrBranchExists, "try again"), "try again"},
+		{"interrupted", nil, fmt.Errorf("stopped: %w", errInterrupted), "Run the same command again to start over"},
+		{"budget exhausted", nil, fmt.Errorf("stopped: %w", gitlab.ErrBudgetExhausted), "once more requests may be used"},
+		{"offline cache miss", nil, fmt.Errorf("failed to fetch: %w", gitlab.ErrOffline), "running the same command online with the same --cache-dir"},
+		{
+			"unauthorized on another instance",
+			[]string{"--repository", "https://gitlab.example.com/group/project"},
+			gitlab.ErrUnauthorized,
+			"The requests went to gitlab.com, but the URL you gave is on gitlab.example.com. Did you mean --base-url https://gitlab.example.com?",
+		},
+		{
+			"group on another instance",
+			[]string{"--group", "https://gitlab.example.com/groups/platform", "--base-url", "https://gitlab.com"},
+			gitlab.ErrProjectNotFound,
+			"Did you mean --base-url https://gitlab.example.com?",
+		},
+		{
+			"unauthorized with matching base URL",
+			[]string{"--repository", "https://gitlab.example.com/group/project", "--base-url", "https://gitlab.example.com"},
+			gitlab.ErrUnauthorized,
+			"Check the token with: gh gl-create-refs token-info --base-url https://gitlab.example.com --repository https://gitlab.example.com/group/project",
+		},
+		{"unauthorized suggests logging in", []string{"--base-url", "https://gitlab.example.com"}, gitlab.ErrUnauthorized, "store a new one with: gh gl-create-refs auth login --base-url https://gitlab.example.com"},
+		{"forbidden", []string{"--repository", "group/project"}, forbidden, "the api scope"},
+		{"not found", []string{"--repository", "group/sub/project"}, gitlab.ErrProjectNotFound, "token-info --repository group/sub/project"},
+		{"forbidden with sudo", []string{"--sudo", "alice"}, forbidden, "administrator's token with the sudo scope"},
+		{"not found with sudo", []string{"--sudo", "alice"}, gitlab.ErrProjectNotFound, "projects alice cannot see"},
+		{"gitlab branch exists", nil, gitlab.ErrBranchExists, "--branch-prefix"},
+		{"github ref exists", nil, github.ErrRefExists, "Run the same command with --update-existing"},
+		{"rate limited", nil, gitlab.ErrRateLimited, "Lower --rps"},
+		{"duplicate IID", nil, fmt.Errorf("refs.csv: %w", csv.ErrDuplicateIID), "Keep one row per merge request"},
+		{"invalid SHA", nil, fmt.Errorf("refs.csv: %w", csv.ErrInvalidSHA), "full 40-character SHAs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test"}
+			cmd.Flags().String("repository", "", "")
+			cmd.Flags().String("group", "", "")
+			cmd.Flags().String("base-url", "", "")
+			cmd.Flags().String("sudo", "", "")
+			if err := cmd.Flags().Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			got := errorHint(cmd, tt.err)
+			if tt.expected == "" {
+				if got != "" {
+					t.Errorf("errorHint() = %q, want no hint", got)
+				}
+				return
+			}
+			if !strings.Contains(got, tt.expected) {
+				t.Errorf("errorHint() = %q, want it to contain %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResumeHint(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected string
+	}{
+		{"create-refs", "Run the same command again with --update-existing"},
+		{"apply", "Make a new plan with 'create-refs plan'"},
+		{"fetch-refs", "with --incremental, the completed repositories are recorded"},
+		{"export-discussions", "Run the same command again to start over"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			got := errorHint(&cobra.Command{Use: tt.command}, fmt.Errorf("stopped: %w", errInterrupted))
+			if !strings.Contains(got, tt.expected) {
+				t.Errorf("errorHint() = %q, want it to contain %q", got, tt.expected)
+			}
+		})
+	}
+}
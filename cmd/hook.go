@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/hook"
+	"github.com/amenocal/gh-gl-create-refs/pkg/redact"
+	"github.com/spf13/cobra"
+)
+
+// createHooks are the commands create-refs runs before, during and after creating branches
+type createHooks struct {
+	pre  string
+	post string
+	ref  string
+}
+
+// addHookFlags adds the hook flags of create-refs to cmd
+func addHookFlags(cmd *cobra.Command) {
+	cmd.Flags().String("pre-create-hook", "", "Shell command run before any branch is created, with the projects as JSON on stdin; a failure stops the run")
+	cmd.Flags().String("post-create-hook", "", "Shell command run once the run is over, with its counts and error as JSON on stdin")
+	cmd.Flags().String("ref-hook", "", "Shell command run after the branch of each reference is created, fails or is skipped, with the branch as JSON on stdin")
+}
+
+// hookFlags reads the hook flags of cmd
+func hookFlags(cmd *cobra.Command) createHooks {
+	return createHooks{
+		pre:  cmd.Flag("pre-create-hook").Value.String(),
+		post: cmd.Flag("post-create-hook").Value.String(),
+		ref:  cmd.Flag("ref-hook").Value.String(),
+	}
+}
+
+// preCreate runs the pre-create hook, if any
+func (h createHooks) preCreate(ctx context.Context, event hook.PreCreateEvent) error {
+	if h.pre == "" {
+		return nil
+	}
+	event.Event = hook.EventPreCreate
+	logger.Info("running hook", "event", event.Event)
+	return hook.Run(ctx, h.pre, event.Event, event)
+}
+
+// branchDone runs the ref hook, if any. A failing hook is only logged: the branch is
+// already created, and one broken notification should not stop a migration.
+func (h createHooks) branchDone(ctx context.Context, event hook.RefEvent) {
+	if h.ref == "" {
+		return
+	}
+	event.Event = hook.EventRef
+	if err := hook.Run(ctx, h.ref, event.Event, event); err != nil {
+		logger.Warn("ref hook failed", "repository", event.Repository, "branch", event.Branch, "error", err)
+	}
+}
+
+// postCreate runs the post-create hook, if any, with the counts and error of the run. It
+// runs even when the run was interrupted, so it gets a context of its own.
+func (h createHooks) postCreate(counts branchCounts, runErr error) error {
+	if h.post == "" {
+		return nil
+	}
+	event := hook.PostCreateEvent{
+		Event:     hook.EventPostCreate,
+		Total:     counts.total,
+		Created:   counts.created,
+		Failed:    counts.failed,
+		Skipped:   counts.skipped,
+		Existing:  counts.existing,
+		Updated:   counts.updated,
+		Unchanged: counts.unchanged,
+	}
+	if runErr != nil {
+		event.Error = redact.String(runErr.Error())
+	}
+	logger.Info("running hook", "event", event.Event)
+	return hook.Run(context.Background(), h.post, event.Event, event)
+}
+
+// branchStatus is the hook status of a branch that failed with err, or was created when err is nil
+func branchStatus(err error) (string, string) {
+	if err != nil {
+		return hook.StatusFailed, redact.String(err.Error())
+	}
+	return hook.StatusCreated, ""
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/output"
+	"github.com/amenocal/gh-gl-create-refs/pkg/projectexport"
+	"github.com/spf13/cobra"
+)
+
+var importExportCmd = &cobra.Command{
+	Use:   "import-export",
+	Short: "Write merge request references from a GitLab project export archive",
+	Long: `Write the merge request references of a GitLab project export archive, the .tar.gz file
+GitLab produces with "Export project", without calling the GitLab API. Use it for projects
+whose instance or API access goes away before the migration is finished.
+
+--archive is the export archive, or the tree/project/merge_requests.ndjson file extracted
+from it. The head SHA of each merge request is that of its latest diff; merge requests
+without one, such as those whose diff was never generated, are skipped and counted. The
+references are written in IID order in the format selected with --format, like fetch-refs
+writes them, so create-refs can read them.
+
+Archives of GitLab before 14.0, which hold a single project.json file, are not supported.
+
+Examples:
+  gh gl-create-refs import-export --archive 2024-01-31_12-00-000_group_project_export.tar.gz --repository group/project
+  gh gl-create-refs import-export -a export.tar.gz -o refs.csv
+  gh gl-create-refs import-export -a merge_requests.ndjson -o refs.csv`,
+	Args: cobra.NoArgs,
+	RunE: runImportExport,
+}
+
+func init() {
+	rootCmd.AddCommand(importExportCmd)
+
+	importExportCmd.Flags().StringP("archive", "a", "", "GitLab project export archive (.tar.gz), or its merge_requests.ndjson file (required)")
+	importExportCmd.Flags().StringP("repository", "r", "", "GitLab repository path the export is of, naming the default output file")
+	importExportCmd.Flags().StringP("output", "o", "", "Output file path (default: <group-project>.csv, required without --repository)")
+	addFormatFlag(importExportCmd)
+	importExportCmd.MarkFlagRequired("archive")
+}
+
+func runImportExport(cmd *cobra.Command, args []string) error {
+	archive := cmd.Flag("archive").Value.String()
+	repository := cmd.Flag("repository").Value.String()
+	outputPath := cmd.Flag("output").Value.String()
+	format := outputFormat{name: cmd.Flag("format").Value.String()}
+
+	if outputPath == "" {
+		if repository == "" {
+			return fmt.Errorf("--output is required unless --repository is given")
+		}
+		if _, _, err := gitlab.ParseRepoPath(repository); err != nil {
+			return fmt.Errorf("failed to parse repository path: %w", err)
+		}
+		var err error
+		if outputPath, err = format.filename(repository); err != nil {
+			return err
+		}
+	}
+	if output.IsRemote(outputPath) {
+		return fmt.Errorf("import-export writes local files only; copy %s to object storage afterwards", outputPath)
+	}
+
+	count, skipped, err := importExport(archive, outputPath, format)
+	if err != nil {
+		return err
+	}
+
+	absPath, _ := filepath.Abs(outputPath)
+	statusf("%s %d merge request references to: %s\n", green("Successfully imported"), count, absPath)
+	if skipped > 0 {
+		statusf("⚠️  %s: %d merge requests\n", yellow("Skipped, no head commit"), skipped)
+	}
+	return nil
+}
+
+// importExport writes the references of the export archive to outputPath, returning how
+// many were written and how many merge requests were skipped
+func importExport(archive, outputPath string, format outputFormat) (int, int, error) {
+	logger.Info("reading project export", "file", archive)
+	result, err := projectexport.ReadFile(archive)
+	if err != nil {
+		return 0, 0, err
+	}
+	if result.Skipped > 0 {
+		logger.Warn("skipped merge requests without a head commit", "count", result.Skipped)
+	}
+
+	formatter, err := format.formatter()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	l, err := lockOutput(outputPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer releaseLock(l)
+
+	writer, err := formatter.NewWriter(outputPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer writer.Abort()
+
+	for _, ref := range result.Refs {
+		if err := writer.WriteRef(ref); err != nil {
+			return 0, 0, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return 0, 0, err
+	}
+
+	logger.Info("imported merge request references", "file", archive, "output", outputPath, "count", len(result.Refs), "skipped", result.Skipped)
+	return len(result.Refs), result.Skipped, nil
+}
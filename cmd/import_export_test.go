@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+)
+
+func TestImportExport(t *testing.T) {
+	dir := t.TempDir()
+	ndjson := filepath.Join(dir, "merge_requests.ndjson")
+	content := `{"iid":2,"merge_request_diff":{"head_commit_sha":"bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"}}
+{"iid":1,"merge_request_diff":{"head_commit_sha":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"}}
+{"iid":3}
+`
+	if err := os.WriteFile(ndjson, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	outputPath := filepath.Join(dir, "refs.csv")
+	count, skipped, err := importExport(ndjson, outputPath, outputFormat{})
+	if err != nil {
+		t.Fatalf("importExport() unexpected error = %v", err)
+	}
+	if count != 2 || skipped != 1 {
+		t.Errorf("importExport() = %d, %d, want 2 written and 1 skipped", count, skipped)
+	}
+
+	refs, err := csv.ReadRefsFromFile(outputPath)
+	if err != nil {
+		t.Fatalf("ReadRefsFromFile() unexpected error = %v", err)
+	}
+	if len(refs) != 2 || refs[0].IID != 1 || refs[1].HeadSHA != "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb" {
+		t.Errorf("refs = %+v", refs)
+	}
+	if err := csv.VerifyChecksum(outputPath); err != nil {
+		t.Errorf("VerifyChecksum() = %v", err)
+	}
+}
+
+func TestImportExport_MissingArchive(t *testing.T) {
+	dir := t.TempDir()
+	if _, _, err := importExport(filepath.Join(dir, "missing.tar.gz"), filepath.Join(dir, "refs.csv"), outputFormat{}); err == nil {
+		t.Error("importExport() expected an error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "refs.csv")); !os.IsNotExist(err) {
+		t.Error("no output should be written")
+	}
+}
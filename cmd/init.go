@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/auth"
+	"github.com/amenocal/gh-gl-create-refs/pkg/config"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/spf13/cobra"
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a configuration file interactively",
+	Long: `Create a configuration file by answering a few questions: the GitLab base URL, where
+the token comes from, the repository, the branch name prefix and the output location.
+
+Answers are checked as they are given: the token is looked up and its owner, scopes and
+expiry are shown, and the token's access to the repository is verified, like token-info.
+Use --skip-checks to write a configuration without contacting GitLab.
+
+The configuration is written to ` + config.DefaultPath + ` in the current directory, or to
+--config. Every command then uses its values for the flags not given on the command line.
+Tokens are never written to the file: it records the token file, or nothing when the token
+comes from GITLAB_TOKEN or the OS keyring.
+
+With --profile, the answers are written to that profile of the file instead, leaving the
+file's defaults and other profiles as they are, so that one file can hold a profile per
+GitLab instance. Run init again with another --profile to add one.
+
+Examples:
+  gh gl-create-refs init
+  gh gl-create-refs init --config ~/migrations/acme.yaml --force
+  gh gl-create-refs init --profile staging`,
+	Args: cobra.NoArgs,
+	RunE: runInit,
+}
+
+// Token sources offered by init
+const (
+	tokenSourceEnv     = "GITLAB_TOKEN environment variable"
+	tokenSourceKeyring = "OS keyring (see auth login)"
+	tokenSourceFile    = "Token file"
+)
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+
+	initCmd.Flags().Bool("force", false, "Overwrite an existing configuration file without asking")
+	initCmd.Flags().Bool("skip-checks", false, "Don't contact GitLab to check the token and repository")
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	err := initConfig(cmd)
+	if errors.Is(err, errNoAnswer) {
+		return fmt.Errorf("init needs answers on standard input: %w", err)
+	}
+	return err
+}
+
+// initConfig asks for the settings and writes the configuration file, or its --profile
+func initConfig(cmd *cobra.Command) error {
+	ctx := cmd.Context()
+	force, _ := cmd.Flags().GetBool("force")
+	skipChecks, _ := cmd.Flags().GetBool("skip-checks")
+
+	path := configPath(cmd)
+	profile := flagValue(cmd, "profile")
+	p := newPrompter(cmd.InOrStdin(), cmd.ErrOrStderr())
+
+	// Re-running init edits the existing configuration, or the existing profile
+	file, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	existing, exists := file, false
+	if profile != "" {
+		existing, exists = file.Profiles[profile]
+	} else if _, err := os.Stat(path); err == nil {
+		exists = true
+	}
+
+	if exists && !force {
+		what := path
+		if profile != "" {
+			what = fmt.Sprintf("profile %s of %s", profile, path)
+		}
+		overwrite, err := p.confirm(fmt.Sprintf("%s already exists. Overwrite it?", what), false)
+		if err != nil {
+			return err
+		}
+		if !overwrite {
+			return fmt.Errorf("%s already exists; use --force to overwrite it", what)
+		}
+	}
+
+	var cfg config.Config
+
+	cfg.BaseURL, err = p.ask("GitLab base URL", cmp.Or(existing.BaseURL, "https://gitlab.com"), func(s string) error {
+		_, err := auth.Host(s)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var client *gitlab.Client
+	var scopes []string
+	for {
+		source := tokenSourceKeyring
+		switch {
+		case existing.TokenFile != "":
+			source = tokenSourceFile
+		case os.Getenv("GITLAB_TOKEN") != "":
+			source = tokenSourceEnv
+		}
+
+		source, err = p.choose("Where should the GitLab token come from?", []string{tokenSourceEnv, tokenSourceKeyring, tokenSourceFile}, source)
+		if err != nil {
+			return err
+		}
+
+		cfg.TokenFile = ""
+		if source == tokenSourceFile {
+			cfg.TokenFile, err = p.ask("Token file", existing.TokenFile, func(s string) error {
+				if s == "" {
+					return fmt.Errorf("a token file is required")
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if skipChecks {
+			break
+		}
+
+		token, err := initToken(source, cfg)
+		if err != nil {
+			p.printf("❌ %v\n", err)
+			continue
+		}
+
+		client, scopes, err = checkInitToken(cmd, cfg.BaseURL, token)
+		if err != nil {
+			p.printf("❌ Token check failed: %v\n", err)
+			continue
+		}
+		break
+	}
+
+	cfg.Repository, err = p.ask("GitLab repository (optional, e.g. group/project)", existing.Repository, func(s string) error {
+		if s == "" {
+			return nil
+		}
+		_, projectPath, err := gitlab.ParseRepoPath(s)
+		if err != nil || client == nil {
+			return err
+		}
+
+		access, err := client.GetProjectAccess(ctx, projectPath, scopes)
+		if err != nil {
+			return err
+		}
+		printProjectAccess(access)
+		if !access.CanReadMergeRequests {
+			return fmt.Errorf("the token cannot read merge requests of %s", projectPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	cfg.BranchPrefix, err = p.ask("Branch name prefix", cmp.Or(existing.BranchPrefix, defaultBranchPrefix), validateBranchPrefix)
+	if err != nil {
+		return err
+	}
+
+	cfg.Output, err = p.ask("fetch-refs output file or path template (optional, default: named after the repository)", existing.Output, nil)
+	if err != nil {
+		return err
+	}
+
+	// Settings the wizard doesn't ask about are kept
+	cfg.Naming = existing.Naming
+	cfg.Sudo = existing.Sudo
+	cfg.AuthType = existing.AuthType
+	cfg.OAuthClientID = existing.OAuthClientID
+	cfg.CACert = existing.CACert
+	cfg.RPS = existing.RPS
+	cfg.Burst = existing.Burst
+	cfg.Notifications = existing.Notifications
+	cfg.Email = existing.Email
+	cfg.Profiles = existing.Profiles
+
+	if profile != "" {
+		if file.Profiles == nil {
+			file.Profiles = make(map[string]config.Config)
+		}
+		file.Profiles[profile] = cfg
+		cfg = file
+	}
+
+	if err := config.Save(path, cfg); err != nil {
+		return err
+	}
+
+	statusf("%s Wrote %s\n", green("✅"), path)
+	return nil
+}
+
+// initToken reads the token from the source chosen in init, failing when there is none
+func initToken(source string, cfg config.Config) (string, error) {
+	switch source {
+	case tokenSourceEnv:
+		if token := os.Getenv("GITLAB_TOKEN"); token != "" {
+			return token, nil
+		}
+		return "", fmt.Errorf("GITLAB_TOKEN is not set")
+	case tokenSourceFile:
+		return auth.ReadTokenFile(cfg.TokenFile)
+	default:
+		token := lookupStoredToken(cfg.BaseURL)
+		if token == "" {
+			return "", fmt.Errorf("no token is stored in the OS keyring for %s; log in with: gh gl-create-refs auth login --base-url %s", cfg.BaseURL, cfg.BaseURL)
+		}
+		return token, nil
+	}
+}
+
+// checkInitToken creates a client authenticated with token and shows the token's details,
+// failing when it is expired or inactive
+func checkInitToken(cmd *cobra.Command, baseURL, token string) (*gitlab.Client, []string, error) {
+	options, err := clientOptions(baseURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	tlsConfig, err := tlsConfigFromFlags()
+	if err != nil {
+		return nil, nil, err
+	}
+	if tlsConfig != nil {
+		options = append(options, gitlab.WithTLSConfig(tlsConfig))
+	}
+
+	client, err := gitlab.NewClient(token, baseURL, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := client.GetTokenInfo(cmd.Context())
+	if err != nil {
+		return nil, nil, err
+	}
+	printTokenInfo(info, time.Now())
+
+	if info.Expired(time.Now()) || info.Revoked || !info.Active {
+		return nil, nil, fmt.Errorf("the token is expired or inactive")
+	}
+	return client, info.Scopes, nil
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+func TestRunInit(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    string
+		flags       []string
+		input       string
+		expected    config.Config
+		expectError bool
+	}{
+		{
+			name: "new configuration",
+			// base URL, token source, token file, repository, branch prefix, output
+			input: "https://gitlab.example.com\n3\ntoken.txt\ngroup/project\ngl-mr-\nrefs.csv\n",
+			expected: config.Config{
+				BaseURL:      "https://gitlab.example.com",
+				TokenFile:    "token.txt",
+				Repository:   "group/project",
+				BranchPrefix: "gl-mr-",
+				Output:       "refs.csv",
+			},
+		},
+		{
+			name:  "defaults and re-asked invalid answers",
+			input: "\n1\n\n-bad\n\n\n",
+			expected: config.Config{
+				BaseURL:      "https://gitlab.com",
+				BranchPrefix: defaultBranchPrefix,
+			},
+		},
+		{
+			name:     "existing configuration is the default",
+			existing: "base-url: https://gitlab.example.com\ntoken-file: token.txt\nrepository: group/project\n",
+			flags:    []string{"--force"},
+			input:    "\n\n\n\n\n\n",
+			expected: config.Config{
+				BaseURL:      "https://gitlab.example.com",
+				TokenFile:    "token.txt",
+				Repository:   "group/project",
+				BranchPrefix: defaultBranchPrefix,
+			},
+		},
+		{
+			name:        "existing configuration is kept unless confirmed",
+			existing:    "base-url: https://gitlab.example.com\n",
+			input:       "n\n",
+			expected:    config.Config{BaseURL: "https://gitlab.example.com"},
+			expectError: true,
+		},
+		{
+			name:     "new profile",
+			existing: "base-url: https://gitlab.com\nrepository: group/project\n",
+			flags:    []string{"--profile", "prod"},
+			input:    "https://gitlab.example.com\n3\nprod-token.txt\n\n\n\n",
+			expected: config.Config{
+				BaseURL:    "https://gitlab.com",
+				Repository: "group/project",
+				Profiles: map[string]config.Config{
+					"prod": {BaseURL: "https://gitlab.example.com", TokenFile: "prod-token.txt", BranchPrefix: defaultBranchPrefix},
+				},
+			},
+		},
+		{
+			name:        "existing profile is kept unless confirmed",
+			existing:    "profiles:\n  prod:\n    base-url: https://gitlab.example.com\n",
+			flags:       []string{"--profile", "prod"},
+			input:       "n\n",
+			expected:    config.Config{Profiles: map[string]config.Config{"prod": {BaseURL: "https://gitlab.example.com"}}},
+			expectError: true,
+		},
+		{
+			name:        "input ends early",
+			input:       "https://gitlab.example.com\n",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITLAB_TOKEN", "")
+			path := filepath.Join(t.TempDir(), config.DefaultPath)
+			if tt.existing != "" {
+				if err := os.WriteFile(path, []byte(tt.existing), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cmd := &cobra.Command{}
+			cmd.Flags().String("config", "", "")
+			cmd.Flags().String("profile", "", "")
+			cmd.Flags().Bool("force", false, "")
+			cmd.Flags().Bool("skip-checks", false, "")
+			if err := cmd.ParseFlags(append([]string{"--config", path, "--skip-checks"}, tt.flags...)); err != nil {
+				t.Fatal(err)
+			}
+			cmd.SetIn(strings.NewReader(tt.input))
+			cmd.SetErr(io.Discard)
+
+			err := runInit(cmd, nil)
+			if tt.expectError != (err != nil) {
+				t.Fatalf("runInit() error = %v, expectError %v", err, tt.expectError)
+			}
+
+			got, err := config.Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("config = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// errInterrupted ends a run stopped by Ctrl+C or SIGTERM after its in-flight work finished
+var errInterrupted = errors.New("interrupted")
+
+// interruptKey is the context key of the channel closed on the first interrupt
+type interruptKey struct{}
+
+// withInterrupt returns a context whose interrupted reports true once done is closed. The
+// context itself is not cancelled, so requests already sent are allowed to finish.
+func withInterrupt(ctx context.Context, done <-chan struct{}) context.Context {
+	return context.WithValue(ctx, interruptKey{}, done)
+}
+
+// interruptDone returns the channel closed on the first interrupt, or nil, which never
+// receives, when ctx has none
+func interruptDone(ctx context.Context) <-chan struct{} {
+	done, _ := ctx.Value(interruptKey{}).(<-chan struct{})
+	return done
+}
+
+// interrupted returns errInterrupted once the run has been interrupted, the context's error
+// once it is cancelled, or gitlab.ErrBudgetExhausted once --max-api-calls is used up. Loops
+// call it before starting each unit of work, so that an interrupted run stops between
+// branches, merge requests or repositories. Outside the run's --window, it first waits for
+// the window to open.
+func interrupted(ctx context.Context) error {
+	select {
+	case <-interruptDone(ctx):
+		return errInterrupted
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := budgetExhausted(); err != nil {
+		return err
+	}
+	return waitForWindow(ctx)
+}
+
+// notifyInterrupt returns a context that reports the first SIGINT or SIGTERM through
+// interrupted. A second signal exits immediately. Call stop to stop listening.
+func notifyInterrupt(ctx context.Context) (context.Context, func()) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		if _, ok := <-signals; !ok {
+			return
+		}
+		logger.Warn("interrupted: finishing in-flight requests and saving progress, press Ctrl+C again to quit immediately")
+		close(done)
+
+		if _, ok := <-signals; ok {
+			os.Exit(exitInterrupted)
+		}
+	}()
+
+	stop := func() {
+		signal.Stop(signals)
+		close(signals)
+	}
+	return withInterrupt(ctx, done), stop
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+func TestInterrupted(t *testing.T) {
+	if err := interrupted(context.Background()); err != nil {
+		t.Errorf("interrupted() without interrupt = %v, want nil", err)
+	}
+
+	done := make(chan struct{})
+	ctx := withInterrupt(context.Background(), done)
+	if err := interrupted(ctx); err != nil {
+		t.Errorf("interrupted() before interrupt = %v, want nil", err)
+	}
+
+	close(done)
+	if err := interrupted(ctx); !errors.Is(err, errInterrupted) {
+		t.Errorf("interrupted() after interrupt = %v, want errInterrupted", err)
+	}
+	// The context is not cancelled, so requests in flight finish
+	if ctx.Err() != nil {
+		t.Errorf("ctx.Err() = %v, want nil", ctx.Err())
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := interrupted(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("interrupted() of a cancelled context = %v, want context.Canceled", err)
+	}
+}
+
+func TestCreateBranchesInRepo_Interrupted(t *testing.T) {
+	api := newMockAPI()
+	refs := []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: "aaa"},
+		{IID: 2, HeadSHA: "bbb"},
+	}
+
+	done := make(chan struct{})
+	ctx := withInterrupt(context.Background(), done)
+
+	// Interrupt while the first branch is being created
+	seq := func(yield func(gitlab.MergeRequestRef, error) bool) {
+		for i, ref := range refs {
+			if i == 1 {
+				close(done)
+			}
+			if !yield(ref, nil) {
+				return
+			}
+		}
+	}
+
+	counts, err := createBranchesInRepo(ctx, api, seq, len(refs), "target/project", createOptions{fetch: true})
+	if !errors.Is(err, errInterrupted) {
+		t.Fatalf("createBranchesInRepo() error = %v, want errInterrupted", err)
+	}
+	if counts.created != 1 {
+		t.Errorf("created = %d, want 1", counts.created)
+	}
+	if _, ok := api.branches["target/project"]["migration-pr-2"]; ok {
+		t.Error("No branch should be created after the interrupt")
+	}
+	if exitCode(err) != exitInterrupted {
+		t.Errorf("exitCode() = %d, want %d", exitCode(err), exitInterrupted)
+	}
+}
+
+func TestFetchRefsToFile_Interrupted(t *testing.T) {
+	api := newMockAPI()
+	api.refs["group/project"] = []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaa"}, {IID: 2, HeadSHA: "bbb"}}
+	api.projects["group/project"] = &gitlab.ProjectInfo{Path: "group/project", TotalMergeRequests: 2}
+
+	done := make(chan struct{})
+	close(done)
+	ctx := withInterrupt(context.Background(), done)
+
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "refs.csv")
+
+	_, _, err := fetchRefsToFile(ctx, api, "group/project", "", outputPath, outputFormat{})
+	if !errors.Is(err, errInterrupted) {
+		t.Fatalf("fetchRefsToFile() error = %v, want errInterrupted", err)
+	}
+
+	// No partially written output is left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no files in %s after an interrupted fetch, found %d", dir, len(entries))
+	}
+}
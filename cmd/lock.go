@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/lock"
+)
+
+// lockOutput keeps other runs from writing the output or state file at path until the
+// lock is released
+func lockOutput(path string) (*lock.Lock, error) {
+	l, err := lock.Acquire(path + ".lock")
+	if err != nil {
+		return nil, fmt.Errorf("another run is writing %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// lockRepository keeps other runs on this machine from creating branches in project, a
+// GitLab project or a GitHub repository depending on kind, until the lock is released
+func lockRepository(kind, project string) (*lock.Lock, error) {
+	name := fmt.Sprintf("gh-gl-create-refs-%s-%s.lock", kind, strings.ReplaceAll(strings.ToLower(project), "/", "-"))
+	l, err := lock.Acquire(filepath.Join(os.TempDir(), name))
+	if err != nil {
+		return nil, fmt.Errorf("another run is creating branches in %s: %w", project, err)
+	}
+	return l, nil
+}
+
+// releaseLock releases l, logging rather than failing the run when it cannot
+func releaseLock(l *lock.Lock) {
+	if err := l.Release(); err != nil {
+		logger.Warn("failed to release lock", "error", err)
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// metrics aggregates API request statistics across every client created during the run
+var metrics = gitlab.NewMetrics()
+
+// runStats counts the work done during the run, for the --metrics-addr endpoint
+var runStats struct {
+	refsFetched       atomic.Int64
+	branchesCreated   atomic.Int64
+	branchesFailed    atomic.Int64
+	scheduledRuns     atomic.Int64
+	scheduledFailures atomic.Int64
+	// repositoriesQueued is how many repositories of a multi-repository fetch have not started
+	repositoriesQueued atomic.Int64
+}
+
+// reportMetrics logs the run's request metrics and writes them to --metrics-file if set
+func reportMetrics() error {
+	s := metrics.Snapshot()
+	if s.Requests == 0 {
+		return nil
+	}
+
+	logger.Info("api request metrics",
+		"requests", s.Requests,
+		"retries", s.Retries,
+		"rate_limited", s.RateLimited,
+		"errors", s.Errors,
+		"p50", s.LatencyP50.Round(time.Millisecond),
+		"p90", s.LatencyP90.Round(time.Millisecond),
+		"p99", s.LatencyP99.Round(time.Millisecond),
+		"max", s.LatencyMax.Round(time.Millisecond),
+	)
+
+	metricsFile := rootCmd.PersistentFlags().Lookup("metrics-file").Value.String()
+	if metricsFile == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode metrics: %w", err)
+	}
+
+	if err := os.WriteFile(metricsFile, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write metrics file %s: %w", metricsFile, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/config"
+	"github.com/amenocal/gh-gl-create-refs/pkg/notify"
+	"github.com/amenocal/gh-gl-create-refs/pkg/redact"
+)
+
+// notifyTimeout bounds each notification, so that an unreachable server cannot hold the
+// end of a run
+const notifyTimeout = time.Minute
+
+// runConfig is the configuration file read for the run, for the settings that have no flags
+var runConfig config.Config
+
+// summary converts the report for notifications
+func (r runReport) summary() notify.Summary {
+	s := notify.Summary{
+		Command:   r.command,
+		StartedAt: r.startedAt,
+		Duration:  r.duration,
+		File:      r.file,
+	}
+	for _, c := range r.counts {
+		s.Counts = append(s.Counts, notify.Count{Label: c.label, Value: c.value})
+	}
+	if r.err != nil {
+		s.Error = redact.String(r.err.Error())
+	}
+	return s
+}
+
+// notifyRunSummary sends the report to every notification of the configuration file that
+// wants it, or logs that it would in mock mode. A failing channel does not keep the others
+// from being notified; the errors are returned for the caller to report when the run itself
+// succeeded.
+func notifyRunSummary(ctx context.Context, report runReport, mock bool) error {
+	// The run's context may be canceled by then, and the summary of an interrupted run is
+	// the one most worth sending
+	ctx = context.WithoutCancel(ctx)
+
+	var errs []error
+	for _, n := range runConfig.AllNotifications() {
+		if n.On == config.NotifyOnFailure && report.err == nil {
+			continue
+		}
+		if mock {
+			logger.Info("mock mode: not sending the run summary", "notification", n.Type)
+			continue
+		}
+
+		if err := sendNotification(ctx, n, report.summary()); err != nil {
+			logger.Error("failed to send the run summary", "notification", n.Type, "error", err)
+			errs = append(errs, err)
+			continue
+		}
+		logger.Info("sent the run summary", "notification", n.Type)
+	}
+	return errors.Join(errs...)
+}
+
+// sendNotification sends the summary to the channel of n
+func sendNotification(ctx context.Context, n config.Notification, s notify.Summary) error {
+	notifier, err := newNotifier(n)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	return notifier.Notify(ctx, s)
+}
+
+// newNotifier builds the notifier of a notification of the configuration file, reading its
+// secrets from the environment and its template from its file
+func newNotifier(n config.Notification) (notify.Notifier, error) {
+	message := n.Message
+	if message == "" && n.Template != "" {
+		data, err := os.ReadFile(n.Template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s notification template: %w", n.Type, err)
+		}
+		message = string(data)
+	}
+
+	if n.Type == config.NotifyEmail {
+		password := os.Getenv(cmp.Or(n.PasswordEnv, config.DefaultPasswordEnv))
+		redact.Add(password)
+		return &notify.Email{
+			Host:     n.Host,
+			Port:     n.Port,
+			Username: n.Username,
+			Password: password,
+			From:     n.From,
+			To:       n.To,
+			Subject:  n.Subject,
+			Body:     message,
+		}, nil
+	}
+
+	url := n.URL
+	if n.URLEnv != "" {
+		url = os.Getenv(n.URLEnv)
+		if url == "" {
+			return nil, fmt.Errorf("%s notification: %s is not set", n.Type, n.URLEnv)
+		}
+	}
+	// Incoming webhook URLs carry their credentials in the path
+	redact.Add(url)
+
+	format := notify.FormatJSON
+	switch n.Type {
+	case config.NotifySlack:
+		format = notify.FormatSlack
+	case config.NotifyTeams:
+		format = notify.FormatTeams
+	}
+	return &notify.Webhook{
+		URL:       url,
+		Format:    format,
+		Message:   message,
+		UserAgent: rootCmd.PersistentFlags().Lookup("user-agent").Value.String(),
+	}, nil
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/config"
+)
+
+func TestNotifyRunSummary(t *testing.T) {
+	defer func() { runConfig = config.Config{} }()
+
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Text string }
+		json.NewDecoder(r.Body).Decode(&body)
+		received = append(received, body.Text)
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_SLACK_WEBHOOK_URL", server.URL)
+	runConfig = config.Config{Notifications: []config.Notification{
+		{Type: config.NotifySlack, URLEnv: "TEST_SLACK_WEBHOOK_URL"},
+		{Type: config.NotifySlack, URL: server.URL, On: config.NotifyOnFailure, Message: "failed: {{.Error}}"},
+	}}
+
+	report := runReport{command: "create-refs", counts: []reportCount{{"Branches created", 2}}}
+	if err := notifyRunSummary(context.Background(), report, false); err != nil {
+		t.Fatalf("notifyRunSummary() unexpected error = %v", err)
+	}
+	if len(received) != 1 || !strings.Contains(received[0], "create-refs completed") || !strings.Contains(received[0], "Branches created: 2") {
+		t.Fatalf("successful run sent %q, want one default message", received)
+	}
+
+	received = nil
+	report.err = errors.New("1 of 3 branches failed")
+	if err := notifyRunSummary(context.Background(), report, false); err != nil {
+		t.Fatalf("notifyRunSummary() unexpected error = %v", err)
+	}
+	if len(received) != 2 || received[1] != "failed: 1 of 3 branches failed" {
+		t.Errorf("failed run sent %q, want both messages", received)
+	}
+}
+
+func TestNotifyRunSummary_NotSent(t *testing.T) {
+	defer func() { runConfig = config.Config{} }()
+
+	// The host is unreachable, so any attempt to send would fail
+	email := &config.Notification{Host: "smtp.invalid", Port: 1, From: "bot@example.com", To: []string{"team@example.com"}, On: config.NotifyOnFailure}
+
+	tests := []struct {
+		name   string
+		config config.Config
+		report runReport
+		mock   bool
+	}{
+		{"not configured", config.Config{}, runReport{command: "create-refs", err: errors.New("failed")}, false},
+		{"only on failure", config.Config{Email: email}, runReport{command: "create-refs"}, false},
+		{"mock mode", config.Config{Email: email}, runReport{command: "create-refs", err: errors.New("failed")}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runConfig = tt.config
+			if err := notifyRunSummary(context.Background(), tt.report, tt.mock); err != nil {
+				t.Errorf("notifyRunSummary() = %v, want nothing sent", err)
+			}
+		})
+	}
+}
+
+func TestNotifyRunSummary_ReportsFailures(t *testing.T) {
+	defer func() { runConfig = config.Config{} }()
+
+	runConfig = config.Config{Notifications: []config.Notification{{Type: config.NotifyTeams, URLEnv: "TEST_UNSET_TEAMS_WEBHOOK_URL"}}}
+	err := notifyRunSummary(context.Background(), runReport{command: "fetch-refs"}, false)
+	if err == nil || !strings.Contains(err.Error(), "TEST_UNSET_TEAMS_WEBHOOK_URL is not set") {
+		t.Errorf("notifyRunSummary() error = %v, want the missing URL", err)
+	}
+}
+
+func TestRunReport_Summary(t *testing.T) {
+	report := runReport{
+		command:   "fetch-refs",
+		startedAt: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		duration:  time.Minute,
+		counts:    []reportCount{{"Merge requests exported", 42}},
+		file:      "refs.csv",
+		err:       errors.New("1 of 2 repositories failed"),
+	}
+
+	s := report.summary()
+	if s.Command != "fetch-refs" || s.Succeeded() || s.Error != "1 of 2 repositories failed" || s.File != "refs.csv" || len(s.Counts) != 1 || s.Counts[0].Value != 42 {
+		t.Errorf("summary() = %+v", s)
+	}
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"context"
+	"iter"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// defaultPauseEvery is how many branches are created between --batch-pause pauses by default
+const defaultPauseEvery = 50
+
+// paced yields refs, pausing for pause after every size of them, so that branches are
+// created in bursts of size with quiet periods in between for the instance's background
+// jobs to catch up. A pause ends early when ctx is done or the run is interrupted, leaving
+// the caller to notice.
+func paced(ctx context.Context, refs iter.Seq2[gitlab.MergeRequestRef, error], size int, pause time.Duration) iter.Seq2[gitlab.MergeRequestRef, error] {
+	if pause <= 0 {
+		return refs
+	}
+
+	return func(yield func(gitlab.MergeRequestRef, error) bool) {
+		count := 0
+		for ref, err := range refs {
+			if err == nil && count > 0 && count%size == 0 {
+				logger.Info("pausing between batches", "branches", count, "pause", pause)
+				timer := time.NewTimer(pause)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+				case <-interruptDone(ctx):
+				}
+				timer.Stop()
+			}
+			if !yield(ref, err) {
+				return
+			}
+			count++
+		}
+	}
+}
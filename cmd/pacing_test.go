@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+func TestPaced(t *testing.T) {
+	refs := []gitlab.MergeRequestRef{{IID: 1}, {IID: 2}, {IID: 3}, {IID: 4}, {IID: 5}}
+
+	var gaps []time.Duration
+	last := time.Now()
+	for range paced(context.Background(), refSeq(refs), 2, 50*time.Millisecond) {
+		gaps = append(gaps, time.Since(last))
+		last = time.Now()
+	}
+
+	if len(gaps) != len(refs) {
+		t.Fatalf("Expected %d references, got %d", len(refs), len(gaps))
+	}
+	// Pauses come before the third and fifth references
+	for i, gap := range gaps {
+		paused := gap >= 50*time.Millisecond
+		if want := i == 2 || i == 4; paused != want {
+			t.Errorf("Reference %d came after %v, paused = %v, want %v", i+1, gap, paused, want)
+		}
+	}
+}
+
+func TestPaced_Interrupted(t *testing.T) {
+	refs := []gitlab.MergeRequestRef{{IID: 1}, {IID: 2}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	count := 0
+	for range paced(ctx, refSeq(refs), 1, time.Hour) {
+		count++
+	}
+	if count != 2 || time.Since(start) > time.Second {
+		t.Errorf("Expected the pause to end with the context, got %d references after %v", count, time.Since(start))
+	}
+}
+
+func TestPaced_InterruptedByUser(t *testing.T) {
+	refs := []gitlab.MergeRequestRef{{IID: 1}, {IID: 2}}
+	done := make(chan struct{})
+	close(done)
+	ctx := withInterrupt(context.Background(), done)
+
+	start := time.Now()
+	count := 0
+	for range paced(ctx, refSeq(refs), 1, time.Hour) {
+		count++
+	}
+	if count != 2 || time.Since(start) > time.Second {
+		t.Errorf("Expected the pause to end on interrupt, got %d references after %v", count, time.Since(start))
+	}
+}
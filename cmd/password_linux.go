@@ -0,0 +1,9 @@
+package cmd
+
+import "golang.org/x/sys/unix"
+
+// The requests reading and setting the terminal attributes
+const (
+	ioctlGetTermios = unix.TCGETS
+	ioctlSetTermios = unix.TCSETS
+)
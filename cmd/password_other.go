@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+
+package cmd
+
+import (
+	"errors"
+	"os"
+)
+
+// readPassword fails: this system's terminal echo can't be turned off
+func readPassword(f *os.File) (string, error) {
+	return "", errors.New("hiding terminal input is not supported on this system")
+}
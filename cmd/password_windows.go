@@ -0,0 +1,26 @@
+//go:build windows
+
+package cmd
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// readPassword reads a line from the console f without echoing it
+func readPassword(f *os.File) (string, error) {
+	handle := windows.Handle(f.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return "", err
+	}
+
+	noEcho := mode&^windows.ENABLE_ECHO_INPUT | windows.ENABLE_PROCESSED_INPUT | windows.ENABLE_LINE_INPUT
+	if err := windows.SetConsoleMode(handle, noEcho); err != nil {
+		return "", err
+	}
+	defer windows.SetConsoleMode(handle, mode)
+
+	return readSecretLine(f)
+}
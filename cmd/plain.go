@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// plainOutput replaces emoji in status output with ASCII; it is set from --plain and the
+// locale before any subcommand runs
+var plainOutput bool
+
+// plainSymbols maps the emoji used in status output to ASCII. Emoji are printed followed by
+// extra spaces where they are wide, which the replacements absorb.
+var plainSymbols = strings.NewReplacer(
+	"✅", "[ok]",
+	"❌", "[x]",
+	"⚠️  ", "[!] ",
+	"⚠️", "[!]",
+	"⏭️  ", "[-] ",
+	"⏭️", "[-]",
+	"📋", "*",
+	"📄", "*",
+	"📝", "*",
+	"💬", "*",
+	"…", "...",
+)
+
+// usePlain decides whether to print ASCII only: with --plain, or when the locale is not
+// UTF-8. A POSIX system without any locale setting, as on many CI agents, uses the C
+// locale and gets ASCII too.
+func usePlain(plain bool) bool {
+	if plain {
+		return true
+	}
+
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return !isUTF8Locale(value)
+		}
+	}
+	// Windows consoles don't use the locale variables
+	return runtime.GOOS != "windows"
+}
+
+// isUTF8Locale reports whether a locale such as en_US.UTF-8 uses UTF-8
+func isUTF8Locale(locale string) bool {
+	locale = strings.ToLower(locale)
+	return strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+}
+
+// symbols returns s with emoji replaced by ASCII when plain output is enabled
+func symbols(s string) string {
+	if !plainOutput {
+		return s
+	}
+	return plainSymbols.Replace(s)
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestUsePlain(t *testing.T) {
+	tests := []struct {
+		name     string
+		plain    bool
+		lcAll    string
+		lcCtype  string
+		lang     string
+		expected bool
+	}{
+		{"utf-8 locale", false, "", "", "en_US.UTF-8", false},
+		{"utf8 spelling", false, "", "", "de_DE.utf8", false},
+		{"--plain", true, "", "", "en_US.UTF-8", true},
+		{"C locale", false, "", "", "C", true},
+		{"latin-1 locale", false, "", "", "en_US.ISO-8859-1", true},
+		{"LC_ALL takes precedence", false, "POSIX", "", "en_US.UTF-8", true},
+		{"LC_CTYPE takes precedence over LANG", false, "", "C.UTF-8", "C", false},
+		{"no locale", false, "", "", "", runtime.GOOS != "windows"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LC_ALL", tt.lcAll)
+			t.Setenv("LC_CTYPE", tt.lcCtype)
+			t.Setenv("LANG", tt.lang)
+			if got := usePlain(tt.plain); got != tt.expected {
+				t.Errorf("usePlain(%v) = %v, want %v", tt.plain, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	defer func() { plainOutput = false }()
+
+	line := "⚠️  Skipped: 2 branches"
+	plainOutput = false
+	if got := symbols(line); got != line {
+		t.Errorf("symbols() without plain output = %q, want %q", got, line)
+	}
+
+	plainOutput = true
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"✅ Successfully created: 3 branches", "[ok] Successfully created: 3 branches"},
+		{"❌ Failed: 1 branches", "[x] Failed: 1 branches"},
+		{"⚠️  Skipped: 2 branches", "[!] Skipped: 2 branches"},
+		{"⏭️  Unchanged: 4 branches", "[-] Unchanged: 4 branches"},
+		{"📄 Input file: refs.csv", "* Input file: refs.csv"},
+		{"plain text", "plain text"},
+	}
+	for _, tt := range tests {
+		if got := symbols(tt.input); got != tt.expected {
+			t.Errorf("symbols(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
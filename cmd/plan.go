@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/plan"
+	"github.com/spf13/cobra"
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Write a plan file describing the branches create-refs would create, update or skip",
+	Long: `Compute the branch operations needed in the target repository and write them to a plan file
+without changing anything in GitLab.
+
+Each merge request reference results in exactly one action:
+- create:   the branch does not exist yet
+- update:   the branch exists but points at a different SHA
+- skip:     the branch already points at the expected SHA
+- conflict: an earlier merge request of the plan gets the same branch name
+
+Review the plan, then execute exactly those actions with 'create-refs apply'.
+
+Examples:
+  gh gl-create-refs create-refs plan --input group-project.csv --repository group/project
+  gh gl-create-refs create-refs plan -r source/repo --target target/repo --fetch --out plan.json`,
+	Args: cobra.NoArgs,
+	RunE: runPlan,
+}
+
+func init() {
+	createRefsCmd.AddCommand(planCmd)
+
+	planCmd.Flags().StringP("input", "i", "", "Input CSV file path, or - to read standard input (required unless --fetch is used)")
+	planCmd.Flags().StringP("repository", "r", "", "Source GitLab repository path (required)")
+	planCmd.Flags().String("target", "", "Target GitLab repository path where branches will be created (optional, defaults to repository)")
+	addTokenFlags(planCmd)
+	planCmd.Flags().StringP("base-url", "b", "", "GitLab base URL (default: https://gitlab.com)")
+	planCmd.Flags().Bool("fetch", false, "Fetch merge requests in real-time instead of using CSV file")
+	planCmd.Flags().StringP("out", "o", "plan.json", "Output plan file path")
+
+	planCmd.MarkFlagRequired("repository")
+	addProjectCompletion(planCmd, "repository", "target")
+}
+
+func runPlan(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	inputFile := cmd.Flag("input").Value.String()
+	repository := cmd.Flag("repository").Value.String()
+	targetRepository := cmd.Flag("target").Value.String()
+	baseURL := cmd.Flag("base-url").Value.String()
+	outFile := cmd.Flag("out").Value.String()
+	fetch, _ := cmd.Flags().GetBool("fetch")
+
+	if err := validateCreateRefsFlags(repository, fetch, inputFile); err != nil {
+		return err
+	}
+	naming, err := namingFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if err := naming.validate(fetch); err != nil {
+		return err
+	}
+
+	client, err := newGitLabClient(cmd, baseURL)
+	if err != nil {
+		return err
+	}
+
+	refs, err := getMergeRequestRefs(ctx, client, fetch, inputFile, repository, baseURL)
+	if err != nil {
+		return err
+	}
+
+	targetRepo := targetRepository
+	if targetRepo == "" {
+		targetRepo = repository
+	}
+
+	_, targetProjectPath, err := gitlab.ParseRepoPath(targetRepo)
+	if err != nil {
+		return fmt.Errorf("failed to parse target repository path: %w", err)
+	}
+
+	logger.Info("reading existing branches", "project", targetProjectPath)
+
+	existing, err := client.ListBranchSHAs(ctx, targetProjectPath, naming.branchPrefix())
+	if err != nil {
+		return fmt.Errorf("failed to read existing branches: %w", err)
+	}
+
+	p := &plan.Plan{
+		Version:    plan.Version,
+		CreatedAt:  time.Now().UTC(),
+		BaseURL:    baseURL,
+		Repository: repository,
+		Target:     targetProjectPath,
+		Actions:    plan.Build(refs, existing, naming.branchName),
+	}
+
+	if err := plan.WriteFile(p, outFile); err != nil {
+		return err
+	}
+
+	printPlan(p)
+
+	absPath, err := filepath.Abs(outFile)
+	if err != nil {
+		absPath = outFile // Fallback to relative path
+	}
+	statusf("📄 Plan written to: %s\n", absPath)
+
+	return nil
+}
+
+// printPlan lists every non-skip action followed by per-type totals
+func printPlan(p *plan.Plan) {
+	fmt.Printf("\nPlanned changes for %s:\n", p.Target)
+	for _, action := range p.Actions {
+		switch action.Type {
+		case plan.ActionCreate:
+			fmt.Printf("  %s %s at %s\n", green("+ create"), action.Branch, action.SHA)
+		case plan.ActionUpdate:
+			fmt.Printf("  %s %s from %s to %s\n", yellow("~ update"), action.Branch, action.CurrentSHA, action.SHA)
+		case plan.ActionConflict:
+			fmt.Printf("  %s %s for !%d, already taken by an earlier merge request\n", red("! conflict"), action.Branch, action.IID)
+		}
+	}
+
+	s := p.Summarize()
+	statusf("\nPlan: %d to create, %d to update, %d unchanged\n", s.Create, s.Update, s.Skip)
+	if s.Conflict > 0 {
+		statusf("⚠️  %d merge requests get a branch name an earlier one takes and will fail\n", s.Conflict)
+	}
+}
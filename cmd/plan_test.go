@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/plan"
+)
+
+func TestPlanCmd_FlagDefinitions(t *testing.T) {
+	expectedFlags := []struct {
+		name      string
+		shorthand string
+	}{
+		{"input", "i"},
+		{"repository", "r"},
+		{"target", ""},
+		{"token", "t"},
+		{"base-url", "b"},
+		{"fetch", ""},
+		{"out", "o"},
+	}
+
+	for _, expected := range expectedFlags {
+		flag := planCmd.Flag(expected.name)
+		if flag == nil {
+			t.Errorf("Flag %q should be defined", expected.name)
+			continue
+		}
+
+		if flag.Shorthand != expected.shorthand {
+			t.Errorf("Flag %q shorthand should be %q, got %q",
+				expected.name, expected.shorthand, flag.Shorthand)
+		}
+	}
+
+	if def := planCmd.Flag("out").DefValue; def != "plan.json" {
+		t.Errorf("Expected --out default to be 'plan.json', got %q", def)
+	}
+}
+
+func TestPlanApplyCmd_Registration(t *testing.T) {
+	found := map[string]bool{}
+	for _, sub := range createRefsCmd.Commands() {
+		found[sub.Name()] = true
+	}
+
+	for _, name := range []string{"plan", "apply"} {
+		if !found[name] {
+			t.Errorf("Expected %q to be a subcommand of create-refs", name)
+		}
+	}
+
+	if err := applyCmd.Args(applyCmd, []string{}); err == nil {
+		t.Error("apply should require a plan file argument")
+	}
+
+	if err := applyCmd.Args(applyCmd, []string{"plan.json"}); err != nil {
+		t.Errorf("apply should accept a single plan file argument, got %v", err)
+	}
+}
+
+func TestApplyPlan(t *testing.T) {
+	api := newMockAPI()
+	api.branches["target/project"] = map[string]string{
+		"migration-pr-2": "old",
+		"migration-pr-3": "ccc",
+	}
+
+	p := &plan.Plan{
+		Version: plan.Version,
+		Target:  "target/project",
+		Actions: []plan.Action{
+			{Type: plan.ActionCreate, Branch: "migration-pr-1", IID: 1, SHA: "aaa"},
+			{Type: plan.ActionUpdate, Branch: "migration-pr-2", IID: 2, SHA: "bbb", CurrentSHA: "old"},
+			{Type: plan.ActionSkip, Branch: "migration-pr-3", IID: 3, SHA: "ccc", CurrentSHA: "ccc"},
+		},
+	}
+
+	if err := applyPlan(context.Background(), api, p, false); err != nil {
+		t.Fatalf("applyPlan() unexpected error = %v", err)
+	}
+
+	expectedCalls := []string{
+		"create target/project migration-pr-1@aaa",
+		"delete target/project migration-pr-2",
+		"create target/project migration-pr-2@bbb",
+	}
+	if len(api.calls) != len(expectedCalls) {
+		t.Fatalf("Expected calls %v, got %v", expectedCalls, api.calls)
+	}
+	for i := range expectedCalls {
+		if api.calls[i] != expectedCalls[i] {
+			t.Errorf("Call %d = %q, want %q", i, api.calls[i], expectedCalls[i])
+		}
+	}
+}
+
+func TestApplyPlan_ReportsFailures(t *testing.T) {
+	api := newMockAPI()
+	api.failBranches["migration-pr-1"] = true
+
+	p := &plan.Plan{
+		Version: plan.Version,
+		Target:  "target/project",
+		Actions: []plan.Action{
+			{Type: plan.ActionCreate, Branch: "migration-pr-1", IID: 1, SHA: "aaa"},
+		},
+	}
+
+	if err := applyPlan(context.Background(), api, p, false); err == nil {
+		t.Error("applyPlan() expected error when an action fails")
+	}
+}
+
+func TestApplyPlan_UpdateOfDeletedBranch(t *testing.T) {
+	api := newMockAPI()
+
+	p := &plan.Plan{
+		Version: plan.Version,
+		Target:  "target/project",
+		Actions: []plan.Action{
+			{Type: plan.ActionUpdate, Branch: "migration-pr-1", IID: 1, SHA: "aaa", CurrentSHA: "old"},
+		},
+	}
+
+	if err := applyPlan(context.Background(), api, p, false); err != nil {
+		t.Fatalf("applyPlan() unexpected error = %v", err)
+	}
+
+	if api.branches["target/project"]["migration-pr-1"] != "aaa" {
+		t.Error("Expected the branch to be created when it was already deleted")
+	}
+}
+
+func TestApplyPlan_Drift(t *testing.T) {
+	api := newMockAPI()
+	api.branches["target/project"] = map[string]string{
+		// Moved since the plan saw it at "old"
+		"migration-pr-1": "pushed",
+		// Created since the plan was made
+		"migration-pr-2": "other",
+	}
+
+	p := &plan.Plan{
+		Version: plan.Version,
+		Target:  "target/project",
+		Actions: []plan.Action{
+			{Type: plan.ActionUpdate, Branch: "migration-pr-1", IID: 1, SHA: "aaa", CurrentSHA: "old"},
+			{Type: plan.ActionCreate, Branch: "migration-pr-2", IID: 2, SHA: "bbb"},
+		},
+	}
+
+	err := applyPlan(context.Background(), api, p, false)
+	if err == nil || !strings.Contains(errorHint(applyCmd, err), "changed since the plan was made") {
+		t.Fatalf("applyPlan() error = %v, want the drift reported", err)
+	}
+	if branches := api.branches["target/project"]; branches["migration-pr-1"] != "pushed" || branches["migration-pr-2"] != "other" {
+		t.Errorf("Branches = %v, want the changed branches left alone", branches)
+	}
+	for _, call := range api.calls {
+		if strings.HasPrefix(call, "delete") {
+			t.Errorf("Expected no branch to be deleted, got %v", api.calls)
+		}
+	}
+}
+
+func TestApplyPlan_Conflict(t *testing.T) {
+	api := newMockAPI()
+
+	p := &plan.Plan{
+		Version: plan.Version,
+		Target:  "target/project",
+		Actions: []plan.Action{
+			{Type: plan.ActionCreate, Branch: "feature", IID: 1, SHA: "aaa"},
+			{Type: plan.ActionConflict, Branch: "feature", IID: 2, SHA: "bbb"},
+		},
+	}
+
+	if err := applyPlan(context.Background(), api, p, false); err == nil {
+		t.Error("applyPlan() expected an error for the conflicting action")
+	}
+	if got := api.branches["target/project"]["feature"]; got != "aaa" {
+		t.Errorf("Branch is at %q, want the first merge request's aaa", got)
+	}
+}
+
+func TestCheckPlanTarget(t *testing.T) {
+	tests := []struct {
+		name        string
+		planBaseURL string
+		baseURL     string
+		target      string
+		force       bool
+		expectError bool
+	}{
+		{"no --base-url", "https://gitlab.example.com", "", "", false, false},
+		{"same instance", "https://gitlab.example.com", "https://GITLAB.example.com/", "", false, false},
+		{"default instance", "", "https://gitlab.com", "", false, false},
+		{"other instance", "https://gitlab.example.com", "https://gitlab.com", "", false, true},
+		{"other instance forced", "https://gitlab.example.com", "https://gitlab.com", "", true, false},
+		{"same project", "https://gitlab.example.com", "", "target/project", false, false},
+		{"same project as URL", "https://gitlab.example.com", "", "https://gitlab.example.com/target/project", false, false},
+		{"other project", "https://gitlab.example.com", "", "other/project", false, true},
+		{"other project forced", "https://gitlab.example.com", "", "other/project", true, false},
+		{"project on other instance", "https://gitlab.example.com", "", "https://gitlab.com/target/project", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &plan.Plan{BaseURL: tt.planBaseURL, Target: "target/project"}
+			err := checkPlanTarget(p, tt.baseURL, tt.target, tt.force)
+			if tt.expectError != (err != nil) {
+				t.Errorf("checkPlanTarget() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
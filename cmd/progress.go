@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// progressEvery is how often long-running loops log their throughput; it is zero, disabling
+// those logs, when stderr is a terminal, where the per-item messages already show progress
+var progressEvery time.Duration
+
+// progress logs how many items a loop has processed, its rate, the estimated time remaining
+// and the rate limit state at most every progressEvery. It is safe for concurrent use, and
+// a nil *progress does nothing.
+type progress struct {
+	msg    string
+	total  int
+	attrs  []any
+	every  time.Duration
+	now    func() time.Time
+	logger *slog.Logger
+
+	mu       sync.Mutex
+	start    time.Time
+	last     time.Time
+	done     int
+	requests int
+}
+
+// newProgress starts tracking a loop over total items (0 if unknown), logging msg with attrs
+// at every interval. It returns nil when throughput logging is disabled.
+func newProgress(msg string, total int, attrs ...any) *progress {
+	if progressEvery <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	return &progress{
+		msg:      msg,
+		total:    total,
+		attrs:    attrs,
+		every:    progressEvery,
+		now:      time.Now,
+		logger:   logger,
+		start:    now,
+		last:     now,
+		requests: metrics.Snapshot().Requests,
+	}
+}
+
+// add records n more processed items, logging the progress if the interval has passed
+func (p *progress) add(n int) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done += n
+	now := p.now()
+	if now.Sub(p.last) < p.every {
+		return
+	}
+	p.last = now
+	p.log(now)
+}
+
+// log writes one progress message; the caller holds p.mu
+func (p *progress) log(now time.Time) {
+	elapsed := now.Sub(p.start)
+	if elapsed <= 0 {
+		return
+	}
+
+	rate := float64(p.done) / elapsed.Seconds()
+	requestRate := float64(metrics.Snapshot().Requests-p.requests) / elapsed.Seconds()
+
+	attrs := slices.Clone(p.attrs)
+	attrs = append(attrs, "processed", p.done)
+	if p.total > 0 {
+		attrs = append(attrs, "total", p.total, "percent", p.done*100/p.total)
+	}
+	attrs = append(attrs,
+		"rate", roundRate(rate),
+		"requests_per_second", roundRate(requestRate),
+		"elapsed", elapsed.Round(time.Second),
+	)
+	if p.total > 0 && rate > 0 {
+		remaining := time.Duration(float64(max(p.total-p.done, 0)) / rate * float64(time.Second))
+		attrs = append(attrs, "eta", remaining.Round(time.Second))
+	}
+
+	states := rateLimits.States()
+	for _, host := range slices.Sorted(maps.Keys(states)) {
+		s := states[host]
+		limit := []any{"rps", roundRate(s.RequestsPerSecond)}
+		if s.Remaining >= 0 {
+			limit = append(limit, "remaining", s.Remaining)
+		}
+		if s.PausedUntil.After(now) {
+			limit = append(limit, "paused_for", s.PausedUntil.Sub(now).Round(time.Second))
+		}
+		attrs = append(attrs, slog.Group(host, limit...))
+	}
+
+	p.logger.Info(p.msg, attrs...)
+}
+
+// roundRate rounds a per-second rate to one decimal for logging
+func roundRate(rate float64) float64 {
+	return float64(int(rate*10+0.5)) / 10
+}
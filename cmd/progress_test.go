@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/ratelimit"
+)
+
+func TestProgress_Add(t *testing.T) {
+	savedLimits := rateLimits
+	t.Cleanup(func() { rateLimits = savedLimits })
+	rateLimits = ratelimit.NewHosts(slog.New(slog.DiscardHandler))
+	rateLimits.Bucket("gitlab.com", 2, 1)
+
+	var b bytes.Buffer
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	p := &progress{
+		msg:    "fetch throughput",
+		total:  100,
+		attrs:  []any{"project", "group/project"},
+		every:  30 * time.Second,
+		now:    func() time.Time { return now },
+		logger: slog.New(slog.NewTextHandler(&b, nil)),
+		start:  start,
+		last:   start,
+	}
+
+	// Nothing is logged before the interval has passed
+	now = start.Add(10 * time.Second)
+	p.add(10)
+	if b.Len() != 0 {
+		t.Fatalf("add() logged before the interval: %s", b.String())
+	}
+
+	now = start.Add(40 * time.Second)
+	p.add(10)
+	line := b.String()
+	for _, expected := range []string{
+		`msg="fetch throughput"`,
+		"project=group/project",
+		"processed=20",
+		"total=100",
+		"percent=20",
+		"rate=0.5",
+		"elapsed=40s",
+		"eta=2m40s",
+		"gitlab.com.rps=2",
+	} {
+		if !strings.Contains(line, expected) {
+			t.Errorf("progress log %q does not contain %q", line, expected)
+		}
+	}
+	if strings.Contains(line, "gitlab.com.remaining") {
+		t.Errorf("progress log %q reports a quota the server never sent", line)
+	}
+
+	// The interval restarts from the last message
+	b.Reset()
+	now = start.Add(50 * time.Second)
+	p.add(1)
+	if b.Len() != 0 {
+		t.Errorf("add() logged twice within an interval: %s", b.String())
+	}
+}
+
+func TestNewProgress_Disabled(t *testing.T) {
+	saved := progressEvery
+	t.Cleanup(func() { progressEvery = saved })
+	progressEvery = 0
+
+	p := newProgress("fetch throughput", 10)
+	if p != nil {
+		t.Fatal("newProgress() tracked progress with logging disabled")
+	}
+	// A disabled progress is safe to use
+	p.add(1)
+}
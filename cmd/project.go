@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// followMovedProject returns the current path of the project at projectPath, warning when
+// the project was renamed or transferred, so that changes are sent to and outputs record
+// its new path
+func followMovedProject(ctx context.Context, client gitlab.API, projectPath string) (string, error) {
+	current, err := client.ProjectPath(ctx, projectPath)
+	if err != nil {
+		return "", err
+	}
+	return movedProject(projectPath, current), nil
+}
+
+// movedProject returns current, the path GitLab reports for the project at projectPath,
+// warning that the project has moved, or projectPath when both name the same path. Paths
+// differing only in case name the same project.
+func movedProject(projectPath, current string) string {
+	if current == "" || strings.EqualFold(current, projectPath) {
+		return projectPath
+	}
+	logger.Warn("project has moved; using its new path", "project", projectPath, "new_path", current)
+	return current
+}
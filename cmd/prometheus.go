@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// metricsPrefix namespaces the exported Prometheus metrics
+const metricsPrefix = "gh_gl_create_refs_"
+
+// metricsServer serves /metrics while the command runs when --metrics-addr is set
+var metricsServer *http.Server
+
+// startMetricsServer serves the run's metrics in the Prometheus text format at /metrics on
+// addr. Listening happens before it returns, so a port in use fails the command right away.
+func startMetricsServer(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on --metrics-addr %s: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", servePrometheus)
+	metricsServer = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := metricsServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("metrics server stopped", "error", err)
+		}
+	}()
+	logger.Info("serving metrics", "url", fmt.Sprintf("http://%s/metrics", listener.Addr()))
+	return nil
+}
+
+// stopMetricsServer stops the server started by startMetricsServer, if any
+func stopMetricsServer() {
+	if metricsServer != nil {
+		metricsServer.Close()
+	}
+}
+
+func servePrometheus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writePrometheus(w)
+}
+
+// writePrometheus writes the API request metrics, the rate limit state of every host and the
+// run's counters in the Prometheus text exposition format
+func writePrometheus(w io.Writer) {
+	s := metrics.Snapshot()
+	metric(w, "gitlab_requests_total", "counter", "GitLab API requests sent, including retries", s.Requests)
+	metric(w, "gitlab_retries_total", "counter", "GitLab API requests that were retries", s.Retries)
+	metric(w, "gitlab_rate_limited_total", "counter", "GitLab API responses with status 429", s.RateLimited)
+	metric(w, "gitlab_errors_total", "counter", "GitLab API requests that failed or returned a 5xx status", s.Errors)
+
+	name := metricsPrefix + "gitlab_request_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Latency of GitLab API requests\n# TYPE %s summary\n", name, name)
+	for _, q := range []struct {
+		quantile string
+		latency  time.Duration
+	}{{"0.5", s.LatencyP50}, {"0.9", s.LatencyP90}, {"0.99", s.LatencyP99}} {
+		fmt.Fprintf(w, "%s{quantile=%q} %g\n", name, q.quantile, q.latency.Seconds())
+	}
+
+	metric(w, "refs_fetched_total", "counter", "Merge request references fetched", runStats.refsFetched.Load())
+	metric(w, "branches_created_total", "counter", "Branches created or moved", runStats.branchesCreated.Load())
+	metric(w, "branches_failed_total", "counter", "Branches that could not be created or moved", runStats.branchesFailed.Load())
+	metric(w, "repositories_queued", "gauge", "Repositories of a multi-repository fetch not started yet", runStats.repositoriesQueued.Load())
+
+	name = metricsPrefix + "scheduled_runs_total"
+	fmt.Fprintf(w, "# HELP %s Scheduled fetches run by --schedule\n# TYPE %s counter\n", name, name)
+	fmt.Fprintf(w, "%s{result=\"success\"} %d\n", name, runStats.scheduledRuns.Load())
+	fmt.Fprintf(w, "%s{result=\"failure\"} %d\n", name, runStats.scheduledFailures.Load())
+
+	states := rateLimits.States()
+	hosts := slices.Sorted(maps.Keys(states))
+	hostMetric(w, hosts, "rate_limit_waits_total", "counter", "Requests delayed by the client-side rate limiter", func(host string) any {
+		return states[host].Waits
+	})
+	hostMetric(w, hosts, "rate_limit_wait_seconds_total", "counter", "Time requests spent waiting for the client-side rate limiter", func(host string) any {
+		return states[host].WaitTime.Seconds()
+	})
+	hostMetric(w, hosts, "rate_limit_requests_per_second", "gauge", "Current client-side request rate, 0 when unlimited", func(host string) any {
+		return states[host].RequestsPerSecond
+	})
+
+	// Hosts that never reported a quota are left out rather than reported as exhausted
+	reporting := slices.DeleteFunc(slices.Clone(hosts), func(host string) bool { return states[host].Remaining < 0 })
+	hostMetric(w, reporting, "rate_limit_remaining", "gauge", "Request quota the server last reported as remaining", func(host string) any {
+		return states[host].Remaining
+	})
+}
+
+// metric writes a metric without labels
+func metric(w io.Writer, name, kind, help string, value any) {
+	name = metricsPrefix + name
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+}
+
+// hostMetric writes a metric with one sample per API host
+func hostMetric(w io.Writer, hosts []string, name, kind, help string, value func(host string) any) {
+	if len(hosts) == 0 {
+		return
+	}
+	name = metricsPrefix + name
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	for _, host := range hosts {
+		fmt.Fprintf(w, "%s{host=%q} %v\n", name, host, value(host))
+	}
+}
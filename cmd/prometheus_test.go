@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/ratelimit"
+)
+
+func TestServePrometheus(t *testing.T) {
+	savedLimits := rateLimits
+	t.Cleanup(func() {
+		rateLimits = savedLimits
+		runStats.branchesCreated.Store(0)
+		runStats.repositoriesQueued.Store(0)
+	})
+	rateLimits = ratelimit.NewHosts(slog.New(slog.DiscardHandler))
+	rateLimits.Bucket("gitlab.com", 10, 1).Observe(&http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Ratelimit-Remaining": []string{"1500"}},
+	})
+	rateLimits.Bucket("github.com", 0, 1)
+	runStats.branchesCreated.Store(42)
+	runStats.repositoriesQueued.Store(3)
+
+	recorder := httptest.NewRecorder()
+	servePrometheus(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if got := recorder.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	body := recorder.Body.String()
+	for _, expected := range []string{
+		"# TYPE gh_gl_create_refs_gitlab_requests_total counter\n",
+		"gh_gl_create_refs_branches_created_total 42\n",
+		"gh_gl_create_refs_repositories_queued 3\n",
+		`gh_gl_create_refs_scheduled_runs_total{result="failure"} 0` + "\n",
+		`gh_gl_create_refs_gitlab_request_duration_seconds{quantile="0.99"} 0` + "\n",
+		`gh_gl_create_refs_rate_limit_requests_per_second{host="gitlab.com"} 10` + "\n",
+		`gh_gl_create_refs_rate_limit_waits_total{host="github.com"} 0` + "\n",
+		`gh_gl_create_refs_rate_limit_remaining{host="gitlab.com"} 1500` + "\n",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("metrics do not contain %q:\n%s", expected, body)
+		}
+	}
+	if strings.Contains(body, `rate_limit_remaining{host="github.com"}`) {
+		t.Errorf("metrics report a quota github.com never sent:\n%s", body)
+	}
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// errNoAnswer is returned when standard input ends before a question is answered
+var errNoAnswer = errors.New("standard input ended before the question was answered")
+
+// prompter asks questions on out and reads the answers from in, one per line
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+	// terminal is in when it is a terminal, whose echo is turned off for secrets
+	terminal *os.File
+}
+
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	p := &prompter{in: bufio.NewReader(in), out: out}
+	if f, ok := in.(*os.File); ok && isTerminal(f) {
+		p.terminal = f
+	}
+	return p
+}
+
+func (p *prompter) printf(format string, a ...any) {
+	fmt.Fprint(p.out, symbols(fmt.Sprintf(format, a...)))
+}
+
+// readLine reads the next answer, without surrounding whitespace
+func (p *prompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", errNoAnswer
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// askSecret asks for a secret, such as a token, until one is given. On a terminal it is
+// typed without being shown; otherwise it is read as any other answer.
+func (p *prompter) askSecret(question string) (string, error) {
+	for {
+		p.printf("? %s: ", question)
+
+		answer, err := p.readSecret()
+		if err != nil {
+			return "", err
+		}
+		if answer != "" {
+			return answer, nil
+		}
+		p.printf("❌ an answer is required\n")
+	}
+}
+
+// readSecret reads the next answer without echoing it when in is a terminal
+func (p *prompter) readSecret() (string, error) {
+	if p.terminal == nil || p.in.Buffered() > 0 {
+		return p.readLine()
+	}
+
+	answer, err := readPassword(p.terminal)
+	// The newline typed after the secret was not echoed either
+	p.printf("\n")
+	if errors.Is(err, io.EOF) {
+		return "", errNoAnswer
+	}
+	if err != nil {
+		logger.Debug("could not turn off terminal echo, reading the answer as typed", "error", err)
+		return p.readLine()
+	}
+	return answer, nil
+}
+
+// readSecretLine reads a line from r byte by byte, so nothing after it is consumed, and
+// returns it without surrounding whitespace
+func readSecretLine(r io.Reader) (string, error) {
+	var line []byte
+	var b [1]byte
+	for {
+		n, err := r.Read(b[:])
+		if n > 0 {
+			if b[0] == '\n' {
+				break
+			}
+			line = append(line, b[0])
+		}
+		if errors.Is(err, io.EOF) && len(line) > 0 {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(string(line)), nil
+}
+
+// ask asks a question until check accepts the answer. An empty answer means defaultValue.
+// check may be nil.
+func (p *prompter) ask(question, defaultValue string, check func(string) error) (string, error) {
+	for {
+		if defaultValue != "" {
+			p.printf("? %s [%s]: ", question, defaultValue)
+		} else {
+			p.printf("? %s: ", question)
+		}
+
+		answer, err := p.readLine()
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			answer = defaultValue
+		}
+
+		if check == nil {
+			return answer, nil
+		}
+		if err := check(answer); err != nil {
+			p.printf("❌ %v\n", err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// choose asks to pick one of choices by number. An empty answer means defaultChoice.
+func (p *prompter) choose(question string, choices []string, defaultChoice string) (string, error) {
+	p.printf("? %s\n", question)
+	defaultNumber := ""
+	for i, choice := range choices {
+		p.printf("  %d) %s\n", i+1, choice)
+		if choice == defaultChoice {
+			defaultNumber = strconv.Itoa(i + 1)
+		}
+	}
+
+	answer, err := p.ask("Choice", defaultNumber, func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > len(choices) {
+			return fmt.Errorf("enter a number from 1 to %d", len(choices))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	n, _ := strconv.Atoi(answer)
+	return choices[n-1], nil
+}
+
+// confirm asks a yes or no question
+func (p *prompter) confirm(question string, defaultYes bool) (bool, error) {
+	defaultValue := "y/N"
+	if defaultYes {
+		defaultValue = "Y/n"
+	}
+
+	answer, err := p.ask(question, defaultValue, func(s string) error {
+		switch strings.ToLower(s) {
+		case "y", "yes", "n", "no", strings.ToLower(defaultValue):
+			return nil
+		}
+		return fmt.Errorf("answer y or n")
+	})
+	if err != nil {
+		return false, err
+	}
+
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, nil
+	case "n", "no":
+		return false, nil
+	}
+	return defaultYes, nil
+}
+
+// confirmTyped asks the user to retype expected, like GitHub does before deleting a
+// repository. Any other answer is a refusal.
+func (p *prompter) confirmTyped(question, expected string) error {
+	p.printf("%s\n? Type %s to confirm: ", question, expected)
+
+	answer, err := p.readLine()
+	if err != nil {
+		return err
+	}
+	if answer != expected {
+		return fmt.Errorf("confirmation did not match %s; nothing was changed", expected)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPrompter_Ask(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		defaultValue string
+		expected     string
+		expectError  error
+	}{
+		{"answer", "group/project\n", "", "group/project", nil},
+		{"trims whitespace", "  group/project  \n", "", "group/project", nil},
+		{"empty answer uses default", "\n", "https://gitlab.com", "https://gitlab.com", nil},
+		{"last line without newline", "group/project", "", "group/project", nil},
+		{"asks again after a rejected answer", "bad\ngood\n", "", "good", nil},
+		{"no answer", "", "", "", errNoAnswer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPrompter(strings.NewReader(tt.input), io.Discard)
+			got, err := p.ask("Question", tt.defaultValue, func(s string) error {
+				if s == "bad" {
+					return errors.New("bad answer")
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.expectError) {
+				t.Fatalf("ask() error = %v, want %v", err, tt.expectError)
+			}
+			if got != tt.expected {
+				t.Errorf("ask() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPrompter_Choose(t *testing.T) {
+	choices := []string{"one", "two", "three"}
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"by number", "3\n", "three"},
+		{"default", "\n", "two"},
+		{"asks again after an invalid number", "4\nx\n1\n", "one"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPrompter(strings.NewReader(tt.input), io.Discard)
+			got, err := p.choose("Pick", choices, "two")
+			if err != nil {
+				t.Fatalf("choose() unexpected error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("choose() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPrompter_Confirm(t *testing.T) {
+	tests := []struct {
+		input      string
+		defaultYes bool
+		expected   bool
+	}{
+		{"y\n", false, true},
+		{"YES\n", false, true},
+		{"n\n", true, false},
+		{"\n", false, false},
+		{"\n", true, true},
+		{"maybe\ny\n", false, true},
+	}
+
+	for _, tt := range tests {
+		p := newPrompter(strings.NewReader(tt.input), io.Discard)
+		got, err := p.confirm("Sure?", tt.defaultYes)
+		if err != nil {
+			t.Fatalf("confirm(%q) unexpected error = %v", tt.input, err)
+		}
+		if got != tt.expected {
+			t.Errorf("confirm(%q, %v) = %v, want %v", tt.input, tt.defaultYes, got, tt.expected)
+		}
+	}
+}
+
+func TestPrompter_ConfirmTyped(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+	}{
+		{"matching path", "group/project\n", false},
+		{"surrounding whitespace", " group/project \n", false},
+		{"different path", "group/other\n", true},
+		{"empty answer", "\n", true},
+		{"no answer", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPrompter(strings.NewReader(tt.input), io.Discard)
+			err := p.confirmTyped("Delete branches?", "group/project")
+			if tt.expectError != (err != nil) {
+				t.Errorf("confirmTyped(%q) error = %v, expectError %v", tt.input, err, tt.expectError)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/queue"
+)
+
+// refQueue holds the fetched references whose branches are still to be created
+type refQueue = queue.Queue[gitlab.MergeRequestRef]
+
+// queueWriter is an output.Writer pushing the references written to it to a queue. Closing
+// and aborting are left to the queue's owner.
+type queueWriter struct {
+	q *refQueue
+}
+
+func (w queueWriter) WriteRef(ref gitlab.MergeRequestRef) error {
+	// Only the reference is kept; the merge request itself would bloat the queue
+	return w.q.Push(gitlab.MergeRequestRef{ID: ref.ID, IID: ref.IID, HeadSHA: ref.HeadSHA, SourceBranch: ref.SourceBranch, Fork: ref.Fork})
+}
+
+func (w queueWriter) Close() error { return nil }
+
+func (w queueWriter) Abort() {}
+
+// queueFilename names the queue of repository in --queue-dir
+func queueFilename(repository string) string {
+	return strings.TrimSuffix(csv.GenerateFilename(repository), ".csv") + ".queue"
+}
+
+// refQueuePath returns the queue file of repository in dir, or a new temporary file when
+// dir is empty
+func refQueuePath(dir, repository string) (string, error) {
+	if dir == "" {
+		file, err := os.CreateTemp("", "gh-gl-create-refs-*.queue")
+		if err != nil {
+			return "", fmt.Errorf("failed to create queue: %w", err)
+		}
+		file.Close()
+		return file.Name(), nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create queue directory: %w", err)
+	}
+	return filepath.Join(dir, queueFilename(repository)), nil
+}
+
+// closeRefQueue deletes a drained or temporary queue, and keeps the pending references of
+// a persistent one for the next run
+func closeRefQueue(q *refQueue, persistent bool) {
+	if persistent && q.Len() > 0 {
+		if err := q.Close(); err != nil {
+			logger.Error("failed to close queue", "queue", q.Path(), "error", err)
+			return
+		}
+		logger.Info("kept queued references for the next run", "queue", q.Path(), "pending", q.Len())
+		return
+	}
+	if err := q.Remove(); err != nil {
+		logger.Error("failed to remove queue", "queue", q.Path(), "error", err)
+	}
+}
+
+// queueMergeRequestRefs fetches the merge request references of repository into the queue
+// at path, so that branches are created from disk rather than from references held in
+// memory. A queue with references left pending by an interrupted run is returned as it is
+// instead of fetching again. With persistent, a fetch that fails or is interrupted keeps the
+// references it got, and the next one only fetches the detail of the other merge requests.
+func queueMergeRequestRefs(ctx context.Context, client gitlab.API, path, repository, baseURL string, persistent bool) (*refQueue, error) {
+	q, err := queue.Open[gitlab.MergeRequestRef](path)
+	if err != nil {
+		return nil, err
+	}
+	if pending := q.Len(); pending > 0 {
+		logger.Info("resuming queued references", "project", repository, "queue", path, "pending", pending)
+		return q, nil
+	}
+	if err := q.Remove(); err != nil {
+		return nil, err
+	}
+
+	// The fetch fills a separate file that only becomes the queue once it is complete, so
+	// a failed fetch is never mistaken for pending work
+	if !persistent {
+		os.Remove(path + ".partial")
+	}
+	partial, err := queue.Open[gitlab.MergeRequestRef](path + ".partial")
+	if err != nil {
+		return nil, err
+	}
+
+	// The merge requests an earlier fetch got are not requested again
+	fetched := make(map[int]bool, partial.Len())
+	for ref, err := range partial.Items() {
+		if err != nil {
+			partial.Close()
+			return nil, err
+		}
+		fetched[ref.IID] = true
+	}
+	if len(fetched) > 0 {
+		logger.Info("resuming interrupted fetch", "project", repository, "queue", path, "fetched", len(fetched))
+	}
+
+	logger.Info("fetching merge requests", "project", repository, "queue", path)
+
+	processor := func(ref gitlab.MergeRequestRef) error {
+		if err := interrupted(ctx); err != nil {
+			return err
+		}
+		runStats.refsFetched.Add(1)
+		return queueWriter{partial}.WriteRef(ref)
+	}
+
+	skip := gitlab.WithSkip(func(iid int) bool { return fetched[iid] })
+	if _, err := client.FetchMergeRequestRefsFromRepo(ctx, repository, baseURL, processor, skip); err != nil {
+		if persistent {
+			if closeErr := partial.Close(); closeErr == nil {
+				logger.Info("kept fetched references for the next run", "queue", partial.Path(), "fetched", partial.Len())
+			}
+		} else {
+			partial.Remove()
+		}
+		return nil, fmt.Errorf("failed to fetch merge requests: %w", err)
+	}
+	count := partial.Len()
+	if err := partial.Close(); err != nil {
+		partial.Remove()
+		return nil, err
+	}
+	if err := os.Rename(partial.Path(), path); err != nil {
+		partial.Remove()
+		return nil, fmt.Errorf("failed to create queue: %w", err)
+	}
+
+	logger.Info("fetched merge requests", "project", repository, "count", count)
+	return queue.Open[gitlab.MergeRequestRef](path)
+}
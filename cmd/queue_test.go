@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/github"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/queue"
+)
+
+func TestQueueMergeRequestRefs_Resume(t *testing.T) {
+	api := newMockAPI()
+	api.refs["group/project"] = []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: "aaa"},
+		{IID: 2, HeadSHA: "bbb"},
+		{IID: 3, HeadSHA: "ccc"},
+	}
+	api.failBranches["migration-pr-2"] = true
+	path := filepath.Join(t.TempDir(), queueFilename("group/project"))
+
+	q, err := queueMergeRequestRefs(context.Background(), api, path, "group/project", "", true)
+	if err != nil {
+		t.Fatalf("queueMergeRequestRefs() unexpected error = %v", err)
+	}
+	if q.Len() != 3 {
+		t.Fatalf("queue holds %d references, want 3", q.Len())
+	}
+
+	// The run stops after the first branch, leaving the others queued
+	for ref, err := range q.Drain() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ref.IID == 2 {
+			break
+		}
+	}
+	closeRefQueue(q, true)
+
+	// The next run drains the queue without fetching again
+	delete(api.refs, "group/project")
+	resumed, err := queueMergeRequestRefs(context.Background(), api, path, "group/project", "", true)
+	if err != nil {
+		t.Fatalf("queueMergeRequestRefs() unexpected error = %v", err)
+	}
+	counts, err := createBranchesInRepo(context.Background(), api, resumed.Drain(), resumed.Len(), "group/project", createOptions{fetch: true})
+	if err != nil {
+		t.Fatalf("createBranchesInRepo() unexpected error = %v", err)
+	}
+	if counts != (branchCounts{created: 1, failed: 1, total: 2}) {
+		t.Errorf("createBranchesInRepo() counts = %+v, want 1 created and 1 failed of 2", counts)
+	}
+
+	closeRefQueue(resumed, true)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("drained queue %s was not removed", path)
+	}
+}
+
+func TestQueueMergeRequestRefs_FailedFetch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), queueFilename("group/missing"))
+
+	if _, err := queueMergeRequestRefs(context.Background(), newMockAPI(), path, "group/missing", "", false); err == nil {
+		t.Fatal("queueMergeRequestRefs() expected an error for an unknown repository")
+	}
+	for _, p := range []string{path, path + ".partial"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("failed fetch left %s behind", p)
+		}
+	}
+}
+
+func TestQueueMergeRequestRefs_ResumesFailedFetch(t *testing.T) {
+	api := newMockAPI()
+	api.refs["group/project"] = []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaa"}, {IID: 2, HeadSHA: "bbb"}}
+	api.fetchErr = errors.New("connection reset")
+	path := filepath.Join(t.TempDir(), queueFilename("group/project"))
+
+	if _, err := queueMergeRequestRefs(context.Background(), api, path, "group/project", "", true); err == nil {
+		t.Fatal("queueMergeRequestRefs() expected the fetch error")
+	}
+
+	// The next fetch keeps the references of the failed one and only adds the others
+	api.fetchErr = nil
+	api.refs["group/project"] = append(api.refs["group/project"], gitlab.MergeRequestRef{IID: 3, HeadSHA: "ccc"})
+	q, err := queueMergeRequestRefs(context.Background(), api, path, "group/project", "", true)
+	if err != nil {
+		t.Fatalf("queueMergeRequestRefs() unexpected error = %v", err)
+	}
+	defer closeRefQueue(q, true)
+
+	var iids []int
+	for ref, err := range q.Drain() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		iids = append(iids, ref.IID)
+	}
+	if fmt.Sprint(iids) != "[1 2 3]" {
+		t.Errorf("queued references = %v, want [1 2 3] once each", iids)
+	}
+}
+
+func TestCreateBranchesOnGitHub_AcksFlushedBatches(t *testing.T) {
+	api := newMockGitHubAPI()
+	path := filepath.Join(t.TempDir(), queueFilename("group/project"))
+	q, err := queue.Open[gitlab.MergeRequestRef](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		if err := q.Push(gitlab.MergeRequestRef{IID: i, HeadSHA: fmt.Sprintf("sha%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The second batch fails as a whole, so its reference stays queued
+	opts := githubOptions{createOptions: createOptions{fetch: true}, batchSize: 2, ack: q.Ack}
+	calls := 0
+	failing := &failingCreateRefs{mockGitHubAPI: api, failAt: 2, calls: &calls}
+	if _, err := createBranchesOnGitHub(context.Background(), failing, q.Items(), q.Len(), "group/project", "octo/repo", opts); err == nil {
+		t.Fatal("createBranchesOnGitHub() expected the batch error")
+	}
+	if q.Len() != 1 {
+		t.Errorf("queue holds %d references, want the 1 of the failed batch", q.Len())
+	}
+	closeRefQueue(q, true)
+}
+
+// failingCreateRefs fails the failAt-th CreateRefs call as a whole
+type failingCreateRefs struct {
+	*mockGitHubAPI
+	failAt int
+	calls  *int
+}
+
+func (f *failingCreateRefs) CreateRefs(ctx context.Context, repositoryID string, refs []github.Ref) ([]error, error) {
+	*f.calls++
+	if *f.calls == f.failAt {
+		return nil, errors.New("connection reset")
+	}
+	return f.mockGitHubAPI.CreateRefs(ctx, repositoryID, refs)
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/github"
+	"github.com/amenocal/gh-gl-create-refs/pkg/redact"
+	"github.com/spf13/cobra"
+)
+
+// maxAttachmentSize is the largest results file included in a posted summary, keeping the
+// comment well below GitHub's body size limit
+const maxAttachmentSize = 32 * 1024
+
+// runReport is the summary of a run posted with --summary-issue
+type runReport struct {
+	command   string
+	startedAt time.Time
+	duration  time.Duration
+	counts    []reportCount
+	// file is the results file of the run, if any
+	file string
+	err  error
+}
+
+// reportCount is one line of the counts table
+type reportCount struct {
+	label string
+	value int
+}
+
+// addSummaryIssueFlag registers --summary-issue on cmd
+func addSummaryIssueFlag(cmd *cobra.Command) {
+	cmd.Flags().String("summary-issue", "", "Post the run's summary as a comment on this GitHub issue or pull request (owner/name#number or URL), using gh's credentials")
+}
+
+// summaryIssueFlag returns the issue given with --summary-issue, or nil
+func summaryIssueFlag(cmd *cobra.Command) (*github.IssueRef, error) {
+	value := cmd.Flag("summary-issue").Value.String()
+	if value == "" {
+		return nil, nil
+	}
+
+	issue, err := github.ParseIssueRef(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --summary-issue: %w", err)
+	}
+	return &issue, nil
+}
+
+// markdown renders the report as the body of a comment
+func (r runReport) markdown() string {
+	var b strings.Builder
+
+	if r.err != nil {
+		fmt.Fprintf(&b, "### ❌ %s failed\n\n", r.command)
+	} else {
+		fmt.Fprintf(&b, "### ✅ %s completed\n\n", r.command)
+	}
+
+	b.WriteString("| | |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Started | %s |\n", r.startedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "| Duration | %s |\n", r.duration.Round(time.Second))
+	for _, c := range r.counts {
+		fmt.Fprintf(&b, "| %s | %d |\n", c.label, c.value)
+	}
+	if r.file != "" {
+		fmt.Fprintf(&b, "| Results file | `%s` |\n", r.file)
+	}
+
+	if r.err != nil {
+		fmt.Fprintf(&b, "\n**Error:** %s\n", redact.String(r.err.Error()))
+	}
+
+	if r.file != "" {
+		b.WriteString(attachment(r.file))
+	}
+
+	return b.String()
+}
+
+// attachment returns the contents of a small results file as a collapsed block, or a note
+// when it is too large or cannot be read
+func attachment(filename string) string {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return ""
+	}
+	if info.Size() > maxAttachmentSize {
+		return fmt.Sprintf("\n_%s is too large to attach (%d bytes)._\n", filepath.Base(filename), info.Size())
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("\n<details><summary>%s</summary>\n\n```\n%s\n```\n\n</details>\n", filepath.Base(filename), strings.TrimRight(string(data), "\r\n"))
+}
+
+// postRunSummary posts the report as a comment on issue, or prints it in mock mode. It
+// returns the run's error, which takes precedence over failing to post.
+func postRunSummary(ctx context.Context, client github.API, issue github.IssueRef, report runReport, mock bool) error {
+	body := report.markdown()
+
+	if mock {
+		logger.Info("mock mode: printing the summary instead of posting it", "issue", issue.String())
+		fmt.Printf("\n%s", body)
+		return report.err
+	}
+
+	url, err := client.AddComment(ctx, issue, body)
+	if err != nil {
+		err = fmt.Errorf("failed to post summary to %s: %w", issue, err)
+		if report.err != nil {
+			logger.Error("failed to post summary", "issue", issue.String(), "error", err)
+			return report.err
+		}
+		return err
+	}
+
+	logger.Info("posted summary", "issue", issue.String(), "url", url)
+	statusf("💬 Summary: %s\n", url)
+	return report.err
+}
@@ -1,26 +1,229 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
+	"runtime/debug"
+	"time"
 
+	"github.com/amenocal/gh-gl-create-refs/pkg/config"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/migrate"
+	"github.com/amenocal/gh-gl-create-refs/pkg/output"
+	"github.com/amenocal/gh-gl-create-refs/pkg/ratelimit"
+	"github.com/amenocal/gh-gl-create-refs/pkg/redact"
+	"github.com/amenocal/gh-gl-create-refs/pkg/replay"
+	"github.com/amenocal/gh-gl-create-refs/pkg/schedule"
 	"github.com/spf13/cobra"
 )
 
+// logger receives progress and diagnostic messages; it is configured from the
+// global logging flags before any subcommand runs
+var logger = slog.New(slog.NewTextHandler(redact.NewWriter(os.Stderr), nil))
+
+// quiet suppresses status messages and summaries on stdout; set by --quiet
+var quiet bool
+
 var rootCmd = &cobra.Command{
 	Use:   "gh-gl-create-refs",
 	Short: "A GitHub CLI extension to work with GitLab repository references",
 	Long: `gh-gl-create-refs is a GitHub CLI extension that provides utilities to work with GitLab repository references.
 It can fetch merge request references from GitLab and export them in various formats.`,
+	PersistentPreRunE: setup,
+	Version:           appVersion(),
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	defer recoverPanic()
+
+	ctx, stop := notifyInterrupt(context.Background())
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	stop()
+	stopMetricsServer()
+
+	// Report metrics for failed runs too; they are most useful when tuning a run that went wrong
+	if reportErr := reportMetrics(); reportErr != nil && err == nil {
+		err = reportErr
+	}
+	// Failed runs make fixtures for the error handling
+	if saveErr := saveFixture(); saveErr != nil && err == nil {
+		err = saveErr
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", redact.String(err.Error()))
+		if hint := errorHint(cmd, err); hint != "" {
+			fmt.Fprintf(os.Stderr, "Hint: %s\n", redact.String(hint))
+		}
+		os.Exit(exitCode(err))
+	}
+}
+
+// recoverPanic prints a panic of the command's goroutine with its stack trace and exits.
+// The panic value can hold a request URL or a token, so it is redacted like any other output.
+func recoverPanic() {
+	r := recover()
+	if r == nil {
+		return
 	}
+	fmt.Fprintf(os.Stderr, "panic: %s\n\n%s", redact.String(fmt.Sprint(r)), redact.String(string(debug.Stack())))
+	os.Exit(exitError)
 }
 
 func init() {
-	// Add subcommands here
-}
\ No newline at end of file
+	rootCmd.SetFlagErrorFunc(flagError)
+	rootCmd.PersistentFlags().String("config", "", "Read default flag values from this file, as written by init (default: "+config.DefaultPath+" if it exists)")
+	rootCmd.PersistentFlags().String("profile", "", "Use the values of this profile of the config file, such as one per GitLab instance, over the file's defaults")
+	rootCmd.PersistentFlags().String("branch-prefix", defaultBranchPrefix, "Prefix of the branch names created for merge requests")
+	rootCmd.PersistentFlags().String("naming", string(migrate.NamingPRNumber), "Pattern of the branch names after the prefix: pr-number, source-branch, short-sha or pr-and-sha")
+	rootCmd.PersistentFlags().String("log-format", "text", "Log output format: text or json")
+	rootCmd.PersistentFlags().String("log-level", "info", "Minimum level of log messages: debug, info, warn or error")
+	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Log debug messages, such as rate limit waits (same as --log-level debug)")
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Only log errors and don't print status messages or summaries")
+	rootCmd.MarkFlagsMutuallyExclusive("log-level", "verbose", "quiet")
+	rootCmd.PersistentFlags().Bool("debug", false, "Log every GitLab and GitHub API request with its status, TLS connection and rate limit headers, never the token (implies --verbose)")
+	rootCmd.MarkFlagsMutuallyExclusive("debug", "quiet")
+	rootCmd.PersistentFlags().Bool("plain", false, "Print ASCII instead of emoji in status output (the default when the locale is not UTF-8)")
+	rootCmd.PersistentFlags().Bool("no-color", false, "Don't color status output (also disabled by NO_COLOR and when stdout is not a terminal)")
+	rootCmd.PersistentFlags().Float64("rps", gitlab.DefaultRequestsPerSecond, "Maximum GitLab API requests per second (0 for no client-side limit)")
+	rootCmd.PersistentFlags().Int("burst", gitlab.DefaultBurst, "Number of GitLab API requests allowed back to back")
+	rootCmd.PersistentFlags().String("auth-type", string(gitlab.AuthTypeToken), "How --token authenticates: token (personal, project or group access token) or oauth")
+	rootCmd.PersistentFlags().String("oauth-client-id", "", "OAuth application ID used to obtain a token by device authorization when --auth-type oauth is set without --token")
+	rootCmd.PersistentFlags().String("sudo", "", "Make every GitLab API request on behalf of this username, with an administrator token that has the sudo scope")
+	rootCmd.PersistentFlags().String("ca-cert", "", "PEM file of certificate authorities to trust for the GitLab instance, in addition to the system roots")
+	rootCmd.PersistentFlags().Bool("insecure-skip-verify", false, "Skip TLS certificate verification (insecure, for testing only)")
+	rootCmd.PersistentFlags().String("cache-dir", "", "Cache GitLab API responses in this directory so re-runs skip identical requests")
+	rootCmd.PersistentFlags().Bool("offline", false, "Answer every GitLab API request from --cache-dir without contacting GitLab, failing on requests it did not record")
+	rootCmd.PersistentFlags().Duration("cache-ttl", time.Hour, "How long cached responses are used without checking GitLab (0 to follow GitLab's cache headers)")
+	rootCmd.PersistentFlags().Duration("project-cache-ttl", 15*time.Minute, "How long project lookups (ID, default branch, merge request counts) are reused across runs (0 to disable)")
+	rootCmd.PersistentFlags().String("user-agent", defaultUserAgent(), "User-Agent header sent with every GitLab API request")
+	rootCmd.PersistentFlags().String("record-fixture", "", "Record the GitLab API requests of the run, sanitized, to this JSON fixture file for tests")
+	rootCmd.PersistentFlags().String("metrics-file", "", "Write GitLab API request metrics for the run to this JSON file")
+	rootCmd.PersistentFlags().String("metrics-addr", "", "Serve Prometheus metrics at /metrics on this address (e.g. :9090) while the command runs, such as fetch-refs --schedule")
+	rootCmd.PersistentFlags().Int("max-api-calls", 0, "Stop the run, keeping its progress, after this many GitLab API requests (0 for no limit)")
+	rootCmd.PersistentFlags().String("window", "", "Only work during this daily local time window, e.g. 22:00-06:00; long runs pause outside it and resume when it opens")
+	rootCmd.PersistentFlags().Duration("progress-interval", 30*time.Second, "How often to log throughput and time remaining when stderr is not a terminal (0 to disable)")
+}
+
+// setup runs before any subcommand: it configures logging, applies the configuration file
+// to the flags not given on the command line, and checks the global flags
+func setup(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(cmd, args); err != nil {
+		return err
+	}
+	if err := applyConfig(cmd); err != nil {
+		return err
+	}
+
+	// Commands writing references select formats from the same registry, so check --format once for all
+	if name := flagValue(cmd, "format"); name != "" {
+		if _, err := output.New(name, output.Options{}); err != nil {
+			return fmt.Errorf("invalid --format: %w", err)
+		}
+	}
+
+	// Commands naming branches read these again; check them before any command runs
+	if _, err := namingFlags(cmd); err != nil {
+		return err
+	}
+
+	recorder = nil
+	if cmd.Flag("record-fixture").Value.String() != "" {
+		recorder = replay.NewRecorder()
+	}
+
+	maxCalls, _ := cmd.Flags().GetInt("max-api-calls")
+	if maxCalls < 0 {
+		return fmt.Errorf("--max-api-calls must not be negative")
+	}
+	budget = nil
+	if maxCalls > 0 {
+		budget = gitlab.NewBudget(maxCalls)
+	}
+
+	if spec := cmd.Flag("window").Value.String(); spec != "" {
+		window, err := schedule.ParseWindow(spec)
+		if err != nil {
+			return fmt.Errorf("invalid --window: %w", err)
+		}
+		cmd.SetContext(withWindow(cmd.Context(), window))
+	}
+
+	if addr := cmd.Flag("metrics-addr").Value.String(); addr != "" {
+		return startMetricsServer(addr)
+	}
+	return nil
+}
+
+// setupLogging builds the logger from the global logging and verbosity flags, and decides
+// whether status output is colored
+func setupLogging(cmd *cobra.Command, args []string) error {
+	format := cmd.Flag("log-format").Value.String()
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	quiet, _ = cmd.Flags().GetBool("quiet")
+	debugHTTP, _ = cmd.Flags().GetBool("debug")
+	noColor, _ := cmd.Flags().GetBool("no-color")
+	colorEnabled = useColor(noColor, os.Stdout)
+	plain, _ := cmd.Flags().GetBool("plain")
+	plainOutput = usePlain(plain)
+	progressEvery, _ = cmd.Flags().GetDuration("progress-interval")
+	if isTerminal(os.Stderr) {
+		progressEvery = 0
+	}
+
+	level, err := parseLogLevel(cmd.Flag("log-level").Value.String())
+	if err != nil {
+		return err
+	}
+	switch {
+	case verbose || debugHTTP:
+		level = slog.LevelDebug
+	case quiet:
+		level = slog.LevelError
+	}
+
+	handler, err := newLogHandler(format, level)
+	if err != nil {
+		return err
+	}
+
+	logger = slog.New(handler)
+	slog.SetDefault(logger)
+	rateLimits = ratelimit.NewHosts(logger)
+	return nil
+}
+
+// parseLogLevel parses a --log-level value
+func parseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid --log-level %q: must be debug, info, warn or error", s)
+	}
+	return level, nil
+}
+
+// newLogHandler creates the handler of the logger, writing to stderr with credentials redacted
+func newLogHandler(format string, level slog.Level) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	w := redact.NewWriter(os.Stderr)
+
+	switch format {
+	case "text":
+		return slog.NewTextHandler(w, opts), nil
+	case "json":
+		return slog.NewJSONHandler(w, opts), nil
+	default:
+		return nil, fmt.Errorf("invalid --log-format %q: must be text or json", format)
+	}
+}
+
+// statusf prints a status message or summary line to stdout unless --quiet is set. Results
+// a command was asked for, such as token details or validation problems, are printed directly.
+func statusf(format string, a ...any) {
+	if quiet {
+		return
+	}
+	fmt.Print(symbols(redact.String(fmt.Sprintf(format, a...))))
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestNewLogHandler(t *testing.T) {
+	for _, format := range []string{"text", "json"} {
+		if _, err := newLogHandler(format, slog.LevelInfo); err != nil {
+			t.Errorf("newLogHandler(%q) unexpected error: %v", format, err)
+		}
+	}
+
+	if _, err := newLogHandler("xml", slog.LevelInfo); err == nil {
+		t.Error("newLogHandler(\"xml\") expected error, got nil")
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    slog.Level
+		expectError bool
+	}{
+		{input: "debug", expected: slog.LevelDebug},
+		{input: "info", expected: slog.LevelInfo},
+		{input: "WARN", expected: slog.LevelWarn},
+		{input: "error", expected: slog.LevelError},
+		{input: "verbose", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseLogLevel(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("parseLogLevel(%q) expected error, got %v", tt.input, got)
+				}
+				return
+			}
+			if err != nil || got != tt.expected {
+				t.Errorf("parseLogLevel(%q) = %v, %v; want %v", tt.input, got, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSetupLogging_Verbosity(t *testing.T) {
+	defer func() { quiet = false }()
+
+	tests := []struct {
+		name          string
+		flags         map[string]string
+		expectedLevel slog.Level
+		expectedQuiet bool
+	}{
+		{"default", nil, slog.LevelInfo, false},
+		{"log level", map[string]string{"log-level": "warn"}, slog.LevelWarn, false},
+		{"verbose", map[string]string{"verbose": "true"}, slog.LevelDebug, false},
+		{"quiet", map[string]string{"quiet": "true"}, slog.LevelError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"log-level", "verbose", "quiet"} {
+				flag := rootCmd.PersistentFlags().Lookup(name)
+				flag.Value.Set(flag.DefValue)
+			}
+			for name, value := range tt.flags {
+				if err := rootCmd.PersistentFlags().Set(name, value); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := setupLogging(rootCmd, nil); err != nil {
+				t.Fatalf("setupLogging() unexpected error = %v", err)
+			}
+			if !logger.Enabled(t.Context(), tt.expectedLevel) || (tt.expectedLevel > slog.LevelDebug && logger.Enabled(t.Context(), tt.expectedLevel-1)) {
+				t.Errorf("logger level is not %v", tt.expectedLevel)
+			}
+			if quiet != tt.expectedQuiet {
+				t.Errorf("quiet = %v, want %v", quiet, tt.expectedQuiet)
+			}
+		})
+	}
+
+	for _, name := range []string{"log-level", "verbose", "quiet"} {
+		flag := rootCmd.PersistentFlags().Lookup(name)
+		flag.Value.Set(flag.DefValue)
+	}
+	if err := setupLogging(rootCmd, nil); err != nil {
+		t.Fatal(err)
+	}
+}
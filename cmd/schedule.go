@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/plan"
+	"github.com/amenocal/gh-gl-create-refs/pkg/redact"
+	"github.com/amenocal/gh-gl-create-refs/pkg/schedule"
+	"github.com/amenocal/gh-gl-create-refs/pkg/state"
+)
+
+// runScheduledFetch repeats the incremental fetch (and optional branch sync) on a cron
+// schedule until interrupted. Failed runs are logged and retried at the next scheduled time.
+func runScheduledFetch(ctx context.Context, client gitlab.API, opts fetchOptions, spec, syncTarget string) error {
+	sched, err := schedule.Parse(spec)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("scheduler started, press Ctrl+C to stop", "schedule", spec)
+
+	for {
+		next := sched.Next(time.Now())
+		if next.IsZero() {
+			return fmt.Errorf("schedule %q never fires", spec)
+		}
+
+		logger.Info("next scheduled run", "at", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-interruptDone(ctx):
+			timer.Stop()
+			logger.Info("scheduler stopped")
+			return nil
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		if _, err := runFetch(ctx, client, opts); err != nil {
+			if errors.Is(err, errInterrupted) {
+				logger.Info("scheduler stopped")
+				return nil
+			}
+			if errors.Is(err, gitlab.ErrBudgetExhausted) {
+				return err
+			}
+			logger.Error("scheduled fetch failed", "error", err)
+			runStats.scheduledFailures.Add(1)
+			continue
+		}
+		runStats.scheduledRuns.Add(1)
+
+		if syncTarget != "" {
+			if err := syncBranches(ctx, client, opts, syncTarget); err != nil {
+				logger.Error("scheduled sync failed", "error", err)
+			}
+		}
+	}
+}
+
+// syncBranches creates or updates migration branches in the target repository so they
+// match the references in the fetched output file, and records the run in the state store
+func syncBranches(ctx context.Context, client gitlab.API, opts fetchOptions, syncTarget string) error {
+	startedAt := time.Now()
+
+	outputPath := opts.output
+	if outputPath == "" {
+		outputPath = csv.GenerateFilename(opts.repository)
+	}
+
+	p, err := buildSyncPlan(ctx, client, outputPath, opts.repository, syncTarget, opts.naming)
+	if err == nil {
+		err = applyPlan(ctx, client, p, false)
+	}
+
+	run := state.Run{
+		StartedAt:    startedAt,
+		FinishedAt:   time.Now(),
+		Mode:         "sync",
+		Repositories: 1,
+	}
+	if p != nil {
+		s := p.Summarize()
+		run.Refs = s.Create + s.Update
+	}
+	if err != nil {
+		run.Error = redact.String(err.Error())
+		run.Failed = 1
+	}
+	opts.store.RecordRun(run)
+
+	if saveErr := opts.store.Save(); saveErr != nil && err == nil {
+		err = saveErr
+	}
+
+	return err
+}
+
+func buildSyncPlan(ctx context.Context, client gitlab.API, inputFile, repository, syncTarget string, naming refNaming) (*plan.Plan, error) {
+	refs, err := readMergeRequestRefsFromCSV(inputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	_, targetProjectPath, err := gitlab.ParseRepoPath(syncTarget)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse target repository path: %w", err)
+	}
+
+	existing, err := client.ListBranchSHAs(ctx, targetProjectPath, naming.branchPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read existing branches: %w", err)
+	}
+
+	return &plan.Plan{
+		Version:    plan.Version,
+		CreatedAt:  time.Now().UTC(),
+		Repository: repository,
+		Target:     targetProjectPath,
+		Actions:    plan.Build(refs, existing, naming.branchName),
+	}, nil
+}
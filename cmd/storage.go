@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/output"
+)
+
+// remoteOutput stages the files of a run whose --output is a storage URL such as
+// s3://bucket/refs.csv: they are written to a local directory, complete with their checksum
+// files, and uploaded once the run succeeds
+type remoteOutput struct {
+	storage  output.Storage
+	location string
+	dir      string
+}
+
+// stageRemoteOutput returns the local --output standing in for the storage URL outputFlag,
+// and the remoteOutput that uploads it. When dir is set, outputFlag is a directory, as for
+// multi-repository fetches; otherwise its last element is the file name.
+func stageRemoteOutput(outputFlag string, dir bool) (string, *remoteOutput, error) {
+	location, name := outputFlag, ""
+	if !dir {
+		i := strings.LastIndex(outputFlag, "/")
+		location, name = outputFlag[:i+1], outputFlag[i+1:]
+		if name == "" || strings.HasSuffix(location, "://") {
+			return "", nil, fmt.Errorf("--output %s must name a file in a bucket", outputFlag)
+		}
+	}
+	if output.IsTemplate(location) {
+		return "", nil, fmt.Errorf("--output %s can only use a template in its file name", outputFlag)
+	}
+
+	storage, err := output.NewStorage(location)
+	if err != nil {
+		return "", nil, err
+	}
+
+	staging, err := os.MkdirTemp("", "gh-gl-create-refs-output-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	r := &remoteOutput{storage: storage, location: strings.TrimSuffix(location, "/"), dir: staging}
+	return filepath.Join(staging, name), r, nil
+}
+
+// upload copies every file written to the staging directory to the storage, returning
+// how many were uploaded
+func (r *remoteOutput) upload(ctx context.Context) (int, error) {
+	count := 0
+	err := filepath.WalkDir(r.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(path, ".lock") {
+			return err
+		}
+		name, err := filepath.Rel(r.dir, path)
+		if err != nil {
+			return err
+		}
+		if err := r.storage.Upload(ctx, path, filepath.ToSlash(name)); err != nil {
+			return err
+		}
+		logger.Debug("uploaded output file", "file", r.location+"/"+filepath.ToSlash(name))
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// cleanup removes the staging directory
+func (r *remoteOutput) cleanup() {
+	if err := os.RemoveAll(r.dir); err != nil {
+		logger.Warn("failed to remove staging directory", "dir", r.dir, "error", err)
+	}
+}
+
+// finish uploads the staged files when the run succeeded and removes the staging directory
+func (r *remoteOutput) finish(ctx context.Context, runErr error) error {
+	defer r.cleanup()
+	if runErr != nil {
+		return runErr
+	}
+
+	count, err := r.upload(ctx)
+	if err != nil {
+		return err
+	}
+	statusf("%s %d files to: %s\n", green("Uploaded"), count, r.location)
+	return nil
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/output"
+)
+
+// memoryStorage records uploads for the test:// storage
+type memoryStorage struct {
+	mu      sync.Mutex
+	uploads map[string]string
+}
+
+var testStorage = &memoryStorage{uploads: make(map[string]string)}
+
+func init() {
+	output.RegisterStorage("test", func(location *url.URL) (output.Storage, error) {
+		return testStorage, nil
+	})
+}
+
+func (s *memoryStorage) Upload(ctx context.Context, localPath, name string) error {
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.uploads[name] = string(data)
+	return nil
+}
+
+func TestStageRemoteOutput(t *testing.T) {
+	local, remote, err := stageRemoteOutput("test://bucket/refs/group-project.csv", false)
+	if err != nil {
+		t.Fatalf("stageRemoteOutput() unexpected error = %v", err)
+	}
+	defer remote.cleanup()
+
+	if filepath.Base(local) != "group-project.csv" || filepath.Dir(local) != remote.dir {
+		t.Errorf("Local output = %q, want group-project.csv in %q", local, remote.dir)
+	}
+	if remote.location != "test://bucket/refs" {
+		t.Errorf("Location = %q, want test://bucket/refs", remote.location)
+	}
+
+	for _, invalid := range []string{"test://bucket/", "test://bucket", "test://bucket/{{.Repo}}/refs.csv"} {
+		if _, _, err := stageRemoteOutput(invalid, false); err == nil {
+			t.Errorf("stageRemoteOutput(%q) expected an error", invalid)
+		}
+	}
+}
+
+func TestRemoteOutput_Upload(t *testing.T) {
+	testStorage.uploads = make(map[string]string)
+
+	local, remote, err := stageRemoteOutput("test://bucket/out/", true)
+	if err != nil {
+		t.Fatalf("stageRemoteOutput() unexpected error = %v", err)
+	}
+
+	api := newMockAPI()
+	api.refs["group/project"] = []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaaaaaa"}}
+	api.projects["group/project"] = &gitlab.ProjectInfo{Path: "group/project", TotalMergeRequests: 1}
+
+	outputPath, err := outputPathFor(local, "group/project", true, outputFormat{}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := fetchRepository(context.Background(), api, nil, "group/project", "", outputPath, outputFormat{}); err != nil {
+		t.Fatalf("fetchRepository() unexpected error = %v", err)
+	}
+
+	if err := remote.finish(context.Background(), nil); err != nil {
+		t.Fatalf("finish() unexpected error = %v", err)
+	}
+
+	names := slices.Sorted(maps.Keys(testStorage.uploads))
+	if !slices.Equal(names, []string{"group-project.csv", "group-project.csv.sha256"}) {
+		t.Errorf("Uploaded %v, want the output and its checksum", names)
+	}
+	if got := testStorage.uploads["group-project.csv"]; got != "# gh-gl-create-refs refs v1\n1,aaaaaaa\n" {
+		t.Errorf("Uploaded output = %q", got)
+	}
+	if _, err := os.Stat(remote.dir); !os.IsNotExist(err) {
+		t.Error("Expected the staging directory to be removed")
+	}
+}
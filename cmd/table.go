@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// writeTable writes rows under headers as columns aligned with spaces. Cells are written
+// as is, so they must not contain tabs, color codes or wide characters such as emoji.
+func writeTable(w io.Writer, headers []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// statusTable prints a table to stdout unless --quiet is set, like statusf
+func statusTable(headers []string, rows [][]string) {
+	if quiet {
+		return
+	}
+	fmt.Println()
+	writeTable(os.Stdout, headers, rows)
+}
+
+// formatDuration rounds d for display in tables
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+// statusCell describes the outcome of a row: ok, or the error shortened to its first line
+func statusCell(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	message, _, _ := strings.Cut(err.Error(), "\n")
+	const maxLength = 60
+	if runes := []rune(message); len(runes) > maxLength {
+		message = string(runes[:maxLength-3]) + "..."
+	}
+	return "error: " + message
+}
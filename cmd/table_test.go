@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteTable(t *testing.T) {
+	var b bytes.Buffer
+	rows := [][]string{
+		{"group/project", "12", "1.2s", "ok"},
+		{"group/a-much-longer-project", "3", "250ms", "error: not found"},
+	}
+	if err := writeTable(&b, []string{"REPOSITORY", "MERGE REQUESTS", "DURATION", "STATUS"}, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `REPOSITORY                   MERGE REQUESTS  DURATION  STATUS
+group/project                12              1.2s      ok
+group/a-much-longer-project  3               250ms     error: not found
+`
+	if b.String() != expected {
+		t.Errorf("writeTable() =\n%s\nwant\n%s", b.String(), expected)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		expected string
+	}{
+		{1234567 * time.Nanosecond, "1ms"},
+		{250 * time.Millisecond, "250ms"},
+		{1234 * time.Millisecond, "1.2s"},
+		{83 * time.Second, "1m23s"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.expected {
+			t.Errorf("formatDuration(%v) = %q, want %q", tt.d, got, tt.expected)
+		}
+	}
+}
+
+func TestStatusCell(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"success", nil, "ok"},
+		{"error", errors.New("project not found"), "error: project not found"},
+		{"first line only", errors.New("failed\ndetails"), "error: failed"},
+		{"long error", errors.New(strings.Repeat("x", 100)), "error: " + strings.Repeat("x", 57) + "..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusCell(tt.err); got != tt.expected {
+				t.Errorf("statusCell() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
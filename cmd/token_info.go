@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/spf13/cobra"
+)
+
+// tokenExpiryWarning is how far ahead of expiry token-info starts warning
+const tokenExpiryWarning = 7 * 24 * time.Hour
+
+var tokenInfoCmd = &cobra.Command{
+	Use:   "token-info",
+	Short: "Show details and permissions of the configured GitLab token",
+	Long: `Show the owner, scopes and expiry date of the configured GitLab access token.
+
+When --repository is given, also check whether the token can read merge requests
+from the repository (needed by fetch-refs) and create branches in it (needed by create-refs).
+
+Examples:
+  gh gl-create-refs token-info
+  gh gl-create-refs token-info --repository group/project
+  gh gl-create-refs token-info -r group/project --base-url https://gitlab.example.com`,
+	Args: cobra.NoArgs,
+	RunE: runTokenInfo,
+}
+
+func init() {
+	rootCmd.AddCommand(tokenInfoCmd)
+
+	addTokenFlags(tokenInfoCmd)
+	tokenInfoCmd.Flags().StringP("base-url", "b", "", "GitLab base URL (default: https://gitlab.com)")
+	tokenInfoCmd.Flags().StringP("repository", "r", "", "GitLab repository path to check permissions on (optional)")
+	addProjectCompletion(tokenInfoCmd, "repository")
+}
+
+func runTokenInfo(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	baseURL := cmd.Flag("base-url").Value.String()
+	repository := cmd.Flag("repository").Value.String()
+
+	client, err := newGitLabClient(cmd, baseURL)
+	if err != nil {
+		return err
+	}
+
+	info, err := client.GetTokenInfo(ctx)
+	if err != nil {
+		return err
+	}
+
+	printTokenInfo(info, time.Now())
+
+	if repository == "" {
+		return nil
+	}
+
+	_, projectPath, err := gitlab.ParseRepoPath(repository)
+	if err != nil {
+		return fmt.Errorf("failed to parse repository path: %w", err)
+	}
+
+	access, err := client.GetProjectAccess(ctx, projectPath, info.Scopes)
+	if err != nil {
+		return err
+	}
+
+	printProjectAccess(access)
+	return nil
+}
+
+func printTokenInfo(info *gitlab.TokenInfo, now time.Time) {
+	fmt.Printf("Token:   %s\n", info.Name)
+	fmt.Printf("Owner:   %s\n", info.Owner)
+	fmt.Printf("Scopes:  %s\n", strings.Join(info.Scopes, ", "))
+
+	switch {
+	case info.ExpiresAt == nil:
+		fmt.Printf("Expires: never\n")
+	case info.Expired(now):
+		fmt.Print(symbols(fmt.Sprintf("Expires: %s ❌ expired\n", info.ExpiresAt.Format(time.DateOnly))))
+	case info.ExpiresAt.Sub(now) < tokenExpiryWarning:
+		fmt.Print(symbols(fmt.Sprintf("Expires: %s ⚠️  expires soon\n", info.ExpiresAt.Format(time.DateOnly))))
+	default:
+		fmt.Printf("Expires: %s\n", info.ExpiresAt.Format(time.DateOnly))
+	}
+
+	if info.Revoked || !info.Active {
+		fmt.Print(symbols("Status:  ❌ inactive\n"))
+	} else {
+		fmt.Print(symbols("Status:  ✅ active\n"))
+	}
+}
+
+func printProjectAccess(access *gitlab.ProjectAccess) {
+	fmt.Printf("\nProject: %s (%s)\n", access.ProjectPath, access.Visibility)
+	fmt.Printf("Role:    %s\n", gitlab.AccessLevelName(access.AccessLevel))
+	fmt.Printf("%s Read merge requests\n", checkMark(access.CanReadMergeRequests))
+	fmt.Printf("%s Write repository (create branches)\n", checkMark(access.CanWriteRepository))
+}
+
+func checkMark(ok bool) string {
+	if ok {
+		return symbols("✅")
+	}
+	return symbols("❌")
+}
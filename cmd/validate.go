@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+	"github.com/spf13/cobra"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check a merge request reference CSV file for problems",
+	Long: `Check a CSV file of merge request references without calling the GitLab API.
+
+Every row is checked and all problems are reported with their line number:
+- the file must parse as CSV with exactly two columns per row, or three for combined
+  files written by fetch-refs --combined, which start with a non-empty project path
+- merge request IIDs must be positive integers and must not repeat within a project
+- SHAs must be full 40-character hexadecimal commit SHAs
+
+Examples:
+  gh gl-create-refs validate --input group-project.csv
+  gh gl-create-refs validate -i refs.csv
+  gh gl-create-refs validate --input - < refs.csv`,
+	Args: cobra.NoArgs,
+	RunE: runValidate,
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().StringP("input", "i", "", "Input CSV file path, or - to read standard input (required)")
+
+	validateCmd.MarkFlagRequired("input")
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	inputFile := cmd.Flag("input").Value.String()
+
+	result, err := csv.ValidateFile(inputFile)
+	if err != nil {
+		return err
+	}
+
+	for _, problem := range result.Problems {
+		fmt.Printf("%s %s: %s\n", symbols("❌"), inputFile, red(problem.String()))
+	}
+
+	if !result.Valid() {
+		return withExitCode(exitValidation, fmt.Errorf("%s has %d problem(s) in %d rows", inputFile, len(result.Problems), result.Rows))
+	}
+
+	statusf("✅ %s %s (%d merge request references)\n", inputFile, green("is valid"), result.Rows)
+	return nil
+}
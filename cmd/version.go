@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"runtime/debug"
+)
+
+// version is set at build time with -ldflags "-X github.com/amenocal/gh-gl-create-refs/cmd.version=v1.2.3"
+var version = ""
+
+// appVersion returns the version of this build: the linked version if set, otherwise the
+// module version or VCS revision recorded by the Go toolchain, otherwise "dev"
+func appVersion() string {
+	if version != "" {
+		return version
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "dev"
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" && len(setting.Value) >= 7 {
+			return "dev-" + setting.Value[:7]
+		}
+	}
+	return "dev"
+}
+
+// defaultUserAgent identifies this tool's requests to GitLab
+func defaultUserAgent() string {
+	return "gh-gl-create-refs/" + appVersion()
+}
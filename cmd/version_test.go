@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAppVersion(t *testing.T) {
+	original := version
+	defer func() { version = original }()
+
+	version = "v1.2.3"
+	if got := appVersion(); got != "v1.2.3" {
+		t.Errorf("appVersion() = %q, want %q", got, "v1.2.3")
+	}
+	if got := defaultUserAgent(); got != "gh-gl-create-refs/v1.2.3" {
+		t.Errorf("defaultUserAgent() = %q, want %q", got, "gh-gl-create-refs/v1.2.3")
+	}
+
+	version = ""
+	if got := appVersion(); got == "" {
+		t.Error("appVersion() should fall back to build information or dev")
+	}
+	if !strings.HasPrefix(defaultUserAgent(), "gh-gl-create-refs/") {
+		t.Errorf("defaultUserAgent() = %q, want a gh-gl-create-refs/ prefix", defaultUserAgent())
+	}
+}
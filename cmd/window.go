@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/schedule"
+)
+
+// windowKey is the context key of the --window the run may work in
+type windowKey struct{}
+
+// withWindow returns a context whose loops only start work inside w
+func withWindow(ctx context.Context, w *schedule.Window) context.Context {
+	return context.WithValue(ctx, windowKey{}, w)
+}
+
+// waitForWindow returns at once inside the run's --window, or when there is none. Outside
+// it, it waits until the window opens again, keeping the run's progress, and returns
+// errInterrupted or the context's error if the run is stopped meanwhile.
+func waitForWindow(ctx context.Context) error {
+	w, _ := ctx.Value(windowKey{}).(*schedule.Window)
+	if w == nil {
+		return nil
+	}
+
+	now := time.Now()
+	opens := w.Next(now)
+	if !opens.After(now) {
+		return nil
+	}
+
+	logger.Info("outside the run window, pausing until it opens", "window", w.String(), "resume_at", opens.Format(time.RFC3339))
+	timer := time.NewTimer(opens.Sub(now))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		logger.Info("run window open, resuming", "window", w.String())
+		return nil
+	case <-interruptDone(ctx):
+		return errInterrupted
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
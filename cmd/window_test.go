@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/schedule"
+)
+
+// windowAround returns a window from now+from to now+to
+func windowAround(t *testing.T, from, to time.Duration) *schedule.Window {
+	t.Helper()
+	now := time.Now()
+	w, err := schedule.ParseWindow(now.Add(from).Format("15:04") + "-" + now.Add(to).Format("15:04"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestInterrupted_InsideWindow(t *testing.T) {
+	ctx := withWindow(context.Background(), windowAround(t, -time.Hour, time.Hour))
+
+	if err := interrupted(ctx); err != nil {
+		t.Errorf("interrupted() = %v, want nil inside the window", err)
+	}
+}
+
+func TestInterrupted_WaitsOutsideWindow(t *testing.T) {
+	done := make(chan struct{})
+	ctx := withWindow(withInterrupt(context.Background(), done), windowAround(t, time.Hour, 2*time.Hour))
+
+	result := make(chan error)
+	go func() { result <- interrupted(ctx) }()
+
+	select {
+	case err := <-result:
+		t.Fatalf("interrupted() = %v before the window opened", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(done)
+	if err := <-result; !errors.Is(err, errInterrupted) {
+		t.Errorf("interrupted() = %v, want errInterrupted", err)
+	}
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
+
+// keyringService is the service name tokens are stored under in the OS keyring
+const keyringService = "gh-gl-create-refs"
+
+// DefaultHost is the host tokens are stored for when no base URL is given
+const DefaultHost = "gitlab.com"
+
+// Host returns the keyring key for a GitLab base URL: its host name, or gitlab.com
+// for an empty base URL. URLs without a scheme are accepted.
+func Host(baseURL string) (string, error) {
+	if baseURL == "" {
+		return DefaultHost, nil
+	}
+
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "https://" + baseURL
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid GitLab base URL: %s", baseURL)
+	}
+
+	return strings.ToLower(u.Host), nil
+}
+
+// StoreToken saves the token for a GitLab instance in the OS keyring
+func StoreToken(baseURL, token string) error {
+	host, err := Host(baseURL)
+	if err != nil {
+		return err
+	}
+
+	if err := keyring.Set(keyringService, host, token); err != nil {
+		return fmt.Errorf("failed to store token for %s in keyring: %w", host, err)
+	}
+	return nil
+}
+
+// LookupToken returns the token stored for a GitLab instance in the OS keyring,
+// or an empty string when none is stored
+func LookupToken(baseURL string) (string, error) {
+	host, err := Host(baseURL)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := keyring.Get(keyringService, host)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read token for %s from keyring: %w", host, err)
+	}
+	return token, nil
+}
+
+// DeleteToken removes the token stored for a GitLab instance from the OS keyring.
+// Deleting a token that is not stored is not an error.
+func DeleteToken(baseURL string) error {
+	host, err := Host(baseURL)
+	if err != nil {
+		return err
+	}
+
+	err = keyring.Delete(keyringService, host)
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("failed to delete token for %s from keyring: %w", host, err)
+	}
+	return nil
+}
+
+// TokenFilePath is the file auth login keeps the token of host in when no OS keyring is
+// available: a file of its own in the user's configuration directory
+func TokenFilePath(host string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the configuration directory: %w", err)
+	}
+	// Ports are kept apart from the host without a colon, which Windows file names can't hold
+	return filepath.Join(dir, keyringService, "tokens", strings.ReplaceAll(host, ":", "_")), nil
+}
+
+// WriteTokenFile writes token to filename, readable and writable by the user only, creating
+// its directory when needed
+func WriteTokenFile(filename, token string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return fmt.Errorf("failed to create token directory: %w", err)
+	}
+	if err := os.WriteFile(filename, []byte(token+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(filename, 0600); err != nil {
+		return fmt.Errorf("failed to restrict token file: %w", err)
+	}
+	return nil
+}
+
+// ReadTokenFile reads a token from the first line of a file, ignoring surrounding whitespace
+func ReadTokenFile(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	token, _, _ := strings.Cut(string(data), "\n")
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", filename)
+	}
+	return token, nil
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestHost(t *testing.T) {
+	tests := []struct {
+		name        string
+		baseURL     string
+		expected    string
+		expectError bool
+	}{
+		{"empty uses gitlab.com", "", "gitlab.com", false},
+		{"https URL", "https://gitlab.example.com", "gitlab.example.com", false},
+		{"URL with path", "https://gitlab.example.com/api/v4/", "gitlab.example.com", false},
+		{"without scheme", "gitlab.example.com", "gitlab.example.com", false},
+		{"with port", "http://localhost:8080", "localhost:8080", false},
+		{"mixed case", "https://GitLab.Example.com", "gitlab.example.com", false},
+		{"invalid", "https://", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Host(tt.baseURL)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Host(%q) expected error, got nil", tt.baseURL)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Host(%q) unexpected error = %v", tt.baseURL, err)
+			}
+			if got != tt.expected {
+				t.Errorf("Host(%q) = %q, want %q", tt.baseURL, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTokenKeyring(t *testing.T) {
+	keyring.MockInit()
+
+	if token, err := LookupToken("https://gitlab.example.com"); err != nil || token != "" {
+		t.Fatalf("LookupToken() before store = %q, %v; want empty, nil", token, err)
+	}
+
+	if err := StoreToken("https://gitlab.example.com", "secret"); err != nil {
+		t.Fatalf("StoreToken() unexpected error = %v", err)
+	}
+
+	// Any URL for the same host finds the token
+	token, err := LookupToken("gitlab.example.com/api/v4")
+	if err != nil || token != "secret" {
+		t.Errorf("LookupToken() = %q, %v; want %q, nil", token, err, "secret")
+	}
+
+	if token, _ := LookupToken(""); token != "" {
+		t.Errorf("LookupToken() for gitlab.com = %q, want empty", token)
+	}
+
+	if err := DeleteToken("https://gitlab.example.com"); err != nil {
+		t.Fatalf("DeleteToken() unexpected error = %v", err)
+	}
+	if err := DeleteToken("https://gitlab.example.com"); err != nil {
+		t.Errorf("DeleteToken() of a missing token should succeed, got %v", err)
+	}
+	if token, _ := LookupToken("https://gitlab.example.com"); token != "" {
+		t.Errorf("LookupToken() after delete = %q, want empty", token)
+	}
+}
+
+func TestReadTokenFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expected    string
+		expectError bool
+	}{
+		{"plain", "glpat-abc", "glpat-abc", false},
+		{"trailing newline", "glpat-abc\n", "glpat-abc", false},
+		{"surrounding whitespace", "  glpat-abc \r\n", "glpat-abc", false},
+		{"extra lines ignored", "glpat-abc\ncomment\n", "glpat-abc", false},
+		{"empty", "\n", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "token")
+			if err := os.WriteFile(filename, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ReadTokenFile(filename)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("ReadTokenFile() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("ReadTokenFile() unexpected error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("ReadTokenFile() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	if _, err := ReadTokenFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("ReadTokenFile() expected error for missing file")
+	}
+}
+
+func TestWriteTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens", "gitlab.example.com")
+
+	if err := WriteTokenFile(path, "secret"); err != nil {
+		t.Fatalf("WriteTokenFile() unexpected error = %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("token file mode = %v, want 0600", info.Mode().Perm())
+	}
+	if token, err := ReadTokenFile(path); err != nil || token != "secret" {
+		t.Errorf("ReadTokenFile() = %q, %v; want secret", token, err)
+	}
+}
+
+func TestTokenFilePath(t *testing.T) {
+	path, err := TokenFilePath("gitlab.example.com:8443")
+	if err != nil {
+		t.Skipf("no configuration directory: %v", err)
+	}
+	if filepath.Base(path) != "gitlab.example.com_8443" {
+		t.Errorf("TokenFilePath() = %q", path)
+	}
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// GitLabUsername is the user name git sends with a GitLab token; GitLab accepts it for
+// personal, project, group and OAuth access tokens
+const GitLabUsername = "oauth2"
+
+// Credential is the description of a credential exchanged with git by the credential helper
+// protocol (see gitcredentials(7)). Attributes other than these are ignored.
+type Credential struct {
+	Protocol string
+	Host     string
+	Path     string
+	Username string
+	Password string
+}
+
+// ReadCredential reads a credential description from git: key=value lines ending at a blank
+// line or the end of the input
+func ReadCredential(r io.Reader) (Credential, error) {
+	var c Credential
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return Credential{}, fmt.Errorf("invalid credential line %q", line)
+		}
+		switch key {
+		case "protocol":
+			c.Protocol = value
+		case "host":
+			c.Host = value
+		case "path":
+			c.Path = value
+		case "username":
+			c.Username = value
+		case "password":
+			c.Password = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Credential{}, fmt.Errorf("failed to read credential: %w", err)
+	}
+	return c, nil
+}
+
+// Write writes the non-empty attributes of the credential for git
+func (c Credential) Write(w io.Writer) error {
+	for _, attr := range [][2]string{
+		{"protocol", c.Protocol},
+		{"host", c.Host},
+		{"path", c.Path},
+		{"username", c.Username},
+		{"password", c.Password},
+	} {
+		if attr[1] == "" {
+			continue
+		}
+		if strings.ContainsAny(attr[1], "\n\x00") {
+			return fmt.Errorf("invalid credential %s: contains a newline or NUL", attr[0])
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", attr[0], attr[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetupGit configures git globally to ask helper for the credentials of host over https,
+// replacing any helper configured for that host, so that pushes authenticate without
+// tokens embedded in remote URLs. It returns the configuration key that was set.
+func SetupGit(host, helper string) (string, error) {
+	key := fmt.Sprintf("credential.https://%s.helper", host)
+
+	// An empty helper first resets the helpers inherited from less specific configuration
+	if err := gitConfig("--global", "--replace-all", key, ""); err != nil {
+		return "", err
+	}
+	if err := gitConfig("--global", "--add", key, helper); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// gitConfig runs git config with args
+func gitConfig(args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"config"}, args...)...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run git config: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadCredential(t *testing.T) {
+	input := "protocol=https\nhost=gitlab.example.com\npath=group/project.git\nwwwauth[]=Basic\n\nignored=after blank line\n"
+
+	got, err := ReadCredential(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadCredential() unexpected error = %v", err)
+	}
+	want := Credential{Protocol: "https", Host: "gitlab.example.com", Path: "group/project.git"}
+	if got != want {
+		t.Errorf("ReadCredential() = %+v, want %+v", got, want)
+	}
+
+	if _, err := ReadCredential(strings.NewReader("not a credential\n")); err == nil {
+		t.Error("ReadCredential() expected an error for a line without =")
+	}
+}
+
+func TestCredential_Write(t *testing.T) {
+	var b strings.Builder
+	c := Credential{Protocol: "https", Host: "gitlab.com", Username: GitLabUsername, Password: "secret"}
+	if err := c.Write(&b); err != nil {
+		t.Fatalf("Write() unexpected error = %v", err)
+	}
+
+	want := "protocol=https\nhost=gitlab.com\nusername=oauth2\npassword=secret\n"
+	if b.String() != want {
+		t.Errorf("Write() = %q, want %q", b.String(), want)
+	}
+
+	if err := (Credential{Password: "a\nb"}).Write(&b); err == nil {
+		t.Error("Write() expected an error for a value with a newline")
+	}
+}
+
+func TestSetupGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	config := filepath.Join(t.TempDir(), "gitconfig")
+	t.Setenv("GIT_CONFIG_GLOBAL", config)
+
+	// Running setup twice must not stack helpers
+	for range 2 {
+		key, err := SetupGit("gitlab.example.com", "!/usr/bin/helper auth git-credential")
+		if err != nil {
+			t.Fatalf("SetupGit() unexpected error = %v", err)
+		}
+		if key != "credential.https://gitlab.example.com.helper" {
+			t.Errorf("SetupGit() key = %q", key)
+		}
+	}
+
+	data, err := os.ReadFile(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(data), "helper = "); got != 2 {
+		t.Errorf("Expected a reset and one helper, got:\n%s", data)
+	}
+	if !strings.Contains(string(data), `helper = !/usr/bin/helper auth git-credential`) {
+		t.Errorf("Helper not configured:\n%s", data)
+	}
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+
+	ghauth "github.com/cli/go-gh/v2/pkg/auth"
+)
+
+// ErrNoGitHubToken is returned when neither the environment nor gh has a token for a GitHub host
+var ErrNoGitHubToken = errors.New("no GitHub token found")
+
+// GitHubHost returns the GitHub host gh is working with: GH_HOST when set, else the host gh
+// is logged in to, or github.com
+func GitHubHost() string {
+	host, _ := ghauth.DefaultHost()
+	return host
+}
+
+// GitHubToken returns the token gh uses for a GitHub host, and where it was found, so
+// GitHub-facing features reuse the user's gh credentials instead of another token flag.
+// The lookup is gh's own: GH_TOKEN or GITHUB_TOKEN for github.com and GHE.com hosts,
+// GH_ENTERPRISE_TOKEN or GITHUB_ENTERPRISE_TOKEN for other hosts, then gh's configuration,
+// then the token gh auth login stored in the OS keyring.
+func GitHubToken(host string) (string, string, error) {
+	if host == "" {
+		host = GitHubHost()
+	}
+
+	token, source := ghauth.TokenForHost(host)
+	if token == "" {
+		return "", "", fmt.Errorf("%w for %s: run 'gh auth login --hostname %s'", ErrNoGitHubToken, host, host)
+	}
+	return token, source, nil
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// notLoggedIn points gh's configuration at an empty directory and installs a gh executable
+// that has no token either
+func notLoggedIn(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("GH_CONFIG_DIR", t.TempDir())
+	gh := filepath.Join(t.TempDir(), "gh")
+	if err := os.WriteFile(gh, []byte("#!/bin/sh\necho 'not logged in' >&2\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GH_PATH", gh)
+}
+
+func TestGitHubHost(t *testing.T) {
+	notLoggedIn(t)
+
+	t.Setenv("GH_HOST", "github.example.com")
+	if got := GitHubHost(); got != "github.example.com" {
+		t.Errorf("GitHubHost() = %q, want github.example.com", got)
+	}
+}
+
+func TestGitHubToken(t *testing.T) {
+	tests := []struct {
+		name           string
+		host           string
+		env            map[string]string
+		expectedToken  string
+		expectedSource string
+		expectError    bool
+	}{
+		{
+			name:           "GH_TOKEN for github.com",
+			host:           "github.com",
+			env:            map[string]string{"GH_TOKEN": "env-token", "GITHUB_TOKEN": "other"},
+			expectedToken:  "env-token",
+			expectedSource: "GH_TOKEN",
+		},
+		{
+			name:           "GITHUB_TOKEN for github.com",
+			host:           "github.com",
+			env:            map[string]string{"GITHUB_TOKEN": "env-token"},
+			expectedToken:  "env-token",
+			expectedSource: "GITHUB_TOKEN",
+		},
+		{
+			name:           "enterprise token for other hosts",
+			host:           "github.example.com",
+			env:            map[string]string{"GH_TOKEN": "ignored", "GH_ENTERPRISE_TOKEN": "enterprise"},
+			expectedToken:  "enterprise",
+			expectedSource: "GH_ENTERPRISE_TOKEN",
+		},
+		{
+			name:        "not logged in",
+			host:        "github.example.com",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"} {
+				t.Setenv(name, tt.env[name])
+			}
+			notLoggedIn(t)
+
+			token, source, err := GitHubToken(tt.host)
+			if tt.expectError {
+				if !errors.Is(err, ErrNoGitHubToken) {
+					t.Errorf("GitHubToken() error = %v, want ErrNoGitHubToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GitHubToken() unexpected error = %v", err)
+			}
+			if token != tt.expectedToken || source != tt.expectedSource {
+				t.Errorf("GitHubToken() = %q, %q; want %q, %q", token, source, tt.expectedToken, tt.expectedSource)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package batch
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of processing a single repository
+type Result struct {
+	Repository string
+	Refs       int
+	Output     string
+	Duration   time.Duration
+	Err        error
+}
+
+// Job processes one repository and reports its outcome
+type Job func(repository string) Result
+
+// Run processes repositories with at most concurrency jobs in flight at once.
+// Results are returned in the same order as repositories.
+func Run(repositories []string, concurrency int, job Job) []Result {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]Result, len(repositories))
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, repository := range repositories {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, repository string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			start := time.Now()
+			result := job(repository)
+			result.Repository = repository
+			result.Duration = time.Since(start)
+			results[i] = result
+		}(i, repository)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Summary aggregates the results of a batch run
+type Summary struct {
+	Repositories int
+	Failed       int
+	Refs         int
+	// Output is the main file written by the run, when the caller records it
+	Output string
+}
+
+// Summarize totals the results of a batch run
+func Summarize(results []Result) Summary {
+	s := Summary{Repositories: len(results)}
+	for _, result := range results {
+		if result.Err != nil {
+			s.Failed++
+			continue
+		}
+		s.Refs += result.Refs
+	}
+	return s
+}
+
+// ReadManifest reads repository paths from a file, one per line.
+// Blank lines and lines starting with # are ignored.
+func ReadManifest(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	var repositories []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		repositories = append(repositories, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", filename, err)
+	}
+
+	return repositories, nil
+}
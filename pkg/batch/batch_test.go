@@ -0,0 +1,91 @@
+package batch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun_PreservesOrder(t *testing.T) {
+	repositories := []string{"g/a", "g/b", "g/c", "g/d"}
+
+	results := Run(repositories, 2, func(repository string) Result {
+		return Result{Refs: len(repository)}
+	})
+
+	if len(results) != len(repositories) {
+		t.Fatalf("Expected %d results, got %d", len(repositories), len(results))
+	}
+
+	for i, result := range results {
+		if result.Repository != repositories[i] {
+			t.Errorf("Result %d repository = %q, want %q", i, result.Repository, repositories[i])
+		}
+	}
+}
+
+func TestRun_BoundsConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+
+	repositories := make([]string, 10)
+	for i := range repositories {
+		repositories[i] = "g/p"
+	}
+
+	Run(repositories, 3, func(repository string) Result {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return Result{}
+	})
+
+	if maxInFlight > 3 {
+		t.Errorf("Expected at most 3 concurrent jobs, got %d", maxInFlight)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	results := []Result{
+		{Refs: 3},
+		{Refs: 5},
+		{Refs: 7, Err: errors.New("boom")},
+	}
+
+	s := Summarize(results)
+	if s != (Summary{Repositories: 3, Failed: 1, Refs: 8}) {
+		t.Errorf("Summarize() = %+v", s)
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "repos.txt")
+
+	content := "# migration wave 1\ngroup/a\n\n  group/sub/b  \n# group/c\n"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	repositories, err := ReadManifest(testFile)
+	if err != nil {
+		t.Fatalf("ReadManifest failed: %v", err)
+	}
+
+	expected := []string{"group/a", "group/sub/b"}
+	if len(repositories) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, repositories)
+	}
+	for i := range expected {
+		if repositories[i] != expected[i] {
+			t.Errorf("Repository %d = %q, want %q", i, repositories[i], expected[i])
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// Package config reads and writes the configuration file holding default flag values, as
+// written by the init wizard
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is the configuration file read from the working directory when none is given
+const DefaultPath = ".gh-gl-create-refs.yaml"
+
+// Config holds default values of command flags. Each field is named after the flag it
+// sets, and only applies to commands that have that flag.
+type Config struct {
+	BaseURL      string `yaml:"base-url,omitempty"`
+	TokenFile    string `yaml:"token-file,omitempty"`
+	Repository   string `yaml:"repository,omitempty"`
+	BranchPrefix string `yaml:"branch-prefix,omitempty"`
+	Naming       string `yaml:"naming,omitempty"`
+	Output       string `yaml:"output,omitempty"`
+	Sudo         string `yaml:"sudo,omitempty"`
+
+	// How requests to the instance authenticate and how fast they are sent. RPS and Burst are
+	// kept as written and checked by the flags they set.
+	AuthType      string `yaml:"auth-type,omitempty"`
+	OAuthClientID string `yaml:"oauth-client-id,omitempty"`
+	CACert        string `yaml:"ca-cert,omitempty"`
+	RPS           string `yaml:"rps,omitempty"`
+	Burst         string `yaml:"burst,omitempty"`
+
+	// Profiles are named sets of flag values selected with --profile, such as one per GitLab
+	// instance, applied over the values above. They cannot hold notifications or profiles.
+	Profiles map[string]Config `yaml:"profiles,omitempty"`
+
+	// Notifications are the channels told when fetch-refs or create-refs finishes; they
+	// have no flags
+	Notifications []Notification `yaml:"notifications,omitempty"`
+	// Email is a notification of type email, kept as its own section for brevity
+	Email *Notification `yaml:"email,omitempty"`
+}
+
+// Notification types
+const (
+	NotifySlack   = "slack"
+	NotifyTeams   = "teams"
+	NotifyWebhook = "webhook"
+	NotifyEmail   = "email"
+)
+
+// Values of Notification.On
+const (
+	NotifyAlways    = "always"
+	NotifyOnFailure = "failure"
+)
+
+// DefaultPasswordEnv is the environment variable holding the SMTP password when
+// password-env is not set
+const DefaultPasswordEnv = "SMTP_PASSWORD"
+
+// Notification configures one channel notified when a run finishes. Secrets, such as
+// webhook URLs and the SMTP password, are best read from the environment variables named by
+// url-env and password-env rather than written to the file.
+type Notification struct {
+	Type string `yaml:"type,omitempty"`
+	// On is always, the default, or failure to only notify of failed runs
+	On string `yaml:"on,omitempty"`
+	// Message is a template of the message, and Template the file of one; Message wins
+	Message  string `yaml:"message,omitempty"`
+	Template string `yaml:"template,omitempty"`
+
+	// URL, or the environment variable URLEnv names, is where slack, teams and webhook
+	// notifications are posted
+	URL    string `yaml:"url,omitempty"`
+	URLEnv string `yaml:"url-env,omitempty"`
+
+	// The SMTP settings of email notifications
+	Host        string   `yaml:"host,omitempty"`
+	Port        int      `yaml:"port,omitempty"`
+	Username    string   `yaml:"username,omitempty"`
+	PasswordEnv string   `yaml:"password-env,omitempty"`
+	From        string   `yaml:"from,omitempty"`
+	To          []string `yaml:"to,omitempty"`
+	Subject     string   `yaml:"subject,omitempty"`
+}
+
+// AllNotifications returns the notifications of the file, the email section included
+func (c Config) AllNotifications() []Notification {
+	all := slices.Clone(c.Notifications)
+	if c.Email != nil {
+		email := *c.Email
+		email.Type = NotifyEmail
+		all = append(all, email)
+	}
+	return all
+}
+
+// validate checks the settings that can be checked before a run
+func (n Notification) validate() error {
+	switch n.Type {
+	case NotifySlack, NotifyTeams, NotifyWebhook:
+		if (n.URL == "") == (n.URLEnv == "") {
+			return fmt.Errorf("%s notification needs one of url and url-env", n.Type)
+		}
+	case NotifyEmail:
+		if n.Host == "" || n.From == "" || len(n.To) == 0 {
+			return errors.New("email notification needs host, from and to")
+		}
+	case "":
+		return fmt.Errorf("notification needs a type: %s, %s, %s or %s", NotifySlack, NotifyTeams, NotifyWebhook, NotifyEmail)
+	default:
+		return fmt.Errorf("invalid notification type %q: must be %s, %s, %s or %s", n.Type, NotifySlack, NotifyTeams, NotifyWebhook, NotifyEmail)
+	}
+	if n.On != "" && n.On != NotifyAlways && n.On != NotifyOnFailure {
+		return fmt.Errorf("invalid %s notification on %q: must be %s or %s", n.Type, n.On, NotifyAlways, NotifyOnFailure)
+	}
+	return nil
+}
+
+// Flags returns the configured values by flag name, leaving out empty ones
+func (c Config) Flags() map[string]string {
+	flags := make(map[string]string)
+	for name, value := range map[string]string{
+		"base-url":        c.BaseURL,
+		"token-file":      c.TokenFile,
+		"repository":      c.Repository,
+		"branch-prefix":   c.BranchPrefix,
+		"naming":          c.Naming,
+		"output":          c.Output,
+		"sudo":            c.Sudo,
+		"auth-type":       c.AuthType,
+		"oauth-client-id": c.OAuthClientID,
+		"ca-cert":         c.CACert,
+		"rps":             c.RPS,
+		"burst":           c.Burst,
+	} {
+		if value != "" {
+			flags[name] = value
+		}
+	}
+	return flags
+}
+
+// ProfileFlags returns the configured values by flag name with those of the profile name
+// applied over them, failing when the file has no such profile
+func (c Config) ProfileFlags(name string) (map[string]string, error) {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return nil, fmt.Errorf("profile %q not found: the config file has no profiles", name)
+		}
+		return nil, fmt.Errorf("profile %q not found: the config file's profiles are %s", name, strings.Join(c.ProfileNames(), ", "))
+	}
+
+	flags := c.Flags()
+	maps.Copy(flags, profile.Flags())
+	return flags, nil
+}
+
+// ProfileNames returns the names of the profiles in order
+func (c Config) ProfileNames() []string {
+	return slices.Sorted(maps.Keys(c.Profiles))
+}
+
+// Load reads a configuration file. A missing file is an empty configuration.
+func Load(filename string) (Config, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var c Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("failed to parse config file %s: %w", filename, err)
+	}
+	if c.Email != nil && c.Email.Type != "" && c.Email.Type != NotifyEmail {
+		return Config{}, fmt.Errorf("invalid config file %s: the email section cannot have type %s", filename, c.Email.Type)
+	}
+	for _, n := range c.AllNotifications() {
+		if err := n.validate(); err != nil {
+			return Config{}, fmt.Errorf("invalid config file %s: %w", filename, err)
+		}
+	}
+	for _, name := range c.ProfileNames() {
+		profile := c.Profiles[name]
+		if len(profile.Profiles) > 0 || len(profile.AllNotifications()) > 0 {
+			return Config{}, fmt.Errorf("invalid config file %s: profile %s cannot have profiles or notifications", filename, name)
+		}
+	}
+	return c, nil
+}
+
+// Save writes the configuration to a file, replacing it
+func Save(filename string, c Config) error {
+	var b bytes.Buffer
+	b.WriteString("# gh-gl-create-refs configuration: default values of command flags.\n")
+	b.WriteString("# Flags given on the command line take precedence.\n")
+
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	if err := os.WriteFile(filename, b.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad_MissingFile(t *testing.T) {
+	c, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(c, Config{}) {
+		t.Errorf("Load() = %+v, want empty config", c)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultPath)
+	want := Config{
+		BaseURL:      "https://gitlab.example.com",
+		TokenFile:    "token.txt",
+		Repository:   "group/project",
+		BranchPrefix: "gl-mr-",
+		Output:       "refs.csv",
+	}
+
+	if err := Save(path, want); err != nil {
+		t.Fatalf("Save() unexpected error = %v", err)
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() = %+v, want %+v", got, want)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("config file mode = %o, want 600", perm)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expected    Config
+		expectError bool
+	}{
+		{"empty file", "", Config{}, false},
+		{"comments only", "# nothing yet\n", Config{}, false},
+		{"some keys", "base-url: https://gitlab.example.com\nrepository: group/project\n", Config{BaseURL: "https://gitlab.example.com", Repository: "group/project"}, false},
+		{"unknown key", "token: secret\n", Config{}, true},
+		{"invalid yaml", "base-url: [\n", Config{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Load(path)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Load() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Load() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConfig_Flags(t *testing.T) {
+	flags := Config{BaseURL: "https://gitlab.example.com", BranchPrefix: "gl-mr-"}.Flags()
+	if len(flags) != 2 || flags["base-url"] != "https://gitlab.example.com" || flags["branch-prefix"] != "gl-mr-" {
+		t.Errorf("Flags() = %v, want base-url and branch-prefix only", flags)
+	}
+}
+
+func TestLoad_Notifications(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expected    []Notification
+		expectError bool
+	}{
+		{
+			name:     "chat and webhook",
+			content:  "notifications:\n  - type: slack\n    url-env: SLACK_WEBHOOK_URL\n    on: failure\n  - type: webhook\n    url: https://hooks.example.com/runs\n    template: webhook.tmpl\n",
+			expected: []Notification{{Type: NotifySlack, URLEnv: "SLACK_WEBHOOK_URL", On: NotifyOnFailure}, {Type: NotifyWebhook, URL: "https://hooks.example.com/runs", Template: "webhook.tmpl"}},
+		},
+		{
+			name:     "email section",
+			content:  "email:\n  host: smtp.example.com\n  port: 587\n  from: bot@example.com\n  to: [team@example.com]\n",
+			expected: []Notification{{Type: NotifyEmail, Host: "smtp.example.com", Port: 587, From: "bot@example.com", To: []string{"team@example.com"}}},
+		},
+		{name: "email without recipients", content: "email:\n  host: smtp.example.com\n  from: bot@example.com\n", expectError: true},
+		{name: "email section of another type", content: "email:\n  type: slack\n  url: https://hooks.slack.com/x\n", expectError: true},
+		{name: "missing type", content: "notifications:\n  - url: https://hooks.example.com\n", expectError: true},
+		{name: "unknown type", content: "notifications:\n  - type: pager\n    url: https://hooks.example.com\n", expectError: true},
+		{name: "no url", content: "notifications:\n  - type: teams\n", expectError: true},
+		{name: "url and url-env", content: "notifications:\n  - type: teams\n    url: https://example.com\n    url-env: TEAMS_URL\n", expectError: true},
+		{name: "invalid on", content: "notifications:\n  - type: slack\n    url-env: SLACK_WEBHOOK_URL\n    on: sometimes\n", expectError: true},
+		{name: "password in file", content: "email:\n  host: smtp.example.com\n  password: hunter2\n", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := Load(path)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Load() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() unexpected error = %v", err)
+			}
+			if got := c.AllNotifications(); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("AllNotifications() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoad_Profiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expected    map[string]Config
+		expectError bool
+	}{
+		{
+			name:    "profiles",
+			content: "base-url: https://gitlab.com\nprofiles:\n  prod:\n    base-url: https://gitlab.example.com\n    token-file: prod-token.txt\n    rps: 2.5\n    burst: 5\n  staging:\n    base-url: https://gitlab-staging.example.com\n    auth-type: oauth\n",
+			expected: map[string]Config{
+				"prod":    {BaseURL: "https://gitlab.example.com", TokenFile: "prod-token.txt", RPS: "2.5", Burst: "5"},
+				"staging": {BaseURL: "https://gitlab-staging.example.com", AuthType: "oauth"},
+			},
+		},
+		{name: "nested profiles", content: "profiles:\n  prod:\n    profiles:\n      inner:\n        sudo: bot\n", expectError: true},
+		{name: "profile notifications", content: "profiles:\n  prod:\n    notifications:\n      - type: slack\n        url-env: SLACK_WEBHOOK_URL\n", expectError: true},
+		{name: "unknown key in profile", content: "profiles:\n  prod:\n    token: secret\n", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := Load(path)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Load() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(c.Profiles, tt.expected) {
+				t.Errorf("Load() profiles = %+v, want %+v", c.Profiles, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConfig_ProfileFlags(t *testing.T) {
+	c := Config{
+		BaseURL:      "https://gitlab.com",
+		BranchPrefix: "gl-mr-",
+		Profiles: map[string]Config{
+			"prod":    {BaseURL: "https://gitlab.example.com", RPS: "2"},
+			"staging": {BaseURL: "https://gitlab-staging.example.com"},
+		},
+	}
+
+	flags, err := c.ProfileFlags("prod")
+	if err != nil {
+		t.Fatalf("ProfileFlags() unexpected error = %v", err)
+	}
+	want := map[string]string{"base-url": "https://gitlab.example.com", "branch-prefix": "gl-mr-", "rps": "2"}
+	if !reflect.DeepEqual(flags, want) {
+		t.Errorf("ProfileFlags() = %v, want %v", flags, want)
+	}
+
+	_, err = c.ProfileFlags("dev")
+	if err == nil || err.Error() != `profile "dev" not found: the config file's profiles are prod, staging` {
+		t.Errorf("ProfileFlags() error = %v, want the profiles listed", err)
+	}
+}
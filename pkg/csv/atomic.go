@@ -0,0 +1,137 @@
+package csv
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/encrypt"
+)
+
+// outputFileMode is the permission of completed output files
+const outputFileMode = 0644
+
+// AtomicFile is written to a temporary file next to its destination and renamed
+// into place by Commit, so readers never see a partially written file. Commit also
+// writes the checksum file of the destination. Outputs other than CSV files use it too,
+// so that every file the tool writes comes with a checksum and is encrypted when
+// SetEncrypter is in effect.
+type AtomicFile struct {
+	file     *os.File
+	hash     hash.Hash
+	filename string
+	done     bool
+	// stream encrypts the content on its way to file; nil writes it in plaintext
+	stream *encrypt.Stream
+}
+
+// encrypter, when set, encrypts every file created by CreateAtomic
+var encrypter *encrypt.Encrypter
+
+// SetEncrypter has every output file created from now on encrypted by e, or written in
+// plaintext again when e is nil. It applies process-wide so that no output is left out;
+// checksum files, which only hold the digest of the encrypted file, stay in plaintext.
+func SetEncrypter(e *encrypt.Encrypter) {
+	encrypter = e
+}
+
+// CreateAtomic starts writing filename; the caller must Commit or Abort it
+func CreateAtomic(filename string) (*AtomicFile, error) {
+	file, err := createTemp(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &AtomicFile{file: file, hash: sha256.New(), filename: filename}
+	if encrypter != nil {
+		// The checksum is of the encrypted file, the one on disk
+		f.stream, err = encrypter.Encrypt(io.MultiWriter(file, f.hash))
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// createTemp creates the temporary file that will be renamed to filename
+func createTemp(filename string) (*os.File, error) {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+
+	file, err := os.CreateTemp(dir, "."+base+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file %s: %w", filename, err)
+	}
+	return file, nil
+}
+
+// Write writes to the temporary file
+func (f *AtomicFile) Write(p []byte) (int, error) {
+	if f.stream != nil {
+		return f.stream.Write(p)
+	}
+	n, err := f.file.Write(p)
+	f.hash.Write(p[:n])
+	return n, err
+}
+
+// Commit closes the temporary file, moves it to its destination and writes its checksum file
+func (f *AtomicFile) Commit() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+
+	if f.stream != nil {
+		if err := f.stream.Close(); err != nil {
+			f.file.Close()
+			os.Remove(f.file.Name())
+			return err
+		}
+	}
+
+	if err := publish(f.file, f.filename); err != nil {
+		return err
+	}
+
+	return writeChecksumFile(f.filename, f.hash.Sum(nil))
+}
+
+// Abort discards the temporary file, leaving any existing destination untouched.
+// It does nothing after Commit.
+func (f *AtomicFile) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+	if f.stream != nil {
+		f.stream.Abort()
+	}
+	f.file.Close()
+	os.Remove(f.file.Name())
+}
+
+// publish closes a temporary file and renames it to filename, removing it on failure
+func publish(file *os.File, filename string) error {
+	if err := file.Chmod(outputFileMode); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return fmt.Errorf("failed to set permissions of %s: %w", filename, err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	if err := os.Rename(file.Name(), filename); err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
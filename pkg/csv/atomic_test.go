@@ -0,0 +1,148 @@
+package csv
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/encrypt"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+func TestStreamWriter_Atomic(t *testing.T) {
+	tests := []struct {
+		name     string
+		commit   bool
+		expected string
+	}{
+		{
+			name:     "close publishes the file",
+			commit:   true,
+			expected: "# gh-gl-create-refs refs v1\n2,new\n",
+		},
+		{
+			name:     "abort keeps the previous file",
+			commit:   false,
+			expected: "1,old\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			filename := filepath.Join(dir, "refs.csv")
+			if err := os.WriteFile(filename, []byte("1,old\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			sw, err := NewStreamWriter(filename)
+			if err != nil {
+				t.Fatalf("NewStreamWriter failed: %v", err)
+			}
+			defer sw.Abort()
+
+			if err := sw.WriteRef(gitlab.MergeRequestRef{IID: 2, HeadSHA: "new"}); err != nil {
+				t.Fatalf("WriteRef failed: %v", err)
+			}
+
+			// Nothing is visible until the writer is closed
+			content, err := os.ReadFile(filename)
+			if err != nil || string(content) != "1,old\n" {
+				t.Errorf("File content while writing = %q, %v", content, err)
+			}
+
+			if tt.commit {
+				if err := sw.Close(); err != nil {
+					t.Fatalf("Close failed: %v", err)
+				}
+			} else {
+				sw.Abort()
+			}
+
+			content, err = os.ReadFile(filename)
+			if err != nil || string(content) != tt.expected {
+				t.Errorf("File content = %q, %v; want %q", content, err, tt.expected)
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range entries {
+				if strings.HasSuffix(entry.Name(), ".tmp") {
+					t.Errorf("Temporary file %s should not remain", entry.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestStreamWriter_AbortAfterClose(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "refs.csv")
+
+	sw, err := NewStreamWriter(filename)
+	if err != nil {
+		t.Fatalf("NewStreamWriter failed: %v", err)
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	sw.Abort()
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatalf("Closed file should remain after Abort: %v", err)
+	}
+	if info.Mode().Perm() != outputFileMode {
+		t.Errorf("File mode = %v, want %v", info.Mode().Perm(), os.FileMode(outputFileMode))
+	}
+}
+
+func TestCreateAtomic_Encrypted(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is not installed")
+	}
+	home, err := os.MkdirTemp("", "gpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+		os.RemoveAll(home)
+	})
+	t.Setenv("GNUPGHOME", home)
+	if out, err := exec.Command("gpg", "--batch", "--quiet", "--passphrase", "", "--quick-gen-key", "test@example.com", "default", "default", "never").CombinedOutput(); err != nil {
+		t.Fatalf("Failed to generate a key: %v: %s", err, out)
+	}
+
+	e, err := encrypt.New([]string{"test@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetEncrypter(e)
+	defer SetEncrypter(nil)
+
+	filename := filepath.Join(t.TempDir(), "refs.csv")
+	refs := []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaaaaaa"}}
+	if err := WriteRefsToFile(refs, filename); err != nil {
+		t.Fatalf("WriteRefsToFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "aaaaaaa") {
+		t.Fatal("Expected the file to be encrypted")
+	}
+	if err := VerifyChecksum(filename); err != nil {
+		t.Errorf("Expected the checksum of the encrypted file, got %v", err)
+	}
+
+	decrypted, err := exec.Command("gpg", "--batch", "--quiet", "--decrypt", filename).Output()
+	if err != nil || string(decrypted) != "# gh-gl-create-refs refs v1\n1,aaaaaaa\n" {
+		t.Errorf("Decrypted = %q, %v", decrypted, err)
+	}
+}
@@ -0,0 +1,77 @@
+package csv
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrChecksumMismatch is returned by VerifyChecksum when a file does not match its checksum file
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ChecksumFilename returns the file holding the SHA-256 checksum of filename
+func ChecksumFilename(filename string) string {
+	return filename + ".sha256"
+}
+
+// writeChecksumFile records sum as the checksum of filename, in the format of sha256sum
+// so it can also be checked with `sha256sum -c`
+func writeChecksumFile(filename string, sum []byte) error {
+	checksumFilename := ChecksumFilename(filename)
+
+	file, err := createTemp(checksumFilename)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(file, "%s  %s\n", hex.EncodeToString(sum), filepath.Base(filename)); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return fmt.Errorf("failed to write %s: %w", checksumFilename, err)
+	}
+
+	return publish(file, checksumFilename)
+}
+
+// VerifyChecksum checks filename against the checksum recorded in its checksum file,
+// returning ErrChecksumMismatch if the file was truncated or changed since it was written
+func VerifyChecksum(filename string) error {
+	checksumFilename := ChecksumFilename(filename)
+
+	data, err := os.ReadFile(checksumFilename)
+	if err != nil {
+		return fmt.Errorf("failed to read checksum file %s: %w", checksumFilename, err)
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return fmt.Errorf("checksum file %s is empty", checksumFilename)
+	}
+	expected, err := hex.DecodeString(fields[0])
+	if err != nil || len(expected) != sha256.Size {
+		return fmt.Errorf("checksum file %s does not contain a SHA-256 checksum", checksumFilename)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return fmt.Errorf("failed to read file %s: %w", filename, err)
+	}
+
+	if !bytes.Equal(hash.Sum(nil), expected) {
+		return fmt.Errorf("%s does not match %s: %w", filename, checksumFilename, ErrChecksumMismatch)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package csv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+func TestWriteRefsToFile_Checksum(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "refs.csv")
+	refs := []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "abc123"}}
+
+	if err := WriteRefsToFile(refs, filename); err != nil {
+		t.Fatalf("WriteRefsToFile failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+
+	checksum, err := os.ReadFile(ChecksumFilename(filename))
+	if err != nil {
+		t.Fatalf("Checksum file should be written: %v", err)
+	}
+	expected := hex.EncodeToString(sum[:]) + "  refs.csv\n"
+	if string(checksum) != expected {
+		t.Errorf("Checksum file = %q, want %q", checksum, expected)
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(t *testing.T, filename string)
+		expectError bool
+		mismatch    bool
+	}{
+		{
+			name:   "unchanged file",
+			modify: func(t *testing.T, filename string) {},
+		},
+		{
+			name: "truncated file",
+			modify: func(t *testing.T, filename string) {
+				if err := os.Truncate(filename, 10); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expectError: true,
+			mismatch:    true,
+		},
+		{
+			name: "missing checksum file",
+			modify: func(t *testing.T, filename string) {
+				if err := os.Remove(ChecksumFilename(filename)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expectError: true,
+		},
+		{
+			name: "malformed checksum file",
+			modify: func(t *testing.T, filename string) {
+				if err := os.WriteFile(ChecksumFilename(filename), []byte("not-a-checksum  refs.csv\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "refs.csv")
+			refs := []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "abc123"}, {IID: 2, HeadSHA: "def456"}}
+			if err := WriteRefsToFile(refs, filename); err != nil {
+				t.Fatalf("WriteRefsToFile failed: %v", err)
+			}
+
+			tt.modify(t, filename)
+
+			err := VerifyChecksum(filename)
+			if tt.expectError != (err != nil) {
+				t.Fatalf("VerifyChecksum() error = %v, expectError %v", err, tt.expectError)
+			}
+			if errors.Is(err, ErrChecksumMismatch) != tt.mismatch {
+				t.Errorf("VerifyChecksum() error = %v, want ErrChecksumMismatch: %v", err, tt.mismatch)
+			}
+		})
+	}
+}
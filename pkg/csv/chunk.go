@@ -0,0 +1,106 @@
+package csv
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// ChunkFilename returns the name of the nth (1-based) chunk of filename,
+// e.g. group-project-002.csv for group-project.csv
+func ChunkFilename(filename string, n int) string {
+	ext := filepath.Ext(filename)
+	return fmt.Sprintf("%s-%03d%s", strings.TrimSuffix(filename, ext), n, ext)
+}
+
+// ChunkedWriter writes merge request references to numbered files of at most size
+// references each (see ChunkFilename). Each chunk is moved into place as soon as it is
+// full, so only the chunk being written is held back, like a StreamWriter's file.
+type ChunkedWriter struct {
+	filename string
+	size     int
+	options  []WriteOption
+	current  *StreamWriter
+	chunks   int
+	count    int
+}
+
+// NewChunkedWriter creates a writer that splits the references for filename into chunks of size
+func NewChunkedWriter(filename string, size int, options ...WriteOption) (*ChunkedWriter, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive, got %d", size)
+	}
+
+	return &ChunkedWriter{filename: filename, size: size, options: options}, nil
+}
+
+// WriteRef writes a single merge request reference, starting a new chunk when the current
+// one is full and moving a chunk into place once it is
+func (cw *ChunkedWriter) WriteRef(ref gitlab.MergeRequestRef) error {
+	if cw.current == nil {
+		if err := cw.startChunk(); err != nil {
+			return err
+		}
+	}
+
+	if err := cw.current.WriteRef(ref); err != nil {
+		return err
+	}
+	cw.count++
+
+	if cw.count%cw.size == 0 {
+		return cw.commitChunk()
+	}
+	return nil
+}
+
+// startChunk opens the next chunk file
+func (cw *ChunkedWriter) startChunk() error {
+	sw, err := NewStreamWriter(ChunkFilename(cw.filename, cw.chunks+1), cw.options...)
+	if err != nil {
+		return err
+	}
+	cw.current = sw
+	cw.chunks++
+	return nil
+}
+
+// commitChunk moves the chunk being written into place
+func (cw *ChunkedWriter) commitChunk() error {
+	sw := cw.current
+	cw.current = nil
+	return sw.Close()
+}
+
+// Filenames returns the chunk files written so far
+func (cw *ChunkedWriter) Filenames() []string {
+	filenames := make([]string, cw.chunks)
+	for i := range filenames {
+		filenames[i] = ChunkFilename(cw.filename, i+1)
+	}
+	return filenames
+}
+
+// Close moves the last chunk into place; the full ones already are. Without any references
+// a single empty chunk is written.
+func (cw *ChunkedWriter) Close() error {
+	if cw.chunks == 0 {
+		if err := cw.startChunk(); err != nil {
+			return err
+		}
+	}
+	if cw.current == nil {
+		return nil
+	}
+	return cw.commitChunk()
+}
+
+// Abort discards the chunk being written. Full chunks, already moved into place, are kept.
+// It does nothing after Close.
+func (cw *ChunkedWriter) Abort() {
+	if cw.current != nil {
+		cw.current.Abort()
+	}
+}
@@ -0,0 +1,116 @@
+package csv
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+func TestChunkFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		n        int
+		expected string
+	}{
+		{filename: "group-project.csv", n: 1, expected: "group-project-001.csv"},
+		{filename: "out/refs.tsv", n: 12, expected: "out/refs-012.tsv"},
+		{filename: "refs", n: 1000, expected: "refs-1000"},
+	}
+
+	for _, tt := range tests {
+		if got := ChunkFilename(tt.filename, tt.n); got != tt.expected {
+			t.Errorf("ChunkFilename(%q, %d) = %q, want %q", tt.filename, tt.n, got, tt.expected)
+		}
+	}
+}
+
+func TestChunkedWriter(t *testing.T) {
+	tests := []struct {
+		name          string
+		refs          int
+		size          int
+		expectedSizes []int
+	}{
+		{name: "partial last chunk", refs: 5, size: 2, expectedSizes: []int{2, 2, 1}},
+		{name: "exact multiple", refs: 4, size: 2, expectedSizes: []int{2, 2}},
+		{name: "single chunk", refs: 3, size: 10, expectedSizes: []int{3}},
+		{name: "no references", refs: 0, size: 10, expectedSizes: []int{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "refs.csv")
+
+			cw, err := NewChunkedWriter(filename, tt.size)
+			if err != nil {
+				t.Fatalf("NewChunkedWriter failed: %v", err)
+			}
+			defer cw.Abort()
+
+			for i := 1; i <= tt.refs; i++ {
+				if err := cw.WriteRef(gitlab.MergeRequestRef{IID: i, HeadSHA: "abc1234"}); err != nil {
+					t.Fatalf("WriteRef failed: %v", err)
+				}
+			}
+			if err := cw.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			var sizes []int
+			for i, chunk := range cw.Filenames() {
+				if chunk != ChunkFilename(filename, i+1) {
+					t.Errorf("Chunk %d = %s", i+1, chunk)
+				}
+				refs, err := ReadRefsFromFile(chunk)
+				if err != nil {
+					t.Fatalf("ReadRefsFromFile(%s) failed: %v", chunk, err)
+				}
+				sizes = append(sizes, len(refs))
+			}
+			if !reflect.DeepEqual(sizes, tt.expectedSizes) {
+				t.Errorf("Chunk sizes = %v, want %v", sizes, tt.expectedSizes)
+			}
+
+			if _, err := os.Stat(filename); !os.IsNotExist(err) {
+				t.Error("The unchunked file should not be written")
+			}
+		})
+	}
+}
+
+func TestChunkedWriter_Abort(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "refs.csv")
+	cw, err := NewChunkedWriter(filename, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		if err := cw.WriteRef(gitlab.MergeRequestRef{IID: i, HeadSHA: "abc1234"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The full first chunk is in place before Close
+	if refs, err := ReadRefsFromFile(ChunkFilename(filename, 1)); err != nil || len(refs) != 2 {
+		t.Fatalf("First chunk = %d references, %v; want 2", len(refs), err)
+	}
+
+	cw.Abort()
+
+	if _, err := os.Stat(ChunkFilename(filename, 1)); err != nil {
+		t.Errorf("The full first chunk should stay after Abort: %v", err)
+	}
+	if _, err := os.Stat(ChunkFilename(filename, 2)); !os.IsNotExist(err) {
+		t.Error("The partial second chunk should be discarded by Abort")
+	}
+}
+
+func TestNewChunkedWriter_InvalidSize(t *testing.T) {
+	if _, err := NewChunkedWriter("refs.csv", 0); err == nil {
+		t.Error("NewChunkedWriter() expected an error for a zero chunk size")
+	}
+}
@@ -0,0 +1,152 @@
+package csv
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// ErrNoProject is returned when reading a single-repository file without naming its project
+var ErrNoProject = errors.New("file does not name the project of its merge requests")
+
+// ProjectRef is a merge request reference together with the path of its project, as stored
+// in a combined CSV file: project path, IID and head SHA
+type ProjectRef struct {
+	Project string
+	gitlab.MergeRequestRef
+}
+
+// NewCombinedWriter starts a combined CSV of several projects on w; write to it with WriteProjectRef
+func NewCombinedWriter(w io.Writer, options ...WriteOption) (*Writer, error) {
+	return newKindWriter(w, KindCombined, options)
+}
+
+// NewCombinedStreamWriter creates a stream writer for a combined file; write to it with WriteProjectRef
+func NewCombinedStreamWriter(filename string, options ...WriteOption) (*StreamWriter, error) {
+	return newStreamWriter(filename, KindCombined, options)
+}
+
+// WriteProjectRef writes a single merge request reference and its project
+func (w *Writer) WriteProjectRef(ref ProjectRef) error {
+	record := []string{
+		ref.Project,
+		strconv.Itoa(ref.IID),
+		w.config.sha(ref.HeadSHA),
+	}
+
+	if err := w.writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+
+	return nil
+}
+
+// WriteProjectRef writes a single merge request reference and its project to the CSV file
+func (sw *StreamWriter) WriteProjectRef(ref ProjectRef) error {
+	if err := sw.Writer.WriteProjectRef(ref); err != nil {
+		return err
+	}
+	return sw.Flush()
+}
+
+// ProjectRefsFromFile iterates over the merge request references of a combined or
+// single-repository CSV file one row at a time; see ProjectRefsFrom
+func ProjectRefsFromFile(filename, project string) iter.Seq2[ProjectRef, error] {
+	return fromFile(filename, func(r io.Reader) iter.Seq2[ProjectRef, error] {
+		return ProjectRefsFrom(r, project)
+	})
+}
+
+// ProjectRefsFrom iterates over the merge request references read from r one row at a
+// time, together with their project. Combined files name the project of every row; the
+// rows of a single-repository file are attributed to project, and ErrNoProject is
+// returned if it is empty. A merge request listed twice for the same project is an error
+// wrapping ErrDuplicateIID; SHAs are checked as by RefsFrom. Iteration stops after the
+// first error.
+func ProjectRefsFrom(r io.Reader, project string) iter.Seq2[ProjectRef, error] {
+	return func(yield func(ProjectRef, error) bool) {
+		buffered := bufio.NewReader(r)
+		skipBOM(buffered)
+		schema, err := ReadSchema(buffered, KindRefs)
+		if err != nil {
+			yield(ProjectRef{}, fmt.Errorf("failed to read CSV: %w", err))
+			return
+		}
+
+		if schema.Kind != KindCombined {
+			if project == "" {
+				yield(ProjectRef{}, ErrNoProject)
+				return
+			}
+			for ref, err := range RefsFrom(buffered) {
+				if !yield(ProjectRef{Project: project, MergeRequestRef: ref}, err) || err != nil {
+					return
+				}
+			}
+			return
+		}
+
+		if err := schema.check(KindCombined); err != nil {
+			yield(ProjectRef{}, err)
+			return
+		}
+
+		reader := csv.NewReader(buffered)
+		reader.Comma = detectDelimiter(buffered)
+		reader.Comment = '#'
+		reader.FieldsPerRecord = -1 // Column counts are checked per row below
+		reader.ReuseRecord = true
+		seen := make(iidLines)
+
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(ProjectRef{}, fmt.Errorf("failed to read CSV: %w", err))
+				return
+			}
+
+			line, _ := reader.FieldPos(0)
+			if len(record) != 3 {
+				yield(ProjectRef{}, fmt.Errorf("invalid CSV format at line %d: expected 3 columns, got %d", line, len(record)))
+				return
+			}
+			if record[0] == "" {
+				yield(ProjectRef{}, fmt.Errorf("missing project path at line %d", line))
+				return
+			}
+
+			iid, err := strconv.Atoi(record[1])
+			if err != nil {
+				yield(ProjectRef{}, fmt.Errorf("invalid merge request IID at line %d: %w", line, err))
+				return
+			}
+			if err := seen.check(record[0], iid, line); err != nil {
+				yield(ProjectRef{}, err)
+				return
+			}
+
+			sha := unwrapSHA(record[2])
+			if err := checkSHA(sha, line); err != nil {
+				yield(ProjectRef{}, err)
+				return
+			}
+
+			ref := ProjectRef{
+				Project:         record[0],
+				MergeRequestRef: gitlab.MergeRequestRef{IID: iid, HeadSHA: sha},
+			}
+			if !yield(ref, nil) {
+				return
+			}
+		}
+	}
+}
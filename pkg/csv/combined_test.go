@@ -0,0 +1,192 @@
+package csv
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+func TestCombinedStreamWriter(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "combined.csv")
+
+	sw, err := NewCombinedStreamWriter(filename)
+	if err != nil {
+		t.Fatalf("NewCombinedStreamWriter failed: %v", err)
+	}
+	defer sw.Abort()
+
+	refs := []ProjectRef{
+		{Project: "acme/a", MergeRequestRef: gitlab.MergeRequestRef{IID: 1, HeadSHA: "aaaaaaa"}},
+		{Project: "acme/b", MergeRequestRef: gitlab.MergeRequestRef{IID: 1, HeadSHA: "bbbbbbb"}},
+	}
+	for _, ref := range refs {
+		if err := sw.WriteProjectRef(ref); err != nil {
+			t.Fatalf("WriteProjectRef failed: %v", err)
+		}
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "# gh-gl-create-refs combined v1\nacme/a,1,aaaaaaa\nacme/b,1,bbbbbbb\n"
+	if string(data) != expected {
+		t.Errorf("Combined file = %q, want %q", data, expected)
+	}
+
+	var read []ProjectRef
+	for ref, err := range ProjectRefsFromFile(filename, "") {
+		if err != nil {
+			t.Fatalf("ProjectRefsFromFile failed: %v", err)
+		}
+		read = append(read, ref)
+	}
+	if len(read) != len(refs) {
+		t.Fatalf("Read %d references, want %d", len(read), len(refs))
+	}
+	for i := range refs {
+		if read[i].Project != refs[i].Project || read[i].IID != refs[i].IID || read[i].HeadSHA != refs[i].HeadSHA {
+			t.Errorf("Reference %d = %+v, want %+v", i, read[i], refs[i])
+		}
+	}
+}
+
+func TestNewCombinedWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewCombinedWriter(&buf, WithExcelCompatible())
+	if err != nil {
+		t.Fatalf("NewCombinedWriter failed: %v", err)
+	}
+
+	ref := ProjectRef{Project: "acme/a", MergeRequestRef: gitlab.MergeRequestRef{IID: 2, HeadSHA: "0123"}}
+	if err := writer.WriteProjectRef(ref); err != nil {
+		t.Fatalf("WriteProjectRef failed: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	expected := utf8BOM + "# gh-gl-create-refs combined v1\r\nacme/a,2,\"=\"\"0123\"\"\"\r\n"
+	if buf.String() != expected {
+		t.Errorf("NewCombinedWriter output = %q, want %q", buf.String(), expected)
+	}
+}
+
+func TestProjectRefsFrom(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		project     string
+		expected    []ProjectRef
+		expectError bool
+	}{
+		{
+			name:    "combined file",
+			content: "# gh-gl-create-refs combined v1\nacme/a,1,aaaaaaa\nacme/b,2,bbbbbbb\n",
+			expected: []ProjectRef{
+				{Project: "acme/a", MergeRequestRef: gitlab.MergeRequestRef{IID: 1, HeadSHA: "aaaaaaa"}},
+				{Project: "acme/b", MergeRequestRef: gitlab.MergeRequestRef{IID: 2, HeadSHA: "bbbbbbb"}},
+			},
+		},
+		{
+			name:    "combined file ignores project",
+			content: "# gh-gl-create-refs combined v1\nacme/a,1,aaaaaaa\n",
+			project: "other/project",
+			expected: []ProjectRef{
+				{Project: "acme/a", MergeRequestRef: gitlab.MergeRequestRef{IID: 1, HeadSHA: "aaaaaaa"}},
+			},
+		},
+		{
+			name:    "single-repository file",
+			content: "# gh-gl-create-refs refs v1\n1,aaaaaaa\n",
+			project: "acme/a",
+			expected: []ProjectRef{
+				{Project: "acme/a", MergeRequestRef: gitlab.MergeRequestRef{IID: 1, HeadSHA: "aaaaaaa"}},
+			},
+		},
+		{
+			name:        "combined file with missing column",
+			content:     "# gh-gl-create-refs combined v1\n1,aaaaaaa\n",
+			expectError: true,
+		},
+		{
+			name:        "combined file with missing project",
+			content:     "# gh-gl-create-refs combined v1\n,1,aaaaaaa\n",
+			expectError: true,
+		},
+		{
+			name:    "same IID in different projects",
+			content: "# gh-gl-create-refs combined v1\nacme/a,1,aaaaaaa\nacme/b,1,bbbbbbb\n",
+			expected: []ProjectRef{
+				{Project: "acme/a", MergeRequestRef: gitlab.MergeRequestRef{IID: 1, HeadSHA: "aaaaaaa"}},
+				{Project: "acme/b", MergeRequestRef: gitlab.MergeRequestRef{IID: 1, HeadSHA: "bbbbbbb"}},
+			},
+		},
+		{
+			name:        "duplicate IID in one project",
+			content:     "# gh-gl-create-refs combined v1\nacme/a,1,aaaaaaa\nacme/a,1,bbbbbbb\n",
+			expectError: true,
+		},
+		{
+			name:        "combined file with invalid SHA",
+			content:     "# gh-gl-create-refs combined v1\nacme/a,1,not-a-sha\n",
+			expectError: true,
+		},
+		{
+			name:        "newer combined schema",
+			content:     "# gh-gl-create-refs combined v2\nacme/a,1,aaaaaaa\n",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var refs []ProjectRef
+			var err error
+			for ref, readErr := range ProjectRefsFrom(strings.NewReader(tt.content), tt.project) {
+				if readErr != nil {
+					err = readErr
+					break
+				}
+				refs = append(refs, ref)
+			}
+
+			if tt.expectError {
+				if err == nil {
+					t.Error("ProjectRefsFrom() expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProjectRefsFrom() unexpected error = %v", err)
+			}
+			if len(refs) != len(tt.expected) {
+				t.Fatalf("ProjectRefsFrom() returned %d references, want %d", len(refs), len(tt.expected))
+			}
+			for i := range refs {
+				if refs[i].Project != tt.expected[i].Project || refs[i].IID != tt.expected[i].IID || refs[i].HeadSHA != tt.expected[i].HeadSHA {
+					t.Errorf("Reference %d = %+v, want %+v", i, refs[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProjectRefsFrom_NoProject(t *testing.T) {
+	for _, err := range ProjectRefsFrom(strings.NewReader("1,aaaaaaa\n"), "") {
+		if !errors.Is(err, ErrNoProject) {
+			t.Errorf("ProjectRefsFrom() error = %v, want ErrNoProject", err)
+		}
+		return
+	}
+	t.Error("ProjectRefsFrom() expected an error")
+}
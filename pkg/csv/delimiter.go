@@ -0,0 +1,64 @@
+package csv
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultDelimiter separates fields unless WithDelimiter is given
+const DefaultDelimiter = ','
+
+// delimiters maps the accepted --delimiter values to the field separator they select
+var delimiters = map[string]rune{
+	",":         ',',
+	"comma":     ',',
+	"\t":        '\t',
+	`\t`:        '\t',
+	"tab":       '\t',
+	";":         ';',
+	"semicolon": ';',
+	"|":         '|',
+	"pipe":      '|',
+}
+
+// ParseDelimiter converts a delimiter name ("comma", "tab", "semicolon", "pipe") or the
+// delimiter character itself into the field separator
+func ParseDelimiter(s string) (rune, error) {
+	if d, ok := delimiters[strings.ToLower(s)]; ok {
+		return d, nil
+	}
+
+	var names []string
+	for name := range delimiters {
+		if len(name) > 1 && name != `\t` {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return 0, fmt.Errorf("unsupported delimiter %q: use one of %s", s, strings.Join(names, ", "))
+}
+
+// sniffLimit bounds how far ahead detectDelimiter looks for the first record
+const sniffLimit = 4096
+
+// detectDelimiter guesses the field separator of a generated file from its first record
+// without consuming input. IIDs and SHAs never contain a separator, so the first one found
+// wins; files with a single column fall back to DefaultDelimiter.
+func detectDelimiter(r *bufio.Reader) rune {
+	data, _ := r.Peek(sniffLimit)
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.IndexAny(line, "\t;|,"); i >= 0 {
+			return rune(line[i])
+		}
+		break
+	}
+
+	return DefaultDelimiter
+}
@@ -0,0 +1,105 @@
+package csv
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+func TestParseDelimiter(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    rune
+		expectError bool
+	}{
+		{input: ",", expected: ','},
+		{input: "comma", expected: ','},
+		{input: "tab", expected: '\t'},
+		{input: "TAB", expected: '\t'},
+		{input: `\t`, expected: '\t'},
+		{input: "\t", expected: '\t'},
+		{input: ";", expected: ';'},
+		{input: "semicolon", expected: ';'},
+		{input: "pipe", expected: '|'},
+		{input: "", expectError: true},
+		{input: "\"", expectError: true},
+		{input: "colon", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			delimiter, err := ParseDelimiter(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("ParseDelimiter(%q) expected an error, got %q", tt.input, delimiter)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDelimiter(%q) unexpected error = %v", tt.input, err)
+			}
+			if delimiter != tt.expected {
+				t.Errorf("ParseDelimiter(%q) = %q, want %q", tt.input, delimiter, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWriteRefsToFile_Delimiter(t *testing.T) {
+	refs := []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: "abc1234"},
+		{IID: 16, HeadSHA: "def4567"},
+	}
+
+	tests := []struct {
+		name      string
+		delimiter rune
+		expected  string
+	}{
+		{name: "tab", delimiter: '\t', expected: "1\tabc1234\n16\tdef4567\n"},
+		{name: "semicolon", delimiter: ';', expected: "1;abc1234\n16;def4567\n"},
+		{name: "pipe", delimiter: '|', expected: "1|abc1234\n16|def4567\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "refs.csv")
+			if err := WriteRefsToFile(refs, filename, WithDelimiter(tt.delimiter)); err != nil {
+				t.Fatalf("WriteRefsToFile failed: %v", err)
+			}
+
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := strings.TrimPrefix(string(content), "# gh-gl-create-refs refs v1\n")
+			if body != tt.expected {
+				t.Errorf("File content = %q, want %q", body, tt.expected)
+			}
+
+			// Readers detect the delimiter
+			read, err := ReadRefsFromFile(filename)
+			if err != nil {
+				t.Fatalf("ReadRefsFromFile failed: %v", err)
+			}
+			if len(read) != 2 || read[1].IID != 16 || read[1].HeadSHA != "def4567" {
+				t.Errorf("ReadRefsFromFile() = %+v", read)
+			}
+		})
+	}
+}
+
+func TestValidate_DetectsDelimiter(t *testing.T) {
+	content := "# gh-gl-create-refs refs v1\n1\t" + validSHA1 + "\n2\t" + validSHA2 + "\n"
+
+	result, err := Validate(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if result.Rows != 2 || !result.Valid() {
+		t.Errorf("Validate() = %+v, want 2 valid rows", result)
+	}
+}
@@ -0,0 +1,39 @@
+package csv
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrDuplicateIID means a file lists the same merge request twice, so it names two commits
+// for one branch
+var ErrDuplicateIID = errors.New("duplicate merge request IID")
+
+// iidKey identifies a merge request; project is empty in single-repository files
+type iidKey struct {
+	project string
+	iid     int
+}
+
+// iidLines records the line each merge request was first seen on
+type iidLines map[iidKey]int
+
+// see records that the merge request iid of project is on line, and returns the line it
+// was first seen on when it was seen before
+func (l iidLines) see(project string, iid, line int) (int, bool) {
+	key := iidKey{project, iid}
+	if first, ok := l[key]; ok {
+		return first, true
+	}
+	l[key] = line
+	return 0, false
+}
+
+// check records the merge request like see, returning an error wrapping ErrDuplicateIID
+// when it was seen before
+func (l iidLines) check(project string, iid, line int) error {
+	if first, ok := l.see(project, iid, line); ok {
+		return fmt.Errorf("%w %d at line %d (first seen on line %d)", ErrDuplicateIID, iid, line, first)
+	}
+	return nil
+}
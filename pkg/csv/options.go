@@ -0,0 +1,94 @@
+package csv
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// utf8BOM marks a file as UTF-8 for spreadsheet applications
+const utf8BOM = "\ufeff"
+
+// WriteOption configures how files are written
+type WriteOption func(*writeConfig)
+
+// writeConfig holds the settings applied by WriteOption values
+type writeConfig struct {
+	delimiter rune
+	excel     bool
+}
+
+// WithDelimiter separates fields with delimiter instead of a comma
+func WithDelimiter(delimiter rune) WriteOption {
+	return func(c *writeConfig) {
+		c.delimiter = delimiter
+	}
+}
+
+// WithExcelCompatible writes files that Excel opens without mangling them: a UTF-8 byte
+// order mark, CRLF line endings, and SHAs written as ="<sha>" text formulas so that
+// SHAs made of digits and an "e" are not turned into numbers. The readers in this
+// package undo all three.
+func WithExcelCompatible() WriteOption {
+	return func(c *writeConfig) {
+		c.excel = true
+	}
+}
+
+// newWriteConfig applies options to the defaults
+func newWriteConfig(options []WriteOption) writeConfig {
+	config := writeConfig{delimiter: DefaultDelimiter}
+	for _, option := range options {
+		option(&config)
+	}
+	return config
+}
+
+// start writes what precedes the first line of a file
+func (c writeConfig) start(w io.Writer) error {
+	if !c.excel {
+		return nil
+	}
+	_, err := io.WriteString(w, utf8BOM)
+	return err
+}
+
+// lineEnding returns the line terminator of written files
+func (c writeConfig) lineEnding() string {
+	if c.excel {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// sha formats a commit SHA field
+func (c writeConfig) sha(sha string) string {
+	if c.excel && sha != "" {
+		return `="` + sha + `"`
+	}
+	return sha
+}
+
+// newCSVWriter creates a CSV writer for w configured by config
+func (c writeConfig) newCSVWriter(w io.Writer) *csv.Writer {
+	writer := csv.NewWriter(w)
+	writer.Comma = c.delimiter
+	writer.UseCRLF = c.excel
+	return writer
+}
+
+// skipBOM discards a UTF-8 byte order mark at the start of r
+func skipBOM(r *bufio.Reader) {
+	if data, _ := r.Peek(len(utf8BOM)); string(data) == utf8BOM {
+		r.Discard(len(utf8BOM))
+	}
+}
+
+// unwrapSHA reverses the ="<sha>" formatting of WithExcelCompatible
+func unwrapSHA(field string) string {
+	if strings.HasPrefix(field, `="`) && strings.HasSuffix(field, `"`) && len(field) >= 3 {
+		return field[2 : len(field)-1]
+	}
+	return field
+}
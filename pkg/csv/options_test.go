@@ -0,0 +1,63 @@
+package csv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+func TestWithExcelCompatible(t *testing.T) {
+	refs := []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: validSHA1},
+		{IID: 2, HeadSHA: "1234e567"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteRefs(&buf, refs, WithExcelCompatible()); err != nil {
+		t.Fatalf("WriteRefs failed: %v", err)
+	}
+
+	expected := "\ufeff# gh-gl-create-refs refs v1\r\n" +
+		"1,\"=\"\"" + validSHA1 + "\"\"\"\r\n" +
+		"2,\"=\"\"1234e567\"\"\"\r\n"
+	if buf.String() != expected {
+		t.Errorf("WriteRefs() wrote %q, want %q", buf.String(), expected)
+	}
+
+	// The readers undo the Excel formatting
+	read, err := ReadRefs(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadRefs failed: %v", err)
+	}
+	if len(read) != 2 || read[0].HeadSHA != validSHA1 || read[1].HeadSHA != "1234e567" {
+		t.Errorf("ReadRefs() = %+v", read)
+	}
+
+	result, err := Validate(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if result.Rows != 2 || len(result.Problems) != 1 || result.Problems[0].Line != 3 {
+		t.Errorf("Validate() = %+v, want only the short SHA on line 3 reported", result)
+	}
+}
+
+func TestUnwrapSHA(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: `="abc123"`, expected: "abc123"},
+		{input: "abc123", expected: "abc123"},
+		{input: `="`, expected: `="`},
+		{input: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		if got := unwrapSHA(tt.input); got != tt.expected {
+			t.Errorf("unwrapSHA(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
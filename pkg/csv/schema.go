@@ -0,0 +1,87 @@
+package csv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// SchemaVersion is the version of the CSV layouts written by this package.
+// Increase it whenever columns are added, removed or change meaning.
+const SchemaVersion = 1
+
+// schemaMarker starts the comment line that records the schema of a generated file
+const schemaMarker = "# gh-gl-create-refs"
+
+// Kinds of generated files, recorded in the schema line
+const (
+	KindRefs     = "refs"
+	KindVersions = "versions"
+	KindCombined = "combined"
+)
+
+// Schema identifies the layout of a CSV file
+type Schema struct {
+	Kind string
+	// Version is 0 for files written before schema lines were introduced
+	Version int
+}
+
+// String formats the schema as the comment line written at the top of generated files
+func (s Schema) String() string {
+	return fmt.Sprintf("%s %s v%d", schemaMarker, s.Kind, s.Version)
+}
+
+// writeSchema writes the schema line for a file of the given kind
+func writeSchema(w io.Writer, kind, lineEnding string) error {
+	_, err := fmt.Fprint(w, Schema{Kind: kind, Version: SchemaVersion}.String()+lineEnding)
+	return err
+}
+
+// ReadSchema detects the schema of a file from its first line without consuming it.
+// Files without a schema line are reported as version 0 of kind.
+func ReadSchema(r *bufio.Reader, kind string) (Schema, error) {
+	line, err := r.Peek(len(schemaMarker))
+	if err != nil || string(line) != schemaMarker {
+		// Too short or no marker: a file written before schema lines
+		return Schema{Kind: kind}, nil
+	}
+
+	// Peek up to the end of the schema line
+	var header string
+	for size := len(schemaMarker); ; size *= 2 {
+		data, err := r.Peek(size)
+		if i := strings.IndexByte(string(data), '\n'); i >= 0 {
+			header = string(data[:i])
+			break
+		}
+		if err != nil {
+			header = string(data)
+			break
+		}
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(header), schemaMarker))
+	if len(fields) != 2 || !strings.HasPrefix(fields[1], "v") {
+		return Schema{}, fmt.Errorf("invalid schema line %q", header)
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(fields[1], "v"))
+	if err != nil {
+		return Schema{}, fmt.Errorf("invalid schema line %q", header)
+	}
+
+	return Schema{Kind: fields[0], Version: version}, nil
+}
+
+// check verifies that a file of this schema can be read as kind by this version
+func (s Schema) check(kind string) error {
+	if s.Kind != kind {
+		return fmt.Errorf("file contains %s, not %s", s.Kind, kind)
+	}
+	if s.Version > SchemaVersion {
+		return fmt.Errorf("file uses schema version %d, but this version of gh-gl-create-refs only reads up to version %d; please upgrade", s.Version, SchemaVersion)
+	}
+	return nil
+}
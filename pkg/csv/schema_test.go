@@ -0,0 +1,143 @@
+package csv
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+func TestReadSchema(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expected    Schema
+		expectError bool
+	}{
+		{
+			name:     "legacy file without schema line",
+			content:  "1,abc1234\n",
+			expected: Schema{Kind: KindRefs, Version: 0},
+		},
+		{
+			name:     "empty file",
+			content:  "",
+			expected: Schema{Kind: KindRefs, Version: 0},
+		},
+		{
+			name:     "current refs schema",
+			content:  "# gh-gl-create-refs refs v1\n1,abc1234\n",
+			expected: Schema{Kind: KindRefs, Version: 1},
+		},
+		{
+			name:     "versions schema without trailing newline",
+			content:  "# gh-gl-create-refs versions v3",
+			expected: Schema{Kind: KindVersions, Version: 3},
+		},
+		{
+			name:        "malformed version",
+			content:     "# gh-gl-create-refs refs vX\n",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bufio.NewReader(strings.NewReader(tt.content))
+			schema, err := ReadSchema(reader, KindRefs)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected an error, got schema %+v", schema)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadSchema() unexpected error = %v", err)
+			}
+			if schema != tt.expected {
+				t.Errorf("ReadSchema() = %+v, want %+v", schema, tt.expected)
+			}
+
+			// The schema line is left for the CSV reader to skip
+			rest, _ := reader.ReadString(0)
+			if rest != tt.content {
+				t.Errorf("ReadSchema() consumed input, remaining %q", rest)
+			}
+		})
+	}
+}
+
+func TestReadRefsFromFile_Schema(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expectError string
+	}{
+		{
+			name:    "legacy file",
+			content: "1,abc1234\n",
+		},
+		{
+			name:    "current schema",
+			content: "# gh-gl-create-refs refs v1\n1,abc1234\n",
+		},
+		{
+			name:        "newer schema",
+			content:     "# gh-gl-create-refs refs v2\n1,abc1234,extra\n",
+			expectError: "please upgrade",
+		},
+		{
+			name:        "versions file",
+			content:     "# gh-gl-create-refs versions v1\n1,1,abc1234,def4567,\n",
+			expectError: "file contains versions, not refs",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "refs.csv")
+			if err := os.WriteFile(filename, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			refs, err := ReadRefsFromFile(filename)
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Errorf("ReadRefsFromFile() error = %v, want error containing %q", err, tt.expectError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadRefsFromFile() unexpected error = %v", err)
+			}
+			if len(refs) != 1 || refs[0].IID != 1 || refs[0].HeadSHA != "abc1234" {
+				t.Errorf("ReadRefsFromFile() = %+v", refs)
+			}
+		})
+	}
+}
+
+func TestWriteRefsToFile_RoundTrip(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "refs.csv")
+	sw, err := NewStreamWriter(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.WriteRef(gitlab.MergeRequestRef{IID: 5, HeadSHA: "abc1234"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	refs, err := ReadRefsFromFile(filename)
+	if err != nil {
+		t.Fatalf("ReadRefsFromFile() unexpected error = %v", err)
+	}
+	if len(refs) != 1 || refs[0].IID != 5 || refs[0].HeadSHA != "abc1234" {
+		t.Errorf("ReadRefsFromFile() = %+v", refs)
+	}
+}
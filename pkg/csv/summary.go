@@ -0,0 +1,66 @@
+package csv
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/batch"
+)
+
+// summaryHeader lists the columns of a batch summary file
+var summaryHeader = []string{"repository", "status", "merge_requests", "output", "duration_seconds", "error"}
+
+// WriteSummaryFile writes one row per repository of a batch run to a file
+func WriteSummaryFile(results []batch.Result, filename string, options ...WriteOption) error {
+	file, err := CreateAtomic(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Abort()
+
+	if err := WriteSummary(file, results, options...); err != nil {
+		return err
+	}
+
+	return file.Commit()
+}
+
+// WriteSummary writes one row per repository of a batch run to w
+func WriteSummary(w io.Writer, results []batch.Result, options ...WriteOption) error {
+	config := newWriteConfig(options)
+	if err := config.start(w); err != nil {
+		return fmt.Errorf("failed to write byte order mark: %w", err)
+	}
+
+	writer := config.newCSVWriter(w)
+
+	if err := writer.Write(summaryHeader); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+
+	for _, result := range results {
+		status := "ok"
+		errMsg := ""
+		if result.Err != nil {
+			status = "failed"
+			errMsg = result.Err.Error()
+		}
+
+		record := []string{
+			result.Repository,
+			status,
+			strconv.Itoa(result.Refs),
+			result.Output,
+			strconv.FormatFloat(result.Duration.Seconds(), 'f', 1, 64),
+			errMsg,
+		}
+
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
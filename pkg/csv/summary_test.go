@@ -0,0 +1,52 @@
+package csv
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/batch"
+)
+
+func TestWriteSummaryFile(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "summary.csv")
+
+	results := []batch.Result{
+		{Repository: "g/a", Refs: 3, Output: "g-a.csv", Duration: 1500 * time.Millisecond},
+		{Repository: "g/b", Err: errors.New("repository not found")},
+	}
+
+	if err := WriteSummaryFile(results, testFile); err != nil {
+		t.Fatalf("WriteSummaryFile failed: %v", err)
+	}
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+
+	expected := "repository,status,merge_requests,output,duration_seconds,error\n" +
+		"g/a,ok,3,g-a.csv,1.5,\n" +
+		"g/b,failed,0,,0.0,repository not found\n"
+	if string(content) != expected {
+		t.Errorf("File content = %q, want %q", string(content), expected)
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	results := []batch.Result{{Repository: "g/a", Refs: 1, Output: "g-a.csv"}}
+
+	var buf bytes.Buffer
+	if err := WriteSummary(&buf, results, WithDelimiter('|')); err != nil {
+		t.Fatalf("WriteSummary failed: %v", err)
+	}
+
+	expected := "repository|status|merge_requests|output|duration_seconds|error\n" +
+		"g/a|ok|1|g-a.csv|0.0|\n"
+	if buf.String() != expected {
+		t.Errorf("WriteSummary() wrote %q, want %q", buf.String(), expected)
+	}
+}
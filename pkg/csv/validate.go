@@ -0,0 +1,142 @@
+package csv
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// shaPattern matches a full 40-character hexadecimal commit SHA
+var shaPattern = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+
+// abbreviatedSHAPattern matches a full or abbreviated commit SHA; GitLab never abbreviates
+// to fewer than 7 characters
+var abbreviatedSHAPattern = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
+// ErrInvalidSHA means a row's commit SHA is not a full or abbreviated hexadecimal SHA
+var ErrInvalidSHA = errors.New("invalid SHA")
+
+// checkSHA returns an error wrapping ErrInvalidSHA when sha, read on line, is not a
+// full or abbreviated commit SHA
+func checkSHA(sha string, line int) error {
+	if !abbreviatedSHAPattern.MatchString(sha) {
+		return fmt.Errorf("%w %q at line %d: expected 7 to 40 hexadecimal characters", ErrInvalidSHA, sha, line)
+	}
+	return nil
+}
+
+// Problem describes a single validation issue found in a CSV file
+type Problem struct {
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+}
+
+// ValidationResult holds the outcome of validating a CSV file
+type ValidationResult struct {
+	Rows     int
+	Problems []Problem
+}
+
+// Valid reports whether no problems were found
+func (r *ValidationResult) Valid() bool {
+	return len(r.Problems) == 0
+}
+
+// ValidateFile checks every row of a merge request reference CSV file, single-repository
+// or combined, or standard input for Stdin, and reports all problems found instead of
+// stopping at the first one
+func ValidateFile(filename string) (*ValidationResult, error) {
+	file, err := openInput(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Validate(file)
+}
+
+// Validate checks every row read from r; see ValidateFile
+func Validate(r io.Reader) (*ValidationResult, error) {
+	buffered := bufio.NewReader(r)
+	skipBOM(buffered)
+	result := &ValidationResult{}
+
+	schema, err := ReadSchema(buffered, KindRefs)
+	if err != nil {
+		result.Problems = append(result.Problems, Problem{Line: 1, Message: err.Error()})
+		return result, nil
+	}
+	// Combined files have a leading project column
+	kind, columns := KindRefs, 2
+	if schema.Kind == KindCombined {
+		kind, columns = KindCombined, 3
+	}
+	if err := schema.check(kind); err != nil {
+		result.Problems = append(result.Problems, Problem{Line: 1, Message: err.Error()})
+		return result, nil
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.Comma = detectDelimiter(buffered)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1 // Column counts are checked per row below
+
+	seen := make(iidLines)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				result.Problems = append(result.Problems, Problem{Line: parseErr.Line, Message: parseErr.Err.Error()})
+				continue
+			}
+			return nil, fmt.Errorf("failed to read CSV file: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		result.Rows++
+
+		if len(record) != columns {
+			result.Problems = append(result.Problems, Problem{Line: line, Message: fmt.Sprintf("expected %d columns, got %d", columns, len(record))})
+			continue
+		}
+
+		project := ""
+		if kind == KindCombined {
+			project, record = record[0], record[1:]
+			if project == "" {
+				result.Problems = append(result.Problems, Problem{Line: line, Message: "missing project path"})
+			}
+		}
+
+		iid, err := strconv.Atoi(record[0])
+		switch {
+		case err != nil:
+			result.Problems = append(result.Problems, Problem{Line: line, Message: fmt.Sprintf("invalid merge request IID %q", record[0])})
+		case iid <= 0:
+			result.Problems = append(result.Problems, Problem{Line: line, Message: fmt.Sprintf("merge request IID must be positive, got %d", iid)})
+		default:
+			if first, ok := seen.see(project, iid, line); ok {
+				result.Problems = append(result.Problems, Problem{Line: line, Message: fmt.Sprintf("duplicate merge request IID %d (first seen on line %d)", iid, first)})
+			}
+		}
+
+		if sha := unwrapSHA(record[1]); !shaPattern.MatchString(sha) {
+			result.Problems = append(result.Problems, Problem{Line: line, Message: fmt.Sprintf("invalid SHA %q: expected 40 hexadecimal characters", sha)})
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,110 @@
+package csv
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	validSHA1 = "e8a44ccde03fc255605d38aec8db81db176398eb"
+	validSHA2 = "f70267410222c85b3ea62df436acef0de0e9bda3"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		expectedRows  int
+		expectedLines []int
+	}{
+		{
+			name:         "valid file",
+			content:      "1," + validSHA1 + "\n16," + validSHA2 + "\n",
+			expectedRows: 2,
+		},
+		{
+			name:         "valid file with schema line",
+			content:      "# gh-gl-create-refs refs v1\n1," + validSHA1 + "\n",
+			expectedRows: 1,
+		},
+		{
+			name:          "newer schema",
+			content:       "# gh-gl-create-refs refs v2\n1," + validSHA1 + "\n",
+			expectedLines: []int{1},
+		},
+		{
+			name:          "non-numeric IID",
+			content:       "abc," + validSHA1 + "\n",
+			expectedRows:  1,
+			expectedLines: []int{1},
+		},
+		{
+			name:          "non-positive IID",
+			content:       "0," + validSHA1 + "\n-3," + validSHA2 + "\n",
+			expectedRows:  2,
+			expectedLines: []int{1, 2},
+		},
+		{
+			name:          "duplicate IID",
+			content:       "1," + validSHA1 + "\n2," + validSHA2 + "\n1," + validSHA2 + "\n",
+			expectedRows:  3,
+			expectedLines: []int{3},
+		},
+		{
+			name:          "short SHA",
+			content:       "1,abc123\n",
+			expectedRows:  1,
+			expectedLines: []int{1},
+		},
+		{
+			name:          "wrong column count",
+			content:       "1," + validSHA1 + ",extra\n2\n",
+			expectedRows:  2,
+			expectedLines: []int{1, 2},
+		},
+		{
+			name:         "valid combined file",
+			content:      "# gh-gl-create-refs combined v1\nacme/a,1," + validSHA1 + "\nacme/b,1," + validSHA2 + "\n",
+			expectedRows: 2,
+		},
+		{
+			name:          "combined file problems",
+			content:       "# gh-gl-create-refs combined v1\nacme/a,1," + validSHA1 + "\nacme/a,1," + validSHA2 + "\n,2," + validSHA1 + "\nacme/b,3\n",
+			expectedRows:  4,
+			expectedLines: []int{3, 4, 5},
+		},
+		{
+			name:          "multiple problems on one line",
+			content:       "x,zzz\n",
+			expectedRows:  1,
+			expectedLines: []int{1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Validate(strings.NewReader(tt.content))
+			if err != nil {
+				t.Fatalf("Validate failed: %v", err)
+			}
+
+			if result.Rows != tt.expectedRows {
+				t.Errorf("Rows = %d, want %d", result.Rows, tt.expectedRows)
+			}
+
+			if len(result.Problems) != len(tt.expectedLines) {
+				t.Fatalf("Expected %d problems, got %d: %v", len(tt.expectedLines), len(result.Problems), result.Problems)
+			}
+
+			for i, problem := range result.Problems {
+				if problem.Line != tt.expectedLines[i] {
+					t.Errorf("Problem %d line = %d, want %d (%s)", i, problem.Line, tt.expectedLines[i], problem)
+				}
+			}
+
+			if result.Valid() != (len(tt.expectedLines) == 0) {
+				t.Errorf("Valid() = %v, want %v", result.Valid(), len(tt.expectedLines) == 0)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package csv
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// VersionsFilename returns the file that holds the merge request versions for a references CSV file
+func VersionsFilename(refsFilename string) string {
+	ext := filepath.Ext(refsFilename)
+	return strings.TrimSuffix(refsFilename, ext) + "-versions" + ext
+}
+
+// NewVersionsWriter starts a merge request versions CSV on w; write to it with WriteVersions
+func NewVersionsWriter(w io.Writer, options ...WriteOption) (*Writer, error) {
+	return newKindWriter(w, KindVersions, options)
+}
+
+// NewVersionsStreamWriter creates a stream writer for a merge request versions file; write to it with WriteVersions
+func NewVersionsStreamWriter(filename string, options ...WriteOption) (*StreamWriter, error) {
+	return newStreamWriter(filename, KindVersions, options)
+}
+
+// WriteVersions writes one row per diff version of a merge request:
+// IID, version number (1 is the oldest), head SHA, base SHA and creation time
+func (w *Writer) WriteVersions(ref gitlab.MergeRequestRef) error {
+	for i, version := range ref.Versions {
+		createdAt := ""
+		if !version.CreatedAt.IsZero() {
+			createdAt = version.CreatedAt.UTC().Format(time.RFC3339)
+		}
+
+		record := []string{
+			strconv.Itoa(ref.IID),
+			strconv.Itoa(i + 1),
+			w.config.sha(version.HeadSHA),
+			w.config.sha(version.BaseSHA),
+			createdAt,
+		}
+		if err := w.writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package csv
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+func TestVersionsFilename(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"group-project.csv", "group-project-versions.csv"},
+		{"out/refs.csv", "out/refs-versions.csv"},
+		{"refs", "refs-versions"},
+	}
+
+	for _, tt := range tests {
+		if got := VersionsFilename(tt.input); got != tt.expected {
+			t.Errorf("VersionsFilename(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestStreamWriter_WriteVersions(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "versions.csv")
+
+	sw, err := NewVersionsStreamWriter(filename)
+	if err != nil {
+		t.Fatalf("NewVersionsStreamWriter failed: %v", err)
+	}
+
+	ref := gitlab.MergeRequestRef{
+		IID: 7,
+		Versions: []gitlab.MergeRequestVersion{
+			{HeadSHA: "old", BaseSHA: "base", CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			{HeadSHA: "new", BaseSHA: "base"},
+		},
+	}
+	if err := sw.WriteVersions(ref); err != nil {
+		t.Fatalf("WriteVersions failed: %v", err)
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "# gh-gl-create-refs versions v1\n7,1,old,base,2024-01-01T00:00:00Z\n7,2,new,base,\n"
+	if string(data) != expected {
+		t.Errorf("Versions file = %q, want %q", data, expected)
+	}
+}
+
+func TestNewVersionsWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewVersionsWriter(&buf, WithDelimiter('\t'))
+	if err != nil {
+		t.Fatalf("NewVersionsWriter failed: %v", err)
+	}
+
+	ref := gitlab.MergeRequestRef{
+		IID:      2,
+		Versions: []gitlab.MergeRequestVersion{{HeadSHA: "new", BaseSHA: "base"}},
+	}
+	if err := writer.WriteVersions(ref); err != nil {
+		t.Fatalf("WriteVersions failed: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	expected := "# gh-gl-create-refs versions v1\n2\t1\tnew\tbase\t\n"
+	if buf.String() != expected {
+		t.Errorf("Versions output = %q, want %q", buf.String(), expected)
+	}
+}
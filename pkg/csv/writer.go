@@ -1,10 +1,15 @@
 package csv
 
 import (
+	"bufio"
+	"cmp"
 	"encoding/csv"
 	"fmt"
+	"io"
+	"iter"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -31,32 +36,37 @@ func GenerateFilename(repoPath string) string {
 	return fmt.Sprintf("%s.csv", name)
 }
 
-// WriteRefsToFile writes merge request references to a CSV file
-func WriteRefsToFile(refs []gitlab.MergeRequestRef, filename string) error {
-	// Create the file
-	file, err := os.Create(filename)
+// WriteRefs writes merge request references as CSV to w
+func WriteRefs(w io.Writer, refs []gitlab.MergeRequestRef, options ...WriteOption) error {
+	writer, err := NewWriter(w, options...)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", filename, err)
+		return err
 	}
-	defer file.Close()
-
-	// Create CSV writer
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
 
 	// Write each merge request reference
 	for _, ref := range refs {
-		record := []string{
-			strconv.Itoa(ref.IID), // Use IID (internal ID) which is the MR number shown in GitLab UI
-			ref.HeadSHA,
+		if err := writer.WriteRef(ref); err != nil {
+			return err
 		}
+	}
 
-		if err := writer.Write(record); err != nil {
-			return fmt.Errorf("failed to write record: %w", err)
-		}
+	return writer.Flush()
+}
+
+// WriteRefsToFile writes merge request references to a CSV file. The file is replaced
+// atomically, so an existing file is left untouched if writing fails.
+func WriteRefsToFile(refs []gitlab.MergeRequestRef, filename string, options ...WriteOption) error {
+	file, err := CreateAtomic(filename)
+	if err != nil {
+		return err
 	}
+	defer file.Abort()
 
-	return nil
+	if err := WriteRefs(file, refs, options...); err != nil {
+		return err
+	}
+
+	return file.Commit()
 }
 
 // WriteRefsToCSV is a convenience function that generates filename and writes refs
@@ -77,89 +87,287 @@ func WriteRefsToCSV(refs []gitlab.MergeRequestRef, repoPath string) (string, err
 	return absPath, nil
 }
 
-// StreamWriter handles incremental writing of merge request references to CSV
-type StreamWriter struct {
-	file   *os.File
+// Writer writes merge request references, or merge request versions, as CSV to an
+// io.Writer such as a buffer or an HTTP response. Rows are buffered; call Flush when done.
+type Writer struct {
 	writer *csv.Writer
+	config writeConfig
+}
+
+// NewWriter starts a merge request references CSV on w by writing its schema line
+func NewWriter(w io.Writer, options ...WriteOption) (*Writer, error) {
+	return newKindWriter(w, KindRefs, options)
 }
 
-// NewStreamWriter creates a new CSV stream writer for incremental writing
-func NewStreamWriter(filename string) (*StreamWriter, error) {
-	file, err := os.Create(filename)
+// newKindWriter starts a CSV of the given kind on w
+func newKindWriter(w io.Writer, kind string, options []WriteOption) (*Writer, error) {
+	config := newWriteConfig(options)
+
+	if err := config.start(w); err != nil {
+		return nil, fmt.Errorf("failed to write byte order mark: %w", err)
+	}
+	if err := writeSchema(w, kind, config.lineEnding()); err != nil {
+		return nil, fmt.Errorf("failed to write schema line: %w", err)
+	}
+
+	return &Writer{writer: config.newCSVWriter(w), config: config}, nil
+}
+
+// WriteRef writes a single merge request reference
+func (w *Writer) WriteRef(ref gitlab.MergeRequestRef) error {
+	record := []string{
+		strconv.Itoa(ref.IID), // Use IID (internal ID) which is the MR number shown in GitLab UI
+		w.config.sha(ref.HeadSHA),
+	}
+
+	if err := w.writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+
+	return nil
+}
+
+// Flush writes any buffered rows to the underlying io.Writer
+func (w *Writer) Flush() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return fmt.Errorf("failed to flush CSV writer: %w", err)
+	}
+	return nil
+}
+
+// StreamWriter handles incremental writing of merge request references to a CSV file.
+// Rows are written to a temporary file that Close moves into place, so an interrupted
+// run never leaves a truncated file that looks complete; call Abort to discard it.
+type StreamWriter struct {
+	*Writer
+	file *AtomicFile
+}
+
+// NewStreamWriter creates a new CSV stream writer for incremental writing of merge request references
+func NewStreamWriter(filename string, options ...WriteOption) (*StreamWriter, error) {
+	return newStreamWriter(filename, KindRefs, options)
+}
+
+// newStreamWriter creates a stream writer for a file of the given kind
+func newStreamWriter(filename, kind string, options []WriteOption) (*StreamWriter, error) {
+	file, err := CreateAtomic(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create file %s: %w", filename, err)
+		return nil, err
 	}
 
-	writer := csv.NewWriter(file)
+	writer, err := newKindWriter(file, kind, options)
+	if err != nil {
+		file.Abort()
+		return nil, err
+	}
 
 	return &StreamWriter{
+		Writer: writer,
 		file:   file,
-		writer: writer,
 	}, nil
 }
 
 // WriteRef writes a single merge request reference to the CSV file
 func (sw *StreamWriter) WriteRef(ref gitlab.MergeRequestRef) error {
-	record := []string{
-		strconv.Itoa(ref.IID), // Use IID (internal ID) which is the MR number shown in GitLab UI
-		ref.HeadSHA,
-	}
-
-	if err := sw.writer.Write(record); err != nil {
-		return fmt.Errorf("failed to write record: %w", err)
+	if err := sw.Writer.WriteRef(ref); err != nil {
+		return err
 	}
 
 	// Flush after each write to ensure data is written immediately
-	sw.writer.Flush()
+	return sw.Flush()
+}
 
-	return sw.writer.Error()
+// WriteVersions writes the diff versions of a merge request to the CSV file
+func (sw *StreamWriter) WriteVersions(ref gitlab.MergeRequestRef) error {
+	if err := sw.Writer.WriteVersions(ref); err != nil {
+		return err
+	}
+	return sw.Flush()
 }
 
-// Close closes the CSV writer and file
+// Close flushes the CSV writer and moves the completed file into place
 func (sw *StreamWriter) Close() error {
-	sw.writer.Flush()
-	if err := sw.writer.Error(); err != nil {
-		sw.file.Close() // Still close the file even if flush fails
-		return fmt.Errorf("failed to flush CSV writer: %w", err)
+	if err := sw.Flush(); err != nil {
+		sw.file.Abort() // Don't publish a file that is missing rows
+		return err
 	}
 
-	if err := sw.file.Close(); err != nil {
-		return fmt.Errorf("failed to close file: %w", err)
-	}
+	return sw.file.Commit()
+}
 
-	return nil
+// Abort discards everything written so far, leaving any existing file untouched.
+// It does nothing after Close, so it can be deferred right after NewStreamWriter.
+func (sw *StreamWriter) Abort() {
+	sw.file.Abort()
 }
 
-// ReadRefsFromFile reads merge request references from a CSV file
-func ReadRefsFromFile(filename string) ([]gitlab.MergeRequestRef, error) {
+// Stdin is the filename that makes the ...File readers read standard input
+const Stdin = "-"
+
+// openInput opens filename for reading, or standard input for Stdin
+func openInput(filename string) (io.ReadCloser, error) {
+	if filename == Stdin {
+		return io.NopCloser(os.Stdin), nil
+	}
+
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file %s: %w", filename, err)
 	}
-	defer file.Close()
+	return file, nil
+}
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV file: %w", err)
+// ReadRefsFromFile reads all merge request references from a CSV file, or standard input
+// for Stdin, into memory; see RefsFromFile for reading large files row by row
+func ReadRefsFromFile(filename string) ([]gitlab.MergeRequestRef, error) {
+	return collectRefs(RefsFromFile(filename))
+}
+
+// ReadRefs reads all merge request references from r into memory; see RefsFrom
+func ReadRefs(r io.Reader) ([]gitlab.MergeRequestRef, error) {
+	return collectRefs(RefsFrom(r))
+}
+
+// collectRefs gathers the references yielded by refs, stopping at the first error
+func collectRefs(refs iter.Seq2[gitlab.MergeRequestRef, error]) ([]gitlab.MergeRequestRef, error) {
+	var collected []gitlab.MergeRequestRef
+	for ref, err := range refs {
+		if err != nil {
+			return nil, err
+		}
+		collected = append(collected, ref)
 	}
 
-	var refs []gitlab.MergeRequestRef
-	for i, record := range records {
-		if len(record) != 2 {
-			return nil, fmt.Errorf("invalid CSV format at line %d: expected 2 columns, got %d", i+1, len(record))
+	return collected, nil
+}
+
+// RefsFromFile iterates over the merge request references of a CSV file one row at a
+// time, without loading the file into memory; see RefsFrom. Stdin reads standard input,
+// which can only be iterated once.
+func RefsFromFile(filename string) iter.Seq2[gitlab.MergeRequestRef, error] {
+	return fromFile(filename, RefsFrom)
+}
+
+// fromFile iterates over the rows read from filename by from, prefixing errors with the filename
+func fromFile[T any](filename string, from func(io.Reader) iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		file, err := openInput(filename)
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+		defer file.Close()
+
+		name := filename
+		if filename == Stdin {
+			name = "stdin"
 		}
 
-		iid, err := strconv.Atoi(record[0])
+		for row, err := range from(file) {
+			if err != nil {
+				err = fmt.Errorf("%s: %w", name, err)
+			}
+			if !yield(row, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// RefsFrom iterates over the merge request references read from r one row at a time.
+// Input with or without a schema line is accepted; input written with a newer schema
+// version is rejected. The field delimiter is detected from the first record.
+// A merge request listed twice is an error wrapping ErrDuplicateIID, and a SHA that is not
+// 7 to 40 hexadecimal characters one wrapping ErrInvalidSHA.
+// Iteration stops after the first error.
+func RefsFrom(r io.Reader) iter.Seq2[gitlab.MergeRequestRef, error] {
+	return func(yield func(gitlab.MergeRequestRef, error) bool) {
+		buffered := bufio.NewReader(r)
+		skipBOM(buffered)
+		schema, err := ReadSchema(buffered, KindRefs)
 		if err != nil {
-			return nil, fmt.Errorf("invalid merge request IID at line %d: %w", i+1, err)
+			yield(gitlab.MergeRequestRef{}, fmt.Errorf("failed to read CSV: %w", err))
+			return
+		}
+		if err := schema.check(KindRefs); err != nil {
+			yield(gitlab.MergeRequestRef{}, err)
+			return
 		}
 
-		refs = append(refs, gitlab.MergeRequestRef{
-			IID:     iid,
-			HeadSHA: record[1],
-		})
+		reader := csv.NewReader(buffered)
+		reader.Comma = detectDelimiter(buffered)
+		reader.Comment = '#'
+		reader.FieldsPerRecord = -1 // Column counts are checked per row below
+		reader.ReuseRecord = true
+		seen := make(iidLines)
+
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(gitlab.MergeRequestRef{}, fmt.Errorf("failed to read CSV: %w", err))
+				return
+			}
+
+			line, _ := reader.FieldPos(0)
+			if len(record) != 2 {
+				yield(gitlab.MergeRequestRef{}, fmt.Errorf("invalid CSV format at line %d: expected 2 columns, got %d", line, len(record)))
+				return
+			}
+
+			iid, err := strconv.Atoi(record[0])
+			if err != nil {
+				yield(gitlab.MergeRequestRef{}, fmt.Errorf("invalid merge request IID at line %d: %w", line, err))
+				return
+			}
+			if err := seen.check("", iid, line); err != nil {
+				yield(gitlab.MergeRequestRef{}, err)
+				return
+			}
+
+			sha := unwrapSHA(record[1])
+			if err := checkSHA(sha, line); err != nil {
+				yield(gitlab.MergeRequestRef{}, err)
+				return
+			}
+
+			if !yield(gitlab.MergeRequestRef{IID: iid, HeadSHA: sha}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// SortRefsByIID orders references by merge request IID, in place
+func SortRefsByIID(refs []gitlab.MergeRequestRef) {
+	slices.SortStableFunc(refs, func(a, b gitlab.MergeRequestRef) int {
+		return cmp.Compare(a.IID, b.IID)
+	})
+}
+
+// MergeRefs overlays updates onto existing references by IID: changed references keep
+// their position, and references not seen before are appended in the order given
+func MergeRefs(existing, updates []gitlab.MergeRequestRef) []gitlab.MergeRequestRef {
+	merged := make([]gitlab.MergeRequestRef, len(existing))
+	copy(merged, existing)
+
+	index := make(map[int]int, len(merged))
+	for i, ref := range merged {
+		index[ref.IID] = i
+	}
+
+	for _, ref := range updates {
+		if i, ok := index[ref.IID]; ok {
+			merged[i] = ref
+			continue
+		}
+		index[ref.IID] = len(merged)
+		merged = append(merged, ref)
 	}
 
-	return refs, nil
+	return merged
 }
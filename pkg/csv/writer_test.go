@@ -1,8 +1,11 @@
 package csv
 
 import (
+	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
@@ -58,9 +61,9 @@ func TestWriteRefsToFile(t *testing.T) {
 
 	// Test data
 	refs := []gitlab.MergeRequestRef{
-		{ID: 1, IID: 1, HeadSHA: "abc123"},
-		{ID: 2, IID: 16, HeadSHA: "def456"},
-		{ID: 3, IID: 17, HeadSHA: "ghi789"},
+		{ID: 1, IID: 1, HeadSHA: "abc1234"},
+		{ID: 2, IID: 16, HeadSHA: "def4567"},
+		{ID: 3, IID: 17, HeadSHA: "0789abc"},
 	}
 
 	// Write refs to file
@@ -75,7 +78,7 @@ func TestWriteRefsToFile(t *testing.T) {
 		t.Fatalf("Failed to read test file: %v", err)
 	}
 
-	expected := "1,abc123\n16,def456\n17,ghi789\n"
+	expected := "# gh-gl-create-refs refs v1\n1,abc1234\n16,def4567\n17,0789abc\n"
 	if string(content) != expected {
 		t.Errorf("File content = %q, want %q", string(content), expected)
 	}
@@ -87,7 +90,7 @@ func TestReadRefsFromFile(t *testing.T) {
 	testFile := filepath.Join(tempDir, "test.csv")
 
 	// Create test CSV content
-	content := "1,abc123\n16,def456\n17,ghi789\n"
+	content := "1,abc1234\n16,def4567\n17,0789abc\n"
 	err := os.WriteFile(testFile, []byte(content), 0644)
 	if err != nil {
 		t.Fatalf("Failed to write test file: %v", err)
@@ -101,9 +104,9 @@ func TestReadRefsFromFile(t *testing.T) {
 
 	// Verify the content
 	expected := []gitlab.MergeRequestRef{
-		{IID: 1, HeadSHA: "abc123"},
-		{IID: 16, HeadSHA: "def456"},
-		{IID: 17, HeadSHA: "ghi789"},
+		{IID: 1, HeadSHA: "abc1234"},
+		{IID: 16, HeadSHA: "def4567"},
+		{IID: 17, HeadSHA: "0789abc"},
 	}
 
 	if len(refs) != len(expected) {
@@ -123,7 +126,7 @@ func TestReadRefsFromFile_InvalidFormat(t *testing.T) {
 	testFile := filepath.Join(tempDir, "invalid.csv")
 
 	// Test with invalid number of columns
-	content := "1,abc123,extra\n"
+	content := "1,abc1234,extra\n"
 	err := os.WriteFile(testFile, []byte(content), 0644)
 	if err != nil {
 		t.Fatalf("Failed to write test file: %v", err)
@@ -140,7 +143,7 @@ func TestReadRefsFromFile_InvalidIID(t *testing.T) {
 	testFile := filepath.Join(tempDir, "invalid_iid.csv")
 
 	// Test with invalid IID
-	content := "not_a_number,abc123\n"
+	content := "not_a_number,abc1234\n"
 	err := os.WriteFile(testFile, []byte(content), 0644)
 	if err != nil {
 		t.Fatalf("Failed to write test file: %v", err)
@@ -150,4 +153,213 @@ func TestReadRefsFromFile_InvalidIID(t *testing.T) {
 	if err == nil {
 		t.Fatal("Expected error for invalid IID, got nil")
 	}
-}
\ No newline at end of file
+}
+func TestReadRefs_DuplicateIID(t *testing.T) {
+	_, err := ReadRefs(strings.NewReader("1,abc1234\n2,def4567\n1,0789abc\n"))
+	if !errors.Is(err, ErrDuplicateIID) {
+		t.Fatalf("ReadRefs() error = %v, want ErrDuplicateIID", err)
+	}
+	if !strings.Contains(err.Error(), "line 3 (first seen on line 1)") {
+		t.Errorf("ReadRefs() error = %q, want both line numbers", err)
+	}
+}
+
+func TestReadRefs_InvalidSHA(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		valid   bool
+	}{
+		{"full SHA", "1,0123456789abcdef0123456789ABCDEF01234567\n", true},
+		{"abbreviated SHA", "1,abc1234\n", true},
+		{"too short", "1,abc123\n", false},
+		{"too long", "1,0123456789abcdef0123456789abcdef012345678\n", false},
+		{"not hexadecimal", "1,ghijklm\n", false},
+		{"empty", "1,\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadRefs(strings.NewReader(tt.content))
+			if tt.valid {
+				if err != nil {
+					t.Errorf("ReadRefs() unexpected error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidSHA) || !strings.Contains(err.Error(), "line 1") {
+				t.Errorf("ReadRefs() error = %v, want ErrInvalidSHA at line 1", err)
+			}
+		})
+	}
+}
+
+func TestMergeRefs(t *testing.T) {
+	existing := []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: "aaaaaaa"},
+		{IID: 2, HeadSHA: "bbbbbbb"},
+	}
+	updates := []gitlab.MergeRequestRef{
+		{IID: 3, HeadSHA: "ccccccc"},
+		{IID: 1, HeadSHA: "new"},
+	}
+
+	merged := MergeRefs(existing, updates)
+
+	expected := []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: "new"},
+		{IID: 2, HeadSHA: "bbbbbbb"},
+		{IID: 3, HeadSHA: "ccccccc"},
+	}
+
+	if len(merged) != len(expected) {
+		t.Fatalf("Expected %d refs, got %d", len(expected), len(merged))
+	}
+	for i := range expected {
+		if merged[i].IID != expected[i].IID || merged[i].HeadSHA != expected[i].HeadSHA {
+			t.Errorf("Ref %d = %+v, want %+v", i, merged[i], expected[i])
+		}
+	}
+
+	if existing[0].HeadSHA != "aaaaaaa" {
+		t.Error("MergeRefs should not modify the existing slice")
+	}
+}
+
+func TestSortRefsByIID(t *testing.T) {
+	refs := []gitlab.MergeRequestRef{
+		{IID: 17, HeadSHA: "ccccccc"},
+		{IID: 1, HeadSHA: "aaaaaaa"},
+		{IID: 16, HeadSHA: "bbbbbbb"},
+	}
+
+	SortRefsByIID(refs)
+
+	for i, iid := range []int{1, 16, 17} {
+		if refs[i].IID != iid {
+			t.Errorf("Ref %d IID = %d, want %d", i, refs[i].IID, iid)
+		}
+	}
+}
+
+func TestRefsFromFile(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "refs.csv")
+	content := "# gh-gl-create-refs refs v1\n1,abc1234\n16,def4567\nbad,0789abc\n"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rows are yielded until the malformed one, which stops the iteration with an error
+	var iids []int
+	var lastErr error
+	for ref, err := range RefsFromFile(testFile) {
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		iids = append(iids, ref.IID)
+	}
+
+	if len(iids) != 2 || iids[0] != 1 || iids[1] != 16 {
+		t.Errorf("RefsFromFile() yielded IIDs %v, want [1 16]", iids)
+	}
+	if lastErr == nil || !strings.Contains(lastErr.Error(), "line 4") {
+		t.Errorf("RefsFromFile() error = %v, want an error for line 4", lastErr)
+	}
+
+	// Stopping early is allowed
+	for range RefsFromFile(testFile) {
+		break
+	}
+}
+
+func TestWriteRefs(t *testing.T) {
+	refs := []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: "abc1234"},
+		{IID: 16, HeadSHA: "def4567"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteRefs(&buf, refs, WithDelimiter(';')); err != nil {
+		t.Fatalf("WriteRefs failed: %v", err)
+	}
+
+	expected := "# gh-gl-create-refs refs v1\n1;abc1234\n16;def4567\n"
+	if buf.String() != expected {
+		t.Errorf("WriteRefs() wrote %q, want %q", buf.String(), expected)
+	}
+
+	// The output reads back with RefsFrom
+	var iids []int
+	for ref, err := range RefsFrom(&buf) {
+		if err != nil {
+			t.Fatalf("RefsFrom() unexpected error = %v", err)
+		}
+		iids = append(iids, ref.IID)
+	}
+	if len(iids) != 2 || iids[0] != 1 || iids[1] != 16 {
+		t.Errorf("RefsFrom() yielded IIDs %v, want [1 16]", iids)
+	}
+}
+
+func TestWriter_Flush(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+
+	if err := writer.WriteRef(gitlab.MergeRequestRef{IID: 3, HeadSHA: "abc1234"}); err != nil {
+		t.Fatalf("WriteRef failed: %v", err)
+	}
+
+	// Rows are buffered until Flush
+	if strings.Contains(buf.String(), "abc1234") {
+		t.Errorf("Row written before Flush: %q", buf.String())
+	}
+
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if !strings.HasSuffix(buf.String(), "3,abc1234\n") {
+		t.Errorf("Output after Flush = %q", buf.String())
+	}
+}
+
+func TestReadRefs(t *testing.T) {
+	refs, err := ReadRefs(strings.NewReader("# gh-gl-create-refs refs v1\n1,abc1234\n16,def4567\n"))
+	if err != nil {
+		t.Fatalf("ReadRefs failed: %v", err)
+	}
+	if len(refs) != 2 || refs[1].IID != 16 || refs[1].HeadSHA != "def4567" {
+		t.Errorf("ReadRefs() = %+v", refs)
+	}
+
+	if _, err := ReadRefs(strings.NewReader("1,abc1234,extra\n")); err == nil {
+		t.Error("ReadRefs() expected an error for an extra column")
+	}
+}
+
+func TestReadRefsFromFile_Stdin(t *testing.T) {
+	stdin := filepath.Join(t.TempDir(), "stdin.csv")
+	if err := os.WriteFile(stdin, []byte("1,abc1234\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	original := os.Stdin
+	os.Stdin = file
+	t.Cleanup(func() { os.Stdin = original })
+
+	refs, err := ReadRefsFromFile(Stdin)
+	if err != nil {
+		t.Fatalf("ReadRefsFromFile(Stdin) failed: %v", err)
+	}
+	if len(refs) != 1 || refs[0].IID != 1 {
+		t.Errorf("ReadRefsFromFile(Stdin) = %+v", refs)
+	}
+}
@@ -0,0 +1,134 @@
+// Package encrypt encrypts output files at rest for age or OpenPGP recipients. The
+// encryption is done by the age or gpg command, so keys stay in the tools and keyrings
+// users already manage.
+package encrypt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// ErrMixedRecipients means age and OpenPGP recipients were given together; a file is
+// encrypted with one tool
+var ErrMixedRecipients = errors.New("age and GPG recipients cannot be mixed")
+
+// Encrypter encrypts streams for a set of recipients
+type Encrypter struct {
+	tool string
+	path string
+	args []string
+}
+
+// New returns an Encrypter for recipients. Recipients starting with age1 are age public
+// keys and are encrypted to with age; any other recipient is a GPG key ID, fingerprint or
+// email address of a public key in the GPG keyring. The tool must be installed.
+func New(recipients []string) (*Encrypter, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no encryption recipients")
+	}
+
+	age := 0
+	for _, r := range recipients {
+		if strings.TrimSpace(r) == "" {
+			return nil, errors.New("empty encryption recipient")
+		}
+		if isAgeRecipient(r) {
+			age++
+		}
+	}
+
+	e := &Encrypter{}
+	switch age {
+	case len(recipients):
+		e.tool = "age"
+		for _, r := range recipients {
+			e.args = append(e.args, "--recipient", r)
+		}
+	case 0:
+		e.tool = "gpg"
+		// The keyring's trust settings are for signatures; the recipients were chosen explicitly
+		e.args = []string{"--batch", "--quiet", "--yes", "--trust-model", "always", "--encrypt"}
+		for _, r := range recipients {
+			e.args = append(e.args, "--recipient", r)
+		}
+		e.args = append(e.args, "--output", "-")
+	default:
+		return nil, ErrMixedRecipients
+	}
+
+	path, err := exec.LookPath(e.tool)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encrypt for %s: %s is not installed", strings.Join(recipients, ", "), e.tool)
+	}
+	e.path = path
+	return e, nil
+}
+
+// isAgeRecipient reports whether recipient is an age public key
+func isAgeRecipient(recipient string) bool {
+	return strings.HasPrefix(recipient, "age1")
+}
+
+// Tool returns the command that encrypts, age or gpg
+func (e *Encrypter) Tool() string {
+	return e.tool
+}
+
+// Stream encrypts what is written to it. Close completes the encrypted output; Abort stops
+// the encryption, leaving a truncated output to be discarded.
+type Stream struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr bytes.Buffer
+	tool   string
+}
+
+// Encrypt starts encrypting to w
+func (e *Encrypter) Encrypt(w io.Writer) (*Stream, error) {
+	s := &Stream{cmd: exec.Command(e.path, e.args...), tool: e.tool}
+	s.cmd.Stdout = w
+	s.cmd.Stderr = &s.stderr
+
+	stdin, err := s.cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", e.tool, err)
+	}
+	s.stdin = stdin
+
+	if err := s.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", e.tool, err)
+	}
+	return s, nil
+}
+
+// Write passes p to the encryption
+func (s *Stream) Write(p []byte) (int, error) {
+	n, err := s.stdin.Write(p)
+	if err != nil {
+		return n, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	return n, nil
+}
+
+// Close ends the input and waits for the encrypted output to be written
+func (s *Stream) Close() error {
+	s.stdin.Close()
+	if err := s.cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(s.stderr.String()); msg != "" {
+			return fmt.Errorf("%s failed to encrypt: %s", s.tool, msg)
+		}
+		return fmt.Errorf("%s failed to encrypt: %w", s.tool, err)
+	}
+	return nil
+}
+
+// Abort stops the encryption
+func (s *Stream) Abort() {
+	s.stdin.Close()
+	s.cmd.Process.Kill()
+	s.cmd.Wait()
+}
@@ -0,0 +1,130 @@
+package encrypt
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name       string
+		recipients []string
+		wantTool   string
+		wantErr    error
+	}{
+		{"age", []string{"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"}, "age", nil},
+		{"gpg", []string{"ops@example.com", "0xDEADBEEF"}, "gpg", nil},
+		{"mixed", []string{"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p", "ops@example.com"}, "", ErrMixedRecipients},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := New(tt.recipients)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("New() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if _, lookErr := exec.LookPath(tt.wantTool); lookErr != nil {
+				if err == nil {
+					t.Fatalf("New() succeeded without %s installed", tt.wantTool)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() unexpected error = %v", err)
+			}
+			if e.Tool() != tt.wantTool {
+				t.Errorf("Tool() = %q, want %q", e.Tool(), tt.wantTool)
+			}
+		})
+	}
+}
+
+func TestNew_NoRecipients(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Error("Expected an error without recipients")
+	}
+	if _, err := New([]string{" "}); err == nil {
+		t.Error("Expected an error for an empty recipient")
+	}
+}
+
+// gpgKeyring creates a GPG home with a key for test@example.com, skipping the test when gpg
+// is not installed
+func gpgKeyring(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is not installed")
+	}
+
+	home, err := os.MkdirTemp("", "gpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+		os.RemoveAll(home)
+	})
+	t.Setenv("GNUPGHOME", home)
+
+	out, err := exec.Command("gpg", "--batch", "--quiet", "--passphrase", "", "--quick-gen-key", "test@example.com", "default", "default", "never").CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to generate a key: %v: %s", err, out)
+	}
+}
+
+func TestEncrypt_GPG(t *testing.T) {
+	gpgKeyring(t)
+
+	e, err := New([]string{"test@example.com"})
+	if err != nil {
+		t.Fatalf("New() unexpected error = %v", err)
+	}
+
+	var encrypted bytes.Buffer
+	s, err := e.Encrypt(&encrypted)
+	if err != nil {
+		t.Fatalf("Encrypt() unexpected error = %v", err)
+	}
+	plaintext := "# gh-gl-create-refs refs v1\n1,aaaaaaa\n"
+	if _, err := s.Write([]byte(plaintext)); err != nil {
+		t.Fatalf("Write() unexpected error = %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() unexpected error = %v", err)
+	}
+
+	if bytes.Contains(encrypted.Bytes(), []byte("aaaaaaa")) {
+		t.Fatal("Expected the output to be encrypted")
+	}
+
+	decrypt := exec.Command("gpg", "--batch", "--quiet", "--decrypt")
+	decrypt.Stdin = &encrypted
+	decrypted, err := decrypt.Output()
+	if err != nil || string(decrypted) != plaintext {
+		t.Errorf("Decrypted = %q, %v, want %q", decrypted, err, plaintext)
+	}
+}
+
+func TestEncrypt_UnknownRecipient(t *testing.T) {
+	gpgKeyring(t)
+
+	e, err := New([]string{"nobody@example.com"})
+	if err != nil {
+		t.Fatalf("New() unexpected error = %v", err)
+	}
+
+	s, err := e.Encrypt(&bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Encrypt() unexpected error = %v", err)
+	}
+	s.Write([]byte("data"))
+	if err := s.Close(); err == nil {
+		t.Error("Expected Close() to fail for a recipient without a key")
+	}
+}
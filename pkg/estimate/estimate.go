@@ -0,0 +1,133 @@
+// Package estimate predicts how many API requests a fetch and create run makes and roughly
+// how long it takes, from the merge request counts of the projects, so that runs can be
+// fitted into maintenance windows.
+package estimate
+
+import (
+	"time"
+)
+
+// DefaultLatency is the assumed time of one API request, response included
+const DefaultLatency = 250 * time.Millisecond
+
+// projectInfoRequests are the requests of the pre-flight check of each fetched project:
+// the project and its open and total merge request counts
+const projectInfoRequests = 3
+
+// Run describes a fetch followed by a create run
+type Run struct {
+	// MergeRequests holds the number of merge requests of each project
+	MergeRequests []int
+	// PerPage is the number of merge requests per list page
+	PerPage int
+	// Detail fetches each merge request for its diff_refs, as fetch-refs does by default
+	Detail bool
+	// AllVersions also lists the diff versions of each merge request
+	AllVersions bool
+	// VerifySHA looks up each head commit
+	VerifySHA bool
+	// GitHub creates the branches on GitHub, BatchSize per GraphQL request after a request
+	// checking their commits, instead of one GitLab request per branch
+	GitHub    bool
+	BatchSize int
+	// BatchPause is the pause create-refs makes after every PauseEvery branches
+	BatchPause time.Duration
+	PauseEvery int
+	// Concurrency is the number of projects fetched in parallel
+	Concurrency int
+	// RequestsPerSecond and Burst are the client-side GitLab rate limit; zero requests per
+	// second means no limit
+	RequestsPerSecond float64
+	Burst             int
+	// Latency is the time of one request
+	Latency time.Duration
+}
+
+// Estimate is the predicted cost of a run. Requests that depend on data not known in
+// advance, such as the head lookups of merge requests from forks and retries, are not
+// counted, so the numbers are lower bounds.
+type Estimate struct {
+	// FetchRequests are GitLab requests; CreateRequests are GitLab or, with Run.GitHub,
+	// GitHub requests
+	FetchRequests  int
+	CreateRequests int
+	FetchDuration  time.Duration
+	CreateDuration time.Duration
+}
+
+// Total returns the predicted duration of the whole run
+func (e Estimate) Total() time.Duration {
+	return e.FetchDuration + e.CreateDuration
+}
+
+// FetchRequests returns the GitLab requests of fetching a project with mergeRequests merge requests
+func (r Run) FetchRequests(mergeRequests int) int {
+	pages := max(1, (mergeRequests+r.PerPage-1)/r.PerPage)
+	perMergeRequest := 0
+	if r.Detail {
+		perMergeRequest++
+	}
+	if r.AllVersions {
+		perMergeRequest++
+	}
+	if r.VerifySHA {
+		perMergeRequest++
+	}
+	return projectInfoRequests + pages + mergeRequests*perMergeRequest
+}
+
+// CreateRequests returns the requests of creating the branches of a project with
+// mergeRequests merge requests
+func (r Run) CreateRequests(mergeRequests int) int {
+	if !r.GitHub {
+		return mergeRequests
+	}
+	// The repository ID, then a commit check and a creation per batch
+	batches := (mergeRequests + r.BatchSize - 1) / r.BatchSize
+	return 1 + 2*batches
+}
+
+// pauses returns the time create-refs spends in --batch-pause pauses for a project
+func (r Run) pauses(mergeRequests int) time.Duration {
+	if mergeRequests == 0 || r.BatchPause <= 0 {
+		return 0
+	}
+	return time.Duration((mergeRequests-1)/r.PauseEvery) * r.BatchPause
+}
+
+// limited returns how long requests take at the client-side rate limit
+func (r Run) limited(requests int) time.Duration {
+	if r.RequestsPerSecond <= 0 || requests <= r.Burst {
+		return 0
+	}
+	return time.Duration(float64(requests-r.Burst) / r.RequestsPerSecond * float64(time.Second))
+}
+
+// Estimate predicts the requests and duration of the run. Projects are fetched
+// Concurrency at a time, each making its requests one after another, and all share the
+// rate limit. Branches are created one project after another.
+func (r Run) Estimate() Estimate {
+	var e Estimate
+	var longest, sequential time.Duration
+
+	for _, n := range r.MergeRequests {
+		fetch := r.FetchRequests(n)
+		e.FetchRequests += fetch
+		d := time.Duration(fetch) * r.Latency
+		sequential += d
+		longest = max(longest, d)
+
+		create := r.CreateRequests(n)
+		e.CreateRequests += create
+		createDuration := time.Duration(create) * r.Latency
+		if !r.GitHub {
+			// GitHub's quota is enforced from its headers, not by the GitLab limit
+			createDuration = max(createDuration, r.limited(create))
+		}
+		e.CreateDuration += createDuration + r.pauses(n)
+	}
+
+	parallel := sequential / time.Duration(max(1, r.Concurrency))
+	e.FetchDuration = max(longest, parallel, r.limited(e.FetchRequests))
+	return e
+}
@@ -0,0 +1,105 @@
+package estimate
+
+import (
+	"testing"
+	"time"
+)
+
+func baseRun() Run {
+	return Run{
+		PerPage:           100,
+		Detail:            true,
+		BatchSize:         50,
+		PauseEvery:        50,
+		Concurrency:       1,
+		RequestsPerSecond: 10,
+		Burst:             1,
+		Latency:           DefaultLatency,
+	}
+}
+
+func TestFetchRequests(t *testing.T) {
+	tests := []struct {
+		name          string
+		modify        func(*Run)
+		mergeRequests int
+		expected      int
+	}{
+		{"empty project", func(r *Run) {}, 0, 3 + 1},
+		{"detail", func(r *Run) {}, 250, 3 + 3 + 250},
+		{"list only", func(r *Run) { r.Detail = false }, 250, 3 + 3},
+		{"all versions and verify", func(r *Run) { r.AllVersions, r.VerifySHA = true, true }, 100, 3 + 1 + 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := baseRun()
+			tt.modify(&r)
+			if got := r.FetchRequests(tt.mergeRequests); got != tt.expected {
+				t.Errorf("FetchRequests(%d) = %d, want %d", tt.mergeRequests, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCreateRequests(t *testing.T) {
+	r := baseRun()
+	if got := r.CreateRequests(120); got != 120 {
+		t.Errorf("GitLab CreateRequests(120) = %d, want 120", got)
+	}
+
+	r.GitHub = true
+	if got := r.CreateRequests(120); got != 1+2*3 {
+		t.Errorf("GitHub CreateRequests(120) = %d, want 7", got)
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	r := baseRun()
+	r.MergeRequests = []int{1000}
+
+	e := r.Estimate()
+	if e.FetchRequests != 3+10+1000 || e.CreateRequests != 1000 {
+		t.Fatalf("Estimate() requests = %d, %d", e.FetchRequests, e.CreateRequests)
+	}
+	// 1013 requests at 250ms each outlast the 10 per second limit
+	if want := 1013 * DefaultLatency; e.FetchDuration != want {
+		t.Errorf("FetchDuration = %v, want %v", e.FetchDuration, want)
+	}
+	if e.Total() != e.FetchDuration+e.CreateDuration {
+		t.Errorf("Total() = %v", e.Total())
+	}
+}
+
+func TestEstimate_RateLimited(t *testing.T) {
+	r := baseRun()
+	r.MergeRequests = []int{1000, 1000, 1000, 1000}
+	r.Concurrency = 4
+	r.RequestsPerSecond = 2
+
+	e := r.Estimate()
+	// The four projects fetched in parallel share 2 requests per second
+	if want := time.Duration(float64(e.FetchRequests-1) / 2 * float64(time.Second)); e.FetchDuration != want {
+		t.Errorf("FetchDuration = %v, want %v", e.FetchDuration, want)
+	}
+}
+
+func TestEstimate_BatchPause(t *testing.T) {
+	r := baseRun()
+	r.MergeRequests = []int{100}
+	r.RequestsPerSecond = 0
+	r.BatchPause = time.Minute
+
+	e := r.Estimate()
+	// Every 50 branches pause once between them
+	if want := 100*DefaultLatency + time.Minute; e.CreateDuration != want {
+		t.Errorf("CreateDuration = %v, want %v", e.CreateDuration, want)
+	}
+
+	// Pauses follow --pause-every, whatever the GraphQL batch size
+	r.PauseEvery = 25
+	e = r.Estimate()
+	if want := 100*DefaultLatency + 3*time.Minute; e.CreateDuration != want {
+		t.Errorf("CreateDuration = %v, want %v", e.CreateDuration, want)
+	}
+}
@@ -0,0 +1,88 @@
+package export
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// pseudonymPrefix starts every pseudonym, so anonymized usernames are recognizable
+const pseudonymPrefix = "user-"
+
+// Anonymizer removes personal data from export records so they can be shared outside the
+// organization. Usernames become pseudonyms, the same for a given key, so one person's
+// notes and reviews still line up across records and exports. Display names, note bodies
+// and GitHub logins are dropped. Projects, IIDs, SHAs, diff positions and timestamps are kept.
+//
+// The methods of a nil *Anonymizer return records unchanged.
+type Anonymizer struct {
+	key []byte
+}
+
+// NewAnonymizer returns an Anonymizer deriving pseudonyms from key. With an empty key, a
+// random one is used and the pseudonyms only line up within this run.
+func NewAnonymizer(key string) (*Anonymizer, error) {
+	if key != "" {
+		return &Anonymizer{key: []byte(key)}, nil
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	return &Anonymizer{key: random}, nil
+}
+
+// Username returns the pseudonym of a GitLab username, such as user-3f2a9c01b7e4, matching
+// usernames case-insensitively as GitLab does. An empty username stays empty.
+func (a *Anonymizer) Username(username string) string {
+	if a == nil || username == "" {
+		return username
+	}
+
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(strings.ToLower(username)))
+	return pseudonymPrefix + hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+// Discussion returns d with its authors replaced by pseudonyms and its note bodies and
+// display names dropped
+func (a *Anonymizer) Discussion(d gitlab.Discussion) gitlab.Discussion {
+	if a == nil {
+		return d
+	}
+
+	notes := make([]gitlab.Note, len(d.Notes))
+	for i, n := range d.Notes {
+		n.Author = a.Username(n.Author)
+		n.AuthorName = ""
+		n.Body = ""
+		n.ResolvedBy = a.Username(n.ResolvedBy)
+		notes[i] = n
+	}
+	d.Notes = notes
+	return d
+}
+
+// Reviewers returns r with its assignees and reviewers replaced by pseudonyms and their
+// GitHub logins dropped
+func (a *Anonymizer) Reviewers(r ReviewersRecord) ReviewersRecord {
+	if a == nil {
+		return r
+	}
+
+	convert := func(people []User) []User {
+		converted := make([]User, len(people))
+		for i, p := range people {
+			converted[i] = User{GitLab: a.Username(p.GitLab)}
+		}
+		return converted
+	}
+	r.Assignees = convert(r.Assignees)
+	r.Reviewers = convert(r.Reviewers)
+	return r
+}
@@ -0,0 +1,85 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+func TestAnonymizer_Username(t *testing.T) {
+	a, err := NewAnonymizer("shared-key")
+	if err != nil {
+		t.Fatalf("NewAnonymizer failed: %v", err)
+	}
+
+	alice := a.Username("alice")
+	if !strings.HasPrefix(alice, "user-") || len(alice) != len("user-")+12 || strings.Contains(alice, "alice") {
+		t.Errorf("Username(alice) = %q, want a pseudonym", alice)
+	}
+	if got := a.Username("Alice"); got != alice {
+		t.Errorf("Username(Alice) = %q, want %q like alice", got, alice)
+	}
+	if a.Username("bob") == alice {
+		t.Error("Expected different users to get different pseudonyms")
+	}
+	if got := a.Username(""); got != "" {
+		t.Errorf("Username(\"\") = %q, want empty", got)
+	}
+
+	other, _ := NewAnonymizer("other-key")
+	if other.Username("alice") == alice {
+		t.Error("Expected another key to give another pseudonym")
+	}
+
+	var none *Anonymizer
+	if got := none.Username("alice"); got != "alice" {
+		t.Errorf("nil Anonymizer Username() = %q, want alice", got)
+	}
+}
+
+func TestAnonymizer_Discussion(t *testing.T) {
+	a, _ := NewAnonymizer("")
+	d := gitlab.Discussion{ID: "d1", Notes: []gitlab.Note{{
+		ID:         1,
+		Author:     "alice",
+		AuthorName: "Alice Liddell",
+		Body:       "Please ping alice@example.com",
+		Resolved:   true,
+		ResolvedBy: "bob",
+		Position:   &gitlab.NotePosition{HeadSHA: "abc123", NewPath: "main.go", NewLine: 12},
+	}}}
+
+	got := a.Discussion(d)
+	n := got.Notes[0]
+	if n.Author != a.Username("alice") || n.ResolvedBy != a.Username("bob") || n.AuthorName != "" || n.Body != "" {
+		t.Errorf("Discussion() note = %+v, want pseudonyms and no name or body", n)
+	}
+	if got.ID != "d1" || n.ID != 1 || !n.Resolved || n.Position.HeadSHA != "abc123" || n.Position.NewLine != 12 {
+		t.Errorf("Discussion() lost non-personal fields: %+v", n)
+	}
+	if d.Notes[0].Author != "alice" {
+		t.Error("Discussion() modified its argument")
+	}
+}
+
+func TestAnonymizer_Reviewers(t *testing.T) {
+	a, _ := NewAnonymizer("")
+	r := ReviewersRecord{
+		Project:   "group/project",
+		IID:       7,
+		Assignees: []User{{GitLab: "alice", GitHub: "alice-gh"}},
+		Reviewers: []User{{GitLab: "bob"}},
+	}
+
+	got := a.Reviewers(r)
+	if got.Project != "group/project" || got.IID != 7 {
+		t.Errorf("Reviewers() lost the merge request: %+v", got)
+	}
+	if got.Assignees[0] != (User{GitLab: a.Username("alice")}) || got.Reviewers[0] != (User{GitLab: a.Username("bob")}) {
+		t.Errorf("Reviewers() = %+v, want pseudonyms without GitHub logins", got)
+	}
+	if r.Assignees[0].GitLab != "alice" {
+		t.Error("Reviewers() modified its argument")
+	}
+}
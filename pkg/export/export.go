@@ -0,0 +1,98 @@
+// Package export writes merge request data that reference files don't carry, such as
+// discussions, as newline-delimited JSON (NDJSON): one JSON object per line, so that large
+// exports can be streamed, split and replayed line by line.
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// Writer writes records to an NDJSON file. Like the CSV outputs, the file is only put in
+// place, with its checksum file, by Close.
+type Writer struct {
+	file  *csv.AtomicFile
+	buf   *bufio.Writer
+	enc   *json.Encoder
+	count int
+}
+
+// Create starts writing the NDJSON file filename
+func Create(filename string) (*Writer, error) {
+	file, err := csv.CreateAtomic(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bufio.NewWriter(file)
+	enc := json.NewEncoder(buf)
+	// Bodies are Markdown; keep <, > and & readable
+	enc.SetEscapeHTML(false)
+	return &Writer{file: file, buf: buf, enc: enc}, nil
+}
+
+// Write appends record as one line
+func (w *Writer) Write(record any) error {
+	if err := w.enc.Encode(record); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	w.count++
+	return nil
+}
+
+// Count returns the number of records written so far
+func (w *Writer) Count() int {
+	return w.count
+}
+
+// Close completes the file
+func (w *Writer) Close() error {
+	if err := w.buf.Flush(); err != nil {
+		w.file.Abort()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return w.file.Commit()
+}
+
+// Abort discards the file, leaving any earlier export in place
+func (w *Writer) Abort() {
+	w.file.Abort()
+}
+
+// Filename returns the default export file of repository for kind, such as
+// group-project-discussions.ndjson
+func Filename(repository, kind string) string {
+	return strings.TrimSuffix(csv.GenerateFilename(repository), ".csv") + "-" + kind + ".ndjson"
+}
+
+// DiscussionRecord is one line of a discussions export: a discussion thread with the
+// project and merge request it belongs to
+type DiscussionRecord struct {
+	Project string `json:"project"`
+	IID     int    `json:"iid"`
+	gitlab.Discussion
+}
+
+// WithoutSystemNotes removes the notes GitLab writes itself, such as "added 1 commit", and
+// the discussions left without notes
+func WithoutSystemNotes(discussions []gitlab.Discussion) []gitlab.Discussion {
+	var kept []gitlab.Discussion
+	for _, d := range discussions {
+		var notes []gitlab.Note
+		for _, n := range d.Notes {
+			if !n.System {
+				notes = append(notes, n)
+			}
+		}
+		if len(notes) > 0 {
+			d.Notes = notes
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
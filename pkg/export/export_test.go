@@ -0,0 +1,81 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+func TestWriter(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "discussions.ndjson")
+
+	w, err := Create(filename)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	record := DiscussionRecord{
+		Project: "group/project",
+		IID:     1,
+		Discussion: gitlab.Discussion{
+			ID:    "d1",
+			Notes: []gitlab.Note{{ID: 1, Author: "alice", Body: "a <b> & c", CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}},
+		},
+	}
+	if err := w.Write(record); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if _, err := os.Stat(filename); err == nil {
+		t.Error("Expected the file to appear only on Close")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"project":"group/project","iid":1,"discussion_id":"d1","individual_note":false,"notes":[{"id":1,"author":"alice","body":"a <b> & c","created_at":"2024-01-01T00:00:00Z"}]}` + "\n"
+	if string(data) != expected {
+		t.Errorf("File = %s, want %s", data, expected)
+	}
+	if _, err := os.Stat(filename + ".sha256"); err != nil {
+		t.Errorf("Expected a checksum file: %v", err)
+	}
+}
+
+func TestWriter_Abort(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "discussions.ndjson")
+
+	w, err := Create(filename)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	w.Write(DiscussionRecord{Project: "group/project", IID: 1})
+	w.Abort()
+
+	if entries, _ := os.ReadDir(filepath.Dir(filename)); len(entries) != 0 {
+		t.Errorf("Expected no files after Abort, got %v", entries)
+	}
+}
+
+func TestFilename(t *testing.T) {
+	if got := Filename("https://gitlab.com/group/sub/project.git", "discussions"); got != "group-sub-project-discussions.ndjson" {
+		t.Errorf("Filename() = %q", got)
+	}
+}
+
+func TestWithoutSystemNotes(t *testing.T) {
+	discussions := []gitlab.Discussion{
+		{ID: "system", Notes: []gitlab.Note{{Body: "added 1 commit", System: true}}},
+		{ID: "mixed", Notes: []gitlab.Note{{Body: "LGTM"}, {Body: "resolved all threads", System: true}}},
+	}
+
+	kept := WithoutSystemNotes(discussions)
+	if len(kept) != 1 || kept[0].ID != "mixed" || len(kept[0].Notes) != 1 || kept[0].Notes[0].Body != "LGTM" {
+		t.Errorf("WithoutSystemNotes() = %+v", kept)
+	}
+}
@@ -0,0 +1,57 @@
+package export
+
+import (
+	"github.com/amenocal/gh-gl-create-refs/pkg/mapping"
+	gogitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// User is a person named by a merge request: their GitLab username and, when the user
+// mapping lists them, their GitHub login
+type User struct {
+	GitLab string `json:"gitlab"`
+	GitHub string `json:"github,omitempty"`
+}
+
+// ReviewersRecord is one line of a reviewers export: the assignees and reviewers of a
+// merge request
+type ReviewersRecord struct {
+	Project   string `json:"project"`
+	IID       int    `json:"iid"`
+	State     string `json:"state,omitempty"`
+	Assignees []User `json:"assignees"`
+	Reviewers []User `json:"reviewers"`
+}
+
+// NewReviewersRecord builds the record of mr, listed from project, translating usernames
+// with users, which may be nil. It also returns the usernames users does not list.
+func NewReviewersRecord(project string, mr *gogitlab.BasicMergeRequest, users mapping.Users) (ReviewersRecord, []string) {
+	record := ReviewersRecord{Project: project, IID: mr.IID, Assignees: []User{}, Reviewers: []User{}}
+
+	var unmapped []string
+	convert := func(people []*gogitlab.BasicUser) []User {
+		converted := []User{}
+		for _, p := range people {
+			if p == nil {
+				continue
+			}
+			user := User{GitLab: p.Username}
+			if login, ok := users.Login(p.Username); ok {
+				user.GitHub = login
+			} else if users != nil {
+				unmapped = append(unmapped, p.Username)
+			}
+			converted = append(converted, user)
+		}
+		return converted
+	}
+
+	record.State = mr.State
+	assignees := mr.Assignees
+	if len(assignees) == 0 && mr.Assignee != nil {
+		// Older GitLab versions only report a single assignee
+		assignees = []*gogitlab.BasicUser{mr.Assignee}
+	}
+	record.Assignees = convert(assignees)
+	record.Reviewers = convert(mr.Reviewers)
+	return record, unmapped
+}
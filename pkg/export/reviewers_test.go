@@ -0,0 +1,52 @@
+package export
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/mapping"
+	gogitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestNewReviewersRecord(t *testing.T) {
+	mr := &gogitlab.BasicMergeRequest{
+		IID:       7,
+		State:     "opened",
+		Assignees: []*gogitlab.BasicUser{{Username: "Alice"}},
+		Reviewers: []*gogitlab.BasicUser{{Username: "bob"}, {Username: "carol"}},
+	}
+	users := mapping.Users{"alice": "alice-gh", "bob": "bobby"}
+
+	record, unmapped := NewReviewersRecord("group/project", mr, users)
+
+	expected := ReviewersRecord{
+		Project:   "group/project",
+		IID:       7,
+		State:     "opened",
+		Assignees: []User{{GitLab: "Alice", GitHub: "alice-gh"}},
+		Reviewers: []User{{GitLab: "bob", GitHub: "bobby"}, {GitLab: "carol"}},
+	}
+	if record.Project != expected.Project || record.IID != expected.IID || record.State != expected.State ||
+		!slices.Equal(record.Assignees, expected.Assignees) || !slices.Equal(record.Reviewers, expected.Reviewers) {
+		t.Errorf("NewReviewersRecord() = %+v, want %+v", record, expected)
+	}
+	if !slices.Equal(unmapped, []string{"carol"}) {
+		t.Errorf("unmapped = %v, want [carol]", unmapped)
+	}
+}
+
+func TestNewReviewersRecord_WithoutMapping(t *testing.T) {
+	mr := &gogitlab.BasicMergeRequest{
+		IID: 7,
+		// Older GitLab versions only set the single assignee
+		Assignee: &gogitlab.BasicUser{Username: "alice"},
+	}
+
+	record, unmapped := NewReviewersRecord("group/project", mr, nil)
+	if !slices.Equal(record.Assignees, []User{{GitLab: "alice"}}) || len(record.Reviewers) != 0 {
+		t.Errorf("NewReviewersRecord() = %+v", record)
+	}
+	if len(unmapped) != 0 {
+		t.Errorf("Expected no unmapped users without a mapping, got %v", unmapped)
+	}
+}
@@ -0,0 +1,230 @@
+// Package github creates Git references in GitHub repositories through the GraphQL API,
+// packing many createRef mutations into each request.
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/ratelimit"
+	"github.com/amenocal/gh-gl-create-refs/pkg/trace"
+)
+
+// Errors returned by the client. Test for them with errors.Is.
+var (
+	// ErrRepositoryNotFound means the repository does not exist or is not visible to the token
+	ErrRepositoryNotFound = errors.New("repository not found")
+	// ErrUnauthorized means the token is invalid, expired or lacks access (401 or 403)
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrRateLimited means GitHub rejected the request because a rate limit was exceeded
+	ErrRateLimited = errors.New("rate limited")
+	// ErrRefExists means a reference with the requested name already exists
+	ErrRefExists = errors.New("reference already exists")
+	// ErrIssueNotFound means the issue or pull request does not exist
+	ErrIssueNotFound = errors.New("issue not found")
+)
+
+// DefaultHost is the GitHub host used when none is given
+const DefaultHost = "github.com"
+
+// Client calls the GitHub GraphQL API
+type Client struct {
+	httpClient *http.Client
+	endpoint   string
+	token      string
+	userAgent  string
+	limiter    ratelimit.Limiter
+	trace      *slog.Logger
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithRateLimiter paces requests with limiter, for example the bucket of the host shared
+// with other clients. By default requests are not paced, but GitHub's rate limit headers
+// and Retry-After are still honoured.
+func WithRateLimiter(limiter ratelimit.Limiter) Option {
+	return func(c *Client) {
+		c.limiter = limiter
+	}
+}
+
+// WithTrace logs every HTTP request to logger at debug level with its status and rate limit
+// headers. The token is never logged.
+func WithTrace(logger *slog.Logger) Option {
+	return func(c *Client) {
+		c.trace = logger
+	}
+}
+
+// WithEndpoint overrides the GraphQL endpoint derived from the host
+func WithEndpoint(endpoint string) Option {
+	return func(c *Client) {
+		c.endpoint = endpoint
+	}
+}
+
+// GraphQLEndpoint returns the GraphQL endpoint of a GitHub host: api.github.com for
+// github.com, and /api/graphql on the host for GitHub Enterprise Server
+func GraphQLEndpoint(host string) string {
+	if host == "" || host == DefaultHost {
+		return "https://api.github.com/graphql"
+	}
+	return fmt.Sprintf("https://%s/api/graphql", host)
+}
+
+// ParseBaseURL returns the host and GraphQL endpoint of a GitHub base URL such as
+// https://github.example.com. URLs without a scheme use https, paths such as /api/v3 are
+// ignored, and an empty base URL means github.com.
+func ParseBaseURL(baseURL string) (string, string, error) {
+	if baseURL == "" {
+		return DefaultHost, GraphQLEndpoint(DefaultHost), nil
+	}
+
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "https://" + baseURL
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return "", "", fmt.Errorf("invalid GitHub base URL: %s", baseURL)
+	}
+
+	host := strings.ToLower(u.Host)
+	if host == DefaultHost || host == "api."+DefaultHost {
+		return DefaultHost, GraphQLEndpoint(DefaultHost), nil
+	}
+
+	return host, fmt.Sprintf("%s://%s/api/graphql", u.Scheme, host), nil
+}
+
+// NewClient creates a client for a GitHub host authenticated with token
+func NewClient(token, host string, options ...Option) (*Client, error) {
+	if token == "" {
+		return nil, fmt.Errorf("failed to create GitHub client: %w: no token", ErrUnauthorized)
+	}
+
+	c := &Client{
+		httpClient: http.DefaultClient,
+		endpoint:   GraphQLEndpoint(host),
+		token:      token,
+	}
+	for _, option := range options {
+		option(c)
+	}
+
+	if c.limiter == nil {
+		c.limiter = ratelimit.NewTokenBucket(0, 1, slog.Default())
+	}
+
+	// Requests wait for the limiter and are retried after secondary rate limits
+	httpClient := *c.httpClient
+	if c.trace != nil {
+		httpClient.Transport = trace.NewTransport(httpClient.Transport, c.trace)
+	}
+	httpClient.Transport = ratelimit.NewTransport(httpClient.Transport, c.limiter)
+	c.httpClient = &httpClient
+
+	return c, nil
+}
+
+// graphqlError is an entry of the errors list of a GraphQL response
+type graphqlError struct {
+	Type    string   `json:"type"`
+	Message string   `json:"message"`
+	Path    []string `json:"path"`
+}
+
+// graphqlResponse is the body of a GraphQL response; Data is set for the fields that succeeded
+type graphqlResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []graphqlError             `json:"errors"`
+}
+
+// do sends a GraphQL request. Errors of individual fields are returned in the response;
+// only failures of the whole request are returned as an error.
+func (c *Client) do(ctx context.Context, query string, variables map[string]any) (*graphqlResponse, error) {
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode GraphQL request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GraphQL request: %w", err)
+	}
+	req.Header.Set("Authorization", "bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GraphQL request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0":
+		return nil, fmt.Errorf("GraphQL request failed with status %d: %w", resp.StatusCode, ErrRateLimited)
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		return nil, fmt.Errorf("GraphQL request failed with status %d: %w", resp.StatusCode, ErrUnauthorized)
+	case resp.StatusCode != http.StatusOK:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("GraphQL request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var result graphqlResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode GraphQL response: %w", err)
+	}
+
+	// Errors without a path concern the whole request
+	for _, e := range result.Errors {
+		if len(e.Path) == 0 {
+			return nil, classifyError(e)
+		}
+	}
+
+	return &result, nil
+}
+
+// classifyError converts a GraphQL error into an error wrapping the matching sentinel
+func classifyError(e graphqlError) error {
+	switch {
+	case e.Type == "RATE_LIMITED":
+		return fmt.Errorf("%s: %w", e.Message, ErrRateLimited)
+	case e.Type == "NOT_FOUND":
+		return fmt.Errorf("%s: %w", e.Message, ErrRepositoryNotFound)
+	case e.Type == "FORBIDDEN":
+		return fmt.Errorf("%s: %w", e.Message, ErrUnauthorized)
+	case strings.Contains(e.Message, "already exists"):
+		return fmt.Errorf("%s: %w", e.Message, ErrRefExists)
+	default:
+		return errors.New(e.Message)
+	}
+}
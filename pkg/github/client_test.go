@@ -0,0 +1,177 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/ratelimit"
+)
+
+func TestGraphQLEndpoint(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{"", "https://api.github.com/graphql"},
+		{"github.com", "https://api.github.com/graphql"},
+		{"github.example.com", "https://github.example.com/api/graphql"},
+	}
+
+	for _, tt := range tests {
+		if got := GraphQLEndpoint(tt.host); got != tt.expected {
+			t.Errorf("GraphQLEndpoint(%q) = %q, want %q", tt.host, got, tt.expected)
+		}
+	}
+}
+
+func TestParseBaseURL(t *testing.T) {
+	tests := []struct {
+		name             string
+		baseURL          string
+		expectedHost     string
+		expectedEndpoint string
+		expectError      bool
+	}{
+		{"empty uses github.com", "", "github.com", "https://api.github.com/graphql", false},
+		{"github.com", "https://github.com", "github.com", "https://api.github.com/graphql", false},
+		{"api.github.com", "https://api.github.com/", "github.com", "https://api.github.com/graphql", false},
+		{"enterprise server", "https://GHES.example.com", "ghes.example.com", "https://ghes.example.com/api/graphql", false},
+		{"enterprise server REST path", "https://ghes.example.com/api/v3", "ghes.example.com", "https://ghes.example.com/api/graphql", false},
+		{"without scheme", "ghes.example.com", "ghes.example.com", "https://ghes.example.com/api/graphql", false},
+		{"http with port", "http://localhost:8080", "localhost:8080", "http://localhost:8080/api/graphql", false},
+		{"invalid", "https://", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, endpoint, err := ParseBaseURL(tt.baseURL)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("ParseBaseURL(%q) expected error, got nil", tt.baseURL)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseBaseURL(%q) unexpected error = %v", tt.baseURL, err)
+			}
+			if host != tt.expectedHost || endpoint != tt.expectedEndpoint {
+				t.Errorf("ParseBaseURL(%q) = %q, %q; want %q, %q", tt.baseURL, host, endpoint, tt.expectedHost, tt.expectedEndpoint)
+			}
+		})
+	}
+}
+
+func TestNewClient_RequiresToken(t *testing.T) {
+	if _, err := NewClient("", ""); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("NewClient() error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestClient_Do(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		headers  map[string]string
+		body     string
+		expected error
+	}{
+		{
+			name:     "unauthorized",
+			status:   http.StatusUnauthorized,
+			expected: ErrUnauthorized,
+		},
+		{
+			name:     "forbidden",
+			status:   http.StatusForbidden,
+			expected: ErrUnauthorized,
+		},
+		{
+			name:     "secondary rate limit",
+			status:   http.StatusForbidden,
+			headers:  map[string]string{"X-RateLimit-Remaining": "0"},
+			expected: ErrRateLimited,
+		},
+		{
+			name:     "rate limit error",
+			status:   http.StatusOK,
+			body:     `{"errors":[{"type":"RATE_LIMITED","message":"API rate limit exceeded"}]}`,
+			expected: ErrRateLimited,
+		},
+		{
+			name:   "success",
+			status: http.StatusOK,
+			body:   `{"data":{"viewer":{"login":"octocat"}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != "bearer secret" {
+					t.Errorf("Authorization = %q, want bearer secret", got)
+				}
+				if got := r.Header.Get("User-Agent"); got != "test-agent" {
+					t.Errorf("User-Agent = %q, want test-agent", got)
+				}
+				for name, value := range tt.headers {
+					w.Header().Set(name, value)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client, err := NewClient("secret", "", WithEndpoint(server.URL), WithUserAgent("test-agent"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = client.do(context.Background(), "query { viewer { login } }", nil)
+			if tt.expected == nil {
+				if err != nil {
+					t.Errorf("do() unexpected error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("do() error = %v, want %v", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestClient_RetriesAfterRetryAfter(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "viewer") {
+			t.Errorf("retried request lost its body: %q", body)
+		}
+		w.Write([]byte(`{"data":{"viewer":{"login":"octocat"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("secret", "", WithEndpoint(server.URL), WithRateLimiter(ratelimit.NewTokenBucket(0, 1, slog.New(slog.DiscardHandler))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.do(context.Background(), "query { viewer { login } }", nil); err != nil {
+		t.Fatalf("do() unexpected error = %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+}
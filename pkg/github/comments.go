@@ -0,0 +1,105 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// IssueRef identifies an issue or pull request
+type IssueRef struct {
+	// Repository is the owner/name repository
+	Repository string
+	Number     int
+}
+
+func (r IssueRef) String() string {
+	return fmt.Sprintf("%s#%d", r.Repository, r.Number)
+}
+
+// ParseIssueRef parses an issue or pull request given as owner/name#number or as its URL,
+// such as https://github.com/owner/name/issues/12 or .../pull/12
+func ParseIssueRef(s string) (IssueRef, error) {
+	repository, number, ok := strings.Cut(s, "#")
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return IssueRef{}, fmt.Errorf("invalid issue %q: %w", s, err)
+		}
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(parts) != 4 || (parts[2] != "issues" && parts[2] != "pull") {
+			return IssueRef{}, fmt.Errorf("invalid issue URL %q: expected .../owner/name/issues/number", s)
+		}
+		repository, number, ok = parts[0]+"/"+parts[1], parts[3], true
+	}
+
+	if !ok {
+		return IssueRef{}, fmt.Errorf("invalid issue %q: expected owner/name#number", s)
+	}
+	if _, _, err := splitRepository(repository); err != nil {
+		return IssueRef{}, err
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil || n <= 0 {
+		return IssueRef{}, fmt.Errorf("invalid issue number in %q", s)
+	}
+
+	return IssueRef{Repository: repository, Number: n}, nil
+}
+
+// AddComment comments on an issue or pull request and returns the comment's URL
+func (c *Client) AddComment(ctx context.Context, issue IssueRef, body string) (string, error) {
+	owner, name, err := splitRepository(issue.Repository)
+	if err != nil {
+		return "", err
+	}
+
+	const lookup = `query($owner: String!, $name: String!, $number: Int!) { repository(owner: $owner, name: $name) { issueOrPullRequest(number: $number) { ... on Issue { id } ... on PullRequest { id } } } }`
+	resp, err := c.do(ctx, lookup, map[string]any{"owner": owner, "name": name, "number": issue.Number})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up %s: %w", issue, err)
+	}
+	if len(resp.Errors) > 0 {
+		return "", fmt.Errorf("failed to look up %s: %w", issue, classifyError(resp.Errors[0]))
+	}
+
+	var repo *struct {
+		Subject *struct {
+			ID string `json:"id"`
+		} `json:"issueOrPullRequest"`
+	}
+	if err := json.Unmarshal(resp.Data["repository"], &repo); err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", issue, err)
+	}
+	if repo == nil || repo.Subject == nil || repo.Subject.ID == "" {
+		return "", fmt.Errorf("%s: %w", issue, ErrIssueNotFound)
+	}
+
+	const mutation = `mutation($subjectId: ID!, $body: String!) { addComment(input: {subjectId: $subjectId, body: $body}) { commentEdge { node { url } } } }`
+	resp, err = c.do(ctx, mutation, map[string]any{"subjectId": repo.Subject.ID, "body": body})
+	if err != nil {
+		return "", fmt.Errorf("failed to comment on %s: %w", issue, err)
+	}
+	if len(resp.Errors) > 0 {
+		return "", fmt.Errorf("failed to comment on %s: %w", issue, classifyError(resp.Errors[0]))
+	}
+
+	var result *struct {
+		CommentEdge struct {
+			Node struct {
+				URL string `json:"url"`
+			} `json:"node"`
+		} `json:"commentEdge"`
+	}
+	if err := json.Unmarshal(resp.Data["addComment"], &result); err != nil {
+		return "", fmt.Errorf("failed to decode comment on %s: %w", issue, err)
+	}
+	if result == nil {
+		return "", fmt.Errorf("failed to comment on %s: no comment returned", issue)
+	}
+
+	return result.CommentEdge.Node.URL, nil
+}
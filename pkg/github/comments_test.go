@@ -0,0 +1,69 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseIssueRef(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    IssueRef
+		expectError bool
+	}{
+		{input: "octo/war-room#12", expected: IssueRef{Repository: "octo/war-room", Number: 12}},
+		{input: "https://github.com/octo/war-room/issues/12", expected: IssueRef{Repository: "octo/war-room", Number: 12}},
+		{input: "https://ghes.example.com/octo/repo/pull/7/", expected: IssueRef{Repository: "octo/repo", Number: 7}},
+		{input: "octo/war-room", expectError: true},
+		{input: "octo#12", expectError: true},
+		{input: "octo/war-room#0", expectError: true},
+		{input: "https://github.com/octo/war-room/discussions/3", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseIssueRef(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("ParseIssueRef(%q) expected error, got %v", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseIssueRef(%q) unexpected error = %v", tt.input, err)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseIssueRef(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestClient_AddComment(t *testing.T) {
+	client, _ := newTestClient(t, func(req graphqlRequest) string {
+		if strings.HasPrefix(req.Query, "query") {
+			if req.Variables["number"] == float64(12) {
+				return `{"data":{"repository":{"issueOrPullRequest":{"id":"I_12"}}}}`
+			}
+			return `{"data":{"repository":{"issueOrPullRequest":null}}}`
+		}
+		if req.Variables["subjectId"] != "I_12" || req.Variables["body"] != "summary" {
+			t.Errorf("unexpected variables %v", req.Variables)
+		}
+		return `{"data":{"addComment":{"commentEdge":{"node":{"url":"https://github.com/octo/war-room/issues/12#issuecomment-1"}}}}}`
+	})
+
+	url, err := client.AddComment(context.Background(), IssueRef{Repository: "octo/war-room", Number: 12}, "summary")
+	if err != nil {
+		t.Fatalf("AddComment() unexpected error = %v", err)
+	}
+	if url != "https://github.com/octo/war-room/issues/12#issuecomment-1" {
+		t.Errorf("AddComment() = %q", url)
+	}
+
+	if _, err := client.AddComment(context.Background(), IssueRef{Repository: "octo/war-room", Number: 13}, "summary"); !errors.Is(err, ErrIssueNotFound) {
+		t.Errorf("AddComment() error = %v, want ErrIssueNotFound", err)
+	}
+}
@@ -0,0 +1,38 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// CreateIssue opens an issue in an owner/name repository and returns its URL
+func (c *Client) CreateIssue(ctx context.Context, repository, title, body string) (string, error) {
+	repositoryID, err := c.RepositoryID(ctx, repository)
+	if err != nil {
+		return "", err
+	}
+
+	const query = `mutation($repositoryId: ID!, $title: String!, $body: String!) { createIssue(input: {repositoryId: $repositoryId, title: $title, body: $body}) { issue { url } } }`
+	resp, err := c.do(ctx, query, map[string]any{"repositoryId": repositoryID, "title": title, "body": body})
+	if err != nil {
+		return "", fmt.Errorf("failed to create issue in %s: %w", repository, err)
+	}
+	if len(resp.Errors) > 0 {
+		return "", fmt.Errorf("failed to create issue in %s: %w", repository, classifyError(resp.Errors[0]))
+	}
+
+	var result *struct {
+		Issue struct {
+			URL string `json:"url"`
+		} `json:"issue"`
+	}
+	if err := json.Unmarshal(resp.Data["createIssue"], &result); err != nil {
+		return "", fmt.Errorf("failed to decode issue of %s: %w", repository, err)
+	}
+	if result == nil || result.Issue.URL == "" {
+		return "", fmt.Errorf("failed to create issue in %s: no issue returned", repository)
+	}
+
+	return result.Issue.URL, nil
+}
@@ -0,0 +1,42 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestClient_CreateIssue(t *testing.T) {
+	client, requests := newTestClient(t, func(req graphqlRequest) string {
+		if strings.HasPrefix(req.Query, "query") {
+			if req.Variables["name"] == "locked" {
+				return `{"data":{"repository":{"id":"R_locked"}}}`
+			}
+			return `{"data":{"repository":{"id":"R_123"}}}`
+		}
+
+		if req.Variables["repositoryId"] == "R_locked" {
+			return `{"data":{"createIssue":null},"errors":[{"type":"FORBIDDEN","path":["createIssue"],"message":"Issues are disabled"}]}`
+		}
+		if req.Variables["title"] != "Broken refs" || req.Variables["body"] != "details" {
+			t.Errorf("unexpected variables %v", req.Variables)
+		}
+		return `{"data":{"createIssue":{"issue":{"url":"https://github.com/octo/repo/issues/7"}}}}`
+	})
+
+	url, err := client.CreateIssue(context.Background(), "octo/repo", "Broken refs", "details")
+	if err != nil {
+		t.Fatalf("CreateIssue() unexpected error = %v", err)
+	}
+	if url != "https://github.com/octo/repo/issues/7" {
+		t.Errorf("CreateIssue() = %q", url)
+	}
+	if *requests != 2 {
+		t.Errorf("CreateIssue() made %d requests, want 2", *requests)
+	}
+
+	if _, err := client.CreateIssue(context.Background(), "octo/locked", "Broken refs", "details"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("CreateIssue() error = %v, want ErrUnauthorized", err)
+	}
+}
@@ -0,0 +1,81 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+)
+
+// pullRequestPageSize is how many pull requests are requested per page, GitHub's maximum
+const pullRequestPageSize = 100
+
+// PullRequest is a pull request's number and the commit its head points to
+type PullRequest struct {
+	Number  int
+	HeadSHA string
+	// State is OPEN, CLOSED or MERGED
+	State string
+}
+
+// PullRequests returns an iterator over the pull requests of an owner/name repository, in
+// creation order. Pages of 100 are requested lazily with a paginated GraphQL query, one
+// request per page instead of one per pull request, so breaking out of the loop stops
+// further requests. A request error is yielded once and ends the iteration.
+func (c *Client) PullRequests(ctx context.Context, repository string) iter.Seq2[PullRequest, error] {
+	return func(yield func(PullRequest, error) bool) {
+		owner, name, err := splitRepository(repository)
+		if err != nil {
+			yield(PullRequest{}, err)
+			return
+		}
+
+		const query = `query($owner: String!, $name: String!, $first: Int!, $after: String) { repository(owner: $owner, name: $name) { pullRequests(first: $first, after: $after, orderBy: {field: CREATED_AT, direction: ASC}) { nodes { number headRefOid state } pageInfo { hasNextPage endCursor } } } }`
+		variables := map[string]any{"owner": owner, "name": name, "first": pullRequestPageSize, "after": nil}
+
+		for {
+			resp, err := c.do(ctx, query, variables)
+			if err != nil {
+				yield(PullRequest{}, fmt.Errorf("failed to list pull requests of %s: %w", repository, err))
+				return
+			}
+			if len(resp.Errors) > 0 {
+				yield(PullRequest{}, fmt.Errorf("failed to list pull requests of %s: %w", repository, classifyError(resp.Errors[0])))
+				return
+			}
+
+			var repo *struct {
+				PullRequests struct {
+					Nodes []struct {
+						Number     int    `json:"number"`
+						HeadRefOid string `json:"headRefOid"`
+						State      string `json:"state"`
+					} `json:"nodes"`
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+				} `json:"pullRequests"`
+			}
+			if err := json.Unmarshal(resp.Data["repository"], &repo); err != nil {
+				yield(PullRequest{}, fmt.Errorf("failed to decode pull requests of %s: %w", repository, err))
+				return
+			}
+			if repo == nil {
+				yield(PullRequest{}, fmt.Errorf("failed to list pull requests of %s: %w", repository, ErrRepositoryNotFound))
+				return
+			}
+
+			for _, pr := range repo.PullRequests.Nodes {
+				if !yield(PullRequest{Number: pr.Number, HeadSHA: pr.HeadRefOid, State: pr.State}, nil) {
+					return
+				}
+			}
+
+			if !repo.PullRequests.PageInfo.HasNextPage {
+				return
+			}
+			variables["after"] = repo.PullRequests.PageInfo.EndCursor
+		}
+	}
+}
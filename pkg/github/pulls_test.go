@@ -0,0 +1,58 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClient_PullRequests(t *testing.T) {
+	client, requests := newTestClient(t, func(req graphqlRequest) string {
+		if req.Variables["name"] == "missing" {
+			return `{"data":{"repository":null},"errors":[{"type":"NOT_FOUND","path":["repository"],"message":"Could not resolve to a Repository"}]}`
+		}
+		if req.Variables["first"] != float64(pullRequestPageSize) {
+			t.Errorf("first = %v, want %d", req.Variables["first"], pullRequestPageSize)
+		}
+		switch req.Variables["after"] {
+		case nil:
+			return `{"data":{"repository":{"pullRequests":{"nodes":[{"number":1,"headRefOid":"sha1","state":"MERGED"},{"number":2,"headRefOid":"sha2","state":"OPEN"}],"pageInfo":{"hasNextPage":true,"endCursor":"c2"}}}}}`
+		case "c2":
+			return `{"data":{"repository":{"pullRequests":{"nodes":[{"number":3,"headRefOid":"sha3","state":"CLOSED"}],"pageInfo":{"hasNextPage":false,"endCursor":"c3"}}}}}`
+		}
+		t.Errorf("unexpected cursor %v", req.Variables["after"])
+		return `{}`
+	})
+
+	var got []PullRequest
+	for pr, err := range client.PullRequests(context.Background(), "octo/repo") {
+		if err != nil {
+			t.Fatalf("PullRequests() unexpected error = %v", err)
+		}
+		got = append(got, pr)
+	}
+
+	want := []PullRequest{{1, "sha1", "MERGED"}, {2, "sha2", "OPEN"}, {3, "sha3", "CLOSED"}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("PullRequests() = %v, want %v", got, want)
+	}
+	if *requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", *requests)
+	}
+
+	// Breaking out of the loop stops paging
+	*requests = 0
+	for range client.PullRequests(context.Background(), "octo/repo") {
+		break
+	}
+	if *requests != 1 {
+		t.Errorf("Expected 1 request after breaking early, got %d", *requests)
+	}
+
+	for _, err := range client.PullRequests(context.Background(), "octo/missing") {
+		if !errors.Is(err, ErrRepositoryNotFound) {
+			t.Errorf("PullRequests() error = %v, want ErrRepositoryNotFound", err)
+		}
+	}
+}
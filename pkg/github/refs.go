@@ -0,0 +1,321 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"strconv"
+	"strings"
+)
+
+// DefaultBatchSize is how many references CreateRefs is typically given per request;
+// GitHub limits the cost of a single GraphQL request, so much larger batches can be rejected
+const DefaultBatchSize = 50
+
+// Ref is a Git reference to create: a fully qualified name such as refs/heads/main and
+// the commit SHA it points to
+type Ref struct {
+	Name string
+	SHA  string
+}
+
+// BranchRef returns the reference for a branch
+func BranchRef(branch, sha string) Ref {
+	return Ref{Name: "refs/heads/" + branch, SHA: sha}
+}
+
+// TagRef returns the reference for a lightweight tag
+func TagRef(tag, sha string) Ref {
+	return Ref{Name: "refs/tags/" + tag, SHA: sha}
+}
+
+// RefTarget is a reference found in a repository: its GraphQL node ID and the commit it
+// points to
+type RefTarget struct {
+	ID  string
+	SHA string
+}
+
+// RefUpdate moves the existing reference with node ID to Ref.SHA
+type RefUpdate struct {
+	ID string
+	Ref
+}
+
+// API is the set of GitHub operations the commands rely on. *Client implements it.
+type API interface {
+	// RepositoryID returns the GraphQL node ID of an owner/name repository
+	RepositoryID(ctx context.Context, repository string) (string, error)
+	// CreateRefs creates refs in a repository with a single request
+	CreateRefs(ctx context.Context, repositoryID string, refs []Ref) ([]error, error)
+	// CommitsExist reports which of shas are commits in an owner/name repository, with a single request
+	CommitsExist(ctx context.Context, repository string, shas []string) ([]bool, error)
+	// RefTargets looks up which of names exist in an owner/name repository, with a single request
+	RefTargets(ctx context.Context, repository string, names []string) ([]*RefTarget, error)
+	// UpdateRefs moves existing refs to new commits with a single request
+	UpdateRefs(ctx context.Context, updates []RefUpdate) ([]error, error)
+	// PullRequests iterates over the pull requests of an owner/name repository, a page of
+	// them per request
+	PullRequests(ctx context.Context, repository string) iter.Seq2[PullRequest, error]
+	// CreateIssue opens an issue in an owner/name repository and returns its URL
+	CreateIssue(ctx context.Context, repository, title, body string) (string, error)
+	// AddComment comments on an issue or pull request and returns the comment's URL
+	AddComment(ctx context.Context, issue IssueRef, body string) (string, error)
+}
+
+var _ API = (*Client)(nil)
+
+// RepositoryID returns the GraphQL node ID of an owner/name repository
+func (c *Client) RepositoryID(ctx context.Context, repository string) (string, error) {
+	owner, name, err := splitRepository(repository)
+	if err != nil {
+		return "", err
+	}
+
+	const query = `query($owner: String!, $name: String!) { repository(owner: $owner, name: $name) { id } }`
+	resp, err := c.do(ctx, query, map[string]any{"owner": owner, "name": name})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up repository %s: %w", repository, err)
+	}
+	if len(resp.Errors) > 0 {
+		return "", fmt.Errorf("failed to look up repository %s: %w", repository, classifyError(resp.Errors[0]))
+	}
+
+	var repo *struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(resp.Data["repository"], &repo); err != nil {
+		return "", fmt.Errorf("failed to decode repository %s: %w", repository, err)
+	}
+	if repo == nil || repo.ID == "" {
+		return "", fmt.Errorf("repository %s: %w", repository, ErrRepositoryNotFound)
+	}
+
+	return repo.ID, nil
+}
+
+// splitRepository splits an owner/name repository
+func splitRepository(repository string) (string, string, error) {
+	owner, name, ok := strings.Cut(repository, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", fmt.Errorf("invalid GitHub repository %q: expected owner/name", repository)
+	}
+	return owner, name, nil
+}
+
+// CommitsExist reports, for each of shas in order, whether it is a commit in an owner/name
+// repository. All SHAs are looked up with one request of aliased object queries, so refs
+// can be checked before creating them instead of failing one by one.
+func (c *Client) CommitsExist(ctx context.Context, repository string, shas []string) ([]bool, error) {
+	owner, name, err := splitRepository(repository)
+	if err != nil {
+		return nil, err
+	}
+	if len(shas) == 0 {
+		return nil, nil
+	}
+
+	var params, fields strings.Builder
+	variables := map[string]any{"owner": owner, "name": name}
+
+	params.WriteString("$owner: String!, $name: String!")
+	for i, sha := range shas {
+		fmt.Fprintf(&params, ", $sha%d: String!", i)
+		fmt.Fprintf(&fields, " %s: object(expression: $sha%d) { __typename }", commitAlias(i), i)
+		variables["sha"+strconv.Itoa(i)] = sha
+	}
+	query := fmt.Sprintf("query(%s) { repository(owner: $owner, name: $name) {%s } }", params.String(), fields.String())
+
+	resp, err := c.do(ctx, query, variables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up commits in %s: %w", repository, err)
+	}
+
+	var objects map[string]*struct {
+		Typename string `json:"__typename"`
+	}
+	if err := json.Unmarshal(resp.Data["repository"], &objects); err != nil {
+		return nil, fmt.Errorf("failed to decode commits of %s: %w", repository, err)
+	}
+	if objects == nil {
+		return nil, fmt.Errorf("failed to look up commits in %s: %w", repository, ErrRepositoryNotFound)
+	}
+
+	exists := make([]bool, len(shas))
+	for i := range shas {
+		object := objects[commitAlias(i)]
+		exists[i] = object != nil && object.Typename == "Commit"
+	}
+	return exists, nil
+}
+
+// commitAlias is the name of the i-th object query of a batch
+func commitAlias(i int) string {
+	return "commit" + strconv.Itoa(i)
+}
+
+// CreateRefs creates refs in a repository with a single request of aliased createRef
+// mutations, one round-trip for the whole batch instead of one per reference. It returns
+// the outcome of each reference, in order (nil when it was created), or an error when the
+// request as a whole failed.
+func (c *Client) CreateRefs(ctx context.Context, repositoryID string, refs []Ref) ([]error, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	query, variables := createRefsMutation(repositoryID, refs)
+	resp, err := c.do(ctx, query, variables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create references: %w", err)
+	}
+
+	results := make([]error, len(refs))
+	for _, e := range resp.Errors {
+		i, ok := aliasIndex(e.Path[0])
+		if !ok || i >= len(refs) {
+			return nil, fmt.Errorf("failed to create references: %w", classifyError(e))
+		}
+		results[i] = fmt.Errorf("failed to create %s: %w", refs[i].Name, classifyError(e))
+	}
+
+	// A field without data and without an error was not created either
+	for i := range refs {
+		if results[i] == nil && isNull(resp.Data[alias(i)]) {
+			results[i] = fmt.Errorf("failed to create %s: no result returned", refs[i].Name)
+		}
+	}
+
+	return results, nil
+}
+
+// RefTargets looks up, for each of names in order, the fully qualified reference of that
+// name in an owner/name repository, with one request of aliased ref queries. References
+// that don't exist are nil.
+func (c *Client) RefTargets(ctx context.Context, repository string, names []string) ([]*RefTarget, error) {
+	owner, name, err := splitRepository(repository)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	var params, fields strings.Builder
+	variables := map[string]any{"owner": owner, "name": name}
+
+	params.WriteString("$owner: String!, $name: String!")
+	for i, ref := range names {
+		fmt.Fprintf(&params, ", $ref%d: String!", i)
+		fmt.Fprintf(&fields, " %s: ref(qualifiedName: $ref%d) { id target { oid } }", alias(i), i)
+		variables["ref"+strconv.Itoa(i)] = ref
+	}
+	query := fmt.Sprintf("query(%s) { repository(owner: $owner, name: $name) {%s } }", params.String(), fields.String())
+
+	resp, err := c.do(ctx, query, variables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up references in %s: %w", repository, err)
+	}
+
+	var refs map[string]*struct {
+		ID     string `json:"id"`
+		Target struct {
+			OID string `json:"oid"`
+		} `json:"target"`
+	}
+	if err := json.Unmarshal(resp.Data["repository"], &refs); err != nil {
+		return nil, fmt.Errorf("failed to decode references of %s: %w", repository, err)
+	}
+	if refs == nil {
+		return nil, fmt.Errorf("failed to look up references in %s: %w", repository, ErrRepositoryNotFound)
+	}
+
+	targets := make([]*RefTarget, len(names))
+	for i := range names {
+		if ref := refs[alias(i)]; ref != nil {
+			targets[i] = &RefTarget{ID: ref.ID, SHA: ref.Target.OID}
+		}
+	}
+	return targets, nil
+}
+
+// UpdateRefs moves existing references to their new commit with a single request of
+// aliased updateRef mutations, forced so that commits rewritten by a force-push are taken
+// too. It returns the outcome of each update, in order (nil when it was made), or an error
+// when the request as a whole failed.
+func (c *Client) UpdateRefs(ctx context.Context, updates []RefUpdate) ([]error, error) {
+	if len(updates) == 0 {
+		return nil, nil
+	}
+
+	var params, fields strings.Builder
+	variables := map[string]any{}
+	for i, u := range updates {
+		if i > 0 {
+			params.WriteString(", ")
+		}
+		fmt.Fprintf(&params, "$id%d: ID!, $oid%d: GitObjectID!", i, i)
+		fmt.Fprintf(&fields, " %s: updateRef(input: {refId: $id%d, oid: $oid%d, force: true}) { ref { name } }", alias(i), i, i)
+		variables["id"+strconv.Itoa(i)] = u.ID
+		variables["oid"+strconv.Itoa(i)] = u.SHA
+	}
+	query := fmt.Sprintf("mutation(%s) {%s }", params.String(), fields.String())
+
+	resp, err := c.do(ctx, query, variables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update references: %w", err)
+	}
+
+	results := make([]error, len(updates))
+	for _, e := range resp.Errors {
+		i, ok := aliasIndex(e.Path[0])
+		if !ok || i >= len(updates) {
+			return nil, fmt.Errorf("failed to update references: %w", classifyError(e))
+		}
+		results[i] = fmt.Errorf("failed to update %s: %w", updates[i].Name, classifyError(e))
+	}
+	for i := range updates {
+		if results[i] == nil && isNull(resp.Data[alias(i)]) {
+			results[i] = fmt.Errorf("failed to update %s: no result returned", updates[i].Name)
+		}
+	}
+
+	return results, nil
+}
+
+// createRefsMutation builds a mutation with one aliased createRef per reference. Names
+// and SHAs are passed as variables so they never need escaping.
+func createRefsMutation(repositoryID string, refs []Ref) (string, map[string]any) {
+	var params, fields strings.Builder
+	variables := map[string]any{"repositoryId": repositoryID}
+
+	params.WriteString("$repositoryId: ID!")
+	for i, ref := range refs {
+		fmt.Fprintf(&params, ", $name%d: String!, $oid%d: GitObjectID!", i, i)
+		fmt.Fprintf(&fields, " %s: createRef(input: {repositoryId: $repositoryId, name: $name%d, oid: $oid%d}) { ref { name } }", alias(i), i, i)
+		variables["name"+strconv.Itoa(i)] = ref.Name
+		variables["oid"+strconv.Itoa(i)] = ref.SHA
+	}
+
+	return fmt.Sprintf("mutation(%s) {%s }", params.String(), fields.String()), variables
+}
+
+// alias is the name of the i-th mutation of a batch
+func alias(i int) string {
+	return "ref" + strconv.Itoa(i)
+}
+
+// aliasIndex returns the batch position of an alias
+func aliasIndex(name string) (int, bool) {
+	digits, ok := strings.CutPrefix(name, "ref")
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(digits)
+	return i, err == nil
+}
+
+// isNull reports whether a response field is missing or null
+func isNull(data json.RawMessage) bool {
+	return len(data) == 0 || string(data) == "null"
+}
@@ -0,0 +1,223 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// graphqlRequest is the body of a request received by the test server
+type graphqlRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+// newTestClient starts a GraphQL server answering with handler and returns a client for it
+func newTestClient(t *testing.T, handler func(req graphqlRequest) string) (*Client, *int) {
+	t.Helper()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var req graphqlRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Write([]byte(handler(req)))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient("secret", "", WithEndpoint(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, &requests
+}
+
+func TestBranchRef(t *testing.T) {
+	ref := BranchRef("migration-pr-1", "abc")
+	if ref.Name != "refs/heads/migration-pr-1" || ref.SHA != "abc" {
+		t.Errorf("BranchRef() = %+v", ref)
+	}
+}
+
+func TestTagRef(t *testing.T) {
+	ref := TagRef("gl-mr/1", "abc")
+	if ref.Name != "refs/tags/gl-mr/1" || ref.SHA != "abc" {
+		t.Errorf("TagRef() = %+v", ref)
+	}
+}
+
+func TestClient_RepositoryID(t *testing.T) {
+	client, _ := newTestClient(t, func(req graphqlRequest) string {
+		if req.Variables["owner"] == "octo" && req.Variables["name"] == "repo" {
+			return `{"data":{"repository":{"id":"R_123"}}}`
+		}
+		return `{"data":{"repository":null},"errors":[{"type":"NOT_FOUND","path":["repository"],"message":"Could not resolve to a Repository"}]}`
+	})
+
+	id, err := client.RepositoryID(context.Background(), "octo/repo")
+	if err != nil || id != "R_123" {
+		t.Errorf("RepositoryID() = %q, %v; want R_123", id, err)
+	}
+
+	if _, err := client.RepositoryID(context.Background(), "octo/missing"); !errors.Is(err, ErrRepositoryNotFound) {
+		t.Errorf("RepositoryID() error = %v, want ErrRepositoryNotFound", err)
+	}
+
+	if _, err := client.RepositoryID(context.Background(), "octo"); err == nil {
+		t.Error("RepositoryID() expected an error for a repository without owner")
+	}
+}
+
+func TestClient_CreateRefs(t *testing.T) {
+	client, requests := newTestClient(t, func(req graphqlRequest) string {
+		// Answer every aliased mutation; the second reference already exists
+		var data, errs []string
+		for i := 0; ; i++ {
+			name, ok := req.Variables[fmt.Sprintf("name%d", i)]
+			if !ok {
+				break
+			}
+			if !strings.Contains(req.Query, fmt.Sprintf("ref%d: createRef(", i)) {
+				t.Errorf("query is missing mutation %d: %s", i, req.Query)
+			}
+			if i == 1 {
+				data = append(data, fmt.Sprintf(`"ref%d":null`, i))
+				errs = append(errs, fmt.Sprintf(`{"path":["ref%d"],"message":"A ref named \"%s\" already exists in the repository."}`, i, name))
+				continue
+			}
+			data = append(data, fmt.Sprintf(`"ref%d":{"ref":{"name":%q}}`, i, name))
+		}
+		return fmt.Sprintf(`{"data":{%s},"errors":[%s]}`, strings.Join(data, ","), strings.Join(errs, ","))
+	})
+
+	refs := []Ref{
+		BranchRef("migration-pr-1", "aaa"),
+		BranchRef("migration-pr-2", "bbb"),
+		BranchRef("migration-pr-3", "ccc"),
+	}
+
+	results, err := client.CreateRefs(context.Background(), "R_123", refs)
+	if err != nil {
+		t.Fatalf("CreateRefs() unexpected error = %v", err)
+	}
+	if *requests != 1 {
+		t.Errorf("CreateRefs() made %d requests, want 1", *requests)
+	}
+	if len(results) != 3 {
+		t.Fatalf("CreateRefs() returned %d results, want 3", len(results))
+	}
+	if results[0] != nil || results[2] != nil {
+		t.Errorf("CreateRefs() results = %v, want references 1 and 3 created", results)
+	}
+	if !errors.Is(results[1], ErrRefExists) {
+		t.Errorf("CreateRefs() result 2 = %v, want ErrRefExists", results[1])
+	}
+}
+
+func TestCreateRefsMutation(t *testing.T) {
+	query, variables := createRefsMutation("R_1", []Ref{{Name: `refs/heads/"quoted"`, SHA: "aaa"}})
+
+	expected := `mutation($repositoryId: ID!, $name0: String!, $oid0: GitObjectID!) { ref0: createRef(input: {repositoryId: $repositoryId, name: $name0, oid: $oid0}) { ref { name } } }`
+	if query != expected {
+		t.Errorf("query = %q, want %q", query, expected)
+	}
+	if variables["name0"] != `refs/heads/"quoted"` || variables["oid0"] != "aaa" || variables["repositoryId"] != "R_1" {
+		t.Errorf("variables = %v", variables)
+	}
+}
+
+func TestClient_CommitsExist(t *testing.T) {
+	client, requests := newTestClient(t, func(req graphqlRequest) string {
+		if req.Variables["owner"] != "octo" || req.Variables["name"] != "repo" {
+			return `{"data":{"repository":null},"errors":[{"type":"NOT_FOUND","path":["repository"],"message":"Could not resolve to a Repository"}]}`
+		}
+
+		var fields []string
+		for i := 0; ; i++ {
+			sha, ok := req.Variables[fmt.Sprintf("sha%d", i)]
+			if !ok {
+				break
+			}
+			switch sha {
+			case "aaa":
+				fields = append(fields, fmt.Sprintf(`"commit%d":{"__typename":"Commit"}`, i))
+			case "tree":
+				fields = append(fields, fmt.Sprintf(`"commit%d":{"__typename":"Tree"}`, i))
+			default:
+				fields = append(fields, fmt.Sprintf(`"commit%d":null`, i))
+			}
+		}
+		return fmt.Sprintf(`{"data":{"repository":{%s}}}`, strings.Join(fields, ","))
+	})
+
+	exists, err := client.CommitsExist(context.Background(), "octo/repo", []string{"aaa", "missing", "tree"})
+	if err != nil {
+		t.Fatalf("CommitsExist() unexpected error = %v", err)
+	}
+	if *requests != 1 {
+		t.Errorf("CommitsExist() made %d requests, want 1", *requests)
+	}
+	if fmt.Sprint(exists) != "[true false false]" {
+		t.Errorf("CommitsExist() = %v, want [true false false]", exists)
+	}
+
+	if _, err := client.CommitsExist(context.Background(), "octo/missing", []string{"aaa"}); !errors.Is(err, ErrRepositoryNotFound) {
+		t.Errorf("CommitsExist() error = %v, want ErrRepositoryNotFound", err)
+	}
+}
+
+func TestClient_RefTargets(t *testing.T) {
+	client, requests := newTestClient(t, func(req graphqlRequest) string {
+		var fields []string
+		for i := 0; ; i++ {
+			name, ok := req.Variables[fmt.Sprintf("ref%d", i)]
+			if !ok {
+				break
+			}
+			if name == "refs/heads/migration-pr-1" {
+				fields = append(fields, fmt.Sprintf(`"ref%d":{"id":"REF_1","target":{"oid":"aaa"}}`, i))
+				continue
+			}
+			fields = append(fields, fmt.Sprintf(`"ref%d":null`, i))
+		}
+		return fmt.Sprintf(`{"data":{"repository":{%s}}}`, strings.Join(fields, ","))
+	})
+
+	targets, err := client.RefTargets(context.Background(), "octo/repo", []string{"refs/heads/migration-pr-1", "refs/heads/migration-pr-2"})
+	if err != nil {
+		t.Fatalf("RefTargets() unexpected error = %v", err)
+	}
+	if *requests != 1 {
+		t.Errorf("RefTargets() made %d requests, want 1", *requests)
+	}
+	if len(targets) != 2 || targets[0] == nil || *targets[0] != (RefTarget{ID: "REF_1", SHA: "aaa"}) || targets[1] != nil {
+		t.Errorf("RefTargets() = %v", targets)
+	}
+}
+
+func TestClient_UpdateRefs(t *testing.T) {
+	client, _ := newTestClient(t, func(req graphqlRequest) string {
+		if !strings.Contains(req.Query, "force: true") {
+			t.Errorf("query = %q, want forced updates", req.Query)
+		}
+		return `{"data":{"ref0":{"ref":{"name":"migration-pr-1"}},"ref1":null},"errors":[{"type":"UNPROCESSABLE","path":["ref1"],"message":"Object does not exist"}]}`
+	})
+
+	results, err := client.UpdateRefs(context.Background(), []RefUpdate{
+		{ID: "REF_1", Ref: BranchRef("migration-pr-1", "bbb")},
+		{ID: "REF_2", Ref: BranchRef("migration-pr-2", "ccc")},
+	})
+	if err != nil {
+		t.Fatalf("UpdateRefs() unexpected error = %v", err)
+	}
+	if len(results) != 2 || results[0] != nil || results[1] == nil || !strings.Contains(results[1].Error(), "refs/heads/migration-pr-2") {
+		t.Errorf("UpdateRefs() = %v", results)
+	}
+}
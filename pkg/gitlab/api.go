@@ -0,0 +1,47 @@
+package gitlab
+
+import (
+	"context"
+	"iter"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// API is the set of GitLab operations the commands rely on. *Client implements it;
+// tests and alternate backends can provide their own implementation.
+type API interface {
+	// FetchMergeRequestRefsFromRepo streams the merge request references of a repository selected by options to processor
+	FetchMergeRequestRefsFromRepo(ctx context.Context, repoPath string, baseURLOverride string, processor MergeRequestProcessor, options ...FetchOption) (string, error)
+	// MergeRequestRefs iterates over the merge request references of a project selected by options
+	MergeRequestRefs(ctx context.Context, projectPath string, options ...FetchOption) iter.Seq2[MergeRequestRef, error]
+	// MergeRequests iterates over the listed merge requests of a project selected by options,
+	// including those without a head SHA
+	MergeRequests(ctx context.Context, projectPath string, options ...FetchOption) iter.Seq2[*gitlab.BasicMergeRequest, error]
+	// MergeRequestDiscussions lists the discussion threads of a merge request
+	MergeRequestDiscussions(ctx context.Context, projectPath string, iid int) ([]Discussion, error)
+	// CommitExists reports whether the repository of a project has a commit
+	CommitExists(ctx context.Context, projectPath, sha string) (bool, error)
+	// GetProjectInfo summarizes a project for pre-flight checks and progress reporting
+	GetProjectInfo(ctx context.Context, projectPath string) (*ProjectInfo, error)
+	// ProjectPath returns the current full path of a project, which differs from projectPath
+	// once the project was renamed or transferred
+	ProjectPath(ctx context.Context, projectPath string) (string, error)
+	// ListGroupProjects lists the projects of a group and its subgroups selected by filter
+	ListGroupProjects(ctx context.Context, groupPath string, filter ProjectFilter) ([]string, error)
+	// ListBranchSHAs returns the head SHA of every branch starting with prefix
+	ListBranchSHAs(ctx context.Context, projectPath, prefix string) (map[string]string, error)
+	// BranchSHA returns the head SHA of a branch, or ErrBranchNotFound when it does not exist
+	BranchSHA(ctx context.Context, projectPath, branchName string) (string, error)
+	// CreateBranch creates a branch at ref
+	CreateBranch(ctx context.Context, projectPath, branchName, ref string) error
+	// DeleteBranch deletes a branch
+	DeleteBranch(ctx context.Context, projectPath, branchName string) error
+	// GetTokenInfo describes the token the client is authenticated with
+	GetTokenInfo(ctx context.Context) (*TokenInfo, error)
+	// GetProjectAccess reports what a token with the given scopes can do on a project
+	GetProjectAccess(ctx context.Context, projectPath string, scopes []string) (*ProjectAccess, error)
+	// ProtectedBranches lists the protected branch names and patterns of a project
+	ProtectedBranches(ctx context.Context, projectPath string) ([]ProtectedBranch, error)
+}
+
+var _ API = (*Client)(nil)
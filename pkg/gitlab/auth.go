@@ -0,0 +1,122 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+	"gitlab.com/gitlab-org/api/client-go/gitlaboauth2"
+	"golang.org/x/oauth2"
+)
+
+// AuthType selects how the client authenticates with GitLab
+type AuthType string
+
+const (
+	// AuthTypeToken authenticates with a personal, project or group access token
+	AuthTypeToken AuthType = "token"
+	// AuthTypeOAuth authenticates with an OAuth 2.0 access token sent as a bearer token
+	AuthTypeOAuth AuthType = "oauth"
+)
+
+// ParseAuthType validates an authentication type name
+func ParseAuthType(s string) (AuthType, error) {
+	switch t := AuthType(s); t {
+	case AuthTypeToken, AuthTypeOAuth:
+		return t, nil
+	default:
+		return "", fmt.Errorf("invalid auth type %q: must be %s or %s", s, AuthTypeToken, AuthTypeOAuth)
+	}
+}
+
+// DeviceCodePrompt shows the user where to enter the code of a device authorization request
+type DeviceCodePrompt func(verificationURI, userCode string)
+
+// DeviceAuthorization obtains an OAuth access token with the OAuth 2.0 device
+// authorization grant. The user approves the request in a browser on any device;
+// prompt tells them where to go and which code to enter. An empty baseURL means gitlab.com.
+func DeviceAuthorization(ctx context.Context, baseURL, clientID string, scopes []string, prompt DeviceCodePrompt) (*oauth2.Token, error) {
+	config := gitlaboauth2.NewOAuth2Config(baseURL, clientID, "", scopes)
+
+	auth, err := config.DeviceAuth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start device authorization: %w", sanitizeError(err))
+	}
+
+	verificationURI := auth.VerificationURIComplete
+	if verificationURI == "" {
+		verificationURI = auth.VerificationURI
+	}
+	prompt(verificationURI, auth.UserCode)
+
+	token, err := config.DeviceAccessToken(ctx, auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete device authorization: %w", sanitizeError(err))
+	}
+
+	return token, nil
+}
+
+// newAuthenticatedClient creates the client-go client for the configured authentication type
+func newAuthenticatedClient(authType AuthType, token string, options ...gitlab.ClientOptionFunc) (*gitlab.Client, error) {
+	switch authType {
+	case AuthTypeToken, "":
+		return gitlab.NewClient(token, options...)
+	case AuthTypeOAuth:
+		source := gitlab.OAuthTokenSource{
+			TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}),
+		}
+		return gitlab.NewAuthSourceClient(source, options...)
+	default:
+		return nil, fmt.Errorf("unsupported auth type %q", authType)
+	}
+}
+
+// oauthTokenInfo is the response of GitLab's OAuth token introspection endpoint
+type oauthTokenInfo struct {
+	Scope       []string `json:"scope"`
+	ExpiresIn   *int     `json:"expires_in"`
+	Application struct {
+		UID string `json:"uid"`
+	} `json:"application"`
+}
+
+// getOAuthTokenInfo looks up the scopes and expiry of an OAuth access token.
+// The introspection endpoint lives outside the /api/v4 prefix.
+func (c *Client) getOAuthTokenInfo(ctx context.Context) (*TokenInfo, error) {
+	base := c.client.BaseURL()
+	endpoint := &url.URL{
+		Scheme: base.Scheme,
+		Host:   base.Host,
+		Path:   strings.TrimSuffix(strings.TrimSuffix(base.Path, "/"), "/api/v4") + "/oauth/token/info",
+	}
+
+	req, err := c.client.NewRequestToURL(http.MethodGet, endpoint, nil, []gitlab.RequestOptionFunc{gitlab.WithContext(ctx)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up OAuth token: %w", sanitizeError(err))
+	}
+
+	var tokenInfo oauthTokenInfo
+	if _, err := c.client.Do(req, &tokenInfo); err != nil {
+		return nil, fmt.Errorf("failed to look up OAuth token: %w", sanitizeError(err))
+	}
+
+	info := &TokenInfo{
+		Name:   "OAuth token",
+		Scopes: tokenInfo.Scope,
+		Active: true,
+	}
+	if tokenInfo.Application.UID != "" {
+		info.Name = fmt.Sprintf("OAuth token (application %s)", tokenInfo.Application.UID)
+	}
+	if tokenInfo.ExpiresIn != nil {
+		expiresAt := time.Now().Add(time.Duration(*tokenInfo.ExpiresIn) * time.Second)
+		info.ExpiresAt = &expiresAt
+	}
+
+	return info, nil
+}
@@ -0,0 +1,113 @@
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestParseAuthType(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    AuthType
+		expectError bool
+	}{
+		{"token", AuthTypeToken, false},
+		{"oauth", AuthTypeOAuth, false},
+		{"", "", true},
+		{"OAuth", "", true},
+		{"basic", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseAuthType(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("ParseAuthType(%q) expected error, got nil", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("ParseAuthType(%q) unexpected error = %v", tt.input, err)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseAuthType(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewClient_AuthHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		authType AuthType
+		header   string
+		value    string
+	}{
+		{"access token", AuthTypeToken, "PRIVATE-TOKEN", "secret"},
+		{"oauth token", AuthTypeOAuth, "Authorization", "Bearer secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get(tt.header); got != tt.value {
+					t.Errorf("Header %s = %q, want %q", tt.header, got, tt.value)
+				}
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"name": "migration-pr-1"}`))
+			}))
+			defer server.Close()
+
+			client, err := NewClient("secret", server.URL, WithAuthType(tt.authType), WithLogger(quietLogger()))
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+
+			if err := client.CreateBranch(context.Background(), "group/project", "migration-pr-1", "abc123"); err != nil {
+				t.Fatalf("CreateBranch failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetTokenInfo_OAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/token/info":
+			w.Write([]byte(`{"scope": ["api"], "expires_in": 7200, "application": {"uid": "app-1"}}`))
+		case "/api/v4/user":
+			w.Write([]byte(`{"username": "migrator"}`))
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient("secret", server.URL, WithAuthType(AuthTypeOAuth), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	info, err := client.GetTokenInfo(context.Background())
+	if err != nil {
+		t.Fatalf("GetTokenInfo failed: %v", err)
+	}
+
+	if info.Owner != "migrator" {
+		t.Errorf("Owner = %q, want %q", info.Owner, "migrator")
+	}
+	if !slices.Equal(info.Scopes, []string{"api"}) {
+		t.Errorf("Scopes = %v, want [api]", info.Scopes)
+	}
+	if info.ExpiresAt == nil {
+		t.Error("ExpiresAt should be set from expires_in")
+	}
+	if !info.Active {
+		t.Error("OAuth token should be reported as active")
+	}
+}
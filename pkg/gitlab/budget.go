@@ -0,0 +1,59 @@
+package gitlab
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrBudgetExhausted means the run made as many requests as its Budget allows
+var ErrBudgetExhausted = errors.New("API request budget exhausted")
+
+// Budget caps the number of requests sent to GitLab, for runs that share a rate limit pool
+// with other tooling. It is safe for concurrent use and may be shared by several clients to
+// cap a whole run. Every attempt counts, including retries; responses served from the
+// cache do not.
+type Budget struct {
+	limit int64
+	used  atomic.Int64
+}
+
+// NewBudget creates a budget of limit requests
+func NewBudget(limit int) *Budget {
+	return &Budget{limit: int64(limit)}
+}
+
+// Limit returns the number of requests the budget allows
+func (b *Budget) Limit() int {
+	return int(b.limit)
+}
+
+// Used returns the number of requests sent so far
+func (b *Budget) Used() int {
+	return int(min(b.used.Load(), b.limit))
+}
+
+// Exhausted reports whether every request of the budget has been used. A nil budget is
+// never exhausted.
+func (b *Budget) Exhausted() bool {
+	return b != nil && b.used.Load() >= b.limit
+}
+
+// take uses one request of the budget, and reports false when none is left
+func (b *Budget) take() bool {
+	return b.used.Add(1) <= b.limit
+}
+
+// budgetTransport refuses requests once its budget is exhausted
+type budgetTransport struct {
+	next   http.RoundTripper
+	budget *Budget
+}
+
+func (t *budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.budget.take() {
+		return nil, fmt.Errorf("%w: all %d requests used", ErrBudgetExhausted, t.budget.limit)
+	}
+	return t.next.RoundTrip(req)
+}
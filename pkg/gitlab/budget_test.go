@@ -0,0 +1,53 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBudget_RefusesRequestsOverLimit(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"name": "migration-pr-1"}`))
+	}))
+	defer server.Close()
+
+	budget := NewBudget(2)
+	client, err := NewClient("token", server.URL, WithBudget(budget), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	for i := range 2 {
+		if err := client.CreateBranch(context.Background(), "group/project", "migration-pr-1", "abc123"); err != nil {
+			t.Fatalf("CreateBranch %d failed: %v", i, err)
+		}
+	}
+	if !budget.Exhausted() {
+		t.Error("Expected the budget to be exhausted after 2 requests")
+	}
+
+	err = client.CreateBranch(context.Background(), "group/project", "migration-pr-1", "abc123")
+	if !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("CreateBranch() error = %v, want ErrBudgetExhausted", err)
+	}
+	if calls != 2 {
+		t.Errorf("Server received %d requests, want 2", calls)
+	}
+	if budget.Used() != 2 {
+		t.Errorf("Used() = %d, want 2", budget.Used())
+	}
+}
+
+func TestBudget_Nil(t *testing.T) {
+	var b *Budget
+	if b.Exhausted() {
+		t.Error("Expected a nil budget never to be exhausted")
+	}
+}
@@ -0,0 +1,282 @@
+package gitlab
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DiskCache stores successful GET responses on disk so repeated runs against the same
+// project do not repeat identical requests. Entries are fresh for the configured TTL, or
+// for the Cache-Control max-age of the response when no TTL is set. Stale entries with an
+// ETag are revalidated with a conditional request. A successful request that changes a
+// project (such as creating a branch) drops the cached responses of that project.
+type DiskCache struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+	// offline answers every request from the cache; see OpenOfflineCache
+	offline bool
+}
+
+// ErrOffline means an offline client was asked for a response the cache does not hold, or
+// to send a request that changes GitLab
+var ErrOffline = errors.New("offline")
+
+// cacheEntry is the on-disk form of a cached response
+type cacheEntry struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	ExpiresAt  time.Time   `json:"expires_at"`
+}
+
+// NewDiskCache creates a cache in dir. A positive ttl overrides the freshness lifetime
+// GitLab sends in Cache-Control headers.
+func NewDiskCache(dir string, ttl time.Duration) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &DiskCache{dir: dir, ttl: ttl, now: time.Now}, nil
+}
+
+// OpenOfflineCache opens the cache recorded in dir by earlier runs to replay it without
+// contacting GitLab: every GET is answered from the cache however old its entry is, and a
+// request the cache does not hold, or any request that would change GitLab, fails with
+// ErrOffline. Entries are stored per token, so replay with the token they were recorded with.
+func OpenOfflineCache(dir string) (*DiskCache, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open offline cache: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("failed to open offline cache: %s is not a directory", dir)
+	}
+	return &DiskCache{dir: dir, now: time.Now, offline: true}, nil
+}
+
+// Clear removes every cached response
+func (c *DiskCache) Clear() error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read cache directory: %w", err)
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(c.dir, entry.Name())); err != nil {
+			return fmt.Errorf("failed to clear cache: %w", err)
+		}
+	}
+	return nil
+}
+
+// Transport returns a RoundTripper that serves requests from the cache and sends the rest to next
+func (c *DiskCache) Transport(next http.RoundTripper) http.RoundTripper {
+	return &cacheTransport{cache: c, next: next}
+}
+
+// cacheTransport answers GET requests from a DiskCache
+type cacheTransport struct {
+	cache *DiskCache
+	next  http.RoundTripper
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.cache.offline {
+		return t.cache.replay(req)
+	}
+
+	if req.Method != http.MethodGet {
+		resp, err := t.next.RoundTrip(req)
+		if err == nil && resp.StatusCode < 400 {
+			t.cache.invalidate(req)
+		}
+		return resp, err
+	}
+
+	path := t.cache.entryPath(req)
+	entry, _ := t.cache.load(path)
+
+	if entry != nil && t.cache.now().Before(entry.ExpiresAt) {
+		return entry.response(req), nil
+	}
+
+	if entry != nil {
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			req = req.Clone(req.Context())
+			req.Header.Set("If-None-Match", etag)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close()
+		entry.ExpiresAt = t.cache.expiry(resp.Header)
+		t.cache.store(path, entry)
+		return entry.response(req), nil
+	}
+
+	if resp.StatusCode != http.StatusOK || noStore(resp.Header) {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.cache.store(path, &cacheEntry{
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+		ExpiresAt:  t.cache.expiry(resp.Header),
+	})
+
+	return resp, nil
+}
+
+// replay answers req from the cache without contacting GitLab
+func (c *DiskCache) replay(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return nil, fmt.Errorf("%w: %s requests change GitLab and are not sent", ErrOffline, req.Method)
+	}
+
+	entry, err := c.load(c.entryPath(req))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s is not in the response cache", ErrOffline, req.URL.Path)
+	}
+	return entry.response(req), nil
+}
+
+// response rebuilds an HTTP response from a cached entry
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// expiry is when a response received now stops being fresh
+func (c *DiskCache) expiry(header http.Header) time.Time {
+	if c.ttl > 0 {
+		return c.now().Add(c.ttl)
+	}
+	return c.now().Add(maxAge(header))
+}
+
+// maxAge reads the max-age directive of a Cache-Control header
+func maxAge(header http.Header) time.Duration {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.EqualFold(name, "max-age") {
+			if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return 0
+}
+
+// noStore reports whether the response forbids caching
+func noStore(header http.Header) bool {
+	return strings.Contains(strings.ToLower(header.Get("Cache-Control")), "no-store")
+}
+
+// entryPath places each response under a directory for its project so changes to a
+// project can drop its entries. The key includes the credentials and the impersonated user
+// so tokens and users with different access never share responses.
+func (c *DiskCache) entryPath(req *http.Request) string {
+	key := sha256.New()
+	fmt.Fprintln(key, req.URL.String())
+	fmt.Fprintln(key, req.Header.Get("PRIVATE-TOKEN"), req.Header.Get("Authorization"), req.Header.Get("JOB-TOKEN"), req.Header.Get("Sudo"))
+
+	return filepath.Join(c.projectDir(req), hex.EncodeToString(key.Sum(nil))+".json")
+}
+
+// projectDir is the cache directory of the project a request belongs to
+func (c *DiskCache) projectDir(req *http.Request) string {
+	scope := "global"
+
+	// EscapedPath keeps namespaced project paths (group%2Fproject) in one segment
+	segments := strings.Split(req.URL.EscapedPath(), "/")
+	for i, segment := range segments {
+		if segment == "projects" && i+1 < len(segments) {
+			scope = req.URL.Host + "/projects/" + segments[i+1]
+			break
+		}
+	}
+
+	sum := sha256.Sum256([]byte(scope))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:8]))
+}
+
+// invalidate drops the cached responses of the project a request changed
+func (c *DiskCache) invalidate(req *http.Request) {
+	os.RemoveAll(c.projectDir(req))
+}
+
+// load reads a cache entry; a missing or unreadable entry is a cache miss
+func (c *DiskCache) load(path string) (*cacheEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entry cacheEntry
+	if err := json.NewDecoder(bufio.NewReader(f)).Decode(&entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// store writes a cache entry; failures only cost a future cache miss
+func (c *DiskCache) store(path string, entry *cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+
+	// Write to a unique temporary file so concurrent writers never interleave
+	tmp, err := os.CreateTemp(filepath.Dir(path), "entry-*.tmp")
+	if err != nil {
+		return
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if writeErr != nil || closeErr != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
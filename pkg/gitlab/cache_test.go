@@ -0,0 +1,243 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newCachedClient creates a client with a disk cache in a temporary directory
+func newCachedClient(t *testing.T, serverURL string, ttl time.Duration) (*Client, *DiskCache) {
+	t.Helper()
+
+	cache, err := NewDiskCache(t.TempDir(), ttl)
+	if err != nil {
+		t.Fatalf("NewDiskCache failed: %v", err)
+	}
+
+	client, err := NewClient("token", serverURL, WithCache(cache), WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	return client, cache
+}
+
+func TestDiskCache_TTL(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`[{"name": "migration-pr-1", "commit": {"id": "abc"}}]`))
+	}))
+	defer server.Close()
+
+	client, cache := newCachedClient(t, server.URL, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		branches, err := client.ListBranchSHAs(context.Background(), "group/project", "migration-pr-")
+		if err != nil {
+			t.Fatalf("ListBranchSHAs failed: %v", err)
+		}
+		if branches["migration-pr-1"] != "abc" {
+			t.Errorf("Unexpected branches from cache: %v", branches)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request to GitLab, got %d", requests)
+	}
+
+	// Once the TTL has passed, GitLab is asked again
+	cache.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := client.ListBranchSHAs(context.Background(), "group/project", "migration-pr-"); err != nil {
+		t.Fatalf("ListBranchSHAs failed: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected a new request after the TTL, got %d requests", requests)
+	}
+}
+
+func TestDiskCache_Revalidation(t *testing.T) {
+	var requests, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Cache-Control", "max-age=0, private, must-revalidate")
+		w.Header().Set("ETag", `W/"v1"`)
+		if r.Header.Get("If-None-Match") == `W/"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`[{"name": "migration-pr-1", "commit": {"id": "abc"}}]`))
+	}))
+	defer server.Close()
+
+	// Without a TTL, GitLab's max-age=0 makes every entry stale immediately
+	client, _ := newCachedClient(t, server.URL, 0)
+
+	for i := 0; i < 2; i++ {
+		branches, err := client.ListBranchSHAs(context.Background(), "group/project", "migration-pr-")
+		if err != nil {
+			t.Fatalf("ListBranchSHAs failed: %v", err)
+		}
+		if branches["migration-pr-1"] != "abc" {
+			t.Errorf("Unexpected branches: %v", branches)
+		}
+	}
+
+	if requests != 2 || notModified != 1 {
+		t.Errorf("Expected the second request to be revalidated, got %d requests and %d not modified", requests, notModified)
+	}
+}
+
+func TestDiskCache_InvalidatedByChanges(t *testing.T) {
+	var lists int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"name": "migration-pr-2"}`))
+			return
+		}
+		atomic.AddInt32(&lists, 1)
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client, _ := newCachedClient(t, server.URL, time.Hour)
+	ctx := context.Background()
+
+	client.ListBranchSHAs(ctx, "group/project", "migration-pr-")
+	client.ListBranchSHAs(ctx, "group/other", "migration-pr-")
+
+	if err := client.CreateBranch(ctx, "group/project", "migration-pr-2", "def"); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+
+	client.ListBranchSHAs(ctx, "group/project", "migration-pr-")
+	client.ListBranchSHAs(ctx, "group/other", "migration-pr-")
+
+	// Only the changed project is fetched again
+	if lists != 3 {
+		t.Errorf("Expected 3 list requests, got %d", lists)
+	}
+}
+
+func TestDiskCache_ErrorsNotCached(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message": "403 Forbidden"}`))
+	}))
+	defer server.Close()
+
+	client, _ := newCachedClient(t, server.URL, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListBranchSHAs(context.Background(), "group/project", "migration-pr-"); err == nil {
+			t.Fatal("Expected an error")
+		}
+	}
+	if requests != 2 {
+		t.Errorf("Error responses should not be cached, got %d requests", requests)
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"max-age=60", time.Minute},
+		{"private, max-age=30, must-revalidate", 30 * time.Second},
+		{"max-age=0, private", 0},
+		{"max-age=invalid", 0},
+	}
+
+	for _, tt := range tests {
+		header := http.Header{}
+		header.Set("Cache-Control", tt.header)
+		if got := maxAge(header); got != tt.expected {
+			t.Errorf("maxAge(%q) = %v, want %v", tt.header, got, tt.expected)
+		}
+	}
+}
+
+func TestOpenOfflineCache(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`[{"name": "migration-pr-1", "commit": {"id": "abc"}}]`))
+	}))
+	defer server.Close()
+
+	// Record online, with a TTL long expired by the time of the replay
+	online, cache := newCachedClient(t, server.URL, time.Nanosecond)
+	if _, err := online.ListBranchSHAs(context.Background(), "group/project", "migration-pr-"); err != nil {
+		t.Fatalf("ListBranchSHAs failed: %v", err)
+	}
+
+	offlineCache, err := OpenOfflineCache(cache.dir)
+	if err != nil {
+		t.Fatalf("OpenOfflineCache failed: %v", err)
+	}
+	client, err := NewClient("token", server.URL, WithCache(offlineCache), WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	branches, err := client.ListBranchSHAs(context.Background(), "group/project", "migration-pr-")
+	if err != nil {
+		t.Fatalf("ListBranchSHAs offline failed: %v", err)
+	}
+	if branches["migration-pr-1"] != "abc" {
+		t.Errorf("Unexpected branches from the offline cache: %v", branches)
+	}
+
+	if _, err := client.ListBranchSHAs(context.Background(), "group/other", "migration-pr-"); !errors.Is(err, ErrOffline) {
+		t.Errorf("ListBranchSHAs() of an unrecorded project error = %v, want ErrOffline", err)
+	}
+	if err := client.CreateBranch(context.Background(), "group/project", "migration-pr-2", "def"); !errors.Is(err, ErrOffline) {
+		t.Errorf("CreateBranch() offline error = %v, want ErrOffline", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected only the recording request to reach GitLab, got %d", requests)
+	}
+}
+
+func TestOpenOfflineCache_Missing(t *testing.T) {
+	if _, err := OpenOfflineCache(t.TempDir() + "/missing"); err == nil {
+		t.Error("Expected an error for a missing cache directory")
+	}
+}
+
+func TestDiskCache_SeparatesSudoUsers(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`[{"name": "migration-pr-1", "commit": {"id": "abc"}}]`))
+	}))
+	defer server.Close()
+
+	cache, err := NewDiskCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("NewDiskCache failed: %v", err)
+	}
+
+	// Users see different projects and branches, so their responses are cached apart
+	for _, sudo := range []string{"alice", "bob", "alice"} {
+		client, err := NewClient("token", server.URL, WithCache(cache), WithSudo(sudo), WithRateLimit(0, 1), WithLogger(quietLogger()))
+		if err != nil {
+			t.Fatalf("NewClient failed: %v", err)
+		}
+		if _, err := client.ListBranchSHAs(context.Background(), "group/project", "migration-pr-"); err != nil {
+			t.Fatalf("ListBranchSHAs failed: %v", err)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests to GitLab, one per user, got %d", requests)
+	}
+}
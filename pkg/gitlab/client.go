@@ -1,23 +1,33 @@
 package gitlab
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
 	gitlab "gitlab.com/gitlab-org/api/client-go"
 )
 
-// Client wraps the GitLab client with additional functionality
+// Client wraps the GitLab client with additional functionality.
+// It is safe for concurrent use; all goroutines share one rate limiter.
 type Client struct {
-	client          *gitlab.Client
-	lastRequestTime time.Time
-	minInterval     time.Duration
+	client   *gitlab.Client
+	logger   *slog.Logger
+	authType AuthType
+	metrics  *Metrics
+
+	// projectCache serves GetProjectInfo when set; cacheScope keeps the entries of
+	// different tokens apart
+	projectCache *ProjectCache
+	cacheScope   string
 }
 
 // MergeRequestRef represents a merge request reference
@@ -25,95 +35,83 @@ type MergeRequestRef struct {
 	ID      int
 	IID     int
 	HeadSHA string
+
+	// SourceBranch is the branch the merge request was opened from; empty for references
+	// read from a CSV file
+	SourceBranch string
+
+	// Fork is set when the merge request's source branch is in a fork. Its commits are in
+	// the project only through MergeRequestHeadRef, which a plain mirror does not copy.
+	Fork bool
+
+	// MergeRequest is the merge request as returned by GitLab, for callers that need more
+	// than the reference. When fetched with WithDetail(false) only the fields of the list
+	// response (BasicMergeRequest) are set. It is nil for references read from a CSV file.
+	MergeRequest *gitlab.MergeRequest
+
+	// Versions holds every diff version of the merge request, oldest first, when fetched with WithAllVersions
+	Versions []MergeRequestVersion
 }
 
 // MergeRequestProcessor is a callback function that processes each merge request as it's fetched
 type MergeRequestProcessor func(MergeRequestRef) error
 
 // NewClient creates a new GitLab client
-func NewClient(token, baseURL string) (*Client, error) {
-	var client *gitlab.Client
-	var err error
+func NewClient(token, baseURL string, options ...Option) (*Client, error) {
+	cfg := &clientConfig{
+		logger:   slog.Default(),
+		rps:      DefaultRequestsPerSecond,
+		burst:    DefaultBurst,
+		authType: AuthTypeToken,
+	}
+	for _, option := range options {
+		option(cfg)
+	}
+	if cfg.limiter == nil {
+		cfg.limiter = NewTokenBucket(cfg.rps, cfg.burst, cfg.logger)
+	}
 
+	gitlabOpts := cfg.gitlabOptions()
 	if baseURL != "" {
-		log.Println("Using custom GitLab base URL:", baseURL)
-		client, err = gitlab.NewClient(token, gitlab.WithBaseURL(baseURL))
-	} else {
-		client, err = gitlab.NewClient(token)
+		cfg.logger.Info("using custom GitLab base URL", "base_url", baseURL)
+		gitlabOpts = append(gitlabOpts, gitlab.WithBaseURL(baseURL))
 	}
 
+	client, err := newAuthenticatedClient(cfg.authType, token, gitlabOpts...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create GitLab client: %w", err)
-	}
-
-	return &Client{
-		client:      client,
-		minInterval: 100 * time.Millisecond, // Conservative rate limit: max 10 requests/second
-	}, nil
-}
-
-// rateLimitWait ensures we don't exceed rate limits by waiting if necessary
-func (c *Client) rateLimitWait() {
-	now := time.Now()
-	if !c.lastRequestTime.IsZero() {
-		elapsed := now.Sub(c.lastRequestTime)
-		if elapsed < c.minInterval {
-			sleepDuration := c.minInterval - elapsed
-			fmt.Printf("⏳ Respecting GitLab API rate limits, waiting %v before next request...\n", sleepDuration.Round(time.Millisecond))
-			time.Sleep(sleepDuration)
-		}
+		return nil, fmt.Errorf("failed to create GitLab client: %w", sanitizeError(err))
 	}
-	c.lastRequestTime = time.Now()
-}
-
-// checkRateLimitHeaders examines GitLab's rate limit headers and adjusts behavior accordingly
-func (c *Client) checkRateLimitHeaders(resp *http.Response) {
-	if resp == nil {
-		return
+	// The caller's own client keeps its redirect policy
+	if hc := client.HTTPClient(); hc.CheckRedirect == nil {
+		hc.CheckRedirect = followReadRedirects
 	}
 
-	// GitLab.com rate limit headers
-	rateLimitRemaining := resp.Header.Get("RateLimit-Remaining")
-	rateLimitReset := resp.Header.Get("RateLimit-ResetTime")
-
-	// Alternative headers that might be present
-	if rateLimitRemaining == "" {
-		rateLimitRemaining = resp.Header.Get("X-RateLimit-Remaining")
+	c := &Client{
+		client:       client,
+		logger:       cfg.logger,
+		authType:     cfg.authType,
+		metrics:      cfg.metrics,
+		projectCache: cfg.projectCache,
 	}
-	if rateLimitReset == "" {
-		rateLimitReset = resp.Header.Get("X-RateLimit-Reset")
+	if c.projectCache != nil {
+		sum := sha256.Sum256([]byte(string(cfg.authType) + " " + token + " " + cfg.sudo))
+		c.cacheScope = hex.EncodeToString(sum[:8])
 	}
+	return c, nil
+}
 
-	if rateLimitRemaining != "" {
-		if remaining, err := strconv.Atoi(rateLimitRemaining); err == nil {
-			if remaining <= 10 { // If we're getting close to the limit
-				fmt.Printf("⚠️  Rate limit warning: only %d requests remaining, slowing down requests\n", remaining)
-				// Increase our conservative interval
-				c.minInterval = 1 * time.Second
-			} else if remaining <= 5 {
-				fmt.Printf("🚨 Rate limit critical: only %d requests remaining, significantly slowing down\n", remaining)
-				c.minInterval = 5 * time.Second
-			}
-		}
+// followReadRedirects follows the redirects GitLab answers reads of a renamed or transferred
+// project with, as net/http does by default, but not those of changes: net/http would send
+// a redirected POST or DELETE again as a GET, so a branch would seem created when it was
+// not. The redirect response ends a change instead, and is reported as ErrProjectMoved.
+func followReadRedirects(req *http.Request, via []*http.Request) error {
+	if method := via[0].Method; method != http.MethodGet && method != http.MethodHead {
+		return http.ErrUseLastResponse
 	}
-
-	// Check if we hit the rate limit (status 429)
-	if resp.StatusCode == 429 {
-		retryAfter := resp.Header.Get("Retry-After")
-		if retryAfter != "" {
-			if seconds, err := strconv.Atoi(retryAfter); err == nil {
-				sleepDuration := time.Duration(seconds) * time.Second
-				fmt.Printf("🛑 GitLab API rate limit exceeded! Waiting %v as requested by server...\n", sleepDuration)
-				fmt.Printf("   This is normal and helps ensure fair API usage. Please wait...\n")
-				time.Sleep(sleepDuration)
-				return
-			}
-		}
-		// Fallback if no Retry-After header
-		fmt.Printf("🛑 GitLab API rate limit exceeded! Waiting 60 seconds to retry...\n")
-		fmt.Printf("   This is normal and helps ensure fair API usage. Please wait...\n")
-		time.Sleep(60 * time.Second)
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
 	}
+	return nil
 }
 
 // ParseRepoPath parses various GitLab repository path formats
@@ -142,72 +140,60 @@ func ParseRepoPath(repoPath string) (string, string, error) {
 	return "", repoPath, nil
 }
 
-// FetchMergeRequestRefs fetches all merge request references for a given repository and processes them via callback
-func (c *Client) FetchMergeRequestRefs(projectPath string, processor MergeRequestProcessor) error {
-	// List all merge requests for the project
-	opts := &gitlab.ListProjectMergeRequestsOptions{
-		ListOptions: gitlab.ListOptions{
-			PerPage: 100, // GitLab API max per page
-		},
-		State: gitlab.Ptr("all"), // Get both open and closed MRs
-	}
-
-	pageCount := 0
-
-	for {
-		pageCount++
-		// Apply rate limiting before making the list request
-		c.rateLimitWait()
-
-		mrs, resp, err := c.client.MergeRequests.ListProjectMergeRequests(projectPath, opts)
+// ParseGroupPath parses a GitLab group path or URL
+// returns the base URL (if any) and the group path (example: group/subgroup)
+func ParseGroupPath(groupPath string) (string, string, error) {
+	if strings.HasPrefix(groupPath, "http") {
+		u, err := url.Parse(groupPath)
 		if err != nil {
-			return fmt.Errorf("failed to fetch merge requests: %w", err)
+			return "", "", fmt.Errorf("invalid URL: %w", err)
 		}
 
-		fmt.Printf("📋 Processing page %d: found %d merge requests...\n", pageCount, len(mrs))
-
-		// Check rate limit headers from the response
-		c.checkRateLimitHeaders(resp.Response)
+		baseURL := fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+		path := strings.Trim(u.Path, "/")
+		path = strings.TrimPrefix(path, "groups/")
 
-		for _, mr := range mrs {
-			// Apply rate limiting before each detailed request
-			c.rateLimitWait()
+		if path == "" {
+			return "", "", fmt.Errorf("invalid group URL: %s", groupPath)
+		}
 
-			// Fetch detailed merge request to get diff_refs
-			detailedMR, detailResp, err := c.client.MergeRequests.GetMergeRequest(projectPath, mr.IID, nil)
-			if err != nil {
-				return fmt.Errorf("failed to fetch merge request %d: %w", mr.IID, err)
-			}
+		return baseURL, path, nil
+	}
 
-			// Check rate limit headers from the detailed request response
-			c.checkRateLimitHeaders(detailResp.Response)
+	// Unlike repositories, a top-level group is a single path segment
+	if !regexp.MustCompile(`^[a-zA-Z0-9._-]+(/[a-zA-Z0-9._-]+)*$`).MatchString(groupPath) {
+		return "", "", fmt.Errorf("invalid group path format: %s", groupPath)
+	}
 
-			if detailedMR.DiffRefs.HeadSha != "" {
-				ref := MergeRequestRef{
-					ID:      mr.ID,
-					IID:     mr.IID,
-					HeadSHA: detailedMR.DiffRefs.HeadSha,
-				}
+	return "", groupPath, nil
+}
 
-				// Process the merge request via callback
-				if err := processor(ref); err != nil {
-					return fmt.Errorf("failed to process merge request %d: %w", mr.IID, err)
-				}
-			}
+// FetchMergeRequestRefs fetches the merge request references of a project and processes them via callback.
+// By default every merge request is fetched; options narrow or tune the fetch.
+func (c *Client) FetchMergeRequestRefs(ctx context.Context, projectPath string, processor MergeRequestProcessor, options ...FetchOption) error {
+	for ref, err := range c.MergeRequestRefs(ctx, projectPath, options...) {
+		if err != nil {
+			return err
 		}
 
-		// Check if there are more pages
-		if resp.NextPage == 0 {
-			break
+		// Process the merge request via callback
+		if err := processor(ref); err != nil {
+			return fmt.Errorf("failed to process merge request %d: %w", ref.IID, err)
 		}
-		opts.Page = resp.NextPage
 	}
 
 	return nil
 }
 
+// FetchMergeRequestRefsSince fetches only merge requests updated after since; a zero since fetches all of them
+//
+// Deprecated: use FetchMergeRequestRefs with WithUpdatedAfter.
+func (c *Client) FetchMergeRequestRefsSince(ctx context.Context, projectPath string, since time.Time, processor MergeRequestProcessor) error {
+	return c.FetchMergeRequestRefs(ctx, projectPath, processor, WithUpdatedAfter(since))
+}
+
 // FetchMergeRequestRefsFromRepo processes merge request references using a callback
-func (c *Client) FetchMergeRequestRefsFromRepo(repoPath string, baseURLOverride string, processor MergeRequestProcessor) (string, error) {
+func (c *Client) FetchMergeRequestRefsFromRepo(ctx context.Context, repoPath string, baseURLOverride string, processor MergeRequestProcessor, options ...FetchOption) (string, error) {
 	// Parse repository path and determine base URL
 	baseURL, projectPath, err := ParseRepoPath(repoPath)
 	if err != nil {
@@ -219,7 +205,7 @@ func (c *Client) FetchMergeRequestRefsFromRepo(repoPath string, baseURLOverride
 	_ = baseURL
 
 	// Fetch merge request references using callback
-	err = c.FetchMergeRequestRefs(projectPath, processor)
+	err = c.FetchMergeRequestRefs(ctx, projectPath, processor, options...)
 	if err != nil {
 		return "", c.wrapFetchError(err, projectPath)
 	}
@@ -227,42 +213,131 @@ func (c *Client) FetchMergeRequestRefsFromRepo(repoPath string, baseURLOverride
 	return projectPath, nil
 }
 
-// CreateBranch creates a new branch in the GitLab repository
-func (c *Client) CreateBranch(projectPath, branchName, ref string) error {
-	// Apply rate limiting before making the create branch request
-	c.rateLimitWait()
+// FetchMergeRequestRefsFromRepoSince is FetchMergeRequestRefsFromRepo restricted to merge requests updated after since
+//
+// Deprecated: use FetchMergeRequestRefsFromRepo with WithUpdatedAfter.
+func (c *Client) FetchMergeRequestRefsFromRepoSince(ctx context.Context, repoPath string, baseURLOverride string, since time.Time, processor MergeRequestProcessor) (string, error) {
+	return c.FetchMergeRequestRefsFromRepo(ctx, repoPath, baseURLOverride, processor, WithUpdatedAfter(since))
+}
 
+// CreateBranch creates a new branch in the GitLab repository. Rate limited and failed
+// requests are retried, and a branch found at ref after a server error counts as created.
+func (c *Client) CreateBranch(ctx context.Context, projectPath, branchName, ref string) error {
 	createOpts := &gitlab.CreateBranchOptions{
 		Branch: gitlab.Ptr(branchName),
 		Ref:    gitlab.Ptr(ref),
 	}
 
-	_, resp, err := c.client.Branches.CreateBranch(projectPath, createOpts)
+	send := func(options ...gitlab.RequestOptionFunc) (*gitlab.Response, error) {
+		_, resp, err := c.client.Branches.CreateBranch(projectPath, createOpts, options...)
+		return resp, err
+	}
+	settled := func(ctx context.Context) (bool, error) {
+		return c.branchAt(ctx, projectPath, branchName, ref)
+	}
+
+	resp, err := c.mutate(ctx, send, settled)
 	if err != nil {
 		// Check if it's a specific error we can handle
 		if resp != nil && resp.StatusCode == 409 {
-			return fmt.Errorf("branch '%s' already exists", branchName)
+			return &apiError{kind: ErrBranchExists, msg: fmt.Sprintf("branch '%s' already exists", branchName), err: err}
 		}
-		return fmt.Errorf("failed to create branch '%s': %w", branchName, err)
+		return classifyError(err, ErrProjectNotFound, "failed to create branch '%s'", branchName)
 	}
 
-	// Check rate limit headers from the response
-	c.checkRateLimitHeaders(resp.Response)
-
 	return nil
 }
 
 // wrapFetchError provides more helpful error messages for common GitLab API issues
 func (c *Client) wrapFetchError(err error, projectPath string) error {
-	errMsg := err.Error()
+	err = sanitizeError(err)
+	switch StatusCode(err) {
+	case 404:
+		return &apiError{
+			kind: ErrProjectNotFound,
+			msg:  fmt.Sprintf("repository not found: %s. Please check the repository path and your access permissions", projectPath),
+			err:  err,
+		}
+	case 401, 403:
+		return &apiError{
+			kind: ErrUnauthorized,
+			msg:  fmt.Sprintf("authentication failed: please check your GitLab token has access to repository %s", projectPath),
+			err:  err,
+		}
+	case 429:
+		return &apiError{
+			kind: ErrRateLimited,
+			msg:  fmt.Sprintf("rate limit exceeded while fetching from %s: try again later or lower --rps", projectPath),
+			err:  err,
+		}
+	}
+
+	return fmt.Errorf("failed to fetch merge request references from %s: %w", projectPath, err)
+}
 
-	if strings.Contains(errMsg, "404") {
-		return fmt.Errorf("repository not found: %s. Please check the repository path and your access permissions", projectPath)
+// ListBranchSHAs returns the head commit SHA of every branch whose name starts with prefix, keyed by branch name
+func (c *Client) ListBranchSHAs(ctx context.Context, projectPath, prefix string) (map[string]string, error) {
+	opts := &gitlab.ListBranchesOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+		},
+		Search: gitlab.Ptr("^" + prefix),
 	}
 
-	if strings.Contains(errMsg, "401") || strings.Contains(errMsg, "403") {
-		return fmt.Errorf("authentication failed: please check your GitLab token has access to repository %s", projectPath)
+	branches := make(map[string]string)
+
+	for {
+		page, resp, err := c.client.Branches.ListBranches(projectPath, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, classifyError(err, ErrProjectNotFound, "failed to list branches of %s", projectPath)
+		}
+
+		for _, branch := range page {
+			// The search is a hint to the server; re-check the prefix locally
+			if !strings.HasPrefix(branch.Name, prefix) || branch.Commit == nil {
+				continue
+			}
+			branches[branch.Name] = branch.Commit.ID
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
 	}
 
-	return fmt.Errorf("failed to fetch merge request references from %s: %w", projectPath, err)
+	return branches, nil
+}
+
+// BranchSHA returns the head commit SHA of a branch, or ErrBranchNotFound when it does not exist
+func (c *Client) BranchSHA(ctx context.Context, projectPath, branchName string) (string, error) {
+	branch, _, err := c.client.Branches.GetBranch(projectPath, branchName, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", classifyError(err, ErrBranchNotFound, "failed to read branch '%s'", branchName)
+	}
+	if branch.Commit == nil {
+		return "", nil
+	}
+	return branch.Commit.ID, nil
+}
+
+// DeleteBranch deletes a branch from the GitLab repository. Rate limited and failed
+// requests are retried, and a branch found gone after a server error counts as deleted.
+func (c *Client) DeleteBranch(ctx context.Context, projectPath, branchName string) error {
+	send := func(options ...gitlab.RequestOptionFunc) (*gitlab.Response, error) {
+		return c.client.Branches.DeleteBranch(projectPath, branchName, options...)
+	}
+	settled := func(ctx context.Context) (bool, error) {
+		return c.branchGone(ctx, projectPath, branchName)
+	}
+
+	resp, err := c.mutate(ctx, send, settled)
+	if err != nil {
+		if resp != nil && resp.StatusCode == 404 {
+			return &apiError{kind: ErrBranchNotFound, msg: fmt.Sprintf("branch '%s' not found", branchName), err: err}
+		}
+		return classifyError(err, ErrProjectNotFound, "failed to delete branch '%s'", branchName)
+	}
+
+	return nil
 }
@@ -1,6 +1,7 @@
 package gitlab
 
 import (
+	"log/slog"
 	"testing"
 )
 
@@ -125,3 +126,81 @@ func TestParseRepoPath(t *testing.T) {
 		})
 	}
 }
+
+func TestParseGroupPath(t *testing.T) {
+	tests := []struct {
+		name         string
+		groupPath    string
+		expectedBase string
+		expectedPath string
+		expectError  bool
+	}{
+		{
+			name:         "top-level group",
+			groupPath:    "acme",
+			expectedPath: "acme",
+		},
+		{
+			name:         "subgroup",
+			groupPath:    "acme/backend",
+			expectedPath: "acme/backend",
+		},
+		{
+			name:         "group URL",
+			groupPath:    "https://gitlab.example.com/acme/backend",
+			expectedBase: "https://gitlab.example.com",
+			expectedPath: "acme/backend",
+		},
+		{
+			name:         "group URL with groups prefix",
+			groupPath:    "https://gitlab.com/groups/acme/",
+			expectedBase: "https://gitlab.com",
+			expectedPath: "acme",
+		},
+		{
+			name:        "empty",
+			groupPath:   "",
+			expectError: true,
+		},
+		{
+			name:        "URL without path",
+			groupPath:   "https://gitlab.com/",
+			expectError: true,
+		},
+		{
+			name:        "invalid characters",
+			groupPath:   "acme/back end",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseURL, path, err := ParseGroupPath(tt.groupPath)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("ParseGroupPath(%s) expected error, but got none", tt.groupPath)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("ParseGroupPath(%s) unexpected error: %v", tt.groupPath, err)
+				return
+			}
+
+			if baseURL != tt.expectedBase {
+				t.Errorf("ParseGroupPath(%s) baseURL = %s, want %s", tt.groupPath, baseURL, tt.expectedBase)
+			}
+
+			if path != tt.expectedPath {
+				t.Errorf("ParseGroupPath(%s) path = %s, want %s", tt.groupPath, path, tt.expectedPath)
+			}
+		})
+	}
+}
+
+func quietLogger() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
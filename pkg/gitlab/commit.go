@@ -0,0 +1,23 @@
+package gitlab
+
+import (
+	"context"
+	"net/http"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// CommitExists reports whether the project's repository has the commit sha. Commits of
+// merge requests from forks exist in the project through their merge request ref; commits
+// lost to a force push stay only until GitLab's housekeeping prunes them.
+func (c *Client) CommitExists(ctx context.Context, projectPath, sha string) (bool, error) {
+	_, _, err := c.client.Commits.GetCommit(projectPath, sha, nil, gitlab.WithContext(ctx))
+	if StatusCode(err) == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, classifyError(err, ErrProjectNotFound, "failed to look up commit %s", sha)
+	}
+
+	return true, nil
+}
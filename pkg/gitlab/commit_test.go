@@ -0,0 +1,39 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCommitExists(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v4/projects/group/project/repository/commits/aaaaaaa":
+			w.Write([]byte(`{"id": "aaaaaaa"}`))
+		case "/api/v4/projects/group/project/repository/commits/bbbbbbb":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "404 Commit Not Found"}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if ok, err := client.CommitExists(context.Background(), "group/project", "aaaaaaa"); err != nil || !ok {
+		t.Errorf("CommitExists(aaaaaaa) = %v, %v, want true", ok, err)
+	}
+	if ok, err := client.CommitExists(context.Background(), "group/project", "bbbbbbb"); err != nil || ok {
+		t.Errorf("CommitExists(bbbbbbb) = %v, %v, want false", ok, err)
+	}
+	if _, err := client.CommitExists(context.Background(), "group/other", "aaaaaaa"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("CommitExists() error = %v, want ErrUnauthorized", err)
+	}
+}
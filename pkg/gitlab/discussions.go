@@ -0,0 +1,109 @@
+package gitlab
+
+import (
+	"context"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// Discussion is a thread of notes on a merge request. A comment that was never replied to
+// is a discussion of one note with IndividualNote set.
+type Discussion struct {
+	ID             string `json:"discussion_id"`
+	IndividualNote bool   `json:"individual_note"`
+	Notes          []Note `json:"notes"`
+}
+
+// Note is one comment of a discussion
+type Note struct {
+	ID int `json:"id"`
+	// Author is the GitLab username of the note's author, and AuthorName their display name
+	Author     string `json:"author"`
+	AuthorName string `json:"author_name,omitempty"`
+	Body       string `json:"body"`
+	// System is set for notes GitLab writes itself, such as "added 1 commit"
+	System     bool       `json:"system,omitempty"`
+	Internal   bool       `json:"internal,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+	Resolvable bool       `json:"resolvable,omitempty"`
+	Resolved   bool       `json:"resolved,omitempty"`
+	ResolvedBy string     `json:"resolved_by,omitempty"`
+	// Position locates a comment on the diff; it is nil for comments on the merge request itself
+	Position *NotePosition `json:"position,omitempty"`
+}
+
+// NotePosition locates a diff comment: the commits of the diff version it was made on and
+// the line in the old or new file
+type NotePosition struct {
+	PositionType string `json:"position_type"`
+	BaseSHA      string `json:"base_sha"`
+	StartSHA     string `json:"start_sha"`
+	HeadSHA      string `json:"head_sha"`
+	OldPath      string `json:"old_path,omitempty"`
+	NewPath      string `json:"new_path,omitempty"`
+	OldLine      int    `json:"old_line,omitempty"`
+	NewLine      int    `json:"new_line,omitempty"`
+}
+
+// MergeRequestDiscussions lists the discussions of a merge request, oldest first
+func (c *Client) MergeRequestDiscussions(ctx context.Context, projectPath string, iid int) ([]Discussion, error) {
+	opts := &gitlab.ListMergeRequestDiscussionsOptions{PerPage: 100}
+
+	var discussions []Discussion
+
+	for {
+		page, resp, err := c.client.Discussions.ListMergeRequestDiscussions(projectPath, iid, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, classifyError(err, ErrProjectNotFound, "failed to fetch discussions of merge request %d", iid)
+		}
+
+		for _, d := range page {
+			discussion := Discussion{ID: d.ID, IndividualNote: d.IndividualNote}
+			for _, n := range d.Notes {
+				discussion.Notes = append(discussion.Notes, newNote(n))
+			}
+			discussions = append(discussions, discussion)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return discussions, nil
+}
+
+// newNote converts a client-go note
+func newNote(n *gitlab.Note) Note {
+	note := Note{
+		ID:         n.ID,
+		Author:     n.Author.Username,
+		AuthorName: n.Author.Name,
+		Body:       n.Body,
+		System:     n.System,
+		Internal:   n.Internal,
+		UpdatedAt:  n.UpdatedAt,
+		Resolvable: n.Resolvable,
+		Resolved:   n.Resolved,
+		ResolvedBy: n.ResolvedBy.Username,
+	}
+	if n.CreatedAt != nil {
+		note.CreatedAt = *n.CreatedAt
+	}
+	if p := n.Position; p != nil {
+		note.Position = &NotePosition{
+			PositionType: p.PositionType,
+			BaseSHA:      p.BaseSHA,
+			StartSHA:     p.StartSHA,
+			HeadSHA:      p.HeadSHA,
+			OldPath:      p.OldPath,
+			NewPath:      p.NewPath,
+			OldLine:      p.OldLine,
+			NewLine:      p.NewLine,
+		}
+	}
+	return note
+}
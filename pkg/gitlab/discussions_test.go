@@ -0,0 +1,60 @@
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMergeRequestDiscussions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/projects/group/project/merge_requests/1/discussions" {
+			t.Errorf("Unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`[{"id": "d2", "individual_note": true, "notes": [
+				{"id": 3, "body": "added 1 commit", "system": true, "author": {"username": "alice"}, "created_at": "2024-01-03T00:00:00Z"}
+			]}]`))
+			return
+		}
+		w.Header().Set("X-Next-Page", "2")
+		w.Write([]byte(`[{"id": "d1", "individual_note": false, "notes": [
+			{"id": 1, "body": "Use a constant here", "author": {"username": "alice", "name": "Alice"}, "created_at": "2024-01-01T00:00:00Z",
+			 "resolvable": true, "resolved": true, "resolved_by": {"username": "bob"},
+			 "position": {"position_type": "text", "base_sha": "b", "start_sha": "s", "head_sha": "h", "new_path": "main.go", "new_line": 12}},
+			{"id": 2, "body": "Done", "author": {"username": "bob"}, "created_at": "2024-01-02T00:00:00Z"}
+		]}]`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	discussions, err := client.MergeRequestDiscussions(context.Background(), "group/project", 1)
+	if err != nil {
+		t.Fatalf("MergeRequestDiscussions failed: %v", err)
+	}
+
+	if len(discussions) != 2 || discussions[0].ID != "d1" || discussions[1].ID != "d2" {
+		t.Fatalf("Expected discussions d1 and d2 from both pages, got %+v", discussions)
+	}
+
+	first := discussions[0].Notes[0]
+	if first.Author != "alice" || first.AuthorName != "Alice" || first.CreatedAt.IsZero() || !first.Resolved || first.ResolvedBy != "bob" {
+		t.Errorf("Note fields not populated: %+v", first)
+	}
+	if first.Position == nil || first.Position.NewPath != "main.go" || first.Position.NewLine != 12 || first.Position.HeadSHA != "h" {
+		t.Errorf("Position = %+v, want main.go line 12 at h", first.Position)
+	}
+	if discussions[0].Notes[1].Position != nil {
+		t.Errorf("Expected no position for a reply, got %+v", discussions[0].Notes[1].Position)
+	}
+	if !discussions[1].Notes[0].System {
+		t.Error("Expected the system note to be marked")
+	}
+}
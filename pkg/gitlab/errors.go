@@ -0,0 +1,202 @@
+package gitlab
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/redact"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// Errors returned by the client, derived from the GitLab response status.
+// Test for them with errors.Is; a copy of the client-go error, with credentials removed, stays
+// reachable with errors.As.
+var (
+	// ErrProjectNotFound means the project does not exist or is not visible to the token (404)
+	ErrProjectNotFound = errors.New("project not found")
+	// ErrUnauthorized means the token is invalid, expired or lacks access (401 or 403)
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrRateLimited means GitLab kept rejecting requests with 429 after all retries
+	ErrRateLimited = errors.New("rate limited")
+	// ErrBranchExists means a branch with the requested name already exists (409)
+	ErrBranchExists = errors.New("branch already exists")
+	// ErrBranchNotFound means the branch does not exist (404)
+	ErrBranchNotFound = errors.New("branch not found")
+	// ErrProjectMoved means the project was renamed or transferred, and GitLab answered a
+	// request changing it at its old path with a redirect (301, 302, 307 or 308)
+	ErrProjectMoved = errors.New("project moved")
+	// ErrInsufficientAccess means a pre-flight check found the token cannot create the
+	// branches: it lacks the api scope or the role, or they match a protected branch
+	ErrInsufficientAccess = errors.New("insufficient access")
+)
+
+// apiError pairs a readable message with the sentinel error classifying it and the underlying cause
+type apiError struct {
+	kind error
+	msg  string
+	err  error
+}
+
+func (e *apiError) Error() string {
+	return e.msg
+}
+
+func (e *apiError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// StatusCode returns the HTTP status of the GitLab response that caused err, or 0 if
+// err did not come from an error response
+func StatusCode(err error) int {
+	// client-go reports 404 with a sentinel instead of an ErrorResponse
+	if errors.Is(err, gitlab.ErrNotFound) {
+		return 404
+	}
+
+	var errResp *gitlab.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response != nil {
+		return errResp.Response.StatusCode
+	}
+	return 0
+}
+
+// classifyError wraps err with the sentinel matching its status code, leaving other errors unchanged.
+// notFound is the sentinel used for a 404, which depends on what was requested.
+func classifyError(err error, notFound error, format string, args ...any) error {
+	err = sanitizeError(err)
+	msg := fmt.Sprintf(format, args...)
+
+	switch StatusCode(err) {
+	case 401, 403:
+		return &apiError{kind: ErrUnauthorized, msg: fmt.Sprintf("%s: %v", msg, err), err: err}
+	case 404:
+		return &apiError{kind: notFound, msg: fmt.Sprintf("%s: %v", msg, err), err: err}
+	case 429:
+		return &apiError{kind: ErrRateLimited, msg: fmt.Sprintf("%s: %v", msg, err), err: err}
+	case 301, 302, 307, 308:
+		return &apiError{kind: ErrProjectMoved, msg: fmt.Sprintf("%s: the project has moved to %s", msg, movedTo(err)), err: err}
+	default:
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+}
+
+// movedTo returns the project path the redirect err came with points to, or "a new path"
+// when its Location header doesn't name a project
+func movedTo(err error) string {
+	var errResp *gitlab.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil {
+		return "a new path"
+	}
+	location, parseErr := url.Parse(errResp.Response.Header.Get("Location"))
+	if parseErr != nil {
+		return "a new path"
+	}
+	_, rest, ok := strings.Cut(location.EscapedPath(), "/projects/")
+	if !ok {
+		return "a new path"
+	}
+	project, _, _ := strings.Cut(rest, "/")
+	if project, err := url.PathUnescape(project); err == nil && project != "" {
+		return project
+	}
+	return "a new path"
+}
+
+// sanitizedError carries the message of an error with the credentials it contained removed.
+// The errors it wraps are sanitized too, so errors.As never reaches the original.
+type sanitizedError struct {
+	msg string
+	err error
+}
+
+func (e *sanitizedError) Error() string {
+	return e.msg
+}
+
+func (e *sanitizedError) Unwrap() []error {
+	var wrapped []error
+	switch err := e.err.(type) {
+	case interface{ Unwrap() error }:
+		wrapped = []error{err.Unwrap()}
+	case interface{ Unwrap() []error }:
+		wrapped = err.Unwrap()
+	}
+
+	sanitized := make([]error, 0, len(wrapped))
+	for _, err := range wrapped {
+		if err != nil {
+			sanitized = append(sanitized, sanitizeError(err))
+		}
+	}
+	return sanitized
+}
+
+// sanitizeError removes credentials, such as private_token or job_token query parameters and
+// passwords, from the URLs err mentions. net/http reports transport failures with the full
+// request URL, so every error from client-go passes through here before it leaves the
+// package. A *url.Error or *gitlab.ErrorResponse keeps its type as a redacted copy, and
+// errors wrapping them or credentials keep their chain, made of sanitized copies.
+func sanitizeError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch err := err.(type) {
+	case *url.Error:
+		return &url.Error{Op: err.Op, URL: redact.String(err.URL), Err: sanitizeError(err.Err)}
+	case *gitlab.ErrorResponse:
+		return sanitizeErrorResponse(err)
+	}
+
+	// A request can carry the token though the message doesn't, so chains holding one are
+	// copied as well
+	msg := err.Error()
+	if clean := redact.String(msg); clean != msg || carriesRequest(err) {
+		return &sanitizedError{msg: clean, err: err}
+	}
+	return err
+}
+
+// carriesRequest reports whether the chain of err holds a request URL or headers
+func carriesRequest(err error) bool {
+	var urlErr *url.Error
+	var errResp *gitlab.ErrorResponse
+	return errors.As(err, &urlErr) || errors.As(err, &errResp)
+}
+
+// sanitizeErrorResponse copies errResp with its request reduced to the method and the
+// redacted URL, dropping the headers that carry the token
+func sanitizeErrorResponse(errResp *gitlab.ErrorResponse) *gitlab.ErrorResponse {
+	clean := &gitlab.ErrorResponse{Body: []byte(redact.String(string(errResp.Body))), Message: redact.String(errResp.Message)}
+	if errResp.Response == nil {
+		return clean
+	}
+
+	resp := *errResp.Response
+	if req := resp.Request; req != nil {
+		resp.Request = &http.Request{Method: req.Method, URL: redactURL(req.URL)}
+	}
+	clean.Response = &resp
+	return clean
+}
+
+// redactURL returns a copy of u with its credentials removed
+func redactURL(u *url.URL) *url.URL {
+	if u == nil {
+		return nil
+	}
+	clean, err := url.Parse(redact.String(u.String()))
+	if err != nil {
+		return &url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}
+	}
+	return clean
+}
+
+// sanitizingErrorHandler returns client-go's final response and error unchanged, as its
+// default handler does, except for credentials removed from the error
+func sanitizingErrorHandler(resp *http.Response, err error, _ int) (*http.Response, error) {
+	return resp, sanitizeError(err)
+}
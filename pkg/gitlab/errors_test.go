@@ -0,0 +1,220 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		call     func(*Client) error
+		expected error
+	}{
+		{
+			name:   "fetch from missing project",
+			status: http.StatusNotFound,
+			call: func(c *Client) error {
+				_, err := c.FetchMergeRequestRefsFromRepo(context.Background(), "group/project", "", func(MergeRequestRef) error { return nil })
+				return err
+			},
+			expected: ErrProjectNotFound,
+		},
+		{
+			name:   "fetch with invalid token",
+			status: http.StatusUnauthorized,
+			call: func(c *Client) error {
+				_, err := c.FetchMergeRequestRefsFromRepo(context.Background(), "group/project", "", func(MergeRequestRef) error { return nil })
+				return err
+			},
+			expected: ErrUnauthorized,
+		},
+		{
+			name:   "fetch without access",
+			status: http.StatusForbidden,
+			call: func(c *Client) error {
+				_, err := c.FetchMergeRequestRefsFromRepo(context.Background(), "group/project", "", func(MergeRequestRef) error { return nil })
+				return err
+			},
+			expected: ErrUnauthorized,
+		},
+		{
+			name:   "create existing branch",
+			status: http.StatusConflict,
+			call: func(c *Client) error {
+				return c.CreateBranch(context.Background(), "group/project", "migration-pr-1", "abc123")
+			},
+			expected: ErrBranchExists,
+		},
+		{
+			name:   "create in missing project",
+			status: http.StatusNotFound,
+			call: func(c *Client) error {
+				return c.CreateBranch(context.Background(), "group/project", "migration-pr-1", "abc123")
+			},
+			expected: ErrProjectNotFound,
+		},
+		{
+			name:   "delete missing branch",
+			status: http.StatusNotFound,
+			call: func(c *Client) error {
+				return c.DeleteBranch(context.Background(), "group/project", "migration-pr-1")
+			},
+			expected: ErrBranchNotFound,
+		},
+		{
+			name:   "list branches without access",
+			status: http.StatusForbidden,
+			call: func(c *Client) error {
+				_, err := c.ListBranchSHAs(context.Background(), "group/project", "migration-pr-")
+				return err
+			},
+			expected: ErrUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{"message": "error"}`))
+			}))
+			defer server.Close()
+
+			client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+
+			err = tt.call(client)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected error matching %v, got %v", tt.expected, err)
+			}
+			if StatusCode(err) != tt.status {
+				t.Errorf("StatusCode() = %d, want %d", StatusCode(err), tt.status)
+			}
+		})
+	}
+}
+
+func TestStatusCode_NonAPIError(t *testing.T) {
+	if code := StatusCode(errors.New("connection refused")); code != 0 {
+		t.Errorf("StatusCode() = %d, want 0", code)
+	}
+}
+
+func TestSanitizeError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"no credentials", errors.New("connection refused"), "connection refused"},
+		{
+			"url error",
+			&url.Error{Op: "Get", URL: "https://gitlab.example.com/api/v4/projects?private_token=glpat-secret&page=2", Err: errors.New("EOF")},
+			`Get "https://gitlab.example.com/api/v4/projects?private_token=REDACTED&page=2": EOF`,
+		},
+		{
+			"wrapped url error",
+			fmt.Errorf("giving up: %w", &url.Error{Op: "Get", URL: "https://gitlab.example.com/api/v4/jobs?job_token=secret", Err: errors.New("EOF")}),
+			`giving up: Get "https://gitlab.example.com/api/v4/jobs?job_token=REDACTED": EOF`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sanitizeError(tt.err)
+			if err.Error() != tt.expected {
+				t.Errorf("sanitizeError() = %q, want %q", err, tt.expected)
+			}
+
+			var urlErr *url.Error
+			if errors.As(tt.err, &urlErr) && !errors.As(err, &urlErr) {
+				t.Errorf("sanitizeError() lost the *url.Error in %v", err)
+			}
+		})
+	}
+}
+
+func TestSanitizeError_UnwrapsCopies(t *testing.T) {
+	original := &url.Error{Op: "Get", URL: "https://gitlab.example.com/api/v4/projects?private_token=glpat-secret", Err: errors.New("EOF")}
+	err := sanitizeError(fmt.Errorf("giving up: %w", fmt.Errorf("request failed: %w", original)))
+
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		t.Fatalf("sanitizeError() lost the *url.Error in %v", err)
+	}
+	if urlErr == original || strings.Contains(urlErr.URL, "glpat-secret") {
+		t.Errorf("errors.As() reached the original *url.Error: %q", urlErr.URL)
+	}
+	if leaked := fmt.Sprintf("%+v", err); strings.Contains(leaked, "glpat-secret") {
+		t.Errorf("%%+v leaks the token: %s", leaked)
+	}
+}
+
+func TestSanitizeError_ErrorResponse(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "https://gitlab.example.com/api/v4/projects/1?private_token=glpat-secret", nil)
+	req.Header.Set("PRIVATE-TOKEN", "glpat-secret")
+	original := &gitlab.ErrorResponse{Response: &http.Response{StatusCode: http.StatusForbidden, Request: req}, Message: "403 Forbidden"}
+
+	err := sanitizeError(fmt.Errorf("failed: %w", original))
+	if StatusCode(err) != http.StatusForbidden {
+		t.Errorf("StatusCode() = %d, want 403", StatusCode(err))
+	}
+
+	var errResp *gitlab.ErrorResponse
+	if !errors.As(err, &errResp) {
+		t.Fatalf("sanitizeError() lost the *gitlab.ErrorResponse in %v", err)
+	}
+	if errResp == original {
+		t.Fatal("errors.As() reached the original *gitlab.ErrorResponse")
+	}
+	if got := errResp.Response.Request.Header.Get("PRIVATE-TOKEN"); got != "" {
+		t.Errorf("Request header PRIVATE-TOKEN = %q, want none", got)
+	}
+	if strings.Contains(errResp.Response.Request.URL.String(), "glpat-secret") {
+		t.Errorf("Request URL leaks the token: %s", errResp.Response.Request.URL)
+	}
+}
+
+func TestClientErrors_StripCredentials(t *testing.T) {
+	// A port nothing listens on, so the redirected request fails in the transport
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead := listener.Addr().String()
+	listener.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://"+dead+"/api/v4/projects?private_token=glpat-leaked", http.StatusFound)
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	_, err = client.FetchMergeRequestRefsFromRepo(context.Background(), "group/project", "", func(MergeRequestRef) error { return nil })
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if strings.Contains(err.Error(), "glpat-leaked") {
+		t.Errorf("Error contains the token: %v", err)
+	}
+	if !strings.Contains(err.Error(), "private_token=REDACTED") {
+		t.Errorf("Expected the redacted URL in the error, got %v", err)
+	}
+}
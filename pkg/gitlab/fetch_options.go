@@ -0,0 +1,176 @@
+package gitlab
+
+import (
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// defaultPerPage is the page size used when none is configured; it is GitLab's maximum
+const defaultPerPage = 100
+
+// FetchOptions controls which merge requests are fetched and how. Build it with
+// NewFetchOptions; implementations of API use it to interpret FetchOption values.
+type FetchOptions struct {
+	// State limits results to opened, closed, locked or merged merge requests; "all" fetches every state
+	State string
+	// OrderBy is created_at, updated_at or title; empty uses GitLab's default
+	OrderBy string
+	// Sort is asc or desc; empty uses GitLab's default
+	Sort string
+	// PerPage is the number of merge requests requested per page
+	PerPage int
+	// UpdatedAfter, when non-zero, limits results to merge requests updated after this time
+	UpdatedAfter time.Time
+	// CreatedAfter, when non-zero, limits results to merge requests created after this time
+	CreatedAfter time.Time
+	// TargetBranch, when set, limits results to merge requests targeting this branch
+	TargetBranch string
+	// Search, when set, limits results to merge requests whose title or description contains it
+	Search string
+	// IncludeDetail fetches each merge request individually to read its head SHA from diff_refs
+	IncludeDetail bool
+	// AllVersions fetches the diff versions of every merge request into MergeRequestRef.Versions
+	AllVersions bool
+	// IncludeDrafts fetches draft merge requests too
+	IncludeDrafts bool
+	// Skip, when set, leaves out the merge requests whose IID it reports, before any request
+	// is made for their detail
+	Skip func(iid int) bool
+}
+
+// FetchOption configures a merge request fetch
+type FetchOption func(*FetchOptions)
+
+// NewFetchOptions applies options on top of the defaults: every state, 100 per page,
+// with per-merge-request detail, drafts included
+func NewFetchOptions(options ...FetchOption) FetchOptions {
+	opts := FetchOptions{
+		State:         "all",
+		PerPage:       defaultPerPage,
+		IncludeDetail: true,
+		IncludeDrafts: true,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+	return opts
+}
+
+// WithState limits the fetch to merge requests in state: opened, closed, locked, merged or all
+func WithState(state string) FetchOption {
+	return func(opts *FetchOptions) {
+		opts.State = state
+	}
+}
+
+// WithOrder sorts merge requests by orderBy (created_at, updated_at or title) in sort order (asc or desc)
+func WithOrder(orderBy, sort string) FetchOption {
+	return func(opts *FetchOptions) {
+		opts.OrderBy = orderBy
+		opts.Sort = sort
+	}
+}
+
+// WithPerPage sets the page size, between 1 and 100
+func WithPerPage(perPage int) FetchOption {
+	return func(opts *FetchOptions) {
+		opts.PerPage = max(1, min(perPage, defaultPerPage))
+	}
+}
+
+// WithUpdatedAfter fetches only merge requests updated after t; a zero t fetches all of them
+func WithUpdatedAfter(t time.Time) FetchOption {
+	return func(opts *FetchOptions) {
+		opts.UpdatedAfter = t
+	}
+}
+
+// WithCreatedAfter fetches only merge requests created after t; a zero t fetches all of them
+func WithCreatedAfter(t time.Time) FetchOption {
+	return func(opts *FetchOptions) {
+		opts.CreatedAfter = t
+	}
+}
+
+// WithTargetBranch fetches only merge requests targeting branch
+func WithTargetBranch(branch string) FetchOption {
+	return func(opts *FetchOptions) {
+		opts.TargetBranch = branch
+	}
+}
+
+// WithSearch fetches only merge requests whose title or description contains text, matched
+// by GitLab as with the search box of the merge request list
+func WithSearch(text string) FetchOption {
+	return func(opts *FetchOptions) {
+		opts.Search = text
+	}
+}
+
+// WithDetail controls whether each merge request is fetched individually. Without detail
+// the head SHA comes from the list response, saving one request per merge request;
+// GitLab reports the same commit there unless the merge request's source branch is gone.
+func WithDetail(include bool) FetchOption {
+	return func(opts *FetchOptions) {
+		opts.IncludeDetail = include
+	}
+}
+
+// WithAllVersions fetches every diff version of each merge request, one extra request per
+// merge request, so the head SHA of every push is available in MergeRequestRef.Versions
+func WithAllVersions(include bool) FetchOption {
+	return func(opts *FetchOptions) {
+		opts.AllVersions = include
+	}
+}
+
+// WithDrafts controls whether draft merge requests are fetched. They are left out by GitLab's
+// wip filter, so excluding them saves the requests for their pages and detail.
+func WithDrafts(include bool) FetchOption {
+	return func(opts *FetchOptions) {
+		opts.IncludeDrafts = include
+	}
+}
+
+// WithSkip leaves out the merge requests whose IID skip reports, without requesting their
+// detail, for example those an interrupted fetch already got
+func WithSkip(skip func(iid int) bool) FetchOption {
+	return func(opts *FetchOptions) {
+		opts.Skip = skip
+	}
+}
+
+// listOptions converts the options into the client-go list request
+func (opts FetchOptions) listOptions() *gitlab.ListProjectMergeRequestsOptions {
+	listOpts := &gitlab.ListProjectMergeRequestsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: opts.PerPage,
+		},
+	}
+	if opts.State != "" {
+		listOpts.State = gitlab.Ptr(opts.State)
+	}
+	if opts.OrderBy != "" {
+		listOpts.OrderBy = gitlab.Ptr(opts.OrderBy)
+	}
+	if opts.Sort != "" {
+		listOpts.Sort = gitlab.Ptr(opts.Sort)
+	}
+	if !opts.UpdatedAfter.IsZero() {
+		listOpts.UpdatedAfter = gitlab.Ptr(opts.UpdatedAfter)
+	}
+	if !opts.CreatedAfter.IsZero() {
+		listOpts.CreatedAfter = gitlab.Ptr(opts.CreatedAfter)
+	}
+	if opts.TargetBranch != "" {
+		listOpts.TargetBranch = gitlab.Ptr(opts.TargetBranch)
+	}
+	if opts.Search != "" {
+		listOpts.Search = gitlab.Ptr(opts.Search)
+	}
+	if !opts.IncludeDrafts {
+		listOpts.WIP = gitlab.Ptr("no")
+	}
+	return listOpts
+}
@@ -0,0 +1,163 @@
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewFetchOptions(t *testing.T) {
+	defaults := NewFetchOptions()
+	if defaults.State != "all" || defaults.PerPage != 100 || !defaults.IncludeDetail || !defaults.IncludeDrafts {
+		t.Errorf("Unexpected defaults %+v", defaults)
+	}
+
+	tests := []struct {
+		name    string
+		option  FetchOption
+		perPage int
+	}{
+		{"per page within range", WithPerPage(20), 20},
+		{"per page above maximum", WithPerPage(500), 100},
+		{"per page below minimum", WithPerPage(0), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewFetchOptions(tt.option).PerPage; got != tt.perPage {
+				t.Errorf("PerPage = %d, want %d", got, tt.perPage)
+			}
+		})
+	}
+}
+
+func TestFetchOptions_ListOptions(t *testing.T) {
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	listOpts := NewFetchOptions(
+		WithState("merged"),
+		WithOrder("updated_at", "asc"),
+		WithUpdatedAfter(since),
+		WithCreatedAfter(since),
+		WithTargetBranch("main"),
+		WithSearch("JIRA-1234"),
+	).listOptions()
+
+	if *listOpts.State != "merged" {
+		t.Errorf("State = %q, want merged", *listOpts.State)
+	}
+	if *listOpts.OrderBy != "updated_at" || *listOpts.Sort != "asc" {
+		t.Errorf("Order = %q %q, want updated_at asc", *listOpts.OrderBy, *listOpts.Sort)
+	}
+	if !listOpts.UpdatedAfter.Equal(since) || !listOpts.CreatedAfter.Equal(since) {
+		t.Errorf("Time filters = %v, %v, want %v", listOpts.UpdatedAfter, listOpts.CreatedAfter, since)
+	}
+	if *listOpts.TargetBranch != "main" {
+		t.Errorf("TargetBranch = %q, want main", *listOpts.TargetBranch)
+	}
+	if *listOpts.Search != "JIRA-1234" {
+		t.Errorf("Search = %q, want JIRA-1234", *listOpts.Search)
+	}
+
+	empty := NewFetchOptions().listOptions()
+	if empty.OrderBy != nil || empty.UpdatedAfter != nil || empty.TargetBranch != nil || empty.Search != nil || empty.WIP != nil {
+		t.Error("Unset options should not be sent")
+	}
+
+	if wip := NewFetchOptions(WithDrafts(false)).listOptions().WIP; wip == nil || *wip != "no" {
+		t.Errorf("WIP = %v, want no", wip)
+	}
+}
+
+func TestMergeRequestRefs_WithoutDrafts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("wip"); got != "no" {
+			t.Errorf("wip = %q, want no", got)
+		}
+		// A server that ignores the filter
+		w.Write([]byte(`[{"id": 101, "iid": 1, "sha": "abc"}, {"id": 102, "iid": 2, "sha": "def", "draft": true}]`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	var iids []int
+	for ref, err := range client.MergeRequestRefs(context.Background(), "group/project", WithDetail(false), WithDrafts(false)) {
+		if err != nil {
+			t.Fatalf("MergeRequestRefs failed: %v", err)
+		}
+		iids = append(iids, ref.IID)
+	}
+	if len(iids) != 1 || iids[0] != 1 {
+		t.Errorf("Expected only merge request 1, got %v", iids)
+	}
+}
+
+func TestMergeRequestRefs_WithoutDetail(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/projects/group/project/merge_requests" {
+			t.Errorf("Unexpected request %s; detail requests should be skipped", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("state"); got != "opened" {
+			t.Errorf("state = %q, want opened", got)
+		}
+		w.Write([]byte(`[{"id": 101, "iid": 1, "sha": "abc", "title": "First"}, {"id": 102, "iid": 2, "sha": ""}]`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	var refs []MergeRequestRef
+	err = client.FetchMergeRequestRefs(context.Background(), "group/project", func(ref MergeRequestRef) error {
+		refs = append(refs, ref)
+		return nil
+	}, WithDetail(false), WithState("opened"))
+	if err != nil {
+		t.Fatalf("FetchMergeRequestRefs failed: %v", err)
+	}
+
+	if len(refs) != 1 || refs[0].IID != 1 || refs[0].HeadSHA != "abc" {
+		t.Fatalf("Expected merge request 1 at abc, got %v", refs)
+	}
+	if refs[0].MergeRequest == nil || refs[0].MergeRequest.Title != "First" {
+		t.Errorf("Expected the list fields to be available, got %+v", refs[0].MergeRequest)
+	}
+}
+
+func TestMergeRequestRefs_WithSkip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v4/projects/group/project/merge_requests/1" {
+			t.Error("The detail of a skipped merge request was requested")
+		}
+		if r.URL.Path == "/api/v4/projects/group/project/merge_requests/2" {
+			w.Write([]byte(`{"id": 102, "iid": 2, "diff_refs": {"head_sha": "def"}}`))
+			return
+		}
+		w.Write([]byte(`[{"id": 101, "iid": 1, "sha": "abc"}, {"id": 102, "iid": 2, "sha": "def"}]`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	var iids []int
+	for ref, err := range client.MergeRequestRefs(context.Background(), "group/project", WithSkip(func(iid int) bool { return iid == 1 })) {
+		if err != nil {
+			t.Fatalf("MergeRequestRefs failed: %v", err)
+		}
+		iids = append(iids, ref.IID)
+	}
+	if len(iids) != 1 || iids[0] != 2 {
+		t.Errorf("Expected only merge request 2, got %v", iids)
+	}
+}
@@ -0,0 +1,33 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// MergeRequestHeadRef is the ref GitLab keeps in the target project pointing at the head of
+// merge request iid. It is the only ref reaching the commits of a merge request from a fork.
+func MergeRequestHeadRef(iid int) string {
+	return fmt.Sprintf("refs/merge-requests/%d/head", iid)
+}
+
+// isFork reports whether the source branch of mr is in another project than the merge request
+func isFork(mr *gitlab.BasicMergeRequest) bool {
+	return mr.SourceProjectID != 0 && mr.SourceProjectID != mr.ProjectID
+}
+
+// mergeRequestHeadSHA resolves MergeRequestHeadRef of merge request iid to its commit SHA,
+// returning "" when GitLab has no such ref
+func (c *Client) mergeRequestHeadSHA(ctx context.Context, projectPath string, iid int) (string, error) {
+	commit, _, err := c.client.Commits.GetCommit(projectPath, MergeRequestHeadRef(iid), nil, gitlab.WithContext(ctx))
+	if StatusCode(err) == 404 {
+		return "", nil
+	}
+	if err != nil {
+		return "", classifyError(err, ErrProjectNotFound, "failed to resolve %s", MergeRequestHeadRef(iid))
+	}
+
+	return commit.ID, nil
+}
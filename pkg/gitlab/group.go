@@ -0,0 +1,87 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// ProjectFilter selects projects by glob patterns matched against their path
+// relative to the group (for example "backend/api" in group "acme").
+// Patterns use path.Match syntax, so "*" does not cross a "/".
+type ProjectFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// Validate checks that every pattern is a well-formed glob
+func (f ProjectFilter) Validate() error {
+	for _, pattern := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid project pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Match reports whether a project path is selected: it must match at least one
+// include pattern (when any are given) and no exclude pattern
+func (f ProjectFilter) Match(relativePath string) bool {
+	if len(f.Include) > 0 && !matchAny(f.Include, relativePath) {
+		return false
+	}
+	return !matchAny(f.Exclude, relativePath)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ListGroupProjects returns the full paths of all projects in a group and its subgroups
+// that are selected by filter
+func (c *Client) ListGroupProjects(ctx context.Context, groupPath string, filter ProjectFilter) ([]string, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	opts := &gitlab.ListGroupProjectsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+		},
+		IncludeSubGroups: gitlab.Ptr(true),
+		Simple:           gitlab.Ptr(true),
+		OrderBy:          gitlab.Ptr("path"),
+		Sort:             gitlab.Ptr("asc"),
+	}
+
+	var projects []string
+
+	for {
+		page, resp, err := c.client.Groups.ListGroupProjects(groupPath, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, c.wrapFetchError(err, groupPath)
+		}
+
+		for _, project := range page {
+			relativePath := strings.TrimPrefix(project.PathWithNamespace, groupPath+"/")
+			if filter.Match(relativePath) {
+				projects = append(projects, project.PathWithNamespace)
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return projects, nil
+}
@@ -0,0 +1,75 @@
+package gitlab
+
+import (
+	"testing"
+)
+
+func TestProjectFilterMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   ProjectFilter
+		path     string
+		expected bool
+	}{
+		{
+			name:     "no patterns matches everything",
+			filter:   ProjectFilter{},
+			path:     "backend/api",
+			expected: true,
+		},
+		{
+			name:     "include match",
+			filter:   ProjectFilter{Include: []string{"backend/*"}},
+			path:     "backend/api",
+			expected: true,
+		},
+		{
+			name:     "include miss",
+			filter:   ProjectFilter{Include: []string{"backend/*"}},
+			path:     "frontend/web",
+			expected: false,
+		},
+		{
+			name:     "star does not cross slash",
+			filter:   ProjectFilter{Include: []string{"backend/*"}},
+			path:     "backend/services/api",
+			expected: false,
+		},
+		{
+			name:     "exclude wins over include",
+			filter:   ProjectFilter{Include: []string{"backend/*"}, Exclude: []string{"*/archive-*"}},
+			path:     "backend/archive-2019",
+			expected: false,
+		},
+		{
+			name:     "exclude only",
+			filter:   ProjectFilter{Exclude: []string{"*/archive-*"}},
+			path:     "frontend/web",
+			expected: true,
+		},
+		{
+			name:     "any of several includes",
+			filter:   ProjectFilter{Include: []string{"backend/*", "tools"}},
+			path:     "tools",
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.path); got != tt.expected {
+				t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProjectFilterValidate(t *testing.T) {
+	if err := (ProjectFilter{Include: []string{"backend/*"}}).Validate(); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+
+	if err := (ProjectFilter{Exclude: []string{"[unclosed"}}).Validate(); err == nil {
+		t.Error("Validate() expected error for malformed pattern, got nil")
+	}
+}
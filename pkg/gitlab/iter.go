@@ -0,0 +1,193 @@
+package gitlab
+
+import (
+	"context"
+	"iter"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// MergeRequestRefs returns an iterator over the merge request references of a project.
+// Pages are fetched lazily as the loop advances, so breaking out of the loop stops
+// further API requests. Once the loop has taken the first reference of a page, the next
+// page is requested in the background, so the list request overlaps the per-merge request
+// requests instead of stalling the loop between pages; breaking out cancels it. A fetch
+// error is yielded once and ends the iteration.
+// Merge requests without a head SHA are skipped. The head of a merge request from a fork
+// is resolved through MergeRequestHeadRef when it is not read from the merge request's
+// diff_refs, since the fork's branch may be ahead of what GitLab copied into the project.
+func (c *Client) MergeRequestRefs(ctx context.Context, projectPath string, options ...FetchOption) iter.Seq2[MergeRequestRef, error] {
+	opts := NewFetchOptions(options...)
+
+	return func(yield func(MergeRequestRef, error) bool) {
+		// Cancels a prefetched page the loop no longer wants
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		pageCount := 0
+		page := c.listMergeRequestPage(ctx, projectPath, opts.listOptions())
+
+		for {
+			pageCount++
+			if page.err != nil {
+				yield(MergeRequestRef{}, page.err)
+				return
+			}
+			mrs, resp := page.mrs, page.resp
+
+			c.logger.Info("processing merge request page", "project", projectPath, "page", pageCount, "count", len(mrs))
+
+			var next chan mergeRequestPage
+			prefetch := func() {
+				if next != nil || resp.NextPage == 0 {
+					return
+				}
+				listOpts := opts.listOptions()
+				listOpts.Page = resp.NextPage
+				ch := make(chan mergeRequestPage, 1)
+				go func() {
+					ch <- c.listMergeRequestPage(ctx, projectPath, listOpts)
+				}()
+				next = ch
+			}
+
+			for i, mr := range mrs {
+				if i > 0 {
+					prefetch()
+				}
+
+				// Skip drafts locally too, for servers that don't apply the wip filter
+				if !opts.IncludeDrafts && mr.Draft {
+					continue
+				}
+				if opts.Skip != nil && opts.Skip(mr.IID) {
+					continue
+				}
+
+				full := &gitlab.MergeRequest{BasicMergeRequest: *mr}
+				headSHA := mr.SHA
+
+				if opts.IncludeDetail {
+					// Fetch detailed merge request to get diff_refs
+					detailedMR, _, err := c.client.MergeRequests.GetMergeRequest(projectPath, mr.IID, nil, gitlab.WithContext(ctx))
+					if err != nil {
+						yield(MergeRequestRef{}, classifyError(err, ErrProjectNotFound, "failed to fetch merge request %d", mr.IID))
+						return
+					}
+
+					full = detailedMR
+					headSHA = detailedMR.DiffRefs.HeadSha
+				}
+
+				var versions []MergeRequestVersion
+				if opts.AllVersions || (opts.IncludeDetail && headSHA == "") {
+					// Some GitLab versions return empty diff_refs for old merge requests,
+					// but their versions still carry the head SHAs
+					var err error
+					versions, err = c.MergeRequestVersions(ctx, projectPath, mr.IID)
+					if err != nil {
+						yield(MergeRequestRef{}, err)
+						return
+					}
+					if headSHA == "" {
+						headSHA = latestHeadSHA(versions)
+					}
+				}
+
+				fork := isFork(mr)
+				if fork && (!opts.IncludeDetail || headSHA == "") {
+					sha, err := c.mergeRequestHeadSHA(ctx, projectPath, mr.IID)
+					if err != nil {
+						yield(MergeRequestRef{}, err)
+						return
+					}
+					if sha != "" {
+						headSHA = sha
+					}
+				}
+
+				if headSHA == "" {
+					continue
+				}
+
+				ref := MergeRequestRef{
+					ID:           mr.ID,
+					IID:          mr.IID,
+					HeadSHA:      headSHA,
+					SourceBranch: mr.SourceBranch,
+					Fork:         fork,
+					MergeRequest: full,
+				}
+				if opts.AllVersions {
+					ref.Versions = versions
+				}
+				if !yield(ref, nil) {
+					return
+				}
+			}
+
+			// Check if there are more pages
+			if resp.NextPage == 0 {
+				return
+			}
+			prefetch()
+			page = <-next
+		}
+	}
+}
+
+// mergeRequestPage is a page of a merge request list, or the error fetching it
+type mergeRequestPage struct {
+	mrs  []*gitlab.BasicMergeRequest
+	resp *gitlab.Response
+	err  error
+}
+
+// listMergeRequestPage fetches the page of a project's merge requests listOpts selects
+func (c *Client) listMergeRequestPage(ctx context.Context, projectPath string, listOpts *gitlab.ListProjectMergeRequestsOptions) mergeRequestPage {
+	mrs, resp, err := c.client.MergeRequests.ListProjectMergeRequests(projectPath, listOpts, gitlab.WithContext(ctx))
+	if err != nil {
+		return mergeRequestPage{err: classifyError(err, ErrProjectNotFound, "failed to fetch merge requests")}
+	}
+
+	return mergeRequestPage{mrs: mrs, resp: resp}
+}
+
+// MergeRequests returns an iterator over the merge requests of a project as the list
+// endpoint returns them. Unlike MergeRequestRefs it yields merge requests without a head
+// SHA too and makes no request per merge request, for callers that only need what the
+// listing carries. Drafts and options.Skip are honoured; the detail and version options
+// are ignored. A fetch error is yielded once and ends the iteration.
+func (c *Client) MergeRequests(ctx context.Context, projectPath string, options ...FetchOption) iter.Seq2[*gitlab.BasicMergeRequest, error] {
+	opts := NewFetchOptions(options...)
+
+	return func(yield func(*gitlab.BasicMergeRequest, error) bool) {
+		listOpts := opts.listOptions()
+		for pageCount := 1; ; pageCount++ {
+			page := c.listMergeRequestPage(ctx, projectPath, listOpts)
+			if page.err != nil {
+				yield(nil, page.err)
+				return
+			}
+
+			c.logger.Info("processing merge request page", "project", projectPath, "page", pageCount, "count", len(page.mrs))
+
+			for _, mr := range page.mrs {
+				if !opts.IncludeDrafts && mr.Draft {
+					continue
+				}
+				if opts.Skip != nil && opts.Skip(mr.IID) {
+					continue
+				}
+				if !yield(mr, nil) {
+					return
+				}
+			}
+
+			if page.resp.NextPage == 0 {
+				return
+			}
+			listOpts.Page = page.resp.NextPage
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newMergeRequestServer serves two pages of two merge requests each and counts list requests
+func newMergeRequestServer(t *testing.T, listRequests *int32) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const prefix = "/api/v4/projects/group/project/merge_requests"
+		switch {
+		case r.URL.Path == prefix:
+			atomic.AddInt32(listRequests, 1)
+			if r.URL.Query().Get("page") == "2" {
+				w.Write([]byte(`[{"id": 103, "iid": 3}, {"id": 104, "iid": 4}]`))
+				return
+			}
+			w.Header().Set("X-Next-Page", "2")
+			w.Write([]byte(`[{"id": 101, "iid": 1}, {"id": 102, "iid": 2}]`))
+		case strings.HasSuffix(r.URL.Path, "/versions"):
+			// No versions either, so merge request 2 is skipped
+			w.Write([]byte(`[]`))
+		case strings.HasPrefix(r.URL.Path, prefix+"/"):
+			iid := strings.TrimPrefix(r.URL.Path, prefix+"/")
+			sha := "sha" + iid
+			if iid == "2" {
+				sha = "" // a merge request without diff refs is skipped
+			}
+			fmt.Fprintf(w, `{"iid": %s, "title": "MR %s", "diff_refs": {"head_sha": %q}}`, iid, iid, sha)
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestMergeRequestRefs(t *testing.T) {
+	var listRequests int32
+	server := newMergeRequestServer(t, &listRequests)
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	var iids []int
+	for ref, err := range client.MergeRequestRefs(context.Background(), "group/project") {
+		if err != nil {
+			t.Fatalf("MergeRequestRefs yielded error: %v", err)
+		}
+		if ref.HeadSHA != fmt.Sprintf("sha%d", ref.IID) {
+			t.Errorf("Merge request %d has head SHA %q", ref.IID, ref.HeadSHA)
+		}
+		if ref.MergeRequest == nil || ref.MergeRequest.Title != fmt.Sprintf("MR %d", ref.IID) {
+			t.Errorf("Merge request %d should carry the detailed merge request, got %+v", ref.IID, ref.MergeRequest)
+		}
+		iids = append(iids, ref.IID)
+	}
+
+	if fmt.Sprint(iids) != "[1 3 4]" {
+		t.Errorf("Expected merge requests [1 3 4], got %v", iids)
+	}
+	if listRequests != 2 {
+		t.Errorf("Expected 2 page requests, got %d", listRequests)
+	}
+}
+
+func TestMergeRequestRefs_EarlyTermination(t *testing.T) {
+	var listRequests int32
+	server := newMergeRequestServer(t, &listRequests)
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	for ref, err := range client.MergeRequestRefs(context.Background(), "group/project") {
+		if err != nil {
+			t.Fatalf("MergeRequestRefs yielded error: %v", err)
+		}
+		if ref.IID == 1 {
+			break
+		}
+	}
+
+	if listRequests != 1 {
+		t.Errorf("Breaking out of the loop should stop pagination, got %d page requests", listRequests)
+	}
+}
+
+func TestMergeRequestRefs_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message": "403 Forbidden"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	errors := 0
+	for _, err := range client.MergeRequestRefs(context.Background(), "group/project") {
+		if err == nil {
+			t.Error("Expected only an error to be yielded")
+		}
+		errors++
+	}
+
+	if errors != 1 {
+		t.Errorf("Expected exactly one error, got %d", errors)
+	}
+}
+
+func TestMergeRequestRefs_Fork(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/api/v4/projects/group%2Fproject/merge_requests":
+			// Merge request 2 comes from a fork, whose branch was pushed since GitLab copied it
+			w.Write([]byte(`[
+				{"id": 101, "iid": 1, "project_id": 7, "source_project_id": 7, "sha": "sha1"},
+				{"id": 102, "iid": 2, "project_id": 7, "source_project_id": 9, "sha": "fork-ahead"}
+			]`))
+		case "/api/v4/projects/group%2Fproject/repository/commits/refs%2Fmerge-requests%2F2%2Fhead":
+			w.Write([]byte(`{"id": "sha2"}`))
+		default:
+			t.Errorf("Unexpected request %s", r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	var refs []MergeRequestRef
+	for ref, err := range client.MergeRequestRefs(context.Background(), "group/project", WithDetail(false)) {
+		if err != nil {
+			t.Fatalf("MergeRequestRefs yielded error: %v", err)
+		}
+		refs = append(refs, ref)
+	}
+
+	if len(refs) != 2 {
+		t.Fatalf("Expected 2 merge requests, got %d", len(refs))
+	}
+	if refs[0].Fork || refs[0].HeadSHA != "sha1" {
+		t.Errorf("Merge request 1 = fork %v at %q, want not a fork at sha1", refs[0].Fork, refs[0].HeadSHA)
+	}
+	if !refs[1].Fork || refs[1].HeadSHA != "sha2" {
+		t.Errorf("Merge request 2 = fork %v at %q, want a fork resolved through its merge request ref to sha2", refs[1].Fork, refs[1].HeadSHA)
+	}
+}
+
+func TestMergeRequestRefs_PrefetchesNextPage(t *testing.T) {
+	page2Requested := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const prefix = "/api/v4/projects/group/project/merge_requests"
+		switch {
+		case r.URL.Path == prefix && r.URL.Query().Get("page") == "2":
+			close(page2Requested)
+			w.Write([]byte(`[{"id": 103, "iid": 3}]`))
+		case r.URL.Path == prefix:
+			w.Header().Set("X-Next-Page", "2")
+			w.Write([]byte(`[{"id": 101, "iid": 1}, {"id": 102, "iid": 2}]`))
+		case r.URL.Path == prefix+"/2":
+			// The detail of the last merge request of page 1 is only answered once page 2 was requested
+			select {
+			case <-page2Requested:
+			case <-time.After(5 * time.Second):
+				t.Error("Page 2 was not requested while page 1 was processed")
+			}
+			fallthrough
+		default:
+			iid := strings.TrimPrefix(r.URL.Path, prefix+"/")
+			fmt.Fprintf(w, `{"iid": %s, "diff_refs": {"head_sha": "sha%s"}}`, iid, iid)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	var iids []int
+	for ref, err := range client.MergeRequestRefs(context.Background(), "group/project") {
+		if err != nil {
+			t.Fatalf("MergeRequestRefs yielded error: %v", err)
+		}
+		iids = append(iids, ref.IID)
+	}
+	if fmt.Sprint(iids) != "[1 2 3]" {
+		t.Errorf("Expected merge requests [1 2 3], got %v", iids)
+	}
+}
+
+func TestMergeRequests(t *testing.T) {
+	var listRequests int32
+	server := newMergeRequestServer(t, &listRequests)
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	var iids []int
+	for mr, err := range client.MergeRequests(context.Background(), "group/project") {
+		if err != nil {
+			t.Fatalf("MergeRequests yielded error: %v", err)
+		}
+		iids = append(iids, mr.IID)
+	}
+
+	// Merge request 2 has no head SHA but is listed all the same
+	if fmt.Sprint(iids) != "[1 2 3 4]" {
+		t.Errorf("Expected merge requests [1 2 3 4], got %v", iids)
+	}
+	if listRequests != 2 {
+		t.Errorf("Expected 2 page requests, got %d", listRequests)
+	}
+}
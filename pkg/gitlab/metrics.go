@@ -0,0 +1,117 @@
+package gitlab
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// Metrics collects request statistics for a client. It is safe for concurrent use
+// and may be shared by several clients to aggregate a whole run.
+type Metrics struct {
+	mu          sync.Mutex
+	requests    int
+	retries     int
+	rateLimited int
+	errors      int
+	latencies   []time.Duration
+}
+
+// MetricsSnapshot is a point-in-time summary of collected metrics
+type MetricsSnapshot struct {
+	Requests    int           `json:"requests"`
+	Retries     int           `json:"retries"`
+	RateLimited int           `json:"rate_limited"`
+	Errors      int           `json:"errors"`
+	LatencyP50  time.Duration `json:"latency_p50_ns"`
+	LatencyP90  time.Duration `json:"latency_p90_ns"`
+	LatencyP99  time.Duration `json:"latency_p99_ns"`
+	LatencyMax  time.Duration `json:"latency_max_ns"`
+}
+
+// NewMetrics creates an empty metrics collector
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+// observe records one HTTP attempt
+func (m *Metrics) observe(latency time.Duration, resp *http.Response, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests++
+	m.latencies = append(m.latencies, latency)
+
+	switch {
+	case err != nil:
+		m.errors++
+	case resp.StatusCode == http.StatusTooManyRequests:
+		m.rateLimited++
+	case resp.StatusCode >= 500:
+		m.errors++
+	}
+}
+
+// observeRetry records that an attempt was a retry of an earlier one
+func (m *Metrics) observeRetry() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.retries++
+}
+
+// Snapshot summarizes the metrics collected so far
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sorted := slices.Clone(m.latencies)
+	slices.Sort(sorted)
+
+	s := MetricsSnapshot{
+		Requests:    m.requests,
+		Retries:     m.retries,
+		RateLimited: m.rateLimited,
+		Errors:      m.errors,
+		LatencyP50:  percentile(sorted, 0.50),
+		LatencyP90:  percentile(sorted, 0.90),
+		LatencyP99:  percentile(sorted, 0.99),
+	}
+	if len(sorted) > 0 {
+		s.LatencyMax = sorted[len(sorted)-1]
+	}
+	return s
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	rank = max(0, min(rank, len(sorted)-1))
+	return sorted[rank]
+}
+
+// metricsTransport times every HTTP attempt, including retries
+type metricsTransport struct {
+	next    http.RoundTripper
+	metrics *Metrics
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	t.metrics.observe(time.Since(start), resp, err)
+	return resp, err
+}
+
+// retryHook counts retries; client-go calls it before every attempt with a zero-based attempt number
+func (m *Metrics) retryHook(_ retryablehttp.Logger, _ *http.Request, attempt int) {
+	if attempt > 0 {
+		m.observeRetry()
+	}
+}
@@ -0,0 +1,71 @@
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMetrics_CountsRetriesAndRateLimits(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"name": "migration-pr-1"}`))
+	}))
+	defer server.Close()
+
+	metrics := NewMetrics()
+	client, err := NewClient("token", server.URL, WithMetrics(metrics), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if err := client.CreateBranch(context.Background(), "group/project", "migration-pr-1", "abc123"); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+
+	s := metrics.Snapshot()
+	if s.Requests != 2 || s.Retries != 1 || s.RateLimited != 1 || s.Errors != 0 {
+		t.Errorf("Snapshot() = %+v, want 2 requests, 1 retry, 1 rate limited", s)
+	}
+	if s.LatencyMax <= 0 {
+		t.Errorf("Expected a positive max latency, got %v", s.LatencyMax)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		p        float64
+		expected time.Duration
+	}{
+		{0.50, 50 * time.Millisecond},
+		{0.90, 90 * time.Millisecond},
+		{0.99, 99 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); got != tt.expected {
+			t.Errorf("percentile(%v) = %v, want %v", tt.p, got, tt.expected)
+		}
+	}
+
+	if got := percentile(nil, 0.5); got != 0 {
+		t.Errorf("percentile of empty slice = %v, want 0", got)
+	}
+	if got := percentile(sorted[:1], 0.99); got != time.Millisecond {
+		t.Errorf("percentile of single value = %v, want 1ms", got)
+	}
+}
@@ -0,0 +1,118 @@
+package gitlab
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+const (
+	// mutationRetries is how many times a branch creation or deletion is sent again after a
+	// rate limit or server error
+	mutationRetries = 4
+	// mutationBackoff is the wait before the first retry without a Retry-After header; it
+	// doubles with every retry
+	mutationBackoff = time.Second
+	// maxMutationWait caps the wait before one retry, whatever Retry-After asks for
+	maxMutationWait = 5 * time.Minute
+)
+
+// noRetry keeps client-go from resending a mutation itself, since a resent request cannot
+// tell whether the failed one took effect
+func noRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	return false, err
+}
+
+// mutate sends a branch mutation with send, retrying after 429 and 5xx responses. A 5xx may
+// come back for a request GitLab carried out, so before each retry following one, settled
+// reports whether the mutation already took effect, which ends the retries successfully.
+func (c *Client) mutate(ctx context.Context, send func(options ...gitlab.RequestOptionFunc) (*gitlab.Response, error), settled func(ctx context.Context) (bool, error)) (*gitlab.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := send(gitlab.WithContext(ctx), gitlab.WithRequestRetry(noRetry))
+		if err == nil || attempt >= mutationRetries || !transient(resp) {
+			return resp, err
+		}
+
+		delay := retryDelay(resp.Response, attempt)
+		c.logger.Warn("retrying GitLab request", "status", resp.StatusCode, "attempt", attempt+1, "delay", delay.Round(time.Millisecond))
+		if !sleep(ctx, delay) {
+			return resp, err
+		}
+		if c.metrics != nil {
+			c.metrics.observeRetry()
+		}
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			done, checkErr := settled(ctx)
+			if checkErr != nil {
+				return resp, err
+			}
+			if done {
+				c.logger.Info("GitLab carried out the failed request")
+				return resp, nil
+			}
+		}
+	}
+}
+
+// transient reports whether a request that failed with resp may succeed when sent again
+func transient(resp *gitlab.Response) bool {
+	return resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError)
+}
+
+// retryDelay returns how long to wait before retrying after resp: its Retry-After header, or
+// an exponential backoff, with up to 50% jitter added so parallel runs don't retry in step
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	delay := mutationBackoff << attempt
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		delay = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(resp.Header.Get("Retry-After")); err == nil {
+		delay = max(0, time.Until(at))
+	}
+	delay = min(delay, maxMutationWait)
+	return delay + time.Duration(rand.Int64N(int64(delay/2)+1))
+}
+
+// sleep pauses for d, returning false when ctx is done first
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// branchAt reports whether branchName exists and points at ref, a full or abbreviated SHA
+func (c *Client) branchAt(ctx context.Context, projectPath, branchName, ref string) (bool, error) {
+	branch, _, err := c.client.Branches.GetBranch(projectPath, branchName, gitlab.WithContext(ctx))
+	if StatusCode(err) == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return branch.Commit != nil && ref != "" && strings.HasPrefix(branch.Commit.ID, ref), nil
+}
+
+// branchGone reports whether branchName no longer exists
+func (c *Client) branchGone(ctx context.Context, projectPath, branchName string) (bool, error) {
+	_, _, err := c.client.Branches.GetBranch(projectPath, branchName, gitlab.WithContext(ctx))
+	if StatusCode(err) == http.StatusNotFound {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
@@ -0,0 +1,159 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCreateBranch_RetriesAfterRateLimit(t *testing.T) {
+	var posts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Unexpected %s %s: a rate limited request needs no existence check", r.Method, r.URL.Path)
+		}
+		if atomic.AddInt32(&posts, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"name": "migration-pr-1"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if err := client.CreateBranch(context.Background(), "group/project", "migration-pr-1", "abc1234"); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+	if posts != 2 {
+		t.Errorf("Expected 2 create requests, got %d", posts)
+	}
+}
+
+func TestCreateBranch_CreatedDespiteServerError(t *testing.T) {
+	var posts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			// GitLab created the branch but the response was lost
+			atomic.AddInt32(&posts, 1)
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusBadGateway)
+		case http.MethodGet:
+			w.Write([]byte(`{"name": "migration-pr-1", "commit": {"id": "abc1234def5678abc1234def5678abc1234def56"}}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if err := client.CreateBranch(context.Background(), "group/project", "migration-pr-1", "abc1234"); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+	if posts != 1 {
+		t.Errorf("Expected the branch not to be created again, got %d create requests", posts)
+	}
+}
+
+func TestDeleteBranch_GivesUpAfterRetries(t *testing.T) {
+	var deletes int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			atomic.AddInt32(&deletes, 1)
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		// The branch is still there
+		w.Write([]byte(`{"name": "migration-pr-1", "commit": {"id": "abc1234"}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	err = client.DeleteBranch(context.Background(), "group/project", "migration-pr-1")
+	if StatusCode(err) != http.StatusServiceUnavailable {
+		t.Fatalf("DeleteBranch() error = %v, want the 503", err)
+	}
+	if deletes != mutationRetries+1 {
+		t.Errorf("Expected %d delete requests, got %d", mutationRetries+1, deletes)
+	}
+}
+
+func TestCreateBranch_NoRetryOnClientError(t *testing.T) {
+	var posts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&posts, 1)
+		w.WriteHeader(http.StatusConflict)
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if err := client.CreateBranch(context.Background(), "group/project", "migration-pr-1", "abc1234"); !errors.Is(err, ErrBranchExists) {
+		t.Fatalf("CreateBranch() error = %v, want ErrBranchExists", err)
+	}
+	if posts != 1 {
+		t.Errorf("Expected 1 create request, got %d", posts)
+	}
+}
+
+func TestCreateBranch_ProjectMoved(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.EscapedPath() != "/api/v4/projects/old-group%2Fproject/repository/branches" {
+			t.Errorf("Unexpected %s %s: a redirected change must not be sent again", r.Method, r.URL.EscapedPath())
+		}
+		w.Header().Set("Location", "/api/v4/projects/new-group%2Fproject/repository/branches")
+		w.WriteHeader(http.StatusMovedPermanently)
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	err = client.CreateBranch(context.Background(), "old-group/project", "migration-pr-1", "abc1234")
+	if !errors.Is(err, ErrProjectMoved) {
+		t.Fatalf("CreateBranch() error = %v, want ErrProjectMoved", err)
+	}
+	if !strings.Contains(err.Error(), "moved to new-group/project") {
+		t.Errorf("CreateBranch() error = %q, want it to name the new path", err)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "10")
+	if delay := retryDelay(&http.Response{Header: header}, 0); delay < 10*time.Second || delay > 15*time.Second {
+		t.Errorf("retryDelay() with Retry-After 10 = %v, want 10s plus up to 50%% jitter", delay)
+	}
+
+	if delay := retryDelay(&http.Response{Header: http.Header{}}, 2); delay < 4*time.Second || delay > 6*time.Second {
+		t.Errorf("retryDelay() of the third attempt = %v, want 4s plus up to 50%% jitter", delay)
+	}
+
+	header.Set("Retry-After", "86400")
+	if delay := retryDelay(&http.Response{Header: header}, 0); delay > maxMutationWait*3/2 {
+		t.Errorf("retryDelay() = %v, want at most %v plus jitter", delay, maxMutationWait)
+	}
+}
@@ -0,0 +1,217 @@
+package gitlab
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/ratelimit"
+	"github.com/amenocal/gh-gl-create-refs/pkg/trace"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// Option configures optional behaviour of a Client
+type Option func(*clientConfig)
+
+// clientConfig collects the settings applied by Options before the client is built
+type clientConfig struct {
+	httpClient   *http.Client
+	transport    http.RoundTripper
+	logger       *slog.Logger
+	limiter      RateLimiter
+	rps          float64
+	burst        int
+	metrics      *Metrics
+	budget       *Budget
+	authType     AuthType
+	tlsConfig    *tls.Config
+	cache        *DiskCache
+	projectCache *ProjectCache
+	userAgent    string
+	sudo         string
+	trace        *slog.Logger
+}
+
+// WithHTTPClient makes the client send requests through hc, for example to set
+// proxies, timeouts or corporate TLS settings
+func WithHTTPClient(hc *http.Client) Option {
+	return func(cfg *clientConfig) {
+		cfg.httpClient = hc
+	}
+}
+
+// WithTransport wraps every request in rt, for example for instrumentation or
+// record/replay. It is applied on top of any client given by WithHTTPClient.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(cfg *clientConfig) {
+		cfg.transport = rt
+	}
+}
+
+// WithLogger sends the client's progress and rate limit messages to logger instead of
+// slog.Default(). Use a logger with slog.DiscardHandler to silence the client entirely.
+func WithLogger(logger *slog.Logger) Option {
+	return func(cfg *clientConfig) {
+		cfg.logger = logger
+	}
+}
+
+// WithRateLimit sets the steady request rate and burst size of the default token
+// bucket limiter. A non-positive rps disables client-side limiting; the limiter still
+// honours GitLab's rate limit headers.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(cfg *clientConfig) {
+		cfg.rps = rps
+		cfg.burst = burst
+	}
+}
+
+// WithRateLimiter replaces the default token bucket with a custom limiter,
+// for example one shared between several clients
+func WithRateLimiter(limiter RateLimiter) Option {
+	return func(cfg *clientConfig) {
+		cfg.limiter = limiter
+	}
+}
+
+// WithMetrics records request counts, retries, 429 responses and latencies in m
+func WithMetrics(m *Metrics) Option {
+	return func(cfg *clientConfig) {
+		cfg.metrics = m
+	}
+}
+
+// WithBudget refuses requests with ErrBudgetExhausted once the client, and any other
+// client sharing b, has sent as many requests as b allows
+func WithBudget(b *Budget) Option {
+	return func(cfg *clientConfig) {
+		cfg.budget = b
+	}
+}
+
+// WithTLSConfig sets the TLS settings used to connect to GitLab, for example from
+// NewTLSConfig for instances with an internal certificate authority. It applies to the
+// transport of the client given by WithHTTPClient when that is an *http.Transport,
+// and not to a custom RoundTripper given by WithTransport.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(cfg *clientConfig) {
+		cfg.tlsConfig = config
+	}
+}
+
+// WithCache serves repeated GET requests from cache instead of GitLab
+func WithCache(cache *DiskCache) Option {
+	return func(cfg *clientConfig) {
+		cfg.cache = cache
+	}
+}
+
+// WithProjectCache answers GetProjectInfo from cache while its entry for the project is
+// fresh, and stores the projects it looks up there
+func WithProjectCache(cache *ProjectCache) Option {
+	return func(cfg *clientConfig) {
+		cfg.projectCache = cache
+	}
+}
+
+// WithUserAgent sends userAgent as the User-Agent header of every request, so GitLab
+// administrators can identify the tool's traffic
+func WithUserAgent(userAgent string) Option {
+	return func(cfg *clientConfig) {
+		cfg.userAgent = userAgent
+	}
+}
+
+// WithSudo makes every request on behalf of the user with this username or ID, sending it
+// as GitLab's Sudo header. The token must be an administrator's with the sudo scope.
+func WithSudo(username string) Option {
+	return func(cfg *clientConfig) {
+		cfg.sudo = username
+	}
+}
+
+// WithTrace logs every HTTP request to logger at debug level, including retries, with its
+// status, TLS connection and rate limit headers. Tokens and authorization headers are never logged.
+func WithTrace(logger *slog.Logger) Option {
+	return func(cfg *clientConfig) {
+		cfg.trace = logger
+	}
+}
+
+// WithAuthType selects how the token passed to NewClient is sent to GitLab.
+// The default is AuthTypeToken.
+func WithAuthType(authType AuthType) Option {
+	return func(cfg *clientConfig) {
+		cfg.authType = authType
+	}
+}
+
+// gitlabOptions converts the collected settings into client-go options
+func (cfg *clientConfig) gitlabOptions() []gitlab.ClientOptionFunc {
+	// Copy so the caller's client is not modified
+	hc := &http.Client{}
+	if cfg.httpClient != nil {
+		copied := *cfg.httpClient
+		hc = &copied
+	}
+	if cfg.tlsConfig != nil {
+		base, ok := hc.Transport.(*http.Transport)
+		if !ok || base == nil {
+			base = http.DefaultTransport.(*http.Transport)
+		}
+		if hc.Transport == nil || ok {
+			transport := base.Clone()
+			transport.TLSClientConfig = cfg.tlsConfig
+			hc.Transport = transport
+		}
+	}
+	if cfg.transport != nil {
+		hc.Transport = cfg.transport
+	}
+	if cfg.trace != nil {
+		// Closest to the network so every attempt is traced, and cached responses are not
+		hc.Transport = trace.NewTransport(hc.Transport, cfg.trace)
+	}
+	if cfg.metrics != nil {
+		next := hc.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		hc.Transport = &metricsTransport{next: next, metrics: cfg.metrics}
+	}
+	if cfg.budget != nil {
+		next := hc.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		hc.Transport = &budgetTransport{next: next, budget: cfg.budget}
+	}
+	if cfg.limiter != nil {
+		// Every request, retries included, waits for the limiter, and every response it
+		// gets is observed, failed ones too. Retries is left at 0 so the transport never
+		// resends anything: client-go retries reads, and mutate turns client-go's retries
+		// off for branch mutations and retries them itself, after checking that a 5xx
+		// didn't create the branch anyway. Resending here would send a POST twice.
+		hc.Transport = &ratelimit.Transport{Base: hc.Transport, Limiter: cfg.limiter}
+	}
+	if cfg.cache != nil {
+		// Outermost so only requests that reach GitLab are counted and wait for the limiter
+		next := hc.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		hc.Transport = cfg.cache.Transport(next)
+	}
+
+	opts := []gitlab.ClientOptionFunc{gitlab.WithErrorHandler(sanitizingErrorHandler), gitlab.WithHTTPClient(hc)}
+	if cfg.metrics != nil {
+		opts = append(opts, gitlab.WithRequestLogHook(cfg.metrics.retryHook))
+	}
+	if cfg.userAgent != "" {
+		opts = append(opts, gitlab.WithUserAgent(cfg.userAgent))
+	}
+	if cfg.sudo != "" {
+		opts = append(opts, gitlab.WithRequestOptions(gitlab.WithSudo(cfg.sudo)))
+	}
+	return opts
+}
@@ -0,0 +1,146 @@
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingTransport counts requests before passing them to the default transport
+type countingTransport struct {
+	count int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.count, 1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestNewClient_WithTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v4/projects/group/project/repository/branches" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"name": "migration-pr-1"}`))
+	}))
+	defer server.Close()
+
+	transport := &countingTransport{}
+
+	client, err := NewClient("token", server.URL, WithTransport(transport))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if err := client.CreateBranch(context.Background(), "group/project", "migration-pr-1", "abc123"); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+
+	if transport.count != 1 {
+		t.Errorf("Expected 1 request through the custom transport, got %d", transport.count)
+	}
+}
+
+func TestClientConfig_TransportDoesNotModifyCallerClient(t *testing.T) {
+	hc := &http.Client{Timeout: 5 * time.Second}
+	transport := &countingTransport{}
+
+	cfg := &clientConfig{}
+	WithHTTPClient(hc)(cfg)
+	WithTransport(transport)(cfg)
+
+	// The HTTP client and the error handler
+	if opts := cfg.gitlabOptions(); len(opts) != 2 {
+		t.Fatalf("Expected 2 client options, got %d", len(opts))
+	}
+
+	if hc.Transport != nil {
+		t.Error("WithTransport should not modify the caller's http.Client")
+	}
+}
+
+func TestClientConfig_NoOptions(t *testing.T) {
+	// The error handler, and the HTTP client that carries the rate limiter
+	cfg := &clientConfig{}
+	if opts := cfg.gitlabOptions(); len(opts) != 2 {
+		t.Errorf("Expected 2 client options, got %d", len(opts))
+	}
+}
+
+func TestNewClient_WithUserAgent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("User-Agent"); got != "gh-gl-create-refs/v1.2.3" {
+			t.Errorf("User-Agent = %q, want %q", got, "gh-gl-create-refs/v1.2.3")
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"name": "migration-pr-1"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithUserAgent("gh-gl-create-refs/v1.2.3"), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if err := client.CreateBranch(context.Background(), "group/project", "migration-pr-1", "abc123"); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+}
+
+func TestNewClient_WithSudo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Sudo"); got != "project-owner" {
+			t.Errorf("Sudo = %q, want %q", got, "project-owner")
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"name": "migration-pr-1"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithSudo("project-owner"), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if err := client.CreateBranch(context.Background(), "group/project", "migration-pr-1", "abc123"); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+}
+
+func TestNewClient_WithTrace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("RateLimit-Remaining", "599")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"name": "migration-pr-1"}`))
+	}))
+	defer server.Close()
+
+	var b bytes.Buffer
+	trace := slog.New(slog.NewTextHandler(&b, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	client, err := NewClient("glpat-secret", server.URL, WithTrace(trace), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if err := client.CreateBranch(context.Background(), "group/project", "migration-pr-1", "abc123"); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+
+	logged := b.String()
+	for _, expected := range []string{"method=POST", "/api/v4/projects/group%2Fproject/repository/branches", "status=201", "ratelimit-remaining=599"} {
+		if !strings.Contains(logged, expected) {
+			t.Errorf("trace %q does not contain %q", logged, expected)
+		}
+	}
+	if strings.Contains(logged, "glpat-secret") {
+		t.Errorf("trace %q contains the token", logged)
+	}
+}
@@ -0,0 +1,119 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// ProjectInfo summarizes a project for pre-flight checks and progress reporting
+type ProjectInfo struct {
+	ID            int
+	Path          string
+	DefaultBranch string
+	Visibility    string
+	Archived      bool
+	// OpenMergeRequests and TotalMergeRequests come from GitLab's pagination headers.
+	// GitLab omits the totals for very large result sets; they are -1 then.
+	OpenMergeRequests  int
+	TotalMergeRequests int
+}
+
+// GetProjectInfo looks up a project's ID, default branch, visibility, archived flag and
+// merge request counts, from the client's project cache when it has a fresh entry
+func (c *Client) GetProjectInfo(ctx context.Context, projectPath string) (*ProjectInfo, error) {
+	var cacheKey string
+	if c.projectCache != nil {
+		cacheKey = projectCacheKey(c.cacheScope, c.client.BaseURL().String(), projectPath)
+		if info, ok := c.projectCache.get(cacheKey); ok {
+			c.logger.Debug("using cached project info", "project", projectPath)
+			return info, nil
+		}
+	}
+
+	project, _, err := c.client.Projects.GetProject(projectPath, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, c.wrapFetchError(err, projectPath)
+	}
+
+	info := &ProjectInfo{
+		ID:            project.ID,
+		Path:          project.PathWithNamespace,
+		DefaultBranch: project.DefaultBranch,
+		Visibility:    string(project.Visibility),
+		Archived:      project.Archived,
+	}
+
+	info.OpenMergeRequests, err = c.countMergeRequests(ctx, projectPath, "opened")
+	if err != nil {
+		return nil, err
+	}
+	info.TotalMergeRequests, err = c.countMergeRequests(ctx, projectPath, "all")
+	if err != nil {
+		return nil, err
+	}
+
+	if c.projectCache != nil {
+		if err := c.projectCache.put(cacheKey, *info); err != nil {
+			c.logger.Warn("failed to cache project info", "project", projectPath, "error", err)
+		}
+	}
+	return info, nil
+}
+
+// ProjectPath returns the current full path of a project. GitLab redirects reads of a
+// renamed or transferred project from its old path, but not changes such as creating
+// branches, so callers holding an old path should use the returned one from then on.
+func (c *Client) ProjectPath(ctx context.Context, projectPath string) (string, error) {
+	project, _, err := c.client.Projects.GetProject(projectPath, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", c.wrapFetchError(err, projectPath)
+	}
+
+	return project.PathWithNamespace, nil
+}
+
+// countMergeRequests reads the number of merge requests in state from the pagination
+// headers of a one-item page, or returns -1 when GitLab does not report it
+func (c *Client) countMergeRequests(ctx context.Context, projectPath, state string) (int, error) {
+	opts := &gitlab.ListProjectMergeRequestsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 1},
+		State:       gitlab.Ptr(state),
+	}
+
+	_, resp, err := c.client.MergeRequests.ListProjectMergeRequests(projectPath, opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return 0, c.wrapFetchError(err, projectPath)
+	}
+
+	if resp.Header.Get("X-Total") == "" {
+		return -1, nil
+	}
+	return resp.TotalItems, nil
+}
+
+// SearchProjects returns the full paths of up to limit projects the token's user is a
+// member of whose path, including their namespace, contains search, most recently active
+// first. Searching every visible project would mostly find public projects on gitlab.com.
+func (c *Client) SearchProjects(ctx context.Context, search string, limit int) ([]string, error) {
+	opts := &gitlab.ListProjectsOptions{
+		ListOptions:      gitlab.ListOptions{PerPage: limit},
+		Search:           gitlab.Ptr(search),
+		SearchNamespaces: gitlab.Ptr(true),
+		Simple:           gitlab.Ptr(true),
+		Membership:       gitlab.Ptr(true),
+		OrderBy:          gitlab.Ptr("last_activity_at"),
+	}
+
+	projects, _, err := c.client.Projects.ListProjects(opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search projects matching %q: %w", search, sanitizeError(err))
+	}
+
+	paths := make([]string, 0, len(projects))
+	for _, project := range projects {
+		paths = append(paths, project.PathWithNamespace)
+	}
+	return paths, nil
+}
@@ -0,0 +1,121 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProjectCache keeps project lookups (GetProjectInfo) on disk for a TTL, so that repeated
+// runs and subcommands, such as a group fetch followed by create-refs for each project, don't
+// resolve the same projects again. Unlike DiskCache it stores the summarized ProjectInfo, not
+// responses, in a single small file. It is safe for concurrent use; when several processes
+// share the file, the last one to write it wins, which only costs repeated lookups.
+type ProjectCache struct {
+	filename string
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]projectCacheEntry
+}
+
+// projectCacheEntry is a cached project with the time it was looked up
+type projectCacheEntry struct {
+	Info      ProjectInfo `json:"info"`
+	FetchedAt time.Time   `json:"fetched_at"`
+}
+
+// NewProjectCache opens the project cache stored in filename, whose entries are used for
+// ttl. A missing or unreadable file starts an empty cache.
+func NewProjectCache(filename string, ttl time.Duration) (*ProjectCache, error) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create project cache directory: %w", err)
+	}
+
+	c := &ProjectCache{filename: filename, ttl: ttl, now: time.Now, entries: map[string]projectCacheEntry{}}
+	data, err := os.ReadFile(filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read project cache: %w", err)
+	}
+	if len(data) > 0 && json.Unmarshal(data, &c.entries) != nil {
+		// A corrupted cache is only a cache: start over
+		c.entries = map[string]projectCacheEntry{}
+	}
+	return c, nil
+}
+
+// Clear removes every cached project
+func (c *ProjectCache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = map[string]projectCacheEntry{}
+	if err := os.Remove(c.filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to clear project cache: %w", err)
+	}
+	return nil
+}
+
+// get returns a copy of the project cached under key while it is fresh
+func (c *ProjectCache) get(key string) (*ProjectInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || c.now().Sub(entry.FetchedAt) >= c.ttl {
+		return nil, false
+	}
+	info := entry.Info
+	return &info, true
+}
+
+// put caches info under key and writes the cache file, leaving out expired entries. A
+// failure to write only loses the entry for later runs, so it is logged by the caller.
+func (c *ProjectCache) put(key string, info ProjectInfo) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.entries[key] = projectCacheEntry{Info: info, FetchedAt: now}
+	for k, entry := range c.entries {
+		if now.Sub(entry.FetchedAt) >= c.ttl {
+			delete(c.entries, k)
+		}
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode project cache: %w", err)
+	}
+
+	// Write a temporary file and rename it so a concurrent reader never sees half a file
+	tmp, err := os.CreateTemp(filepath.Dir(c.filename), filepath.Base(c.filename)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write project cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write project cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write project cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.filename); err != nil {
+		return fmt.Errorf("failed to write project cache: %w", err)
+	}
+	return nil
+}
+
+// projectCacheKey identifies a project on an instance for the token scope, since tokens
+// with different access may not see the same projects
+func projectCacheKey(scope, baseURL, projectPath string) string {
+	return scope + " " + strings.TrimSuffix(baseURL, "/") + " " + strings.ToLower(projectPath)
+}
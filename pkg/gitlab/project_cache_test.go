@@ -0,0 +1,117 @@
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProjectCache_GetProjectInfo(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		switch r.URL.Path {
+		case "/api/v4/projects/group/project":
+			w.Write([]byte(`{"id": 42, "path_with_namespace": "group/project", "default_branch": "main"}`))
+		default:
+			w.Header().Set("X-Total", "7")
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+
+	filename := filepath.Join(t.TempDir(), "projects.json")
+	cache, err := NewProjectCache(filename, time.Hour)
+	if err != nil {
+		t.Fatalf("NewProjectCache failed: %v", err)
+	}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()), WithProjectCache(cache))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	first, err := client.GetProjectInfo(context.Background(), "group/project")
+	if err != nil {
+		t.Fatalf("GetProjectInfo failed: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("Expected 3 requests for the first lookup, got %d", calls)
+	}
+
+	// Another run reading the same file answers from it
+	reopened, err := NewProjectCache(filename, time.Hour)
+	if err != nil {
+		t.Fatalf("NewProjectCache failed: %v", err)
+	}
+	reopened.now = func() time.Time { return now.Add(30 * time.Minute) }
+	client, err = NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()), WithProjectCache(reopened))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	second, err := client.GetProjectInfo(context.Background(), "Group/Project")
+	if err != nil {
+		t.Fatalf("GetProjectInfo failed: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected the cached lookup to send no requests, got %d", calls-3)
+	}
+	if *second != *first || second.ID != 42 || second.TotalMergeRequests != 7 {
+		t.Errorf("GetProjectInfo() from cache = %+v, want %+v", *second, *first)
+	}
+
+	// Expired entries are looked up again
+	reopened.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if _, err := client.GetProjectInfo(context.Background(), "group/project"); err != nil {
+		t.Fatalf("GetProjectInfo failed: %v", err)
+	}
+	if calls != 6 {
+		t.Errorf("Expected an expired entry to be looked up again, got %d requests", calls)
+	}
+}
+
+func TestProjectCache_SeparatesTokens(t *testing.T) {
+	cache, err := NewProjectCache(filepath.Join(t.TempDir(), "projects.json"), time.Hour)
+	if err != nil {
+		t.Fatalf("NewProjectCache failed: %v", err)
+	}
+
+	a, _ := NewClient("token-a", "", WithProjectCache(cache))
+	b, _ := NewClient("token-b", "", WithProjectCache(cache))
+	if a.cacheScope == b.cacheScope {
+		t.Error("Expected clients with different tokens to use different cache scopes")
+	}
+
+	sudo, _ := NewClient("token-a", "", WithProjectCache(cache), WithSudo("alice"))
+	if a.cacheScope == sudo.cacheScope {
+		t.Error("Expected a client impersonating a user to use a different cache scope")
+	}
+}
+
+func TestNewProjectCache_CorruptedFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "projects.json")
+	if err := os.WriteFile(filename, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := NewProjectCache(filename, time.Hour)
+	if err != nil {
+		t.Fatalf("NewProjectCache() error = %v, want a corrupted cache to start empty", err)
+	}
+	if _, ok := cache.get("key"); ok {
+		t.Error("Expected an empty cache")
+	}
+	if err := cache.Clear(); err != nil {
+		t.Errorf("Clear failed: %v", err)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("Expected Clear to remove %s", filename)
+	}
+}
@@ -0,0 +1,127 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestGetProjectInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v4/projects/group/project":
+			w.Write([]byte(`{"id": 42, "path_with_namespace": "group/project", "default_branch": "main", "visibility": "internal", "archived": true}`))
+		case "/api/v4/projects/group/project/merge_requests":
+			// GitLab omits the total for very large result sets, as for "all" here
+			if r.URL.Query().Get("state") == "opened" {
+				w.Header().Set("X-Total", "3")
+			}
+			w.Write([]byte(`[]`))
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	info, err := client.GetProjectInfo(context.Background(), "group/project")
+	if err != nil {
+		t.Fatalf("GetProjectInfo failed: %v", err)
+	}
+
+	expected := ProjectInfo{
+		ID:                 42,
+		Path:               "group/project",
+		DefaultBranch:      "main",
+		Visibility:         "internal",
+		Archived:           true,
+		OpenMergeRequests:  3,
+		TotalMergeRequests: -1,
+	}
+	if *info != expected {
+		t.Errorf("GetProjectInfo() = %+v, want %+v", *info, expected)
+	}
+}
+
+func TestGetProjectInfo_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "404 Project Not Found"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if _, err := client.GetProjectInfo(context.Background(), "group/missing"); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("GetProjectInfo() error = %v, want ErrProjectNotFound", err)
+	}
+}
+
+func TestSearchProjects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/projects" {
+			t.Errorf("Unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		query := r.URL.Query()
+		if query.Get("search") != "acme/back" || query.Get("search_namespaces") != "true" || query.Get("membership") != "true" || query.Get("per_page") != "20" {
+			t.Errorf("Unexpected query %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`[{"path_with_namespace": "acme/backend/api"}, {"path_with_namespace": "acme/backend/web"}]`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	paths, err := client.SearchProjects(context.Background(), "acme/back", 20)
+	if err != nil {
+		t.Fatalf("SearchProjects failed: %v", err)
+	}
+	if !slices.Equal(paths, []string{"acme/backend/api", "acme/backend/web"}) {
+		t.Errorf("SearchProjects() = %v", paths)
+	}
+}
+
+func TestProjectPath_FollowsRedirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/api/v4/projects/old-group%2Fproject":
+			w.Header().Set("Location", "/api/v4/projects/new-group%2Fproject")
+			w.WriteHeader(http.StatusMovedPermanently)
+		case "/api/v4/projects/new-group%2Fproject":
+			w.Write([]byte(`{"id": 7, "path_with_namespace": "new-group/project"}`))
+		default:
+			t.Errorf("Unexpected request %s", r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	path, err := client.ProjectPath(context.Background(), "old-group/project")
+	if err != nil {
+		t.Fatalf("ProjectPath failed: %v", err)
+	}
+	if path != "new-group/project" {
+		t.Errorf("ProjectPath() = %q, want new-group/project", path)
+	}
+}
@@ -0,0 +1,141 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// ProtectedBranch is a protected branch name or wildcard pattern of a project
+type ProtectedBranch struct {
+	// Name is a branch name or a pattern in which * matches any characters, such as release/*
+	Name string
+	// CreateAccessLevel is the lowest role that may create matching branches. Creating a
+	// branch through the API is allowed to the roles that may push and to those that may
+	// merge; 0 means no role may.
+	CreateAccessLevel int
+	// AllowsOthers is set when specific users, groups or deploy keys may also push or merge
+	AllowsOthers bool
+}
+
+// Matches reports whether branch is protected by p
+func (p ProtectedBranch) Matches(branch string) bool {
+	parts := strings.Split(p.Name, "*")
+	if len(parts) == 1 {
+		return branch == p.Name
+	}
+
+	if !strings.HasPrefix(branch, parts[0]) {
+		return false
+	}
+	rest := branch[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	return strings.HasSuffix(rest, parts[len(parts)-1])
+}
+
+// MatchesPrefix reports whether p protects any branch whose name starts with prefix
+func (p ProtectedBranch) MatchesPrefix(prefix string) bool {
+	pattern := p.Name
+	for prefix != "" {
+		if pattern == "" {
+			return false
+		}
+		if pattern[0] == '*' {
+			// The star takes the rest of the prefix, and the branch can end as the pattern does
+			return true
+		}
+		if pattern[0] != prefix[0] {
+			return false
+		}
+		pattern, prefix = pattern[1:], prefix[1:]
+	}
+	return true
+}
+
+// ProtectedBranches lists the protected branch names and patterns of a project
+func (c *Client) ProtectedBranches(ctx context.Context, projectPath string) ([]ProtectedBranch, error) {
+	opts := &gitlab.ListProtectedBranchesOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+
+	var protected []ProtectedBranch
+
+	for {
+		page, resp, err := c.client.ProtectedBranches.ListProtectedBranches(projectPath, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, classifyError(err, ErrProjectNotFound, "failed to list protected branches of %s", projectPath)
+		}
+
+		for _, b := range page {
+			protected = append(protected, newProtectedBranch(b))
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return protected, nil
+}
+
+// newProtectedBranch converts a client-go protected branch
+func newProtectedBranch(b *gitlab.ProtectedBranch) ProtectedBranch {
+	p := ProtectedBranch{Name: b.Name}
+	for _, a := range slices.Concat(b.PushAccessLevels, b.MergeAccessLevels) {
+		if a.UserID != 0 || a.GroupID != 0 || a.DeployKeyID != 0 {
+			p.AllowsOthers = true
+			continue
+		}
+		if a.AccessLevel != gitlab.NoPermissions && (p.CreateAccessLevel == 0 || int(a.AccessLevel) < p.CreateAccessLevel) {
+			p.CreateAccessLevel = int(a.AccessLevel)
+		}
+	}
+	return p
+}
+
+// CheckCreateBranch returns why a token with access and scopes cannot create branch in a
+// project with the protected branches protected, wrapping ErrInsufficientAccess, or nil
+// when it can. Protected branches that let specific users, groups or deploy keys push or
+// merge are not held against the token, since whether it is one of them is not known.
+func CheckCreateBranch(access *ProjectAccess, scopes []string, protected []ProtectedBranch, branch string) error {
+	return checkCreate(access, scopes, protected, ProtectedBranch.Matches, branch, "branch "+branch+" matches")
+}
+
+// CheckCreateBranchPrefix is CheckCreateBranch for every branch whose name starts with
+// prefix: it fails when any protected branch could match one of them, so that a run whose
+// names are only known as it goes is checked before it creates anything
+func CheckCreateBranchPrefix(access *ProjectAccess, scopes []string, protected []ProtectedBranch, prefix string) error {
+	return checkCreate(access, scopes, protected, ProtectedBranch.MatchesPrefix, prefix, "branches starting with "+prefix+" can match")
+}
+
+// checkCreate checks the scopes and role of a token, and the protected branches matching
+// name, which errors describe as what
+func checkCreate(access *ProjectAccess, scopes []string, protected []ProtectedBranch, matches func(ProtectedBranch, string) bool, name, what string) error {
+	if !slices.Contains(scopes, "api") {
+		return fmt.Errorf("%w: creating branches needs a token with the api scope, but the token's scopes are %s", ErrInsufficientAccess, strings.Join(scopes, ", "))
+	}
+	if access.AccessLevel < int(gitlab.DeveloperPermissions) {
+		return fmt.Errorf("%w: the token has %s access to %s, but creating branches needs at least developer", ErrInsufficientAccess, AccessLevelName(access.AccessLevel), access.ProjectPath)
+	}
+
+	for _, p := range protected {
+		if !matches(p, name) || p.AllowsOthers {
+			continue
+		}
+		if p.CreateAccessLevel == 0 {
+			return fmt.Errorf("%w: %s protected branch %q of %s, which no role may create", ErrInsufficientAccess, what, p.Name, access.ProjectPath)
+		}
+		if access.AccessLevel < p.CreateAccessLevel {
+			return fmt.Errorf("%w: %s protected branch %q of %s, which needs at least %s to create, but the token has %s access", ErrInsufficientAccess, what, p.Name, access.ProjectPath, AccessLevelName(p.CreateAccessLevel), AccessLevelName(access.AccessLevel))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestProtectedBranchMatches(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		branch   string
+		expected bool
+	}{
+		{"main", "main", true},
+		{"main", "main-2", false},
+		{"*", "migration-pr-1", true},
+		{"release/*", "release/1.0", true},
+		{"release/*", "release/1.0/hotfix", true},
+		{"release/*", "releases/1.0", false},
+		{"migration-*", "migration-pr-1", true},
+		{"*-stable", "16-stable", true},
+		{"*-stable", "16-stable-old", false},
+		{"mig*pr*", "migration-pr-1", true},
+		{"mig*pr*x", "migration-pr-1", false},
+		{"ab*ba", "aba", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.branch, func(t *testing.T) {
+			if got := (ProtectedBranch{Name: tt.pattern}).Matches(tt.branch); got != tt.expected {
+				t.Errorf("Matches(%q) = %v, want %v", tt.branch, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProtectedBranchMatchesPrefix(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		prefix   string
+		expected bool
+	}{
+		{"main", "migration-pr-", false},
+		{"*", "migration-pr-", true},
+		{"release/*", "migration-pr-", false},
+		{"migration-*", "migration-pr-", true},
+		{"migration-pr-2*", "migration-pr-", true},
+		{"migration-pr-release", "migration-pr-", true},
+		{"*-stable", "migration-pr-", true},
+		{"migration", "migration-pr-", false},
+		{"mig*", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.prefix, func(t *testing.T) {
+			if got := (ProtectedBranch{Name: tt.pattern}).MatchesPrefix(tt.prefix); got != tt.expected {
+				t.Errorf("MatchesPrefix(%q) = %v, want %v", tt.prefix, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewProtectedBranch(t *testing.T) {
+	b := newProtectedBranch(&gitlab.ProtectedBranch{
+		Name:              "release/*",
+		PushAccessLevels:  []*gitlab.BranchAccessDescription{{AccessLevel: gitlab.NoPermissions}},
+		MergeAccessLevels: []*gitlab.BranchAccessDescription{{AccessLevel: gitlab.MaintainerPermissions}, {AccessLevel: gitlab.DeveloperPermissions}},
+	})
+	if b.CreateAccessLevel != int(gitlab.DeveloperPermissions) || b.AllowsOthers {
+		t.Errorf("newProtectedBranch() = %+v, want developers to create and no others", b)
+	}
+
+	b = newProtectedBranch(&gitlab.ProtectedBranch{
+		Name:             "main",
+		PushAccessLevels: []*gitlab.BranchAccessDescription{{AccessLevel: gitlab.NoPermissions}, {AccessLevel: gitlab.MaintainerPermissions, UserID: 7}},
+	})
+	if b.CreateAccessLevel != 0 || !b.AllowsOthers {
+		t.Errorf("newProtectedBranch() = %+v, want no role to create and others allowed", b)
+	}
+}
+
+func TestProtectedBranches(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/projects/group/project/protected_branches" {
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"name": "main", "push_access_levels": [{"access_level": 40}], "merge_access_levels": [{"access_level": 40}]},
+			{"name": "release/*", "push_access_levels": [{"access_level": 0}], "merge_access_levels": [{"access_level": 30}, {"access_level": 40, "group_id": 5}]}]`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	protected, err := client.ProtectedBranches(context.Background(), "group/project")
+	if err != nil {
+		t.Fatalf("ProtectedBranches failed: %v", err)
+	}
+	want := []ProtectedBranch{
+		{Name: "main", CreateAccessLevel: 40},
+		{Name: "release/*", CreateAccessLevel: 30, AllowsOthers: true},
+	}
+	if len(protected) != len(want) || protected[0] != want[0] || protected[1] != want[1] {
+		t.Errorf("ProtectedBranches() = %+v, want %+v", protected, want)
+	}
+}
+
+func TestCheckCreateBranch(t *testing.T) {
+	developer := &ProjectAccess{ProjectPath: "group/project", AccessLevel: int(gitlab.DeveloperPermissions)}
+	maintainer := &ProjectAccess{ProjectPath: "group/project", AccessLevel: int(gitlab.MaintainerPermissions)}
+	reporter := &ProjectAccess{ProjectPath: "group/project", AccessLevel: int(gitlab.ReporterPermissions)}
+
+	tests := []struct {
+		name      string
+		access    *ProjectAccess
+		scopes    []string
+		protected []ProtectedBranch
+		wantErr   string
+	}{
+		{
+			name:   "developer with api scope",
+			access: developer,
+			scopes: []string{"api"},
+		},
+		{
+			name:    "read_api only",
+			access:  maintainer,
+			scopes:  []string{"read_api", "read_repository"},
+			wantErr: "insufficient access: creating branches needs a token with the api scope, but the token's scopes are read_api, read_repository",
+		},
+		{
+			name:    "reporter",
+			access:  reporter,
+			scopes:  []string{"api"},
+			wantErr: "insufficient access: the token has reporter access to group/project, but creating branches needs at least developer",
+		},
+		{
+			name:      "protected branches not matching",
+			access:    developer,
+			scopes:    []string{"api"},
+			protected: []ProtectedBranch{{Name: "main", CreateAccessLevel: int(gitlab.MaintainerPermissions)}, {Name: "release/*"}},
+		},
+		{
+			name:      "matching pattern for maintainers",
+			access:    developer,
+			scopes:    []string{"api"},
+			protected: []ProtectedBranch{{Name: "migration-*", CreateAccessLevel: int(gitlab.MaintainerPermissions)}},
+			wantErr:   `insufficient access: branch migration-pr-1 matches protected branch "migration-*" of group/project, which needs at least maintainer to create, but the token has developer access`,
+		},
+		{
+			name:      "matching pattern the role may push to",
+			access:    maintainer,
+			scopes:    []string{"api"},
+			protected: []ProtectedBranch{{Name: "*", CreateAccessLevel: int(gitlab.MaintainerPermissions)}},
+		},
+		{
+			name:      "matching pattern no role may push to",
+			access:    maintainer,
+			scopes:    []string{"api"},
+			protected: []ProtectedBranch{{Name: "*"}},
+			wantErr:   `insufficient access: branch migration-pr-1 matches protected branch "*" of group/project, which no role may create`,
+		},
+		{
+			name:      "matching pattern allowing specific users",
+			access:    developer,
+			scopes:    []string{"api"},
+			protected: []ProtectedBranch{{Name: "*", AllowsOthers: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckCreateBranch(tt.access, tt.scopes, tt.protected, "migration-pr-1")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckCreateBranch() unexpected error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInsufficientAccess) {
+				t.Fatalf("CheckCreateBranch() error = %v, want ErrInsufficientAccess", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckCreateBranch() error = %q, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckCreateBranchPrefix(t *testing.T) {
+	developer := &ProjectAccess{ProjectPath: "group/project", AccessLevel: int(gitlab.DeveloperPermissions)}
+
+	// A pattern matching some names of the run fails the check, though the first would pass
+	protected := []ProtectedBranch{{Name: "migration-pr-2*", CreateAccessLevel: int(gitlab.MaintainerPermissions)}}
+	if err := CheckCreateBranch(developer, []string{"api"}, protected, "migration-pr-1"); err != nil {
+		t.Fatalf("CheckCreateBranch() unexpected error = %v", err)
+	}
+	err := CheckCreateBranchPrefix(developer, []string{"api"}, protected, "migration-pr-")
+	if !errors.Is(err, ErrInsufficientAccess) {
+		t.Fatalf("CheckCreateBranchPrefix() error = %v, want ErrInsufficientAccess", err)
+	}
+	want := `insufficient access: branches starting with migration-pr- can match protected branch "migration-pr-2*" of group/project, which needs at least maintainer to create, but the token has developer access`
+	if err.Error() != want {
+		t.Errorf("CheckCreateBranchPrefix() error = %q, want %q", err, want)
+	}
+
+	protected = []ProtectedBranch{{Name: "main"}, {Name: "release/*"}}
+	if err := CheckCreateBranchPrefix(developer, []string{"api"}, protected, "migration-pr-"); err != nil {
+		t.Errorf("CheckCreateBranchPrefix() unexpected error = %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+package gitlab
+
+import (
+	"log/slog"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/ratelimit"
+)
+
+const (
+	// DefaultRequestsPerSecond is the steady request rate used when none is configured
+	DefaultRequestsPerSecond = 10
+	// DefaultBurst is the number of requests allowed back to back when none is configured
+	DefaultBurst = 1
+)
+
+// RateLimiter paces requests to the GitLab API. Implementations must be safe for concurrent use.
+type RateLimiter = ratelimit.Limiter
+
+// TokenBucket is the default RateLimiter; see ratelimit.TokenBucket
+type TokenBucket = ratelimit.TokenBucket
+
+// NewTokenBucket creates a token bucket allowing rps requests per second with the given burst.
+// A non-positive rps means no client-side limit.
+func NewTokenBucket(rps float64, burst int, logger *slog.Logger) *TokenBucket {
+	return ratelimit.NewTokenBucket(rps, burst, logger)
+}
@@ -0,0 +1,70 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// recordingLimiter counts waits and observed responses, failing waits with err when set
+type recordingLimiter struct {
+	err      error
+	waits    atomic.Int32
+	observed atomic.Int32
+}
+
+func (l *recordingLimiter) Wait(ctx context.Context) error {
+	l.waits.Add(1)
+	return l.err
+}
+
+func (l *recordingLimiter) Observe(resp *http.Response) {
+	l.observed.Add(1)
+}
+
+func TestRateLimiter_ObservesFailedRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "404 Project Not Found"}`))
+	}))
+	defer server.Close()
+
+	limiter := &recordingLimiter{}
+	client, err := NewClient("token", server.URL, WithRateLimiter(limiter), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if _, err := client.GetProjectInfo(context.Background(), "group/project"); err == nil {
+		t.Fatal("GetProjectInfo() expected an error")
+	}
+	if waits, observed := limiter.waits.Load(), limiter.observed.Load(); waits != 1 || observed != 1 {
+		t.Errorf("limiter waited %d times and observed %d responses, want 1 and 1", waits, observed)
+	}
+}
+
+func TestRateLimiter_WaitError(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	limiter := &recordingLimiter{err: context.Canceled}
+	client, err := NewClient("token", server.URL, WithRateLimiter(limiter), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	err = client.CreateBranch(context.Background(), "group/project", "migration-pr-1", "abc123")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("CreateBranch() error = %v, want the limiter's error", err)
+	}
+	if calls.Load() != 0 {
+		t.Errorf("Server received %d requests, want none", calls.Load())
+	}
+}
@@ -0,0 +1,92 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/replay"
+)
+
+// newReplayClient creates a client answered by the fixture testdata/replay/name, and
+// checks when the test ends that every recorded request was made
+func newReplayClient(t *testing.T, name string) *Client {
+	t.Helper()
+
+	fixture, err := replay.Load(filepath.Join("testdata", "replay", name))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	replayer := replay.NewReplayer(fixture)
+	t.Cleanup(func() {
+		if n := replayer.Remaining(); n > 0 && !t.Failed() {
+			t.Errorf("%d recorded interactions of %s were not replayed", n, name)
+		}
+	})
+
+	client, err := NewClient("token", "https://"+replay.FixtureHost, WithTransport(replayer), WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	return client
+}
+
+func TestReplay_MergeRequestRefsPaginationAndRetry(t *testing.T) {
+	client := newReplayClient(t, "merge_requests.json")
+
+	var refs []MergeRequestRef
+	for ref, err := range client.MergeRequestRefs(context.Background(), "group/project") {
+		if err != nil {
+			t.Fatalf("MergeRequestRefs failed: %v", err)
+		}
+		refs = append(refs, ref)
+	}
+
+	// Page 2 was rate limited once and retried
+	expected := []struct {
+		iid  int
+		sha  string
+		fork bool
+	}{
+		{3, "3333333333333333333333333333333333333333", false},
+		{2, "2222222222222222222222222222222222222222", true},
+		{1, "1111111111111111111111111111111111111111", false},
+	}
+	if len(refs) != len(expected) {
+		t.Fatalf("Got %d refs, want %d", len(refs), len(expected))
+	}
+	for i, want := range expected {
+		if refs[i].IID != want.iid || refs[i].HeadSHA != want.sha || refs[i].Fork != want.fork {
+			t.Errorf("refs[%d] = IID %d, SHA %s, fork %v; want IID %d, SHA %s, fork %v", i, refs[i].IID, refs[i].HeadSHA, refs[i].Fork, want.iid, want.sha, want.fork)
+		}
+	}
+}
+
+func TestReplay_ProjectNotFound(t *testing.T) {
+	client := newReplayClient(t, "project_not_found.json")
+
+	for _, err := range client.MergeRequestRefs(context.Background(), "group/missing") {
+		if !errors.Is(err, ErrProjectNotFound) {
+			t.Errorf("MergeRequestRefs() error = %v, want ErrProjectNotFound", err)
+		}
+		if StatusCode(err) != 404 {
+			t.Errorf("StatusCode() = %d, want 404", StatusCode(err))
+		}
+		return
+	}
+	t.Error("Expected MergeRequestRefs to yield an error")
+}
+
+func TestReplay_CreateBranch(t *testing.T) {
+	client := newReplayClient(t, "create_branch.json")
+
+	// The first attempt is rate limited and retried
+	if err := client.CreateBranch(context.Background(), "group/project", "migration-pr-1", "1111111111111111111111111111111111111111"); err != nil {
+		t.Errorf("CreateBranch() error = %v, want the retry to succeed", err)
+	}
+	err := client.CreateBranch(context.Background(), "group/project", "migration-pr-2", "2222222222222222222222222222222222222222")
+	if !errors.Is(err, ErrBranchExists) {
+		t.Errorf("CreateBranch() error = %v, want ErrBranchExists", err)
+	}
+}
@@ -0,0 +1,46 @@
+package gitlab
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// NewTLSConfig builds the TLS settings for a self-managed GitLab instance.
+// caCertFile, when set, is a PEM file of certificate authorities trusted in addition
+// to the system roots. insecureSkipVerify disables certificate verification entirely.
+func NewTLSConfig(caCertFile string, insecureSkipVerify bool) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	if caCertFile == "" {
+		return config, nil
+	}
+
+	pem, err := os.ReadFile(caCertFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", caCertFile)
+	}
+	config.RootCAs = pool
+
+	return config, nil
+}
+
+// NewHTTPClient returns an HTTP client using the given TLS settings
+func NewHTTPClient(tlsConfig *tls.Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}
+}
@@ -0,0 +1,75 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"name": "migration-pr-1"}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		caCert      string
+		insecure    bool
+		expectError bool
+	}{
+		{"system roots only", "", false, true},
+		{"custom CA", caFile, false, false},
+		{"skip verification", "", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewTLSConfig(tt.caCert, tt.insecure)
+			if err != nil {
+				t.Fatalf("NewTLSConfig() unexpected error = %v", err)
+			}
+
+			client, err := NewClient("token", server.URL, WithTLSConfig(config), WithLogger(quietLogger()))
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+
+			err = client.CreateBranch(context.Background(), "group/project", "migration-pr-1", "abc123")
+			if tt.expectError && err == nil {
+				t.Error("CreateBranch expected a certificate error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("CreateBranch unexpected error = %v", err)
+			}
+		})
+	}
+}
+
+func TestNewTLSConfig_InvalidCA(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.pem")
+	if err := os.WriteFile(invalid, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewTLSConfig(invalid, false); err == nil {
+		t.Error("NewTLSConfig() expected error for a file without certificates")
+	}
+
+	if _, err := NewTLSConfig(filepath.Join(dir, "missing.pem"), false); err == nil {
+		t.Error("NewTLSConfig() expected error for a missing file")
+	}
+}
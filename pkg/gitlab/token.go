@@ -0,0 +1,141 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// TokenInfo describes the access token the client is authenticated with
+type TokenInfo struct {
+	Name      string
+	Owner     string
+	Scopes    []string
+	ExpiresAt *time.Time
+	Active    bool
+	Revoked   bool
+}
+
+// Expired reports whether the token expiry date is before now
+func (t *TokenInfo) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !t.ExpiresAt.After(now)
+}
+
+// ProjectAccess describes what the authenticated token can do on a project
+type ProjectAccess struct {
+	ProjectPath          string
+	Visibility           string
+	AccessLevel          int
+	CanReadMergeRequests bool
+	CanWriteRepository   bool
+}
+
+// GetTokenInfo looks up the token's owner, scopes and expiry date
+func (c *Client) GetTokenInfo(ctx context.Context) (*TokenInfo, error) {
+	var info *TokenInfo
+	var err error
+	if c.authType == AuthTypeOAuth {
+		info, err = c.getOAuthTokenInfo(ctx)
+	} else {
+		info, err = c.getAccessTokenInfo(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, _, err := c.client.Users.CurrentUser(gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, classifyError(err, ErrUnauthorized, "failed to look up token owner")
+	}
+
+	info.Owner = user.Username
+	return info, nil
+}
+
+// getAccessTokenInfo looks up a personal, project or group access token
+func (c *Client) getAccessTokenInfo(ctx context.Context) (*TokenInfo, error) {
+	token, _, err := c.client.PersonalAccessTokens.GetSinglePersonalAccessToken(gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, classifyError(err, ErrUnauthorized, "failed to look up access token")
+	}
+
+	info := &TokenInfo{
+		Name:    token.Name,
+		Scopes:  token.Scopes,
+		Active:  token.Active,
+		Revoked: token.Revoked,
+	}
+
+	if token.ExpiresAt != nil {
+		expiresAt := time.Time(*token.ExpiresAt)
+		info.ExpiresAt = &expiresAt
+	}
+
+	return info, nil
+}
+
+// GetProjectAccess checks whether a token with the given scopes can read merge
+// requests from and create branches in the project
+func (c *Client) GetProjectAccess(ctx context.Context, projectPath string, scopes []string) (*ProjectAccess, error) {
+	project, _, err := c.client.Projects.GetProject(projectPath, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, c.wrapFetchError(err, projectPath)
+	}
+
+	level := gitlab.NoPermissions
+	if project.Permissions != nil {
+		if p := project.Permissions.ProjectAccess; p != nil && p.AccessLevel > level {
+			level = p.AccessLevel
+		}
+		if g := project.Permissions.GroupAccess; g != nil && g.AccessLevel > level {
+			level = g.AccessLevel
+		}
+	}
+
+	return evaluateProjectAccess(projectPath, string(project.Visibility), level, scopes), nil
+}
+
+// evaluateProjectAccess combines the token scopes with the member access level.
+// Reading merge requests needs reporter access (or any access on a non-private project)
+// and the read_api or api scope; creating branches needs developer access and the api scope.
+func evaluateProjectAccess(projectPath, visibility string, level gitlab.AccessLevelValue, scopes []string) *ProjectAccess {
+	canReadAPI := slices.Contains(scopes, "api") || slices.Contains(scopes, "read_api")
+	canWriteAPI := slices.Contains(scopes, "api")
+
+	canSeeMRs := level >= gitlab.ReporterPermissions || visibility != string(gitlab.PrivateVisibility)
+
+	return &ProjectAccess{
+		ProjectPath:          projectPath,
+		Visibility:           visibility,
+		AccessLevel:          int(level),
+		CanReadMergeRequests: canReadAPI && canSeeMRs,
+		CanWriteRepository:   canWriteAPI && level >= gitlab.DeveloperPermissions,
+	}
+}
+
+// AccessLevelName returns the GitLab role name for an access level
+func AccessLevelName(level int) string {
+	switch gitlab.AccessLevelValue(level) {
+	case gitlab.NoPermissions:
+		return "none"
+	case gitlab.MinimalAccessPermissions:
+		return "minimal access"
+	case gitlab.GuestPermissions:
+		return "guest"
+	case gitlab.PlannerPermissions:
+		return "planner"
+	case gitlab.ReporterPermissions:
+		return "reporter"
+	case gitlab.DeveloperPermissions:
+		return "developer"
+	case gitlab.MaintainerPermissions:
+		return "maintainer"
+	case gitlab.OwnerPermissions:
+		return "owner"
+	default:
+		return fmt.Sprintf("level %d", level)
+	}
+}
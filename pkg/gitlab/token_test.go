@@ -0,0 +1,106 @@
+package gitlab
+
+import (
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestEvaluateProjectAccess(t *testing.T) {
+	tests := []struct {
+		name       string
+		visibility string
+		level      gitlab.AccessLevelValue
+		scopes     []string
+		wantRead   bool
+		wantWrite  bool
+	}{
+		{
+			name:       "developer with api scope",
+			visibility: "private",
+			level:      gitlab.DeveloperPermissions,
+			scopes:     []string{"api"},
+			wantRead:   true,
+			wantWrite:  true,
+		},
+		{
+			name:       "developer with read_api only",
+			visibility: "private",
+			level:      gitlab.DeveloperPermissions,
+			scopes:     []string{"read_api"},
+			wantRead:   true,
+			wantWrite:  false,
+		},
+		{
+			name:       "reporter with api scope",
+			visibility: "private",
+			level:      gitlab.ReporterPermissions,
+			scopes:     []string{"api"},
+			wantRead:   true,
+			wantWrite:  false,
+		},
+		{
+			name:       "guest on private project",
+			visibility: "private",
+			level:      gitlab.GuestPermissions,
+			scopes:     []string{"api"},
+			wantRead:   false,
+			wantWrite:  false,
+		},
+		{
+			name:       "non-member on public project",
+			visibility: "public",
+			level:      gitlab.NoPermissions,
+			scopes:     []string{"read_api"},
+			wantRead:   true,
+			wantWrite:  false,
+		},
+		{
+			name:       "maintainer without api scopes",
+			visibility: "private",
+			level:      gitlab.MaintainerPermissions,
+			scopes:     []string{"read_repository", "write_repository"},
+			wantRead:   false,
+			wantWrite:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			access := evaluateProjectAccess("group/project", tt.visibility, tt.level, tt.scopes)
+
+			if access.CanReadMergeRequests != tt.wantRead {
+				t.Errorf("CanReadMergeRequests = %v, want %v", access.CanReadMergeRequests, tt.wantRead)
+			}
+			if access.CanWriteRepository != tt.wantWrite {
+				t.Errorf("CanWriteRepository = %v, want %v", access.CanWriteRepository, tt.wantWrite)
+			}
+		})
+	}
+}
+
+func TestTokenInfoExpired(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	past := now.Add(-24 * time.Hour)
+	future := now.Add(24 * time.Hour)
+
+	if (&TokenInfo{}).Expired(now) {
+		t.Error("Token without expiry should not be expired")
+	}
+	if !(&TokenInfo{ExpiresAt: &past}).Expired(now) {
+		t.Error("Token with past expiry should be expired")
+	}
+	if (&TokenInfo{ExpiresAt: &future}).Expired(now) {
+		t.Error("Token with future expiry should not be expired")
+	}
+}
+
+func TestAccessLevelName(t *testing.T) {
+	if got := AccessLevelName(30); got != "developer" {
+		t.Errorf("AccessLevelName(30) = %q, want %q", got, "developer")
+	}
+	if got := AccessLevelName(33); got != "level 33" {
+		t.Errorf("AccessLevelName(33) = %q, want %q", got, "level 33")
+	}
+}
@@ -0,0 +1,65 @@
+package gitlab
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// MergeRequestVersion is one diff version of a merge request; GitLab records a new
+// version every time the source branch is pushed
+type MergeRequestVersion struct {
+	ID        int
+	HeadSHA   string
+	BaseSHA   string
+	StartSHA  string
+	CreatedAt time.Time
+}
+
+// MergeRequestVersions lists the diff versions of a merge request, oldest first
+func (c *Client) MergeRequestVersions(ctx context.Context, projectPath string, iid int) ([]MergeRequestVersion, error) {
+	opts := &gitlab.GetMergeRequestDiffVersionsOptions{PerPage: 100}
+
+	var versions []MergeRequestVersion
+
+	for {
+		page, resp, err := c.client.MergeRequests.GetMergeRequestDiffVersions(projectPath, iid, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, classifyError(err, ErrProjectNotFound, "failed to fetch versions of merge request %d", iid)
+		}
+
+		for _, v := range page {
+			version := MergeRequestVersion{
+				ID:       v.ID,
+				HeadSHA:  v.HeadCommitSHA,
+				BaseSHA:  v.BaseCommitSHA,
+				StartSHA: v.StartCommitSHA,
+			}
+			if v.CreatedAt != nil {
+				version.CreatedAt = *v.CreatedAt
+			}
+			versions = append(versions, version)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	// GitLab lists the newest version first
+	slices.Reverse(versions)
+	return versions, nil
+}
+
+// latestHeadSHA returns the head SHA of the newest version that has one
+func latestHeadSHA(versions []MergeRequestVersion) string {
+	for _, v := range slices.Backward(versions) {
+		if v.HeadSHA != "" {
+			return v.HeadSHA
+		}
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newVersionsServer serves one merge request with empty diff_refs and two versions
+func newVersionsServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v4/projects/group/project/merge_requests":
+			w.Write([]byte(`[{"id": 101, "iid": 1}]`))
+		case "/api/v4/projects/group/project/merge_requests/1":
+			w.Write([]byte(`{"iid": 1, "diff_refs": {"head_sha": ""}}`))
+		case "/api/v4/projects/group/project/merge_requests/1/versions":
+			// Newest first, as GitLab returns them
+			w.Write([]byte(`[
+				{"id": 2, "head_commit_sha": "new", "base_commit_sha": "base", "created_at": "2024-02-01T00:00:00Z"},
+				{"id": 1, "head_commit_sha": "old", "base_commit_sha": "base", "created_at": "2024-01-01T00:00:00Z"}
+			]`))
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestMergeRequestVersions(t *testing.T) {
+	server := newVersionsServer(t)
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	versions, err := client.MergeRequestVersions(context.Background(), "group/project", 1)
+	if err != nil {
+		t.Fatalf("MergeRequestVersions failed: %v", err)
+	}
+
+	if len(versions) != 2 || versions[0].HeadSHA != "old" || versions[1].HeadSHA != "new" {
+		t.Fatalf("Expected versions oldest first, got %+v", versions)
+	}
+	if versions[0].CreatedAt.IsZero() || versions[0].BaseSHA != "base" {
+		t.Errorf("Version fields not populated: %+v", versions[0])
+	}
+}
+
+func TestMergeRequestRefs_VersionsFallback(t *testing.T) {
+	server := newVersionsServer(t)
+	defer server.Close()
+
+	client, err := NewClient("token", server.URL, WithRateLimit(0, 1), WithLogger(quietLogger()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		options      []FetchOption
+		wantVersions int
+	}{
+		{"missing diff_refs", nil, 0},
+		{"all versions", []FetchOption{WithAllVersions(true)}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var refs []MergeRequestRef
+			for ref, err := range client.MergeRequestRefs(context.Background(), "group/project", tt.options...) {
+				if err != nil {
+					t.Fatalf("MergeRequestRefs yielded error: %v", err)
+				}
+				refs = append(refs, ref)
+			}
+
+			if len(refs) != 1 || refs[0].HeadSHA != "new" {
+				t.Fatalf("Expected merge request 1 at the newest version's head, got %+v", refs)
+			}
+			if len(refs[0].Versions) != tt.wantVersions {
+				t.Errorf("Expected %d versions on the reference, got %d", tt.wantVersions, len(refs[0].Versions))
+			}
+		})
+	}
+}
+
+func TestLatestHeadSHA(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []MergeRequestVersion
+		expected string
+	}{
+		{"none", nil, ""},
+		{"newest wins", []MergeRequestVersion{{HeadSHA: "old"}, {HeadSHA: "new"}}, "new"},
+		{"skips empty", []MergeRequestVersion{{HeadSHA: "old"}, {HeadSHA: ""}}, "old"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := latestHeadSHA(tt.versions); got != tt.expected {
+				t.Errorf("latestHeadSHA() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,444 @@
+// Package gitlabtest provides a fake GitLab server for tests of code that uses the
+// gitlab package, in this repository or embedding it. The server keeps projects, merge
+// requests and branches in memory and answers the REST API endpoints the gitlab.Client
+// uses, with GitLab's pagination headers, so that pagination, rate limit retries and error
+// handling run as they would against GitLab.
+//
+//	srv := gitlabtest.NewServer(t)
+//	srv.AddProject("group/project", gitlabtest.MergeRequest{IID: 1, SHA: "..."})
+//	client, _ := gitlab.NewClient("token", srv.URL)
+package gitlabtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// MergeRequest is a merge request of a fake project
+type MergeRequest struct {
+	IID   int
+	Title string
+	// State is opened, closed or merged; opened when empty
+	State string
+	// SHA is the head commit, and BaseSHA the target branch commit it was branched from
+	SHA     string
+	BaseSHA string
+	Draft   bool
+	// SourceBranch and TargetBranch default to feature-<IID> and the project's default branch
+	SourceBranch string
+	TargetBranch string
+	// SourceProjectID is the ID of the fork the merge request comes from, or 0 for the project itself
+	SourceProjectID int
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// Project is a project of the fake server
+type Project struct {
+	ID            int
+	Path          string
+	DefaultBranch string
+	Visibility    string
+	Archived      bool
+	MergeRequests []MergeRequest
+	// Branches maps branch names to their commit SHAs
+	Branches map[string]string
+}
+
+// Server is a fake GitLab instance. Its methods are safe to call while a client is using it.
+type Server struct {
+	*httptest.Server
+
+	// Token, when set, is the only token accepted, as a PRIVATE-TOKEN header or bearer token
+	Token string
+
+	mu          sync.Mutex
+	projects    []*Project
+	rateLimited int
+	requests    []string
+}
+
+// NewServer starts a fake GitLab server, closed when the test ends. Pass srv.URL as the
+// base URL of the client.
+func NewServer(tb testing.TB) *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// AddProject adds a project with mrs at path, with a main default branch, and returns it.
+// Change the project only through the server's methods once a client is using it.
+func (s *Server) AddProject(path string, mrs ...MergeRequest) *Project {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := &Project{
+		ID:            len(s.projects) + 1,
+		Path:          path,
+		DefaultBranch: "main",
+		Visibility:    "private",
+		MergeRequests: mrs,
+		Branches:      map[string]string{},
+	}
+	s.projects = append(s.projects, p)
+	return p
+}
+
+// RateLimit answers the next n requests with 429 Too Many Requests and Retry-After: 0
+func (s *Server) RateLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rateLimited = n
+}
+
+// Branches returns a copy of the branches of the project at path
+func (s *Server) Branches(path string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.project(path)
+	if p == nil {
+		return nil
+	}
+	branches := make(map[string]string, len(p.Branches))
+	for name, sha := range p.Branches {
+		branches[name] = sha
+	}
+	return branches
+}
+
+// Requests returns the requests received so far, such as
+// "GET /api/v4/projects/group%2Fproject/merge_requests?page=2", including rate limited ones
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.requests)
+}
+
+// project finds a project by its numeric ID or path; callers hold s.mu
+func (s *Server) project(id string) *Project {
+	for _, p := range s.projects {
+		if strings.EqualFold(p.Path, id) || strconv.Itoa(p.ID) == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// serve routes a request under /api/v4
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+
+	if s.rateLimited > 0 {
+		s.rateLimited--
+		w.Header().Set("Retry-After", "0")
+		writeJSON(w, http.StatusTooManyRequests, message("429 Too Many Requests"))
+		return
+	}
+
+	if s.Token != "" && r.Header.Get("PRIVATE-TOKEN") != s.Token && r.Header.Get("Authorization") != "Bearer "+s.Token {
+		writeJSON(w, http.StatusUnauthorized, message("401 Unauthorized"))
+		return
+	}
+
+	// Project paths and branch names arrive escaped in one segment each
+	var segments []string
+	for _, segment := range strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/api/v4/"), "/") {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, message("400 Bad Request"))
+			return
+		}
+		segments = append(segments, unescaped)
+	}
+
+	if len(segments) < 2 || segments[0] != "projects" {
+		writeJSON(w, http.StatusNotFound, message("404 Not Found"))
+		return
+	}
+	p := s.project(segments[1])
+	if p == nil {
+		writeJSON(w, http.StatusNotFound, message("404 Project Not Found"))
+		return
+	}
+
+	route := strings.Join(segments[2:], "/")
+	switch {
+	case route == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, projectJSON(p))
+	case route == "merge_requests" && r.Method == http.MethodGet:
+		s.listMergeRequests(w, r, p)
+	case len(segments) >= 4 && segments[2] == "merge_requests" && r.Method == http.MethodGet:
+		s.mergeRequest(w, p, segments[3], segments[4:])
+	case route == "repository/branches" && r.Method == http.MethodGet:
+		s.listBranches(w, r, p)
+	case route == "repository/branches" && r.Method == http.MethodPost:
+		s.createBranch(w, r, p)
+	case len(segments) == 5 && route == "repository/branches/"+segments[4]:
+		s.branch(w, r, p, segments[4])
+	case len(segments) == 5 && route == "repository/commits/"+segments[4] && r.Method == http.MethodGet:
+		s.commit(w, p, segments[4])
+	default:
+		writeJSON(w, http.StatusNotFound, message("404 Not Found"))
+	}
+}
+
+// listMergeRequests answers a merge request list, filtered by state, wip and updated_after
+func (s *Server) listMergeRequests(w http.ResponseWriter, r *http.Request, p *Project) {
+	query := r.URL.Query()
+	state := query.Get("state")
+	updatedAfter, _ := time.Parse(time.RFC3339, query.Get("updated_after"))
+
+	var matching []any
+	for _, mr := range p.MergeRequests {
+		if state != "" && state != "all" && state != mergeRequestState(mr) {
+			continue
+		}
+		if query.Get("wip") == "no" && mr.Draft {
+			continue
+		}
+		if !updatedAfter.IsZero() && !mr.UpdatedAt.After(updatedAfter) {
+			continue
+		}
+		matching = append(matching, mergeRequestJSON(p, mr, false))
+	}
+	writePage(w, r, matching)
+}
+
+// mergeRequest answers a merge request and its versions
+func (s *Server) mergeRequest(w http.ResponseWriter, p *Project, iid string, rest []string) {
+	i := slices.IndexFunc(p.MergeRequests, func(mr MergeRequest) bool { return strconv.Itoa(mr.IID) == iid })
+	if i < 0 {
+		writeJSON(w, http.StatusNotFound, message("404 Not found"))
+		return
+	}
+	mr := p.MergeRequests[i]
+
+	switch strings.Join(rest, "/") {
+	case "":
+		writeJSON(w, http.StatusOK, mergeRequestJSON(p, mr, true))
+	case "versions":
+		writeJSON(w, http.StatusOK, []any{map[string]any{
+			"id":               mr.IID,
+			"head_commit_sha":  mr.SHA,
+			"base_commit_sha":  mr.BaseSHA,
+			"start_commit_sha": mr.BaseSHA,
+			"created_at":       mr.CreatedAt,
+			"state":            "collected",
+		}})
+	default:
+		writeJSON(w, http.StatusNotFound, message("404 Not Found"))
+	}
+}
+
+// listBranches answers a branch list, filtered by a search that may be anchored with ^
+func (s *Server) listBranches(w http.ResponseWriter, r *http.Request, p *Project) {
+	search := r.URL.Query().Get("search")
+	prefix, anchored := strings.CutPrefix(search, "^")
+
+	names := make([]string, 0, len(p.Branches))
+	for name := range p.Branches {
+		if (anchored && strings.HasPrefix(name, prefix)) || (!anchored && strings.Contains(name, search)) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	branches := make([]any, len(names))
+	for i, name := range names {
+		branches[i] = branchJSON(name, p.Branches[name])
+	}
+	writePage(w, r, branches)
+}
+
+// createBranch creates a branch at a commit SHA or at another branch
+func (s *Server) createBranch(w http.ResponseWriter, r *http.Request, p *Project) {
+	var body struct {
+		Branch string `json:"branch"`
+		Ref    string `json:"ref"`
+	}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	if body.Branch == "" {
+		body.Branch = r.URL.Query().Get("branch")
+	}
+	if body.Ref == "" {
+		body.Ref = r.URL.Query().Get("ref")
+	}
+
+	if body.Branch == "" || body.Ref == "" {
+		writeJSON(w, http.StatusBadRequest, message("branch and ref are required"))
+		return
+	}
+	if _, ok := p.Branches[body.Branch]; ok {
+		writeJSON(w, http.StatusConflict, message("Branch already exists"))
+		return
+	}
+
+	sha := body.Ref
+	if target, ok := p.Branches[body.Ref]; ok {
+		sha = target
+	}
+	p.Branches[body.Branch] = sha
+	writeJSON(w, http.StatusCreated, branchJSON(body.Branch, sha))
+}
+
+// branch answers or deletes a branch
+func (s *Server) branch(w http.ResponseWriter, r *http.Request, p *Project, name string) {
+	sha, ok := p.Branches[name]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, message("404 Branch Not Found"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, branchJSON(name, sha))
+	case http.MethodDelete:
+		delete(p.Branches, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, message("405 Method Not Allowed"))
+	}
+}
+
+// commit answers a commit known to the project: the head or base of a merge request, the
+// head of a branch, or a merge request's refs/merge-requests/<iid>/head
+func (s *Server) commit(w http.ResponseWriter, p *Project, ref string) {
+	found := func(sha string) {
+		writeJSON(w, http.StatusOK, map[string]any{"id": sha, "short_id": sha[:min(8, len(sha))]})
+	}
+
+	for _, mr := range p.MergeRequests {
+		if ref == fmt.Sprintf("refs/merge-requests/%d/head", mr.IID) && mr.SHA != "" {
+			found(mr.SHA)
+			return
+		}
+		if ref != "" && (ref == mr.SHA || ref == mr.BaseSHA) {
+			found(ref)
+			return
+		}
+	}
+	if sha, ok := p.Branches[ref]; ok {
+		found(sha)
+		return
+	}
+	for _, sha := range p.Branches {
+		if sha == ref {
+			found(sha)
+			return
+		}
+	}
+	writeJSON(w, http.StatusNotFound, message("404 Commit Not Found"))
+}
+
+// writePage writes the page of items the request asks for, with GitLab's pagination headers
+func writePage(w http.ResponseWriter, r *http.Request, items []any) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	page = max(page, 1)
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage <= 0 {
+		perPage = 20
+	}
+	perPage = min(perPage, 100)
+
+	totalPages := max((len(items)+perPage-1)/perPage, 1)
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+
+	h := w.Header()
+	h.Set("X-Page", strconv.Itoa(page))
+	h.Set("X-Per-Page", strconv.Itoa(perPage))
+	h.Set("X-Total", strconv.Itoa(len(items)))
+	h.Set("X-Total-Pages", strconv.Itoa(totalPages))
+	if page < totalPages {
+		h.Set("X-Next-Page", strconv.Itoa(page+1))
+	}
+	if page > 1 {
+		h.Set("X-Prev-Page", strconv.Itoa(page-1))
+	}
+
+	writeJSON(w, http.StatusOK, append([]any{}, items[start:end]...))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func message(msg string) map[string]string {
+	return map[string]string{"message": msg}
+}
+
+func mergeRequestState(mr MergeRequest) string {
+	if mr.State == "" {
+		return "opened"
+	}
+	return mr.State
+}
+
+func projectJSON(p *Project) map[string]any {
+	return map[string]any{
+		"id":                  p.ID,
+		"path_with_namespace": p.Path,
+		"default_branch":      p.DefaultBranch,
+		"visibility":          p.Visibility,
+		"archived":            p.Archived,
+	}
+}
+
+func mergeRequestJSON(p *Project, mr MergeRequest, detail bool) map[string]any {
+	sourceProjectID := p.ID
+	if mr.SourceProjectID != 0 {
+		sourceProjectID = mr.SourceProjectID
+	}
+	sourceBranch := mr.SourceBranch
+	if sourceBranch == "" {
+		sourceBranch = fmt.Sprintf("feature-%d", mr.IID)
+	}
+	targetBranch := mr.TargetBranch
+	if targetBranch == "" {
+		targetBranch = p.DefaultBranch
+	}
+
+	m := map[string]any{
+		"id":                p.ID*100000 + mr.IID,
+		"iid":               mr.IID,
+		"project_id":        p.ID,
+		"title":             mr.Title,
+		"state":             mergeRequestState(mr),
+		"sha":               mr.SHA,
+		"draft":             mr.Draft,
+		"source_branch":     sourceBranch,
+		"target_branch":     targetBranch,
+		"source_project_id": sourceProjectID,
+		"target_project_id": p.ID,
+		"created_at":        mr.CreatedAt,
+		"updated_at":        mr.UpdatedAt,
+	}
+	if detail {
+		m["diff_refs"] = map[string]string{"base_sha": mr.BaseSHA, "start_sha": mr.BaseSHA, "head_sha": mr.SHA}
+	}
+	return m
+}
+
+func branchJSON(name, sha string) map[string]any {
+	return map[string]any{"name": name, "commit": map[string]string{"id": sha}}
+}
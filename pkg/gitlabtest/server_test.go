@@ -0,0 +1,131 @@
+package gitlabtest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlabtest"
+)
+
+func newClient(t *testing.T, srv *gitlabtest.Server) *gitlab.Client {
+	t.Helper()
+
+	client, err := gitlab.NewClient("token", srv.URL, gitlab.WithRateLimit(0, 1), gitlab.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	return client
+}
+
+func TestServer_MergeRequestRefs(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	srv.AddProject("group/project",
+		gitlabtest.MergeRequest{IID: 1, SHA: "aaa", State: "merged"},
+		gitlabtest.MergeRequest{IID: 2, SHA: "bbb", Draft: true},
+		gitlabtest.MergeRequest{IID: 3, SHA: "ccc", SourceProjectID: 99},
+		gitlabtest.MergeRequest{IID: 4, SHA: "ddd", State: "closed"},
+	)
+	client := newClient(t, srv)
+
+	// Rate limited on the first page, then paged two at a time
+	srv.RateLimit(1)
+
+	var iids []int
+	for ref, err := range client.MergeRequestRefs(context.Background(), "group/project", gitlab.WithPerPage(2), gitlab.WithDrafts(true)) {
+		if err != nil {
+			t.Fatalf("MergeRequestRefs failed: %v", err)
+		}
+		if ref.IID == 3 && !ref.Fork {
+			t.Error("Expected merge request 3 to come from a fork")
+		}
+		iids = append(iids, ref.IID)
+	}
+	if len(iids) != 4 {
+		t.Errorf("Fetched merge requests %v, want all 4", iids)
+	}
+
+	var pages int
+	for _, request := range srv.Requests() {
+		if strings.HasPrefix(request, "GET /api/v4/projects/group%2Fproject/merge_requests?") {
+			pages++
+		}
+	}
+	if pages != 3 {
+		t.Errorf("Expected 3 list requests (one rate limited), got %d: %v", pages, srv.Requests())
+	}
+}
+
+func TestServer_ProjectInfo(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	srv.AddProject("group/project", gitlabtest.MergeRequest{IID: 1, SHA: "aaa"}, gitlabtest.MergeRequest{IID: 2, SHA: "bbb", State: "merged"})
+	client := newClient(t, srv)
+
+	info, err := client.GetProjectInfo(context.Background(), "group/project")
+	if err != nil {
+		t.Fatalf("GetProjectInfo failed: %v", err)
+	}
+	if info.ID != 1 || info.DefaultBranch != "main" || info.OpenMergeRequests != 1 || info.TotalMergeRequests != 2 {
+		t.Errorf("GetProjectInfo() = %+v", *info)
+	}
+
+	if _, err := client.GetProjectInfo(context.Background(), "group/missing"); !errors.Is(err, gitlab.ErrProjectNotFound) {
+		t.Errorf("GetProjectInfo() of a missing project error = %v, want ErrProjectNotFound", err)
+	}
+}
+
+func TestServer_Branches(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	srv.AddProject("group/project", gitlabtest.MergeRequest{IID: 1, SHA: "aaa"})
+	client := newClient(t, srv)
+	ctx := context.Background()
+
+	if err := client.CreateBranch(ctx, "group/project", "migration-pr-1", "aaa"); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+	if err := client.CreateBranch(ctx, "group/project", "migration-pr-1", "aaa"); !errors.Is(err, gitlab.ErrBranchExists) {
+		t.Errorf("CreateBranch() of an existing branch error = %v, want ErrBranchExists", err)
+	}
+
+	branches, err := client.ListBranchSHAs(ctx, "group/project", "migration-pr-")
+	if err != nil {
+		t.Fatalf("ListBranchSHAs failed: %v", err)
+	}
+	if len(branches) != 1 || branches["migration-pr-1"] != "aaa" {
+		t.Errorf("ListBranchSHAs() = %v", branches)
+	}
+
+	if ok, err := client.CommitExists(ctx, "group/project", "aaa"); err != nil || !ok {
+		t.Errorf("CommitExists(aaa) = %v, %v; want true", ok, err)
+	}
+	if ok, err := client.CommitExists(ctx, "group/project", "fff"); err != nil || ok {
+		t.Errorf("CommitExists(fff) = %v, %v; want false", ok, err)
+	}
+
+	// Mutations are retried after a rate limit
+	srv.RateLimit(1)
+	if err := client.DeleteBranch(ctx, "group/project", "migration-pr-1"); err != nil {
+		t.Fatalf("DeleteBranch failed: %v", err)
+	}
+	if got := srv.Branches("group/project"); len(got) != 0 {
+		t.Errorf("Branches() after delete = %v", got)
+	}
+	if err := client.DeleteBranch(ctx, "group/project", "migration-pr-1"); !errors.Is(err, gitlab.ErrBranchNotFound) {
+		t.Errorf("DeleteBranch() of a missing branch error = %v, want ErrBranchNotFound", err)
+	}
+}
+
+func TestServer_Token(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	srv.Token = "expected-token"
+	srv.AddProject("group/project")
+
+	client := newClient(t, srv)
+	if _, err := client.GetProjectInfo(context.Background(), "group/project"); !errors.Is(err, gitlab.ErrUnauthorized) {
+		t.Errorf("GetProjectInfo() with a wrong token error = %v, want ErrUnauthorized", err)
+	}
+}
@@ -0,0 +1,115 @@
+// Package hook runs user commands at points of a run, such as before branches are created or
+// after each one, so that custom steps can be plugged in without changing the tool. Each hook
+// receives its event as one JSON object on standard input, and the event's name in the
+// GH_GL_HOOK_EVENT environment variable.
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// EventEnv is the environment variable holding the name of the event a hook runs for
+const EventEnv = "GH_GL_HOOK_EVENT"
+
+// Event names
+const (
+	EventPreCreate  = "pre-create"
+	EventRef        = "ref"
+	EventPostCreate = "post-create"
+)
+
+// Branch statuses of a RefEvent
+const (
+	StatusCreated = "created"
+	StatusFailed  = "failed"
+	// StatusSkipped is for branches not created on GitHub because their commit is missing
+	StatusSkipped = "skipped"
+	// StatusUpdated and StatusUnchanged are for existing branches with --update-existing:
+	// moved to the head commit, or already there
+	StatusUpdated   = "updated"
+	StatusUnchanged = "unchanged"
+)
+
+// Project is a project of a PreCreateEvent with the number of branches to create in it
+type Project struct {
+	Project string `json:"project"`
+	// Target is the GitLab project or GitHub owner/name repository the branches are created in
+	Target string `json:"target"`
+	Count  int    `json:"count"`
+}
+
+// PreCreateEvent is sent before any branch is created
+type PreCreateEvent struct {
+	Event    string    `json:"event"`
+	Projects []Project `json:"projects"`
+	Total    int       `json:"total"`
+}
+
+// RefEvent is sent after the branch of each reference is created, fails or is skipped
+type RefEvent struct {
+	Event string `json:"event"`
+	// Platform is gitlab or github
+	Platform   string `json:"platform"`
+	Repository string `json:"repository"`
+	IID        int    `json:"iid"`
+	Branch     string `json:"branch"`
+	SHA        string `json:"sha"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+}
+
+// PostCreateEvent is sent once the run is over, whether it succeeded or not
+type PostCreateEvent struct {
+	Event    string `json:"event"`
+	Total    int    `json:"total"`
+	Created  int    `json:"created"`
+	Failed   int    `json:"failed"`
+	Skipped  int    `json:"skipped"`
+	Existing int    `json:"existing"`
+	// Updated and Unchanged count the existing branches handled with --update-existing
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	// Error is why the run failed, and empty when it succeeded
+	Error string `json:"error,omitempty"`
+}
+
+// Run runs command with the shell for event name, writing payload to its standard input as
+// JSON. The command's output goes to stderr, keeping stdout for the tool's own output. It
+// fails when the command cannot be started or exits with a non-zero status; the error then
+// ends with the last line the command wrote to stderr.
+func Run(ctx context.Context, command, name string, payload any) error {
+	input, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s hook event: %w", name, err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := shellCommand(ctx, command)
+	cmd.Env = append(os.Environ(), EventEnv+"="+name)
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+
+	if err := cmd.Run(); err != nil {
+		if last := lastLine(stderr.String()); last != "" {
+			return fmt.Errorf("%s hook failed: %w: %s", name, err, last)
+		}
+		return fmt.Errorf("%s hook failed: %w", name, err)
+	}
+	return nil
+}
+
+// lastLine returns the last non-empty line of s
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(s)
+}
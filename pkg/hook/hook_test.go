@@ -0,0 +1,58 @@
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	dir := t.TempDir()
+	out := filepath.Join(dir, "event.json")
+
+	event := RefEvent{Event: EventRef, Platform: "gitlab", Repository: "group/project", IID: 7, Branch: "migration-pr-7", SHA: "abc123", Status: StatusCreated}
+	if err := Run(context.Background(), `cat > "`+out+`"; echo "$`+EventEnv+`" >> "`+out+`.env"`, EventRef, event); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got RefEvent
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("hook input %q is not JSON: %v", data, err)
+	}
+	if got != event {
+		t.Errorf("hook input = %+v, want %+v", got, event)
+	}
+
+	env, err := os.ReadFile(out + ".env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(env)) != EventRef {
+		t.Errorf("%s = %q, want %q", EventEnv, env, EventRef)
+	}
+}
+
+func TestRun_Failure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	err := Run(context.Background(), "echo starting >&2; echo 'endpoint unreachable' >&2; exit 3", EventPreCreate, PreCreateEvent{Event: EventPreCreate})
+	if err == nil {
+		t.Fatal("Run() expected an error")
+	}
+	if !strings.HasPrefix(err.Error(), "pre-create hook failed") || !strings.HasSuffix(err.Error(), ": endpoint unreachable") {
+		t.Errorf("Run() error = %q, want the event and the last line of stderr", err)
+	}
+}
@@ -0,0 +1,114 @@
+// Package lock keeps two runs of the tool from working on the same output file or
+// repository at once. Locks are advisory file locks held by the operating system, so they
+// are released when a run exits, even when it crashes.
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrLocked means another process holds the lock
+var ErrLocked = errors.New("locked by another run")
+
+// Holder describes the process holding a lock; it is written into the lock file so that a
+// run that finds the lock taken can say who holds it
+type Holder struct {
+	PID       int       `json:"pid"`
+	Host      string    `json:"host"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+func (h Holder) String() string {
+	return fmt.Sprintf("pid %d on %s since %s", h.PID, h.Host, h.StartedAt.Format(time.RFC3339))
+}
+
+// Lock is a held lock
+type Lock struct {
+	path string
+	file *os.File
+}
+
+// Acquire takes the lock at path without waiting. When another process holds it, the error
+// wraps ErrLocked and names the holder.
+func Acquire(path string) (*Lock, error) {
+	for {
+		l, err := acquire(path)
+		if !errors.Is(err, errRemoved) {
+			return l, err
+		}
+	}
+}
+
+// errRemoved means the lock file was removed by its holder between being opened and locked
+var errRemoved = errors.New("lock file removed")
+
+func acquire(path string) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", path, err)
+	}
+
+	if err := tryLock(file); err != nil {
+		file.Close()
+		if !errors.Is(err, ErrLocked) {
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if holder, ok := readHolder(path); ok {
+			return nil, fmt.Errorf("%s is %w (%s)", path, ErrLocked, holder)
+		}
+		return nil, fmt.Errorf("%s is %w", path, ErrLocked)
+	}
+
+	// A holder releasing the lock removes the file first, so a file locked after that is no
+	// longer the lock file
+	if opened, err := file.Stat(); err == nil {
+		if current, err := os.Stat(path); err != nil || !os.SameFile(opened, current) {
+			unlock(file)
+			file.Close()
+			return nil, errRemoved
+		}
+	}
+
+	host, _ := os.Hostname()
+	data, err := json.Marshal(Holder{PID: os.Getpid(), Host: host, StartedAt: time.Now().UTC()})
+	if err == nil {
+		file.Truncate(0)
+		file.WriteAt(data, 0)
+	}
+	return &Lock{path: path, file: file}, nil
+}
+
+// readHolder reads the holder recorded in a lock file
+func readHolder(path string) (Holder, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Holder{}, false
+	}
+	var holder Holder
+	if err := json.Unmarshal(data, &holder); err != nil || holder.PID == 0 {
+		return Holder{}, false
+	}
+	return holder, true
+}
+
+// Release removes the lock file and releases the lock. It is safe to call on a nil *Lock.
+func (l *Lock) Release() error {
+	if l == nil {
+		return nil
+	}
+
+	// Removed while still locked, so no other run locks the file about to be deleted
+	removeErr := os.Remove(l.path)
+	unlock(l.file)
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close lock file %s: %w", l.path, err)
+	}
+	if removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove lock file %s: %w", l.path, removeErr)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAcquire_HeldByAnotherRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "refs.csv.lock")
+
+	first, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer first.Release()
+
+	_, err = Acquire(path)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("Acquire() error = %v, want ErrLocked", err)
+	}
+	if want := fmt.Sprintf("pid %d", os.Getpid()); !strings.Contains(err.Error(), want) {
+		t.Errorf("Acquire() error = %q, want it to name the holder (%s)", err, want)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "refs.csv.lock")
+
+	l, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if err := l.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("lock file still exists after Release: %v", err)
+	}
+
+	again, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire after Release failed: %v", err)
+	}
+	again.Release()
+}
+
+func TestRelease_Nil(t *testing.T) {
+	var l *Lock
+	if err := l.Release(); err != nil {
+		t.Errorf("Release() on nil = %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package lock
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockOffset is where the locked byte lies: far past the holder written at the start of the
+// file, which Windows would otherwise keep other runs from reading
+const lockOffset = 0x7fffffff
+
+// tryLock takes an exclusive lock on file, failing with ErrLocked when it is held
+func tryLock(file *os.File) error {
+	overlapped := windows.Overlapped{OffsetHigh: lockOffset}
+	err := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &overlapped)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return ErrLocked
+	}
+	return err
+}
+
+// unlock releases the lock on file
+func unlock(file *os.File) error {
+	overlapped := windows.Overlapped{OffsetHigh: lockOffset}
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &overlapped)
+}
@@ -0,0 +1,152 @@
+// Package mapping reads the file that records which GitHub repository each GitLab project
+// was migrated to, for multi-repository operations whose names do not map one to one.
+package mapping
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNotMapped is returned by Target for projects the mapping does not list
+var ErrNotMapped = errors.New("project is not in the mapping")
+
+// Mapping maps GitLab project paths to GitHub owner/name repositories
+type Mapping map[string]string
+
+// Formats of mapping files
+const (
+	FormatCSV  = "csv"
+	FormatYAML = "yaml"
+)
+
+// FormatOf returns the format of a mapping file from its extension: YAML for .yaml and
+// .yml files, CSV otherwise
+func FormatOf(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	default:
+		return FormatCSV
+	}
+}
+
+// ReadFile reads a mapping file; see Read
+func ReadFile(filename string) (Mapping, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mapping %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	m, err := Read(file, FormatOf(filename))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return m, nil
+}
+
+// Read reads a mapping in format from r. CSV mappings have two columns, the GitLab project
+// and the GitHub repository, and may start with a gitlab,github header row; lines starting
+// with # are ignored. YAML mappings are a single map of GitLab project to GitHub repository.
+// GitLab projects may be given in any form accepted by gitlab.ParseRepoPath.
+func Read(r io.Reader, format string) (Mapping, error) {
+	pairs, err := readPairs(r, format, "GitLab project to GitHub repository")
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(Mapping, len(pairs))
+	for _, pair := range pairs {
+		_, project, err := gitlab.ParseRepoPath(strings.TrimSpace(pair[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid GitLab project %q in mapping: %w", pair[0], err)
+		}
+
+		target := strings.TrimSpace(pair[1])
+		owner, name, ok := strings.Cut(target, "/")
+		if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("invalid GitHub repository %q for %s in mapping: expected owner/name", target, project)
+		}
+
+		if existing, ok := m[project]; ok && existing != target {
+			return nil, fmt.Errorf("project %s is mapped to both %s and %s", project, existing, target)
+		}
+		m[project] = target
+	}
+
+	return m, nil
+}
+
+// Target returns the GitHub repository a GitLab project was migrated to
+func (m Mapping) Target(project string) (string, error) {
+	_, projectPath, err := gitlab.ParseRepoPath(project)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse repository path: %w", err)
+	}
+
+	target, ok := m[projectPath]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotMapped, projectPath)
+	}
+	return target, nil
+}
+
+// readPairs reads the two-column rows of a CSV mapping, or the entries of a YAML map, in
+// format from r; what describes the entries in errors
+func readPairs(r io.Reader, format, what string) ([][2]string, error) {
+	var pairs [][2]string
+
+	switch format {
+	case FormatYAML:
+		var entries yaml.Node
+		if err := yaml.NewDecoder(r).Decode(&entries); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read YAML mapping: %w", err)
+		}
+		if len(entries.Content) == 0 {
+			break
+		}
+		root := entries.Content[0]
+		if root.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("invalid YAML mapping at line %d: expected a map of %s", root.Line, what)
+		}
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			key, value := root.Content[i], root.Content[i+1]
+			if value.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("invalid YAML mapping at line %d: expected a value for %s", value.Line, key.Value)
+			}
+			pairs = append(pairs, [2]string{key.Value, value.Value})
+		}
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.Comment = '#'
+		reader.TrimLeadingSpace = true
+		for first := true; ; first = false {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CSV mapping: %w", err)
+			}
+			line, _ := reader.FieldPos(0)
+			if len(record) != 2 {
+				return nil, fmt.Errorf("invalid CSV mapping at line %d: expected 2 columns, got %d", line, len(record))
+			}
+			if first && strings.EqualFold(record[0], "gitlab") {
+				continue // Header row
+			}
+			pairs = append(pairs, [2]string{record[0], record[1]})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported mapping format %q", format)
+	}
+	return pairs, nil
+}
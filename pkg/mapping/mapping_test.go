@@ -0,0 +1,141 @@
+package mapping
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		filename string
+		expected string
+	}{
+		{"mapping.csv", FormatCSV},
+		{"mapping.yaml", FormatYAML},
+		{"mapping.YML", FormatYAML},
+		{"mapping", FormatCSV},
+	}
+
+	for _, tt := range tests {
+		if got := FormatOf(tt.filename); got != tt.expected {
+			t.Errorf("FormatOf(%q) = %q, want %q", tt.filename, got, tt.expected)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		format      string
+		expected    Mapping
+		expectError bool
+	}{
+		{
+			name:     "CSV with header and comments",
+			content:  "gitlab,github\n# Restructured teams\nacme/backend/api, octo-org/api\nhttps://gitlab.com/acme/web.git,octo-org/website\n",
+			format:   FormatCSV,
+			expected: Mapping{"acme/backend/api": "octo-org/api", "acme/web": "octo-org/website"},
+		},
+		{
+			name:     "YAML",
+			content:  "# Restructured teams\nacme/backend/api: octo-org/api\n\"acme/web\": octo-org/website\n",
+			format:   FormatYAML,
+			expected: Mapping{"acme/backend/api": "octo-org/api", "acme/web": "octo-org/website"},
+		},
+		{
+			name:     "empty YAML",
+			content:  "",
+			format:   FormatYAML,
+			expected: Mapping{},
+		},
+		{
+			name:        "YAML list",
+			content:     "- acme/web\n",
+			format:      FormatYAML,
+			expectError: true,
+		},
+		{
+			name:        "invalid GitHub repository",
+			content:     "acme/web,website\n",
+			format:      FormatCSV,
+			expectError: true,
+		},
+		{
+			name:        "wrong column count",
+			content:     "acme/web,octo-org/website,extra\n",
+			format:      FormatCSV,
+			expectError: true,
+		},
+		{
+			name:        "conflicting targets",
+			content:     "acme/web,octo-org/website\nacme/web,octo-org/web\n",
+			format:      FormatCSV,
+			expectError: true,
+		},
+		{
+			name:        "unsupported format",
+			content:     "{}",
+			format:      "json",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Read(strings.NewReader(tt.content), tt.format)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Read() expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read() unexpected error = %v", err)
+			}
+			if len(m) != len(tt.expected) {
+				t.Fatalf("Read() = %v, want %v", m, tt.expected)
+			}
+			for project, target := range tt.expected {
+				if m[project] != target {
+					t.Errorf("Read()[%q] = %q, want %q", project, m[project], target)
+				}
+			}
+		})
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "mapping.yml")
+	if err := os.WriteFile(filename, []byte("acme/web: octo-org/website\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ReadFile() unexpected error = %v", err)
+	}
+	if m["acme/web"] != "octo-org/website" {
+		t.Errorf("ReadFile() = %v", m)
+	}
+
+	if _, err := ReadFile(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("ReadFile() expected an error for a missing file")
+	}
+}
+
+func TestMapping_Target(t *testing.T) {
+	m := Mapping{"acme/web": "octo-org/website"}
+
+	target, err := m.Target("https://gitlab.com/acme/web")
+	if err != nil || target != "octo-org/website" {
+		t.Errorf("Target() = %q, %v; want octo-org/website", target, err)
+	}
+
+	if _, err := m.Target("acme/other"); !errors.Is(err, ErrNotMapped) {
+		t.Errorf("Target() error = %v, want ErrNotMapped", err)
+	}
+}
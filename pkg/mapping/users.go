@@ -0,0 +1,59 @@
+package mapping
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Users maps GitLab usernames to GitHub logins, for exports that name people
+type Users map[string]string
+
+// ReadUsersFile reads a user mapping file; see ReadUsers
+func ReadUsersFile(filename string) (Users, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open user mapping %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	u, err := ReadUsers(file, FormatOf(filename))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return u, nil
+}
+
+// ReadUsers reads a user mapping in format from r, laid out like a repository mapping: CSV
+// files have two columns, the GitLab username and the GitHub login, and may start with a
+// gitlab,github header row; YAML files are a single map. A leading @ is dropped from either
+// name. Usernames are matched case-insensitively, as both GitLab and GitHub treat them.
+func ReadUsers(r io.Reader, format string) (Users, error) {
+	pairs, err := readPairs(r, format, "GitLab username to GitHub login")
+	if err != nil {
+		return nil, err
+	}
+
+	u := make(Users, len(pairs))
+	for _, pair := range pairs {
+		username := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(pair[0]), "@"))
+		login := strings.TrimPrefix(strings.TrimSpace(pair[1]), "@")
+		if username == "" || login == "" || strings.ContainsAny(login, "/ ") {
+			return nil, fmt.Errorf("invalid user mapping %q to %q: expected a GitLab username and a GitHub login", pair[0], pair[1])
+		}
+
+		if existing, ok := u[username]; ok && !strings.EqualFold(existing, login) {
+			return nil, fmt.Errorf("user %s is mapped to both %s and %s", username, existing, login)
+		}
+		u[username] = login
+	}
+
+	return u, nil
+}
+
+// Login returns the GitHub login of a GitLab user, and false when the mapping does not list them
+func (u Users) Login(username string) (string, bool) {
+	login, ok := u[strings.ToLower(username)]
+	return login, ok
+}
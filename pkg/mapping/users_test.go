@@ -0,0 +1,71 @@
+package mapping
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestReadUsers(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		format      string
+		expected    Users
+		expectError bool
+	}{
+		{
+			name:     "CSV with header",
+			content:  "gitlab,github\n# Contractors\nAlice,alice-gh\n@bob, @bobby\n",
+			format:   FormatCSV,
+			expected: Users{"alice": "alice-gh", "bob": "bobby"},
+		},
+		{
+			name:     "YAML",
+			content:  "alice: alice-gh\nbob: bobby\n",
+			format:   FormatYAML,
+			expected: Users{"alice": "alice-gh", "bob": "bobby"},
+		},
+		{
+			name:        "mapped twice",
+			content:     "alice,alice-gh\nALICE,someone-else\n",
+			format:      FormatCSV,
+			expectError: true,
+		},
+		{
+			name:        "empty login",
+			content:     "alice,\n",
+			format:      FormatCSV,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := ReadUsers(strings.NewReader(tt.content), tt.format)
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("Expected an error, got %v", users)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadUsers() unexpected error = %v", err)
+			}
+			if !maps.Equal(users, tt.expected) {
+				t.Errorf("ReadUsers() = %v, want %v", users, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUsers_Login(t *testing.T) {
+	users := Users{"alice": "alice-gh"}
+
+	if login, ok := users.Login("Alice"); !ok || login != "alice-gh" {
+		t.Errorf("Login(Alice) = %q, %v", login, ok)
+	}
+	if _, ok := users.Login("carol"); ok {
+		t.Error("Expected carol to be unmapped")
+	}
+}
@@ -0,0 +1,178 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// CreateOptions tunes CreateBranches
+type CreateOptions struct {
+	// BranchName names the branch of a merge request; BranchName with DefaultBranchPrefix when nil
+	BranchName func(iid int) string
+	// RefBranchName names the branch of a reference, for names made of more than the IID,
+	// such as those of a Naming; it takes precedence over BranchName
+	RefBranchName func(ref gitlab.MergeRequestRef) string
+	// Total is the number of references expected, only used in error messages
+	Total int
+	// Check is called before each branch is created; an error stops the run with that
+	// error, for example to stop between branches on a signal
+	Check func(ctx context.Context) error
+	// Existing maps the branches already in the project to their head commit, as returned
+	// by gitlab.API.ListBranchSHAs. When set, a branch already at the head commit of its
+	// reference is left as it is, and one at another commit is deleted and created again at
+	// it, so that a run can be repeated; otherwise a taken name fails as existing.
+	Existing map[string]string
+	// OnBranch is called after each branch is created, updated, left unchanged or fails
+	OnBranch func(BranchResult)
+	Logger   *slog.Logger
+}
+
+// BranchResult is the outcome of creating the branch of one merge request
+type BranchResult struct {
+	Ref    gitlab.MergeRequestRef
+	Branch string
+	// Err is nil when the branch was created, updated or left unchanged
+	Err error
+	// Updated is set when an existing branch was moved to the head commit, and Unchanged
+	// when it was already there; see CreateOptions.Existing
+	Updated   bool
+	Unchanged bool
+}
+
+// CreateResult counts the branches CreateBranches created and failed to create
+type CreateResult struct {
+	Created int
+	Failed  int
+	// Existing counts the failed branches whose name was already taken
+	Existing int
+	// Updated and Unchanged count the existing branches moved to their head commit and
+	// those already there, with CreateOptions.Existing
+	Updated   int
+	Unchanged int
+}
+
+// handled is the number of references processed so far
+func (r CreateResult) handled() int {
+	return r.Created + r.Failed + r.Updated + r.Unchanged
+}
+
+// Refs yields refs without error, to create the branches of references already in memory
+func Refs(refs []gitlab.MergeRequestRef) iter.Seq2[gitlab.MergeRequestRef, error] {
+	return func(yield func(gitlab.MergeRequestRef, error) bool) {
+		for _, ref := range refs {
+			if !yield(ref, nil) {
+				return
+			}
+		}
+	}
+}
+
+// CreateBranches creates a branch in the GitLab project projectPath at the head commit of
+// each reference yielded by refs. A branch that fails is counted and the run goes on,
+// unless the error stops the run (see StopsRun); the result then counts the branches
+// handled so far and the error says after how many it stopped.
+func CreateBranches(ctx context.Context, client gitlab.API, projectPath string, refs iter.Seq2[gitlab.MergeRequestRef, error], opts CreateOptions) (CreateResult, error) {
+	logger := loggerOr(opts.Logger)
+	branchName := opts.BranchName
+	if branchName == nil {
+		branchName = func(iid int) string { return BranchName(DefaultBranchPrefix, iid) }
+	}
+
+	var result CreateResult
+	// Names shared by several references are only taken by the first, as without Existing
+	taken := make(map[string]bool)
+	stopped := func(err error) error {
+		if opts.Total > 0 {
+			return fmt.Errorf("stopped after %d of %d branches: %w", result.handled(), opts.Total, err)
+		}
+		return fmt.Errorf("stopped after %d branches: %w", result.handled(), err)
+	}
+
+	for ref, err := range refs {
+		if err != nil {
+			return result, fmt.Errorf("failed to read merge request references: %w", err)
+		}
+		if opts.Check != nil {
+			if err := opts.Check(ctx); err != nil {
+				return result, stopped(err)
+			}
+		}
+
+		name := branchName(ref.IID)
+		if opts.RefBranchName != nil {
+			name = opts.RefBranchName(ref)
+		}
+		sha, exists := opts.Existing[name]
+		exists = exists && !taken[name]
+		if opts.Existing != nil {
+			taken[name] = true
+		}
+		if exists && sha == ref.HeadSHA {
+			logger.Info("branch already at head commit", "project", projectPath, "iid", ref.IID, "branch", name, "sha", ref.HeadSHA)
+			result.Unchanged++
+			if opts.OnBranch != nil {
+				opts.OnBranch(BranchResult{Ref: ref, Branch: name, Unchanged: true})
+			}
+			continue
+		}
+
+		var err error
+		if exists {
+			err = UpdateBranch(ctx, client, projectPath, name, "", ref.HeadSHA)
+		} else {
+			err = client.CreateBranch(ctx, projectPath, name, ref.HeadSHA)
+		}
+		switch {
+		case err != nil:
+			logger.Error("failed to create branch", "project", projectPath, "iid", ref.IID, "branch", name, "sha", ref.HeadSHA, "error", err)
+			result.Failed++
+			if errors.Is(err, gitlab.ErrBranchExists) {
+				result.Existing++
+			}
+		case exists:
+			logger.Info("updated branch", "project", projectPath, "iid", ref.IID, "branch", name, "sha", ref.HeadSHA, "previous_sha", sha)
+			result.Updated++
+		default:
+			logger.Info("created branch", "project", projectPath, "iid", ref.IID, "branch", name, "sha", ref.HeadSHA)
+			result.Created++
+		}
+		if opts.OnBranch != nil {
+			opts.OnBranch(BranchResult{Ref: ref, Branch: name, Err: err, Updated: exists && err == nil})
+		}
+
+		if err != nil && StopsRun(err) {
+			return result, stopped(err)
+		}
+	}
+
+	return result, nil
+}
+
+// ErrBranchMoved is returned by UpdateBranch when a branch no longer points at the commit
+// it was expected at
+var ErrBranchMoved = errors.New("branch moved")
+
+// UpdateBranch moves an existing branch to sha. GitLab has no "move branch" endpoint, so it
+// is deleted and created again; a branch deleted in the meantime only needs to be created.
+// When from is set, the branch is read again first and left alone with ErrBranchMoved unless
+// it still points at from, so that commits pushed to it since it was read are not lost.
+func UpdateBranch(ctx context.Context, client gitlab.API, projectPath, name, from, sha string) error {
+	if from != "" {
+		current, err := client.BranchSHA(ctx, projectPath, name)
+		if err != nil && !errors.Is(err, gitlab.ErrBranchNotFound) {
+			return err
+		}
+		if err == nil && current != from {
+			return fmt.Errorf("branch '%s' is at %s, not %s: %w", name, current, from, ErrBranchMoved)
+		}
+	}
+	if err := client.DeleteBranch(ctx, projectPath, name); err != nil && !errors.Is(err, gitlab.ErrBranchNotFound) {
+		return err
+	}
+	return client.CreateBranch(ctx, projectPath, name, sha)
+}
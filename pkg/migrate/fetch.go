@@ -0,0 +1,125 @@
+package migrate
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/output"
+)
+
+// FetchOptions tunes FetchRefs. The zero value fetches every merge request in the order
+// GitLab lists them.
+type FetchOptions struct {
+	// Filters select and tune the merge requests fetched, such as gitlab.WithState
+	Filters []gitlab.FetchOption
+	// VerifySHA checks that the head commit of each merge request is in the project, and
+	// writes the references whose commit is missing to Unreachable instead of the output
+	VerifySHA   bool
+	Unreachable output.Writer
+	// Sort, when set, orders the references before they are written. They are kept in
+	// memory until the fetch completes.
+	Sort func([]gitlab.MergeRequestRef)
+	// Check is called before each merge request is processed; an error stops the fetch
+	// with that error, for example to stop between merge requests on a signal
+	Check func(ctx context.Context) error
+	// OnRef is called for each reference written to the output
+	OnRef  func(gitlab.MergeRequestRef)
+	Logger *slog.Logger
+}
+
+// FetchResult counts what FetchRefs wrote
+type FetchResult struct {
+	// ProjectPath is the project fetched, as parsed from the repository
+	ProjectPath string
+	Refs        int
+	// Unreachable counts the references written to FetchOptions.Unreachable
+	Unreachable int
+	// Forks counts the merge requests from forks among Refs
+	Forks int
+}
+
+// FetchRefs writes the merge request references of repository, a project path or URL, to
+// w. It leaves closing or aborting w and opts.Unreachable to the caller, so that a failed
+// fetch can discard its output.
+func FetchRefs(ctx context.Context, client gitlab.API, repository string, w output.Writer, opts FetchOptions) (FetchResult, error) {
+	var result FetchResult
+	_, projectPath, err := gitlab.ParseRepoPath(repository)
+	if err != nil {
+		return result, err
+	}
+	result.ProjectPath = projectPath
+
+	var buffered []gitlab.MergeRequestRef
+	write := func(ref gitlab.MergeRequestRef) error {
+		result.Refs++
+		if ref.Fork {
+			result.Forks++
+		}
+		if opts.OnRef != nil {
+			opts.OnRef(ref)
+		}
+		if opts.Sort != nil {
+			buffered = append(buffered, ref)
+			return nil
+		}
+		return w.WriteRef(ref)
+	}
+
+	processor := func(ref gitlab.MergeRequestRef) error {
+		if opts.Check != nil {
+			if err := opts.Check(ctx); err != nil {
+				return err
+			}
+		}
+		if opts.VerifySHA {
+			ok, err := client.CommitExists(ctx, projectPath, ref.HeadSHA)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				loggerOr(opts.Logger).Warn("head commit not found in project", "project", projectPath, "iid", ref.IID, "sha", ref.HeadSHA)
+				result.Unreachable++
+				if opts.Unreachable == nil {
+					return nil
+				}
+				return opts.Unreachable.WriteRef(ref)
+			}
+		}
+		return write(ref)
+	}
+
+	if _, err := client.FetchMergeRequestRefsFromRepo(ctx, repository, "", processor, opts.Filters...); err != nil {
+		return result, err
+	}
+
+	if opts.Sort != nil {
+		opts.Sort(buffered)
+		for _, ref := range buffered {
+			if err := w.WriteRef(ref); err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// CollectRefs fetches the merge request references of repository into a slice
+func CollectRefs(ctx context.Context, client gitlab.API, repository string, opts FetchOptions) ([]gitlab.MergeRequestRef, error) {
+	var refs sliceWriter
+	_, err := FetchRefs(ctx, client, repository, &refs, opts)
+	return slices.Clip(refs), err
+}
+
+// sliceWriter is an output.Writer collecting references in memory
+type sliceWriter []gitlab.MergeRequestRef
+
+func (s *sliceWriter) WriteRef(ref gitlab.MergeRequestRef) error {
+	*s = append(*s, ref)
+	return nil
+}
+
+func (s *sliceWriter) Close() error { return nil }
+
+func (s *sliceWriter) Abort() {}
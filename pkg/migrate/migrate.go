@@ -0,0 +1,48 @@
+// Package migrate is the core of gh-gl-create-refs as a library: fetching the merge request
+// references of a GitLab project into an output, and creating a branch for each of them, so
+// that a migration orchestrator can embed the tool instead of running it. It never prints,
+// exits or reads flags and environment variables; progress is reported through the
+// callbacks and the *slog.Logger of the options, and failures as errors that can be tested
+// with errors.Is against the sentinel errors of the gitlab package.
+//
+// This package, gitlab, output and csv make up the stable API: exported identifiers are not
+// removed or changed incompatibly within a major version, and deprecated ones stay until the
+// next. The cmd package and the other packages are the command-line tool's and may change
+// in any release.
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// DefaultBranchPrefix is the prefix of the branch names created for merge requests when
+// CreateOptions.BranchName is not set
+const DefaultBranchPrefix = "migration-pr-"
+
+// BranchName returns the branch created for merge request iid with prefix, e.g. migration-pr-42
+func BranchName(prefix string, iid int) string {
+	return fmt.Sprintf("%s%d", prefix, iid)
+}
+
+// StopsRun reports whether err fails every remaining request the same way, so a run should
+// stop instead of repeating it: the token is rejected, the project is gone or GitLab keeps
+// rate limiting, or the request budget is used up
+func StopsRun(err error) bool {
+	return errors.Is(err, gitlab.ErrUnauthorized) || errors.Is(err, gitlab.ErrProjectNotFound) ||
+		errors.Is(err, gitlab.ErrRateLimited) || errors.Is(err, gitlab.ErrBudgetExhausted)
+}
+
+// discardLogger is used when the options give no logger
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func loggerOr(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return discardLogger
+	}
+	return logger
+}
@@ -0,0 +1,219 @@
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlabtest"
+	"github.com/amenocal/gh-gl-create-refs/pkg/migrate"
+)
+
+func newClient(t *testing.T, srv *gitlabtest.Server) *gitlab.Client {
+	t.Helper()
+
+	client, err := gitlab.NewClient("token", srv.URL, gitlab.WithRateLimit(0, 1))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	return client
+}
+
+func TestFetchRefs(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	srv.AddProject("group/project",
+		gitlabtest.MergeRequest{IID: 2, SHA: "bbb"},
+		gitlabtest.MergeRequest{IID: 3, SHA: "ccc", SourceProjectID: 99},
+		gitlabtest.MergeRequest{IID: 1, SHA: "aaa"},
+	)
+	client := newClient(t, srv)
+
+	var seen []int
+	refs, err := migrate.CollectRefs(context.Background(), client, "group/project", migrate.FetchOptions{
+		Sort: func(refs []gitlab.MergeRequestRef) {
+			slices.SortFunc(refs, func(a, b gitlab.MergeRequestRef) int { return a.IID - b.IID })
+		},
+		OnRef: func(ref gitlab.MergeRequestRef) { seen = append(seen, ref.IID) },
+	})
+	if err != nil {
+		t.Fatalf("CollectRefs failed: %v", err)
+	}
+
+	var iids []int
+	for _, ref := range refs {
+		iids = append(iids, ref.IID)
+	}
+	if !slices.Equal(iids, []int{1, 2, 3}) {
+		t.Errorf("CollectRefs() IIDs = %v, want sorted [1 2 3]", iids)
+	}
+	if !slices.Equal(seen, []int{2, 3, 1}) {
+		t.Errorf("OnRef saw %v, want the fetch order [2 3 1]", seen)
+	}
+}
+
+func TestFetchRefs_VerifySHA(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	srv.AddProject("group/project", gitlabtest.MergeRequest{IID: 1, SHA: "aaa"})
+	client := newClient(t, srv)
+
+	// A stand-in for a client that can't find the commit
+	missing := &missingCommits{API: client}
+
+	var out, unreachable collector
+	result, err := migrate.FetchRefs(context.Background(), missing, "group/project", &out, migrate.FetchOptions{VerifySHA: true, Unreachable: &unreachable})
+	if err != nil {
+		t.Fatalf("FetchRefs failed: %v", err)
+	}
+	if result.Refs != 0 || result.Unreachable != 1 || len(out) != 0 || len(unreachable) != 1 {
+		t.Errorf("FetchRefs() = %+v, wrote %d and %d unreachable; want the reference unreachable", result, len(out), len(unreachable))
+	}
+}
+
+func TestFetchRefs_Check(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	srv.AddProject("group/project", gitlabtest.MergeRequest{IID: 1, SHA: "aaa"})
+	client := newClient(t, srv)
+
+	stop := errors.New("stop")
+	_, err := migrate.CollectRefs(context.Background(), client, "group/project", migrate.FetchOptions{
+		Check: func(context.Context) error { return stop },
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("CollectRefs() error = %v, want the Check error", err)
+	}
+}
+
+func TestCreateBranches(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	srv.AddProject("group/project")
+	client := newClient(t, srv)
+	ctx := context.Background()
+
+	if err := client.CreateBranch(ctx, "group/project", "migration-pr-2", "bbb"); err != nil {
+		t.Fatal(err)
+	}
+
+	refs := []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaa"}, {IID: 2, HeadSHA: "bbb"}}
+	var results []migrate.BranchResult
+	result, err := migrate.CreateBranches(ctx, client, "group/project", migrate.Refs(refs), migrate.CreateOptions{
+		OnBranch: func(b migrate.BranchResult) { results = append(results, b) },
+	})
+	if err != nil {
+		t.Fatalf("CreateBranches failed: %v", err)
+	}
+	if result != (migrate.CreateResult{Created: 1, Failed: 1, Existing: 1}) {
+		t.Errorf("CreateBranches() = %+v", result)
+	}
+	if len(results) != 2 || results[0].Branch != "migration-pr-1" || !errors.Is(results[1].Err, gitlab.ErrBranchExists) {
+		t.Errorf("OnBranch results = %+v", results)
+	}
+	if srv.Branches("group/project")["migration-pr-1"] != "aaa" {
+		t.Errorf("Branches() = %v, want migration-pr-1 at aaa", srv.Branches("group/project"))
+	}
+}
+
+func TestCreateBranches_Existing(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	srv.AddProject("group/project")
+	client := newClient(t, srv)
+	ctx := context.Background()
+
+	for name, sha := range map[string]string{"migration-pr-2": "bbb", "migration-pr-3": "old"} {
+		if err := client.CreateBranch(ctx, "group/project", name, sha); err != nil {
+			t.Fatal(err)
+		}
+	}
+	existing, err := client.ListBranchSHAs(ctx, "group/project", "migration-pr-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refs := []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaa"}, {IID: 2, HeadSHA: "bbb"}, {IID: 3, HeadSHA: "ccc"}}
+	var results []migrate.BranchResult
+	result, err := migrate.CreateBranches(ctx, client, "group/project", migrate.Refs(refs), migrate.CreateOptions{
+		Existing: existing,
+		OnBranch: func(b migrate.BranchResult) { results = append(results, b) },
+	})
+	if err != nil {
+		t.Fatalf("CreateBranches failed: %v", err)
+	}
+	if result != (migrate.CreateResult{Created: 1, Updated: 1, Unchanged: 1}) {
+		t.Errorf("CreateBranches() = %+v", result)
+	}
+	if len(results) != 3 || !results[1].Unchanged || !results[2].Updated {
+		t.Errorf("OnBranch results = %+v", results)
+	}
+	want := map[string]string{"migration-pr-1": "aaa", "migration-pr-2": "bbb", "migration-pr-3": "ccc"}
+	if got := srv.Branches("group/project"); !maps.Equal(got, want) {
+		t.Errorf("Branches() = %v, want %v", got, want)
+	}
+}
+
+func TestCreateBranches_StopsRun(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	client := newClient(t, srv)
+
+	refs := []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaa"}, {IID: 2, HeadSHA: "bbb"}}
+	result, err := migrate.CreateBranches(context.Background(), client, "group/missing", migrate.Refs(refs), migrate.CreateOptions{Total: 2})
+	if !errors.Is(err, gitlab.ErrProjectNotFound) {
+		t.Fatalf("CreateBranches() error = %v, want ErrProjectNotFound", err)
+	}
+	if !strings.HasPrefix(err.Error(), "stopped after 1 of 2 branches") {
+		t.Errorf("CreateBranches() error = %q", err)
+	}
+	if result.Failed != 1 {
+		t.Errorf("CreateBranches() = %+v, want 1 failed", result)
+	}
+}
+
+// missingCommits is a gitlab.API where no commit exists
+type missingCommits struct {
+	gitlab.API
+}
+
+func (missingCommits) CommitExists(context.Context, string, string) (bool, error) {
+	return false, nil
+}
+
+// collector is an output.Writer collecting references
+type collector []gitlab.MergeRequestRef
+
+func (c *collector) WriteRef(ref gitlab.MergeRequestRef) error {
+	*c = append(*c, ref)
+	return nil
+}
+
+func (c *collector) Close() error { return nil }
+
+func (c *collector) Abort() {}
+
+func TestUpdateBranch(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	srv.AddProject("group/project")
+	client := newClient(t, srv)
+	ctx := context.Background()
+
+	if err := client.CreateBranch(ctx, "group/project", "migration-pr-1", "pushed"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The branch is no longer where the caller last saw it
+	err := migrate.UpdateBranch(ctx, client, "group/project", "migration-pr-1", "old", "aaa")
+	if !errors.Is(err, migrate.ErrBranchMoved) {
+		t.Errorf("UpdateBranch() error = %v, want ErrBranchMoved", err)
+	}
+	if got := srv.Branches("group/project")["migration-pr-1"]; got != "pushed" {
+		t.Errorf("Branch is at %q, want it left at pushed", got)
+	}
+
+	if err := migrate.UpdateBranch(ctx, client, "group/project", "migration-pr-1", "pushed", "aaa"); err != nil {
+		t.Fatalf("UpdateBranch() unexpected error = %v", err)
+	}
+	if got := srv.Branches("group/project")["migration-pr-1"]; got != "aaa" {
+		t.Errorf("Branch is at %q, want aaa", got)
+	}
+}
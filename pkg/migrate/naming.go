@@ -0,0 +1,76 @@
+package migrate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// Naming is a preset pattern of the branch names created for merge requests. Every preset
+// starts the name with the branch prefix.
+type Naming string
+
+const (
+	// NamingPRNumber names branches after the merge request IID, e.g. migration-pr-42. Names
+	// are unique within a project, and a re-run finds the branches of the earlier run.
+	NamingPRNumber Naming = "pr-number"
+	// NamingSourceBranch names branches after the merge request's source branch, e.g.
+	// migration-pr-feature/login. Merge requests sharing a source branch, such as one
+	// reopened as another or two forks' main branches, share a name: the first one gets the
+	// branch and the others fail as already existing. References without a source branch,
+	// such as those read from a CSV file, are named as with NamingPRNumber.
+	NamingSourceBranch Naming = "source-branch"
+	// NamingShortSHA names branches after the abbreviated head commit, e.g.
+	// migration-pr-1a2b3c4d. Merge requests with the same head commit share a branch, and
+	// the others fail as already existing; that branch points at their commit all the same.
+	NamingShortSHA Naming = "short-sha"
+	// NamingPRAndSHA names branches after both, e.g. migration-pr-42-1a2b3c4d. Names never
+	// collide, but a merge request that gets new commits gets a new branch on the next run
+	// rather than a conflict with its old one.
+	NamingPRAndSHA Naming = "pr-and-sha"
+)
+
+// ShortSHALength is the number of hexadecimal digits of the commits in branch names, as in
+// GitLab's short IDs
+const ShortSHALength = 8
+
+// Namings lists the presets
+func Namings() []Naming {
+	return []Naming{NamingPRNumber, NamingSourceBranch, NamingShortSHA, NamingPRAndSHA}
+}
+
+// ParseNaming returns the preset called name
+func ParseNaming(name string) (Naming, error) {
+	for _, n := range Namings() {
+		if string(n) == name {
+			return n, nil
+		}
+	}
+	names := make([]string, 0, len(Namings()))
+	for _, n := range Namings() {
+		names = append(names, string(n))
+	}
+	return "", fmt.Errorf("unknown naming %q: must be one of %s", name, strings.Join(names, ", "))
+}
+
+// BranchName returns the branch created for ref with prefix. The empty Naming is
+// NamingPRNumber.
+func (n Naming) BranchName(prefix string, ref gitlab.MergeRequestRef) string {
+	switch n {
+	case NamingSourceBranch:
+		if ref.SourceBranch != "" {
+			return prefix + ref.SourceBranch
+		}
+	case NamingShortSHA:
+		return prefix + shortSHA(ref.HeadSHA)
+	case NamingPRAndSHA:
+		return fmt.Sprintf("%s%d-%s", prefix, ref.IID, shortSHA(ref.HeadSHA))
+	}
+	return BranchName(prefix, ref.IID)
+}
+
+// shortSHA abbreviates sha to ShortSHALength digits
+func shortSHA(sha string) string {
+	return sha[:min(len(sha), ShortSHALength)]
+}
@@ -0,0 +1,82 @@
+package migrate_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlabtest"
+	"github.com/amenocal/gh-gl-create-refs/pkg/migrate"
+)
+
+func TestNaming_BranchName(t *testing.T) {
+	ref := gitlab.MergeRequestRef{IID: 42, HeadSHA: "1a2b3c4d5e6f7a8b9c0d", SourceBranch: "feature/login"}
+
+	tests := []struct {
+		naming   migrate.Naming
+		ref      gitlab.MergeRequestRef
+		expected string
+	}{
+		{"", ref, "migration-pr-42"},
+		{migrate.NamingPRNumber, ref, "migration-pr-42"},
+		{migrate.NamingSourceBranch, ref, "migration-pr-feature/login"},
+		{migrate.NamingSourceBranch, gitlab.MergeRequestRef{IID: 42, HeadSHA: "1a2b3c4d"}, "migration-pr-42"},
+		{migrate.NamingShortSHA, ref, "migration-pr-1a2b3c4d"},
+		{migrate.NamingShortSHA, gitlab.MergeRequestRef{IID: 42, HeadSHA: "abc"}, "migration-pr-abc"},
+		{migrate.NamingPRAndSHA, ref, "migration-pr-42-1a2b3c4d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.naming)+" "+tt.ref.SourceBranch, func(t *testing.T) {
+			if got := tt.naming.BranchName(migrate.DefaultBranchPrefix, tt.ref); got != tt.expected {
+				t.Errorf("BranchName() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseNaming(t *testing.T) {
+	for _, n := range migrate.Namings() {
+		got, err := migrate.ParseNaming(string(n))
+		if err != nil || got != n {
+			t.Errorf("ParseNaming(%q) = %q, %v", n, got, err)
+		}
+	}
+	if _, err := migrate.ParseNaming("pr-title"); err == nil {
+		t.Error("ParseNaming() expected an error for an unknown naming")
+	}
+}
+
+func TestCreateBranches_SourceBranchNaming(t *testing.T) {
+	srv := gitlabtest.NewServer(t)
+	srv.AddProject("group/project",
+		gitlabtest.MergeRequest{IID: 1, SHA: "aaa", SourceBranch: "feature/login"},
+		gitlabtest.MergeRequest{IID: 2, SHA: "bbb", SourceBranch: "feature/login"},
+		gitlabtest.MergeRequest{IID: 3, SHA: "ccc", SourceBranch: "fix/typo"},
+	)
+	client := newClient(t, srv)
+	ctx := context.Background()
+
+	refs, err := migrate.CollectRefs(ctx, client, "group/project", migrate.FetchOptions{})
+	if err != nil {
+		t.Fatalf("CollectRefs failed: %v", err)
+	}
+
+	result, err := migrate.CreateBranches(ctx, client, "group/project", migrate.Refs(refs), migrate.CreateOptions{
+		RefBranchName: func(ref gitlab.MergeRequestRef) string {
+			return migrate.NamingSourceBranch.BranchName("mr/", ref)
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateBranches failed: %v", err)
+	}
+
+	// Both merge requests from feature/login want the same branch; the first one gets it
+	if result != (migrate.CreateResult{Created: 2, Failed: 1, Existing: 1}) {
+		t.Errorf("CreateBranches() = %+v", result)
+	}
+	branches := srv.Branches("group/project")
+	if branches["mr/feature/login"] != "aaa" || branches["mr/fix/typo"] != "ccc" {
+		t.Errorf("Branches() = %v", branches)
+	}
+}
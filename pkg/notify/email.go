@@ -0,0 +1,245 @@
+package notify
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSMTPPort is the submission port used when no port is configured
+const DefaultSMTPPort = 587
+
+// maxEmailAttachmentSize is the largest results file attached to an email; larger files are
+// named in the body instead, keeping the message well below common size limits
+const maxEmailAttachmentSize = 10 * 1024 * 1024
+
+// Email sends run summaries by SMTP
+type Email struct {
+	Host string
+	// Port is DefaultSMTPPort when zero. Port 465 uses implicit TLS; other ports upgrade the
+	// connection with STARTTLS when the server offers it.
+	Port int
+	// Username and Password authenticate with PLAIN authentication when Username is set,
+	// which net/smtp only allows over TLS or to localhost
+	Username string
+	Password string
+	From     string
+	To       []string
+	// Subject and Body are templates rendered with the Summary; DefaultSubject and
+	// DefaultBody when empty
+	Subject string
+	Body    string
+	// TLSConfig is used for implicit TLS and STARTTLS; the default verifies Host
+	TLSConfig *tls.Config
+}
+
+// Notify renders the summary and emails it, attaching its results file when it is small enough
+func (e *Email) Notify(ctx context.Context, s Summary) error {
+	if e.Host == "" || e.From == "" || len(e.To) == 0 {
+		return errors.New("email notifications need a host, a from address and at least one recipient")
+	}
+
+	subject, err := Render("subject", cmp.Or(e.Subject, DefaultSubject), s)
+	if err != nil {
+		return err
+	}
+	body, err := Render("body", cmp.Or(e.Body, DefaultBody), s)
+	if err != nil {
+		return err
+	}
+
+	msg, err := e.message(strings.TrimSpace(subject), body, s.File)
+	if err != nil {
+		return err
+	}
+	if err := e.send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", strings.Join(e.To, ", "), err)
+	}
+	return nil
+}
+
+// message builds the MIME message: the body as text, and the results file as an attachment
+func (e *Email) message(subject, body, file string) ([]byte, error) {
+	var b bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&b, "%s: %s\r\n", key, value)
+	}
+	header("From", e.From)
+	header("To", strings.Join(e.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(e.From))
+	header("MIME-Version", "1.0")
+
+	var attachment []byte
+	if file != "" {
+		info, err := os.Stat(file)
+		switch {
+		case err != nil:
+			// A results file that is gone is not attached; the body still names it
+		case info.Size() > maxEmailAttachmentSize:
+			body += fmt.Sprintf("\n%s is too large to attach (%d bytes).\n", filepath.Base(file), info.Size())
+		default:
+			if attachment, err = os.ReadFile(file); err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", file, err)
+			}
+		}
+	}
+
+	if attachment == nil {
+		header("Content-Type", `text/plain; charset="utf-8"`)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		b.WriteString("\r\n")
+		if err := writeQuotedPrintable(&b, body); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&b)
+	header("Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mw.Boundary()}))
+	b.WriteString("\r\n")
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {`text/plain; charset="utf-8"`},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeQuotedPrintable(part, body); err != nil {
+		return nil, err
+	}
+
+	name := filepath.Base(file)
+	part, err = mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {mime.FormatMediaType("application/octet-stream", map[string]string{"name": name})},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": name})},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBase64(part, attachment); err != nil {
+		return nil, err
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// send delivers msg to every recipient
+func (e *Email) send(ctx context.Context, msg []byte) error {
+	port := e.Port
+	if port == 0 {
+		port = DefaultSMTPPort
+	}
+	addr := net.JoinHostPort(e.Host, strconv.Itoa(port))
+	tlsConfig := e.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: e.Host}
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if port == 465 {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	// Don't let a stalled server hold the end of the run
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, e.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok && port != 465 {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if e.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.Username, e.Password, e.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(e.From); err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// messageID returns a unique Message-ID in the domain of the from address
+func messageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndexByte(from, '@'); i >= 0 {
+		domain = strings.TrimSuffix(from[i+1:], ">")
+	}
+	var id [12]byte
+	rand.Read(id[:])
+	return fmt.Sprintf("<%x.%d@%s>", id, time.Now().Unix(), domain)
+}
+
+// writeQuotedPrintable writes text quoted-printable encoded with CRLF line endings
+func writeQuotedPrintable(w io.Writer, text string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qp, strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// writeBase64 writes data base64 encoded in lines of 76 characters, as MIME requires
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := min(76, len(encoded))
+		if _, err := io.WriteString(w, encoded[:n]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
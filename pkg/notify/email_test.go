@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// smtpServer is a minimal SMTP server accepting one message, without TLS or authentication
+type smtpServer struct {
+	addr       string
+	recipients []string
+	data       chan string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	s := &smtpServer{addr: l.Addr().String(), data: make(chan string, 1)}
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				s.recipients = append(s.recipients, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "DATA"):
+				reply("354 Go ahead")
+				var b strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					b.WriteString(strings.TrimPrefix(l, "."))
+				}
+				s.data <- b.String()
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "QUIT"):
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return s
+}
+
+func TestEmail_Notify(t *testing.T) {
+	server := newSMTPServer(t)
+	host, port, _ := net.SplitHostPort(server.addr)
+	portNumber, _ := strconv.Atoi(port)
+
+	file := filepath.Join(t.TempDir(), "refs.csv")
+	if err := os.WriteFile(file, []byte("1,sha1\n2,sha2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	email := &Email{
+		Host:    host,
+		Port:    portNumber,
+		From:    "migration-bot@example.com",
+		To:      []string{"team@example.com", "lead@example.com"},
+		Subject: "[migration] {{.Command}} {{.Status}}",
+	}
+	s := Summary{Command: "create-refs", Counts: []Count{{"Branches created", 2}}, File: file}
+	if err := email.Notify(context.Background(), s); err != nil {
+		t.Fatalf("Notify() unexpected error = %v", err)
+	}
+
+	if strings.Join(server.recipients, ",") != "team@example.com,lead@example.com" {
+		t.Errorf("recipients = %v", server.recipients)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(<-server.data))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	if got := msg.Header.Get("Subject"); got != "[migration] create-refs completed" {
+		t.Errorf("Subject = %q", got)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q, want multipart/mixed", msg.Header.Get("Content-Type"))
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+
+	body, err := mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := readAll(t, body)
+	if !strings.Contains(text, "create-refs completed.") || !strings.Contains(text, "Branches created: 2") {
+		t.Errorf("body = %q", text)
+	}
+
+	attachment, err := mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attachment.FileName() != "refs.csv" {
+		t.Errorf("attachment name = %q", attachment.FileName())
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(readAll(t, attachment), "\r\n", ""))
+	if err != nil || string(data) != "1,sha1\n2,sha2\n" {
+		t.Errorf("attachment = %q, %v", data, err)
+	}
+}
+
+func TestEmail_NotifyRequiresRecipients(t *testing.T) {
+	email := &Email{Host: "localhost", From: "migration-bot@example.com"}
+	if err := email.Notify(context.Background(), Summary{}); err == nil {
+		t.Error("Notify() expected an error without recipients")
+	}
+}
+
+func readAll(t *testing.T, r io.Reader) string {
+	t.Helper()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
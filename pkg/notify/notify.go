@@ -0,0 +1,107 @@
+// Package notify tells people how a run went once it is over, over channels such as Slack,
+// Microsoft Teams, webhooks and email, with messages rendered from templates that users can
+// replace.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+)
+
+// Notifier sends the summary of a finished run to one channel
+type Notifier interface {
+	Notify(ctx context.Context, s Summary) error
+}
+
+// Summary is the data a notification is rendered from
+type Summary struct {
+	// Command is the command that ran, such as create-refs
+	Command   string        `json:"command"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"-"`
+	Counts    []Count       `json:"counts"`
+	// Error is why the run failed, and empty when it succeeded
+	Error string `json:"error,omitempty"`
+	// File is the results file of the run, if any
+	File string `json:"file,omitempty"`
+}
+
+// Count is one counter of a run, such as the branches created
+type Count struct {
+	Label string `json:"label"`
+	Value int    `json:"value"`
+}
+
+// MarshalJSON adds the status and the duration in seconds, which is what webhooks receive
+func (s Summary) MarshalJSON() ([]byte, error) {
+	type summary Summary
+	return json.Marshal(struct {
+		summary
+		Status          string  `json:"status"`
+		DurationSeconds float64 `json:"duration_seconds"`
+	}{summary(s), s.Status(), s.Duration.Seconds()})
+}
+
+// Succeeded reports whether the run succeeded
+func (s Summary) Succeeded() bool {
+	return s.Error == ""
+}
+
+// Status is "completed" or "failed"
+func (s Summary) Status() string {
+	if s.Succeeded() {
+		return "completed"
+	}
+	return "failed"
+}
+
+// DefaultSubject is the subject of notifications when none is configured
+const DefaultSubject = `gh-gl-create-refs: {{.Command}} {{.Status}}`
+
+// DefaultBody is the text of notifications when no template is configured
+const DefaultBody = `{{.Command}} {{.Status}}.
+
+Started:  {{.StartedAt.UTC.Format "2006-01-02T15:04:05Z07:00"}}
+Duration: {{.Duration}}
+{{range .Counts}}{{.Label}}: {{.Value}}
+{{end}}{{with .File}}Results file: {{.}}
+{{end}}{{with .Error}}
+Error: {{.}}
+{{end}}`
+
+// DefaultMessage is the text of chat notifications when no template is configured
+const DefaultMessage = `{{if .Succeeded}}✅{{else}}❌{{end}} gh-gl-create-refs {{.Command}} {{.Status}} after {{.Duration}}
+{{- range .Counts}}
+• {{.Label}}: {{.Value}}{{end}}
+{{- with .Error}}
+Error: {{.}}{{end}}`
+
+// funcs are the functions templates can use besides the built-in ones
+var funcs = template.FuncMap{
+	// json quotes a value for templates writing JSON bodies, such as {{json .Error}}
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// Render executes the template text with the summary. name identifies the template in errors.
+func Render(name, text string, s Summary) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(funcs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", name, err)
+	}
+
+	// Durations are rounded so that templates needn't
+	s.Duration = s.Duration.Round(time.Second)
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, s); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", name, err)
+	}
+	return b.String(), nil
+}
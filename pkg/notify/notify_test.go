@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	s := Summary{
+		Command:   "create-refs",
+		StartedAt: time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC),
+		Duration:  90*time.Second + 400*time.Millisecond,
+		Counts:    []Count{{"Branches created", 2}, {"Branches failed", 1}},
+		Error:     "1 of 3 branches failed",
+		File:      "refs.csv",
+	}
+
+	subject, err := Render("subject", DefaultSubject, s)
+	if err != nil {
+		t.Fatalf("Render() unexpected error = %v", err)
+	}
+	if subject != "gh-gl-create-refs: create-refs failed" {
+		t.Errorf("subject = %q", subject)
+	}
+
+	body, err := Render("body", DefaultBody, s)
+	if err != nil {
+		t.Fatalf("Render() unexpected error = %v", err)
+	}
+	for _, want := range []string{"Started:  2024-01-31T23:59:59Z", "Duration: 1m30s", "Branches created: 2\nBranches failed: 1\n", "Results file: refs.csv", "Error: 1 of 3 branches failed"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body is missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestRender_Succeeded(t *testing.T) {
+	body, err := Render("body", "{{if .Succeeded}}all good{{else}}broken{{end}}", Summary{Command: "fetch-refs"})
+	if err != nil {
+		t.Fatalf("Render() unexpected error = %v", err)
+	}
+	if body != "all good" {
+		t.Errorf("Render() = %q", body)
+	}
+}
+
+func TestRender_Invalid(t *testing.T) {
+	if _, err := Render("body", "{{.Command", Summary{}); err == nil || !strings.Contains(err.Error(), "invalid body template") {
+		t.Errorf("Render() error = %v, want invalid template", err)
+	}
+	if _, err := Render("body", "{{.Branches}}", Summary{}); err == nil {
+		t.Error("Render() expected an error for an unknown field")
+	}
+}
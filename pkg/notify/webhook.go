@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Webhook formats
+const (
+	// FormatSlack posts the message as the text of a Slack incoming webhook
+	FormatSlack = "slack"
+	// FormatTeams posts the message as an Adaptive Card, as Microsoft Teams workflow
+	// webhooks expect
+	FormatTeams = "teams"
+	// FormatJSON posts the summary as JSON, or the rendered template as is when one is set
+	FormatJSON = "json"
+)
+
+// Webhook posts run summaries to an HTTP endpoint, such as a Slack or Microsoft Teams
+// incoming webhook
+type Webhook struct {
+	URL    string
+	Format string
+	// Message is a template of the message; DefaultMessage when empty, except for FormatJSON,
+	// which then posts the summary itself
+	Message   string
+	UserAgent string
+	// Client sends the requests; http.DefaultClient when nil
+	Client *http.Client
+}
+
+// Notify renders the summary and posts it
+func (w *Webhook) Notify(ctx context.Context, s Summary) error {
+	body, err := w.body(s)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid %s webhook URL: %w", w.Format, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.UserAgent != "" {
+		req.Header.Set("User-Agent", w.UserAgent)
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to %s webhook: %w", w.Format, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to post to %s webhook: %s: %s", w.Format, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// body builds the request body of the format
+func (w *Webhook) body(s Summary) ([]byte, error) {
+	if w.Format == FormatJSON {
+		if w.Message == "" {
+			return json.Marshal(s)
+		}
+		text, err := Render("message", w.Message, s)
+		return []byte(text), err
+	}
+
+	text, err := Render("message", cmp.Or(w.Message, DefaultMessage), s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch w.Format {
+	case FormatSlack:
+		return json.Marshal(map[string]string{"text": text})
+	case FormatTeams:
+		return json.Marshal(adaptiveCard(text))
+	default:
+		return nil, fmt.Errorf("unknown webhook format %q", w.Format)
+	}
+}
+
+// adaptiveCard wraps text in the message a Microsoft Teams workflow webhook posts
+func adaptiveCard(text string) map[string]any {
+	return map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    []map[string]any{{"type": "TextBlock", "text": text, "wrap": true}},
+			},
+		}},
+	}
+}
@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhook_Notify(t *testing.T) {
+	s := Summary{Command: "create-refs", Duration: 90 * time.Second, Counts: []Count{{"Branches created", 2}}, Error: "1 of 3 branches failed"}
+
+	tests := []struct {
+		name     string
+		webhook  Webhook
+		expected []string
+	}{
+		{"slack", Webhook{Format: FormatSlack}, []string{`"text":"❌ gh-gl-create-refs create-refs failed after 1m30s\n• Branches created: 2\nError: 1 of 3 branches failed"`}},
+		{"teams", Webhook{Format: FormatTeams}, []string{`"type":"AdaptiveCard"`, `"text":"❌ gh-gl-create-refs create-refs failed after 1m30s`}},
+		{"json summary", Webhook{Format: FormatJSON}, []string{`"command":"create-refs"`, `"status":"failed"`, `"duration_seconds":90`, `"counts":[{"label":"Branches created","value":2}]`}},
+		{"json template", Webhook{Format: FormatJSON, Message: `{"summary": {{json .Error}}}`}, []string{`{"summary": "1 of 3 branches failed"}`}},
+		{"custom slack message", Webhook{Format: FormatSlack, Message: "{{.Command}}: {{.Status}}"}, []string{`{"text":"create-refs: failed"}`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("request = %s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+				}
+				data, _ := io.ReadAll(r.Body)
+				body = string(data)
+			}))
+			defer server.Close()
+
+			tt.webhook.URL = server.URL
+			if err := tt.webhook.Notify(context.Background(), s); err != nil {
+				t.Fatalf("Notify() unexpected error = %v", err)
+			}
+			if tt.webhook.Format != FormatJSON || tt.webhook.Message == "" {
+				if !json.Valid([]byte(body)) {
+					t.Errorf("body is not JSON: %s", body)
+				}
+			}
+			for _, want := range tt.expected {
+				if !strings.Contains(body, want) {
+					t.Errorf("body is missing %s:\n%s", want, body)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhook_NotifyFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	w := Webhook{URL: server.URL, Format: FormatSlack}
+	err := w.Notify(context.Background(), Summary{Command: "fetch-refs"})
+	if err == nil || !strings.Contains(err.Error(), "403 Forbidden: invalid_token") {
+		t.Errorf("Notify() error = %v, want the status and response", err)
+	}
+}
@@ -0,0 +1,112 @@
+package output
+
+import (
+	"fmt"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// FormatCSV is the name of the CSV format, the format written by default
+const FormatCSV = "csv"
+
+func init() {
+	Register(FormatCSV, NewCSV)
+}
+
+// csvFormatter writes the two-column references CSV, and with AllVersions the matching versions file
+type csvFormatter struct {
+	options Options
+}
+
+// NewCSV creates the CSV formatter
+func NewCSV(options Options) (Formatter, error) {
+	if options.ChunkSize < 0 {
+		return nil, fmt.Errorf("chunk size must not be negative, got %d", options.ChunkSize)
+	}
+	return &csvFormatter{options: options}, nil
+}
+
+func (f *csvFormatter) Name() string {
+	return FormatCSV
+}
+
+func (f *csvFormatter) Extension() string {
+	return ".csv"
+}
+
+// NewWriter starts writing the references to filename, split into chunks when ChunkSize is set
+func (f *csvFormatter) NewWriter(filename string) (Writer, error) {
+	options := CSVOptions(f.options)
+
+	var refs Writer
+	var err error
+	if f.options.ChunkSize > 0 {
+		refs, err = csv.NewChunkedWriter(filename, f.options.ChunkSize, options...)
+	} else {
+		refs, err = csv.NewStreamWriter(filename, options...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CSV writer: %w", err)
+	}
+
+	w := &csvWriter{refs: refs}
+	if f.options.AllVersions {
+		w.versions, err = csv.NewVersionsStreamWriter(csv.VersionsFilename(filename), options...)
+		if err != nil {
+			refs.Abort()
+			return nil, fmt.Errorf("failed to create versions CSV writer: %w", err)
+		}
+	}
+
+	return w, nil
+}
+
+// CSVOptions converts options to the settings of the csv package, for CSV files written
+// outside a formatter such as batch summaries
+func CSVOptions(options Options) []csv.WriteOption {
+	var csvOptions []csv.WriteOption
+	if options.Delimiter != 0 {
+		csvOptions = append(csvOptions, csv.WithDelimiter(options.Delimiter))
+	}
+	if options.Excel {
+		csvOptions = append(csvOptions, csv.WithExcelCompatible())
+	}
+	return csvOptions
+}
+
+// csvWriter writes references and, when versions is set, their diff versions
+type csvWriter struct {
+	refs     Writer
+	versions *csv.StreamWriter
+}
+
+func (w *csvWriter) WriteRef(ref gitlab.MergeRequestRef) error {
+	if err := w.refs.WriteRef(ref); err != nil {
+		return fmt.Errorf("failed to write merge request %d to CSV: %w", ref.IID, err)
+	}
+	if w.versions != nil {
+		if err := w.versions.WriteVersions(ref); err != nil {
+			return fmt.Errorf("failed to write versions of merge request %d to CSV: %w", ref.IID, err)
+		}
+	}
+	return nil
+}
+
+func (w *csvWriter) Close() error {
+	if err := w.refs.Close(); err != nil {
+		w.Abort()
+		return err
+	}
+	if w.versions != nil {
+		return w.versions.Close()
+	}
+	return nil
+}
+
+func (w *csvWriter) Abort() {
+	w.refs.Abort()
+	if w.versions != nil {
+		w.versions.Abort()
+	}
+}
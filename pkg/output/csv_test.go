@@ -0,0 +1,115 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+func TestCSVFormatter(t *testing.T) {
+	ref := gitlab.MergeRequestRef{
+		IID:      1,
+		HeadSHA:  "new",
+		Versions: []gitlab.MergeRequestVersion{{HeadSHA: "old", BaseSHA: "base"}, {HeadSHA: "new", BaseSHA: "base"}},
+	}
+
+	tests := []struct {
+		name     string
+		options  Options
+		expected map[string]string
+	}{
+		{
+			name:    "references only",
+			options: Options{},
+			expected: map[string]string{
+				"refs.csv": "# gh-gl-create-refs refs v1\n1,new\n",
+			},
+		},
+		{
+			name:    "with versions and delimiter",
+			options: Options{AllVersions: true, Delimiter: ';'},
+			expected: map[string]string{
+				"refs.csv":          "# gh-gl-create-refs refs v1\n1;new\n",
+				"refs-versions.csv": "# gh-gl-create-refs versions v1\n1;1;old;base;\n1;2;new;base;\n",
+			},
+		},
+		{
+			name:    "chunked",
+			options: Options{ChunkSize: 1},
+			expected: map[string]string{
+				"refs-001.csv": "# gh-gl-create-refs refs v1\n1,new\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			formatter, err := NewCSV(tt.options)
+			if err != nil {
+				t.Fatalf("NewCSV() unexpected error = %v", err)
+			}
+
+			if err := WriteRefs(formatter, filepath.Join(dir, "refs.csv"), []gitlab.MergeRequestRef{ref}); err != nil {
+				t.Fatalf("WriteRefs() unexpected error = %v", err)
+			}
+
+			for name, want := range tt.expected {
+				content, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil || string(content) != want {
+					t.Errorf("%s = %q, %v; want %q", name, content, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCSVFormatter_Abort(t *testing.T) {
+	dir := t.TempDir()
+	formatter, err := NewCSV(Options{AllVersions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer, err := formatter.NewWriter(filepath.Join(dir, "refs.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteRef(gitlab.MergeRequestRef{IID: 1, HeadSHA: "aaa"}); err != nil {
+		t.Fatal(err)
+	}
+	writer.Abort()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no files after Abort, found %d", len(entries))
+	}
+}
+
+func TestCSVOptions(t *testing.T) {
+	if options := CSVOptions(Options{}); len(options) != 0 {
+		t.Errorf("CSVOptions() of the defaults = %d options, want none", len(options))
+	}
+
+	filename := filepath.Join(t.TempDir(), "refs.csv")
+	refs := []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaa"}}
+	if err := csv.WriteRefsToFile(refs, filename, CSVOptions(Options{Delimiter: '\t'})...); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil || string(content) != "# gh-gl-create-refs refs v1\n1\taaa\n" {
+		t.Errorf("File content = %q, %v", content, err)
+	}
+}
+
+func TestNewCSV_NegativeChunkSize(t *testing.T) {
+	if _, err := NewCSV(Options{ChunkSize: -1}); err == nil {
+		t.Error("NewCSV() expected an error for a negative chunk size")
+	}
+}
@@ -0,0 +1,102 @@
+// Package output writes merge request references in the formats supported by the commands.
+// Each format is a Formatter registered under a name, so commands select formats by name
+// instead of depending on a particular encoding package.
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// Writer receives the merge request references of one output. Nothing is visible at the
+// destination until Close succeeds; Abort discards the output and does nothing after Close.
+type Writer interface {
+	WriteRef(ref gitlab.MergeRequestRef) error
+	Close() error
+	Abort()
+}
+
+// Formatter creates writers for one output format
+type Formatter interface {
+	// Name identifies the format, e.g. "csv"
+	Name() string
+	// Extension is the file extension of outputs in this format, including the dot
+	Extension() string
+	// NewWriter starts writing an output to filename
+	NewWriter(filename string) (Writer, error)
+}
+
+// Options configures a formatter. Formats ignore settings that do not apply to them.
+type Options struct {
+	// Delimiter separates fields in delimited formats; zero selects the format's default
+	Delimiter rune
+	// Excel writes files that open cleanly in spreadsheet applications
+	Excel bool
+	// ChunkSize splits each output into numbered files of at most this many references when positive
+	ChunkSize int
+	// AllVersions also writes the diff versions of each merge request
+	AllVersions bool
+}
+
+// Factory creates a formatter configured by options
+type Factory func(options Options) (Formatter, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register makes a format available under name. Registering a name twice replaces the
+// earlier factory.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[name] = factory
+}
+
+// New creates the formatter registered under name
+func New(name string, options Options) (Formatter, error) {
+	mu.RLock()
+	factory, ok := factories[name]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported output format %q: use one of %s", name, strings.Join(Formats(), ", "))
+	}
+
+	return factory(options)
+}
+
+// Formats returns the names of the registered formats in alphabetical order
+func Formats() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WriteRefs writes refs to filename in the format of f
+func WriteRefs(f Formatter, filename string, refs []gitlab.MergeRequestRef) error {
+	writer, err := f.NewWriter(filename)
+	if err != nil {
+		return err
+	}
+	defer writer.Abort()
+
+	for _, ref := range refs {
+		if err := writer.WriteRef(ref); err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
@@ -0,0 +1,80 @@
+package output
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// memoryFormatter records written references for registry tests
+type memoryFormatter struct {
+	refs []gitlab.MergeRequestRef
+}
+
+func (f *memoryFormatter) Name() string      { return "memory" }
+func (f *memoryFormatter) Extension() string { return ".mem" }
+
+func (f *memoryFormatter) NewWriter(filename string) (Writer, error) {
+	return &memoryWriter{formatter: f}, nil
+}
+
+type memoryWriter struct {
+	formatter *memoryFormatter
+	pending   []gitlab.MergeRequestRef
+}
+
+func (w *memoryWriter) WriteRef(ref gitlab.MergeRequestRef) error {
+	w.pending = append(w.pending, ref)
+	return nil
+}
+
+func (w *memoryWriter) Close() error {
+	w.formatter.refs = w.pending
+	return nil
+}
+
+func (w *memoryWriter) Abort() {}
+
+func TestNew(t *testing.T) {
+	formatter, err := New(FormatCSV, Options{})
+	if err != nil {
+		t.Fatalf("New(csv) unexpected error = %v", err)
+	}
+	if formatter.Name() != FormatCSV || formatter.Extension() != ".csv" {
+		t.Errorf("New(csv) = %s (%s)", formatter.Name(), formatter.Extension())
+	}
+
+	_, err = New("xml", Options{})
+	if err == nil || !strings.Contains(err.Error(), "csv") {
+		t.Errorf("New(xml) error = %v, want an error listing the supported formats", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	memory := &memoryFormatter{}
+	Register("memory", func(Options) (Formatter, error) { return memory, nil })
+	t.Cleanup(func() {
+		mu.Lock()
+		delete(factories, "memory")
+		mu.Unlock()
+	})
+
+	if !slices.Contains(Formats(), "memory") {
+		t.Fatalf("Formats() = %v, want memory registered", Formats())
+	}
+
+	formatter, err := New("memory", Options{})
+	if err != nil {
+		t.Fatalf("New(memory) unexpected error = %v", err)
+	}
+
+	refs := []gitlab.MergeRequestRef{{IID: 1, HeadSHA: "aaa"}, {IID: 2, HeadSHA: "bbb"}}
+	if err := WriteRefs(formatter, "ignored", refs); err != nil {
+		t.Fatalf("WriteRefs() unexpected error = %v", err)
+	}
+	if len(memory.refs) != 2 || memory.refs[1].IID != 2 {
+		t.Errorf("WriteRefs() wrote %+v", memory.refs)
+	}
+}
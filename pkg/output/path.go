@@ -0,0 +1,85 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// PathData holds the fields available in output path templates
+type PathData struct {
+	// Repo is the repository path as a file name slug, e.g. group-subgroup-project
+	Repo string
+	// Project is the repository path, e.g. group/subgroup/project
+	Project string
+	// Date is the UTC date of the run, e.g. 2024-01-31
+	Date string
+	// Timestamp is the UTC time of the run, e.g. 20240131T235959Z
+	Timestamp string
+	// Format is the output format name, e.g. csv
+	Format string
+	// Ext is the file extension of the output format without the dot, e.g. csv
+	Ext string
+}
+
+// NewPathData describes the output of repository written by f in a run started at now
+func NewPathData(repository string, f Formatter, now time.Time) PathData {
+	project := repository
+	if _, projectPath, err := gitlab.ParseRepoPath(repository); err == nil {
+		project = projectPath
+	}
+
+	now = now.UTC()
+	return PathData{
+		Repo:      strings.ReplaceAll(project, "/", "-"),
+		Project:   project,
+		Date:      now.Format("2006-01-02"),
+		Timestamp: now.Format("20060102T150405Z"),
+		Format:    f.Name(),
+		Ext:       strings.TrimPrefix(f.Extension(), "."),
+	}
+}
+
+// IsTemplate reports whether path contains template actions such as {{.Repo}}
+func IsTemplate(path string) bool {
+	return strings.Contains(path, "{{")
+}
+
+// ParsePathTemplate checks that pattern is a valid output path template
+func ParsePathTemplate(pattern string) error {
+	_, err := ExpandPath(pattern, PathData{Repo: "group-project", Project: "group/project", Format: FormatCSV, Ext: "csv"})
+	return err
+}
+
+// ExpandPath fills in an output path template such as out/{{.Repo}}-{{.Date}}.csv
+func ExpandPath(pattern string, data PathData) (string, error) {
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid output path template %q: %w", pattern, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("invalid output path template %q: %w", pattern, err)
+	}
+	if buf.Len() == 0 {
+		return "", fmt.Errorf("output path template %q expands to an empty path", pattern)
+	}
+
+	return buf.String(), nil
+}
+
+// TemplateDir returns the directory of a template that does not depend on any field,
+// e.g. out for out/{{.Date}}/{{.Repo}}.csv, or . when the first element is templated
+func TemplateDir(pattern string) string {
+	static, _, _ := strings.Cut(pattern, "{{")
+	if i := strings.LastIndexAny(static, `/\`); i >= 0 {
+		return filepath.Clean(static[:i+1])
+	}
+	return "."
+}
@@ -0,0 +1,101 @@
+package output
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpandPath(t *testing.T) {
+	formatter, err := NewCSV(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		pattern     string
+		repository  string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:       "repo and date",
+			pattern:    "out/{{.Repo}}-{{.Date}}.csv",
+			repository: "group/subgroup/project",
+			expected:   "out/group-subgroup-project-2024-01-31.csv",
+		},
+		{
+			name:       "project directories and timestamp",
+			pattern:    "out/{{.Project}}/{{.Timestamp}}.{{.Ext}}",
+			repository: "https://gitlab.example.com/group/project",
+			expected:   "out/group/project/20240131T235959Z.csv",
+		},
+		{
+			name:       "format",
+			pattern:    "{{.Repo}}.{{.Format}}",
+			repository: "group/project",
+			expected:   "group-project.csv",
+		},
+		{
+			name:        "unknown field",
+			pattern:     "{{.Branch}}.csv",
+			repository:  "group/project",
+			expectError: true,
+		},
+		{
+			name:        "malformed template",
+			pattern:     "{{.Repo.csv",
+			repository:  "group/project",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := ExpandPath(tt.pattern, NewPathData(tt.repository, formatter, now))
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("ExpandPath() expected an error, got %q", path)
+				}
+				if ParsePathTemplate(tt.pattern) == nil {
+					t.Error("ParsePathTemplate() expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExpandPath() unexpected error = %v", err)
+			}
+			if path != tt.expected {
+				t.Errorf("ExpandPath() = %q, want %q", path, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTemplateDir(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{pattern: "out/{{.Repo}}-{{.Date}}.csv", expected: "out"},
+		{pattern: "out/runs/{{.Date}}/{{.Repo}}.csv", expected: "out/runs"},
+		{pattern: "{{.Date}}/{{.Repo}}.csv", expected: "."},
+		{pattern: "refs-{{.Repo}}.csv", expected: "."},
+	}
+
+	for _, tt := range tests {
+		if got := TemplateDir(tt.pattern); got != tt.expected {
+			t.Errorf("TemplateDir(%q) = %q, want %q", tt.pattern, got, tt.expected)
+		}
+	}
+}
+
+func TestIsTemplate(t *testing.T) {
+	if !IsTemplate("out/{{.Repo}}.csv") {
+		t.Error("IsTemplate() should detect template actions")
+	}
+	if IsTemplate("out/refs.csv") {
+		t.Error("IsTemplate() should not treat plain paths as templates")
+	}
+}
@@ -0,0 +1,133 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Storage receives finished output files at a remote location, such as an object storage
+// bucket. Outputs are written to local files first, so that they are complete and have
+// their checksum files, and then uploaded.
+type Storage interface {
+	// Upload copies the local file to name, a slash-separated path below the location
+	Upload(ctx context.Context, localPath, name string) error
+}
+
+// StorageFactory creates the storage of a location URL such as s3://bucket/prefix
+type StorageFactory func(location *url.URL) (Storage, error)
+
+var (
+	storageMu sync.RWMutex
+	storages  = make(map[string]StorageFactory)
+)
+
+func init() {
+	RegisterStorage("s3", newCommandStorage("aws", func(localPath, bucket, key string) []string {
+		return []string{"s3", "cp", "--only-show-errors", localPath, "s3://" + bucket + "/" + key}
+	}))
+	RegisterStorage("gs", newCommandStorage("gcloud", func(localPath, bucket, key string) []string {
+		return []string{"storage", "cp", "--quiet", localPath, "gs://" + bucket + "/" + key}
+	}))
+	// The storage account is the CLI's, usually from AZURE_STORAGE_ACCOUNT
+	RegisterStorage("azblob", newCommandStorage("az", func(localPath, container, name string) []string {
+		return []string{"storage", "blob", "upload", "--only-show-errors", "--overwrite", "--container-name", container, "--name", name, "--file", localPath}
+	}))
+}
+
+// RegisterStorage makes a storage available for locations with the URL scheme scheme.
+// Registering a scheme twice replaces the earlier factory.
+func RegisterStorage(scheme string, factory StorageFactory) {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+	storages[scheme] = factory
+}
+
+// IsRemote reports whether location is a URL of a registered storage, such as
+// s3://bucket/refs.csv, rather than a local path
+func IsRemote(location string) bool {
+	scheme, _, ok := strings.Cut(location, "://")
+	if !ok {
+		return false
+	}
+
+	storageMu.RLock()
+	defer storageMu.RUnlock()
+	_, ok = storages[scheme]
+	return ok
+}
+
+// NewStorage creates the storage of location, a URL such as s3://bucket/prefix
+func NewStorage(location string) (Storage, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage location %q: %w", location, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid storage location %q: missing bucket", location)
+	}
+
+	storageMu.RLock()
+	factory, ok := storages[u.Scheme]
+	storageMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported storage %q: use one of %s", u.Scheme, strings.Join(StorageSchemes(), ", "))
+	}
+	return factory(u)
+}
+
+// StorageSchemes returns the URL schemes of the registered storages in alphabetical order
+func StorageSchemes() []string {
+	storageMu.RLock()
+	defer storageMu.RUnlock()
+
+	schemes := make([]string, 0, len(storages))
+	for scheme := range storages {
+		schemes = append(schemes, scheme+"://")
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// commandStorage uploads with a cloud provider's command line tool, which brings the
+// provider's usual credential handling: environment variables, profiles and CI identities
+type commandStorage struct {
+	tool   string
+	bucket string
+	prefix string
+	args   func(localPath, bucket, key string) []string
+}
+
+// newCommandStorage returns a factory of storages uploading with tool, called with args
+func newCommandStorage(tool string, args func(localPath, bucket, key string) []string) StorageFactory {
+	return func(location *url.URL) (Storage, error) {
+		if _, err := exec.LookPath(tool); err != nil {
+			return nil, fmt.Errorf("cannot upload to %s://: %s is not installed", location.Scheme, tool)
+		}
+		return &commandStorage{
+			tool:   tool,
+			bucket: location.Host,
+			prefix: strings.Trim(location.Path, "/"),
+			args:   args,
+		}, nil
+	}
+}
+
+func (s *commandStorage) Upload(ctx context.Context, localPath, name string) error {
+	key := path.Join(s.prefix, name)
+
+	out, err := exec.CommandContext(ctx, s.tool, s.args(localPath, s.bucket, key)...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("failed to upload %s to %s/%s: %s", name, s.bucket, key, msg)
+		}
+		return fmt.Errorf("failed to upload %s to %s/%s: %w", name, s.bucket, key, err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package output
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsRemote(t *testing.T) {
+	tests := []struct {
+		location string
+		expected bool
+	}{
+		{"s3://bucket/refs.csv", true},
+		{"gs://bucket/out/", true},
+		{"azblob://container/refs.csv", true},
+		{"refs.csv", false},
+		{"out/{{.Repo}}.csv", false},
+		{"ftp://host/refs.csv", false},
+		{`C:\out\refs.csv`, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsRemote(tt.location); got != tt.expected {
+			t.Errorf("IsRemote(%q) = %v, want %v", tt.location, got, tt.expected)
+		}
+	}
+}
+
+func TestNewStorage_Invalid(t *testing.T) {
+	for _, location := range []string{"s3:///refs.csv", "ftp://host/refs.csv"} {
+		if _, err := NewStorage(location); err == nil {
+			t.Errorf("NewStorage(%q) expected an error", location)
+		}
+	}
+}
+
+// fakeTool puts an executable named tool first on PATH that records its arguments, one per
+// line, in the returned file
+func fakeTool(t *testing.T, tool string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	record := filepath.Join(dir, "args")
+	script := "#!/bin/sh\nfor arg in \"$@\"; do echo \"$arg\" >> " + record + "; done\n"
+	if err := os.WriteFile(filepath.Join(dir, tool), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return record
+}
+
+func TestCommandStorage_Upload(t *testing.T) {
+	tests := []struct {
+		location string
+		tool     string
+		expected string
+	}{
+		{"s3://bucket/migration/", "aws", "s3 cp --only-show-errors LOCAL s3://bucket/migration/out/refs.csv"},
+		{"gs://bucket", "gcloud", "storage cp --quiet LOCAL gs://bucket/out/refs.csv"},
+		{"azblob://container/migration", "az", "storage blob upload --only-show-errors --overwrite --container-name container --name migration/out/refs.csv --file LOCAL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			record := fakeTool(t, tt.tool)
+
+			storage, err := NewStorage(tt.location)
+			if err != nil {
+				t.Fatalf("NewStorage(%q) failed: %v", tt.location, err)
+			}
+			if err := storage.Upload(context.Background(), "LOCAL", "out/refs.csv"); err != nil {
+				t.Fatalf("Upload failed: %v", err)
+			}
+
+			args, err := os.ReadFile(record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(strings.Fields(string(args)), " "); got != tt.expected {
+				t.Errorf("%s called with %q, want %q", tt.tool, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/csv"
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// Version is the plan file format version written by this tool
+const Version = 1
+
+// ActionType describes what apply will do for a single branch
+type ActionType string
+
+const (
+	// ActionCreate creates a branch that does not exist yet
+	ActionCreate ActionType = "create"
+	// ActionUpdate moves an existing branch to a new SHA
+	ActionUpdate ActionType = "update"
+	// ActionSkip leaves a branch that already points at the expected SHA untouched
+	ActionSkip ActionType = "skip"
+	// ActionConflict is a reference whose branch name an earlier reference of the plan already
+	// takes, as --naming source-branch or short-sha can give; it fails as an existing branch
+	ActionConflict ActionType = "conflict"
+)
+
+// Action is a single planned branch operation
+type Action struct {
+	Type       ActionType `json:"type"`
+	Branch     string     `json:"branch"`
+	IID        int        `json:"iid"`
+	SHA        string     `json:"sha"`
+	CurrentSHA string     `json:"current_sha,omitempty"`
+}
+
+// Plan describes every branch operation to be executed against a target repository
+type Plan struct {
+	Version    int       `json:"version"`
+	CreatedAt  time.Time `json:"created_at"`
+	BaseURL    string    `json:"base_url,omitempty"`
+	Repository string    `json:"repository"`
+	Target     string    `json:"target"`
+	Actions    []Action  `json:"actions"`
+}
+
+// Summary holds per-type action counts for a plan
+type Summary struct {
+	Create   int
+	Update   int
+	Skip     int
+	Conflict int
+}
+
+// Build computes the actions needed to make the target branches match refs.
+// existing maps branch names to their current head SHA in the target repository.
+// As in create-refs, the first reference to get a branch name takes it and later ones
+// become conflicts.
+func Build(refs []gitlab.MergeRequestRef, existing map[string]string, branchName func(gitlab.MergeRequestRef) string) []Action {
+	actions := make([]Action, 0, len(refs))
+	taken := make(map[string]bool, len(refs))
+
+	for _, ref := range refs {
+		action := Action{
+			Branch: branchName(ref),
+			IID:    ref.IID,
+			SHA:    ref.HeadSHA,
+		}
+
+		currentSHA, exists := existing[action.Branch]
+		switch {
+		case taken[action.Branch]:
+			action.Type = ActionConflict
+		case !exists:
+			action.Type = ActionCreate
+		case currentSHA == ref.HeadSHA:
+			action.Type = ActionSkip
+			action.CurrentSHA = currentSHA
+		default:
+			action.Type = ActionUpdate
+			action.CurrentSHA = currentSHA
+		}
+
+		taken[action.Branch] = true
+		actions = append(actions, action)
+	}
+
+	return actions
+}
+
+// Summarize counts the actions in the plan by type
+func (p *Plan) Summarize() Summary {
+	var s Summary
+	for _, action := range p.Actions {
+		switch action.Type {
+		case ActionCreate:
+			s.Create++
+		case ActionUpdate:
+			s.Update++
+		case ActionSkip:
+			s.Skip++
+		case ActionConflict:
+			s.Conflict++
+		}
+	}
+	return s
+}
+
+// Validate checks that the plan can be applied by this version of the tool
+func (p *Plan) Validate() error {
+	if p.Version != Version {
+		return fmt.Errorf("unsupported plan version %d (expected %d)", p.Version, Version)
+	}
+
+	if p.Target == "" {
+		return fmt.Errorf("plan has no target repository")
+	}
+
+	for i, action := range p.Actions {
+		switch action.Type {
+		case ActionCreate, ActionUpdate, ActionSkip, ActionConflict:
+		default:
+			return fmt.Errorf("invalid action type %q at index %d", action.Type, i)
+		}
+
+		if action.Branch == "" || action.SHA == "" {
+			return fmt.Errorf("action at index %d is missing a branch or SHA", i)
+		}
+	}
+
+	return nil
+}
+
+// WriteFile writes the plan as indented JSON. Like the other outputs, the file is only put
+// in place, with its checksum file, once it is complete, so an existing plan is never left
+// half overwritten.
+func WriteFile(p *Plan, filename string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+
+	file, err := csv.CreateAtomic(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Abort()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write plan file %s: %w", filename, err)
+	}
+
+	return file.Commit()
+}
+
+// ReadFile reads and validates a plan file
+func ReadFile(filename string) (*Plan, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file %s: %w", filename, err)
+	}
+
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse plan file %s: %w", filename, err)
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid plan file %s: %w", filename, err)
+	}
+
+	return &p, nil
+}
@@ -0,0 +1,144 @@
+package plan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+func branchName(ref gitlab.MergeRequestRef) string {
+	return fmt.Sprintf("migration-pr-%d", ref.IID)
+}
+
+func TestBuild(t *testing.T) {
+	refs := []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: "aaa"},
+		{IID: 2, HeadSHA: "bbb"},
+		{IID: 3, HeadSHA: "ccc"},
+	}
+	existing := map[string]string{
+		"migration-pr-2": "bbb",
+		"migration-pr-3": "old",
+	}
+
+	actions := Build(refs, existing, branchName)
+
+	expected := []Action{
+		{Type: ActionCreate, Branch: "migration-pr-1", IID: 1, SHA: "aaa"},
+		{Type: ActionSkip, Branch: "migration-pr-2", IID: 2, SHA: "bbb", CurrentSHA: "bbb"},
+		{Type: ActionUpdate, Branch: "migration-pr-3", IID: 3, SHA: "ccc", CurrentSHA: "old"},
+	}
+
+	if len(actions) != len(expected) {
+		t.Fatalf("Expected %d actions, got %d", len(expected), len(actions))
+	}
+
+	for i, action := range actions {
+		if action != expected[i] {
+			t.Errorf("Action %d = %+v, want %+v", i, action, expected[i])
+		}
+	}
+
+	p := &Plan{Actions: actions}
+	if s := p.Summarize(); s != (Summary{Create: 1, Update: 1, Skip: 1}) {
+		t.Errorf("Summarize() = %+v, want 1 of each", s)
+	}
+}
+
+func TestBuild_NameCollisions(t *testing.T) {
+	refs := []gitlab.MergeRequestRef{
+		{IID: 1, HeadSHA: "aaa", SourceBranch: "feature"},
+		{IID: 2, HeadSHA: "bbb", SourceBranch: "feature"},
+		{IID: 3, HeadSHA: "ccc", SourceBranch: "fix"},
+	}
+	existing := map[string]string{"feature": "old"}
+	bySourceBranch := func(ref gitlab.MergeRequestRef) string { return ref.SourceBranch }
+
+	actions := Build(refs, existing, bySourceBranch)
+
+	// The first merge request takes the name; the second must not move the branch again
+	expected := []Action{
+		{Type: ActionUpdate, Branch: "feature", IID: 1, SHA: "aaa", CurrentSHA: "old"},
+		{Type: ActionConflict, Branch: "feature", IID: 2, SHA: "bbb"},
+		{Type: ActionCreate, Branch: "fix", IID: 3, SHA: "ccc"},
+	}
+	if fmt.Sprint(actions) != fmt.Sprint(expected) {
+		t.Errorf("Build() = %+v, want %+v", actions, expected)
+	}
+
+	p := &Plan{Actions: actions}
+	if s := p.Summarize(); s != (Summary{Create: 1, Update: 1, Conflict: 1}) {
+		t.Errorf("Summarize() = %+v", s)
+	}
+}
+
+func TestWriteReadFile(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "plan.json")
+
+	p := &Plan{
+		Version:    Version,
+		CreatedAt:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Repository: "group/project",
+		Target:     "group/project",
+		Actions: []Action{
+			{Type: ActionCreate, Branch: "migration-pr-1", IID: 1, SHA: "aaa"},
+		},
+	}
+
+	if err := WriteFile(p, testFile); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	got, err := ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+
+	if got.Target != p.Target || !got.CreatedAt.Equal(p.CreatedAt) || len(got.Actions) != 1 || got.Actions[0] != p.Actions[0] {
+		t.Errorf("ReadFile() = %+v, want %+v", got, p)
+	}
+
+	// The plan is put in place with its checksum, leaving no temporary file behind
+	entries, err := os.ReadDir(filepath.Dir(testFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if !slices.Equal(names, []string{"plan.json", "plan.json.sha256"}) {
+		t.Errorf("Files written = %v, want plan.json and plan.json.sha256", names)
+	}
+}
+
+func TestReadFile_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "not json", content: "not json"},
+		{name: "wrong version", content: `{"version": 99, "target": "g/p", "actions": []}`},
+		{name: "missing target", content: `{"version": 1, "actions": []}`},
+		{name: "unknown action", content: `{"version": 1, "target": "g/p", "actions": [{"type": "rename", "branch": "b", "sha": "s"}]}`},
+		{name: "missing sha", content: `{"version": 1, "target": "g/p", "actions": [{"type": "create", "branch": "b"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), "plan.json")
+			if err := os.WriteFile(testFile, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			if _, err := ReadFile(testFile); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Package projectexport reads merge request references from a GitLab project export: the
+// .tar.gz archive GitLab produces with "Export project", or the merge requests NDJSON file
+// inside it. No GitLab API access is needed, so references can still be produced for
+// projects whose instance is being decommissioned.
+package projectexport
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// MergeRequestsFile is where an export archive keeps its merge requests, one JSON object
+// per line
+const MergeRequestsFile = "tree/project/merge_requests.ndjson"
+
+var (
+	// ErrNoMergeRequests means an archive has no merge requests file
+	ErrNoMergeRequests = errors.New("no " + MergeRequestsFile + " in the export archive")
+	// ErrLegacyFormat means an archive was exported in the single project.json format of
+	// GitLab before 14.0, which is not supported
+	ErrLegacyFormat = errors.New("the export archive uses the legacy project.json format; export the project again from GitLab 14.0 or later")
+)
+
+// Result holds the references read from an export
+type Result struct {
+	// Refs are ordered by IID
+	Refs []gitlab.MergeRequestRef
+	// Skipped counts the merge requests without a head commit, such as those whose diff
+	// was never generated
+	Skipped int
+}
+
+// mergeRequest is the part of an exported merge request that references are made of
+type mergeRequest struct {
+	ID              int    `json:"id"`
+	IID             int    `json:"iid"`
+	SourceBranch    string `json:"source_branch"`
+	SourceProjectID int    `json:"source_project_id"`
+	TargetProjectID int    `json:"target_project_id"`
+	Diff            *struct {
+		HeadCommitSHA string `json:"head_commit_sha"`
+		Commits       []struct {
+			SHA           string `json:"sha"`
+			RelativeOrder int    `json:"relative_order"`
+		} `json:"merge_request_diff_commits"`
+	} `json:"merge_request_diff"`
+}
+
+// headSHA returns the head commit of the merge request's latest diff: the recorded head
+// commit, or else the newest of its commits, which exports list with relative order 0
+func (mr mergeRequest) headSHA() string {
+	if mr.Diff == nil {
+		return ""
+	}
+	if mr.Diff.HeadCommitSHA != "" {
+		return mr.Diff.HeadCommitSHA
+	}
+	for _, c := range mr.Diff.Commits {
+		if c.RelativeOrder == 0 {
+			return c.SHA
+		}
+	}
+	return ""
+}
+
+// ReadFile reads the references of the export archive or merge requests NDJSON file
+// filename, telling them apart by their content
+func ReadFile(filename string) (Result, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to open export: %w", err)
+	}
+	defer file.Close()
+
+	result, err := Read(file)
+	if err != nil {
+		return Result{}, fmt.Errorf("%s: %w", filename, err)
+	}
+	return result, nil
+}
+
+// Read reads the references of an export archive, compressed or not, or of a merge
+// requests NDJSON file from r
+func Read(r io.Reader) (Result, error) {
+	br := bufio.NewReader(r)
+
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return Result{}, fmt.Errorf("failed to decompress export: %w", err)
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	// Tar headers have the ustar magic at offset 257
+	if header, _ := br.Peek(262); len(header) == 262 && string(header[257:262]) == "ustar" {
+		return readArchive(tar.NewReader(br))
+	}
+	return ReadNDJSON(br)
+}
+
+// readArchive finds the merge requests file in an export archive and reads it
+func readArchive(tr *tar.Reader) (Result, error) {
+	legacy := false
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Result{}, fmt.Errorf("failed to read export archive: %w", err)
+		}
+
+		name := strings.TrimPrefix(path.Clean(header.Name), "./")
+		switch {
+		case name == MergeRequestsFile:
+			return ReadNDJSON(tr)
+		case name == "project.json":
+			legacy = true
+		}
+	}
+
+	if legacy {
+		return Result{}, ErrLegacyFormat
+	}
+	return Result{}, ErrNoMergeRequests
+}
+
+// ReadNDJSON reads the references of a merge requests NDJSON file from r. Lines hold whole
+// diffs and can be very long, so the objects are decoded from the stream rather than split
+// into lines first.
+func ReadNDJSON(r io.Reader) (Result, error) {
+	var result Result
+	seen := make(map[int]bool)
+
+	dec := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		var mr mergeRequest
+		err := dec.Decode(&mr)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Result{}, fmt.Errorf("invalid merge request on line %d: %w", n, err)
+		}
+		if mr.IID <= 0 {
+			return Result{}, fmt.Errorf("invalid merge request on line %d: no iid", n)
+		}
+		if seen[mr.IID] {
+			return Result{}, fmt.Errorf("merge request %d appears twice", mr.IID)
+		}
+		seen[mr.IID] = true
+
+		sha := mr.headSHA()
+		if sha == "" {
+			result.Skipped++
+			continue
+		}
+		result.Refs = append(result.Refs, gitlab.MergeRequestRef{
+			ID:           mr.ID,
+			IID:          mr.IID,
+			HeadSHA:      sha,
+			SourceBranch: mr.SourceBranch,
+			Fork:         mr.SourceProjectID != 0 && mr.TargetProjectID != 0 && mr.SourceProjectID != mr.TargetProjectID,
+		})
+	}
+
+	slices.SortFunc(result.Refs, func(a, b gitlab.MergeRequestRef) int { return a.IID - b.IID })
+	return result, nil
+}
@@ -0,0 +1,116 @@
+package projectexport
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/gitlab"
+)
+
+// mergeRequests is an excerpt of an export's merge requests, out of IID order as in
+// real exports, with a merge request from a fork and one without a diff
+const mergeRequests = `{"id":302,"iid":3,"title":"Fork fix","source_branch":"main","source_project_id":99,"target_project_id":7,"merge_request_diff":{"state":"collected","head_commit_sha":"cccccccccccccccccccccccccccccccccccccccc","merge_request_diff_files":[{"diff":"@@ -1 +1 @@\n-a\n+b\n"}]}}
+{"id":300,"iid":1,"source_branch":"feature/login","source_project_id":7,"target_project_id":7,"merge_request_diff":{"merge_request_diff_commits":[{"sha":"0000000000000000000000000000000000000000","relative_order":1},{"sha":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa","relative_order":0}]}}
+{"id":301,"iid":2,"source_branch":"empty","source_project_id":7,"target_project_id":7}
+`
+
+var expectedRefs = []gitlab.MergeRequestRef{
+	{ID: 300, IID: 1, HeadSHA: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", SourceBranch: "feature/login"},
+	{ID: 302, IID: 3, HeadSHA: "cccccccccccccccccccccccccccccccccccccccc", SourceBranch: "main", Fork: true},
+}
+
+// archive builds an export archive holding files
+func archive(t *testing.T, compress bool, files map[string]string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	var gz *gzip.Writer
+	tw := tar.NewWriter(&b)
+	if compress {
+		gz = gzip.NewWriter(&b)
+		tw = tar.NewWriter(gz)
+	}
+	for _, name := range []string{"VERSION", "project.json", "tree/project.json", MergeRequestsFile} {
+		content, ok := files[name]
+		if !ok {
+			continue
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: "./" + name, Mode: 0600, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	files := map[string]string{"VERSION": "0.2.4", "tree/project.json": "{}", MergeRequestsFile: mergeRequests}
+
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"compressed archive", archive(t, true, files)},
+		{"archive", archive(t, false, files)},
+		{"ndjson", []byte(mergeRequests)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Read(bytes.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Read() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(result.Refs, expectedRefs) {
+				t.Errorf("Read() refs = %+v, want %+v", result.Refs, expectedRefs)
+			}
+			if result.Skipped != 1 {
+				t.Errorf("Read() skipped = %d, want 1", result.Skipped)
+			}
+		})
+	}
+}
+
+func TestRead_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		expected error
+		message  string
+	}{
+		{"legacy archive", archive(t, true, map[string]string{"VERSION": "0.2.4", "project.json": "{}"}), ErrLegacyFormat, ""},
+		{"no merge requests", archive(t, true, map[string]string{"VERSION": "0.2.4"}), ErrNoMergeRequests, ""},
+		{"invalid json", []byte("{\"iid\":1}\nnot json\n"), nil, "invalid merge request on line 2"},
+		{"no iid", []byte("{\"id\":1}\n"), nil, "no iid"},
+		{"duplicate", []byte("{\"iid\":1}\n{\"iid\":1}\n"), nil, "merge request 1 appears twice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(bytes.NewReader(tt.input))
+			if err == nil {
+				t.Fatal("Read() expected an error")
+			}
+			if tt.expected != nil && !errors.Is(err, tt.expected) {
+				t.Errorf("Read() error = %v, want %v", err, tt.expected)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Read() error = %v, want it to contain %q", err, tt.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+// Package queue buffers work items in a file instead of memory, so that very large runs use
+// constant memory and work queued before a crash can be drained by the next run.
+package queue
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Queue is a first-in, first-out queue of items of type T stored as JSON lines in a file.
+// Items are removed from the queue as they are drained, or when acknowledged after being
+// read with Items, and the position of the first pending item is saved next to the file
+// each time, so a queue reopened after a crash resumes with the first item not yet
+// removed. It is not safe for concurrent use.
+type Queue[T any] struct {
+	path   string
+	file   *os.File
+	writer *bufio.Writer
+	// offset is where the first pending item starts in the file
+	offset int64
+	len    int
+	// unacked holds the sizes of the items read by Items and not acknowledged yet, oldest first
+	unacked []int64
+}
+
+// Open opens the queue stored at path, creating it when it does not exist. Items left
+// pending by an earlier run are kept.
+func Open[T any](path string) (*Queue[T], error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open queue %s: %w", path, err)
+	}
+
+	q := &Queue[T]{path: path, file: file, writer: bufio.NewWriter(file)}
+	if err := q.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+// load reads the saved offset and counts the pending items after it
+func (q *Queue[T]) load() error {
+	data, err := os.ReadFile(q.offsetPath())
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("failed to read queue offset: %w", err)
+	default:
+		q.offset, err = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid queue offset in %s: %w", q.offsetPath(), err)
+		}
+	}
+
+	end := q.offset
+	reader := bufio.NewReader(io.NewSectionReader(q.file, q.offset, 1<<62))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				// Drop a last line cut short by a crash while it was pushed, so new items
+				// start on a line of their own
+				if err := q.file.Truncate(end); err != nil {
+					return fmt.Errorf("failed to repair queue %s: %w", q.path, err)
+				}
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read queue %s: %w", q.path, err)
+		}
+		end += int64(len(line))
+		q.len++
+	}
+}
+
+// Path returns the file the queue is stored in
+func (q *Queue[T]) Path() string {
+	return q.path
+}
+
+// Len returns the number of pending items
+func (q *Queue[T]) Len() int {
+	return q.len
+}
+
+// Push adds item to the end of the queue
+func (q *Queue[T]) Push(item T) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("failed to encode queue item: %w", err)
+	}
+	if _, err := q.writer.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write queue %s: %w", q.path, err)
+	}
+	q.len++
+	return nil
+}
+
+// Drain returns an iterator over the pending items, oldest first. An item is removed from
+// the queue once the loop body has processed it and asks for the next one; an item whose
+// processing ends the loop, or is cut short by a crash, stays pending. A read error is
+// yielded once and ends the iteration.
+func (q *Queue[T]) Drain() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for item, err := range q.Items() {
+			if !yield(item, err) || err != nil {
+				return
+			}
+			if err := q.Ack(1); err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+		}
+	}
+}
+
+// Items returns an iterator over the pending items, oldest first, that leaves them in the
+// queue until they are acknowledged with Ack, for items processed in batches: a batch that
+// fails, or is cut short by a crash, stays pending. A read error is yielded once and ends
+// the iteration.
+func (q *Queue[T]) Items() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		if err := q.writer.Flush(); err != nil {
+			yield(zero, fmt.Errorf("failed to write queue %s: %w", q.path, err))
+			return
+		}
+
+		q.unacked = q.unacked[:0]
+		offset := q.offset
+		reader := bufio.NewReader(io.NewSectionReader(q.file, q.offset, 1<<62))
+		for {
+			line, err := reader.ReadBytes('\n')
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(zero, fmt.Errorf("failed to read queue %s: %w", q.path, err))
+				return
+			}
+
+			var item T
+			if err := json.Unmarshal(line, &item); err != nil {
+				yield(zero, fmt.Errorf("failed to decode queue item at offset %d of %s: %w", offset, q.path, err))
+				return
+			}
+			offset += int64(len(line))
+			q.unacked = append(q.unacked, int64(len(line)))
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Ack removes the n oldest items read with Items and not acknowledged yet from the queue
+func (q *Queue[T]) Ack(n int) error {
+	if n > len(q.unacked) {
+		return fmt.Errorf("failed to acknowledge %d items of queue %s: only %d were read", n, q.path, len(q.unacked))
+	}
+	for _, size := range q.unacked[:n] {
+		q.offset += size
+	}
+	q.unacked = q.unacked[n:]
+	q.len -= n
+	return q.saveOffset()
+}
+
+// saveOffset records the position of the first pending item
+func (q *Queue[T]) saveOffset() error {
+	if err := os.WriteFile(q.offsetPath(), []byte(strconv.FormatInt(q.offset, 10)), 0600); err != nil {
+		return fmt.Errorf("failed to save queue offset: %w", err)
+	}
+	return nil
+}
+
+// offsetPath is the file recording the position of the first pending item
+func (q *Queue[T]) offsetPath() string {
+	return q.path + ".offset"
+}
+
+// Close writes any buffered items and closes the queue, keeping its pending items
+func (q *Queue[T]) Close() error {
+	flushErr := q.writer.Flush()
+	if err := q.file.Close(); err != nil {
+		return fmt.Errorf("failed to close queue %s: %w", q.path, err)
+	}
+	if flushErr != nil {
+		return fmt.Errorf("failed to write queue %s: %w", q.path, flushErr)
+	}
+	return nil
+}
+
+// Remove closes the queue and deletes its files, discarding any pending items
+func (q *Queue[T]) Remove() error {
+	q.file.Close()
+	for _, path := range []string{q.path, q.offsetPath()} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove queue: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package queue
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+type item struct {
+	IID int    `json:"iid"`
+	SHA string `json:"sha"`
+}
+
+func drain(t *testing.T, q *Queue[item], limit int) []item {
+	t.Helper()
+	var got []item
+	for it, err := range q.Drain() {
+		if err != nil {
+			t.Fatalf("Drain() error = %v", err)
+		}
+		if len(got) == limit {
+			break
+		}
+		got = append(got, it)
+	}
+	return got
+}
+
+func TestQueue_DrainInOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "refs.queue")
+	q, err := Open[item](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	items := []item{{1, "aaa"}, {2, "bbb"}, {3, "ccc"}}
+	for _, it := range items {
+		if err := q.Push(it); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if q.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", q.Len())
+	}
+
+	if got := drain(t, q, -1); !slices.Equal(got, items) {
+		t.Errorf("Drain() = %v, want %v", got, items)
+	}
+	if q.Len() != 0 {
+		t.Errorf("Len() after draining = %d, want 0", q.Len())
+	}
+}
+
+func TestQueue_ResumesAfterReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "refs.queue")
+	q, err := Open[item](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, it := range []item{{1, "aaa"}, {2, "bbb"}, {3, "ccc"}} {
+		if err := q.Push(it); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The loop stops while processing the second item, which stays pending
+	if got := drain(t, q, 1); !slices.Equal(got, []item{{1, "aaa"}}) {
+		t.Fatalf("Drain() = %v", got)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := Open[item](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	if reopened.Len() != 2 {
+		t.Errorf("Len() after reopening = %d, want 2", reopened.Len())
+	}
+	if got := drain(t, reopened, -1); !slices.Equal(got, []item{{2, "bbb"}, {3, "ccc"}}) {
+		t.Errorf("Drain() after reopening = %v", got)
+	}
+}
+
+func TestQueue_ItemsStayUntilAcked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "refs.queue")
+	q, err := Open[item](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, it := range []item{{1, "aaa"}, {2, "bbb"}, {3, "ccc"}} {
+		if err := q.Push(it); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A batch of two is read and acknowledged, then the third is read but its batch fails
+	read := 0
+	for _, err := range q.Items() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		read++
+		if read == 2 {
+			if err := q.Ack(2); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if q.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", q.Len())
+	}
+	if err := q.Ack(2); err == nil {
+		t.Error("Ack() expected an error for more items than were read")
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := Open[item](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	if got := drain(t, reopened, -1); !slices.Equal(got, []item{{3, "ccc"}}) {
+		t.Errorf("Drain() after reopening = %v, want the unacknowledged item", got)
+	}
+}
+
+func TestQueue_DropsTruncatedItem(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "refs.queue")
+	if err := os.WriteFile(path, []byte("{\"iid\":1,\"sha\":\"aaa\"}\n{\"iid\":2,\"sh"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := Open[item](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	if q.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", q.Len())
+	}
+	if err := q.Push(item{3, "ccc"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := drain(t, q, -1); !slices.Equal(got, []item{{1, "aaa"}, {3, "ccc"}}) {
+		t.Errorf("Drain() = %v", got)
+	}
+}
+
+func TestQueue_Remove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "refs.queue")
+	q, err := Open[item](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Push(item{1, "aaa"}); err != nil {
+		t.Fatal(err)
+	}
+	drain(t, q, -1)
+
+	if err := q.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{path, path + ".offset"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s still exists after Remove()", p)
+		}
+	}
+}
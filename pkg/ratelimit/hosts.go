@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Hosts holds one token bucket per API host, so that every client talking to the same
+// instance shares its quota. It is safe for concurrent use.
+type Hosts struct {
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	buckets map[string]*TokenBucket
+}
+
+// NewHosts creates an empty set of per-host buckets logging to logger
+func NewHosts(logger *slog.Logger) *Hosts {
+	return &Hosts{logger: logger, buckets: make(map[string]*TokenBucket)}
+}
+
+// Bucket returns the bucket of host, creating it with rps and burst the first time the host
+// is seen. Host names are compared case-insensitively.
+func (h *Hosts) Bucket(host string, rps float64, burst int) *TokenBucket {
+	host = strings.ToLower(host)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if b, ok := h.buckets[host]; ok {
+		return b
+	}
+	b := NewTokenBucket(rps, burst, h.logger.With("host", host))
+	h.buckets[host] = b
+	return b
+}
+
+// States returns the current state of every host's bucket
+func (h *Hosts) States() map[string]State {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	states := make(map[string]State, len(h.buckets))
+	for host, b := range h.buckets {
+		states[host] = b.State()
+	}
+	return states
+}
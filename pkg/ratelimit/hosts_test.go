@@ -0,0 +1,37 @@
+package ratelimit
+
+import "testing"
+
+func TestHosts_Bucket(t *testing.T) {
+	h := NewHosts(quietLogger())
+
+	gitlab := h.Bucket("gitlab.com", 10, 2)
+	if gitlab.limiter.Limit() != 10 || gitlab.limiter.Burst() != 2 {
+		t.Errorf("Expected 10 rps burst 2, got %v burst %d", gitlab.limiter.Limit(), gitlab.limiter.Burst())
+	}
+
+	// The first settings of a host win, so every client shares one bucket
+	if got := h.Bucket("GitLab.com", 1, 1); got != gitlab {
+		t.Error("Bucket() returned a new bucket for a known host")
+	}
+	if got := h.Bucket("github.com", 0, 1); got == gitlab {
+		t.Error("Bucket() shared a bucket between hosts")
+	}
+}
+
+func TestHosts_States(t *testing.T) {
+	h := NewHosts(quietLogger())
+	h.Bucket("GitLab.com", 10, 2)
+	h.Bucket("github.com", 0, 1)
+
+	states := h.States()
+	if len(states) != 2 {
+		t.Fatalf("Expected 2 states, got %d", len(states))
+	}
+	if got := states["gitlab.com"].RequestsPerSecond; got != 10 {
+		t.Errorf("Expected gitlab.com at 10 rps, got %v", got)
+	}
+	if got := states["github.com"].Remaining; got != -1 {
+		t.Errorf("Expected no reported quota for github.com, got %d", got)
+	}
+}
@@ -0,0 +1,207 @@
+// Package ratelimit paces requests to rate limited APIs such as GitLab's and GitHub's. Token
+// buckets adapt to the rate limit headers of responses and pause after 429 responses, and
+// are shared per host so every client of an instance draws from the same quota.
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// retryAfterFallback is how long to pause after a 429 without a usable Retry-After header
+const retryAfterFallback = 60 * time.Second
+
+// minWait is the shortest Wait counted as having waited for the limiter
+const minWait = time.Millisecond
+
+// Limiter paces requests to an API. Implementations must be safe for concurrent use.
+type Limiter interface {
+	// Wait blocks until the next request may be sent or ctx is done
+	Wait(ctx context.Context) error
+	// Observe adjusts the limiter from a response's rate limit headers and status
+	Observe(resp *http.Response)
+}
+
+// TokenBucket is the default Limiter. It enforces a configured steady rate and burst,
+// slows down when the server reports few remaining requests, and pauses all callers after a
+// 429 response, a secondary rate limit 403 with Retry-After, or when the remaining quota
+// reaches zero.
+type TokenBucket struct {
+	limiter *rate.Limiter
+	base    rate.Limit
+	logger  *slog.Logger
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+	// remaining is the quota last reported by the server, -1 until one is reported
+	remaining int
+	waits     int
+	waitTime  time.Duration
+}
+
+// State describes how a bucket is pacing requests at a point in time
+type State struct {
+	// Remaining is the request quota the server last reported, or -1 if it never reported one
+	Remaining int
+	// RequestsPerSecond is the current client-side rate, lowered when the quota runs low;
+	// zero means no limit
+	RequestsPerSecond float64
+	// PausedUntil is in the future while requests wait for a rate limit reset
+	PausedUntil time.Time
+	// Waits is how many requests were delayed by the bucket, and WaitTime how long in total
+	Waits    int
+	WaitTime time.Duration
+}
+
+// NewTokenBucket creates a token bucket allowing rps requests per second with the given burst.
+// A non-positive rps means no client-side limit.
+func NewTokenBucket(rps float64, burst int, logger *slog.Logger) *TokenBucket {
+	limit := rate.Limit(rps)
+	if rps <= 0 {
+		limit = rate.Inf
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &TokenBucket{
+		limiter:   rate.NewLimiter(limit, burst),
+		base:      limit,
+		logger:    logger,
+		remaining: -1,
+	}
+}
+
+// Wait blocks until any server-requested pause is over and a token is available
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	start := time.Now()
+	defer func() {
+		if waited := time.Since(start); waited >= minWait {
+			b.mu.Lock()
+			b.waits++
+			b.waitTime += waited
+			b.mu.Unlock()
+		}
+	}()
+
+	b.mu.Lock()
+	pause := time.Until(b.pausedUntil)
+	b.mu.Unlock()
+
+	if pause > 0 {
+		b.logger.Debug("waiting for rate limit reset", "delay", pause.Round(time.Millisecond))
+		sleepContext(ctx, pause)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	return b.limiter.Wait(ctx)
+}
+
+// Observe adjusts the request rate from the rate limit headers of GitLab
+// (RateLimit-Remaining, RateLimit-Reset) and GitHub (X-RateLimit-Remaining, X-RateLimit-Reset)
+func (b *TokenBucket) Observe(resp *http.Response) {
+	if resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode == http.StatusForbidden && resp.Header.Get("Retry-After") != "" {
+		delay := retryAfterFallback
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			delay = time.Duration(seconds) * time.Second
+		}
+		b.logger.Warn("rate limit exceeded, pausing requests", "retry_after", delay)
+		b.pause(time.Now().Add(delay))
+		return
+	}
+
+	remaining, ok := headerInt(resp.Header, "RateLimit-Remaining", "X-RateLimit-Remaining")
+	if !ok {
+		return
+	}
+
+	b.mu.Lock()
+	b.remaining = remaining
+	b.mu.Unlock()
+
+	switch {
+	case remaining == 0:
+		if reset, ok := headerInt(resp.Header, "RateLimit-Reset", "X-RateLimit-Reset"); ok {
+			until := time.Unix(int64(reset), 0)
+			b.logger.Warn("rate limit exhausted, pausing until reset", "reset", until.Format(time.RFC3339))
+			b.pause(until)
+		} else {
+			b.slowDown(0.2, remaining)
+		}
+	case remaining <= 5:
+		b.slowDown(0.2, remaining)
+	case remaining <= 10:
+		b.slowDown(1, remaining)
+	default:
+		// Quota has recovered, return to the configured rate
+		if b.limiter.Limit() != b.base {
+			b.logger.Info("rate limit recovered, restoring request rate", "remaining", remaining)
+			b.limiter.SetLimit(b.base)
+		}
+	}
+}
+
+// State returns the bucket's current pacing
+func (b *TokenBucket) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := State{Remaining: b.remaining, PausedUntil: b.pausedUntil, Waits: b.waits, WaitTime: b.waitTime}
+	if limit := b.limiter.Limit(); limit != rate.Inf {
+		s.RequestsPerSecond = float64(limit)
+	}
+	return s
+}
+
+// slowDown lowers the request rate to at most rps
+func (b *TokenBucket) slowDown(rps float64, remaining int) {
+	limit := rate.Limit(rps)
+	if limit >= b.base || b.limiter.Limit() == limit {
+		return
+	}
+	b.logger.Warn("rate limit low, slowing down requests", "remaining", remaining, "rps", rps)
+	b.limiter.SetLimit(limit)
+}
+
+// pause stops all callers from sending requests until the given time
+func (b *TokenBucket) pause(until time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+}
+
+// sleepContext pauses for d or until ctx is done, whichever comes first
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// headerInt returns the first of the named headers that holds an integer
+func headerInt(header http.Header, names ...string) (int, bool) {
+	for _, name := range names {
+		if v, err := strconv.Atoi(header.Get(name)); err == nil {
+			return v, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func quietLogger() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+func responseWithHeaders(status int, headers map[string]string) *http.Response {
+	resp := &http.Response{StatusCode: status, Header: http.Header{}}
+	for k, v := range headers {
+		resp.Header.Set(k, v)
+	}
+	return resp
+}
+
+func TestNewTokenBucket(t *testing.T) {
+	if b := NewTokenBucket(25, 5, quietLogger()); b.limiter.Limit() != 25 || b.limiter.Burst() != 5 {
+		t.Errorf("Expected 25 rps burst 5, got %v burst %d", b.limiter.Limit(), b.limiter.Burst())
+	}
+
+	if b := NewTokenBucket(0, 0, quietLogger()); b.limiter.Limit() != rate.Inf || b.limiter.Burst() != 1 {
+		t.Errorf("Expected unlimited rate with burst 1, got %v burst %d", b.limiter.Limit(), b.limiter.Burst())
+	}
+}
+
+func TestTokenBucket_ObserveRemaining(t *testing.T) {
+	tests := []struct {
+		name      string
+		remaining string
+		expected  rate.Limit
+	}{
+		{name: "plenty remaining", remaining: "500", expected: 10},
+		{name: "low", remaining: "10", expected: 1},
+		{name: "critical", remaining: "5", expected: 0.2},
+		{name: "exhausted without reset", remaining: "0", expected: 0.2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewTokenBucket(10, 1, quietLogger())
+			b.Observe(responseWithHeaders(http.StatusOK, map[string]string{"RateLimit-Remaining": tt.remaining}))
+
+			if got := b.limiter.Limit(); got != tt.expected {
+				t.Errorf("Limit() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTokenBucket_RestoresRate(t *testing.T) {
+	b := NewTokenBucket(10, 1, quietLogger())
+
+	b.Observe(responseWithHeaders(http.StatusOK, map[string]string{"RateLimit-Remaining": "3"}))
+	b.Observe(responseWithHeaders(http.StatusOK, map[string]string{"X-RateLimit-Remaining": "1000"}))
+
+	if got := b.limiter.Limit(); got != 10 {
+		t.Errorf("Limit() = %v, want configured rate 10 after recovery", got)
+	}
+}
+
+func TestTokenBucket_DoesNotSpeedUpSlowConfig(t *testing.T) {
+	b := NewTokenBucket(0.5, 1, quietLogger())
+	b.Observe(responseWithHeaders(http.StatusOK, map[string]string{"RateLimit-Remaining": "10"}))
+
+	if got := b.limiter.Limit(); got != 0.5 {
+		t.Errorf("Limit() = %v, want configured rate 0.5", got)
+	}
+}
+
+func TestTokenBucket_PausesOnTooManyRequests(t *testing.T) {
+	b := NewTokenBucket(10, 1, quietLogger())
+	b.Observe(responseWithHeaders(http.StatusTooManyRequests, map[string]string{"Retry-After": "30"}))
+
+	if pause := time.Until(b.pausedUntil); pause < 29*time.Second || pause > 30*time.Second {
+		t.Errorf("Expected a 30s pause, got %v", pause)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := b.Wait(ctx); err == nil {
+		t.Error("Wait should fail when the context ends during a pause")
+	}
+}
+
+func TestTokenBucket_PausesUntilReset(t *testing.T) {
+	b := NewTokenBucket(10, 1, quietLogger())
+	reset := time.Now().Add(time.Minute).Unix()
+
+	b.Observe(responseWithHeaders(http.StatusOK, map[string]string{
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     strconv.FormatInt(reset, 10),
+	}))
+
+	if !b.pausedUntil.Equal(time.Unix(reset, 0)) {
+		t.Errorf("pausedUntil = %v, want %v", b.pausedUntil, time.Unix(reset, 0))
+	}
+}
+
+func TestSleepContext_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	sleepContext(ctx, time.Minute)
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("sleepContext should return immediately for a cancelled context, took %v", elapsed)
+	}
+}
+
+func TestTokenBucket_State(t *testing.T) {
+	b := NewTokenBucket(10, 1, quietLogger())
+	if s := b.State(); s.Remaining != -1 || s.RequestsPerSecond != 10 || !s.PausedUntil.IsZero() {
+		t.Errorf("State() of a new bucket = %+v, want unknown remaining at 10 rps", s)
+	}
+
+	b.Observe(responseWithHeaders(http.StatusOK, map[string]string{"RateLimit-Remaining": "8"}))
+	if s := b.State(); s.Remaining != 8 || s.RequestsPerSecond != 1 {
+		t.Errorf("State() after a low quota = %+v, want 8 remaining at 1 rps", s)
+	}
+
+	b.Observe(responseWithHeaders(http.StatusTooManyRequests, map[string]string{"Retry-After": "30"}))
+	if s := b.State(); !s.PausedUntil.After(time.Now()) {
+		t.Errorf("State() after 429 = %+v, want a pause", s)
+	}
+
+	if s := NewTokenBucket(0, 1, quietLogger()).State(); s.RequestsPerSecond != 0 {
+		t.Errorf("State() of an unlimited bucket = %+v, want 0 rps", s)
+	}
+}
+
+func TestTokenBucket_StateCountsWaits(t *testing.T) {
+	b := NewTokenBucket(50, 1, quietLogger())
+	ctx := context.Background()
+
+	// The first request uses the burst; the second waits about 20ms for a token
+	for range 2 {
+		if err := b.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := b.State()
+	if s.Waits != 1 || s.WaitTime < 10*time.Millisecond {
+		t.Errorf("State() = %+v, want 1 wait of about 20ms", s)
+	}
+}
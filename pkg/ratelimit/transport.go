@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"net/http"
+)
+
+// DefaultRetries is how many times Transport resends a request rejected with a rate limit
+const DefaultRetries = 3
+
+// Transport is an http.RoundTripper that waits for Limiter before every request and lets it
+// observe every response. Requests rejected with 429, or with 403 and Retry-After, are sent
+// again up to Retries times once the limiter's pause is over, when their body can be
+// replayed.
+type Transport struct {
+	// Base sends the requests; http.DefaultTransport when nil
+	Base    http.RoundTripper
+	Limiter Limiter
+	Retries int
+}
+
+// NewTransport wraps base, which may be nil, in a Transport using limiter and DefaultRetries
+func NewTransport(base http.RoundTripper, limiter Limiter) *Transport {
+	return &Transport{Base: base, Limiter: limiter, Retries: DefaultRetries}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := t.Limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+
+		resp, err := base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		t.Limiter.Observe(resp)
+
+		if !rejected(resp) || attempt >= t.Retries || (req.Body != nil && req.GetBody == nil) {
+			return resp, nil
+		}
+
+		// Send a fresh copy of the body once the pause is over
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return resp, nil
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		resp.Body.Close()
+	}
+}
+
+// rejected reports whether the server turned the request away because of a rate limit
+func rejected(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode == http.StatusForbidden && resp.Header.Get("Retry-After") != ""
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	tests := []struct {
+		name             string
+		responses        []int
+		retryAfter       string
+		expectedStatus   int
+		expectedRequests int
+	}{
+		{name: "success", responses: []int{http.StatusOK}, expectedStatus: http.StatusOK, expectedRequests: 1},
+		{name: "retried after 429", responses: []int{http.StatusTooManyRequests, http.StatusOK}, retryAfter: "0", expectedStatus: http.StatusOK, expectedRequests: 2},
+		{name: "retried after secondary rate limit", responses: []int{http.StatusForbidden, http.StatusOK}, retryAfter: "0", expectedStatus: http.StatusOK, expectedRequests: 2},
+		{name: "forbidden without Retry-After", responses: []int{http.StatusForbidden}, expectedStatus: http.StatusForbidden, expectedRequests: 1},
+		{
+			name:             "gives up after retries",
+			responses:        []int{429, 429, 429, 429, 429},
+			retryAfter:       "0",
+			expectedStatus:   http.StatusTooManyRequests,
+			expectedRequests: DefaultRetries + 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != "payload" {
+					t.Errorf("request %d body = %q, want payload", requests+1, body)
+				}
+				status := tt.responses[requests]
+				requests++
+				if status != http.StatusOK && tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: NewTransport(nil, NewTokenBucket(0, 1, quietLogger()))}
+			resp, err := client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+			if err != nil {
+				t.Fatalf("Post() unexpected error = %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.expectedStatus)
+			}
+			if requests != tt.expectedRequests {
+				t.Errorf("requests = %d, want %d", requests, tt.expectedRequests)
+			}
+		})
+	}
+}
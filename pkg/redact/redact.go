@@ -0,0 +1,97 @@
+// Package redact scrubs credentials from text before it is printed, logged or written to a
+// file. Tokens the run authenticates with are registered with Add and replaced wherever they
+// appear; credentials the run never saw, such as tokens in URLs echoed back by a server or
+// GitLab and GitHub tokens recognizable by their prefix, are found by pattern.
+package redact
+
+import (
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Placeholder replaces every credential found
+const Placeholder = "REDACTED"
+
+// minSecretLength keeps short values, which could be ordinary words or numbers in the
+// output, from being registered as secrets
+const minSecretLength = 8
+
+var (
+	mu      sync.RWMutex
+	secrets []string
+)
+
+// patterns match credentials by their shape; the text matched by their groups is kept
+var patterns = []*regexp.Regexp{
+	// Query parameters such as private_token, job_token, access_token and client_secret
+	regexp.MustCompile(`(?i)([?&][^=&\s"']*(?:token|secret|password|key|code)[^=&\s"']*=)[^&\s"'#]+`),
+	// Passwords in URLs
+	regexp.MustCompile(`(?i)(\b[a-z][a-z0-9+.-]*://[^:/@\s"']*:)[^@/\s"']+(@)`),
+	// Authentication headers, as in dumped requests
+	regexp.MustCompile(`(?i)((?:private-token|job-token|authorization)"?\s*[:=]\s*"?(?:(?:bearer|basic|token)\s+)?)[^\s",]+`),
+	// GitLab tokens, which start with a prefix naming their type
+	regexp.MustCompile(`()\b(?:glpat|gloas|gldt|glrt|glptt|glft|glimt|glagent|glcbt|glsoat|glffct)-[A-Za-z0-9_.-]{20,}`),
+	// GitHub tokens
+	regexp.MustCompile(`()\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`),
+}
+
+// Add registers secrets to replace in everything passed through String or a Writer. Empty
+// and very short values are ignored.
+func Add(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if len(value) < minSecretLength || slices.Contains(secrets, value) {
+			continue
+		}
+		secrets = append(secrets, value)
+	}
+}
+
+// Reset forgets the secrets registered with Add
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	secrets = nil
+}
+
+// String returns s with the registered secrets and anything that looks like a credential
+// replaced by Placeholder
+func String(s string) string {
+	mu.RLock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, Placeholder)
+	}
+	mu.RUnlock()
+
+	for _, pattern := range patterns {
+		s = pattern.ReplaceAllString(s, "${1}"+Placeholder+"${2}")
+	}
+	return s
+}
+
+// Writer redacts everything written to it before passing it on. Each Write is redacted on
+// its own, so a secret split across two writes is not found; log handlers and fmt write
+// whole lines.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter returns a Writer passing redacted output on to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write implements io.Writer. It reports len(p) as written when the redacted text is, since
+// callers check the count against what they passed in.
+func (w *Writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
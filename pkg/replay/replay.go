@@ -0,0 +1,318 @@
+// Package replay records the HTTP interactions of an API client to fixture files and
+// replays them, so tests can exercise a client's pagination, retry and error handling
+// against real responses without a server. Fixtures are sanitized as they are recorded:
+// request headers are left out, only the response headers clients act on are kept,
+// credentials are redacted and the recorded host is replaced with FixtureHost, so they
+// can be committed and shared.
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/redact"
+)
+
+// FixtureHost replaces the host of the recorded server in fixtures
+const FixtureHost = "gitlab.example.com"
+
+// ErrNoInteraction means a replayed request was not recorded, or was sent more often than
+// it was recorded
+var ErrNoInteraction = errors.New("no recorded interaction")
+
+// keptHeaders are the response headers recorded: those that drive pagination, rate
+// limiting and caching
+var keptHeaders = []string{
+	"Content-Type",
+	"Link",
+	"X-Page",
+	"X-Per-Page",
+	"X-Next-Page",
+	"X-Prev-Page",
+	"X-Total",
+	"X-Total-Pages",
+	"RateLimit-Limit",
+	"RateLimit-Remaining",
+	"RateLimit-Reset",
+	"Retry-After",
+	"ETag",
+	"Cache-Control",
+}
+
+// Fixture is the recorded interactions of a client, in the order they happened
+type Fixture struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one request and the response it received
+type Interaction struct {
+	Method string `json:"method"`
+	// URL is the request's path and query, with credentials redacted
+	URL      string   `json:"url"`
+	Body     string   `json:"body,omitempty"`
+	Response Response `json:"response"`
+}
+
+// Response is a recorded response
+type Response struct {
+	StatusCode int               `json:"status_code"`
+	Header     map[string]string `json:"header,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+// Load reads a fixture file
+func Load(filename string) (*Fixture, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", filename, err)
+	}
+	return &f, nil
+}
+
+// Save writes the fixture to filename
+func (f *Fixture) Save(filename string) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// Keep URLs and bodies readable in reviews
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(f); err != nil {
+		return fmt.Errorf("failed to encode fixture: %w", err)
+	}
+	if err := os.WriteFile(filename, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return nil
+}
+
+// requestKey is how a request is matched: its method, path and query with credentials
+// redacted and parameters sorted
+func requestKey(method string, u *url.URL) string {
+	query := u.Query()
+	for name := range query {
+		if sensitive(name) {
+			query.Set(name, redact.Placeholder)
+		}
+	}
+
+	key := u.EscapedPath()
+	if len(query) > 0 {
+		key += "?" + query.Encode()
+	}
+	return method + " " + key
+}
+
+// sensitive reports whether a query parameter carries a credential
+func sensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range []string{"token", "secret", "password", "key", "code"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// Recorder collects the interactions of every transport it wraps. It is safe for
+// concurrent use; concurrent requests are recorded in the order their responses arrive.
+type Recorder struct {
+	mu           sync.Mutex
+	fixture      Fixture
+	hosts        []string
+	interactions int
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Transport returns a RoundTripper that sends requests to next, or http.DefaultTransport
+// when it is nil, and records them
+func (r *Recorder) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &recordingTransport{recorder: r, next: next}
+}
+
+// Fixture returns the sanitized interactions recorded so far
+func (r *Recorder) Fixture() *Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f := Fixture{Interactions: make([]Interaction, len(r.fixture.Interactions))}
+	for i, interaction := range r.fixture.Interactions {
+		f.Interactions[i] = r.sanitize(interaction)
+	}
+	return &f
+}
+
+// Save writes the sanitized interactions recorded so far to filename
+func (r *Recorder) Save(filename string) error {
+	return r.Fixture().Save(filename)
+}
+
+// sanitize redacts credentials and replaces the recorded hosts in an interaction
+func (r *Recorder) sanitize(i Interaction) Interaction {
+	clean := func(s string) string {
+		s = redact.String(s)
+		for _, host := range r.hosts {
+			s = strings.ReplaceAll(s, host, FixtureHost)
+		}
+		return s
+	}
+
+	i.Body = clean(i.Body)
+	i.Response.Body = clean(i.Response.Body)
+	header := make(map[string]string, len(i.Response.Header))
+	for name, value := range i.Response.Header {
+		header[name] = clean(value)
+	}
+	i.Response.Header = header
+	return i
+}
+
+// recordingTransport records the requests it sends for a Recorder
+type recordingTransport struct {
+	recorder *Recorder
+	next     http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = body
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		// Transport failures have no response to replay
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := map[string]string{}
+	for _, name := range keptHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			header[name] = value
+		}
+	}
+
+	method, path, _ := strings.Cut(requestKey(req.Method, req.URL), " ")
+	t.recorder.add(req.URL.Host, Interaction{
+		Method:   method,
+		URL:      path,
+		Body:     string(reqBody),
+		Response: Response{StatusCode: resp.StatusCode, Header: header, Body: string(body)},
+	})
+	return resp, nil
+}
+
+// add records an interaction with the host it was sent to
+func (r *Recorder) add(host string, i Interaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if host != "" && !slices.Contains(r.hosts, host) {
+		r.hosts = append(r.hosts, host)
+	}
+	r.fixture.Interactions = append(r.fixture.Interactions, i)
+}
+
+// Replayer is an http.RoundTripper answering requests from a fixture instead of a server.
+// Requests are matched by method, path and query regardless of host; a request recorded
+// several times, such as a retry after a 429, receives its responses in recorded order.
+type Replayer struct {
+	mu      sync.Mutex
+	pending map[string][]Response
+}
+
+// NewReplayer creates a transport replaying f
+func NewReplayer(f *Fixture) *Replayer {
+	r := &Replayer{pending: map[string][]Response{}}
+	for _, i := range f.Interactions {
+		u, err := url.Parse(i.URL)
+		if err != nil {
+			continue
+		}
+		key := requestKey(i.Method, u)
+		r.pending[key] = append(r.pending[key], i.Response)
+	}
+	return r
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	key := requestKey(req.Method, req.URL)
+
+	r.mu.Lock()
+	responses := r.pending[key]
+	if len(responses) == 0 {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("%w for %s", ErrNoInteraction, key)
+	}
+	recorded := responses[0]
+	r.pending[key] = responses[1:]
+	r.mu.Unlock()
+
+	header := http.Header{}
+	for name, value := range recorded.Header {
+		header.Set(name, value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Remaining returns the number of recorded interactions not replayed yet, for tests to
+// check that every recorded request was made
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, responses := range r.pending {
+		n += len(responses)
+	}
+	return n
+}
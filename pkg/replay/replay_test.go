@@ -0,0 +1,94 @@
+package replay
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorder_Sanitizes(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "<"+server.URL+"/api/v4/projects?page=2>; rel=\"next\"")
+		w.Header().Set("X-Next-Page", "2")
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.Write([]byte(`[{"web_url": "` + server.URL + `/group/project"}]`))
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder()
+	client := &http.Client{Transport: recorder.Transport(nil)}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/v4/projects?private_token=s3cr3t-token&per_page=1", nil)
+	req.Header.Set("PRIVATE-TOKEN", "s3cr3t-token")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), server.URL) {
+		t.Errorf("Expected the caller to receive the original body, got %s", body)
+	}
+
+	interactions := recorder.Fixture().Interactions
+	if len(interactions) != 1 {
+		t.Fatalf("Recorded %d interactions, want 1", len(interactions))
+	}
+	i := interactions[0]
+	if i.URL != "/api/v4/projects?per_page=1&private_token=REDACTED" {
+		t.Errorf("URL = %q, want the token redacted", i.URL)
+	}
+	if _, ok := i.Response.Header["Set-Cookie"]; ok {
+		t.Error("Expected Set-Cookie to be left out")
+	}
+	if got := i.Response.Header["Link"]; got != "<http://"+FixtureHost+"/api/v4/projects?page=2>; rel=\"next\"" {
+		t.Errorf("Link = %q, want the host replaced", got)
+	}
+	if strings.Contains(i.Response.Body, "127.0.0.1") {
+		t.Errorf("Body = %q, want the host replaced", i.Response.Body)
+	}
+}
+
+func TestReplayer(t *testing.T) {
+	f := &Fixture{Interactions: []Interaction{
+		{Method: "GET", URL: "/api/v4/projects?per_page=1", Response: Response{StatusCode: 429, Header: map[string]string{"Retry-After": "0"}}},
+		{Method: "GET", URL: "/api/v4/projects?per_page=1", Response: Response{StatusCode: 200, Body: `[]`}},
+	}}
+
+	filename := filepath.Join(t.TempDir(), "fixture.json")
+	if err := f.Save(filename); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := Load(filename)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	replayer := NewReplayer(loaded)
+	client := &http.Client{Transport: replayer}
+
+	// Requests match whatever host they are sent to
+	for _, want := range []int{429, 200} {
+		resp, err := client.Get("https://gitlab.internal/api/v4/projects?per_page=1")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("StatusCode = %d, want %d", resp.StatusCode, want)
+		}
+	}
+	if n := replayer.Remaining(); n != 0 {
+		t.Errorf("Remaining() = %d, want 0", n)
+	}
+
+	_, err = client.Get("https://gitlab.internal/api/v4/projects?per_page=1")
+	if !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("Get() beyond the recording error = %v, want ErrNoInteraction", err)
+	}
+}
@@ -0,0 +1,118 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse_Invalid(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	}
+
+	for _, spec := range specs {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) expected error, got nil", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// 2024-03-15 is a Friday
+	base := time.Date(2024, 3, 15, 10, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		spec     string
+		from     time.Time
+		expected time.Time
+	}{
+		{
+			name:     "every minute",
+			spec:     "* * * * *",
+			from:     base,
+			expected: time.Date(2024, 3, 15, 10, 31, 0, 0, time.UTC),
+		},
+		{
+			name:     "daily at 02:00 rolls to next day",
+			spec:     "0 2 * * *",
+			from:     base,
+			expected: time.Date(2024, 3, 16, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "every 15 minutes",
+			spec:     "*/15 * * * *",
+			from:     base,
+			expected: time.Date(2024, 3, 15, 10, 45, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekdays only skips the weekend",
+			spec:     "0 9 * * 1-5",
+			from:     base,
+			expected: time.Date(2024, 3, 18, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "sunday as 7",
+			spec:     "0 0 * * 7",
+			from:     base,
+			expected: time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "first of the month rolls over the year",
+			spec:     "0 0 1 1 *",
+			from:     base,
+			expected: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "day of month or day of week",
+			spec:     "0 0 20 * 0",
+			from:     base,
+			expected: time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "exact match is strictly after",
+			spec:     "30 10 * * *",
+			from:     time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC),
+			expected: time.Date(2024, 3, 16, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "list of hours",
+			spec:     "0 8,12,18 * * *",
+			from:     base,
+			expected: time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.spec, err)
+			}
+
+			if got := s.Next(tt.from); !got.Equal(tt.expected) {
+				t.Errorf("Next(%v) = %v, want %v", tt.from, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNext_Impossible(t *testing.T) {
+	s, err := Parse("0 0 31 2 *")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if got := s.Next(time.Now()); !got.IsZero() {
+		t.Errorf("Next() = %v, want zero time for February 31st", got)
+	}
+}
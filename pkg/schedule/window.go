@@ -0,0 +1,73 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is a daily time window such as 22:00-06:00 during which work is allowed. A window
+// whose end is before its start spans midnight. Times are read in the location of the
+// times it is asked about, usually local time.
+type Window struct {
+	// start and end are minutes since midnight; end is excluded
+	start, end int
+}
+
+// ParseWindow parses a window written start-end with 24-hour HH:MM times, e.g. 22:00-06:00
+func ParseWindow(spec string) (*Window, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid window %q: expected start-end, e.g. 22:00-06:00", spec)
+	}
+
+	start, err := parseClock(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid window %q: %w", spec, err)
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid window %q: %w", spec, err)
+	}
+	if start == end {
+		return nil, fmt.Errorf("invalid window %q: start and end are the same", spec)
+	}
+
+	return &Window{start: start, end: end}, nil
+}
+
+// parseClock parses an HH:MM time into minutes since midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q: expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains reports whether t is inside the window
+func (w *Window) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return minute >= w.start && minute < w.end
+	}
+	return minute >= w.start || minute < w.end
+}
+
+// Next returns t when it is inside the window, or else the time the window next opens
+func (w *Window) Next(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+
+	open := time.Date(t.Year(), t.Month(), t.Day(), w.start/60, w.start%60, 0, 0, t.Location())
+	if open.Before(t) {
+		open = time.Date(t.Year(), t.Month(), t.Day()+1, w.start/60, w.start%60, 0, 0, t.Location())
+	}
+	return open
+}
+
+// String returns the window as written, e.g. 22:00-06:00
+func (w *Window) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.start/60, w.start%60, w.end/60, w.end%60)
+}
@@ -0,0 +1,56 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWindow_Invalid(t *testing.T) {
+	specs := []string{"", "22:00", "22:00-", "25:00-06:00", "22:00-06:60", "10pm-6am", "22:00-22:00"}
+
+	for _, spec := range specs {
+		if _, err := ParseWindow(spec); err == nil {
+			t.Errorf("ParseWindow(%q) expected error, got nil", spec)
+		}
+	}
+}
+
+func TestWindow(t *testing.T) {
+	day := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 15, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		spec     string
+		at       time.Time
+		contains bool
+		next     time.Time
+	}{
+		{"overnight, late evening", "22:00-06:00", day(23, 30), true, day(23, 30)},
+		{"overnight, early morning", "22:00-06:00", day(5, 59), true, day(5, 59)},
+		{"overnight, end excluded", "22:00-06:00", day(6, 0), false, day(22, 0)},
+		{"overnight, afternoon", "22:00-06:00", day(14, 0), false, day(22, 0)},
+		{"daytime, inside", "09:00-17:30", day(12, 0), true, day(12, 0)},
+		{"daytime, before", "09:00-17:30", day(8, 0), false, day(9, 0)},
+		{"daytime, after", "09:00-17:30", day(18, 0), false, time.Date(2024, 3, 16, 9, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := ParseWindow(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseWindow(%q) failed: %v", tt.spec, err)
+			}
+			if got := w.Contains(tt.at); got != tt.contains {
+				t.Errorf("Contains(%v) = %v, want %v", tt.at, got, tt.contains)
+			}
+			if got := w.Next(tt.at); !got.Equal(tt.next) {
+				t.Errorf("Next(%v) = %v, want %v", tt.at, got, tt.next)
+			}
+			if w.String() != tt.spec {
+				t.Errorf("String() = %q, want %q", w.String(), tt.spec)
+			}
+		})
+	}
+}
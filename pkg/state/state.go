@@ -0,0 +1,130 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultPath is the state file used when none is specified
+const DefaultPath = ".gh-gl-create-refs-state.json"
+
+// maxRuns caps how many run records are kept in the state file
+const maxRuns = 500
+
+// RepositoryState records the last successful fetch of a repository
+type RepositoryState struct {
+	LastFetchedAt time.Time `json:"last_fetched_at"`
+	Refs          int       `json:"refs"`
+}
+
+// Run records the outcome of one fetch or sync run
+type Run struct {
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	Mode         string    `json:"mode"`
+	Repositories int       `json:"repositories"`
+	Refs         int       `json:"refs"`
+	Failed       int       `json:"failed"`
+	Error        string    `json:"error,omitempty"`
+}
+
+type data struct {
+	Repositories map[string]RepositoryState `json:"repositories"`
+	Runs         []Run                      `json:"runs"`
+}
+
+// Store persists per-repository fetch progress and a run log in a JSON file.
+// It is safe for concurrent use.
+type Store struct {
+	path string
+	mu   sync.Mutex
+	data data
+}
+
+// Open loads the state file at path, starting empty if it does not exist yet
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path: path,
+		data: data{Repositories: make(map[string]RepositoryState)},
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(content, &s.data); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	if s.data.Repositories == nil {
+		s.data.Repositories = make(map[string]RepositoryState)
+	}
+
+	return s, nil
+}
+
+// Repository returns the recorded state for a repository and whether one exists
+func (s *Store) Repository(repository string) (RepositoryState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rs, ok := s.data.Repositories[repository]
+	return rs, ok
+}
+
+// RecordFetch stores the time a repository fetch started and how many refs it now has
+func (s *Store) RecordFetch(repository string, fetchedAt time.Time, refs int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Repositories[repository] = RepositoryState{LastFetchedAt: fetchedAt, Refs: refs}
+}
+
+// RecordRun appends a run to the log, dropping the oldest entries beyond the cap
+func (s *Store) RecordRun(run Run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Runs = append(s.data.Runs, run)
+	if len(s.data.Runs) > maxRuns {
+		s.data.Runs = s.data.Runs[len(s.data.Runs)-maxRuns:]
+	}
+}
+
+// Runs returns a copy of the run log, oldest first
+func (s *Store) Runs() []Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Run(nil), s.data.Runs...)
+}
+
+// Save writes the state file, replacing it atomically
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write state file %s: %w", s.path, err)
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write state file %s: %w", s.path, err)
+	}
+
+	return nil
+}
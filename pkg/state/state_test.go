@@ -0,0 +1,74 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	if _, ok := s.Repository("group/project"); ok {
+		t.Fatal("Expected no state for a new store")
+	}
+
+	fetchedAt := time.Date(2024, 3, 15, 2, 0, 0, 0, time.UTC)
+	s.RecordFetch("group/project", fetchedAt, 42)
+	s.RecordRun(Run{StartedAt: fetchedAt, FinishedAt: fetchedAt.Add(time.Minute), Mode: "incremental", Repositories: 1, Refs: 3})
+
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	rs, ok := reopened.Repository("group/project")
+	if !ok || !rs.LastFetchedAt.Equal(fetchedAt) || rs.Refs != 42 {
+		t.Errorf("Repository() = %+v, %v", rs, ok)
+	}
+
+	runs := reopened.Runs()
+	if len(runs) != 1 || runs[0].Mode != "incremental" || runs[0].Refs != 3 {
+		t.Errorf("Runs() = %+v", runs)
+	}
+}
+
+func TestStore_RunsAreCapped(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	for i := 0; i < maxRuns+10; i++ {
+		s.RecordRun(Run{Refs: i})
+	}
+
+	runs := s.Runs()
+	if len(runs) != maxRuns {
+		t.Fatalf("Expected %d runs, got %d", maxRuns, len(runs))
+	}
+	if runs[0].Refs != 10 {
+		t.Errorf("Expected oldest runs to be dropped, first run has Refs=%d", runs[0].Refs)
+	}
+}
+
+func TestOpen_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	if _, err := Open(path); err == nil {
+		t.Error("Expected error for invalid state file, got nil")
+	}
+}
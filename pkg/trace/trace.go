@@ -0,0 +1,139 @@
+// Package trace logs the HTTP requests sent to the GitLab and GitHub APIs, to diagnose
+// proxy, TLS and permission problems without an intercepting proxy. Credentials are never
+// logged: request headers are left out entirely and token parameters are redacted from URLs.
+package trace
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// redacted replaces secrets in logged URLs
+const redacted = "REDACTED"
+
+// rateLimitHeaders are the response headers logged with every request, as sent by GitLab
+// (RateLimit-*) and GitHub (X-RateLimit-*)
+var rateLimitHeaders = []string{
+	"RateLimit-Limit",
+	"RateLimit-Remaining",
+	"RateLimit-Reset",
+	"X-RateLimit-Limit",
+	"X-RateLimit-Remaining",
+	"X-RateLimit-Reset",
+	"X-RateLimit-Resource",
+	"Retry-After",
+}
+
+// requestIDHeaders identify a request in the server's logs, for support tickets
+var requestIDHeaders = []string{"X-Request-Id", "X-GitHub-Request-Id"}
+
+// Transport is an http.RoundTripper that logs every request at debug level: the method, the
+// URL, the proxy it goes through, the response status, the TLS connection, the rate limit
+// headers and how long it took
+type Transport struct {
+	// Base sends the requests; http.DefaultTransport when nil
+	Base   http.RoundTripper
+	Logger *slog.Logger
+}
+
+// NewTransport wraps base, which may be nil, in a Transport logging to logger
+func NewTransport(base http.RoundTripper, logger *slog.Logger) *Transport {
+	return &Transport{Base: base, Logger: logger}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	attrs := []any{"method", req.Method, "url", RedactURL(req.URL)}
+	if proxy := proxyURL(base, req); proxy != nil {
+		attrs = append(attrs, "proxy", RedactURL(proxy))
+	}
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	attrs = append(attrs, "duration", time.Since(start).Round(time.Millisecond))
+	if err != nil {
+		t.Logger.Debug("http request failed", append(attrs, "error", err)...)
+		return resp, err
+	}
+
+	attrs = append(attrs, "status", resp.StatusCode)
+	if resp.TLS != nil {
+		attrs = append(attrs, tlsAttrs(resp.TLS)...)
+	}
+	for _, header := range append(requestIDHeaders, rateLimitHeaders...) {
+		if value := resp.Header.Get(header); value != "" {
+			attrs = append(attrs, strings.ToLower(header), value)
+		}
+	}
+	t.Logger.Debug("http request", attrs...)
+	return resp, nil
+}
+
+// RedactURL returns u without a password and with the values of query parameters that may
+// carry credentials, such as private_token and access_token, replaced
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	clean := *u
+	if _, ok := clean.User.Password(); ok {
+		clean.User = url.UserPassword(clean.User.Username(), redacted)
+	}
+
+	if clean.RawQuery != "" {
+		query := clean.Query()
+		for name := range query {
+			if sensitive(name) {
+				query.Set(name, redacted)
+			}
+		}
+		clean.RawQuery = query.Encode()
+	}
+	return clean.String()
+}
+
+// sensitive reports whether a query parameter name looks like it holds a credential
+func sensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range []string{"token", "secret", "password", "key", "code"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyURL returns the proxy base sends req through, or nil when it connects directly or
+// the proxy is unknown
+func proxyURL(base http.RoundTripper, req *http.Request) *url.URL {
+	transport, ok := base.(*http.Transport)
+	if !ok || transport.Proxy == nil {
+		return nil
+	}
+	proxy, err := transport.Proxy(req)
+	if err != nil {
+		return nil
+	}
+	return proxy
+}
+
+// tlsAttrs describes the TLS connection of a response: the protocol version and the
+// certificate the server presented
+func tlsAttrs(state *tls.ConnectionState) []any {
+	attrs := []any{"tls_version", tls.VersionName(state.Version)}
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		attrs = append(attrs, "tls_subject", cert.Subject.String(), "tls_issuer", cert.Issuer.String())
+	}
+	return attrs
+}
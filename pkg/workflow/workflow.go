@@ -0,0 +1,291 @@
+// Package workflow generates GitHub Actions workflows that run gh-gl-create-refs on a schedule
+package workflow
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/amenocal/gh-gl-create-refs/pkg/schedule"
+	"github.com/amenocal/gh-gl-create-refs/pkg/state"
+)
+
+const (
+	// DefaultName is the workflow name used when none is given
+	DefaultName = "Sync GitLab merge request refs"
+	// DefaultSchedule runs the workflow daily at 02:00 UTC
+	DefaultSchedule = "0 2 * * *"
+	// DefaultGitLabTokenSecret is the repository secret holding the GitLab token
+	DefaultGitLabTokenSecret = "GITLAB_TOKEN"
+
+	// refsFile is the file the workflow fetches references into
+	refsFile = "refs.csv"
+)
+
+// secretName matches valid GitHub Actions secret names
+var secretName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Options selects what the generated workflow runs
+type Options struct {
+	// Name is the workflow name
+	Name string
+	// Schedule is the five-field cron expression the workflow runs on, in UTC
+	Schedule string
+
+	// Repository, Group and Manifest select what is fetched; exactly one is set
+	Repository string
+	Group      string
+	Manifest   string
+	// BaseURL is the GitLab instance, empty for gitlab.com
+	BaseURL string
+	// Incremental only fetches merge requests updated since the previous run, keeping
+	// the state file and references between runs in the Actions cache
+	Incremental bool
+
+	// Target creates or updates the branches in this GitLab repository after the fetch
+	Target string
+	// GitHubTarget creates the branches in this GitHub owner/name repository instead
+	GitHubTarget string
+	// Mapping creates the branches of each project in its GitHub repository, as listed
+	// in this file of the workflow's repository
+	Mapping string
+	// Verify validates the fetched file and checks its checksum before creating branches
+	Verify bool
+
+	// GitLabTokenSecret is the repository secret holding the GitLab token
+	GitLabTokenSecret string
+	// GitHubTokenSecret is the repository secret holding a GitHub token able to create
+	// the branches; the workflow's own token is used when empty
+	GitHubTokenSecret string
+}
+
+// sync reports whether the workflow creates branches after fetching
+func (o Options) sync() bool {
+	return o.Target != "" || o.GitHubTarget != "" || o.Mapping != ""
+}
+
+// Validate checks that the options describe a workflow that can run
+func (o Options) Validate() error {
+	sources := 0
+	for _, s := range []string{o.Repository, o.Group, o.Manifest} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("exactly one of repository, group or manifest must be given")
+	}
+
+	if _, err := schedule.Parse(o.Schedule); err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+
+	targets := 0
+	for _, s := range []string{o.Target, o.GitHubTarget, o.Mapping} {
+		if s != "" {
+			targets++
+		}
+	}
+	if targets > 1 {
+		return fmt.Errorf("only one of target, GitHub target or mapping can be given")
+	}
+
+	// Several projects are fetched into one combined file, which a single GitLab or
+	// GitHub target cannot take
+	if o.Repository == "" {
+		if o.Target != "" || o.GitHubTarget != "" {
+			return fmt.Errorf("a target requires a single repository; use a mapping for groups and manifests")
+		}
+		if o.Incremental {
+			return fmt.Errorf("incremental fetches require a single repository")
+		}
+	}
+
+	if o.Verify && !o.sync() {
+		return fmt.Errorf("verify requires a target, GitHub target or mapping")
+	}
+
+	if !secretName.MatchString(o.GitLabTokenSecret) {
+		return fmt.Errorf("invalid GitLab token secret name %q", o.GitLabTokenSecret)
+	}
+	if o.GitHubTokenSecret != "" && !secretName.MatchString(o.GitHubTokenSecret) {
+		return fmt.Errorf("invalid GitHub token secret name %q", o.GitHubTokenSecret)
+	}
+
+	return nil
+}
+
+// fetchCommand is the fetch-refs invocation of the workflow
+func (o Options) fetchCommand() string {
+	args := []string{"gh", "gl-create-refs", "fetch-refs"}
+	switch {
+	case o.Repository != "":
+		args = append(args, "--repository", o.Repository)
+	case o.Group != "":
+		args = append(args, "--group", o.Group, "--combined")
+	default:
+		args = append(args, "--manifest", o.Manifest, "--combined")
+	}
+	if o.BaseURL != "" {
+		args = append(args, "--base-url", o.BaseURL)
+	}
+	if o.Incremental {
+		args = append(args, "--incremental")
+	}
+	args = append(args, "--output", refsFile)
+	return shellJoin(args)
+}
+
+// createCommand is the create-refs invocation of the workflow. Every scheduled run finds
+// the branches of the previous ones, so existing branches are updated rather than failing.
+func (o Options) createCommand() string {
+	args := []string{"gh", "gl-create-refs", "create-refs", "--input", refsFile, "--update-existing"}
+	if o.Repository != "" {
+		args = append(args, "--repository", o.Repository)
+	}
+	if o.BaseURL != "" {
+		args = append(args, "--base-url", o.BaseURL)
+	}
+	switch {
+	case o.Target != "":
+		args = append(args, "--target", o.Target)
+	case o.GitHubTarget != "":
+		args = append(args, "--github-target", o.GitHubTarget)
+	case o.Mapping != "":
+		args = append(args, "--mapping", o.Mapping)
+	}
+	if o.Verify {
+		args = append(args, "--verify-checksum")
+	}
+	return shellJoin(args)
+}
+
+// validateCommand is the validate invocation of the workflow
+func (o Options) validateCommand() string {
+	return shellJoin([]string{"gh", "gl-create-refs", "validate", "--input", refsFile})
+}
+
+// safeWord matches arguments that need no shell quoting
+var safeWord = regexp.MustCompile(`^[A-Za-z0-9_./:=@%+,-]+$`)
+
+// shellJoin joins args into a shell command, quoting those that need it
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if safeWord.MatchString(arg) {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// yamlQuote returns s as a single-quoted YAML scalar
+func yamlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+var workflowTemplate = template.Must(template.New("workflow").Funcs(template.FuncMap{"quote": yamlQuote}).Parse(`# Generated by gh gl-create-refs generate-workflow
+name: {{quote .Name}}
+
+on:
+  schedule:
+    - cron: {{quote .Schedule}}
+  workflow_dispatch:
+
+permissions:
+  contents: read
+
+# Runs never overlap, so incremental state is not lost
+concurrency:
+  group: gh-gl-create-refs
+  cancel-in-progress: false
+
+jobs:
+  sync:
+    runs-on: ubuntu-latest
+    env:
+      GITLAB_TOKEN: ${{"{{"}} secrets.{{.GitLabTokenSecret}} {{"}}"}}
+{{- if .GitHubTokenSecret}}
+      GH_TOKEN: ${{"{{"}} secrets.{{.GitHubTokenSecret}} {{"}}"}}
+{{- else}}
+      GH_TOKEN: ${{"{{"}} github.token {{"}}"}}
+{{- end}}
+    steps:
+      - uses: actions/checkout@v4
+
+      - name: Install gh-gl-create-refs
+        run: gh extension install amenocal/gh-gl-create-refs
+{{- if .Incremental}}
+
+      - name: Restore fetch state
+        uses: actions/cache@v4
+        with:
+          path: |
+            {{.StateFile}}
+            {{.RefsFile}}
+            {{.RefsFile}}.sha256
+          key: gh-gl-create-refs-${{"{{"}} github.run_id {{"}}"}}
+          restore-keys: gh-gl-create-refs-
+{{- end}}
+
+      - name: Fetch merge request references
+        run: |
+          {{.FetchCommand}}
+{{- if .Verify}}
+
+      - name: Validate references
+        run: |
+          {{.ValidateCommand}}
+{{- end}}
+{{- if .Sync}}
+
+      - name: Create branches
+        run: |
+          {{.CreateCommand}}
+{{- end}}
+
+      - name: Upload references
+        if: always()
+        uses: actions/upload-artifact@v4
+        with:
+          name: merge-request-refs
+          path: |
+            {{.RefsFile}}
+            {{.RefsFile}}.sha256
+            fetch-summary.csv
+          if-no-files-found: ignore
+`))
+
+// Generate writes the workflow described by o
+func Generate(w io.Writer, o Options) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	data := struct {
+		Options
+		StateFile       string
+		RefsFile        string
+		FetchCommand    string
+		ValidateCommand string
+		CreateCommand   string
+		Sync            bool
+	}{
+		Options:         o,
+		StateFile:       state.DefaultPath,
+		RefsFile:        refsFile,
+		FetchCommand:    o.fetchCommand(),
+		ValidateCommand: o.validateCommand(),
+		CreateCommand:   o.createCommand(),
+		Sync:            o.sync(),
+	}
+
+	if err := workflowTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to write workflow: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package workflow
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func validOptions() Options {
+	return Options{
+		Name:              DefaultName,
+		Schedule:          DefaultSchedule,
+		Repository:        "group/project",
+		GitLabTokenSecret: DefaultGitLabTokenSecret,
+	}
+}
+
+func TestOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(*Options)
+		expectError bool
+	}{
+		{name: "valid", modify: func(o *Options) {}},
+		{name: "no source", modify: func(o *Options) { o.Repository = "" }, expectError: true},
+		{name: "two sources", modify: func(o *Options) { o.Group = "acme" }, expectError: true},
+		{name: "invalid schedule", modify: func(o *Options) { o.Schedule = "daily" }, expectError: true},
+		{name: "two targets", modify: func(o *Options) { o.Target = "a/b"; o.GitHubTarget = "c/d" }, expectError: true},
+		{name: "group with mapping", modify: func(o *Options) { o.Repository, o.Group, o.Mapping = "", "acme", "repos.yaml" }},
+		{name: "group with target", modify: func(o *Options) { o.Repository, o.Group, o.Target = "", "acme", "a/b" }, expectError: true},
+		{name: "incremental group", modify: func(o *Options) { o.Repository, o.Group, o.Incremental = "", "acme", true }, expectError: true},
+		{name: "verify without sync", modify: func(o *Options) { o.Verify = true }, expectError: true},
+		{name: "invalid secret", modify: func(o *Options) { o.GitHubTokenSecret = "MY-TOKEN" }, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := validOptions()
+			tt.modify(&o)
+
+			err := o.Validate()
+			if tt.expectError && err == nil {
+				t.Error("Validate() expected an error")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Validate() unexpected error = %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(*Options)
+		expected []string
+		absent   []string
+	}{
+		{
+			name:     "fetch only",
+			modify:   func(o *Options) {},
+			expected: []string{"gh gl-create-refs fetch-refs --repository group/project --output refs.csv", "GH_TOKEN: ${{ github.token }}"},
+			absent:   []string{"gh gl-create-refs create-refs", "actions/cache", "gh gl-create-refs validate"},
+		},
+		{
+			name: "incremental sync with verification",
+			modify: func(o *Options) {
+				o.Incremental = true
+				o.Target = "target/project"
+				o.Verify = true
+				o.BaseURL = "https://gitlab.example.com"
+			},
+			expected: []string{
+				"--incremental --output refs.csv",
+				"actions/cache@v4",
+				".gh-gl-create-refs-state.json",
+				"gh gl-create-refs validate --input refs.csv",
+				"create-refs --input refs.csv --update-existing --repository group/project --base-url https://gitlab.example.com --target target/project --verify-checksum",
+			},
+		},
+		{
+			name: "group with mapping",
+			modify: func(o *Options) {
+				o.Repository = ""
+				o.Group = "acme corp"
+				o.Mapping = "repos.yaml"
+				o.GitHubTokenSecret = "MIGRATION_TOKEN"
+			},
+			expected: []string{"fetch-refs --group 'acme corp' --combined --output refs.csv", "create-refs --input refs.csv --update-existing --mapping repos.yaml", "GH_TOKEN: ${{ secrets.MIGRATION_TOKEN }}"},
+			absent:   []string{"--repository"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := validOptions()
+			tt.modify(&o)
+
+			var b strings.Builder
+			if err := Generate(&b, o); err != nil {
+				t.Fatalf("Generate() unexpected error = %v", err)
+			}
+			got := b.String()
+
+			var parsed struct {
+				Name string `yaml:"name"`
+				Jobs map[string]struct {
+					Steps []map[string]any `yaml:"steps"`
+				} `yaml:"jobs"`
+			}
+			if err := yaml.Unmarshal([]byte(got), &parsed); err != nil {
+				t.Fatalf("Generate() wrote invalid YAML: %v\n%s", err, got)
+			}
+			if parsed.Name != DefaultName || len(parsed.Jobs["sync"].Steps) == 0 {
+				t.Errorf("Generate() wrote an unexpected workflow:\n%s", got)
+			}
+
+			for _, want := range tt.expected {
+				if !strings.Contains(got, want) {
+					t.Errorf("Generate() missing %q in:\n%s", want, got)
+				}
+			}
+			for _, unwanted := range tt.absent {
+				if strings.Contains(got, unwanted) {
+					t.Errorf("Generate() unexpectedly contains %q in:\n%s", unwanted, got)
+				}
+			}
+		})
+	}
+}
+
+func TestShellJoin(t *testing.T) {
+	got := shellJoin([]string{"gh", "a b", "it's", "group/project"})
+	want := `gh 'a b' 'it'\''s' group/project`
+	if got != want {
+		t.Errorf("shellJoin() = %q, want %q", got, want)
+	}
+}